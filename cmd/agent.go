@@ -0,0 +1,442 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/agent"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/state"
+	"github.com/fynxlabs/rwr/internal/status"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// agentOptions are the flags shared by the agent loop and its install.
+type agentOptions struct {
+	interval time.Duration
+	jitter   time.Duration
+	window   string
+	once     bool
+}
+
+// newAgentCmd is wiring for the pull-mode agent; its decisions live in
+// internal/agent.
+func newAgentCmd(app *AppConfig) *cobra.Command {
+	opts := agentOptions{}
+
+	agentCmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Keep this machine converged: pull the tree and apply it when it or the drift changes",
+		Long: `Run rwr as an agent. Every --interval (plus up to --jitter, so a fleet does
+not pull at once) the agent pulls the blueprint repository, resolves the tree,
+and measures drift the way rwr status does. It applies the tree only when the
+tree changed since the last apply, or the drift changed - and, with --window,
+only inside the maintenance window; a due apply outside it is deferred.
+
+Applies are ordinary runs: non-interactive, recorded in the run journal. The
+last cycle's status is kept in the config directory and served on a unix
+socket there while the agent runs; rwr agent status reads either.
+
+Examples:
+  rwr agent                                # until stopped
+  rwr agent --interval 1h --window 02:00-05:00
+  rwr agent --once                         # one cycle, for a scheduler
+  rwr agent install --interval 1h          # systemd user timer / launchd agent
+  rwr agent status`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			window, err := agent.ParseWindow(opts.window)
+			if err != nil {
+				return err
+			}
+			if opts.interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			// A one-shot cycle comes from a scheduler, which does its own
+			// spreading; only an explicit --jitter delays it.
+			jitter := opts.jitter
+			if opts.once && !cmd.Flags().Changed("jitter") {
+				jitter = 0
+			}
+			// Nobody is at the terminal of an agent.
+			app.Interactive = false
+			return runAgent(app, opts, window, jitter)
+		},
+	}
+	addAgentScheduleFlags(agentCmd, &opts)
+	agentCmd.Flags().BoolVar(&opts.once, "once", false, "Run one cycle and exit")
+
+	agentCmd.AddCommand(newAgentStatusCmd(app))
+	agentCmd.AddCommand(newAgentInstallCmd(app, &opts))
+	agentCmd.AddCommand(newAgentUninstallCmd(app))
+	return agentCmd
+}
+
+// addAgentScheduleFlags gives the agent and its install the same schedule.
+func addAgentScheduleFlags(cmd *cobra.Command, opts *agentOptions) {
+	cmd.Flags().DurationVar(&opts.interval, "interval", 30*time.Minute, "Time between cycles")
+	cmd.Flags().DurationVar(&opts.jitter, "jitter", 5*time.Minute, "Random delay of up to this before each cycle")
+	cmd.Flags().StringVar(&opts.window, "window", "", "Maintenance window to apply in, local time HH:MM-HH:MM (default: any time)")
+}
+
+// runAgent is the loop: cycle, record, wait, until a signal or, with
+// --once, after the first cycle.
+func runAgent(app *AppConfig, opts agentOptions, window *agent.Window, jitter time.Duration) error {
+	// The root signal handler cancels the run context; a cycle in progress
+	// stops the way a run does, and the loop ends with it.
+	ctx, stop := context.WithCancel(system.RunContext())
+	defer stop()
+
+	// Two agents would race each other's applies.
+	socketPath := agent.SocketPath(app.ConfigLocation)
+	if _, err := agent.Query(socketPath); err == nil {
+		return fmt.Errorf("an agent is already running on %s", socketPath)
+	}
+
+	statusPath := agent.StatusPath(app.ConfigLocation)
+	last, err := agent.LoadStatus(statusPath)
+	if err != nil {
+		log.Warnf("%v; starting without the last cycle's record", err)
+	}
+
+	current := make(chan agent.Status, 1)
+	current <- last
+	if !opts.once {
+		served := make(chan error, 1)
+		go func() {
+			served <- agent.Serve(ctx, socketPath, func() agent.Status {
+				s := <-current
+				current <- s
+				return s
+			})
+		}()
+		defer func() {
+			stop()
+			if err := <-served; err != nil {
+				log.Warnf("status socket: %v", err)
+			}
+			_ = os.Remove(socketPath)
+		}()
+	}
+
+	initRef := app.InitFilePath
+	environ := rwrEnviron()
+	for {
+		if !sleepCtx(ctx, randomUpTo(jitter)) {
+			return nil
+		}
+
+		resetRWREnviron(environ)
+		next := agentCycle(app, initRef, window, last)
+		if !opts.once {
+			next.Next = time.Now().Add(opts.interval)
+			// A deferred apply need not wait out a whole interval past
+			// the window opening.
+			if next.Outcome == agent.Deferred {
+				if opens := window.NextOpen(time.Now()); opens.Before(next.Next) {
+					next.Next = opens
+				}
+			}
+		}
+		if err := agent.SaveStatus(statusPath, next); err != nil {
+			log.Warnf("recording the agent status: %v", err)
+		}
+		<-current
+		current <- next
+		last = next
+		log.Infof("Agent cycle %s: %s", next.Outcome, next.Reason)
+
+		if opts.once {
+			switch next.Outcome {
+			case agent.Failed, agent.Errored:
+				return fmt.Errorf("agent cycle %s: %s", next.Outcome, next.Error)
+			}
+			return nil
+		}
+		if !sleepCtx(ctx, time.Until(next.Next)) {
+			return nil
+		}
+	}
+}
+
+// agentCycle is one pass: pull, initialize and resolve the tree from
+// scratch, measure drift, and apply if the tree or drift changed and the
+// window is open.
+func agentCycle(app *AppConfig, initRef string, window *agent.Window, prev agent.Status) (cycle agent.Status) {
+	cycle = prev.Carry(time.Now())
+	defer func() { cycle.Finished = time.Now() }()
+	fail := func(err error) agent.Status {
+		log.Errorf("Agent cycle: %v", err)
+		cycle.Outcome, cycle.Error, cycle.Reason = agent.Errored, err.Error(), "the tree could not be resolved"
+		return cycle
+	}
+
+	cycle.PullError = pullTree(app, initRef)
+	if cycle.PullError != "" {
+		log.Warnf("Pulling the blueprint repository: %s; using the tree on disk", cycle.PullError)
+	}
+
+	// initializeSystemInfo rewrites InitFilePath as it resolves it; every
+	// cycle starts from what the operator gave.
+	app.InitFilePath = initRef
+	if err := initializeSystemInfo(app, true); err != nil {
+		return fail(err)
+	}
+	if _, revision, ok := agent.Repository(app.InitConfig.Init.Location); ok {
+		cycle.Revision = revision
+	}
+
+	plan, err := processors.ResolveStage1(app.InitConfig)
+	if err != nil {
+		return fail(err)
+	}
+	processors.ResolveStage2(plan, app.OSInfo)
+	cycle.Tree = agent.Fingerprint(plan)
+	if cycle.Drift, err = agentDrift(app, plan); err != nil {
+		return fail(err)
+	}
+
+	due, reason := agent.Decide(prev, cycle.Tree, cycle.Drift)
+	cycle.Reason = reason
+	switch {
+	case !due:
+		cycle.Outcome = agent.Unchanged
+		return cycle
+	case !window.Open(time.Now()):
+		cycle.Outcome = agent.Deferred
+		cycle.Reason = fmt.Sprintf("%s; outside the maintenance window %s", reason, window)
+		return cycle
+	}
+
+	log.Infof("Agent applying the tree: %s", reason)
+	if err := runEverythingHeadless(app, nil); err != nil {
+		cycle.Outcome, cycle.Error = agent.Failed, err.Error()
+		return cycle
+	}
+	cycle.Outcome = agent.Applied
+	if system.IsDryRun() {
+		return cycle
+	}
+	// What the apply left drifted, it cannot fix; the next cycle compares
+	// against that, not against the drift it started from.
+	remaining, err := agentDrift(app, plan)
+	if err != nil {
+		log.Warnf("Measuring drift after the apply: %v", err)
+		remaining = cycle.Drift
+	}
+	cycle.AppliedTree, cycle.AppliedDrift, cycle.AppliedAt = cycle.Tree, remaining, time.Now()
+	return cycle
+}
+
+// agentDrift measures drift the way rwr status does.
+func agentDrift(app *AppConfig, plan *types.Plan) ([]string, error) {
+	applies, err := state.Applies(app.ConfigLocation)
+	if err != nil {
+		return nil, err
+	}
+	return agent.DriftSignature(status.Rows(plan, applies, status.NewQuerier())), nil
+}
+
+// pullTree pulls the repository the init source lives in, when it is a git
+// checkout, and reports a failure as text for the cycle's status. A
+// blueprints.git repository configured in the init file is synced by
+// initialization itself.
+func pullTree(app *AppConfig, initRef string) string {
+	resolved, err := helpers.ResolveInitSource(configuredInitFile(initRef))
+	if err != nil || strings.HasPrefix(resolved, "https://") {
+		return ""
+	}
+	root, _, ok := agent.Repository(filepath.Dir(resolved))
+	if !ok {
+		return ""
+	}
+	if system.IsDryRun() {
+		log.Infof("[DRY-RUN] Would pull %s", root)
+		return ""
+	}
+	// Authentication only needs the key and token; the tree's own init
+	// config is not loaded yet.
+	auth := &types.InitConfig{Variables: types.Variables{Flags: types.Flags{SSHKey: app.SSHKey, GHAPIToken: app.GHAPIToken}}}
+	if err := helpers.HandleGitPull(types.GitOptions{Target: root}, auth); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// rwrEnviron is the RWR_* environment the agent started with.
+func rwrEnviron() map[string]bool {
+	environ := map[string]bool{}
+	for _, env := range os.Environ() {
+		if key, _, _ := strings.Cut(env, "="); strings.HasPrefix(key, "RWR_") {
+			environ[key] = true
+		}
+	}
+	return environ
+}
+
+// resetRWREnviron drops the RWR_VAR_* and RWR_CRED_* exports the previous
+// cycle's initialization left. Initialization reads every RWR_* variable
+// back in as a user-defined variable; left in place, the second cycle would
+// resolve the tree with its own exports - and credentials - in scope.
+func resetRWREnviron(environ map[string]bool) {
+	for _, env := range os.Environ() {
+		if key, _, _ := strings.Cut(env, "="); strings.HasPrefix(key, "RWR_") && !environ[key] {
+			_ = os.Unsetenv(key)
+		}
+	}
+}
+
+func randomUpTo(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) // #nosec G404 -- spreading load, not security
+}
+
+// sleepCtx waits d, reporting false if ctx ended first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func newAgentStatusCmd(app *AppConfig) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the agent's last cycle",
+		Long: `Show the agent's last cycle: what it decided and why, the tree revision, the
+drift it measured, and when it next runs. Asks the running agent over its
+socket, and falls back to the status file the last cycle left.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := agent.Query(agent.SocketPath(app.ConfigLocation))
+			running := err == nil
+			if !running {
+				if s, err = agent.LoadStatus(agent.StatusPath(app.ConfigLocation)); err != nil {
+					return err
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			switch format {
+			case "json":
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(s)
+			case "text":
+				helpers.Say(out, "%s", agent.Render(s))
+				if !running && !s.Started.IsZero() {
+					helpers.Say(out, "\n(no agent answering on %s: this is the last recorded cycle)\n", agent.SocketPath(app.ConfigLocation))
+				}
+				return nil
+			}
+			return fmt.Errorf("unknown format %q: want text or json", format)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+	return cmd
+}
+
+func newAgentInstallCmd(app *AppConfig, opts *agentOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Run the agent on a schedule: a systemd user timer, or a launchd agent",
+		Long: `Install the agent as a per-user schedule running one cycle (rwr agent --once)
+every --interval: a systemd user service and timer on Linux, a launchd agent
+on macOS. The init file, config directory, --config-name, --profile and
+--window given here are fixed into the schedule; run install again to change
+them. Logs go to the user journal (journalctl --user -u rwr-agent) or, on
+macOS, to agent.log in the config directory.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := agent.ParseWindow(opts.window); err != nil {
+				return err
+			}
+			if opts.interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			schedule, err := agentSchedule(app, *opts)
+			if err != nil {
+				return err
+			}
+			return agent.Install(schedule, app.Debug)
+		},
+	}
+	addAgentScheduleFlags(cmd, opts)
+	return cmd
+}
+
+// agentSchedule is the one-shot invocation the scheduler runs, pinned to
+// what this invocation resolved: the scheduler starts it elsewhere, with
+// none of this shell's flags.
+func agentSchedule(app *AppConfig, opts agentOptions) (agent.Schedule, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return agent.Schedule{}, fmt.Errorf("finding this rwr binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	initRef := configuredInitFile(app.InitFilePath)
+	if initRef == "" {
+		initRef = "."
+	}
+	if _, err := os.Stat(initRef); err == nil {
+		if initRef, err = filepath.Abs(initRef); err != nil {
+			return agent.Schedule{}, err
+		}
+	}
+
+	args := []string{executable, "agent", "--once", "--init-file", initRef, "--config", app.ConfigLocation}
+	if app.ConfigName != "" {
+		args = append(args, "--config-name", app.ConfigName)
+	}
+	for _, profile := range app.Profiles {
+		args = append(args, "--profile", profile)
+	}
+	if opts.window != "" {
+		args = append(args, "--window", opts.window)
+	}
+	// systemd spreads the timer itself; launchd cannot, so the cycle does.
+	if runtime.GOOS == "darwin" {
+		args = append(args, "--jitter", opts.jitter.String())
+	}
+	return agent.Schedule{
+		Args:     args,
+		Interval: opts.interval,
+		Jitter:   opts.jitter,
+		Log:      filepath.Join(app.ConfigLocation, "agent.log"),
+	}, nil
+}
+
+func newAgentUninstallCmd(app *AppConfig) *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the agent's schedule",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return agent.Uninstall(app.Debug)
+		},
+	}
+}
@@ -6,11 +6,13 @@ import (
 
 // newAllCmd is the whole-tree run: new-system initialization.
 func newAllCmd(app *AppConfig) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "all",
 		Short: "Run All Blueprints - New System Initialization",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runEverything(app)
 		},
 	}
+	addPushFlags(cmd, app)
+	return cmd
 }
@@ -24,6 +24,8 @@ type AppConfig struct {
 	DryRun           bool
 	LogLevel         string
 	Profiles         []string
+	Prune            bool
+	Offline          bool
 
 	// Paths
 	ConfigPath      string // --config: overrides where the config file is looked up
@@ -31,6 +33,7 @@ type AppConfig struct {
 	ConfigLocation  string
 	RunOnceLocation string
 	InitFilePath    string
+	CacheDir        string // --cache-dir: the offline cache, default <config>/cache
 
 	// Display
 	NoTUI     bool
@@ -41,6 +44,17 @@ type AppConfig struct {
 	TUIBuffer int
 	LogFile   string
 
+	// Push (--host, --inventory): the run happens on other machines
+	Hosts        []string
+	Inventory    string
+	RemoteBinary string
+	// ReportEvents is the far end of a push: events framed on stdout, the
+	// log as JSON on stderr, for the pushing rwr to relay.
+	ReportEvents bool
+
+	// Sinks: config.yaml's hooks, webhooks and metrics
+	Sinks types.Sinks
+
 	// Resolved run state
 	InitConfig *types.InitConfig
 	OSInfo     *types.OSInfo
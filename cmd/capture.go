@@ -28,7 +28,8 @@ func newCaptureCmd(app *AppConfig) *cobra.Command {
 		Short: "Turn this handcrafted machine into a blueprint tree",
 		Long: `Scan the machine - explicitly-installed packages across every detected
 package manager, dotfiles and ~/.config entries, enabled services, git
-checkouts - pick what to keep on a per-category form, and generate a
+checkouts, added repositories, Nerd Fonts, local users and groups, changed
+desktop settings - pick what to keep on a per-category form, and generate a
 validated blueprint tree from the selection. --all skips the form and takes
 the defaults; --manifest adds a root manifest matched to this machine.`,
 		Args: cobra.MaximumNArgs(1),
@@ -44,12 +45,16 @@ the defaults; --manifest adds a root manifest matched to this machine.`,
 
 			home, _ := os.UserHomeDir() //nolint:errcheck // empty home degrades templating only
 			findings := capture.Findings{
-				Packages: scan.Packages(system.GetAvailableProviders()),
-				Configs:  scan.Configs(home, false),
-				Services: scan.Services(),
-				Git:      scan.GitCheckouts([]string{home + "/git"}),
-				Home:     home,
+				Packages:     scan.Packages(system.GetAvailableProviders()),
+				Configs:      scan.Configs(home, false),
+				Services:     scan.Services(),
+				Git:          scan.GitCheckouts([]string{home + "/git"}),
+				Repositories: scan.Repositories(),
+				Fonts:        scan.Fonts(home),
+				Settings:     scan.Settings(),
+				Home:         home,
 			}
+			findings.Users, findings.Groups = scan.Users()
 
 			selection := capture.Defaults(findings)
 			if !all {
@@ -153,6 +158,23 @@ func selectionForm(findings capture.Findings, selection *capture.Selection) erro
 		))
 	}
 
+	repositoryPicks := pickGroup(&groups, "Repositories", findings.Repositories, true, func(r scan.RepositoryResult) string {
+		return r.Provider + ": " + r.Name + " (" + r.URL + ")"
+	})
+	fontPicks := pickGroup(&groups, "Nerd Fonts", findings.Fonts, true, func(f scan.FontResult) string {
+		if f.System {
+			return f.Name + " (system)"
+		}
+		return f.Name
+	})
+	userPicks := pickGroup(&groups, "Local users", findings.Users, false, func(u scan.UserResult) string {
+		return u.Name + " (uid " + u.UID + ")"
+	})
+	groupPicks := pickGroup(&groups, "Local groups", findings.Groups, false, func(g scan.GroupResult) string {
+		return g.Name + " (gid " + g.GID + ")"
+	})
+	settingPicks := settingGroups(&groups, findings.Settings)
+
 	if len(groups) == 0 {
 		return fmt.Errorf("the scan found nothing to capture")
 	}
@@ -179,8 +201,86 @@ func selectionForm(findings capture.Findings, selection *capture.Selection) erro
 	for _, index := range gitPicks {
 		selection.Git = append(selection.Git, findings.Git[index])
 	}
+	selection.Repositories = picked(findings.Repositories, repositoryPicks)
+	selection.Fonts = picked(findings.Fonts, fontPicks)
+	selection.Users = picked(findings.Users, userPicks)
+	selection.Groups = picked(findings.Groups, groupPicks)
+	selection.Settings = nil
+	for _, picks := range settingPicks {
+		selection.Settings = append(selection.Settings, picked(findings.Settings, picks)...)
+	}
 	return nil
 }
 
+// pickGroup adds a multi-select over found items to the form, pre-selected
+// or not, and returns where the picked indexes land. Nothing found adds no
+// group.
+func pickGroup[T any](groups *[]*huh.Group, title string, found []T, preselect bool, label func(T) string) *[]int {
+	picks := &[]int{}
+	if len(found) == 0 {
+		return picks
+	}
+	options := make([]huh.Option[int], 0, len(found))
+	for i, item := range found {
+		options = append(options, huh.NewOption(label(item), i).Selected(preselect))
+	}
+	*groups = append(*groups, huh.NewGroup(
+		huh.NewMultiSelect[int]().
+			Title(fmt.Sprintf("%s (%d found)", title, len(found))).
+			Options(options...).Value(picks).Filterable(true),
+	))
+	return picks
+}
+
+// settingGroups adds one page per gsettings schema or defaults domain, so a
+// desktop's changes are reviewed where they belong rather than as one long
+// list. Every change is pre-selected: the scan only found keys that differ
+// from their default.
+func settingGroups(groups *[]*huh.Group, settings []scan.SettingResult) []*[]int {
+	byOwner := map[string][]int{}
+	var owners []string
+	for i, setting := range settings {
+		owner := setting.Schema
+		if setting.Domain != "" {
+			owner = setting.Domain
+		}
+		if byOwner[owner] == nil {
+			owners = append(owners, owner)
+		}
+		byOwner[owner] = append(byOwner[owner], i)
+	}
+	sort.Strings(owners)
+
+	var picks []*[]int
+	for _, owner := range owners {
+		options := make([]huh.Option[int], 0, len(byOwner[owner]))
+		for _, i := range byOwner[owner] {
+			setting := settings[i]
+			label := fmt.Sprintf("%s = %v", setting.Key, setting.Value)
+			if setting.Default != "" {
+				label += "  (default " + setting.Default + ")"
+			}
+			options = append(options, huh.NewOption(label, i).Selected(true))
+		}
+		picked := &[]int{}
+		picks = append(picks, picked)
+		*groups = append(*groups, huh.NewGroup(
+			huh.NewMultiSelect[int]().
+				Title(fmt.Sprintf("Settings - %s (%d changed)", owner, len(options))).
+				Options(options...).Value(picked).Filterable(true),
+		))
+	}
+	return picks
+}
+
+// picked maps the form's indexes back to the found items.
+func picked[T any](found []T, picks *[]int) []T {
+	var items []T
+	for _, index := range *picks {
+		items = append(items, found[index])
+	}
+	return items
+}
+
 // scanConfigKey indexes findings.Configs through the form.
 type scanConfigKey int
@@ -28,10 +28,12 @@ func newDiffCmd(app *AppConfig) *cobra.Command {
 		Use:   "diff",
 		Short: "Show machine drift as blueprint material",
 		Long: `Compare what is actually on this machine - explicitly-installed packages,
-enabled services, git checkouts, configs - against the blueprint tree.
-Additions are things you did by hand; removals are declared things that are
-gone. Output as a readable list, paste-ready blueprint blocks (--format), or
-routed interactively into the tree (--into). Diff never touches the system.`,
+enabled services, git checkouts, configs, added repositories, Nerd Fonts,
+local users and groups, changed desktop settings - against the blueprint tree.
+Additions are things you did by hand; removals are declared packages and
+services that are gone. Output as a readable list, paste-ready blueprint
+blocks (--format), or routed interactively into the tree (--into). Diff never
+touches the system.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			machine := scanMachine(scoped(categories))
 
@@ -74,7 +76,7 @@ routed interactively into the tree (--into). Diff never touches the system.`,
 
 	diffCmd.Flags().StringVar(&format, "format", "", "Emit additions as paste-ready blueprint blocks: yaml, json, toml, or cue")
 	diffCmd.Flags().StringVar(&into, "into", "", "Route additions into this blueprint tree interactively")
-	for _, category := range []string{"packages", "configs", "services", "git"} {
+	for _, category := range []string{"packages", "configs", "services", "git", "repositories", "fonts", "users", "settings"} {
 		categories[category] = diffCmd.Flags().Bool(category, false, "Only the "+category+" category")
 	}
 	return diffCmd
@@ -97,6 +99,18 @@ func scanMachine(only map[string]bool) diff.Machine {
 	if all || only["configs"] {
 		machine.Configs = scan.Configs(home, false)
 	}
+	if all || only["repositories"] {
+		machine.Repositories = scan.Repositories()
+	}
+	if all || only["fonts"] {
+		machine.Fonts = scan.Fonts(home)
+	}
+	if all || only["users"] {
+		machine.Users, machine.Groups = scan.Users()
+	}
+	if all || only["settings"] {
+		machine.Settings = scan.Settings()
+	}
 	return machine
 }
 
@@ -116,7 +130,7 @@ func filterCategories(changes []diff.Change, only map[string]bool) []diff.Change
 	if len(only) == 0 {
 		return changes
 	}
-	alias := map[string]string{types.BlueprintTypeFiles: "configs"}
+	alias := map[string]string{types.BlueprintTypeFiles: "configs", types.BlueprintTypeConfiguration: "settings"}
 	var kept []diff.Change
 	for _, change := range changes {
 		name := change.Category
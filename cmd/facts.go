@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/spf13/cobra"
+)
+
+func newFactsCmd(app *AppConfig) *cobra.Command {
+	var format string
+	factsCmd := &cobra.Command{
+		Use:   "facts",
+		Short: "Show the facts blueprints see as {{ .Facts }}",
+		Long: `Gather this machine's facts - hostname, CPUs, memory, GPU vendor, laptop,
+virtualization, container, desktop - and the custom facts the executables in
+<config dir>/facts.d report, and print them as templates and when: conditions
+see them.`,
+		Example: `  rwr facts
+  rwr facts --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			facts := system.GatherFacts(filepath.Join(app.ConfigLocation, system.FactsDirName))
+			values := facts.ToMap()
+			out := cmd.OutOrStdout()
+
+			switch format {
+			case "json":
+				data, err := json.MarshalIndent(values, "", "  ")
+				if err != nil {
+					return err
+				}
+				helpers.Say(out, "%s\n", data)
+			case "text":
+				custom, _ := values["custom"].(map[string]interface{})
+				delete(values, "custom")
+				sayFacts(cmd, ".Facts.", values)
+				sayFacts(cmd, ".Facts.custom.", custom)
+			default:
+				return fmt.Errorf("unknown facts format %q: use text or json", format)
+			}
+			return nil
+		},
+	}
+	factsCmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+	return factsCmd
+}
+
+// sayFacts prints one fact per line under the reference a template uses.
+func sayFacts(cmd *cobra.Command, prefix string, values map[string]interface{}) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		helpers.Say(cmd.OutOrStdout(), "%s%s: %v\n", prefix, key, values[key])
+	}
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/tui"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// newFetchCmd is wiring; what fetch downloads lives in internal/processors.
+func newFetchCmd(app *AppConfig) *cobra.Command {
+	return &cobra.Command{
+		Use:   "fetch",
+		Short: "Download what a run needs into the offline cache",
+		Long: `Download everything a run of this tree on this machine takes from the
+network into the offline cache, for a later rwr --offline to install from:
+
+  packages   through each package manager's offline fetch, into its own
+             directory in the cache (apt, dnf, pacman and brew)
+  files      URL sources, checked against their sha256
+  fonts      Nerd Font archives
+  git        bare mirrors of git repositories, the blueprint repository's too
+
+Profiles and when: conditions apply as they do in a run, so fetch on the
+machine - or one like it - that will install offline. Fetching again
+refreshes the cache. The cache is <config>/cache, or --cache-dir.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run := func() error {
+				return observeRun(app, func() error {
+					return processors.Fetch(app.InitConfig, app.OSInfo)
+				})
+			}
+			if tui.Active(app.NoTUI) {
+				return runDashboard(app, func() (*types.Plan, func() error, error) {
+					plan, err := processors.FetchPlan(app.InitConfig, app.OSInfo)
+					if err != nil {
+						return nil, nil, err
+					}
+					return plan, run, nil
+				})
+			}
+			return run()
+		},
+	}
+}
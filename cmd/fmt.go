@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/fynxlabs/rwr/internal/convert"
+	"github.com/spf13/cobra"
+)
+
+// newFmtCmd is the cobra wiring for `rwr fmt`; the implementation lives in
+// internal/convert, beside the format conversion it shares a tree walk with.
+func newFmtCmd() *cobra.Command {
+	var check bool
+
+	fmtCmd := &cobra.Command{
+		Use:   "fmt [path]",
+		Short: "Rewrite blueprint files in canonical form",
+		Long: `Rewrite every blueprint, init, bootstrap, and manifest file in a tree in
+canonical form, keeping each file's format: keys in schema order, names lists
+sorted with duplicates dropped, and file modes as quoted octal strings
+("0644").
+
+YAML and CUE comments are kept; TOML comments are not, and the command warns
+per TOML file that carries them. Files that are not blueprints (template
+sources) are left alone, and a file that does not parse is reported and
+skipped.
+
+With --check nothing is written: the command lists the files that are not
+formatted and fails if there are any.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := "."
+			if len(args) == 1 {
+				root = args[0]
+			}
+			return convert.Format(cmd.OutOrStdout(), root, check)
+		},
+	}
+
+	fmtCmd.Flags().BoolVar(&check, "check", false, "List unformatted files and fail if there are any, without writing")
+	return fmtCmd
+}
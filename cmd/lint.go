@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/lint"
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/validate"
+	"github.com/spf13/cobra"
+)
+
+// newLintCmd is wiring; the rules live in internal/lint.
+func newLintCmd() *cobra.Command {
+	var (
+		format    string
+		listRules bool
+	)
+
+	lintCmd := &cobra.Command{
+		Use:   "lint [path]",
+		Short: "Check a blueprint tree for entries that contradict each other",
+		Long: `Check a blueprint tree for problems that no single entry shows: a package
+installed through two package managers, a service enabled in one file and
+disabled in another, a target path written twice, profiles declared and
+never used. Validate checks each entry; lint checks them against each other.
+
+Lint reads the tree for no machine in particular: templates render with empty
+system variables, and only pinned package managers are compared.
+
+Rules are configured in the init file's lint block, and suppressed per file or
+per line with # rwr-lint-disable / # rwr-lint-disable-next-line comments.
+
+Examples:
+  rwr lint
+  rwr lint path/to/blueprints --format json
+  rwr lint --list-rules`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if listRules {
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				for _, rule := range lint.Rules() {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", rule.ID, rule.Severity, rule.Summary)
+				}
+				return w.Flush()
+			}
+
+			path := "."
+			if len(args) > 0 {
+				path = args[0]
+			}
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("error resolving path %s: %w", path, err)
+			}
+
+			plan, err := validate.ResolveTree(absPath, nil)
+			if err != nil {
+				return err
+			}
+			if len(plan.Diags) > 0 {
+				log.Warnf("%d problems keep lint from reading parts of the tree; run rwr validate", len(plan.Diags))
+			}
+			processors.ResolveStage2For(plan, nil, nil)
+
+			findings, err := lint.Run(plan)
+			if err != nil {
+				return err
+			}
+
+			errors := 0
+			for _, finding := range findings {
+				if finding.Severity == lint.SeverityError {
+					errors++
+				}
+			}
+
+			switch format {
+			case "json":
+				if findings == nil {
+					findings = []lint.Finding{}
+				}
+				data, err := json.MarshalIndent(findings, "", "  ")
+				if err != nil {
+					return err
+				}
+				helpers.Say(out, "%s\n", data)
+			case "text":
+				for _, finding := range findings {
+					where := finding.File
+					if where == "" {
+						where = "init"
+					} else if finding.Line > 0 {
+						where = fmt.Sprintf("%s:%d", where, finding.Line)
+					}
+					helpers.Say(out, "%s: %s [%s] %s\n", where, finding.Severity, finding.Rule, finding.Message)
+				}
+			default:
+				return fmt.Errorf("unknown lint format %q: use text or json", format)
+			}
+
+			if errors > 0 {
+				return fmt.Errorf("lint found %d errors", errors)
+			}
+			return nil
+		},
+	}
+
+	lintCmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+	lintCmd.Flags().BoolVar(&listRules, "list-rules", false, "List the rules and their default severities")
+	return lintCmd
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/fynxlabs/rwr/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+// newLspCmd is wiring; the server lives in internal/lsp.
+func newLspCmd(app *AppConfig) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "Run the blueprint language server over stdio",
+		Long: `Run a Language Server Protocol server on stdin and stdout for editing
+blueprint files. It publishes the diagnostics rwr validate reports when a file
+is opened or saved, completes field names, actions, provider names and the
+tree's profile names, documents fields on hover, jumps from an import: line to
+the imported file, and renames a profile everywhere the tree declares it.
+Point your editor's LSP client at "rwr lsp"; logs go to stderr.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lsp.NewServer(app.OSInfo).Serve(os.Stdin, os.Stdout)
+		},
+	}
+}
@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"charm.land/log/v2"
 	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +20,11 @@ This tells you what you can pass to --profile.
 
 Profiles let you select what applies to a machine (work, personal, development,
 gaming, and so on). An entry that declares no profiles is a base item and always
-applies.`,
+applies.
+
+It also shows which profiles are active on this machine and why: requested with
+--profile, activated by a host_profiles rule in the init file, or taken from its
+default_profiles.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.InitConfig == nil {
 				return fmt.Errorf("configuration not initialized: check that you have a valid init file")
@@ -34,6 +40,7 @@ applies.`,
 			if len(summary.Names) == 0 {
 				fmt.Println("No profiles found in your blueprints.")
 				fmt.Printf("All %d item(s) are base items and always apply.\n", summary.BaseItems)
+				printActiveProfiles(app.InitConfig.ActiveProfiles, summary.Names)
 				return nil
 			}
 
@@ -51,7 +58,38 @@ applies.`,
 				fmt.Printf("  rwr run packages --profile %s\n", strings.Join(summary.Names[:2], ","))
 			}
 			fmt.Println("  rwr all --profile all")
+
+			printActiveProfiles(app.InitConfig.ActiveProfiles, summary.Names)
 			return nil
 		},
 	}
 }
+
+// printActiveProfiles lists this machine's active profiles, each with every
+// reason it is active, flagging one the blueprints never use.
+func printActiveProfiles(activations []types.ProfileActivation, declared []string) {
+	fmt.Println()
+	if len(activations) == 0 {
+		fmt.Println("No profiles are active on this machine: every entry applies.")
+		fmt.Println("Pass --profile, or set default_profiles or host_profiles in the init file.")
+		return
+	}
+
+	var order []string
+	reasons := map[string][]string{}
+	for _, activation := range activations {
+		if _, seen := reasons[activation.Profile]; !seen {
+			order = append(order, activation.Profile)
+		}
+		reasons[activation.Profile] = append(reasons[activation.Profile], activation.Reason)
+	}
+
+	fmt.Println("Active on this machine:")
+	for _, profile := range order {
+		note := ""
+		if !slices.Contains(declared, profile) && profile != "all" {
+			note = " - no blueprint declares it"
+		}
+		fmt.Printf("  • %s (%s)%s\n", profile, strings.Join(reasons[profile], "; "), note)
+	}
+}
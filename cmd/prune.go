@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/tui"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// newPruneCmd is wiring; what counts as an orphan lives in internal/processors.
+func newPruneCmd(app *AppConfig) *cobra.Command {
+	var yes bool
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove packages no blueprint declares",
+		Long: `List the packages explicitly installed on this machine that no blueprint in
+the tree declares, ask about each, and remove the ones confirmed. Answering
+"all" removes the rest without asking again.
+
+Only the package managers named under prune.providers in the init file are
+looked at, and only those with an explicitly-installed query - a full list
+would include every dependency. prune.keep protects base-system packages per
+package manager. Removals are recorded in the run journal like any other.
+
+Nothing is removed unasked: with --interactive=false the candidates are only
+listed, unless --yes is given. --dry-run shows what would be removed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run := func(candidates []types.Resource) func() error {
+				return func() error {
+					return observeRun(app, func() error {
+						return processors.Prune(app.InitConfig, app.OSInfo, candidates, yes)
+					})
+				}
+			}
+			if tui.Active(app.NoTUI) {
+				return runDashboard(app, func() (*types.Plan, func() error, error) {
+					candidates, err := processors.PruneCandidates(app.InitConfig)
+					if err != nil {
+						return nil, nil, err
+					}
+					plan := &types.Plan{Init: app.InitConfig, Order: []string{types.BlueprintTypePackages}, Resources: candidates}
+					return plan, run(candidates), nil
+				})
+			}
+			candidates, err := processors.PruneCandidates(app.InitConfig)
+			if err != nil {
+				return err
+			}
+			return run(candidates)()
+		},
+	}
+	pruneCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Remove every candidate without asking")
+	return pruneCmd
+}
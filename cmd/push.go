@@ -0,0 +1,352 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/remote"
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/tui"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// pushTransport is the SSH transport pushes use; tests swap in a stand-in.
+var pushTransport remote.Transport = remote.OpenSSH{}
+
+// addPushFlags gives a command the flags that run it on other machines.
+func addPushFlags(cmd *cobra.Command, app *AppConfig) {
+	cmd.Flags().StringArrayVar(&app.Hosts, "host", nil, "Run on this host over SSH instead of here: [user@]host, or a name from --inventory (repeatable)")
+	cmd.Flags().StringVar(&app.Inventory, "inventory", "", "Inventory file listing the hosts to run on")
+	cmd.Flags().StringVar(&app.RemoteBinary, "remote-binary", "", "rwr binary to copy to the hosts (default: this one)")
+}
+
+// pushing reports whether the command runs on other machines.
+func pushing(app *AppConfig) bool {
+	return len(app.Hosts) > 0 || app.Inventory != ""
+}
+
+// pushHosts resolves --host and --inventory: every inventory host when no
+// --host is given, else each --host by inventory name or address, falling
+// back to the value as an ssh destination.
+func pushHosts(app *AppConfig) ([]types.InventoryHost, error) {
+	var inventory []types.InventoryHost
+	if app.Inventory != "" {
+		loaded, err := helpers.LoadInventory(system.ExpandPath(app.Inventory))
+		if err != nil {
+			return nil, err
+		}
+		inventory = loaded.Hosts
+		if len(app.Hosts) == 0 {
+			if len(inventory) == 0 {
+				return nil, fmt.Errorf("inventory %s lists no hosts", app.Inventory)
+			}
+			return inventory, nil
+		}
+	}
+
+	var hosts []types.InventoryHost
+	for _, value := range app.Hosts {
+		host := types.InventoryHost{Address: value}
+		for _, listed := range inventory {
+			if listed.Label() == value || listed.Address == value {
+				host = listed
+				break
+			}
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// pushPayload is what every host receives: a binary, and the directory
+// holding the init file (or manifest) and everything its tree reads - or,
+// for an init file served over https, no tree and the URL for the host to
+// fetch.
+type pushPayload struct {
+	binary string
+	root   string
+	init   string // --init-file on the host, relative to root
+}
+
+func newPushPayload(app *AppConfig) (pushPayload, error) {
+	binary := system.ExpandPath(app.RemoteBinary)
+	if binary == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return pushPayload{}, fmt.Errorf("finding this rwr binary to copy: %w", err)
+		}
+		binary = executable
+	}
+
+	resolved, err := helpers.ResolveInitSource(configuredInitFile(app.InitFilePath))
+	if err != nil {
+		return pushPayload{}, fmt.Errorf("error resolving init source %q: %w", app.InitFilePath, err)
+	}
+	if strings.HasPrefix(resolved, "https://") {
+		return pushPayload{binary: binary, init: resolved}, nil
+	}
+	abs, err := filepath.Abs(resolved)
+	if err != nil {
+		return pushPayload{}, err
+	}
+	root, err := pushRoot(abs)
+	if err != nil {
+		return pushPayload{}, err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return pushPayload{}, err
+	}
+	return pushPayload{binary: binary, root: root, init: filepath.ToSlash(rel)}, nil
+}
+
+// pushRoot is the directory a push ships: the deepest one holding the init
+// file and every path its resolved tree reads - a blueprint location beside
+// it (location: ../blueprints), imports from a shared directory - so the
+// host's run finds each where the init file's relative paths say. For a
+// manifest, that is every configuration's tree.
+func pushRoot(initFile string) (string, error) {
+	inits := []string{initFile}
+	if isManifestPath(initFile) {
+		manifest, err := helpers.LoadManifest(initFile)
+		if err != nil {
+			return "", err
+		}
+		inits = nil
+		for _, entry := range manifest.Configurations {
+			inits = append(inits, filepath.Join(filepath.Dir(initFile), entry.Init))
+		}
+	}
+
+	root := filepath.Dir(initFile)
+	for _, init := range inits {
+		paths, err := processors.TreePaths(init)
+		if err != nil {
+			return "", fmt.Errorf("cannot push the tree of %s: %w", init, err)
+		}
+		for _, path := range paths {
+			root = commonDir(root, path)
+		}
+	}
+	if filepath.Dir(root) == root {
+		return "", fmt.Errorf("the tree of %s reaches up to %s; a push will not copy a whole filesystem", initFile, root)
+	}
+	return root, nil
+}
+
+// commonDir is the deepest directory holding both dir and path.
+func commonDir(dir, path string) string {
+	path = filepath.Clean(path)
+	for {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// stage checks the host can run the binary and copies the payload over.
+func (p pushPayload) stage(ctx context.Context, app *AppConfig, host types.InventoryHost) error {
+	goos, goarch, err := remote.Platform(ctx, pushTransport, host)
+	if err != nil {
+		return err
+	}
+	if app.RemoteBinary == "" && (goos != runtime.GOOS || goarch != runtime.GOARCH) {
+		return fmt.Errorf("%s is %s/%s but this rwr is built for %s/%s; pass --remote-binary with a build for %s/%s",
+			host.Label(), goos, goarch, runtime.GOOS, runtime.GOARCH, goos, goarch)
+	}
+	log.Infof("Copying rwr and %s to %s", p.describe(), host.Label())
+	return remote.Upload(ctx, pushTransport, host, p.binary, p.root)
+}
+
+func (p pushPayload) describe() string {
+	if p.root == "" {
+		return "no tree (the host fetches " + p.init + ")"
+	}
+	return p.root
+}
+
+// remoteArgs are the arguments the host's rwr runs with: the command, then
+// the flags of this invocation that shape a run. The host never prompts -
+// there is no terminal to prompt on - so elevation needs passwordless sudo
+// or a root login.
+func (p pushPayload) remoteArgs(app *AppConfig, host types.InventoryHost, command ...string) []string {
+	args := append(command, "--init-file", p.init, "--skip-version-check", "--interactive=false")
+	if app.ConfigName != "" {
+		args = append(args, "--config-name", app.ConfigName)
+	}
+	if app.DryRun {
+		args = append(args, "--dry-run")
+	}
+	if app.ForceBootstrap {
+		args = append(args, "--force-bootstrap")
+	}
+	if app.Debug {
+		args = append(args, "--debug")
+	}
+	for _, profile := range append(append([]string(nil), app.Profiles...), host.Profiles...) {
+		args = append(args, "--profile", profile)
+	}
+	return args
+}
+
+// runPush is `rwr all --host`: each host in turn gets the binary and the
+// tree, runs it, and has its journal copied back. One failing host does not
+// stop the others.
+func runPush(app *AppConfig) error {
+	hosts, err := pushHosts(app)
+	if err != nil {
+		return err
+	}
+	payload, err := newPushPayload(app)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, host := range hosts {
+		if err := pushRun(app, payload, host); err != nil {
+			log.Errorf("%s: %v", host.Label(), err)
+			failed = append(failed, host.Label())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("run failed on %d of %d host(s): %s", len(failed), len(hosts), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func pushRun(app *AppConfig, payload pushPayload, host types.InventoryHost) error {
+	ctx := context.Background()
+	if err := payload.stage(ctx, app, host); err != nil {
+		return err
+	}
+
+	args := payload.remoteArgs(app, host, "all", "--report-events", "--no-tui")
+	run := func(relay *remote.Relay) error {
+		if err := remote.Run(ctx, pushTransport, host, args, relay); err != nil {
+			return fmt.Errorf("rwr failed on %s: %w", host.Label(), err)
+		}
+		return nil
+	}
+
+	var runErr error
+	if tui.Active(app.NoTUI) {
+		runErr = pushWithTUI(app, host, run)
+	} else {
+		runErr = run(&remote.Relay{Host: host.Label()})
+	}
+
+	// A failed run still applied what it got through; its journal says what.
+	if !app.DryRun {
+		if err := remote.PullJournal(ctx, pushTransport, host, remote.JournalPath(app.ConfigLocation, host)); err != nil {
+			log.Warnf("%v", err)
+		}
+	}
+	return runErr
+}
+
+// pushWithTUI draws the dashboard from the plan the host sends before its
+// first event; until then, there is nothing to draw.
+func pushWithTUI(app *AppConfig, host types.InventoryHost, run func(*remote.Relay) error) error {
+	return runDashboard(app, func() (*types.Plan, func() error, error) {
+		plans := make(chan *types.Plan, 1)
+		done := make(chan error, 1)
+		relay := &remote.Relay{Host: host.Label(), OnPlan: func(plan *types.Plan) {
+			select {
+			case plans <- plan:
+			default:
+			}
+		}}
+		go func() { done <- run(relay) }()
+
+		wait := func() error { return <-done }
+		select {
+		case plan := <-plans:
+			plan.Init = &types.InitConfig{Init: types.Init{Location: host.Label()}}
+			return plan, wait, nil
+		case err := <-done:
+			if err == nil {
+				err = errors.New("the host's run ended without reporting a plan")
+			}
+			return nil, nil, err
+		}
+	})
+}
+
+// runPushStatus is `rwr status --host`: status runs on each host, against
+// the tree pushed now and the host's own journal, which is then copied back.
+// Drift or failure on any host fails the command.
+func runPushStatus(app *AppConfig, out io.Writer) error {
+	hosts, err := pushHosts(app)
+	if err != nil {
+		return err
+	}
+	payload, err := newPushPayload(app)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var drifted []string
+	for _, host := range hosts {
+		helpers.Say(out, "== %s ==\n", host.Label())
+		if err := payload.stage(ctx, app, host); err != nil {
+			log.Errorf("%s: %v", host.Label(), err)
+			drifted = append(drifted, host.Label())
+			continue
+		}
+
+		relay := &remote.Relay{Host: host.Label()}
+		err := pushTransport.Run(ctx, host, remote.Command(payload.remoteArgs(app, host, "status")), nil, out, relay.Stderr())
+		relay.Flush()
+		if err != nil {
+			drifted = append(drifted, host.Label())
+		}
+		if err := remote.PullJournal(ctx, pushTransport, host, remote.JournalPath(app.ConfigLocation, host)); err != nil {
+			log.Warnf("%v", err)
+		}
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("drift detected or status failed on %d of %d host(s): %s", len(drifted), len(hosts), strings.Join(drifted, ", "))
+	}
+	return nil
+}
+
+// runReportingEvents is the far end of a push: the run headless, its plan
+// and events framed on stdout for the pushing rwr to relay.
+func runReportingEvents(app *AppConfig) error {
+	events := remote.NewEventWriter(os.Stdout)
+	defer reporting.Set(events)()
+
+	plan, err := processors.ResolveStage1(app.InitConfig)
+	if err != nil {
+		return err
+	}
+	processors.ResolveStage2(plan, app.OSInfo)
+	events.Plan(plan)
+
+	err = runEverythingHeadless(app, nil)
+	var errs []types.StepError
+	if err != nil {
+		errs = []types.StepError{{Processor: "run", Err: err}}
+	}
+	reporting.Emit(reporting.RunFinished{Errs: errs})
+	return err
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A push ships every directory the tree reads, not just the init file's: a
+// blueprint location beside it and a shared file a blueprint imports land
+// in the payload, with the init file where its relative paths expect it. A
+// location written for this machine's disk is refused.
+func TestNewPushPayload_ShipsTheResolvedTree(t *testing.T) {
+	base := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(base, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("repo/configs/init.yaml", "blueprints:\n  format: yaml\n  location: ../blueprints\n")
+	write("repo/blueprints/packages/base.yaml", "packages:\n  - names: [git]\n    action: install\n  - import: ../../../shared/extra.yaml\n")
+	write("shared/extra.yaml", "packages:\n  - names: [ripgrep]\n    action: install\n")
+
+	app := &AppConfig{InitFilePath: filepath.Join(base, "repo", "configs", "init.yaml"), RemoteBinary: os.Args[0]}
+	payload, err := newPushPayload(app)
+	if err != nil {
+		t.Fatalf("newPushPayload: %v", err)
+	}
+	if payload.root != base || payload.init != "repo/configs/init.yaml" {
+		t.Fatalf("payload = %s with --init-file %s, want %s with repo/configs/init.yaml", payload.root, payload.init, base)
+	}
+
+	write("repo/configs/init.yaml", "blueprints:\n  format: yaml\n  location: "+filepath.ToSlash(filepath.Join(base, "repo", "blueprints"))+"\n")
+	if _, err := newPushPayload(app); err == nil || !strings.Contains(err.Error(), "blueprints.location") {
+		t.Fatalf("an absolute location was pushed: %v", err)
+	}
+}
@@ -52,6 +52,9 @@ git checkouts, scripts, and desktop configuration.`,
 			if err := loadConfig(app); err != nil {
 				return fmt.Errorf("configuration error: %w", err)
 			}
+			if app.ReportEvents {
+				log.SetFormatter(log.JSONFormatter)
+			}
 
 			// Dry-run is global flag handling, not system initialization: it
 			// must hold for every command, including the ones that skip init
@@ -61,6 +64,18 @@ git checkouts, scripts, and desktop configuration.`,
 				log.Infof("Dry-run mode enabled - no changes will be made")
 			}
 
+			// The offline cache is where fetch downloads to as well as where
+			// an offline run reads from, so it is set for every command.
+			cacheDir := app.CacheDir
+			if cacheDir == "" {
+				cacheDir = filepath.Join(app.ConfigLocation, "cache")
+			}
+			system.SetCacheDir(cacheDir)
+			if app.Offline {
+				system.SetOffline(true)
+				log.Infof("Offline mode enabled - downloads come from %s", cacheDir)
+			}
+
 			// Skip initialization for these commands
 			skipInit := map[string]bool{
 				"help":     true,
@@ -74,14 +89,31 @@ git checkouts, scripts, and desktop configuration.`,
 				// capture creates a tree from the machine; requiring one
 				// first would be backwards.
 				"capture": true,
+				// lsp validates whichever tree the editor has open.
+				"lsp": true,
+				// schema export describes the format, not a tree.
+				"schema": true,
+				// lint reads the tree it is pointed at, for no machine.
+				"lint": true,
+				"fmt":  true,
+				// the agent initializes afresh every cycle, after pulling.
+				"agent": true,
+				// facts describes the machine, whatever tree is around.
+				"facts": true,
+			}
+
+			// A push initializes nothing here: the tree is resolved, and the
+			// OS detected, on each host.
+			if pushing(app) {
+				return nil
 			}
 
 			// Check if the current command or any of its parents should skip init
 			current := cmd
 			for current != nil {
 				if skipInit[current.Name()] {
-					// validate and capture still need the detected OS
-					if current.Name() == "validate" || current.Name() == "capture" {
+					// validate, capture and lsp still need the detected OS
+					if current.Name() == "validate" || current.Name() == "capture" || current.Name() == "lsp" {
 						if err := system.SetPaths(); err != nil {
 							return fmt.Errorf("error setting paths: %w", err)
 						}
@@ -141,11 +173,20 @@ git checkouts, scripts, and desktop configuration.`,
 	rootCmd.AddCommand(newValidateCmd(app))
 	rootCmd.AddCommand(newVersionCmd(app))
 	rootCmd.AddCommand(newProfilesCmd(app))
+	rootCmd.AddCommand(newFactsCmd(app))
 	rootCmd.AddCommand(newConvertCmd())
 	rootCmd.AddCommand(newStatusCmd(app))
 	rootCmd.AddCommand(newUninstallCmd(app))
+	rootCmd.AddCommand(newUpgradeCmd(app))
+	rootCmd.AddCommand(newPruneCmd(app))
+	rootCmd.AddCommand(newFetchCmd(app))
 	rootCmd.AddCommand(newDiffCmd(app))
 	rootCmd.AddCommand(newCaptureCmd(app))
+	rootCmd.AddCommand(newLspCmd(app))
+	rootCmd.AddCommand(newSchemaCmd())
+	rootCmd.AddCommand(newLintCmd())
+	rootCmd.AddCommand(newFmtCmd())
+	rootCmd.AddCommand(newAgentCmd(app))
 
 	return rootCmd
 }
@@ -163,6 +204,10 @@ func registerRootFlags(rootCmd *cobra.Command, app *AppConfig) {
 	flags.BoolVarP(&app.DryRun, "dry-run", "n", false, "Log operations without executing (no-op mode)")
 	flags.BoolVar(&app.DryRun, "no-op", false, "Alias for --dry-run")
 
+	flags.BoolVar(&app.Prune, "prune", false, "After the packages processor, remove packages no blueprint declares (see rwr prune)")
+	flags.BoolVar(&app.Offline, "offline", false, "Install from the offline cache rwr fetch filled, without the network")
+	flags.StringVar(&app.CacheDir, "cache-dir", "", "Offline cache directory (default <config>/cache)")
+
 	flags.BoolVarP(&app.Interactive, "interactive", "I", true, "Enable interactive mode (use --interactive=false to disable; per-item prompts, not -i)")
 
 	// Flag for the init file path. Bound to repository.init-file - the key the
@@ -207,6 +252,10 @@ func registerRootFlags(rootCmd *cobra.Command, app *AppConfig) {
 	flags.BoolVar(&app.NoNotify, "no-notify", false, "Disable the completion notification")
 	flags.IntVar(&app.TUIBuffer, "tui-buffer", 50000, "Dashboard log buffer size in lines")
 	flags.StringVar(&app.LogFile, "log-file", "", "Write the run log to this path (default: a temp file)")
+	flags.BoolVar(&app.ReportEvents, "report-events", false, "Stream run events and JSON logs for a pushing rwr to relay")
+	if err := flags.MarkHidden("report-events"); err != nil {
+		log.Fatalf("hiding --report-events: %v", err)
+	}
 
 	// Secrets are redacted in logs by default. This exists because "is rwr even
 	// reading my token?" has no other answer, but it has to be asked for.
@@ -268,6 +317,7 @@ func initializeSystemInfo(app *AppConfig, syncBlueprints bool, selectedProcessor
 		ConfigLocation:   app.ConfigLocation,
 		RunOnceLocation:  app.RunOnceLocation,
 		Profiles:         app.Profiles,
+		Prune:            app.Prune,
 	}
 
 	types.SetShowSecrets(app.ShowSecrets)
@@ -301,6 +351,23 @@ func initializeSystemInfo(app *AppConfig, syncBlueprints bool, selectedProcessor
 	// else branch - a users blueprint chose the Intel homebrew path for the
 	// login shell on an Apple Silicon machine and broke every new terminal.
 	app.InitConfig.Variables.System = app.OSInfo.System
+
+	// Facts are gathered once, here, so every stage of the run - templates,
+	// `when:` conditions, the plan - sees the same machine.
+	facts := system.GatherFacts(filepath.Join(app.ConfigLocation, system.FactsDirName))
+	app.InitConfig.Variables.Facts = &facts
+
+	// Profiles are settled last: host_profiles rules match on the facts.
+	requested := app.Profiles
+	if len(requested) == 0 {
+		requested = viper.GetStringSlice("rwr.profiles")
+	}
+	profiles, activations, err := helpers.ActivateProfiles(requested, app.InitConfig)
+	if err != nil {
+		return fmt.Errorf("error activating profiles: %w", err)
+	}
+	app.InitConfig.Variables.Flags.Profiles = profiles
+	app.InitConfig.ActiveProfiles = activations
 	return nil
 }
 
@@ -447,6 +514,10 @@ func loadConfig(app *AppConfig) error {
 
 	app.GHAPIToken = viper.GetString("repository.gh_api_token")
 	app.SSHKey = viper.GetString("repository.ssh_private_key")
+	// Decoded now: initialization reads the init file into viper over these.
+	if app.Sinks, err = types.DecodeSinks(viper.Get("hooks"), viper.Get("webhooks"), viper.Get("metrics")); err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
 	return nil
 }
 
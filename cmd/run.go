@@ -55,6 +55,12 @@ run it - "rwr run packages" - or use the shorthand straight off the root:
 // it runs under the dashboard; everywhere else the output is byte-identical
 // to the pre-TUI stream.
 func runEverything(app *AppConfig) error {
+	if pushing(app) {
+		return runPush(app)
+	}
+	if app.ReportEvents {
+		return runReportingEvents(app)
+	}
 	if tui.Active(app.NoTUI) {
 		return runWithTUI(app, nil)
 	}
@@ -90,7 +96,9 @@ func runOneProcessor(app *AppConfig, p runProcessorSpec) error {
 	if tui.Active(app.NoTUI) {
 		return runWithTUI(app, []string{p.blueprint})
 	}
-	return processors.All(app.InitConfig, app.OSInfo, []string{p.blueprint})
+	return observeRun(app, func() error {
+		return processors.All(app.InitConfig, app.OSInfo, []string{p.blueprint})
+	})
 }
 
 // selectedProcessorsFor maps the invoked command to the blueprint types this
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/blueprintschema"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// newSchemaCmd is wiring; generation lives in internal/blueprintschema.
+func newSchemaCmd() *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Work with the blueprint schemas",
+	}
+	schemaCmd.AddCommand(newSchemaExportCmd())
+	return schemaCmd
+}
+
+func newSchemaExportCmd() *cobra.Command {
+	var (
+		format        string
+		blueprintType string
+		version       int
+	)
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the blueprint schema as JSON Schema or CUE",
+		Long: `Print a schema for blueprint files, generated from the structs this build
+decodes them into: JSON Schema for yaml-language-server (YAML, JSON) and taplo
+(TOML), or CUE definitions. Actions are constrained to what each entry type
+accepts and package_manager to the loaded provider definitions - a custom
+provider added later needs a fresh export.
+
+Without --type the schema covers every blueprint type, and accepts a file that
+holds several of them. Without --version each type is at its latest version.
+
+Examples:
+  rwr schema export > rwr.schema.json
+  rwr schema export --type packages --version 1
+  rwr schema export --format cue > rwr_schema.cue`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registered := types.RegisteredBlueprintTypes()
+			selected := registered
+			if blueprintType != "" {
+				if !helpers.Contains(registered, blueprintType) {
+					return fmt.Errorf("unknown blueprint type %q (known: %s)", blueprintType, strings.Join(registered, ", "))
+				}
+				selected = []string{blueprintType}
+			}
+
+			var blueprints []blueprintschema.Blueprint
+			for _, name := range selected {
+				v := version
+				if v == 0 {
+					v = types.LatestSchemaVersion(name)
+				} else if err := types.ValidateSchemaVersion(name, v); err != nil {
+					return err
+				}
+				blueprints = append(blueprints, blueprintschema.Blueprint{Type: name, Version: v})
+			}
+
+			providers := system.ProviderNames()
+			switch format {
+			case "jsonschema":
+				out, err := blueprintschema.JSONSchema(blueprints, providers)
+				if err != nil {
+					return err
+				}
+				helpers.Say(cmd.OutOrStdout(), "%s\n", out)
+			case types.FormatCUE:
+				out, err := blueprintschema.CUE(blueprints, providers)
+				if err != nil {
+					return err
+				}
+				helpers.Say(cmd.OutOrStdout(), "%s", out)
+			default:
+				return fmt.Errorf("unknown schema format %q: use jsonschema or cue", format)
+			}
+			return nil
+		},
+	}
+
+	exportCmd.Flags().StringVar(&format, "format", "jsonschema", "Schema format: jsonschema or cue")
+	exportCmd.Flags().StringVar(&blueprintType, "type", "", "Only this blueprint type (packages, files, ...)")
+	exportCmd.Flags().IntVar(&version, "version", 0, "Schema version to export (default: the latest per type)")
+	return exportCmd
+}
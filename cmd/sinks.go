@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/sinks"
+	"github.com/fynxlabs/rwr/internal/system"
+)
+
+// observeRun runs one apply with config.yaml's sinks watching its events
+// next to whatever reporter is displaying them, then hands them the outcome.
+func observeRun(app *AppConfig, run func() error) error {
+	sink := sinks.Start(app.Sinks, system.IsDryRun())
+	if sink == nil {
+		return run()
+	}
+	remove := reporting.Observe(sink)
+	err := run()
+	remove()
+	sink.Finish(err)
+	return err
+}
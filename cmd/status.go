@@ -13,14 +13,21 @@ import (
 
 // newStatusCmd is wiring; the drift computation lives in internal/status.
 func newStatusCmd(app *AppConfig) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show desired-vs-actual drift without applying anything",
 		Long: `Compare the blueprint tree against the machine: what is in sync, missing,
 modified since the recorded apply, unknown (not queryable), or stale
 (recorded by a past run but no longer in the tree). Read-only: status never
-mutates and never elevates. Exits 1 on drift.`,
+mutates and never elevates. Exits 1 on drift.
+
+With --host or --inventory, status runs on each host against the tree as it
+is now and the host's own journal, whose copy lands under hosts/<name>/ in
+the config directory.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if pushing(app) {
+				return runPushStatus(app, cmd.OutOrStdout())
+			}
 			plan, err := processors.ResolveStage1(app.InitConfig)
 			if err != nil {
 				return err
@@ -38,4 +45,6 @@ mutates and never elevates. Exits 1 on drift.`,
 			return nil
 		},
 	}
+	addPushFlags(cmd, app)
+	return cmd
 }
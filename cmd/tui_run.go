@@ -18,6 +18,25 @@ import (
 // runWithTUI executes the whole-tree run under the dashboard. The run itself
 // is unchanged - All() emits events; the TUI is one consumer of them.
 func runWithTUI(app *AppConfig, order []string) error {
+	return runDashboard(app, func() (*types.Plan, func() error, error) {
+		plan, err := processors.ResolveStage1(app.InitConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		processors.ResolveStage2(plan, app.OSInfo)
+		if order == nil {
+			order = plan.Order
+		} else {
+			plan.Order = order
+		}
+		return plan, func() error { return runEverythingHeadless(app, order) }, nil
+	})
+}
+
+// runDashboard runs under the dashboard whatever prepare returns: the plan
+// the dashboard is drawn from, and the run that emits its events. prepare
+// is called once the log is captured, so what it logs lands in the store.
+func runDashboard(app *AppConfig, prepare func() (*types.Plan, func() error, error)) error {
 	store := reporting.NewStore(app.TUIBuffer)
 	runLogPath := app.LogFile
 	if runLogPath == "" {
@@ -57,16 +76,10 @@ func runWithTUI(app *AppConfig, order []string) error {
 		log.SetLevel(prevLevel)
 	}()
 
-	plan, err := processors.ResolveStage1(app.InitConfig)
+	plan, run, err := prepare()
 	if err != nil {
 		return err
 	}
-	processors.ResolveStage2(plan, app.OSInfo)
-	if order == nil {
-		order = plan.Order
-	} else {
-		plan.Order = order
-	}
 
 	theme, unknownTheme := tui.ResolveTheme(app.ConfigLocation, app.Theme, viper.GetString("rwr.theme"), app.ASCII, app.Unicode)
 	if unknownTheme != "" {
@@ -86,7 +99,7 @@ func runWithTUI(app *AppConfig, order []string) error {
 
 	runErr := make(chan error, 1)
 	go func() {
-		err := runEverythingHeadless(app, order)
+		err := run()
 		// The run's own error has to reach the summary: All() only emits
 		// RunFinished when it gets to the end, so a run that dies early
 		// (package managers, bootstrap, an interactive-halt processor error)
@@ -128,7 +141,10 @@ func runEverythingHeadless(app *AppConfig, order []string) error {
 		return err
 	}
 	log.Debugf("ForceBootstrap: %v", app.InitConfig.Variables.Flags.ForceBootstrap)
-	if err := processors.All(app.InitConfig, app.OSInfo, order); err != nil {
+	err := observeRun(app, func() error {
+		return processors.All(app.InitConfig, app.OSInfo, order)
+	})
+	if err != nil {
 		return fmt.Errorf("error running all processors: %w", err)
 	}
 	return nil
@@ -24,11 +24,12 @@ func newUninstallCmd(app *AppConfig) *cobra.Command {
 		Use:   "uninstall",
 		Short: "Reverse what recorded runs applied - and only that",
 		Long: `Remove what the run journal shows was applied: packages via the provider's
-remove verb, files and git checkouts hash-guarded (modified content is
+remove verb (held packages unheld), files and git checkouts hash-guarded (modified content is
 skipped and listed), services disabled, fonts deleted from their recorded
-directory. Input is the record, never the blueprint tree; with no record the
-command refuses. What cannot be reversed (scripts, configuration writes,
-users, uploaded SSH keys, repositories) is listed up front.`,
+directory, kde/xfconf/ini settings restored to the value they replaced.
+Input is the record, never the blueprint tree; with no record the command
+refuses. What cannot be reversed (scripts, other configuration writes, users,
+uploaded SSH keys, repositories) is listed up front.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			configDir := viper.GetString("rwr.configdir")
 			entries, err := state.Unreversed(configDir)
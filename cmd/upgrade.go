@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/tui"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// newUpgradeCmd is wiring; the upgrade pass lives in internal/processors.
+func newUpgradeCmd(app *AppConfig) *cobra.Command {
+	return &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade everything every available package manager manages",
+		Long: `Run every available package manager's update in one pass: apt update and
+apt upgrade, brew update and brew upgrade, pacman -Syu, flatpak update, and
+so on, one provider after another. A provider that fails is reported and the
+rest still run; the command fails if any did.
+
+Packages held with the packages blueprint's hold action stay where they are:
+the hold lives in the package manager, which every upgrade honours. Nothing
+here is recorded in the run journal - an upgrade leaves nothing to uninstall.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run := func() error {
+				return observeRun(app, func() error { return processors.Upgrade(app.InitConfig) })
+			}
+			if tui.Active(app.NoTUI) {
+				return runDashboard(app, func() (*types.Plan, func() error, error) {
+					return processors.UpgradePlan(app.InitConfig), run, nil
+				})
+			}
+			return run()
+		},
+	}
+}
@@ -16,6 +16,8 @@ func newValidateCmd(app *AppConfig) *cobra.Command {
 		validateBlueprints bool
 		validateProviders  bool
 		validateVerbose    bool
+		validateTargets    []string
+		validateMatrix     bool
 	)
 
 	validateCmd := &cobra.Command{
@@ -36,7 +38,13 @@ Examples:
   rwr validate path/to/dir --blueprints
 
   # Force validation as providers
-  rwr validate path/to/file --providers`,
+  rwr validate path/to/file --providers
+
+  # Validate as if on other machines, whatever this one is
+  rwr validate . --target darwin --target linux/ubuntu/amd64
+
+  # Validate every configuration of a manifest repo for the machine it matches
+  rwr validate . --matrix`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get path from args or use current directory
@@ -80,12 +88,23 @@ Examples:
 				}
 			}
 
+			var targets []types.Target
+			for _, spec := range validateTargets {
+				target, err := types.ParseTarget(spec)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, target)
+			}
+
 			// Set up validation options
 			options := types.ValidationOptions{
 				Path:               absPath,
 				ValidateBlueprints: validateBlueprints,
 				ValidateProviders:  validateProviders,
 				Verbose:            validateVerbose,
+				Targets:            targets,
+				Matrix:             validateMatrix,
 			}
 
 			// Run validation
@@ -94,6 +113,10 @@ Examples:
 				return fmt.Errorf("error during validation: %w", err)
 			}
 
+			if len(targets) > 0 || validateMatrix {
+				printTargetSummary(results)
+			}
+
 			// Display results
 			if results.ErrorCount > 0 {
 				return fmt.Errorf("validation failed with %d errors and %d warnings", results.ErrorCount, results.WarningCount)
@@ -109,6 +132,25 @@ Examples:
 	validateCmd.Flags().BoolVar(&validateBlueprints, "blueprints", false, "Force validation as blueprint files")
 	validateCmd.Flags().BoolVar(&validateProviders, "providers", false, "Force validation as provider configurations")
 	validateCmd.Flags().BoolVar(&validateVerbose, "verbose", false, "Show detailed validation information")
+	validateCmd.Flags().StringArrayVar(&validateTargets, "target", nil, "Validate blueprints for an os[/distro[/arch]] instead of this machine (repeatable)")
+	validateCmd.Flags().BoolVar(&validateMatrix, "matrix", false, "Validate each manifest configuration for the machine its matchers describe")
 
 	return validateCmd
 }
+
+// printTargetSummary prints the error and warning counts of each target, in
+// the order they were validated.
+func printTargetSummary(results *types.ValidationResults) {
+	errors, warnings := map[string]int{}, map[string]int{}
+	for _, issue := range results.Issues {
+		switch issue.Severity {
+		case types.ValidationError:
+			errors[issue.Target]++
+		case types.ValidationWarning:
+			warnings[issue.Target]++
+		}
+	}
+	for _, target := range results.Targets {
+		fmt.Printf("%s: %d errors, %d warnings\n", target, errors[target], warnings[target])
+	}
+}
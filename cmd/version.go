@@ -153,6 +153,10 @@ func checkForNewVersion(app *AppConfig) {
 		log.Debugf("Version check skipped for dev build")
 		return
 	}
+	if system.IsOffline() {
+		log.Debugf("Version check skipped offline")
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -0,0 +1,228 @@
+// Package agent is the pull side of rwr: the state and decisions behind
+// `rwr agent`, which periodically pulls the blueprint repository, resolves
+// the tree, and applies it when the tree or the machine's drift changed since
+// the last apply - inside a maintenance window, if one is set.
+//
+// The loop itself lives in cmd, next to the initialization it repeats; this
+// package holds what the loop decides with and what it leaves behind: the
+// last cycle's Status, on disk and on a local unix socket.
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fynxlabs/rwr/internal/status"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/go-git/go-git/v5"
+)
+
+// Outcome is what one cycle did.
+type Outcome string
+
+const (
+	// Applied: the tree was applied without failures.
+	Applied Outcome = "applied"
+	// Failed: the tree was applied and some of it failed.
+	Failed Outcome = "failed"
+	// Unchanged: neither the tree nor the drift changed; nothing ran.
+	Unchanged Outcome = "unchanged"
+	// Deferred: an apply is due, but the maintenance window is closed.
+	Deferred Outcome = "deferred"
+	// Errored: the tree could not be resolved; nothing ran.
+	Errored Outcome = "error"
+)
+
+// Status is one cycle, as `rwr agent status` shows it. The Applied* fields
+// carry over from cycle to cycle: they are what the next cycle compares
+// against.
+type Status struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitzero"`
+	Outcome  Outcome   `json:"outcome,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// PullError is a failed pull. The cycle goes on with the tree on disk.
+	PullError string `json:"pull_error,omitempty"`
+	// Revision is the blueprint repository's HEAD, when the tree is in one.
+	Revision string `json:"revision,omitempty"`
+	// Tree fingerprints the resolved tree; see Fingerprint.
+	Tree string `json:"tree,omitempty"`
+	// Drift is the drifted status rows, one "processor name: class" each.
+	Drift []string `json:"drift,omitempty"`
+	// Next is when the following cycle is due; zero for a one-shot cycle.
+	Next time.Time `json:"next,omitzero"`
+
+	// AppliedTree is the tree the last successful apply ran, at AppliedAt;
+	// AppliedDrift is the drift it left.
+	AppliedTree  string    `json:"applied_tree,omitempty"`
+	AppliedDrift []string  `json:"applied_drift,omitempty"`
+	AppliedAt    time.Time `json:"applied_at,omitzero"`
+}
+
+// Carry starts a cycle's status from the previous one's.
+func (s Status) Carry(started time.Time) Status {
+	return Status{
+		Started:      started,
+		AppliedTree:  s.AppliedTree,
+		AppliedDrift: s.AppliedDrift,
+		AppliedAt:    s.AppliedAt,
+	}
+}
+
+// Decide reports whether the tree is due to be applied, and why. It applies
+// when the tree changed since the last successful apply, or when the drift
+// differs from what that apply left behind - drift an apply cannot fix (a
+// package the provider no longer has) is not retried every cycle, only when
+// it changes.
+func Decide(prev Status, tree string, drift []string) (bool, string) {
+	switch {
+	case prev.AppliedTree == "":
+		return true, "no previous apply"
+	case tree != prev.AppliedTree:
+		return true, "the tree changed"
+	case len(drift) > 0 && !slices.Equal(drift, prev.AppliedDrift):
+		return true, fmt.Sprintf("drift changed (%d item(s))", len(drift))
+	}
+	return false, "no change in the tree or drift"
+}
+
+// Fingerprint hashes what a run applies: the init config as decoded, and
+// every resolved blueprint file, after templating, in run order. Changing a
+// comment in a blueprint changes it; so does a variable a template reads.
+func Fingerprint(plan *types.Plan) string {
+	h := sha256.New()
+	writeField := func(b []byte) {
+		_, _ = fmt.Fprintf(h, "%d:", len(b))
+		_, _ = h.Write(b)
+	}
+	// Machine facts and flags are not part of the encoding: the same tree
+	// fingerprints the same under --debug.
+	initConfig, _ := json.Marshal(plan.Init)
+	writeField(initConfig)
+	for _, processor := range plan.Order {
+		for _, file := range plan.Files[processor] {
+			writeField([]byte(processor))
+			writeField([]byte(file.Path))
+			writeField(file.Resolved)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// DriftSignature lists the drifted rows, sorted, one "processor name: class"
+// each.
+func DriftSignature(rows []status.Row) []string {
+	var drift []string
+	for _, row := range rows {
+		if status.Drifted([]status.Row{row}) {
+			drift = append(drift, fmt.Sprintf("%s %s: %s", row.Processor, row.Name, row.Class))
+		}
+	}
+	sort.Strings(drift)
+	return drift
+}
+
+// StatusPath is where the last cycle's status is kept, under a config
+// directory.
+func StatusPath(configDir string) string {
+	return filepath.Join(configDir, "state", "agent.json")
+}
+
+// SocketPath is the agent's status socket under a config directory.
+func SocketPath(configDir string) string {
+	return filepath.Join(configDir, "agent.sock")
+}
+
+// LoadStatus reads the status file. No file is a zero Status: the agent has
+// never run.
+func LoadStatus(path string) (Status, error) {
+	var s Status
+	data, err := os.ReadFile(path) // #nosec G304 -- rwr's own state file
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("decoding agent status %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// SaveStatus writes the status file, replacing it whole.
+func SaveStatus(path string, s Status) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Render formats a status for `rwr agent status`.
+func Render(s Status) string {
+	if s.Started.IsZero() {
+		return "The agent has not run yet.\n"
+	}
+	var b strings.Builder
+	row := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%-13s %s\n", label+":", value)
+		}
+	}
+	stamp := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Local().Format("2006-01-02 15:04:05")
+	}
+	row("Last cycle", stamp(s.Started))
+	row("Outcome", string(s.Outcome))
+	row("Reason", s.Reason)
+	row("Error", s.Error)
+	row("Pull error", s.PullError)
+	row("Revision", s.Revision)
+	row("Tree", s.Tree)
+	row("Last apply", stamp(s.AppliedAt))
+	row("Next cycle", stamp(s.Next))
+	if len(s.Drift) > 0 {
+		fmt.Fprintf(&b, "Drift:\n")
+		for _, item := range s.Drift {
+			fmt.Fprintf(&b, "  %s\n", item)
+		}
+	}
+	return b.String()
+}
+
+// Repository finds the git work tree holding path, and its HEAD revision.
+// ok is false when path is not in a repository.
+func Repository(path string) (root, revision string, ok bool) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", "", false
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", "", false
+	}
+	if head, err := repo.Head(); err == nil {
+		revision = head.Hash().String()[:12]
+	}
+	return worktree.Filesystem.Root(), revision, true
+}
@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fynxlabs/rwr/internal/status"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+func TestDecide(t *testing.T) {
+	prev := Status{AppliedTree: "t1", AppliedDrift: []string{"packages jq: missing"}}
+	cases := []struct {
+		name  string
+		prev  Status
+		tree  string
+		drift []string
+		want  bool
+	}{
+		{"first cycle", Status{}, "t1", nil, true},
+		{"tree changed", prev, "t2", nil, true},
+		{"nothing changed", Status{AppliedTree: "t1"}, "t1", nil, false},
+		{"drift the apply could not fix", prev, "t1", []string{"packages jq: missing"}, false},
+		{"new drift", prev, "t1", []string{"files out.txt: modified"}, true},
+		{"drift resolved on its own", prev, "t1", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, reason := Decide(tc.prev, tc.tree, tc.drift)
+			if got != tc.want {
+				t.Errorf("Decide = %v (%s), want %v", got, reason, tc.want)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	plan := func(content string) *types.Plan {
+		return &types.Plan{
+			Init:  &types.InitConfig{Init: types.Init{Format: "yaml"}},
+			Order: []string{"packages"},
+			Files: map[string][]types.ResolvedFile{"packages": {{Path: "packages.yaml", Resolved: []byte(content)}}},
+		}
+	}
+	a, b := Fingerprint(plan("packages: [jq]")), Fingerprint(plan("packages: [jq]"))
+	if a != b {
+		t.Errorf("the same tree fingerprints differently: %s, %s", a, b)
+	}
+	if c := Fingerprint(plan("packages: [jq, fd]")); c == a {
+		t.Error("a changed blueprint did not change the fingerprint")
+	}
+
+	// Machine facts are not the tree.
+	withFacts := plan("packages: [jq]")
+	withFacts.Init.Variables.System.OS = "linux"
+	if Fingerprint(withFacts) != a {
+		t.Error("system variables changed the fingerprint")
+	}
+}
+
+func TestDriftSignature(t *testing.T) {
+	got := DriftSignature([]status.Row{
+		{Processor: "packages", Name: "jq", Class: status.Missing},
+		{Processor: "files", Name: "a", Class: status.InSync},
+		{Processor: "files", Name: "b", Class: status.UnknownItem},
+		{Processor: "files", Name: "c", Class: status.ModifiedItem},
+	})
+	want := []string{"files c: modified", "packages jq: missing"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("DriftSignature = %q, want %q", got, want)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", "2026-03-01 "+clock, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	day, err := ParseWindow("02:00-05:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	night, err := ParseWindow("22:00-04:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		window *Window
+		clock  string
+		open   bool
+	}{
+		{day, "01:59", false}, {day, "02:00", true}, {day, "04:59", true}, {day, "05:00", false},
+		{night, "21:00", false}, {night, "23:30", true}, {night, "03:00", true}, {night, "04:00", false},
+		{nil, "12:00", true},
+	} {
+		if got := tc.window.Open(at(tc.clock)); got != tc.open {
+			t.Errorf("%s open at %s = %v, want %v", tc.window, tc.clock, got, tc.open)
+		}
+	}
+
+	if got := day.NextOpen(at("06:00")); !got.Equal(at("02:00").AddDate(0, 0, 1)) {
+		t.Errorf("NextOpen after the window = %s, want tomorrow 02:00", got)
+	}
+	if got := day.NextOpen(at("01:00")); !got.Equal(at("02:00")) {
+		t.Errorf("NextOpen before the window = %s, want today 02:00", got)
+	}
+
+	for _, bad := range []string{"02:00", "2am-5am", "02:00-02:00", "25:00-01:00"} {
+		if _, err := ParseWindow(bad); err == nil {
+			t.Errorf("ParseWindow(%q) accepted", bad)
+		}
+	}
+}
+
+func TestStatusFile(t *testing.T) {
+	path := StatusPath(t.TempDir())
+	if s, err := LoadStatus(path); err != nil || !s.Started.IsZero() {
+		t.Fatalf("LoadStatus with no file = %+v, %v", s, err)
+	}
+	want := Status{Started: time.Now().Round(0), Outcome: Applied, Tree: "abc", AppliedDrift: []string{"x"}}
+	if err := SaveStatus(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadStatus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Started.Equal(want.Started) || got.Outcome != Applied || got.Tree != "abc" || len(got.AppliedDrift) != 1 {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestServeAndQuery(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unix socket paths are short-limited.
+	if len(dir) > 80 {
+		t.Skipf("temp dir %s is too long for a unix socket path", dir)
+	}
+	path := filepath.Join(dir, "agent.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, path, func() Status { return Status{Outcome: Deferred, Reason: "window"} })
+	}()
+
+	var got Status
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if got, err = Query(path); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Outcome != Deferred || got.Reason != "window" {
+		t.Errorf("Query = %+v", got)
+	}
+
+	if err := Serve(context.Background(), path, func() Status { return Status{} }); err == nil {
+		t.Error("a second agent served on a live socket")
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("Serve after cancel: %v", err)
+	}
+}
+
+func TestSystemdUnits(t *testing.T) {
+	service, timer := systemdUnits(Schedule{
+		Args:     []string{"/usr/local/bin/rwr", "agent", "--once", "--init-file", "/home/me/my tree/init.yaml", "--window", "02:00-05:00"},
+		Interval: time.Hour,
+		Jitter:   5 * time.Minute,
+	})
+	if !strings.Contains(service, `ExecStart=/usr/local/bin/rwr agent --once --init-file "/home/me/my tree/init.yaml" --window 02:00-05:00`) {
+		t.Errorf("service:\n%s", service)
+	}
+	for _, want := range []string{"OnUnitInactiveSec=3600s", "RandomizedDelaySec=300s", "WantedBy=timers.target"} {
+		if !strings.Contains(timer, want) {
+			t.Errorf("timer lacks %q:\n%s", want, timer)
+		}
+	}
+	if got := systemdQuote("100%"); got != "100%%" {
+		t.Errorf("systemdQuote(100%%) = %q", got)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := launchdPlist(Schedule{
+		Args:     []string{"/opt/rwr", "agent", "--once", "--init-file", "a&b.yaml"},
+		Interval: 30 * time.Minute,
+		Log:      "/Users/me/.config/rwr/agent.log",
+	})
+	for _, want := range []string{
+		"<string>" + launchdLabel + "</string>",
+		"<string>a&amp;b.yaml</string>",
+		"<key>StartInterval</key>\n\t<integer>1800</integer>",
+		"<key>StandardErrorPath</key>\n\t<string>/Users/me/.config/rwr/agent.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist lacks %q:\n%s", want, plist)
+		}
+	}
+}
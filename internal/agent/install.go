@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// unitName names the systemd units and, reversed-DNS, the launchd agent.
+const (
+	unitName     = "rwr-agent"
+	launchdLabel = "com.fynxlabs.rwr.agent"
+)
+
+// Schedule is how an installed agent runs: Args (the rwr binary first) for
+// one cycle, every Interval, spread by up to Jitter.
+type Schedule struct {
+	Args     []string
+	Interval time.Duration
+	Jitter   time.Duration
+	// Log is where launchd sends the agent's output; systemd keeps it in the
+	// user journal.
+	Log string
+}
+
+// Unit is one file an install writes.
+type Unit struct {
+	Path    string
+	Content string
+}
+
+// Units renders the per-user scheduler files for this OS: a systemd service
+// and timer on Linux, a launchd agent on macOS.
+func Units(goos string, s Schedule) ([]Unit, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	switch goos {
+	case "linux":
+		dir := filepath.Join(home, ".config", "systemd", "user")
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			dir = filepath.Join(xdg, "systemd", "user")
+		}
+		service, timer := systemdUnits(s)
+		return []Unit{
+			{Path: filepath.Join(dir, unitName+".service"), Content: service},
+			{Path: filepath.Join(dir, unitName+".timer"), Content: timer},
+		}, nil
+	case "darwin":
+		return []Unit{{
+			Path:    filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist"),
+			Content: launchdPlist(s),
+		}}, nil
+	}
+	return nil, fmt.Errorf("installing the agent is supported on Linux (systemd) and macOS (launchd), not %s", goos)
+}
+
+// systemdUnits is a oneshot service running one cycle, and the timer that
+// starts it. The timer does the jitter: RandomizedDelaySec spreads a fleet
+// the way --jitter does for a long-running agent.
+func systemdUnits(s Schedule) (service, timer string) {
+	quoted := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		quoted[i] = systemdQuote(arg)
+	}
+	service = `[Unit]
+Description=rwr agent: converge this machine to its blueprint tree
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=` + strings.Join(quoted, " ") + `
+`
+	timer = fmt.Sprintf(`[Unit]
+Description=Run the rwr agent every %s
+
+[Timer]
+OnBootSec=5min
+OnUnitInactiveSec=%ds
+RandomizedDelaySec=%ds
+
+[Install]
+WantedBy=timers.target
+`, s.Interval, int(s.Interval.Seconds()), int(s.Jitter.Seconds()))
+	return service, timer
+}
+
+// systemdQuote quotes an ExecStart word; systemd expands % and $ itself.
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	s = strings.ReplaceAll(s, "$", "$$")
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// launchdPlist is a launch agent running one cycle every Interval. launchd
+// has no jitter of its own; Args carries --jitter for the cycle to sleep.
+func launchdPlist(s Schedule) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + launchdLabel + `</string>
+	<key>ProgramArguments</key>
+	<array>
+`)
+	for _, arg := range s.Args {
+		b.WriteString("\t\t<string>" + html.EscapeString(arg) + "</string>\n")
+	}
+	fmt.Fprintf(&b, `	</array>
+	<key>StartInterval</key>
+	<integer>%d</integer>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>ProcessType</key>
+	<string>Background</string>
+`, int(s.Interval.Seconds()))
+	if s.Log != "" {
+		fmt.Fprintf(&b, `	<key>StandardOutPath</key>
+	<string>%[1]s</string>
+	<key>StandardErrorPath</key>
+	<string>%[1]s</string>
+`, html.EscapeString(s.Log))
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// Install writes the scheduler files and starts the schedule.
+func Install(s Schedule, debug bool) error {
+	units, err := Units(runtime.GOOS, s)
+	if err != nil {
+		return err
+	}
+	for _, unit := range units {
+		if system.IsDryRun() {
+			log.Infof("[DRY-RUN] Would write %s", unit.Path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(unit.Path), 0o755); err != nil { // #nosec G301 -- the user's own unit directory
+			return err
+		}
+		if err := os.WriteFile(unit.Path, []byte(unit.Content), 0o644); err != nil { // #nosec G306 -- unit files are not secret
+			return err
+		}
+		log.Infof("Wrote %s", unit.Path)
+	}
+
+	if runtime.GOOS == "darwin" {
+		// Reloading picks up a changed plist; the first install has nothing
+		// to unload.
+		_ = system.RunCommand(types.Command{Exec: "launchctl", Args: []string{"unload", units[0].Path}}, debug)
+		return system.RunCommand(types.Command{Exec: "launchctl", Args: []string{"load", "-w", units[0].Path}}, debug)
+	}
+	if err := system.RunCommand(types.Command{Exec: "systemctl", Args: []string{"--user", "daemon-reload"}}, debug); err != nil {
+		return err
+	}
+	return system.RunCommand(types.Command{Exec: "systemctl", Args: []string{"--user", "enable", "--now", unitName + ".timer"}}, debug)
+}
+
+// Uninstall stops the schedule and removes its files.
+func Uninstall(debug bool) error {
+	units, err := Units(runtime.GOOS, Schedule{})
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "darwin" {
+		if err := system.RunCommand(types.Command{Exec: "launchctl", Args: []string{"unload", "-w", units[0].Path}}, debug); err != nil {
+			log.Debugf("unloading %s: %v", launchdLabel, err)
+		}
+	} else if err := system.RunCommand(types.Command{Exec: "systemctl", Args: []string{"--user", "disable", "--now", unitName + ".timer"}}, debug); err != nil {
+		log.Debugf("disabling %s.timer: %v", unitName, err)
+	}
+
+	for _, unit := range units {
+		if system.IsDryRun() {
+			log.Infof("[DRY-RUN] Would remove %s", unit.Path)
+			continue
+		}
+		if err := os.Remove(unit.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if runtime.GOOS == "linux" {
+		return system.RunCommand(types.Command{Exec: "systemctl", Args: []string{"--user", "daemon-reload"}}, debug)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"charm.land/log/v2"
+)
+
+// Serve answers every connection to the unix socket at path with the
+// current status as JSON, then closes it, until ctx is done. A socket file
+// left by an agent that died is replaced; one another agent is answering on
+// is an error.
+func Serve(ctx context.Context, path string, current func() Status) error {
+	if _, err := Query(path); err == nil {
+		return fmt.Errorf("an agent is already running on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing stale socket %s: %w", path, err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", path, err)
+	}
+	// The status names the tree and its drift; it is the user's alone.
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = listener.Close()
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := json.NewEncoder(conn).Encode(current()); err != nil {
+			log.Debugf("answering a status query: %v", err)
+		}
+		_ = conn.Close()
+	}
+}
+
+// Query reads the status from a running agent's socket.
+func Query(path string) (Status, error) {
+	var s Status
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		return s, err
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := json.NewDecoder(conn).Decode(&s); err != nil {
+		return s, fmt.Errorf("reading agent status from %s: %w", path, err)
+	}
+	return s, nil
+}
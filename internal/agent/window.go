@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily maintenance window in local time, as minutes since
+// midnight. End before Start wraps midnight: 22:00-04:00.
+type Window struct {
+	Start, End int
+}
+
+// ParseWindow parses "HH:MM-HH:MM". Empty is no window: always open.
+func ParseWindow(s string) (*Window, error) {
+	if s == "" {
+		return nil, nil
+	}
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("maintenance window %q: want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window %q: %w", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window %q: %w", s, err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("maintenance window %q is empty", s)
+	}
+	return &Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Open reports whether t falls inside the window. A nil window is always
+// open.
+func (w *Window) Open(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// NextOpen is the next time at or after t that the window opens; t itself
+// when it is open.
+func (w *Window) NextOpen(t time.Time) time.Time {
+	if w.Open(t) {
+		return t
+	}
+	opens := time.Date(t.Year(), t.Month(), t.Day(), w.Start/60, w.Start%60, 0, 0, t.Location())
+	if !opens.After(t) {
+		opens = opens.AddDate(0, 0, 1)
+	}
+	return opens
+}
+
+// String is the window as ParseWindow reads it.
+func (w *Window) String() string {
+	if w == nil {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
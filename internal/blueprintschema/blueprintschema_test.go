@@ -0,0 +1,106 @@
+package blueprintschema
+
+import (
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/encoding/jsonschema"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+func allBlueprints(t *testing.T) []Blueprint {
+	t.Helper()
+	var blueprints []Blueprint
+	for _, name := range types.RegisteredBlueprintTypes() {
+		blueprints = append(blueprints, Blueprint{Type: name, Version: types.LatestSchemaVersion(name)})
+	}
+	return blueprints
+}
+
+// documents a blueprint schema must accept, and ones it must refuse.
+var (
+	accepted = map[string]string{
+		"packages": `{"packages": [{"name": "git", "action": "install", "package_manager": "apt", "profiles": ["dev"]}]}`,
+		"all-in-one": `{"schema_version": 1, "packages": [{"name": "git", "action": "remove"}],
+			"files": [{"name": ".vimrc", "action": "copy", "mode": "0644", "variables": {"a": 1}}],
+			"scripts": [{"name": "setup", "action": "run", "exec": "bash", "args": "--fast"}],
+			"users": [{"name": "dev", "action": "shell", "shell": "/bin/zsh"}]}`,
+		"args as list":     `{"scripts": [{"name": "setup", "action": "run", "args": ["-x", "y"]}]}`,
+		"provider chain":   `{"packages": [{"name": "ripgrep", "action": "install", "package_manager": ["apt", "brew"]}]}`,
+		"upgrade and hold": `{"packages": [{"action": "upgrade", "package_manager": "apt"}, {"names": ["linux"], "action": "hold"}]}`,
+	}
+	refused = map[string]string{
+		"bad action":       `{"packages": [{"name": "git", "action": "explode"}]}`,
+		"unknown field":    `{"packages": [{"name": "git", "action": "install", "colour": "red"}]}`,
+		"unknown key":      `{"widgets": []}`,
+		"unknown provider": `{"packages": [{"name": "git", "action": "install", "package_manager": "nonesuch"}]}`,
+		"wrong version":    `{"schema_version": 7}`,
+		"unknown in chain": `{"packages": [{"name": "git", "action": "install", "package_manager": ["apt", "nonesuch"]}]}`,
+	}
+)
+
+func check(t *testing.T, schema cue.Value) {
+	t.Helper()
+	ctx := schema.Context()
+	for name, doc := range accepted {
+		if err := schema.Unify(ctx.CompileString(doc)).Validate(cue.Concrete(true)); err != nil {
+			t.Errorf("%s refused: %v", name, err)
+		}
+	}
+	for name, doc := range refused {
+		if err := schema.Unify(ctx.CompileString(doc)).Validate(cue.Concrete(true)); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+}
+
+func TestCUE(t *testing.T) {
+	out, err := CUE(allBlueprints(t), []string{"apt", "brew"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := cuecontext.New().CompileString(out)
+	if root.Err() != nil {
+		t.Fatalf("generated CUE does not compile: %v\n%s", root.Err(), out)
+	}
+	check(t, root.LookupPath(cue.ParsePath("#Blueprint")))
+
+	if !strings.Contains(out, "#PackagesData: {") || !strings.Contains(out, `action?:          "install" | "remove"`) {
+		t.Fatalf("missing packages definitions:\n%s", out)
+	}
+}
+
+// The JSON Schema is read back through CUE's JSON Schema importer, so the
+// same documents check both renderings.
+func TestJSONSchema(t *testing.T) {
+	out, err := JSONSchema(allBlueprints(t), []string{"apt", "brew"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := cuecontext.New()
+	file, err := jsonschema.Extract(ctx.CompileBytes(out), &jsonschema.Config{})
+	if err != nil {
+		t.Fatalf("generated JSON Schema does not import: %v", err)
+	}
+	schema := ctx.BuildFile(file)
+	if schema.Err() != nil {
+		t.Fatal(schema.Err())
+	}
+	check(t, schema)
+}
+
+func TestJSONSchemaSingleType(t *testing.T) {
+	out, err := JSONSchema([]Blueprint{{Type: types.BlueprintTypeGit, Version: 1}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(out)
+	if !strings.Contains(text, `"title": "rwr git blueprint (schema version 1)"`) || strings.Contains(text, `"packages"`) {
+		t.Fatalf("single-type schema:\n%s", text)
+	}
+	if _, err := JSONSchema([]Blueprint{{Type: types.BlueprintTypeGit, Version: 9}}, nil); err == nil {
+		t.Fatal("an unsupported version exported")
+	}
+}
@@ -0,0 +1,159 @@
+package blueprintschema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// CUE renders the blueprints as CUE definitions named after the structs
+// they decode into: one per blueprint type and version (#PackagesData), one
+// per entry struct (#Package), and, for several blueprints, #Blueprint
+// accepting any of their top-level keys. Definitions are closed, as the
+// decoders are strict. providers, when given, constrain package_manager.
+func CUE(blueprints []Blueprint, providers []string) (string, error) {
+	g := &cueGenerator{providers: providers, entries: map[reflect.Type]string{}}
+	var b strings.Builder
+	b.WriteString("// rwr blueprint schemas. Generated by `rwr schema export --format cue`.\n")
+	var union []string
+	seen := map[string]bool{}
+	for _, blueprint := range blueprints {
+		target, err := Target(blueprint.Type, blueprint.Version)
+		if err != nil {
+			return "", err
+		}
+		name := definitionName(target)
+		var fields []string
+		for _, f := range Fields(target) {
+			line := g.field("", f, blueprint.Version)
+			fields = append(fields, line)
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				union = append(union, line)
+			}
+		}
+		g.definitions = append(g.definitions, definition{name: name, fields: fields})
+	}
+	if len(blueprints) > 1 {
+		g.definitions = append(g.definitions, definition{name: "#Blueprint", fields: union})
+	}
+	for _, d := range g.definitions {
+		var body strings.Builder
+		w := tabwriter.NewWriter(&body, 0, 4, 1, ' ', 0)
+		for _, line := range d.fields {
+			fmt.Fprintln(w, line)
+		}
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+		b.WriteString("\n" + d.name + ": {\n")
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(body.String(), "\n"), "\n") {
+			b.WriteString("\t" + line)
+		}
+		b.WriteString("\n}\n")
+	}
+	return b.String(), nil
+}
+
+type definition struct {
+	name   string
+	fields []string
+}
+
+type cueGenerator struct {
+	providers   []string
+	entries     map[reflect.Type]string // entry struct → its definition's name
+	definitions []definition
+}
+
+// field renders one optional key as "name?:\ttype".
+func (g *cueGenerator) field(listKey string, f Field, version int) string {
+	var value string
+	switch {
+	case f.Name == "schema_version" && listKey == "":
+		value = strconv.Itoa(version)
+	case f.Name == "action" && len(Actions[listKey]) > 0:
+		value = cueEnum(Actions[listKey])
+	case ProviderKeys[f.Name] && len(g.providers) > 0:
+		value = cueEnum(g.providers)
+		if f.Type == chainType {
+			value += " | [...(" + value + ")]"
+		}
+	default:
+		value = g.value(f.Name, f.Type)
+	}
+	return cueLabel(f.Name) + "?:\t" + value
+}
+
+func (g *cueGenerator) value(key string, t reflect.Type) string {
+	switch t {
+	case fileModeType:
+		return `=~"^0?[0-7]{3,4}$" | int`
+	case scriptArgsType, chainType:
+		return "string | [...string]"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.value(key, t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "[..." + g.value(key, t.Elem()) + "]"
+	case reflect.Map:
+		return "{[string]: " + g.value("", t.Elem()) + "}"
+	case reflect.Struct:
+		return g.entry(key, t)
+	}
+	return "_"
+}
+
+// entry names an entry struct's definition, emitting it the first time.
+func (g *cueGenerator) entry(listKey string, t reflect.Type) string {
+	if name, ok := g.entries[t]; ok {
+		return name
+	}
+	name := definitionName(t)
+	g.entries[t] = name
+	index := len(g.definitions)
+	g.definitions = append(g.definitions, definition{name: name})
+	var fields []string
+	for _, f := range Fields(t) {
+		fields = append(fields, g.field(listKey, f, 0))
+	}
+	g.definitions[index].fields = fields
+	return name
+}
+
+func cueEnum(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, " | ")
+}
+
+// cueLabel quotes a key CUE would not read as an identifier.
+func cueLabel(name string) string {
+	for i, r := range name {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return strconv.Quote(name)
+		}
+	}
+	return name
+}
+
+// definitionName names a struct's definition: #PackagesData, #Package.
+func definitionName(t reflect.Type) string {
+	name := t.Name()
+	return "#" + strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,128 @@
+// Package blueprintschema describes the blueprint format from the structs
+// the schema registry decodes into: the keys each type and version accepts,
+// the actions each entry list allows, and the fields that name a provider.
+// The language server completes from it, and `rwr schema export` renders it
+// as JSON Schema or CUE for editors that bring their own tooling.
+package blueprintschema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// Field is one key a blueprint accepts.
+type Field struct {
+	Name string
+	Type reflect.Type
+}
+
+// Actions are the actions each list key's entries accept - the sets
+// validate enforces.
+var Actions = map[string][]string{
+	"packages":       types.PackageActions,
+	"repositories":   {types.RepoActionAdd, types.RepoActionRemove},
+	"files":          types.FileActions,
+	"templates":      types.FileActions,
+	"directories":    types.FileActions,
+	"git":            {types.GitActionClone, types.GitActionPull},
+	"services":       {types.ServiceActionEnable, types.ServiceActionDisable, types.ServiceActionStart, types.ServiceActionStop, types.ServiceActionRestart, types.ServiceActionReload, types.ServiceActionStatus, types.ServiceActionCreate, types.ServiceActionDelete},
+	"users":          {types.UserActionCreate, types.UserActionModify, types.UserActionRemove, types.UserActionDelete, types.UserActionShell},
+	"groups":         {types.UserActionCreate, types.UserActionModify, types.UserActionRemove, types.UserActionDelete},
+	"sudoers":        {types.PolicyActionCreate, types.PolicyActionRemove},
+	"polkit":         {types.PolicyActionCreate, types.PolicyActionRemove},
+	"fonts":          {"install", "remove"},
+	"scripts":        {"run"},
+	"configurations": {types.ConfigurationActionSet},
+}
+
+// ProviderKeys are the fields whose value names a package provider.
+var ProviderKeys = map[string]bool{"package_manager": true}
+
+// FieldDocs describe the fields most entries share. A schema carries them as
+// descriptions; a field not listed goes without.
+var FieldDocs = map[string]string{
+	"name":            "The entry's name - the package, repository, file, service or account it manages.",
+	"names":           "Several names handled as one entry, sharing its other fields.",
+	"action":          "What to do with the entry. Each blueprint type accepts its own set.",
+	"profiles":        "Profiles this entry belongs to. An entry with no profiles always applies; one with profiles applies only when a listed profile is active (--profile). \"!name\" excludes a profile; \"all_of:a,b\" requires every one listed.",
+	"when":            "A template condition (eq .Facts.gpuVendor \"nvidia\") this entry applies under. False skips the entry, reporting the condition as the reason.",
+	"import":          "Path of another blueprint file whose entries replace this one, relative to this file's directory.",
+	"package_manager": "The provider to use (apt, brew, dnf, ...). Empty picks the system's default provider. A package entry may list several to fall back through: the first that has the package installs it.",
+	"provider":        "The font provider. nerd, the default, is the only one.",
+	"elevated":        "Run with elevated privileges (sudo on Unix).",
+	"interactive":     "Override the global interactive mode for this entry.",
+	"args":            "Extra arguments passed to the underlying command.",
+	"schema_version":  "The schema this file is written in. Overrides the tree-wide version from the init file.",
+	"source":          "Where the content comes from, relative to the blueprint tree.",
+	"target":          "Where the entry is applied on the machine.",
+	"mode":            "File mode to apply, as octal (0644).",
+	"owner":           "User that should own the path.",
+	"group":           "Group that should own the path.",
+	"url":             "Location to fetch from.",
+	"key_url":         "URL of the repository's signing key.",
+	"location":        "Install location: user (the default) or system.",
+	"exec":            "The interpreter the script runs with (bash, python, self, ...).",
+	"content":         "Inline content, instead of a source file.",
+	"tool":            "The configuration tool that writes the setting (dconf, gsettings, macos_defaults, kde, xfconf, ini, windows_registry).",
+	"settings":        "The keys to set, as a map of key to value.",
+}
+
+// Target returns the struct a blueprint type's version decodes into.
+func Target(blueprintType string, version int) (reflect.Type, error) {
+	variant, err := types.NewSchemaVariant(blueprintType, version)
+	if err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf(variant.Target())
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s v%d decodes into %s, not a struct", blueprintType, version, t)
+	}
+	return t, nil
+}
+
+// Fields lists a struct's blueprint keys in declaration order, flattening
+// inlined embeds (SchemaVersion).
+func Fields(t reflect.Type) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "-" || !sf.IsExported() {
+			continue
+		}
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, Fields(sf.Type)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		fields = append(fields, Field{Name: name, Type: sf.Type})
+	}
+	return fields
+}
+
+// Entry returns the struct a list field's entries decode into, if it is a
+// list of entries.
+func Entry(f Field) (reflect.Type, bool) {
+	t := f.Type
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct
+}
+
+var (
+	fileModeType   = reflect.TypeOf(types.FileMode(0))
+	scriptArgsType = reflect.TypeOf(types.ScriptArgs{})
+	chainType      = reflect.TypeOf(types.ProviderChain{})
+)
@@ -0,0 +1,113 @@
+package blueprintschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// JSONSchemaDraft is the dialect exported: draft-07, the newest one both
+// yaml-language-server and taplo read.
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Blueprint is one blueprint type at one schema version.
+type Blueprint struct {
+	Type    string
+	Version int
+}
+
+// JSONSchema renders the blueprints as one JSON Schema document. A single
+// blueprint gets its own schema; several are merged into one object that
+// accepts any of their top-level keys, since a file may hold more than one
+// type (an all-in-one tree). providers, when given, constrain
+// package_manager.
+func JSONSchema(blueprints []Blueprint, providers []string) ([]byte, error) {
+	title := "rwr blueprint"
+	if len(blueprints) == 1 {
+		title = fmt.Sprintf("rwr %s blueprint (schema version %d)", blueprints[0].Type, blueprints[0].Version)
+	}
+	properties := map[string]interface{}{}
+	for _, blueprint := range blueprints {
+		target, err := Target(blueprint.Type, blueprint.Version)
+		if err != nil {
+			return nil, err
+		}
+		g := &jsonGenerator{providers: providers, version: blueprint.Version}
+		for _, f := range Fields(target) {
+			if _, ok := properties[f.Name]; !ok {
+				properties[f.Name] = g.field("", f)
+			}
+		}
+	}
+	schema := map[string]interface{}{
+		"$schema":              JSONSchemaDraft,
+		"title":                title,
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+type jsonGenerator struct {
+	providers []string
+	version   int
+}
+
+// field renders one key, under the list key its entry sits in ("" at the
+// top level).
+func (g *jsonGenerator) field(listKey string, f Field) map[string]interface{} {
+	var schema map[string]interface{}
+	switch {
+	case f.Name == "schema_version" && listKey == "":
+		schema = map[string]interface{}{"const": g.version}
+	case f.Name == "action" && len(Actions[listKey]) > 0:
+		schema = map[string]interface{}{"enum": Actions[listKey]}
+	case ProviderKeys[f.Name] && len(g.providers) > 0:
+		schema = map[string]interface{}{"enum": g.providers}
+		if f.Type == chainType {
+			schema = map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "array", "items": schema}}}
+		}
+	default:
+		schema = g.value(f.Name, f.Type)
+	}
+	if doc, ok := FieldDocs[f.Name]; ok {
+		schema["description"] = doc
+	}
+	return schema
+}
+
+// value renders a Go type; key names the list its entries belong to when t
+// is a list of entries.
+func (g *jsonGenerator) value(key string, t reflect.Type) map[string]interface{} {
+	switch t {
+	case fileModeType:
+		return map[string]interface{}{"type": []string{"string", "integer"}, "pattern": "^0?[0-7]{3,4}$"}
+	case scriptArgsType, chainType:
+		return map[string]interface{}{"type": []string{"string", "array"}, "items": map[string]interface{}{"type": "string"}}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.value(key, t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.value(key, t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.value("", t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for _, f := range Fields(t) {
+			properties[f.Name] = g.field(key, f)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	}
+	return map[string]interface{}{} // interface{}: anything
+}
@@ -18,24 +18,35 @@ import (
 
 // Selection is what the operator chose to keep, per category.
 type Selection struct {
-	Packages map[string][]string // provider → names
-	Configs  []scan.ConfigResult
-	Services []string
-	Git      []scan.GitCheckout
+	Packages     map[string][]string // provider → names
+	Configs      []scan.ConfigResult
+	Services     []string
+	Git          []scan.GitCheckout
+	Repositories []scan.RepositoryResult
+	Fonts        []scan.FontResult
+	Users        []scan.UserResult
+	Groups       []scan.GroupResult
+	Settings     []scan.SettingResult
 }
 
 // Findings is everything the scan surfaced, for the form to offer.
 type Findings struct {
-	Packages []scan.PackageResult
-	Configs  []scan.ConfigResult
-	Services []string
-	Git      []scan.GitCheckout
-	Home     string
+	Packages     []scan.PackageResult
+	Configs      []scan.ConfigResult
+	Services     []string
+	Git          []scan.GitCheckout
+	Repositories []scan.RepositoryResult
+	Fonts        []scan.FontResult
+	Users        []scan.UserResult
+	Groups       []scan.GroupResult
+	Settings     []scan.SettingResult
+	Home         string
 }
 
-// Defaults is the pre-selection: explicit packages and known dotfiles and
-// checkouts in, services out (most are distro plumbing), secret-shaped
-// paths never.
+// Defaults is the pre-selection: explicit packages, known dotfiles,
+// checkouts, repositories, fonts and changed settings in; services (most are
+// distro plumbing) and accounts (rarely meant for every machine) out;
+// secret-shaped paths never.
 func Defaults(findings Findings) Selection {
 	selection := Selection{Packages: map[string][]string{}}
 	for _, result := range findings.Packages {
@@ -49,6 +60,9 @@ func Defaults(findings Findings) Selection {
 		}
 	}
 	selection.Git = findings.Git
+	selection.Repositories = findings.Repositories
+	selection.Fonts = findings.Fonts
+	selection.Settings = findings.Settings
 	return selection
 }
 
@@ -129,6 +143,51 @@ func Generate(out io.Writer, dir, format string, selection Selection, findings F
 		}
 	}
 
+	if len(selection.Repositories) > 0 {
+		for _, repository := range selection.Repositories {
+			if repository.KeyURL == "" && (repository.Provider == "apt" || repository.Provider == "dnf") {
+				helpers.Say(out, "warning: repository %s has no key_url - add the signing key URL before applying\n", repository.Name)
+			}
+		}
+		block, err := scan.EmitRepositories(selection.Repositories, format)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, "repositories", "repositories"+ext), block); err != nil {
+			return err
+		}
+	}
+
+	if len(selection.Fonts) > 0 {
+		block, err := scan.EmitFonts(selection.Fonts, format)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, "fonts", "fonts"+ext), block); err != nil {
+			return err
+		}
+	}
+
+	if len(selection.Users) > 0 || len(selection.Groups) > 0 {
+		block, err := scan.EmitUsers(selection.Users, selection.Groups, format)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, "users", "users"+ext), block); err != nil {
+			return err
+		}
+	}
+
+	if len(selection.Settings) > 0 {
+		block, err := scan.EmitSettings(selection.Settings, format)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, "configuration", "configuration"+ext), block); err != nil {
+			return err
+		}
+	}
+
 	if manifest {
 		entry := map[string]interface{}{
 			"name": "captured",
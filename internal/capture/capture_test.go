@@ -27,7 +27,16 @@ func testFindings(t *testing.T) Findings {
 			{Path: filepath.Join(home, ".ssh"), Rel: ".ssh", Known: false},
 		},
 		Services: []string{"tailscaled"},
-		Home:     home,
+		Repositories: []scan.RepositoryResult{
+			{Provider: "dnf", Name: "copr-atim-lazygit", URL: "https://download.copr.fedorainfracloud.org/results/atim/lazygit/fedora-$releasever-$basearch/"},
+		},
+		Fonts:  []scan.FontResult{{Name: "JetBrainsMono"}},
+		Users:  []scan.UserResult{{Name: "builder", UID: "1001", Shell: "/bin/bash"}},
+		Groups: []scan.GroupResult{{Name: "devs", GID: "1002"}},
+		Settings: []scan.SettingResult{
+			{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.interface", Key: "color-scheme", Value: "prefer-dark"},
+		},
+		Home: home,
 	}
 }
 
@@ -47,12 +56,19 @@ func TestDefaults(t *testing.T) {
 	if len(selection.Services) != 0 {
 		t.Fatal("services pre-selected")
 	}
+	if len(selection.Repositories) != 1 || len(selection.Fonts) != 1 || len(selection.Settings) != 1 {
+		t.Fatalf("repositories, fonts or settings not pre-selected: %+v", selection)
+	}
+	if len(selection.Users) != 0 || len(selection.Groups) != 0 {
+		t.Fatal("accounts pre-selected")
+	}
 }
 
 // A capture generates a tree that validates, per format.
 func TestGenerate_ValidTree(t *testing.T) {
 	findings := testFindings(t)
 	selection := Defaults(findings)
+	selection.Users, selection.Groups = findings.Users, findings.Groups
 	for _, format := range []string{"cue", "yaml"} {
 		dir := filepath.Join(t.TempDir(), "out")
 		var out bytes.Buffer
@@ -60,7 +76,9 @@ func TestGenerate_ValidTree(t *testing.T) {
 			t.Fatalf("%s: %v\n%s", format, err, out.String())
 		}
 		ext := "." + format
-		for _, want := range []string{"init" + ext, filepath.Join("packages", "packages"+ext), filepath.Join("files", "files"+ext), filepath.Join("files", "src", ".bashrc"), "manifest" + ext} {
+		for _, want := range []string{"init" + ext, filepath.Join("packages", "packages"+ext), filepath.Join("files", "files"+ext), filepath.Join("files", "src", ".bashrc"), "manifest" + ext,
+			filepath.Join("repositories", "repositories"+ext), filepath.Join("fonts", "fonts"+ext),
+			filepath.Join("users", "users"+ext), filepath.Join("configuration", "configuration"+ext)} {
 			if _, err := os.Stat(filepath.Join(dir, want)); err != nil {
 				t.Fatalf("%s: missing %s", format, want)
 			}
@@ -68,6 +86,9 @@ func TestGenerate_ValidTree(t *testing.T) {
 		if !strings.Contains(out.String(), "validated") {
 			t.Fatalf("%s: no validation confirmation:\n%s", format, out.String())
 		}
+		if !strings.Contains(out.String(), "copr-atim-lazygit has no key_url") {
+			t.Fatalf("%s: no key_url warning for the dnf repository:\n%s", format, out.String())
+		}
 	}
 }
 
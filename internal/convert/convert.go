@@ -20,25 +20,7 @@ import (
 func Run(out io.Writer, root, toFormat string, migrate, write bool) error {
 	changed := 0
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			if path != root && strings.HasPrefix(info.Name(), ".") {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		if !helpers.IsBlueprintFile(path) {
-			return nil
-		}
-
-		format, err := helpers.FormatForPath(path)
-		if err != nil {
-			return nil
-		}
-
+	err := walkTree(root, func(path, format string) error {
 		data, err := os.ReadFile(path) // #nosec G304 G122 -- operator's own tree, walked read-only
 		if err != nil {
 			helpers.Say(out, "  ! %s: %v\n", path, err)
@@ -98,6 +80,30 @@ func Run(out io.Writer, root, toFormat string, migrate, write bool) error {
 	return nil
 }
 
+// walkTree calls fn for every blueprint-format file under root, skipping
+// hidden directories (.git) below it.
+func walkTree(root string, fn func(path, format string) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !helpers.IsBlueprintFile(path) {
+			return nil
+		}
+		format, err := helpers.FormatForPath(path)
+		if err != nil {
+			return nil
+		}
+		return fn(path, format)
+	})
+}
+
 // migrateInitInlineSections moves the removed inline resource sections out of
 // an init file into blueprint files - the first migration rule.
 func migrateInitInlineSections(out io.Writer, initPath string, doc map[string]interface{}, format string, write bool) (int, error) {
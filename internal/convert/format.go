@@ -0,0 +1,183 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/blueprintschema"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Format rewrites every blueprint, init, bootstrap, and manifest file under
+// root into its canonical form, in its own format: keys in the order the
+// schema structs declare them, names lists sorted with duplicates dropped,
+// file modes as quoted octal strings. YAML and CUE comments survive; TOML
+// comments do not, and each file losing them is warned about. A file the
+// schema does not describe (a template source that happens to end in .yaml)
+// is left alone, and an unparseable one is reported and skipped.
+//
+// With check set nothing is written: the files that would change are listed
+// and Format returns an error counting them, for CI.
+func Format(out io.Writer, root string, check bool) error {
+	changed := 0
+
+	err := walkTree(root, func(path, format string) error {
+		data, err := os.ReadFile(path) // #nosec G304 G122 -- operator's own tree
+		if err != nil {
+			helpers.Say(out, "  ! %s: %v\n", path, err)
+			return nil
+		}
+
+		formatted, err := formatFile(data, format, rootShape(path))
+		if err != nil {
+			helpers.Say(out, "  ! %s: cannot parse (%v) - skipped, not mangled\n", path, err)
+			return nil
+		}
+		if formatted == nil || bytes.Equal(formatted, data) {
+			return nil
+		}
+
+		changed++
+		if check {
+			helpers.Say(out, "  ✗ %s is not formatted\n", path)
+			return nil
+		}
+		if format == types.FormatTOML && hasComments(data, format) {
+			helpers.Say(out, "  ~ %s carries comments; TOML formatting does NOT preserve them\n", path)
+		}
+		if err := os.WriteFile(path, formatted, 0o644); err != nil { // #nosec G306 G122 -- blueprint files are world-readable config; operator's own tree
+			return err
+		}
+		helpers.Say(out, "  → formatted %s\n", path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case changed == 0:
+		helpers.Say(out, "nothing to change\n")
+	case check:
+		return fmt.Errorf("%d file(s) not formatted; run rwr fmt to fix them", changed)
+	}
+	return nil
+}
+
+// formatFile returns data in canonical form, or nil when the file is not
+// one the shape describes.
+func formatFile(data []byte, format string, shape shape) ([]byte, error) {
+	switch format {
+	case types.FormatYAML:
+		return formatYAML(data, shape)
+	case types.FormatJSON:
+		return formatJSON(data, shape)
+	case types.FormatTOML:
+		return formatTOML(data, shape)
+	case types.FormatCUE:
+		return formatCUE(data, shape)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// shape is the key order of a file's top-level mapping.
+type shape struct {
+	fields []blueprintschema.Field
+	// strict marks a shape that must cover every top-level key: a blueprint
+	// is recognized by its keys, so a file with any other key is not one.
+	strict bool
+}
+
+// rootShape picks the shape by file name, the way discovery finds init,
+// bootstrap, and manifest files; anything else is a blueprint.
+func rootShape(path string) shape {
+	switch strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) {
+	case "init":
+		return shape{fields: blueprintschema.Fields(reflect.TypeOf(types.InitConfig{}))}
+	case "manifest":
+		return shape{fields: blueprintschema.Fields(reflect.TypeOf(types.Manifest{}))}
+	case types.BlueprintTypeBootstrap:
+		return shape{fields: blueprintFields([]string{types.BlueprintTypeBootstrap})}
+	}
+	// One file can hold several types (an all-in-one tree), so a blueprint
+	// orders its sections the way a run applies them.
+	order, _ := processors.GetBlueprintRunOrder(&types.InitConfig{})
+	return shape{fields: blueprintFields(order), strict: true}
+}
+
+// blueprintFields is the union of the blueprint types' top-level fields,
+// schema_version first, then each type's sections in the order given.
+func blueprintFields(blueprintTypes []string) []blueprintschema.Field {
+	var fields []blueprintschema.Field
+	seen := map[string]bool{}
+	for _, blueprintType := range blueprintTypes {
+		target, err := blueprintschema.Target(blueprintType, types.LatestSchemaVersion(blueprintType))
+		if err != nil {
+			continue
+		}
+		for _, f := range blueprintschema.Fields(target) {
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				fields = append(fields, f)
+			}
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Name == "schema_version" && fields[j].Name != "schema_version" })
+	return fields
+}
+
+// fieldRank orders keys by the fields they name; keys the schema does not
+// know keep their relative order after the known ones.
+func fieldRank(fields []blueprintschema.Field) func(key string) (int, bool) {
+	index := make(map[string]int, len(fields))
+	for i, f := range fields {
+		index[f.Name] = i
+	}
+	return func(key string) (int, bool) {
+		i, ok := index[key]
+		if !ok {
+			return len(fields), false
+		}
+		return i, true
+	}
+}
+
+// structFields is the shape of a value's type, when it is a struct.
+func structFields(t reflect.Type) ([]blueprintschema.Field, bool) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	return blueprintschema.Fields(t), true
+}
+
+var fileModeType = reflect.TypeOf(types.FileMode(0))
+
+// canonicalMode renders a mode the way a blueprint should write it ("0644"),
+// reading it exactly as the decoders do. A mode they would refuse is left
+// as written for validate to report; ok is false then.
+func canonicalMode(tag, value string) (string, bool) {
+	var mode types.FileMode
+	if err := mode.UnmarshalYAML(&yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}); err != nil || !mode.IsSet() {
+		return "", false
+	}
+	return mode.String(), true
+}
+
+// isNamesField reports whether a field is a names list, which fmt sorts and
+// de-duplicates: the entry applies to each name alike, so their order
+// carries nothing but diff noise.
+func isNamesField(f blueprintschema.Field) bool {
+	return f.Name == "names" && f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.String
+}
@@ -0,0 +1,215 @@
+package convert
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/parser"
+	"cuelang.org/go/cue/token"
+	"github.com/fynxlabs/rwr/internal/blueprintschema"
+)
+
+// CUE is formatted on its own syntax tree, not the evaluated value: that
+// keeps comments, definitions and references, which evaluation would flatten
+// into JSON-form CUE. Only literal fields are touched; anything computed is
+// left where and as it was.
+
+func formatCUE(data []byte, s shape) ([]byte, error) {
+	file, err := parser.ParseFile("", data, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON-form CUE (what convert writes) embeds one struct; written by
+	// hand, the fields sit at the top level.
+	decls := &file.Decls
+	var body []ast.Decl
+	for _, decl := range file.Decls {
+		switch decl.(type) {
+		case *ast.Package, *ast.ImportDecl, *ast.CommentGroup, *ast.Attribute:
+		default:
+			body = append(body, decl)
+		}
+	}
+	if len(body) == 1 {
+		if embed, ok := body[0].(*ast.EmbedDecl); ok {
+			if st, ok := embed.Expr.(*ast.StructLit); ok {
+				decls = &st.Elts
+			}
+		}
+	}
+
+	if s.strict && !cueCovers(*decls, s.fields) {
+		return nil, nil
+	}
+	*decls = canonicalDecls(*decls, s.fields)
+	return format.Node(file, format.Simplify())
+}
+
+// cueCovers reports whether every regular field is one the shape knows.
+// Definitions and hidden fields are the author's scaffolding, not keys.
+func cueCovers(decls []ast.Decl, fields []blueprintschema.Field) bool {
+	rank := fieldRank(fields)
+	found := false
+	for _, decl := range decls {
+		field, ok := decl.(*ast.Field)
+		if !ok {
+			continue
+		}
+		name, _, err := ast.LabelName(field.Label)
+		if err != nil || strings.HasPrefix(name, "#") || strings.HasPrefix(name, "_") {
+			continue
+		}
+		if _, known := rank(name); !known {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// canonicalDecls orders a struct's fields like canonicalMapping does. A
+// floating comment moves with the declaration after it; package and import
+// declarations stay first.
+func canonicalDecls(decls []ast.Decl, fields []blueprintschema.Field) []ast.Decl {
+	rank := fieldRank(fields)
+	type unit struct {
+		decls []ast.Decl
+		rank  int
+	}
+	var units []unit
+	var pending []ast.Decl
+	for _, decl := range decls {
+		pending = append(pending, decl)
+		if _, ok := decl.(*ast.CommentGroup); ok {
+			continue
+		}
+		u := unit{decls: pending, rank: len(fields)}
+		switch d := decl.(type) {
+		case *ast.Package, *ast.ImportDecl:
+			u.rank = -1
+		case *ast.Field:
+			if name, _, err := ast.LabelName(d.Label); err == nil {
+				if i, known := rank(name); known {
+					u.rank = i
+					if isNamesField(fields[i]) {
+						sortCUENames(d.Value)
+					} else {
+						canonicalCUEValue(d.Value, fields[i].Type)
+					}
+				}
+			}
+		}
+		units = append(units, u)
+		pending = nil
+	}
+	sort.SliceStable(units, func(i, j int) bool { return units[i].rank < units[j].rank })
+
+	out := make([]ast.Decl, 0, len(decls))
+	for _, u := range units {
+		out = append(out, u.decls...)
+	}
+	return append(out, pending...)
+}
+
+func canonicalCUEValue(expr ast.Expr, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == fileModeType:
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok {
+			return
+		}
+		tag, value := "!!int", lit.Value
+		if lit.Kind == token.STRING {
+			unquoted, err := literal.Unquote(lit.Value)
+			if err != nil {
+				return
+			}
+			tag, value = "!!str", unquoted
+		} else if lit.Kind != token.INT {
+			return
+		}
+		if mode, ok := canonicalMode(tag, value); ok {
+			lit.Kind, lit.Value = token.STRING, strconv.Quote(mode)
+		}
+	case t.Kind() == reflect.Struct:
+		if st, ok := expr.(*ast.StructLit); ok {
+			st.Elts = canonicalDecls(st.Elts, blueprintschema.Fields(t))
+		}
+	case t.Kind() == reflect.Slice:
+		if list, ok := expr.(*ast.ListLit); ok {
+			for _, item := range list.Elts {
+				canonicalCUEValue(item, t.Elem())
+			}
+		}
+	case t.Kind() == reflect.Map:
+		if st, ok := expr.(*ast.StructLit); ok {
+			sortCUEFields(st.Elts)
+			for _, decl := range st.Elts {
+				if field, ok := decl.(*ast.Field); ok {
+					canonicalCUEValue(field.Value, t.Elem())
+				}
+			}
+		}
+	}
+}
+
+// sortCUEFields sorts a map's literal fields by label, as canonicalMapping
+// sorts a map's keys. A struct holding anything else is left as written.
+func sortCUEFields(decls []ast.Decl) {
+	labels := make(map[ast.Decl]string, len(decls))
+	for _, decl := range decls {
+		field, ok := decl.(*ast.Field)
+		if !ok {
+			return
+		}
+		name, _, err := ast.LabelName(field.Label)
+		if err != nil {
+			return
+		}
+		labels[decl] = name
+	}
+	sort.SliceStable(decls, func(i, j int) bool { return labels[decls[i]] < labels[decls[j]] })
+}
+
+// sortCUENames is sortNames for a list of string literals.
+func sortCUENames(expr ast.Expr) {
+	list, ok := expr.(*ast.ListLit)
+	if !ok {
+		return
+	}
+	values := make([]string, len(list.Elts))
+	for i, item := range list.Elts {
+		lit, ok := item.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		value, err := literal.Unquote(lit.Value)
+		if err != nil {
+			return
+		}
+		values[i] = value
+	}
+
+	order := make([]int, len(list.Elts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+	sorted := make([]ast.Expr, 0, len(order))
+	for k, i := range order {
+		if k > 0 && values[i] == values[order[k-1]] {
+			continue
+		}
+		sorted = append(sorted, list.Elts[i])
+	}
+	list.Elts = sorted
+}
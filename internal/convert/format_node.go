@@ -0,0 +1,453 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fynxlabs/rwr/internal/blueprintschema"
+	"gopkg.in/yaml.v3"
+)
+
+// YAML, JSON and TOML are formatted through one tree: each file is read into
+// a yaml.Node - which keeps YAML's comments and quoting - put in canonical
+// order there, and written back out in the format it came in.
+
+// canonicalMapping orders a mapping's keys by fields - or sorts them, when
+// fields is nil - and canonicalizes each value by its field's type.
+func canonicalMapping(node *yaml.Node, fields []blueprintschema.Field) {
+	rank := fieldRank(fields)
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ri, _ := rank(pairs[i][0].Value)
+		rj, _ := rank(pairs[j][0].Value)
+		if fields == nil {
+			return pairs[i][0].Value < pairs[j][0].Value
+		}
+		return ri < rj
+	})
+
+	node.Content = node.Content[:0]
+	for _, pair := range pairs {
+		node.Content = append(node.Content, pair[0], pair[1])
+		i, known := rank(pair[0].Value)
+		if !known {
+			continue
+		}
+		if isNamesField(fields[i]) {
+			sortNames(pair[1])
+		} else {
+			canonicalValue(pair[1], fields[i].Type)
+		}
+	}
+}
+
+// canonicalValue canonicalizes a value of type t. A node whose kind does not
+// fit the type is left as written; the decoder, not fmt, reports it.
+func canonicalValue(node *yaml.Node, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == fileModeType:
+		if node.Kind == yaml.ScalarNode {
+			if mode, ok := canonicalMode(node.ShortTag(), node.Value); ok {
+				node.Tag, node.Value, node.Style = "!!str", mode, yaml.DoubleQuotedStyle
+			}
+		}
+	case t.Kind() == reflect.Struct:
+		if node.Kind == yaml.MappingNode {
+			canonicalMapping(node, blueprintschema.Fields(t))
+		}
+	case t.Kind() == reflect.Slice:
+		if node.Kind == yaml.SequenceNode {
+			for _, item := range node.Content {
+				canonicalValue(item, t.Elem())
+			}
+		}
+	case t.Kind() == reflect.Map:
+		if node.Kind == yaml.MappingNode {
+			// A map has no schema order; sorted is the order TOML, which
+			// cannot keep the written one, agrees with.
+			canonicalMapping(node, nil)
+			for i := 1; i < len(node.Content); i += 2 {
+				canonicalValue(node.Content[i], t.Elem())
+			}
+		}
+	}
+}
+
+// sortNames sorts a names list and drops repeats, keeping the first.
+func sortNames(node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			return
+		}
+	}
+	sort.SliceStable(node.Content, func(i, j int) bool { return node.Content[i].Value < node.Content[j].Value })
+	kept := node.Content[:0]
+	for i, item := range node.Content {
+		if i == 0 || item.Value != node.Content[i-1].Value {
+			kept = append(kept, item)
+		}
+	}
+	node.Content = kept
+}
+
+// canonicalRoot canonicalizes a document's top-level mapping, reporting
+// false when the shape does not describe it.
+func canonicalRoot(doc *yaml.Node, s shape) bool {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) == 1 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode || len(root.Content) == 0 {
+		return false
+	}
+	if s.strict {
+		rank := fieldRank(s.fields)
+		for i := 0; i < len(root.Content); i += 2 {
+			if _, known := rank(root.Content[i].Value); !known {
+				return false
+			}
+		}
+	}
+	canonicalMapping(root, s.fields)
+	return true
+}
+
+// decodeYAML reads exactly one YAML document.
+func decodeYAML(data []byte) (*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	var next yaml.Node
+	if err := dec.Decode(&next); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("holds more than one YAML document")
+	}
+	return &doc, nil
+}
+
+func formatYAML(data []byte, s shape) ([]byte, error) {
+	doc, err := decodeYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	if !canonicalRoot(doc, s) {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return spaceYAML(buf.Bytes())
+}
+
+// spaceYAML puts a blank line between top-level keys and between the
+// entries of a top-level list. yaml.v3 drops the blank lines a file was
+// written with; restoring them by rule, rather than by memory, is what
+// keeps two authors' files alike.
+func spaceYAML(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, nil
+	}
+
+	before := map[int]bool{} // 1-based lines to precede with a blank line
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if i > 0 {
+			before[startLine(root.Content[i])] = true
+		}
+		value := root.Content[i+1]
+		if value.Kind != yaml.SequenceNode || value.Style&yaml.FlowStyle != 0 {
+			continue
+		}
+		for j, item := range value.Content {
+			if j > 0 && item.Kind == yaml.MappingNode {
+				before[startLine(item)] = true
+			}
+		}
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	var out strings.Builder
+	for i, line := range lines {
+		if before[i+1] && i > 0 && strings.TrimSpace(lines[i-1]) != "" {
+			out.WriteString("\n")
+		}
+		out.WriteString(line)
+	}
+	return []byte(out.String()), nil
+}
+
+// startLine is the first line a node occupies, head comments included.
+func startLine(node *yaml.Node) int {
+	line := node.Line - commentLines(node.HeadComment)
+	if node.Kind == yaml.MappingNode && len(node.Content) > 0 {
+		key := node.Content[0]
+		if l := key.Line - commentLines(key.HeadComment); l < line {
+			line = l
+		}
+	}
+	return line
+}
+
+func commentLines(comment string) int {
+	if comment == "" {
+		return 0
+	}
+	return strings.Count(comment, "\n") + 1
+}
+
+// formatJSON reads JSON as the YAML it also is, and writes it back with
+// two-space indentation.
+func formatJSON(data []byte, s shape) ([]byte, error) {
+	doc, err := decodeYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	if !canonicalRoot(doc, s) {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	writeJSON(&buf, doc.Content[0], "")
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, node *yaml.Node, indent string) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		writeJSON(buf, node.Alias, indent)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.WriteString(indent + "  " + jsonString(node.Content[i].Value) + ": ")
+			writeJSON(buf, node.Content[i+1], indent+"  ")
+		}
+		buf.WriteString("\n" + indent + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteString("[\n")
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.WriteString(indent + "  ")
+			writeJSON(buf, item, indent+"  ")
+		}
+		buf.WriteString("\n" + indent + "]")
+	default:
+		switch node.ShortTag() {
+		case "!!str":
+			buf.WriteString(jsonString(node.Value))
+		case "!!null":
+			buf.WriteString("null")
+		default:
+			buf.WriteString(node.Value)
+		}
+	}
+}
+
+// jsonString quotes s for JSON, leaving <, > and & readable.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// formatTOML decodes TOML to values - the decoder keeps neither comments nor
+// key order - and writes them back from the canonical tree: plain keys
+// first in each table, then sub-tables and arrays of tables.
+func formatTOML(data []byte, s shape) ([]byte, error) {
+	var decoded map[string]interface{}
+	if _, err := toml.Decode(string(data), &decoded); err != nil {
+		return nil, err
+	}
+	doc := tomlNode(decoded)
+	if !canonicalRoot(doc, s) {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	writeTOMLTable(&buf, nil, doc)
+	return bytes.TrimLeft(buf.Bytes(), "\n"), nil
+}
+
+// tomlNode builds the tree for a decoded TOML value, keys sorted - the
+// order the canonical sort keeps for keys the schema does not know.
+func tomlNode(value interface{}) *yaml.Node {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range keys {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, tomlNode(v[key]))
+		}
+		return node
+	case []map[string]interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, tomlNode(item))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, tomlNode(item))
+		}
+		return node
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(v, 10)}
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: tomlFloat(v)}
+	case time.Time:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: v.Format(time.RFC3339Nano)}
+	default:
+		// Local dates and times render as TOML writes them.
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: fmt.Sprint(v)}
+	}
+}
+
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func writeTOMLTable(buf *bytes.Buffer, path []string, table *yaml.Node) {
+	for i := 0; i+1 < len(table.Content); i += 2 {
+		key, value := table.Content[i].Value, table.Content[i+1]
+		if !isTOMLTable(value) && !isTOMLTableArray(value) {
+			buf.WriteString(tomlKey(key) + " = " + tomlInline(value) + "\n")
+		}
+	}
+	for i := 0; i+1 < len(table.Content); i += 2 {
+		key, value := table.Content[i].Value, table.Content[i+1]
+		header := tomlHeader(append(append([]string(nil), path...), key))
+		switch {
+		case isTOMLTable(value):
+			buf.WriteString("\n[" + header + "]\n")
+			writeTOMLTable(buf, append(path, key), value)
+		case isTOMLTableArray(value):
+			for _, item := range value.Content {
+				buf.WriteString("\n[[" + header + "]]\n")
+				writeTOMLTable(buf, append(path, key), item)
+			}
+		}
+	}
+}
+
+func isTOMLTable(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode && len(node.Content) > 0
+}
+
+func isTOMLTableArray(node *yaml.Node) bool {
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return false
+	}
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlInline(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		items := make([]string, len(node.Content))
+		for i, item := range node.Content {
+			items[i] = tomlInline(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			return "{}"
+		}
+		pairs := make([]string, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			pairs = append(pairs, tomlKey(node.Content[i].Value)+" = "+tomlInline(node.Content[i+1]))
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }"
+	}
+	if node.ShortTag() == "!!str" {
+		return jsonString(node.Value) // a JSON string is a TOML basic string
+	}
+	return node.Value
+}
+
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if bareTOMLKey.MatchString(key) {
+		return key
+	}
+	return jsonString(key)
+}
+
+func tomlHeader(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	return strings.Join(keys, ".")
+}
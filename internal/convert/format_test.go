@@ -0,0 +1,159 @@
+package convert
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// Keys take schema order, names are sorted and de-duplicated, modes become
+// quoted octal, comments stay with their entry, and entries are spaced.
+func TestFormat_YAML(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"packages/base.yaml": "packages:\n" +
+			"  # tools\n" +
+			"  - action: install\n" +
+			"    names: [vim, git, vim, curl]\n" +
+			"    package_manager: apt\n" +
+			"  - package_manager: apt\n" +
+			"    name: htop\n" +
+			"    action: install\n",
+		"files/dotfiles.yaml": "files:\n- mode: 420\n  name: .profile\n  action: copy\n  source: src/.profile\n  target: '{{ .User.home }}'\n",
+	})
+
+	var out bytes.Buffer
+	if err := Format(&out, dir, false); err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	want := "packages:\n" +
+		"  # tools\n" +
+		"  - action: install\n" +
+		"    package_manager: apt\n" +
+		"    names: [curl, git, vim]\n" +
+		"\n" +
+		"  - name: htop\n" +
+		"    action: install\n" +
+		"    package_manager: apt\n"
+	if got := readFile(t, filepath.Join(dir, "packages", "base.yaml")); got != want {
+		t.Fatalf("packages formatted as:\n%s\nwant:\n%s", got, want)
+	}
+
+	files := readFile(t, filepath.Join(dir, "files", "dotfiles.yaml"))
+	if !strings.Contains(files, `mode: "0644"`) || !strings.HasPrefix(files, "files:\n  - name: .profile\n") {
+		t.Fatalf("files formatted as:\n%s", files)
+	}
+}
+
+// --check writes nothing and fails while anything would change; formatting
+// is idempotent, so a formatted tree passes.
+func TestFormat_Check(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"services/services.yaml": "services:\n  - action: enable\n    name: sshd\n",
+	})
+	path := filepath.Join(dir, "services", "services.yaml")
+	before := readFile(t, path)
+
+	var out bytes.Buffer
+	if err := Format(&out, dir, true); err == nil {
+		t.Fatal("check passed on an unformatted tree")
+	}
+	if readFile(t, path) != before {
+		t.Fatal("check wrote the file")
+	}
+
+	if err := Format(&out, dir, false); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := Format(&out, dir, true); err != nil {
+		t.Fatalf("check failed on a formatted tree: %v\n%s", err, out.String())
+	}
+}
+
+// A file whose keys are not blueprint keys is a template source or other
+// content, not a blueprint; an unparseable one is reported. Neither changes.
+func TestFormat_LeavesOtherFilesAlone(t *testing.T) {
+	source := "zeta: 1\nalpha:   2\n"
+	templated := "packages:\n{{- range .UserDefined.pkgs }}\n  - name: {{ . }}\n{{- end }}\n"
+	dir := writeTree(t, map[string]string{
+		"files/src/app.yaml":      source,
+		"packages/templated.yaml": templated,
+	})
+
+	var out bytes.Buffer
+	if err := Format(&out, dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if readFile(t, filepath.Join(dir, "files", "src", "app.yaml")) != source {
+		t.Fatal("a non-blueprint file was rewritten")
+	}
+	if readFile(t, filepath.Join(dir, "packages", "templated.yaml")) != templated {
+		t.Fatal("an unparseable file was rewritten")
+	}
+	if !strings.Contains(out.String(), "templated.yaml: cannot parse") {
+		t.Fatalf("unparseable file not reported:\n%s", out.String())
+	}
+}
+
+// JSON, TOML and CUE are canonicalized the same way, each in its own format.
+func TestFormat_OtherFormats(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.json": `{"files": [{"mode": 420, "name": "x", "action": "copy"}], "schema_version": 1}`,
+		"b.toml": "[[packages]]\npackage_manager = \"apt\"\nnames = [\"b\", \"a\"]\naction = \"install\"\n\n" +
+			"[[configurations]]\nname = \"theme\"\ntool = \"dconf\"\naction = \"set\"\n[configurations.settings]\nz = 1\na = \"x\"\n",
+		"c.cue": "// dotfiles\nfiles: [{\n\tmode: 0o644\n\tname: \"x\" // the one\n}]\n",
+	})
+
+	var out bytes.Buffer
+	if err := Format(&out, dir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	wantJSON := "{\n  \"schema_version\": 1,\n  \"files\": [\n    {\n      \"name\": \"x\",\n      \"action\": \"copy\",\n      \"mode\": \"0644\"\n    }\n  ]\n}\n"
+	if got := readFile(t, filepath.Join(dir, "a.json")); got != wantJSON {
+		t.Fatalf("JSON formatted as:\n%s\nwant:\n%s", got, wantJSON)
+	}
+
+	wantTOML := "[[packages]]\naction = \"install\"\npackage_manager = \"apt\"\nnames = [\"a\", \"b\"]\n\n" +
+		"[[configurations]]\nname = \"theme\"\naction = \"set\"\ntool = \"dconf\"\n\n[configurations.settings]\na = \"x\"\nz = 1\n"
+	if got := readFile(t, filepath.Join(dir, "b.toml")); got != wantTOML {
+		t.Fatalf("TOML formatted as:\n%s\nwant:\n%s", got, wantTOML)
+	}
+
+	cue := readFile(t, filepath.Join(dir, "c.cue"))
+	for _, want := range []string{"// dotfiles", "// the one", `mode: "0644"`} {
+		if !strings.Contains(cue, want) {
+			t.Fatalf("CUE formatted as:\n%s\nmissing %q", cue, want)
+		}
+	}
+	if strings.Index(cue, "name:") > strings.Index(cue, "mode:") {
+		t.Fatalf("CUE fields not in schema order:\n%s", cue)
+	}
+}
@@ -6,20 +6,25 @@ package diff
 
 import (
 	"fmt"
+	"os"
 	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/fynxlabs/rwr/internal/helpers"
 	"github.com/fynxlabs/rwr/internal/scan"
 	"github.com/fynxlabs/rwr/internal/state"
+	"github.com/fynxlabs/rwr/internal/status"
 	"github.com/fynxlabs/rwr/internal/types"
 )
 
 // Change is one drift item.
 type Change struct {
-	Category string // packages, services, git, configs
-	Provider string // packages only
+	Category string // a blueprint type: packages, repositories, services, ...
+	// Provider is the package manager for packages and repositories, "user"
+	// or "group" for users, and the tool for configuration.
+	Provider string
 	Name     string
 	// Path is where the thing lives on the machine, for the categories that
 	// are identified by one: a config's file and a checkout's directory. It
@@ -36,7 +41,15 @@ type Machine struct {
 	Services []string
 	Git      []scan.GitCheckout
 	Configs  []scan.ConfigResult
-	Home     string
+	// Repositories, Fonts, Users, Groups and Settings are the additions-only
+	// categories: the scan cannot tell a repository or font the tree declares
+	// but the machine lost from one it never had.
+	Repositories []scan.RepositoryResult
+	Fonts        []scan.FontResult
+	Users        []scan.UserResult
+	Groups       []scan.GroupResult
+	Settings     []scan.SettingResult
+	Home         string
 }
 
 // Compute joins machine, tree, and journal. A package the journal shows a
@@ -45,6 +58,9 @@ type Machine struct {
 func Compute(machine Machine, plan *types.Plan, applies []state.Entry) []Change {
 	desired := map[string]map[string]bool{} // category → name set
 	for _, resource := range plan.Resources {
+		if resource.Status == types.StatusSkipped {
+			continue // its `when:` is false here: not desired on this machine
+		}
 		category := resource.Processor
 		if desired[category] == nil {
 			desired[category] = map[string]bool{}
@@ -132,6 +148,34 @@ func Compute(machine Machine, plan *types.Plan, applies []state.Entry) []Change
 		add(types.BlueprintTypeFiles, "", path.Base(config.Rel), config.Path)
 	}
 
+	for _, repository := range machine.Repositories {
+		add(types.BlueprintTypeRepositories, repository.Provider, repository.Name, "")
+	}
+	for _, font := range machine.Fonts {
+		add(types.BlueprintTypeFonts, "", font.Name, "")
+	}
+	for _, group := range machine.Groups {
+		add(types.BlueprintTypeUsers, "group", group.Name, "")
+	}
+	for _, user := range machine.Users {
+		add(types.BlueprintTypeUsers, "user", user.Name, "")
+	}
+
+	// A setting has no name of its own - the tree names the entry, not the
+	// key - so it is matched by address on both sides instead.
+	declared := declaredSettings(plan, applies)
+	for _, setting := range machine.Settings {
+		if declared[settingAddress(setting.Tool, setting.Schema, setting.Domain, setting.Key)] ||
+			(setting.Path != "" && declared[settingAddress(types.ConfigurationToolDconf, "", "", setting.Path)]) {
+			continue
+		}
+		changes = append(changes, Change{
+			Category: types.BlueprintTypeConfiguration,
+			Provider: setting.Tool,
+			Name:     settingName(setting),
+		})
+	}
+
 	sort.SliceStable(changes, func(i, j int) bool {
 		a, b := changes[i], changes[j]
 		if a.Category != b.Category {
@@ -145,6 +189,64 @@ func Compute(machine Machine, plan *types.Plan, applies []state.Entry) []Change
 	return changes
 }
 
+// settingAddress keys a setting by where it lives: schema and key for
+// gsettings, domain and key for defaults, the full path for dconf.
+func settingAddress(tool, schema, domain, key string) string {
+	if tool == types.ConfigurationToolMacOSDefaults && domain == "" {
+		domain = "NSGlobalDomain"
+	}
+	return tool + "\x00" + schema + domain + "\x00" + key
+}
+
+// settingName is how a scanned setting is listed and found again.
+func settingName(setting scan.SettingResult) string {
+	if setting.Tool == types.ConfigurationToolMacOSDefaults {
+		return setting.Domain + " " + setting.Key
+	}
+	return setting.Schema + " " + setting.Key
+}
+
+// declaredSettings collects the addresses of every setting the tree declares
+// or the journal shows applied. A dconf keyfile declares each key in it.
+func declaredSettings(plan *types.Plan, applies []state.Entry) map[string]bool {
+	declared := map[string]bool{}
+	dconfKeys := func(keyfile string) {
+		for _, entry := range helpers.INIKeys(keyfile) {
+			declared[settingAddress(types.ConfigurationToolDconf, "", "", status.DconfKey(entry.Section, entry.Key))] = true
+		}
+	}
+	for _, resource := range plan.Resources {
+		setting := resource.Setting
+		if resource.Processor != types.BlueprintTypeConfiguration || setting == nil || resource.Status == types.StatusSkipped {
+			continue
+		}
+		if setting.Tool == types.ConfigurationToolDconf {
+			if data, err := os.ReadFile(setting.File); err == nil { // #nosec G304 -- a keyfile the operator's own tree names
+				dconfKeys(string(data))
+			}
+			continue
+		}
+		declared[settingAddress(setting.Tool, setting.Schema, setting.Domain, setting.Key)] = true
+	}
+	for _, entry := range applies {
+		if entry.Processor != types.BlueprintTypeConfiguration {
+			continue
+		}
+		identity := entry.Identity
+		if identity["tool"] == types.ConfigurationToolDconf {
+			// The dconf identity's value is the "key=value" lines it loaded.
+			for _, line := range strings.Split(identity["value"], "\n") {
+				if key, _, ok := strings.Cut(line, "="); ok {
+					declared[settingAddress(types.ConfigurationToolDconf, "", "", key)] = true
+				}
+			}
+			continue
+		}
+		declared[settingAddress(identity["tool"], identity["schema"], identity["domain"], identity["key"])] = true
+	}
+	return declared
+}
+
 // Render formats changes as the readable grouped list.
 func Render(changes []Change) string {
 	if len(changes) == 0 {
@@ -193,6 +295,33 @@ func EmitBlocks(changes []Change, machine Machine, format string) (string, error
 		configByPath[filepath.Clean(config.Path)] = config
 	}
 
+	// The name-identified categories are found again by provider and name.
+	var repositories []scan.RepositoryResult
+	repositoryByName := map[string]scan.RepositoryResult{}
+	for _, repository := range machine.Repositories {
+		repositoryByName[repository.Provider+"\x00"+repository.Name] = repository
+	}
+	var fonts []scan.FontResult
+	fontByName := map[string]scan.FontResult{}
+	for _, font := range machine.Fonts {
+		fontByName[font.Name] = font
+	}
+	var users []scan.UserResult
+	userByName := map[string]scan.UserResult{}
+	for _, user := range machine.Users {
+		userByName[user.Name] = user
+	}
+	var groups []scan.GroupResult
+	groupByName := map[string]scan.GroupResult{}
+	for _, group := range machine.Groups {
+		groupByName[group.Name] = group
+	}
+	var settings []scan.SettingResult
+	settingByName := map[string]scan.SettingResult{}
+	for _, setting := range machine.Settings {
+		settingByName[setting.Tool+"\x00"+settingName(setting)] = setting
+	}
+
 	for _, change := range changes {
 		if change.Removal {
 			continue
@@ -210,6 +339,24 @@ func EmitBlocks(changes []Change, machine Machine, format string) (string, error
 			if config, ok := configByPath[filepath.Clean(change.Path)]; ok {
 				configs = append(configs, config)
 			}
+		case types.BlueprintTypeRepositories:
+			if repository, ok := repositoryByName[change.Provider+"\x00"+change.Name]; ok {
+				repositories = append(repositories, repository)
+			}
+		case types.BlueprintTypeFonts:
+			if font, ok := fontByName[change.Name]; ok {
+				fonts = append(fonts, font)
+			}
+		case types.BlueprintTypeUsers:
+			if user, ok := userByName[change.Name]; ok && change.Provider == "user" {
+				users = append(users, user)
+			} else if group, ok := groupByName[change.Name]; ok && change.Provider == "group" {
+				groups = append(groups, group)
+			}
+		case types.BlueprintTypeConfiguration:
+			if setting, ok := settingByName[change.Provider+"\x00"+change.Name]; ok {
+				settings = append(settings, setting)
+			}
 		}
 	}
 
@@ -254,5 +401,33 @@ func EmitBlocks(changes []Change, machine Machine, format string) (string, error
 		}
 		out = append(out, string(block))
 	}
+	if len(repositories) > 0 {
+		block, err := scan.EmitRepositories(repositories, format)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, string(block))
+	}
+	if len(fonts) > 0 {
+		block, err := scan.EmitFonts(fonts, format)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, string(block))
+	}
+	if len(users) > 0 || len(groups) > 0 {
+		block, err := scan.EmitUsers(users, groups, format)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, string(block))
+	}
+	if len(settings) > 0 {
+		block, err := scan.EmitSettings(settings, format)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, string(block))
+	}
 	return strings.Join(out, "\n"), nil
 }
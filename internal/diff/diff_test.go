@@ -362,3 +362,79 @@ func TestCompute_NameMatchingStillAppliesWithoutALocation(t *testing.T) {
 		}
 	}
 }
+
+// Repositories, fonts and accounts match by name against the tree; a setting
+// matches by address - a gsettings key, a key a dconf keyfile in the tree
+// sets, or one the journal shows applied.
+func TestCompute_NewerCategories(t *testing.T) {
+	keyfile := filepath.Join(t.TempDir(), "desktop.ini")
+	if err := os.WriteFile(keyfile, []byte("[org/gnome/desktop/wm/preferences]\nbutton-layout='close:'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	machine := Machine{
+		Repositories: []scan.RepositoryResult{{Provider: "apt", Name: "docker"}, {Provider: "flatpak", Name: "flathub"}},
+		Fonts:        []scan.FontResult{{Name: "Hack"}, {Name: "JetBrainsMono"}},
+		Users:        []scan.UserResult{{Name: "builder", UID: "1001"}},
+		Groups:       []scan.GroupResult{{Name: "devs", GID: "1002"}},
+		Settings: []scan.SettingResult{
+			{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.interface", Key: "color-scheme", Path: "/org/gnome/desktop/interface/color-scheme", Value: "prefer-dark"},
+			{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.interface", Key: "clock-format", Path: "/org/gnome/desktop/interface/clock-format", Value: "24h"},
+			{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.wm.preferences", Key: "button-layout", Path: "/org/gnome/desktop/wm/preferences/button-layout", Value: "close:"},
+			{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.mutter", Key: "center-new-windows", Path: "/org/gnome/mutter/center-new-windows", Value: true},
+		},
+	}
+	plan := &types.Plan{Resources: []types.Resource{
+		{Processor: types.BlueprintTypeRepositories, Provider: "flatpak", Name: "flathub"},
+		{Processor: types.BlueprintTypeFonts, Name: "Hack"},
+		{Processor: types.BlueprintTypeUsers, Name: "devs"},
+		{Processor: types.BlueprintTypeConfiguration, Name: "interface/color-scheme",
+			Setting: &types.Configuration{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.interface", Key: "color-scheme"}},
+		{Processor: types.BlueprintTypeConfiguration, Name: "desktop",
+			Setting: &types.Configuration{Tool: types.ConfigurationToolDconf, File: keyfile}},
+	}}
+	applies := []state.Entry{{
+		Processor: types.BlueprintTypeConfiguration, OK: true, Outcome: "ok",
+		Identity: map[string]string{"tool": "gsettings", "schema": "org.gnome.desktop.interface", "key": "clock-format", "value": "'24h'"},
+	}}
+
+	var got []string
+	for _, change := range Compute(machine, plan, applies) {
+		got = append(got, change.Category+"/"+change.Provider+"/"+change.Name)
+	}
+	want := "configuration/gsettings/org.gnome.mutter center-new-windows," +
+		"fonts//JetBrainsMono,repositories/apt/docker,users/user/builder"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("changes = %v\nwant %s", got, want)
+	}
+}
+
+// Every newer category comes back out of EmitBlocks as a block that decodes.
+func TestEmitBlocks_NewerCategories(t *testing.T) {
+	machine := Machine{
+		Repositories: []scan.RepositoryResult{{Provider: "brew", Name: "homebrew-cask-fonts", URL: "homebrew/cask-fonts"}},
+		Fonts:        []scan.FontResult{{Name: "Hack"}},
+		Users:        []scan.UserResult{{Name: "builder", UID: "1001"}},
+		Groups:       []scan.GroupResult{{Name: "devs", GID: "1002"}},
+		Settings: []scan.SettingResult{
+			{Tool: types.ConfigurationToolMacOSDefaults, Domain: "com.apple.dock", Key: "autohide", Kind: "bool", Value: true},
+		},
+	}
+	block, err := EmitBlocks(Compute(machine, &types.Plan{}, nil), machine, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"repositories:", "fonts:", "users:", "groups:", "configurations:", "tool: macos_defaults"} {
+		if !strings.Contains(block, want) {
+			t.Fatalf("emitted blocks lack %q:\n%s", want, block)
+		}
+	}
+	var u types.UsersData
+	start := strings.Index(block, "groups:")
+	end := strings.Index(block, "configurations:")
+	if start < 0 || end < start {
+		t.Fatalf("users block not found:\n%s", block)
+	}
+	if err := helpers.DecodeBlueprintInto([]byte(block[start:end]), "yaml", types.BlueprintTypeUsers, 0, &u); err != nil || len(u.Users) != 1 {
+		t.Fatalf("users block (%v):\n%s", err, block[start:end])
+	}
+}
@@ -45,20 +45,33 @@ type Recorder struct {
 	Err error
 	// Stdout is returned from Output.
 	Stdout string
+	// Respond, when set, answers Output in place of Stdout and Err, for a
+	// test whose commands need different answers.
+	Respond func(Call) (string, error)
+	// Fail, when set, decides Run's error in place of Err, for a test in
+	// which only some commands fail.
+	Fail func(Call) error
 }
 
 // New returns an empty Recorder.
 func New() *Recorder { return &Recorder{} }
 
-// Run records the command and returns r.Err.
+// Run records the command and returns r.Err, or what r.Fail decides.
 func (r *Recorder) Run(cmd types.Command, _ bool) error {
 	r.record(cmd)
+	if r.Fail != nil {
+		return r.Fail(r.Calls[len(r.Calls)-1])
+	}
 	return r.Err
 }
 
-// Output records the command and returns r.Stdout and r.Err.
+// Output records the command and returns r.Stdout and r.Err, or what
+// r.Respond answers.
 func (r *Recorder) Output(cmd types.Command, _ bool) (string, error) {
 	r.record(cmd)
+	if r.Respond != nil {
+		return r.Respond(r.Calls[len(r.Calls)-1])
+	}
 	return r.Stdout, r.Err
 }
 
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/system"
 	"github.com/fynxlabs/rwr/internal/types"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
@@ -20,22 +21,25 @@ import (
 )
 
 // HandleGitClone clones a Git repository to the specified path with optional
-// authentication via SSH key, GitHub API token, or OAuth.
+// authentication via SSH key, GitHub API token, or OAuth. Offline, it clones
+// the mirror rwr fetch made instead; the checkout's origin is still opts.URL.
 func HandleGitClone(opts types.GitOptions, initConfig *types.InitConfig) error {
-	var auth transport.AuthMethod
-
 	log.Debugf("Cloning Git repository: %s", opts.URL)
 
-	// Use authentication for private repos or SSH URLs
-	if opts.Private || strings.HasPrefix(opts.URL, "git@") {
+	source := opts.URL
+	var auth transport.AuthMethod
+	if system.IsOffline() {
+		source = system.CachedGitPath(opts.URL)
+		if _, err := os.Stat(source); err != nil {
+			return fmt.Errorf("%s: %w", opts.URL, system.ErrNotCached)
+		}
+		log.Debugf("Offline: cloning %s from its mirror %s", opts.URL, source)
+	} else {
 		var err error
-		auth, err = getAuthMethod(opts.URL, initConfig)
+		auth, err = cloneAuth(opts, initConfig)
 		if err != nil {
 			return fmt.Errorf("error authenticating Git clone: %w", err)
 		}
-		log.Debugf("Using authentication for Git clone: %s", opts.URL)
-	} else {
-		log.Debugf("No authentication needed for Git clone: %s", opts.URL)
 	}
 
 	targetDir := filepath.Dir(opts.Target)
@@ -47,7 +51,7 @@ func HandleGitClone(opts types.GitOptions, initConfig *types.InitConfig) error {
 	}
 
 	_, err = git.PlainClone(opts.Target, false, &git.CloneOptions{
-		URL:  opts.URL,
+		URL:  source,
 		Auth: auth,
 	})
 	if err != nil {
@@ -65,6 +69,53 @@ func HandleGitClone(opts types.GitOptions, initConfig *types.InitConfig) error {
 	return nil
 }
 
+// cloneAuth is the credential a clone of opts.URL uses: one for private
+// repositories and SSH URLs, none otherwise.
+func cloneAuth(opts types.GitOptions, initConfig *types.InitConfig) (transport.AuthMethod, error) {
+	if !opts.Private && !strings.HasPrefix(opts.URL, "git@") {
+		log.Debugf("No authentication needed for Git clone: %s", opts.URL)
+		return nil, nil
+	}
+	log.Debugf("Using authentication for Git clone: %s", opts.URL)
+	return getAuthMethod(opts.URL, initConfig)
+}
+
+// MirrorGitRepository keeps a bare mirror of opts.URL in the offline cache
+// for an offline run to clone and pull from: cloned the first time, every
+// ref fetched again after.
+func MirrorGitRepository(opts types.GitOptions, initConfig *types.InitConfig) error {
+	auth, err := cloneAuth(opts, initConfig)
+	if err != nil {
+		return fmt.Errorf("error authenticating Git mirror: %w", err)
+	}
+
+	mirror := system.CachedGitPath(opts.URL)
+	if repo, openErr := git.PlainOpen(mirror); openErr == nil {
+		err = repo.Fetch(&git.FetchOptions{
+			Auth:     auth,
+			RefSpecs: []config.RefSpec{"+refs/*:refs/*"},
+			Force:    true,
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return fmt.Errorf("error updating the mirror of %s: %v", opts.URL, err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(mirror), 0o755); err != nil { // #nosec G301 -- cache directory
+		return fmt.Errorf("error creating cache directory: %v", err)
+	}
+	_, err = git.PlainClone(mirror, true, &git.CloneOptions{
+		URL:    opts.URL,
+		Auth:   auth,
+		Mirror: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error mirroring %s: %v", opts.URL, err)
+	}
+	return nil
+}
+
 // CheckAndUpdateRemoteURL verifies and updates the origin remote URL of an
 // existing Git repository if it differs from the desired URL.
 func CheckAndUpdateRemoteURL(repoPath, desiredURL string) error {
@@ -355,7 +406,17 @@ func HandleGitPull(opts types.GitOptions, initConfig *types.InitConfig) error {
 	}
 
 	var auth transport.AuthMethod
-	if len(remote.Config().URLs) > 0 {
+	pull := &git.PullOptions{}
+	if system.IsOffline() {
+		// Offline, the pull comes from the mirror of the checkout's origin.
+		if len(remote.Config().URLs) == 0 {
+			return fmt.Errorf("repository %s has no origin URL to find a mirror by", opts.Target)
+		}
+		pull.RemoteURL = system.CachedGitPath(remote.Config().URLs[0])
+		if _, err := os.Stat(pull.RemoteURL); err != nil {
+			return fmt.Errorf("%s: %w", remote.Config().URLs[0], system.ErrNotCached)
+		}
+	} else if len(remote.Config().URLs) > 0 {
 		remoteURL := remote.Config().URLs[0]
 		// For SSH URLs (git@...) or if the repo is marked as private, use authentication
 		if strings.HasPrefix(remoteURL, "git@") || opts.Private {
@@ -369,9 +430,8 @@ func HandleGitPull(opts types.GitOptions, initConfig *types.InitConfig) error {
 		}
 	}
 
-	err = worktree.Pull(&git.PullOptions{
-		Auth: auth,
-	})
+	pull.Auth = auth
+	err = worktree.Pull(pull)
 	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
 		if pullErr := explainGitPullFailure(opts.Target, err); pullErr != nil {
 			return pullErr
@@ -0,0 +1,151 @@
+package helpers
+
+import "strings"
+
+// The ini helpers read and edit one key of a GTK/Qt-style ini file in place.
+// They work on the text rather than a parsed model so everything the
+// configuration tool does not own - comments, ordering, blank lines, keys
+// rwr has never heard of - survives a write byte for byte.
+//
+// Keys before the first section header belong to section "". Both "#" and
+// ";" start a comment line. A key that appears twice in a section resolves to
+// the last occurrence, which is what GLib's and Qt's readers do.
+
+// INIValue returns the value of key in section, and whether it is set.
+func INIValue(content, section, key string) (string, bool) {
+	value, found := "", false
+	current := ""
+	for _, line := range strings.Split(content, "\n") {
+		if name, ok := iniSection(line); ok {
+			current = name
+			continue
+		}
+		if current != section {
+			continue
+		}
+		if k, v, ok := iniKeyValue(line); ok && k == key {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
+// INIKey is one key line of an ini file.
+type INIKey struct {
+	Section string
+	Key     string
+	Value   string
+}
+
+// INIKeys lists every key line in content, in file order.
+func INIKeys(content string) []INIKey {
+	var keys []INIKey
+	current := ""
+	for _, line := range strings.Split(content, "\n") {
+		if name, ok := iniSection(line); ok {
+			current = name
+			continue
+		}
+		if k, v, ok := iniKeyValue(line); ok {
+			keys = append(keys, INIKey{Section: current, Key: k, Value: v})
+		}
+	}
+	return keys
+}
+
+// SetINIValue returns content with key in section set to value. An existing
+// line is rewritten in place, keeping its spacing around "="; a new key is
+// added after the last line of its section, and a missing section is
+// appended at the end.
+func SetINIValue(content, section, key, value string) string {
+	lines := strings.Split(content, "\n")
+	current := ""
+	sectionSeen := section == ""
+	lastInSection := -1
+	replaced := -1
+	for i, line := range lines {
+		if name, ok := iniSection(line); ok {
+			current = name
+			if name == section {
+				sectionSeen = true
+				lastInSection = i
+			}
+			continue
+		}
+		if current != section {
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lastInSection = i
+		}
+		if k, _, ok := iniKeyValue(line); ok && k == key {
+			replaced = i
+		}
+	}
+
+	if replaced >= 0 {
+		lines[replaced] = iniRewrite(lines[replaced], value)
+		return strings.Join(lines, "\n")
+	}
+
+	entry := key + "=" + value
+	if !sectionSeen {
+		body := strings.TrimRight(content, "\n")
+		if body != "" {
+			body += "\n\n"
+		}
+		return body + "[" + section + "]\n" + entry + "\n"
+	}
+	if lastInSection < 0 {
+		// No global keys yet: they go before the first section header.
+		return entry + "\n" + content
+	}
+	lines = append(lines[:lastInSection+1], append([]string{entry}, lines[lastInSection+1:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// UnsetINIValue returns content with every line setting key in section
+// removed.
+func UnsetINIValue(content, section, key string) string {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	current := ""
+	for _, line := range lines {
+		if name, ok := iniSection(line); ok {
+			current = name
+		} else if k, _, ok := iniKeyValue(line); ok && current == section && k == key {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func iniSection(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) < 2 || trimmed[0] != '[' || trimmed[len(trimmed)-1] != ']' {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[1 : len(trimmed)-1]), true
+}
+
+func iniKeyValue(line string) (key, value string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';' {
+		return "", "", false
+	}
+	key, value, ok = strings.Cut(trimmed, "=")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(value), true
+}
+
+// iniRewrite replaces the value of an existing key line, keeping everything
+// up to and including the "=" and the whitespace after it.
+func iniRewrite(line, value string) string {
+	eq := strings.Index(line, "=")
+	prefix := line[:eq+1]
+	rest := line[eq+1:]
+	return prefix + rest[:len(rest)-len(strings.TrimLeft(rest, " \t"))] + value
+}
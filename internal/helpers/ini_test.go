@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+)
+
+const gtkSettings = `# managed by hand
+[Settings]
+gtk-theme-name = Adwaita
+; keep the cursor
+gtk-cursor-theme-name=Breeze
+
+[Other]
+key=1
+`
+
+func TestINIValue(t *testing.T) {
+	if v, ok := INIValue(gtkSettings, "Settings", "gtk-theme-name"); !ok || v != "Adwaita" {
+		t.Errorf("gtk-theme-name = %q, %v", v, ok)
+	}
+	if _, ok := INIValue(gtkSettings, "Settings", "key"); ok {
+		t.Error("a key from another section was read")
+	}
+	if v, _ := INIValue("[a]\nk=1\nk=2\n", "a", "k"); v != "2" {
+		t.Errorf("duplicate key resolved to %q, want the last occurrence", v)
+	}
+}
+
+func TestSetINIValue_InPlaceKeepsEverythingElse(t *testing.T) {
+	got := SetINIValue(gtkSettings, "Settings", "gtk-theme-name", "Adwaita-dark")
+	want := `# managed by hand
+[Settings]
+gtk-theme-name = Adwaita-dark
+; keep the cursor
+gtk-cursor-theme-name=Breeze
+
+[Other]
+key=1
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSetINIValue_NewKeyAndSection(t *testing.T) {
+	got := SetINIValue(gtkSettings, "Settings", "gtk-application-prefer-dark-theme", "1")
+	if v, ok := INIValue(got, "Settings", "gtk-application-prefer-dark-theme"); !ok || v != "1" {
+		t.Fatalf("new key not readable:\n%s", got)
+	}
+	// Added after the section's last line, not after the blank separator.
+	if want := "gtk-cursor-theme-name=Breeze\ngtk-application-prefer-dark-theme=1\n\n[Other]"; !strings.Contains(got, want) {
+		t.Errorf("new key misplaced:\n%s", got)
+	}
+
+	got = SetINIValue(gtkSettings, "Appearance", "style", "Fusion")
+	if want := "key=1\n\n[Appearance]\nstyle=Fusion\n"; !strings.Contains(got, want) {
+		t.Errorf("new section not appended:\n%s", got)
+	}
+
+	if got := SetINIValue("", "Settings", "a", "b"); got != "[Settings]\na=b\n" {
+		t.Errorf("empty file = %q", got)
+	}
+	if got := SetINIValue("[s]\nk=v\n", "", "g", "1"); got != "g=1\n[s]\nk=v\n" {
+		t.Errorf("global key = %q", got)
+	}
+}
+
+func TestUnsetINIValue(t *testing.T) {
+	got := UnsetINIValue(gtkSettings, "Settings", "gtk-theme-name")
+	if _, ok := INIValue(got, "Settings", "gtk-theme-name"); ok {
+		t.Fatalf("key survived:\n%s", got)
+	}
+	if UnsetINIValue(gtkSettings, "Other", "gtk-theme-name") != gtkSettings {
+		t.Error("unsetting a key of another section changed the file")
+	}
+	if !strings.Contains(got, "; keep the cursor") {
+		t.Error("comment lost")
+	}
+}
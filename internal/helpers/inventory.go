@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// LoadInventory strictly decodes an inventory file. Every host needs an
+// address, and names must be unique: a name picks the host on the command
+// line and keys its journal copy.
+func LoadInventory(path string) (*types.Inventory, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- operator-supplied inventory
+	if err != nil {
+		return nil, fmt.Errorf("error reading inventory %s: %w", path, err)
+	}
+	format, err := FormatForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var inventory types.Inventory
+	if err := UnmarshalBlueprintStrict(data, format, &inventory); err != nil {
+		return nil, fmt.Errorf("error decoding inventory %s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i, host := range inventory.Hosts {
+		if host.Address == "" {
+			return nil, fmt.Errorf("inventory %s: hosts[%d] has no address", path, i)
+		}
+		if seen[host.Label()] {
+			return nil, fmt.Errorf("inventory %s: host %q is listed twice", path, host.Label())
+		}
+		seen[host.Label()] = true
+	}
+	return &inventory, nil
+}
@@ -1,13 +1,21 @@
 package helpers
 
 import (
+	"errors"
+	"fmt"
+	"path"
 	"slices"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/types"
 )
 
 // ShouldInclude determines if an item should be included based on active profiles.
 // An item should be included if:
 // 1. It has no profiles specified (base item - always included)
-// 2. At least one of its profiles matches an active profile
+// 2. No profile it excludes ("!server") is active, and at least one of its
+// other profiles matches - a name when it is active, an "all_of:" form when
+// every profile it lists holds - or it lists only exclusions
 // 3. "all" is in active profiles (special case to include everything).
 func ShouldInclude(itemProfiles []string, activeProfiles []string) bool {
 	// If no profiles are specified for the item, it's a base item - always include
@@ -25,9 +33,19 @@ func ShouldInclude(itemProfiles []string, activeProfiles []string) bool {
 		return true
 	}
 
+	expr, _ := parseProfiles(itemProfiles)
+	for _, excluded := range expr.exclude {
+		if slices.Contains(activeProfiles, excluded) {
+			return false
+		}
+	}
+	if len(expr.terms) == 0 {
+		return true
+	}
+
 	// Check if any of the item's profiles match active profiles
-	for _, itemProfile := range itemProfiles {
-		if slices.Contains(activeProfiles, itemProfile) {
+	for _, term := range expr.terms {
+		if term.holds(activeProfiles) {
 			return true
 		}
 	}
@@ -35,6 +53,176 @@ func ShouldInclude(itemProfiles []string, activeProfiles []string) bool {
 	return false
 }
 
+// profileExpr is an entry's `profiles` list, parsed: the alternatives any of
+// which selects the entry, and the profiles that deselect it whatever else is
+// active. An entry with exclusions and no alternatives applies unless one of
+// them is active.
+type profileExpr struct {
+	terms   []profileTerm
+	exclude []string
+}
+
+// profileTerm is one alternative: "work" requires work; "all_of:work,macos"
+// requires both, and "all_of:dev,!server" requires dev without server.
+type profileTerm struct {
+	source  string
+	require []string
+	forbid  []string
+}
+
+func (t profileTerm) holds(activeProfiles []string) bool {
+	for _, profile := range t.require {
+		if !slices.Contains(activeProfiles, profile) {
+			return false
+		}
+	}
+	for _, profile := range t.forbid {
+		if slices.Contains(activeProfiles, profile) {
+			return false
+		}
+	}
+	return true
+}
+
+// allOfPrefix starts the form of a profiles entry that requires every
+// profile it lists.
+const allOfPrefix = "all_of:"
+
+// parseProfiles parses an entry's `profiles` list. It always returns what it
+// could read, so a run filters by the rest of a list with one bad element;
+// the error is for validate to report.
+func parseProfiles(profiles []string) (profileExpr, error) {
+	var expr profileExpr
+	var errs []error
+	for _, element := range profiles {
+		element = strings.TrimSpace(element)
+		if list, ok := strings.CutPrefix(element, allOfPrefix); ok {
+			term := profileTerm{source: element}
+			for _, part := range strings.Split(list, ",") {
+				name, negated, err := profileName(part)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%q: %w", element, err))
+					continue
+				}
+				if negated {
+					term.forbid = append(term.forbid, name)
+				} else {
+					term.require = append(term.require, name)
+				}
+			}
+			if len(term.require) == 0 && len(term.forbid) == 0 {
+				continue
+			}
+			expr.terms = append(expr.terms, term)
+			continue
+		}
+		name, negated, err := profileName(element)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if negated {
+			expr.exclude = append(expr.exclude, name)
+		} else {
+			expr.terms = append(expr.terms, profileTerm{source: element, require: []string{name}})
+		}
+	}
+	return expr, errors.Join(errs...)
+}
+
+// profileName reads one profile name, reporting whether it is negated.
+func profileName(element string) (string, bool, error) {
+	element = strings.TrimSpace(element)
+	name, negated := strings.CutPrefix(element, "!")
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		// YAML reads an unquoted !server as a tag on an empty value.
+		return "", false, fmt.Errorf("empty profile name: quote a negation in YAML, as \"!server\"")
+	case strings.HasPrefix(name, "!"):
+		return "", false, fmt.Errorf("%q: a profile is negated once", element)
+	case strings.Contains(name, ":") || strings.Contains(name, ","):
+		return "", false, fmt.Errorf("%q: all_of: forms do not nest", element)
+	case name == "all":
+		return "", false, fmt.Errorf("%q: all selects everything and cannot be combined or negated", element)
+	}
+	return name, negated, nil
+}
+
+// ProfileNames returns the profile names an entry's `profiles` list refers
+// to, whether it requires or excludes them, each once and in order.
+func ProfileNames(profiles []string) []string {
+	expr, _ := parseProfiles(profiles)
+	var names []string
+	add := func(list []string) {
+		for _, name := range list {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, term := range expr.terms {
+		add(term.require)
+		add(term.forbid)
+	}
+	add(expr.exclude)
+	return names
+}
+
+// CheckProfiles reports what is wrong with an entry's `profiles` list: an
+// error when an element does not parse, and otherwise the alternatives no
+// choice of profiles can satisfy - "all_of:dev,!dev", or "dev" beside
+// "!dev". unreachable is true when that is every alternative, so only
+// --profile all selects the entry.
+func CheckProfiles(profiles []string) (never []string, unreachable bool, err error) {
+	expr, err := parseProfiles(profiles)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, term := range expr.terms {
+		forbid := slices.Concat(term.forbid, expr.exclude)
+		if slices.ContainsFunc(term.require, func(profile string) bool { return slices.Contains(forbid, profile) }) {
+			never = append(never, term.source)
+		}
+	}
+	return never, len(never) > 0 && len(never) == len(expr.terms), nil
+}
+
+// ProfilesMayCoincide reports whether two entries can apply in the same run.
+// A base entry (no profiles) always applies; two profile entries apply
+// together only when one profile selects both - selecting two profiles is the
+// operator asking for both - and neither excludes what the other requires.
+// An entry listing only exclusions is selected by any profile it does not
+// exclude.
+func ProfilesMayCoincide(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	exprA, _ := parseProfiles(a)
+	exprB, _ := parseProfiles(b)
+	termsA, termsB := exprA.terms, exprB.terms
+	if len(termsA) == 0 {
+		termsA = []profileTerm{{}}
+	}
+	if len(termsB) == 0 {
+		termsB = []profileTerm{{}}
+	}
+	for _, x := range termsA {
+		for _, y := range termsB {
+			require := append(slices.Clone(x.require), y.require...)
+			forbid := slices.Concat(x.forbid, y.forbid, exprA.exclude, exprB.exclude)
+			if slices.ContainsFunc(require, func(profile string) bool { return slices.Contains(forbid, profile) }) {
+				continue
+			}
+			if len(x.require) == 0 || len(y.require) == 0 ||
+				slices.ContainsFunc(x.require, func(profile string) bool { return slices.Contains(y.require, profile) }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // FilterByProfiles filters a slice of items that have a Profiles field based on active profiles.
 // This is a generic function that works with any type that has a Profiles []string field.
 func FilterByProfiles[T interface{ GetProfiles() []string }](items []T, activeProfiles []string) []T {
@@ -65,10 +253,8 @@ func GetUniqueProfiles[T interface{ GetProfiles() []string }](items []T) []strin
 	profileSet := make(map[string]bool)
 
 	for _, item := range items {
-		for _, profile := range item.GetProfiles() {
-			if profile != "" {
-				profileSet[profile] = true
-			}
+		for _, profile := range ProfileNames(item.GetProfiles()) {
+			profileSet[profile] = true
 		}
 	}
 
@@ -100,3 +286,77 @@ func ValidateProfiles(activeProfiles []string, availableProfiles []string) []str
 
 	return invalid
 }
+
+// ActivateProfiles decides a run's active profiles and why each is active:
+// those requested (--profile or rwr.profiles), plus those of every
+// host_profiles rule this machine matches. When neither names one,
+// default_profiles apply; when there are none of those either, no profile is
+// active and every entry applies, as before.
+//
+// Rules are matched against variables' system and facts, so facts must be
+// gathered first; a rule's `when` that cannot be evaluated is an error, as an
+// entry's is.
+func ActivateProfiles(requested []string, initConfig *types.InitConfig) ([]string, []types.ProfileActivation, error) {
+	var active []string
+	var activations []types.ProfileActivation
+	activate := func(profiles []string, reason string) {
+		for _, profile := range profiles {
+			if !slices.Contains(active, profile) {
+				active = append(active, profile)
+			}
+			activations = append(activations, types.ProfileActivation{Profile: profile, Reason: reason})
+		}
+	}
+
+	activate(requested, "--profile")
+	for _, rule := range initConfig.HostProfiles {
+		matched, err := matchProfileRule(rule, initConfig.Variables)
+		if err != nil {
+			return nil, nil, fmt.Errorf("host_profiles (%s): %w", rule.Describe(), err)
+		}
+		if matched {
+			activate(rule.Profiles, "host_profiles: "+rule.Describe())
+		}
+	}
+	if len(active) == 0 {
+		activate(initConfig.DefaultProfiles, "default_profiles")
+	}
+	return active, activations, nil
+}
+
+// matchProfileRule reports whether every selector a rule sets matches.
+func matchProfileRule(rule types.ProfileRule, variables types.Variables) (bool, error) {
+	if len(rule.Hosts) > 0 && !matchHost(rule.Hosts, variables.Facts) {
+		return false, nil
+	}
+	if rule.OS != "" && !strings.EqualFold(rule.OS, variables.System.OS) {
+		return false, nil
+	}
+	if rule.Distro != "" && !strings.EqualFold(rule.Distro, variables.System.OSFamily) {
+		return false, nil
+	}
+	if rule.When != "" {
+		return EvalWhen(rule.When, variables)
+	}
+	return true, nil
+}
+
+// matchHost matches the hostname, and its first label, against hostname
+// globs. Without gathered facts there is no hostname, and nothing matches.
+func matchHost(patterns []string, facts *types.Facts) bool {
+	if facts == nil || facts.Hostname == "" {
+		return false
+	}
+	hostname := strings.ToLower(facts.Hostname)
+	short, _, _ := strings.Cut(hostname, ".")
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if ok, _ := path.Match(pattern, hostname); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, short); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -814,3 +814,165 @@ func BenchmarkGetUniqueProfiles(b *testing.B) {
 		GetUniqueProfiles(packages)
 	}
 }
+
+func TestActivateProfiles(t *testing.T) {
+	initConfig := &types.InitConfig{
+		DefaultProfiles: []string{"personal"},
+		HostProfiles: []types.ProfileRule{
+			{Hosts: []string{"work-*"}, Profiles: []string{"work", "dev"}},
+			{OS: "linux", Distro: "Arch", Profiles: []string{"arch"}},
+			{When: ".Facts.laptop", Profiles: []string{"laptop", "dev"}},
+		},
+	}
+	initConfig.Variables.System = types.System{OS: "linux", OSFamily: "arch"}
+	initConfig.Variables.Facts = &types.Facts{Hostname: "work-1234.corp.example", Laptop: true}
+
+	active, activations, err := ActivateProfiles([]string{"gaming"}, initConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"gaming", "work", "dev", "arch", "laptop"}; !reflect.DeepEqual(active, want) {
+		t.Errorf("active = %v, want %v", active, want)
+	}
+	if activations[0] != (types.ProfileActivation{Profile: "gaming", Reason: "--profile"}) ||
+		activations[1].Reason != "host_profiles: hosts work-*" {
+		t.Errorf("activations = %+v", activations)
+	}
+	// dev is active for two reasons, and both are kept.
+	reasons := 0
+	for _, activation := range activations {
+		if activation.Profile == "dev" {
+			reasons++
+		}
+	}
+	if reasons != 2 {
+		t.Errorf("dev has %d reasons, want 2: %+v", reasons, activations)
+	}
+
+	// Nothing requested and nothing matched: the defaults.
+	initConfig.Variables.System = types.System{OS: "darwin"}
+	initConfig.Variables.Facts = &types.Facts{Hostname: "home-mini"}
+	active, activations, err = ActivateProfiles(nil, initConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(active, []string{"personal"}) || activations[0].Reason != "default_profiles" {
+		t.Errorf("defaults = %v, %+v", active, activations)
+	}
+
+	// No defaults either: no profile is active, and everything applies.
+	initConfig.DefaultProfiles = nil
+	if active, _, _ := ActivateProfiles(nil, initConfig); len(active) != 0 {
+		t.Errorf("active = %v, want none", active)
+	}
+}
+
+func TestActivateProfiles_HostGlobs(t *testing.T) {
+	tests := []struct {
+		hostname string
+		pattern  string
+		want     bool
+	}{
+		{"laptop.local", "laptop", true},
+		{"LAPTOP", "laptop", true},
+		{"work-42", "work-*", true},
+		{"homework-42", "work-*", false},
+		{"ci-runner-3", "ci-runner-[0-9]", true},
+	}
+	for _, tt := range tests {
+		initConfig := &types.InitConfig{HostProfiles: []types.ProfileRule{{Hosts: []string{tt.pattern}, Profiles: []string{"matched"}}}}
+		initConfig.Variables.Facts = &types.Facts{Hostname: tt.hostname}
+		active, _, err := ActivateProfiles(nil, initConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := slices.Contains(active, "matched"); got != tt.want {
+			t.Errorf("%q against %q = %v, want %v", tt.hostname, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestShouldInclude_Expressions(t *testing.T) {
+	tests := []struct {
+		item   []string
+		active []string
+		want   bool
+	}{
+		// Exclusion alongside a name: dev, but not on servers.
+		{[]string{"dev", "!server"}, []string{"dev"}, true},
+		{[]string{"dev", "!server"}, []string{"dev", "server"}, false},
+		{[]string{"dev", "!server"}, []string{"work"}, false},
+		// Exclusions alone: everywhere but servers.
+		{[]string{"!server"}, []string{"work"}, true},
+		{[]string{"!server"}, []string{"server"}, false},
+		{[]string{"!server"}, nil, true},
+		// all_of: work and macos.
+		{[]string{"all_of:work,macos"}, []string{"work", "macos"}, true},
+		{[]string{"all_of:work,macos"}, []string{"work"}, false},
+		{[]string{"all_of: work, !laptop", "home"}, []string{"work"}, true},
+		{[]string{"all_of: work, !laptop", "home"}, []string{"work", "laptop"}, false},
+		{[]string{"all_of: work, !laptop", "home"}, []string{"home", "laptop"}, true},
+		// all still selects everything.
+		{[]string{"dev", "!server"}, []string{"all", "server"}, true},
+	}
+	for _, tt := range tests {
+		if got := ShouldInclude(tt.item, tt.active); got != tt.want {
+			t.Errorf("ShouldInclude(%q, %q) = %v, want %v", tt.item, tt.active, got, tt.want)
+		}
+	}
+}
+
+func TestProfileNames(t *testing.T) {
+	got := ProfileNames([]string{"dev", "!server", "all_of:work,!laptop,dev"})
+	if want := []string{"dev", "work", "laptop", "server"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ProfileNames = %v, want %v", got, want)
+	}
+}
+
+func TestCheckProfiles(t *testing.T) {
+	tests := []struct {
+		profiles    []string
+		never       []string
+		unreachable bool
+		wantErr     bool
+	}{
+		{profiles: []string{"dev", "!server", "all_of:work,macos"}},
+		{profiles: []string{"dev", "!dev"}, never: []string{"dev"}, unreachable: true},
+		{profiles: []string{"all_of:dev,!dev", "home"}, never: []string{"all_of:dev,!dev"}},
+		// An unquoted !server in YAML is a tag on an empty value.
+		{profiles: []string{""}, wantErr: true},
+		{profiles: []string{"!!server"}, wantErr: true},
+		{profiles: []string{"all_of:work,all_of:macos"}, wantErr: true},
+		{profiles: []string{"!all"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		never, unreachable, err := CheckProfiles(tt.profiles)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckProfiles(%q) error = %v, wantErr %v", tt.profiles, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(never, tt.never) || unreachable != tt.unreachable {
+			t.Errorf("CheckProfiles(%q) = %q, %v; want %q, %v", tt.profiles, never, unreachable, tt.never, tt.unreachable)
+		}
+	}
+}
+
+func TestProfilesMayCoincide(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want bool
+	}{
+		{nil, []string{"dev"}, true},
+		{[]string{"linux"}, []string{"mac"}, false},
+		{[]string{"work", "dev"}, []string{"dev"}, true},
+		{[]string{"all_of:work,macos"}, []string{"macos"}, true},
+		{[]string{"server"}, []string{"!server"}, false},
+		{[]string{"dev"}, []string{"!server"}, true},
+		{[]string{"dev", "!server"}, []string{"all_of:dev,server"}, false},
+	}
+	for _, tt := range tests {
+		if got := ProfilesMayCoincide(tt.a, tt.b); got != tt.want {
+			t.Errorf("ProfilesMayCoincide(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
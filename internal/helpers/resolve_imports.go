@@ -119,6 +119,18 @@ func resolveImports[T any](
 			fileFormat = format
 		}
 
+		// Its entries' `when:` conditions hold it to the same machine too.
+		if vars := templateVariables(); vars != nil {
+			filtered, filteredFormat, skipped, whenErr := FilterWhen(data, fileFormat, *vars)
+			if whenErr != nil {
+				return nil, fmt.Errorf("error evaluating conditions in import %s: %w", fullPath, whenErr)
+			}
+			for _, entry := range skipped {
+				log.Infof("Skipping an entry of %s: when: %s", importPath, entry.When)
+			}
+			data, fileFormat = filtered, filteredFormat
+		}
+
 		imported, err := decode(data, fileFormat)
 		if err != nil {
 			return nil, fmt.Errorf("error reading import file %s: %w", fullPath, err)
@@ -12,8 +12,8 @@ import (
 )
 
 // ResolveTemplate renders a Go text template with the provided variables.
-// It exposes User, Flags, System, and UserDefined variable maps to the template.
-// A reference to a variable that does not exist is an error.
+// It exposes User, Flags, System, Facts, and UserDefined variable maps to the
+// template. A reference to a variable that does not exist is an error.
 func ResolveTemplate(templateData []byte, variables types.Variables) ([]byte, error) {
 	return resolveTemplate(templateData, variables, "error")
 }
@@ -44,11 +44,7 @@ func resolveTemplate(templateData []byte, variables types.Variables, missingKey
 		return nil, fmt.Errorf("error parsing template: %w", err)
 	}
 
-	data := make(map[string]interface{})
-	data["User"] = variables.User.ToMap()
-	data["Flags"] = variables.Flags.ToMap()
-	data["System"] = variables.System.ToMap()
-	data["UserDefined"] = variables.UserDefined
+	data := templateNamespaces(variables)
 
 	// data["Flags"] no longer carries credentials (see types.Flags.ToMap), so this
 	// dump is safe; it is the reason they must stay out of that map. Credentials
@@ -73,13 +69,25 @@ func resolveTemplate(templateData []byte, variables types.Variables, missingKey
 	return renderedTemplate.Bytes(), nil
 }
 
+// templateNamespaces is what a template renders against, minus the credentials,
+// which only blueprint rendering is given.
+func templateNamespaces(variables types.Variables) map[string]interface{} {
+	return map[string]interface{}{
+		"User":        variables.User.ToMap(),
+		"Flags":       variables.Flags.ToMap(),
+		"System":      variables.System.ToMap(),
+		"Facts":       variables.Facts.ToMap(),
+		"UserDefined": variables.UserDefined,
+	}
+}
+
 // builtinRefPattern matches simple references into the fixed template
 // namespaces - {{ .User.home }}, {{ if .System.os }} - whose keys are known at
 // validate time, unlike UserDefined's.
-var builtinRefPattern = regexp.MustCompile(`\.(User|System|Flags)\.([A-Za-z0-9_]+)`)
+var builtinRefPattern = regexp.MustCompile(`\.(User|System|Flags|Facts)\.([A-Za-z0-9_]+)`)
 
-// UnknownTemplateReferences reports references into the User, System, and
-// Flags namespaces that name no existing key. UserDefined is deliberately
+// UnknownTemplateReferences reports references into the User, System, Flags,
+// and Facts namespaces that name no existing key. UserDefined is deliberately
 // exempt: its values vary per machine, so a reference to one is not a defect
 // validate can report. The fixed namespaces have no such excuse - validate
 // used to render every namespace with missingkey=zero, so a typo like
@@ -89,6 +97,7 @@ func UnknownTemplateReferences(templateData []byte, variables types.Variables) [
 		"User":   variables.User.ToMap(),
 		"System": variables.System.ToMap(),
 		"Flags":  variables.Flags.ToMap(),
+		"Facts":  variables.Facts.ToMap(),
 	}
 
 	var unknown []string
@@ -0,0 +1,170 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// whenTemplate turns a `when:` condition into the template that evaluates it.
+// The condition is a template pipeline - `eq .Facts.gpuVendor "nvidia"`,
+// `and .Facts.laptop (ge .Facts.cpus 8)` - and may also be written in its own
+// delimiters, which the blueprint's rendering will already have reduced to
+// "true" or "false".
+func whenTemplate(expr string) (*template.Template, error) {
+	expr = strings.TrimSpace(expr)
+	if inner, ok := strings.CutPrefix(expr, "{{"); ok {
+		if inner, ok = strings.CutSuffix(inner, "}}"); ok {
+			expr = strings.TrimSpace(inner)
+		}
+	}
+	if expr == "" {
+		return nil, fmt.Errorf("when: condition is empty")
+	}
+	t, err := template.New("when").Option("missingkey=error").Parse("{{ if " + expr + " }}true{{ end }}")
+	if err != nil {
+		return nil, fmt.Errorf("when: %q does not parse: %w", expr, err)
+	}
+	return t, nil
+}
+
+// ParseWhen checks that a `when:` condition parses, without evaluating it.
+func ParseWhen(expr string) error {
+	_, err := whenTemplate(expr)
+	return err
+}
+
+// EvalWhen reports whether a `when:` condition holds for variables. Like a
+// run's template rendering, a reference to a key that does not exist is an
+// error rather than false; a custom fact a machine may not report is tested
+// with `index .Facts.custom "name"`, which is empty when it is absent.
+func EvalWhen(expr string, variables types.Variables) (bool, error) {
+	t, err := whenTemplate(expr)
+	if err != nil {
+		return false, err
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, templateNamespaces(variables)); err != nil {
+		return false, fmt.Errorf("when: %q: %w", expr, err)
+	}
+	return out.String() == "true", nil
+}
+
+// FilterWhen evaluates the `when:` condition of every entry in a resolved
+// blueprint and cuts the false ones out, returning the document that remains
+// and the entries it left out.
+//
+// Without gathered facts (variables.Facts is nil, as in validate) nothing is
+// evaluated and nothing is left out: the conditions are only checked to
+// parse, since whether one holds depends on a machine that is not this one.
+//
+// A blueprint with no condition, or none false, is returned untouched and
+// keeps its format; one that lost entries is re-encoded as JSON, as
+// subsetForProcessor does.
+func FilterWhen(data []byte, format string, variables types.Variables) ([]byte, string, []types.SkippedEntry, error) {
+	if !bytes.Contains(data, []byte("when")) {
+		return data, format, nil, nil
+	}
+	top, err := blueprintSections(data, format)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	keys := make([]string, 0, len(top))
+	for key := range top {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var skipped []types.SkippedEntry
+	for _, key := range keys {
+		entries, ok := top[key].([]interface{})
+		if !ok {
+			continue
+		}
+		kept := make([]interface{}, 0, len(entries))
+		for _, entry := range entries {
+			expr, err := entryWhen(key, entry)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			if expr == "" {
+				kept = append(kept, entry)
+				continue
+			}
+			if variables.Facts == nil {
+				if err := ParseWhen(expr); err != nil {
+					return nil, "", nil, fmt.Errorf("%s: %w", key, err)
+				}
+				kept = append(kept, entry)
+				continue
+			}
+			holds, err := EvalWhen(expr, variables)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("%s: %w", key, err)
+			}
+			if holds {
+				kept = append(kept, entry)
+				continue
+			}
+			document, err := json.Marshal(map[string]interface{}{key: []interface{}{entry}})
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("error encoding skipped %s entry: %w", key, err)
+			}
+			skipped = append(skipped, types.SkippedEntry{When: strings.TrimSpace(expr), Document: document})
+		}
+		top[key] = kept
+	}
+
+	if len(skipped) == 0 {
+		return data, format, nil, nil
+	}
+	out, err := json.Marshal(top)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error re-encoding blueprint without its skipped entries: %w", err)
+	}
+	return out, types.FormatJSON, skipped, nil
+}
+
+// blueprintSections decodes a blueprint's top level into plain JSON shapes.
+// The round trip matters for TOML, whose decoder hands back arrays of tables
+// as []map[string]interface{} rather than []interface{}.
+func blueprintSections(data []byte, format string) (map[string]interface{}, error) {
+	var decoded map[string]interface{}
+	if err := UnmarshalBlueprint(data, format, &decoded); err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("error reading blueprint sections: %w", err)
+	}
+	var top map[string]interface{}
+	if err := json.Unmarshal(encoded, &top); err != nil {
+		return nil, fmt.Errorf("error reading blueprint sections: %w", err)
+	}
+	return top, nil
+}
+
+// entryWhen returns an entry's condition, empty when it has none.
+func entryWhen(section string, entry interface{}) (string, error) {
+	fields, ok := entry.(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	switch when := fields["when"].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return when, nil
+	case bool:
+		// `when: {{ .Facts.laptop }}` renders to a bare YAML boolean.
+		return fmt.Sprint(when), nil
+	default:
+		return "", fmt.Errorf("%s: when must be a string, got %v", section, when)
+	}
+}
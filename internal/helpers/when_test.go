@@ -0,0 +1,117 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+func whenVariables() types.Variables {
+	return types.Variables{
+		System: types.System{OS: "linux"},
+		Facts: &types.Facts{
+			CPUs:      16,
+			GPUVendor: "nvidia",
+			Laptop:    true,
+			Custom:    map[string]interface{}{"role": "workstation"},
+		},
+	}
+}
+
+func TestEvalWhen(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`eq .Facts.gpuVendor "nvidia"`, true},
+		{`and .Facts.laptop (ge .Facts.cpus 8)`, true},
+		{`not .Facts.laptop`, false},
+		{`eq .System.os "darwin"`, false},
+		{`{{ eq (index .Facts.custom "role") "workstation" }}`, true},
+		// A custom fact this machine does not report is empty, not an error.
+		{`eq (index .Facts.custom "rack") "4"`, false},
+		// Already rendered by the blueprint's own template pass.
+		{"true", true},
+		{"false", false},
+	}
+	for _, tt := range tests {
+		got, err := EvalWhen(tt.expr, whenVariables())
+		if err != nil {
+			t.Errorf("EvalWhen(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("EvalWhen(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "eq (", ".Facts.gpuvendor"} {
+		if _, err := EvalWhen(expr, whenVariables()); err == nil {
+			t.Errorf("EvalWhen(%q) returned no error", expr)
+		}
+	}
+}
+
+func TestFilterWhen(t *testing.T) {
+	blueprint := []byte(`packages:
+  - name: nvidia-utils
+    action: install
+    when: eq .Facts.gpuVendor "amd"
+  - name: git
+    action: install
+  - name: tlp
+    action: install
+    when: .Facts.laptop
+`)
+	out, format, skipped, err := FilterWhen(blueprint, types.FormatYAML, whenVariables())
+	if err != nil {
+		t.Fatalf("FilterWhen: %v", err)
+	}
+	if format != types.FormatJSON || strings.Contains(string(out), "nvidia-utils") || !strings.Contains(string(out), "tlp") {
+		t.Errorf("filtered = %s (%s), want nvidia-utils cut as JSON", out, format)
+	}
+	if len(skipped) != 1 || skipped[0].When != `eq .Facts.gpuVendor "amd"` || !strings.Contains(string(skipped[0].Document), `"packages":[{`) {
+		t.Errorf("skipped = %+v", skipped)
+	}
+
+	// Nothing false: the document and its format are left alone.
+	kept := []byte("packages:\n  - name: tlp\n    when: .Facts.laptop\n")
+	out, format, skipped, err = FilterWhen(kept, types.FormatYAML, whenVariables())
+	if err != nil || format != types.FormatYAML || string(out) != string(kept) || skipped != nil {
+		t.Errorf("untouched = %s, %s, %v, %v", out, format, skipped, err)
+	}
+}
+
+// TOML decodes arrays of tables as []map[string]interface{}; they are
+// filtered all the same.
+func TestFilterWhen_TOML(t *testing.T) {
+	blueprint := []byte(`[[packages]]
+name = "nvidia-utils"
+when = 'eq .Facts.gpuVendor "amd"'
+
+[[packages]]
+name = "git"
+`)
+	_, _, skipped, err := FilterWhen(blueprint, types.FormatTOML, whenVariables())
+	if err != nil || len(skipped) != 1 {
+		t.Errorf("skipped = %+v, %v; want nvidia-utils", skipped, err)
+	}
+}
+
+// Without facts nothing is evaluated or cut, but a condition that cannot
+// parse is still reported.
+func TestFilterWhen_WithoutFactsOnlyParses(t *testing.T) {
+	variables := whenVariables()
+	variables.Facts = nil
+	blueprint := []byte("services:\n  - name: tlp\n    when: .Facts.laptop\n")
+	out, _, skipped, err := FilterWhen(blueprint, types.FormatYAML, variables)
+	if err != nil || skipped != nil || string(out) != string(blueprint) {
+		t.Errorf("without facts = %s, %v, %v", out, skipped, err)
+	}
+
+	broken := []byte("services:\n  - name: tlp\n    when: eq (\n")
+	if _, _, _, err := FilterWhen(broken, types.FormatYAML, variables); err == nil || !strings.Contains(err.Error(), "does not parse") {
+		t.Errorf("broken condition: err = %v", err)
+	}
+}
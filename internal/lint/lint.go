@@ -0,0 +1,188 @@
+// Package lint checks a resolved blueprint tree for problems no single field
+// shows: the same package installed through two managers, a service enabled
+// in one file and disabled in another, a target written twice, profiles
+// declared and never used. Validate checks each entry against its schema;
+// lint checks the entries against each other, over the stage-2 Plan.
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// Severity is how loudly a finding reports. Only errors fail a lint run.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+	// severityOff disables a rule; it is a config value, never a finding's.
+	severityOff Severity = "off"
+)
+
+// Finding is one rule violation. File is relative to the tree root, and
+// empty for findings about the init file's lint block; Line is zero when
+// the entry could not be placed.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Rule is one check. Check reports findings without a severity; Run stamps
+// the configured one.
+type Rule struct {
+	ID       string
+	Severity Severity // default
+	Summary  string
+	Check    func(t *tree) []Finding
+}
+
+// Rules lists every rule in the order Run applies them.
+func Rules() []Rule {
+	return []Rule{
+		duplicatePackage,
+		conflictingService,
+		duplicateTarget,
+		unusedProfile,
+		undeclaredProfile,
+	}
+}
+
+// Run lints a plan that has been through stage 2 - the rules read its
+// Resources - applying the init file's lint block and the suppression
+// comments in each blueprint. Findings are ordered by file and line.
+func Run(plan *types.Plan) ([]Finding, error) {
+	t := newTree(plan)
+	severities, err := configuredSeverities(t.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, rule := range Rules() {
+		severity := severities[rule.ID]
+		if severity == severityOff {
+			continue
+		}
+		for _, finding := range rule.Check(t) {
+			if t.suppressed(rule.ID, finding) {
+				continue
+			}
+			finding.Rule = rule.ID
+			finding.Severity = severity
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// configuredSeverities resolves each rule's severity: its default, unless
+// the lint block overrides it. An override naming no rule, or no severity,
+// is an error rather than a silently ignored typo.
+func configuredSeverities(config types.LintConfig) (map[string]Severity, error) {
+	severities := map[string]Severity{}
+	for _, rule := range Rules() {
+		severities[rule.ID] = rule.Severity
+	}
+	for id, value := range config.Rules {
+		if _, known := severities[id]; !known {
+			return nil, fmt.Errorf("lint.rules: unknown rule %q", id)
+		}
+		switch severity := Severity(strings.ToLower(value)); severity {
+		case SeverityError, SeverityWarning, SeverityInfo, severityOff:
+			severities[id] = severity
+		default:
+			return nil, fmt.Errorf("lint.rules.%s: %q is not error, warning, info or off", id, value)
+		}
+	}
+	return severities, nil
+}
+
+// tree is what the rules read: the plan's resources, and each blueprint's
+// text as written - not rendered - for placing findings on the lines an
+// editor shows and honouring suppressions.
+type tree struct {
+	root      string
+	resources []placed
+	config    types.LintConfig
+	files     map[string]*source // by path as the plan records it
+}
+
+// placed is a resource and the line declaring it.
+type placed struct {
+	types.Resource
+	line int
+}
+
+func newTree(plan *types.Plan) *tree {
+	t := &tree{files: map[string]*source{}}
+	if plan.Init != nil {
+		t.root = plan.Init.Init.Location
+		if plan.Init.Lint != nil {
+			t.config = *plan.Init.Lint
+		}
+	}
+	for _, files := range plan.Files {
+		for _, file := range files {
+			if _, seen := t.files[file.Path]; !seen {
+				t.files[file.Path] = parseSource(file.Raw)
+			}
+		}
+	}
+
+	// Resources come in declaration order, so the nth entry a file names
+	// "sshd" is on the nth line declaring it.
+	nth := map[[2]string]int{}
+	for _, resource := range plan.Resources {
+		p := placed{Resource: resource}
+		if src, ok := t.files[resource.File]; ok {
+			key := [2]string{resource.File, resource.Name}
+			p.line = src.lineOf(resource.Name, nth[key])
+			nth[key]++
+		}
+		t.resources = append(t.resources, p)
+	}
+	return t
+}
+
+// rel names a file relative to the tree root, for messages and findings.
+func (t *tree) rel(path string) string {
+	if t.root == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(t.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// at places a finding on the resource's entry.
+func (t *tree) at(resource placed, message string) Finding {
+	return Finding{File: t.rel(resource.File), Line: resource.line, Message: message}
+}
+
+// suppressed reports whether a comment in the finding's file disables the
+// rule there.
+func (t *tree) suppressed(rule string, finding Finding) bool {
+	for path, src := range t.files {
+		if t.rel(path) == finding.File {
+			return src.disables(rule, finding.Line)
+		}
+	}
+	return false
+}
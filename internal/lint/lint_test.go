@@ -0,0 +1,209 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/validate"
+)
+
+func lintTree(t *testing.T, files map[string]string) ([]Finding, error) {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan, err := validate.ResolveTree(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processors.ResolveStage2For(plan, nil, nil)
+	return Run(plan)
+}
+
+// summary renders findings as "rule file:line severity", one per line.
+func summary(findings []Finding) string {
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, fmt.Sprintf("%s %s:%d %s", f.Rule, f.File, f.Line, f.Severity))
+	}
+	return strings.Join(lines, "\n")
+}
+
+const lintInit = "blueprints:\n  format: yaml\n"
+
+func TestRules(t *testing.T) {
+	findings, err := lintTree(t, map[string]string{
+		"init.yaml": lintInit + "lint:\n  profiles: [work, gaming]\n",
+		"packages/linux.yaml": `packages:
+  - name: git
+    action: install
+    package_manager: apt
+  - name: steam
+    action: install
+    profiles: [gamng]
+  - name: jq
+    action: install
+`,
+		"packages/mac.yaml": `packages:
+  - name: git
+    action: install
+    package_manager: brew
+  - name: jq
+    action: install
+`,
+		"services/on.yaml":  "services:\n  - name: sshd\n    action: enable\n",
+		"services/off.yaml": "services:\n  - name: sshd\n    action: disable\n  - name: sshd\n    action: stop\n",
+		"files/rc.yaml": `files:
+  - name: .zshrc
+    action: copy
+    source: zshrc
+    target: ~/
+  - name: alt
+    action: create
+    content: x
+    target: ~/.zshrc
+  - name: .zshrc
+    action: delete
+    target: ~/
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"unused-profile :0 warning", // work
+		"unused-profile :0 warning", // gaming: steam's profile is misspelt
+		"duplicate-target files/rc.yaml:2 error",
+		"duplicate-target files/rc.yaml:6 error",
+		"duplicate-package packages/linux.yaml:2 warning",
+		"undeclared-profile packages/linux.yaml:5 warning",
+		"duplicate-package packages/mac.yaml:2 warning",
+		"conflicting-service services/off.yaml:2 error",
+		"conflicting-service services/on.yaml:2 error",
+	}, "\n")
+	if got := summary(findings); got != want {
+		t.Errorf("findings:\n%s\nwant:\n%s", got, want)
+	}
+	for _, f := range findings {
+		if f.Rule == "duplicate-package" && f.File == "packages/mac.yaml" && f.Message != "git is installed by brew here and by apt in packages/linux.yaml" {
+			t.Errorf("message = %q", f.Message)
+		}
+	}
+}
+
+// Entries in disjoint profiles never apply in the same run unless the
+// operator selects both, so they do not conflict.
+func TestDisjointProfilesDoNotConflict(t *testing.T) {
+	findings, err := lintTree(t, map[string]string{
+		"init.yaml": lintInit,
+		"packages/dev.yaml": `packages:
+  - name: git
+    action: install
+    package_manager: apt
+    profiles: [linux]
+  - name: git
+    action: install
+    package_manager: brew
+    profiles: [mac]
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("findings:\n%s", summary(findings))
+	}
+}
+
+// An entry excluding a profile never applies with one requiring it.
+func TestExcludedProfilesDoNotConflict(t *testing.T) {
+	findings, err := lintTree(t, map[string]string{
+		"init.yaml": lintInit,
+		"packages/dev.yaml": `packages:
+  - name: docker
+    action: install
+    package_manager: apt
+    profiles: ["!server"]
+  - name: docker
+    action: install
+    package_manager: snap
+    profiles: [server]
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("findings:\n%s", summary(findings))
+	}
+}
+
+func TestSuppressionAndSeverity(t *testing.T) {
+	services := `services:
+  - name: sshd
+    action: enable
+  # rwr-lint-disable-next-line conflicting-service
+  - name: sshd
+    action: disable
+  - name: cups
+    action: start
+  - name: cups
+    action: stop
+`
+	findings, err := lintTree(t, map[string]string{
+		"init.yaml":         lintInit + "lint:\n  rules:\n    conflicting-service: warning\n",
+		"services/all.yaml": services,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "conflicting-service services/all.yaml:2 warning\nconflicting-service services/all.yaml:7 warning\nconflicting-service services/all.yaml:9 warning"
+	if got := summary(findings); got != want {
+		t.Errorf("findings:\n%s\nwant:\n%s", got, want)
+	}
+
+	findings, err = lintTree(t, map[string]string{
+		"init.yaml":         lintInit,
+		"services/all.yaml": "# rwr-lint-disable\n" + services,
+	})
+	if err != nil || len(findings) != 0 {
+		t.Errorf("file-wide disable: %v\n%s", err, summary(findings))
+	}
+
+	findings, err = lintTree(t, map[string]string{
+		"init.yaml":         lintInit + "lint:\n  rules:\n    conflicting-service: off\n",
+		"services/all.yaml": services,
+	})
+	if err != nil || len(findings) != 0 {
+		t.Errorf("rule off: %v\n%s", err, summary(findings))
+	}
+
+	for _, rules := range []string{"no-such-rule: error", "duplicate-target: loud"} {
+		if _, err := lintTree(t, map[string]string{
+			"init.yaml": lintInit + "lint:\n  rules:\n    " + rules + "\n",
+		}); err == nil {
+			t.Errorf("lint.rules %q accepted", rules)
+		}
+	}
+}
+
+func TestRulesAreDocumented(t *testing.T) {
+	seen := map[string]bool{}
+	for _, rule := range Rules() {
+		if rule.ID == "" || rule.Summary == "" || rule.Check == nil || seen[rule.ID] {
+			t.Errorf("rule %+v", rule)
+		}
+		seen[rule.ID] = true
+	}
+}
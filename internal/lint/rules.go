@@ -0,0 +1,190 @@
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+var duplicatePackage = Rule{
+	ID:       "duplicate-package",
+	Severity: SeverityWarning,
+	Summary:  "A package is installed through more than one package manager",
+	Check: func(t *tree) []Finding {
+		// Only pinned managers compare: an unpinned entry uses whatever the
+		// machine's default is, and a fallback chain whichever of its
+		// managers has the package, neither of which lint knows.
+		return t.conflicts(func(r placed) (string, string, bool) {
+			installs := r.Processor == types.BlueprintTypePackages && (r.Action == types.ActionInstall || r.Action == "")
+			return r.Name, r.Provider, installs && r.Provider != "" && len(r.Candidates) == 0
+		}, func(provider string) string {
+			return "by " + provider
+		}, func(name, here string, others []string) string {
+			return fmt.Sprintf("%s is installed by %s here and %s", name, here, strings.Join(others, ", "))
+		})
+	},
+}
+
+// serviceActionDone phrases the service actions that undo each other.
+var serviceActionDone = map[string]string{
+	types.ServiceActionEnable:  "enabled",
+	types.ServiceActionDisable: "disabled",
+	types.ServiceActionStart:   "started",
+	types.ServiceActionStop:    "stopped",
+}
+
+var conflictingService = Rule{
+	ID:       "conflicting-service",
+	Severity: SeverityError,
+	Summary:  "A service is enabled and disabled, or started and stopped, by different entries",
+	Check: func(t *tree) []Finding {
+		var findings []Finding
+		for _, pair := range [][2]string{{types.ServiceActionEnable, types.ServiceActionDisable}, {types.ServiceActionStart, types.ServiceActionStop}} {
+			findings = append(findings, t.conflicts(func(r placed) (string, string, bool) {
+				return r.Name, r.Action, r.Processor == types.BlueprintTypeServices && (r.Action == pair[0] || r.Action == pair[1])
+			}, func(action string) string {
+				return serviceActionDone[action]
+			}, func(name, here string, others []string) string {
+				return fmt.Sprintf("%s is %s here and %s", name, serviceActionDone[here], strings.Join(others, ", "))
+			})...)
+		}
+		return findings
+	},
+}
+
+// writesTarget lists the files-blueprint actions that write their target.
+var writesTarget = map[string]bool{
+	types.FileActionCreate:  true,
+	types.FileActionCopy:    true,
+	types.FileActionMove:    true,
+	types.FileActionSymlink: true,
+	"template":              true,
+}
+
+var duplicateTarget = Rule{
+	ID:       "duplicate-target",
+	Severity: SeverityError,
+	Summary:  "Two entries write the same target path",
+	Check: func(t *tree) []Finding {
+		byTarget := map[string][]placed{}
+		for _, r := range t.resources {
+			if r.Processor != types.BlueprintTypeFiles || r.Location == "" || !writesTarget[r.Action] {
+				continue
+			}
+			target := filepath.Clean(system.ExpandPath(r.Location))
+			byTarget[target] = append(byTarget[target], r)
+		}
+
+		var findings []Finding
+		for _, target := range sortedKeys(byTarget) {
+			entries := byTarget[target]
+			for i, r := range entries {
+				var others []string
+				for j, other := range entries {
+					if i != j && helpers.ProfilesMayCoincide(r.Profiles, other.Profiles) {
+						others = append(others, fmt.Sprintf("%s in %s", other.Name, t.rel(other.File)))
+					}
+				}
+				if len(others) > 0 {
+					findings = append(findings, t.at(r, fmt.Sprintf("%s is written here and by %s", r.Location, strings.Join(others, ", "))))
+				}
+			}
+		}
+		return findings
+	},
+}
+
+var unusedProfile = Rule{
+	ID:       "unused-profile",
+	Severity: SeverityWarning,
+	Summary:  "A profile declared in lint.profiles tags no entry",
+	Check: func(t *tree) []Finding {
+		used := map[string]bool{}
+		for _, r := range t.resources {
+			for _, profile := range helpers.ProfileNames(r.Profiles) {
+				used[profile] = true
+			}
+		}
+		var findings []Finding
+		for _, profile := range t.config.Profiles {
+			if !used[profile] {
+				findings = append(findings, Finding{Message: fmt.Sprintf("profile %q is declared in lint.profiles but no entry uses it", profile)})
+			}
+		}
+		return findings
+	},
+}
+
+var undeclaredProfile = Rule{
+	ID:       "undeclared-profile",
+	Severity: SeverityWarning,
+	Summary:  "An entry names a profile lint.profiles does not declare",
+	Check: func(t *tree) []Finding {
+		if len(t.config.Profiles) == 0 {
+			return nil
+		}
+		declared := map[string]bool{"all": true}
+		for _, profile := range t.config.Profiles {
+			declared[profile] = true
+		}
+		var findings []Finding
+		seen := map[string]bool{}
+		for _, r := range t.resources {
+			for _, profile := range helpers.ProfileNames(r.Profiles) {
+				key := r.File + "\x00" + r.Name + "\x00" + profile
+				if declared[profile] || seen[key] {
+					continue
+				}
+				seen[key] = true
+				findings = append(findings, t.at(r, fmt.Sprintf("%s uses profile %q, which lint.profiles does not declare", r.Name, profile)))
+			}
+		}
+		return findings
+	},
+}
+
+// conflicts groups resources by key and reports each one that disagrees on
+// value with another of its group that can apply in the same run. pick
+// returns a resource's key, value and whether the rule concerns it; phrase
+// words another entry's value for the message.
+func (t *tree) conflicts(pick func(placed) (key, value string, ok bool), phrase func(value string) string, message func(key, here string, others []string) string) []Finding {
+	groups := map[string][]placed{}
+	for _, r := range t.resources {
+		if key, _, ok := pick(r); ok {
+			groups[key] = append(groups[key], r)
+		}
+	}
+
+	var findings []Finding
+	for _, key := range sortedKeys(groups) {
+		entries := groups[key]
+		for i, r := range entries {
+			_, here, _ := pick(r)
+			var others []string
+			for j, other := range entries {
+				_, there, _ := pick(other)
+				if i != j && there != here && helpers.ProfilesMayCoincide(r.Profiles, other.Profiles) {
+					others = append(others, fmt.Sprintf("%s in %s", phrase(there), t.rel(other.File)))
+				}
+			}
+			if len(others) > 0 {
+				findings = append(findings, t.at(r, message(key, here, others)))
+			}
+		}
+	}
+	return findings
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+)
+
+// directive matches a suppression comment in any format that has comments:
+//
+//	# rwr-lint-disable duplicate-package, duplicate-target   (whole file)
+//	// rwr-lint-disable-next-line conflicting-service        (next line)
+//
+// With no rule IDs it disables every rule. JSON has no comments; use the
+// init file's lint.rules there.
+var directive = regexp.MustCompile(`(?:#|//)\s*rwr-lint-disable(-next-line)?\b([^\n]*)`)
+
+// source is one blueprint's resolved text, as lint reads it.
+type source struct {
+	lines    []string
+	fileWide map[string]bool // rule → disabled; "" disables all
+	byLine   map[int]map[string]bool
+}
+
+func parseSource(raw []byte) *source {
+	src := &source{
+		lines:    strings.Split(string(raw), "\n"),
+		fileWide: map[string]bool{},
+		byLine:   map[int]map[string]bool{},
+	}
+	for i, line := range src.lines {
+		match := directive.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		rules := strings.FieldsFunc(match[2], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(rules) == 0 {
+			rules = []string{""}
+		}
+		target := src.fileWide
+		if match[1] != "" {
+			target = map[string]bool{}
+			src.byLine[i+2] = target // 1-based, the line after
+		}
+		for _, rule := range rules {
+			target[rule] = true
+		}
+	}
+	return src
+}
+
+// disables reports whether a directive turns the rule off at line.
+func (s *source) disables(rule string, line int) bool {
+	if s.fileWide[""] || s.fileWide[rule] {
+		return true
+	}
+	next := s.byLine[line]
+	return next[""] || next[rule]
+}
+
+// lineOf finds the 1-based line declaring the nth (from zero) entry of a
+// name: the lines naming it as a whole word, narrowed to name keys when any
+// are. Zero when the name is not in the text - a templated or imported entry.
+func (s *source) lineOf(name string, nth int) int {
+	if name == "" {
+		return 0
+	}
+	var keyed, named []int
+	for i, line := range s.lines {
+		if !containsWord(line, name) {
+			continue
+		}
+		named = append(named, i+1)
+		if strings.Contains(line, "name") {
+			keyed = append(keyed, i+1)
+		}
+	}
+	if len(keyed) > 0 {
+		named = keyed
+	}
+	if nth < len(named) {
+		return named[nth]
+	}
+	if len(named) > 0 {
+		return named[len(named)-1]
+	}
+	return 0
+}
+
+// containsWord reports whether word appears in line delimited by characters
+// that cannot be part of a name.
+func containsWord(line, word string) bool {
+	for offset := 0; ; {
+		i := strings.Index(line[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		if (start == 0 || !nameChar(line[start-1])) && (end == len(line) || !nameChar(line[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func nameChar(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c == '/' || c == '~' ||
+		(c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
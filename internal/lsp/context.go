@@ -0,0 +1,200 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The server reads the document as text, not as a parsed tree: the file
+// being edited is usually not valid YAML, JSON or TOML at the moment
+// completion is asked for. Where the cursor sits is worked out from
+// indentation (YAML, JSON) or the nearest table header (TOML).
+
+// cursor is what the text around a position says about it.
+type cursor struct {
+	// listKey is the list whose entry the cursor is in ("packages"), or
+	// empty at the top level. It is empty too when the cursor is deeper than
+	// an entry's fields.
+	listKey string
+	// valueOf is the key whose value the cursor is in, or empty when the
+	// cursor is where a key goes.
+	valueOf string
+	// nested reports the cursor is inside some key's value without being at
+	// an entry field or the top level.
+	nested bool
+}
+
+var (
+	// lineKey matches a key at the start of a YAML or JSON line (after its
+	// indent and any list dash).
+	lineKey = regexp.MustCompile(`^"?([A-Za-z_][\w.-]*)"?\s*:`)
+	// tomlKey matches a TOML key/value line.
+	tomlKey = regexp.MustCompile(`^"?([A-Za-z_][\w.-]*)"?\s*=`)
+	// tomlHeader matches a TOML table or array-of-tables header.
+	tomlHeader = regexp.MustCompile(`^\[\[?\s*"?([A-Za-z_][\w.-]*)"?\s*\]\]?`)
+)
+
+// lineContent splits a line into its content indent and content, dropping
+// a YAML list dash: "  - name: git" is content "name: git" at indent 4.
+func lineContent(line string) (indent int, content string, dashed bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	indent = len(line) - len(trimmed)
+	for strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+		dashed = true
+		rest := strings.TrimLeft(strings.TrimPrefix(trimmed, "-"), " \t")
+		indent += len(trimmed) - len(rest)
+		trimmed = rest
+	}
+	return indent, trimmed, dashed
+}
+
+// keyOf returns the key a line's content starts with.
+func keyOf(content string, format string) string {
+	pattern := lineKey
+	if format == "toml" {
+		pattern = tomlKey
+	}
+	if match := pattern.FindStringSubmatch(content); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// ancestors lists the keys enclosing a line, nearest first, by indentation.
+func ancestors(lines []string, row, indent int) []string {
+	var keys []string
+	for i := row - 1; i >= 0 && indent > 0; i-- {
+		if strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
+			continue
+		}
+		lineIndent, content, _ := lineContent(lines[i])
+		if lineIndent >= indent {
+			continue
+		}
+		indent = lineIndent
+		if key := keyOf(content, ""); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// analyze works out where (row, col) sits; col is a byte offset.
+func analyze(s *schema, text, format string, row, col int) cursor {
+	lines := strings.Split(text, "\n")
+	if row >= len(lines) {
+		return cursor{}
+	}
+	line := lines[row]
+	if col > len(line) {
+		col = len(line)
+	}
+	if format == "toml" {
+		return analyzeTOML(lines, row, col)
+	}
+
+	before := line[:col]
+	indent, content, dashed := lineContent(before)
+	if strings.TrimSpace(before) == "" {
+		indent = col
+	}
+	enclosing := ancestors(lines, row, indent)
+
+	var c cursor
+	if len(enclosing) > 0 {
+		if _, isList := s.entries[enclosing[0]]; isList && len(enclosing) == 1 {
+			c.listKey = enclosing[0]
+		}
+	}
+	if key := keyOf(content, format); key != "" {
+		// On a "key: value" line, past the separator.
+		c.valueOf = key
+		return c
+	}
+	if len(enclosing) == 0 || c.listKey != "" {
+		return c // where a top-level key or an entry field goes
+	}
+	if dashed || !strings.Contains(content, ":") {
+		// A list item or continuation line: the value of the enclosing key,
+		// as in a YAML block list under profiles:.
+		c.valueOf = enclosing[0]
+		if len(enclosing) > 1 {
+			if _, isList := s.entries[enclosing[1]]; isList && len(enclosing) == 2 {
+				c.listKey = enclosing[1]
+			}
+		}
+		return c
+	}
+	c.nested = true
+	return c
+}
+
+// analyzeTOML places the cursor under the nearest table header above it.
+func analyzeTOML(lines []string, row, col int) cursor {
+	var c cursor
+	for i := row - 1; i >= 0; i-- {
+		if match := tomlHeader.FindStringSubmatch(strings.TrimSpace(lines[i])); match != nil {
+			c.listKey = match[1]
+			break
+		}
+	}
+	_, content, _ := lineContent(lines[row][:col])
+	if key := keyOf(content, "toml"); key != "" {
+		c.valueOf = key
+		return c
+	}
+	// A continuation line of a multi-line array: find the key that opened
+	// it.
+	depth := 0
+	for i := row - 1; i >= 0; i-- {
+		text := lines[i]
+		depth += strings.Count(text, "]") - strings.Count(text, "[")
+		if depth < 0 {
+			_, opener, _ := lineContent(text)
+			c.valueOf = keyOf(opener, "toml")
+			return c
+		}
+		if tomlHeader.MatchString(strings.TrimSpace(text)) {
+			break
+		}
+	}
+	return c
+}
+
+// wordAt returns the token under a byte column and its byte span.
+func wordAt(line string, col int) (string, int, int) {
+	isWord := func(b byte) bool {
+		return b == '_' || b == '-' || b == '.' || b == '/' || b == '~' ||
+			(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b >= 0x80
+	}
+	if col > len(line) {
+		col = len(line)
+	}
+	start, end := col, col
+	for start > 0 && isWord(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWord(line[end]) {
+		end++
+	}
+	return line[start:end], start, end
+}
+
+// lineValue returns the text of a "key: value" or "key = value" line after
+// its separator, unquoted and without a trailing comma or comment.
+func lineValue(content, format string) string {
+	sep := ":"
+	if format == "toml" {
+		sep = "="
+	}
+	_, value, ok := strings.Cut(content, sep)
+	if !ok {
+		return ""
+	}
+	value = strings.TrimSpace(value)
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	value = strings.TrimSuffix(value, ",")
+	return strings.Trim(value, `"'`)
+}
@@ -0,0 +1,316 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// client drives a Server in-process over a pair of pipes, the way an editor
+// would over stdio.
+type client struct {
+	t             *testing.T
+	in            io.WriteCloser
+	messages      chan *message
+	nextID        int
+	notifications []*message
+	done          chan error
+}
+
+func newClient(t *testing.T, root string) *client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, in: clientOut, messages: make(chan *message, 64), done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer(nil).Serve(serverIn, serverOut)
+		_ = serverOut.Close()
+	}()
+	go func() {
+		reader := bufio.NewReader(clientIn)
+		for {
+			msg, err := readMessage(reader)
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+	c.request("initialize", map[string]string{"rootUri": pathToURI(root)}, nil)
+	c.notify("initialized", struct{}{})
+	t.Cleanup(func() {
+		c.request("shutdown", nil, nil)
+		c.notify("exit", nil)
+		select {
+		case err := <-c.done:
+			if err != nil {
+				t.Errorf("serve: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("server did not exit")
+		}
+	})
+	return c
+}
+
+func (c *client) send(msg *message) {
+	c.t.Helper()
+	if err := writeMessage(c.in, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	body, _ := json.Marshal(params)
+	c.send(&message{Method: method, Params: body})
+}
+
+// request sends a request and decodes its result into result, collecting
+// the notifications that arrive first. It returns the response error.
+func (c *client) request(method string, params, result interface{}) *responseError {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strings.TrimSpace(string(mustJSON(c.nextID))))
+	c.send(&message{ID: &id, Method: method, Params: mustJSON(params)})
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("%s: connection closed", method)
+			}
+			if msg.ID == nil {
+				c.notifications = append(c.notifications, msg)
+				continue
+			}
+			if result != nil && msg.Result != nil {
+				if err := json.Unmarshal(mustJSON(msg.Result), result); err != nil {
+					c.t.Fatal(err)
+				}
+			}
+			return msg.Error
+		case <-time.After(10 * time.Second):
+			c.t.Fatalf("%s: no response", method)
+		}
+	}
+}
+
+func mustJSON(v interface{}) json.RawMessage {
+	body, _ := json.Marshal(v)
+	return body
+}
+
+// diagnostics returns the last diagnostics published for uri.
+func (c *client) diagnostics(uri string) ([]Diagnostic, bool) {
+	var found []Diagnostic
+	published := false
+	for _, msg := range c.notifications {
+		var params publishDiagnosticsParams
+		if msg.Method != "textDocument/publishDiagnostics" || json.Unmarshal(msg.Params, &params) != nil || params.URI != uri {
+			continue
+		}
+		found, published = params.Diagnostics, true
+	}
+	return found, published
+}
+
+func (c *client) open(path string) string {
+	c.t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	uri := pathToURI(path)
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "yaml", "version": 1, "text": string(data)},
+	})
+	return uri
+}
+
+func (c *client) at(uri string, line, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{TextDocument: textDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}}
+}
+
+const packagesBlueprint = `packages:
+  - name: git
+    action: install
+    profiles: [dev, work]
+  - import: more.yaml
+`
+
+const moreBlueprint = `packages:
+  - name: vim
+    action: install
+    package_manager:
+    profiles:
+      - dev
+`
+
+func writeTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"init.yaml":              "blueprints:\n  format: yaml\n  location: .\n",
+		"packages/packages.yaml": packagesBlueprint,
+		"packages/more.yaml":     moreBlueprint,
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func labels(items []CompletionItem) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range items {
+		set[item.Label] = true
+	}
+	return set
+}
+
+// Opening a document publishes the tree's diagnostics; fixing the file on
+// disk and saving clears them.
+func TestDiagnosticsOnOpenAndSave(t *testing.T) {
+	root := writeTree(t)
+	path := filepath.Join(root, "packages", "packages.yaml")
+	broken := strings.Replace(packagesBlueprint, "action: install", "action: explode", 1)
+	if err := os.WriteFile(path, []byte(broken), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t, root)
+	uri := c.open(path)
+	c.request("textDocument/hover", c.at(uri, 0, 0), nil) // a round trip: the publish precedes its response
+
+	diagnostics, published := c.diagnostics(uri)
+	if !published || len(diagnostics) == 0 {
+		t.Fatalf("no diagnostics for the broken file: %+v", c.notifications)
+	}
+	var mentions bool
+	for _, d := range diagnostics {
+		if d.Severity == severityError && strings.Contains(d.Message, "explode") {
+			mentions = true
+		}
+	}
+	if !mentions {
+		t.Fatalf("diagnostics do not report the bad action: %+v", diagnostics)
+	}
+
+	if err := os.WriteFile(path, []byte(packagesBlueprint), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c.notify("textDocument/didSave", map[string]interface{}{"textDocument": map[string]string{"uri": uri}})
+	c.request("textDocument/hover", c.at(uri, 0, 0), nil)
+	if diagnostics, _ := c.diagnostics(uri); len(diagnostics) != 0 {
+		t.Fatalf("diagnostics not cleared after the fix: %+v", diagnostics)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	root := writeTree(t)
+	c := newClient(t, root)
+	packages := c.open(filepath.Join(root, "packages", "packages.yaml"))
+	more := c.open(filepath.Join(root, "packages", "more.yaml"))
+
+	tests := []struct {
+		name      string
+		uri       string
+		line, col int
+		want      []string
+		not       []string
+	}{
+		{"actions", packages, 2, len("    action: "), []string{"install", "remove"}, []string{"clone"}},
+		{"entry fields", packages, 2, 4, []string{"name", "package_manager", "profiles"}, []string{"repositories"}},
+		{"top-level keys", packages, 0, 0, []string{"packages", "repositories", "schema_version"}, []string{"package_manager"}},
+		{"providers", more, 3, len("    package_manager: "), []string{"apt", "brew"}, nil},
+		{"profiles inline", packages, 3, len("    profiles: [dev, "), []string{"dev", "work"}, nil},
+		{"profiles block list", more, 5, len("      - "), []string{"dev", "work"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var items []CompletionItem
+			if err := c.request("textDocument/completion", c.at(tt.uri, tt.line, tt.col), &items); err != nil {
+				t.Fatal(err.Message)
+			}
+			got := labels(items)
+			for _, want := range tt.want {
+				if !got[want] {
+					t.Errorf("missing %q in %v", want, got)
+				}
+			}
+			for _, not := range tt.not {
+				if got[not] {
+					t.Errorf("unexpected %q", not)
+				}
+			}
+		})
+	}
+}
+
+func TestHover(t *testing.T) {
+	root := writeTree(t)
+	c := newClient(t, root)
+	uri := c.open(filepath.Join(root, "packages", "packages.yaml"))
+
+	var hover Hover
+	c.request("textDocument/hover", c.at(uri, 2, 6), &hover)
+	if !strings.Contains(hover.Contents.Value, "**action**") || !strings.Contains(hover.Contents.Value, "install, remove") {
+		t.Fatalf("hover = %q", hover.Contents.Value)
+	}
+	if hover.Range == nil || hover.Range.Start.Character != 4 || hover.Range.End.Character != 10 {
+		t.Fatalf("hover range = %+v", hover.Range)
+	}
+}
+
+func TestDefinitionOfImport(t *testing.T) {
+	root := writeTree(t)
+	c := newClient(t, root)
+	uri := c.open(filepath.Join(root, "packages", "packages.yaml"))
+
+	var locations []Location
+	c.request("textDocument/definition", c.at(uri, 4, 14), &locations)
+	if len(locations) != 1 || locations[0].URI != pathToURI(filepath.Join(root, "packages", "more.yaml")) {
+		t.Fatalf("definition = %+v", locations)
+	}
+	locations = nil
+	c.request("textDocument/definition", c.at(uri, 1, 10), &locations)
+	if len(locations) != 0 {
+		t.Fatalf("definition off an import line = %+v", locations)
+	}
+}
+
+func TestRenameProfileAcrossTree(t *testing.T) {
+	root := writeTree(t)
+	c := newClient(t, root)
+	packages := c.open(filepath.Join(root, "packages", "packages.yaml"))
+	more := pathToURI(filepath.Join(root, "packages", "more.yaml"))
+
+	var edit WorkspaceEdit
+	params := renameParams{textDocumentPositionParams: c.at(packages, 3, 16), NewName: "development"}
+	if err := c.request("textDocument/rename", params, &edit); err != nil {
+		t.Fatal(err.Message)
+	}
+	if got := edit.Changes[packages]; len(got) != 1 || got[0].Range.Start != (Position{Line: 3, Character: 15}) || got[0].Range.End.Character != 18 {
+		t.Fatalf("packages.yaml edits = %+v", got)
+	}
+	if got := edit.Changes[more]; len(got) != 1 || got[0].Range.Start != (Position{Line: 5, Character: 8}) || got[0].NewText != "development" {
+		t.Fatalf("more.yaml edits = %+v", got)
+	}
+
+	if err := c.request("textDocument/rename", renameParams{textDocumentPositionParams: c.at(packages, 1, 12), NewName: "x"}, nil); err == nil {
+		t.Fatal("renaming a non-profile succeeded")
+	}
+	if err := c.request("textDocument/rename", renameParams{textDocumentPositionParams: c.at(packages, 3, 16), NewName: "bad name"}, nil); err == nil {
+		t.Fatal("renaming to an invalid name succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/blueprintschema"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// field is one key a blueprint accepts, as the schema registry's structs
+// declare it.
+type field struct {
+	Name string
+	Type string // Go type, for the hover fallback
+}
+
+// schema is what completion and hover know about the blueprint format:
+// the top-level keys, and the entry fields under each list key. A list key
+// two blueprint types share (packages in a packages blueprint and in
+// bootstrap) reads the same struct, so keying by name loses nothing.
+type schema struct {
+	topLevel  []field
+	entries   map[string][]field // list key → entry fields
+	actions   map[string][]string
+	providers []string
+}
+
+// loadSchema reflects over the latest version of every registered blueprint
+// type.
+func loadSchema() *schema {
+	s := &schema{entries: map[string][]field{}, actions: blueprintschema.Actions, providers: system.ProviderNames()}
+	seenTop := map[string]bool{}
+	for _, blueprintType := range types.RegisteredBlueprintTypes() {
+		target, err := blueprintschema.Target(blueprintType, types.LatestSchemaVersion(blueprintType))
+		if err != nil {
+			continue
+		}
+		for _, f := range blueprintschema.Fields(target) {
+			if !seenTop[f.Name] {
+				seenTop[f.Name] = true
+				s.topLevel = append(s.topLevel, field{Name: f.Name, Type: f.Type.String()})
+			}
+			if entry, ok := blueprintschema.Entry(f); ok {
+				if _, seen := s.entries[f.Name]; !seen {
+					for _, ef := range blueprintschema.Fields(entry) {
+						s.entries[f.Name] = append(s.entries[f.Name], field{Name: ef.Name, Type: ef.Type.String()})
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(s.topLevel, func(i, j int) bool { return s.topLevel[i].Name < s.topLevel[j].Name })
+	return s
+}
+
+// lookup finds a field by the list key it sits under, or at the top level
+// when listKey is empty.
+func (s *schema) lookup(listKey, name string) (field, bool) {
+	candidates := s.topLevel
+	if listKey != "" {
+		candidates = s.entries[listKey]
+	}
+	for _, f := range candidates {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// hoverText documents a field.
+func (s *schema) hoverText(listKey, name string) (string, bool) {
+	f, ok := s.lookup(listKey, name)
+	if !ok {
+		return "", false
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** `%s`", f.Name, f.Type)
+	if listKey != "" {
+		fmt.Fprintf(&b, " - in `%s` entries", listKey)
+	}
+	if doc, ok := blueprintschema.FieldDocs[f.Name]; ok {
+		b.WriteString("\n\n" + doc)
+	}
+	if f.Name == "action" {
+		if actions := s.actions[listKey]; len(actions) > 0 {
+			b.WriteString("\n\nOne of: " + strings.Join(actions, ", "))
+		}
+	}
+	return b.String(), true
+}
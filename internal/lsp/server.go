@@ -0,0 +1,460 @@
+// Package lsp is the language server behind `rwr lsp`: diagnostics from the
+// same validation `rwr validate` runs, and completion, hover, go-to-definition
+// and rename built on the schema registry and the blueprint tree on disk. It
+// speaks the Language Server Protocol over a pair of streams, stdio in
+// practice.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/blueprintschema"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/fynxlabs/rwr/internal/validate"
+)
+
+// Server holds the open documents and what was last published for them.
+type Server struct {
+	osInfo    *types.OSInfo
+	schema    *schema
+	out       io.Writer
+	workspace string
+	docs      map[string]string          // URI → text, for open documents
+	published map[string]map[string]bool // tree root → URIs with diagnostics
+	shutdown  bool
+}
+
+// NewServer returns a server that validates for the given system.
+func NewServer(osInfo *types.OSInfo) *Server {
+	return &Server{
+		osInfo:    osInfo,
+		schema:    loadSchema(),
+		docs:      map[string]string{},
+		published: map[string]map[string]bool{},
+	}
+}
+
+// Serve answers messages from in until the client sends exit or closes the
+// stream.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	reader := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit before shutdown")
+			}
+			return nil
+		}
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil {
+			continue // notifications get no response
+		}
+		response := &message{ID: msg.ID, Error: rpcErr}
+		if rpcErr == nil {
+			if result == nil {
+				result = json.RawMessage("null")
+			}
+			response.Result = result
+		}
+		if err := writeMessage(s.out, response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) (interface{}, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		if err := json.Unmarshal(msg.Params, &params); err == nil && params.RootURI != "" {
+			s.workspace = uriToPath(params.RootURI)
+		}
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   map[string]interface{}{"openClose": true, "change": 1, "save": map[string]bool{"includeText": true}},
+				"completionProvider": map[string]interface{}{"triggerCharacters": []string{":", " ", "[", ",", "\""}},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+				"renameProvider":     true,
+			},
+			"serverInfo": map[string]string{"name": "rwr"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.docs[params.TextDocument.URI] = params.TextDocument.Text
+		s.publish(params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.docs[params.TextDocument.URI] = params.ContentChanges[n-1].Text
+		}
+		return nil, nil
+	case "textDocument/didSave":
+		var params didSaveParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if params.Text != nil {
+			s.docs[params.TextDocument.URI] = *params.Text
+		}
+		s.publish(params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/didClose":
+		var params didSaveParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.completion(params), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.hover(params), nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.definition(params), nil
+	case "textDocument/rename":
+		var params renameParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		edit, err := s.rename(params)
+		if err != nil {
+			return nil, &responseError{Code: codeRequestFailed, Message: err.Error()}
+		}
+		return edit, nil
+	}
+	if msg.ID == nil || strings.HasPrefix(msg.Method, "$/") {
+		return nil, nil // unknown notifications are ignored
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// text returns a document's text: the open buffer, or the file on disk.
+func (s *Server) text(uri string) (string, bool) {
+	if text, ok := s.docs[uri]; ok {
+		return text, true
+	}
+	data, err := os.ReadFile(uriToPath(uri)) // #nosec G304 -- a file of the operator's own blueprint tree
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// format is a document's blueprint format by extension; YAML when unknown.
+func format(uri string) string {
+	if f, err := helpers.FormatForPath(uriToPath(uri)); err == nil {
+		return f
+	}
+	return types.FormatYAML
+}
+
+// publish validates the tree a document belongs to and publishes the
+// diagnostics of every file in it. Validation reads the tree from disk, so
+// it runs when a document is opened or saved, not as it is typed. Files
+// that had diagnostics last time and have none now are cleared.
+func (s *Server) publish(uri string) {
+	path := uriToPath(uri)
+	root := treeRoot(path, s.workspace)
+	results := &types.ValidationResults{}
+	if err := validate.ValidateBlueprints(root, false, results, s.osInfo); err != nil {
+		validate.AddIssue(results, types.ValidationError, err.Error(), path, 0, "")
+	}
+
+	byURI := map[string][]Diagnostic{}
+	for _, issue := range results.Issues {
+		target := issue.File
+		if target != "" && !filepath.IsAbs(target) {
+			target = filepath.Join(root, target)
+		}
+		if target == "" || !helpers.IsBlueprintFile(target) {
+			target = path // about the tree, not one file: show it where the operator is
+		}
+		fileURI := pathToURI(target)
+		byURI[fileURI] = append(byURI[fileURI], s.diagnostic(fileURI, issue))
+	}
+
+	previous := s.published[root]
+	current := map[string]bool{}
+	for fileURI, diagnostics := range byURI {
+		current[fileURI] = true
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: fileURI, Diagnostics: diagnostics})
+	}
+	stale := []string{}
+	for fileURI := range previous {
+		if !current[fileURI] {
+			stale = append(stale, fileURI)
+		}
+	}
+	if _, ok := byURI[pathToURI(path)]; !ok && !previous[pathToURI(path)] {
+		stale = append(stale, pathToURI(path)) // say "clean" for the document itself
+	}
+	sort.Strings(stale)
+	for _, fileURI := range stale {
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: fileURI, Diagnostics: []Diagnostic{}})
+	}
+	s.published[root] = current
+}
+
+// diagnostic turns a validation issue into an LSP diagnostic. An issue
+// without a line marks the file's first line.
+func (s *Server) diagnostic(uri string, issue types.ValidationIssue) Diagnostic {
+	severity := severityInformation
+	switch issue.Severity {
+	case types.ValidationError:
+		severity = severityError
+	case types.ValidationWarning:
+		severity = severityWarning
+	}
+	message := issue.Message
+	if issue.Suggestion != "" {
+		message += " (" + issue.Suggestion + ")"
+	}
+	row := issue.Line - 1
+	if row < 0 {
+		row = 0
+	}
+	var span Range
+	span.Start.Line, span.End.Line = row, row
+	if text, ok := s.text(uri); ok {
+		lines := strings.Split(text, "\n")
+		if row < len(lines) {
+			line := strings.TrimRight(lines[row], "\r")
+			span.Start.Character = utf16Column(line, len(line)-len(strings.TrimLeft(line, " \t")))
+			span.End.Character = utf16Column(line, len(line))
+		}
+	}
+	return Diagnostic{Range: span, Severity: severity, Source: "rwr", Message: message}
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	if err := writeMessage(s.out, &message{Method: method, Params: body}); err != nil {
+		log.Debugf("lsp: writing %s: %v", method, err)
+	}
+}
+
+// position resolves a request's document and cursor to text, lines and a
+// byte column.
+func (s *Server) position(params textDocumentPositionParams) (text string, lines []string, row, col int, ok bool) {
+	text, ok = s.text(params.TextDocument.URI)
+	if !ok {
+		return "", nil, 0, 0, false
+	}
+	lines = strings.Split(text, "\n")
+	row = params.Position.Line
+	if row < 0 || row >= len(lines) {
+		return "", nil, 0, 0, false
+	}
+	return text, lines, row, byteColumn(lines[row], params.Position.Character), true
+}
+
+// completion offers what fits the cursor: fields where a key goes, actions
+// after action:, providers after package_manager:, and the
+// tree's profile names inside a profiles list.
+func (s *Server) completion(params textDocumentPositionParams) []CompletionItem {
+	text, _, row, col, ok := s.position(params)
+	if !ok {
+		return []CompletionItem{}
+	}
+	uri := params.TextDocument.URI
+	c := analyze(s.schema, text, format(uri), row, col)
+	items := []CompletionItem{}
+	switch {
+	case c.valueOf == "action":
+		for _, action := range s.schema.actions[c.listKey] {
+			items = append(items, CompletionItem{Label: action, Kind: kindEnumMember, Detail: c.listKey + " action"})
+		}
+	case blueprintschema.ProviderKeys[c.valueOf]:
+		for _, provider := range s.schema.providers {
+			items = append(items, CompletionItem{Label: provider, Kind: kindValue, Detail: "provider"})
+		}
+	case c.valueOf == "profiles":
+		for _, profile := range s.profiles(uri) {
+			items = append(items, CompletionItem{Label: profile, Kind: kindValue, Detail: "profile"})
+		}
+	case c.valueOf == "" && !c.nested:
+		fields := s.schema.topLevel
+		if c.listKey != "" {
+			fields = s.schema.entries[c.listKey]
+		}
+		for _, f := range fields {
+			item := CompletionItem{Label: f.Name, Kind: kindField, Detail: f.Type}
+			if doc, ok := s.schema.hoverText(c.listKey, f.Name); ok {
+				item.Documentation = &MarkupContent{Kind: "markdown", Value: doc}
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// profiles lists the profile names declared anywhere in the document's
+// tree.
+func (s *Server) profiles(uri string) []string {
+	root := treeRoot(uriToPath(uri), s.workspace)
+	seen := map[string]bool{}
+	var names []string
+	for _, path := range blueprintFiles(root) {
+		fileURI := pathToURI(path)
+		text, ok := s.text(fileURI)
+		if !ok {
+			continue
+		}
+		for _, token := range profileTokens(text, format(fileURI)) {
+			if !seen[token.Name] {
+				seen[token.Name] = true
+				names = append(names, token.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hover documents the field whose key is under the cursor.
+func (s *Server) hover(params textDocumentPositionParams) *Hover {
+	text, lines, row, col, ok := s.position(params)
+	if !ok {
+		return nil
+	}
+	line := lines[row]
+	word, start, end := wordAt(line, col)
+	_, content, _ := lineContent(line)
+	fileFormat := format(params.TextDocument.URI)
+	if word == "" || keyOf(content, fileFormat) != word || start > len(line)-len(content)+1 {
+		return nil
+	}
+	// The field's context is the cursor's at the start of its key.
+	c := analyze(s.schema, text, fileFormat, row, start)
+	doc, ok := s.schema.hoverText(c.listKey, word)
+	if !ok {
+		return nil
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: doc},
+		Range: &Range{
+			Start: Position{Line: row, Character: utf16Column(line, start)},
+			End:   Position{Line: row, Character: utf16Column(line, end)},
+		},
+	}
+}
+
+// definition jumps from an import: line to the file it imports, which
+// resolves relative to the importing file's directory.
+func (s *Server) definition(params textDocumentPositionParams) []Location {
+	_, lines, row, _, ok := s.position(params)
+	if !ok {
+		return []Location{}
+	}
+	fileFormat := format(params.TextDocument.URI)
+	_, content, _ := lineContent(lines[row])
+	if keyOf(content, fileFormat) != "import" {
+		return []Location{}
+	}
+	target := lineValue(content, fileFormat)
+	if target == "" {
+		return []Location{}
+	}
+	path := filepath.Join(filepath.Dir(uriToPath(params.TextDocument.URI)), target)
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return []Location{}
+	}
+	return []Location{{URI: pathToURI(path)}}
+}
+
+// rename renames the profile under the cursor everywhere the tree declares
+// it.
+func (s *Server) rename(params renameParams) (*WorkspaceEdit, error) {
+	text, _, row, col, ok := s.position(params.textDocumentPositionParams)
+	if !ok {
+		return nil, fmt.Errorf("document not available")
+	}
+	uri := params.TextDocument.URI
+	var old string
+	for _, token := range profileTokens(text, format(uri)) {
+		if token.Line == row && col >= token.Start && col <= token.End {
+			old = token.Name
+			break
+		}
+	}
+	if old == "" {
+		return nil, fmt.Errorf("only profile names can be renamed; the cursor is not on one")
+	}
+	if !validProfileName.MatchString(params.NewName) {
+		return nil, fmt.Errorf("%q is not a valid profile name", params.NewName)
+	}
+
+	edit := &WorkspaceEdit{Changes: map[string][]TextEdit{}}
+	for _, path := range blueprintFiles(treeRoot(uriToPath(uri), s.workspace)) {
+		fileURI := pathToURI(path)
+		fileText, ok := s.text(fileURI)
+		if !ok {
+			continue
+		}
+		lines := strings.Split(fileText, "\n")
+		for _, token := range profileTokens(fileText, format(fileURI)) {
+			if token.Name != old {
+				continue
+			}
+			line := lines[token.Line]
+			edit.Changes[fileURI] = append(edit.Changes[fileURI], TextEdit{
+				Range: Range{
+					Start: Position{Line: token.Line, Character: utf16Column(line, token.Start)},
+					End:   Position{Line: token.Line, Character: utf16Column(line, token.End)},
+				},
+				NewText: params.NewName,
+			})
+		}
+	}
+	return edit, nil
+}
@@ -0,0 +1,143 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/helpers"
+)
+
+// profileToken is one profile name as it appears in a document.
+type profileToken struct {
+	Name       string
+	Line       int
+	Start, End int // byte columns of the name, without quotes
+}
+
+// profileItem matches one name in a profiles list: quoted or bare.
+var profileItem = regexp.MustCompile(`"([^"]*)"|'([^']*)'|([^\s,\[\]"'#]+)`)
+
+// validProfileName is what rename accepts as a new profile name.
+var validProfileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// profileTokens finds every profile name a document declares: inline
+// lists after a profiles key (spanning lines until the bracket closes), and
+// YAML block lists under one.
+func profileTokens(text, format string) []profileToken {
+	lines := strings.Split(text, "\n")
+	var tokens []profileToken
+	sep := ":"
+	if format == "toml" {
+		sep = "="
+	}
+	for row := 0; row < len(lines); row++ {
+		indent, content, _ := lineContent(lines[row])
+		if keyOf(content, format) != "profiles" {
+			continue
+		}
+		offset := len(lines[row]) - len(content) + strings.Index(content, sep) + 1
+		rest := lines[row][offset:]
+		trimmed := strings.TrimSpace(rest)
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			// Inline list, possibly continued on following lines.
+			start := offset + strings.Index(rest, "[") + 1
+			for ; row < len(lines); row, start = row+1, 0 {
+				segment := lines[row][start:]
+				closing := strings.Index(segment, "]")
+				if closing >= 0 {
+					segment = segment[:closing]
+				}
+				tokens = append(tokens, lineTokens(segment, row, start)...)
+				if closing >= 0 {
+					break
+				}
+			}
+		case trimmed == "" && format != "json" && format != "toml":
+			// YAML block list: "- name" lines indented at least as far as
+			// the key.
+			for next := row + 1; next < len(lines); next++ {
+				line := lines[next]
+				stripped := strings.TrimSpace(line)
+				if stripped == "" || strings.HasPrefix(stripped, "#") {
+					continue
+				}
+				itemIndent := len(line) - len(strings.TrimLeft(line, " \t"))
+				if !strings.HasPrefix(stripped, "- ") || itemIndent < indent || strings.Contains(stripped, ": ") {
+					break
+				}
+				at := itemIndent + 2
+				tokens = append(tokens, lineTokens(line[at:], next, at)...)
+				row = next
+			}
+		}
+	}
+	return tokens
+}
+
+// lineTokens reads the profile names in one line segment that starts at
+// byte column base.
+func lineTokens(segment string, row, base int) []profileToken {
+	if i := strings.Index(segment, "#"); i >= 0 && !strings.ContainsAny(segment[:i], `"'`) {
+		segment = segment[:i]
+	}
+	var tokens []profileToken
+	for _, match := range profileItem.FindAllStringSubmatchIndex(segment, -1) {
+		for group := 1; group <= 3; group++ {
+			if start, end := match[2*group], match[2*group+1]; start >= 0 && end > start {
+				tokens = append(tokens, profileToken{Name: segment[start:end], Line: row, Start: base + start, End: base + end})
+			}
+		}
+	}
+	return tokens
+}
+
+// treeRoot finds the blueprint tree a document belongs to: the nearest
+// directory up from it holding an init file or a manifest, not going above
+// the workspace root. Without one, the workspace root, then the document's
+// own directory.
+func treeRoot(path, workspace string) string {
+	dir := filepath.Dir(path)
+	for {
+		for _, name := range append(helpers.CandidateFilenames("init"), helpers.CandidateFilenames("manifest")...) {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir || dir == workspace {
+			break
+		}
+		dir = parent
+	}
+	if workspace != "" {
+		return workspace
+	}
+	return filepath.Dir(path)
+}
+
+// blueprintFiles lists the blueprint files under root, skipping hidden
+// directories.
+func blueprintFiles(root string) []string {
+	var files []string
+	_ = filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr // an unreadable directory holds nothing to offer
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if helpers.IsBlueprintFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
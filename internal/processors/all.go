@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"charm.land/log/v2"
@@ -118,6 +119,15 @@ func All(initConfig *types.InitConfig, osInfo *types.OSInfo, runOrder []string)
 		}
 	}
 
+	// Offline, everything the run would download has to be in the cache
+	// before anything changes: finding the first missing package halfway
+	// through leaves a machine half set up with no network to finish it.
+	if system.IsOffline() {
+		if err := checkOfflineCache(initConfig, osInfo, blueprintRunOrder); err != nil {
+			return err
+		}
+	}
+
 	// Process package managers first if specified
 	if initConfig.PackageManagers != nil {
 		log.Debugf("Processing package managers")
@@ -150,6 +160,13 @@ func All(initConfig *types.InitConfig, osInfo *types.OSInfo, runOrder []string)
 		}
 	}
 
+	// Skipped entries are named against the provider their processor would
+	// have used, as the plan names them.
+	defaults := providerDefaults{available: system.GetAvailableProviders(), chain: initConfig.DefaultPackageManager}
+	if provider, ok := defaultProviderFor(osInfo, defaults.available); ok {
+		defaults.provider = provider.Name
+	}
+
 	// Process each blueprint in order
 	for _, processor := range blueprintRunOrder {
 		if files, ok := fileOrder[processor]; ok {
@@ -205,6 +222,16 @@ func All(initConfig *types.InitConfig, osInfo *types.OSInfo, runOrder []string)
 					return fatal(fmt.Errorf("error preparing %s for the %s processor: %w", blueprintFile, processor, err))
 				}
 
+				// Entries whose `when:` is false on this machine never reach
+				// the processor; they are reported skipped with the condition
+				// as the reason.
+				var skipped []types.SkippedEntry
+				resolvedBlueprint, format, skipped, err = helpers.FilterWhen(resolvedBlueprint, format, initConfig.Variables)
+				if err != nil {
+					return fatal(fmt.Errorf("error evaluating conditions in %s: %w", blueprintFile, err))
+				}
+				reportWhenSkipped(processor, types.ResolvedFile{Path: blueprintFile, Skipped: skipped}, defaults, initConfig.Variables.Flags.RunOnceLocation)
+
 				// Checked per file rather than only per command: a cancelled
 				// run should stop reading and decoding blueprints too, not
 				// grind through the rest of the tree refusing one command at a
@@ -224,7 +251,7 @@ func All(initConfig *types.InitConfig, osInfo *types.OSInfo, runOrder []string)
 					case types.BlueprintTypeServices:
 						return ProcessServices(resolvedBlueprint, blueprintDir, format, osInfo, initConfig)
 					case types.BlueprintTypeUsers:
-						return ProcessUsers(resolvedBlueprint, blueprintDir, format, initConfig)
+						return ProcessUsers(resolvedBlueprint, blueprintDir, format, osInfo, initConfig)
 					case types.BlueprintTypeGit:
 						return ProcessGitRepositories(resolvedBlueprint, blueprintDir, format, initConfig)
 					case types.BlueprintTypeScripts:
@@ -276,6 +303,16 @@ func All(initConfig *types.InitConfig, osInfo *types.OSInfo, runOrder []string)
 					break
 				}
 			}
+			// --prune follows the packages it prunes against, inside their
+			// processor: its removals are package removals, on the same lanes.
+			if processor == types.BlueprintTypePackages && initConfig.Variables.Flags.Prune && !system.Cancelled() {
+				if err := prunePackages(initConfig, osInfo); err != nil {
+					if procErr == nil {
+						procErr = err
+					}
+					stepErrs = append(stepErrs, types.StepError{Processor: processor, Err: err})
+				}
+			}
 			reporting.Emit(reporting.ProcFinished{Processor: processor, Err: procErr, Dur: time.Since(procStarted)})
 		}
 	}
@@ -343,9 +380,13 @@ func All(initConfig *types.InitConfig, osInfo *types.OSInfo, runOrder []string)
 // A misspelled profile name was silent: FilterByProfiles matched nothing, every
 // profile-scoped entry was skipped, and the run reported success having installed
 // only the base items. A mistyped profile looked exactly like a working run.
+//
+// Profiles the init file activates - host_profiles, default_profiles - are
+// only warned about: a shared init file may name a profile this tree has no
+// use for, and the machine matching a rule is not a typo.
 func checkRequestedProfiles(initConfig *types.InitConfig) error {
-	requested := initConfig.Variables.Flags.Profiles
-	if len(requested) == 0 {
+	requested, automatic := splitActivations(initConfig)
+	if len(requested) == 0 && len(automatic) == 0 {
 		return nil
 	}
 
@@ -357,6 +398,12 @@ func checkRequestedProfiles(initConfig *types.InitConfig) error {
 		return nil
 	}
 
+	if len(summary.Names) > 0 {
+		for _, profile := range helpers.ValidateProfiles(automatic, summary.Names) {
+			log.Warnf("Profile %q is active (%s), but no blueprint in this tree declares it", profile, activationReason(initConfig, profile))
+		}
+	}
+
 	invalid := helpers.ValidateProfiles(requested, summary.Names)
 	if len(invalid) == 0 {
 		return nil
@@ -372,3 +419,30 @@ func checkRequestedProfiles(initConfig *types.InitConfig) error {
 
 	return fmt.Errorf("no profile named %v exists in this blueprint tree; available profiles: %v", invalid, summary.Names)
 }
+
+// splitActivations separates the profiles the operator asked for from those
+// the init file activated. A config built without activations (tests, older
+// callers) counts every active profile as requested.
+func splitActivations(initConfig *types.InitConfig) (requested, automatic []string) {
+	if initConfig.ActiveProfiles == nil {
+		return initConfig.Variables.Flags.Profiles, nil
+	}
+	for _, activation := range initConfig.ActiveProfiles {
+		if activation.Reason == "--profile" {
+			requested = append(requested, activation.Profile)
+		} else if !slices.Contains(automatic, activation.Profile) {
+			automatic = append(automatic, activation.Profile)
+		}
+	}
+	return requested, automatic
+}
+
+// activationReason is the first reason a profile is active.
+func activationReason(initConfig *types.InitConfig, profile string) string {
+	for _, activation := range initConfig.ActiveProfiles {
+		if activation.Profile == profile {
+			return activation.Reason
+		}
+	}
+	return ""
+}
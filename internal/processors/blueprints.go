@@ -366,6 +366,8 @@ var blueprintKeyToType = map[string]string{
 	"fonts":          types.BlueprintTypeFonts,
 	"users":          types.BlueprintTypeUsers,
 	"groups":         types.BlueprintTypeUsers,
+	"sudoers":        types.BlueprintTypeUsers,
+	"polkit":         types.BlueprintTypeUsers,
 	"configurations": types.BlueprintTypeConfiguration,
 }
 
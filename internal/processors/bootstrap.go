@@ -96,6 +96,15 @@ func ProcessBootstrap(blueprintFile string, initConfig *types.InitConfig, osInfo
 		format = derived
 	}
 
+	blueprintData, format, skipped, err := helpers.FilterWhen(blueprintData, format, initConfig.Variables)
+	if err != nil {
+		log.Errorf("Error evaluating conditions in bootstrap file: %v", err)
+		return err
+	}
+	for _, entry := range skipped {
+		log.Infof("Skipping a bootstrap entry: when: %s", entry.When)
+	}
+
 	// Unmarshal the blueprint data
 	log.Debugf("Unmarshaling bootstrap data from %s", blueprintFile)
 	err = helpers.DecodeBlueprintInto(blueprintData, format,
@@ -163,7 +172,7 @@ func ProcessBootstrap(blueprintFile string, initConfig *types.InitConfig, osInfo
 
 	// Process users/groups
 	log.Debugf("Processing users/groups from %s", blueprintFile)
-	err = processUsers(bootstrapData.Users, initConfig, usersTrack)
+	err = processUsers(bootstrapData.Users, osInfo, initConfig, usersTrack)
 	if err != nil {
 		log.Errorf("Error processing groups: %v", err)
 		return err
@@ -3,11 +3,9 @@ package processors
 import (
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -21,7 +19,9 @@ import (
 )
 
 // ProcessConfiguration applies desktop environment settings from blueprint data.
-// It supports dconf, gsettings, macOS defaults, and Windows registry operations.
+// It supports dconf, gsettings, macOS defaults, and Windows registry operations,
+// plus KDE, xfconf and ini. Every entry that can be read back goes through
+// configuration_readback.go first, so only a key that differs is written.
 func ProcessConfiguration(blueprintData []byte, blueprintDir string, format string, initConfig *types.InitConfig) error {
 	var configData types.ConfigData
 
@@ -34,9 +34,26 @@ func ProcessConfiguration(blueprintData []byte, blueprintDir string, format stri
 	configurations := helpers.FilterByProfiles(configData.Configurations, initConfig.Variables.Flags.Profiles)
 
 	track := newProgress(types.BlueprintTypeConfiguration)
-	track.expect("", len(configurations))
+	track.expect("", configItemCount(configurations))
 
 	for _, config := range configurations {
+		// Read-back entries report for themselves, dry run included: they
+		// read the current value either way, so a dry run can show old -> new.
+		if readsBack(config) {
+			var err error
+			switch config.Tool {
+			case types.ConfigurationToolDconf:
+				err = applyDconf(blueprintDir, config, initConfig, track)
+			case types.ConfigurationToolGSettings:
+				applyGSettings(config, initConfig, track)
+			default:
+				err = applyConfigSetting(config, initConfig, track)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
 		if system.IsDryRun() {
 			log.Infof("[DRY-RUN] Would apply %s configuration: %s", config.Tool, config.Name)
 			track.item("", config.Name, "configure", types.StatusPlanned, "dry-run", 0)
@@ -51,38 +68,31 @@ func ProcessConfiguration(blueprintData []byte, blueprintDir string, format stri
 			continue
 		}
 
+		// What is left cannot be read back: a defaults entry of a plist kind,
+		// or an unknown tool.
 		started := time.Now()
-		var err error
-		switch config.Tool {
-		case "dconf":
-			err = processDconf(blueprintDir, config, initConfig)
-		case "gsettings":
-			err = processGSettings(config)
-		case "macos_defaults":
+		if config.Tool == types.ConfigurationToolMacOSDefaults {
 			err = processMacOSDefaults(config, initConfig)
-		case "windows_registry":
-			err = processWindowsRegistry(config, initConfig)
-		default:
+		} else {
 			err = fmt.Errorf("unsupported configuration tool: %s", config.Tool)
 		}
-
 		if err != nil {
 			log.Errorf("Error processing configuration %s: %v", config.Name, err)
 			track.item("", config.Name, "configure", types.StatusFailed, err.Error(), time.Since(started))
 			return fmt.Errorf("error processing configuration %s: %w", config.Name, err)
 		}
-		if config.Tool == "gsettings" {
-			// processGSettings records per-key failures in the ledger and still
-			// returns nil, so the entry's own outcome is not knowable here.
-			track.item("", config.Name, "configure", types.StatusUnknown, "per-key results recorded in the ledger", time.Since(started))
-		} else {
-			track.item("", config.Name, "configure", types.StatusOK, "", time.Since(started))
-		}
+		track.item("", config.Name, "configure", types.StatusOK, "", time.Since(started))
 	}
 
 	return nil
 }
 
+// dconfMarker is the file whose presence means a run_once dconf entry has
+// been applied.
+func dconfMarker(config types.Configuration, initConfig *types.InitConfig) string {
+	return filepath.Join(initConfig.Variables.Flags.RunOnceLocation, "configuration_"+config.Name+"_bootstrap")
+}
+
 func processDconf(blueprintDir string, config types.Configuration, initConfig *types.InitConfig) error {
 	log.Debugf("Processing Dconf file: %s", config.File)
 
@@ -91,9 +101,7 @@ func processDconf(blueprintDir string, config types.Configuration, initConfig *t
 
 	log.Debugf("Dconf file set for path: %s", file)
 
-	boostrapfileName := "configuration_" + config.Name + "_bootstrap"
-
-	bootstrapFile := filepath.Join(initConfig.Variables.Flags.RunOnceLocation, boostrapfileName)
+	bootstrapFile := dconfMarker(config, initConfig)
 
 	if config.RunOnce {
 		log.Debugf("RunOnce Set: Checking for %s to see if already ran", bootstrapFile)
@@ -133,49 +141,6 @@ func processDconf(blueprintDir string, config types.Configuration, initConfig *t
 	return nil
 }
 
-func processGSettings(config types.Configuration) error {
-	log.Debugf("Processing gsettings configuration: %s", config.Name)
-
-	for key, value := range config.Settings {
-		log.Debugf("Processing key: %s with value: %v", key, value)
-
-		// Check if the key is writable
-		checkCmd := exec.Command("gsettings", "writable", config.Schema, key) // #nosec G204 -- argv, not a shell string: schema/key/value are passed as discrete arguments
-		output, err := checkCmd.CombinedOutput()
-		if err != nil {
-			recordFailure("configuration", config.Schema+"."+key,
-				fmt.Errorf("checking whether the key is writable: %v (%s)", err, strings.TrimSpace(string(output))))
-			continue
-		}
-
-		if strings.TrimSpace(string(output)) != "true" {
-			recordFailure("configuration", config.Schema+"."+key, errors.New("gsetting is not writable"))
-			continue
-		}
-
-		// Convert the value to a string and escape it properly
-		strValue := formatGSettingsValue(value)
-		log.Debugf("Formatted value: %s", strValue)
-
-		args := []string{"set", config.Schema, key, strValue}
-		log.Debugf("Executing command: gsettings %s", strings.Join(args, " "))
-
-		cmd := exec.Command("gsettings", args...) // #nosec G204 -- argv, not a shell string: schema/key/value are passed as discrete arguments
-		output, err = cmd.CombinedOutput()
-		if err != nil {
-			// Every failure here used to be logged and then discarded: this
-			// function returned nil unconditionally, so a run in which no setting
-			// applied still reported success.
-			recordFailure("configuration", config.Schema+"."+key,
-				fmt.Errorf("applying value %s: %v (%s)", strValue, err, strings.TrimSpace(string(output))))
-		} else {
-			log.Debugf("Successfully applied gsettings - Schema: %s, Key: %s, Value: %s", config.Schema, key, strValue)
-		}
-	}
-
-	return nil
-}
-
 func formatGSettingsValue(value interface{}) string {
 	switch v := value.(type) {
 	case string:
@@ -0,0 +1,454 @@
+// The read-back layer every configuration tool goes through. Each entry's key
+// is read before it is written, so an unchanged key is reported as present
+// instead of rewritten, a dry run shows old -> new, and the journal carries the
+// value that was replaced. kde, xfconf and ini write here too; dconf, gsettings,
+// macos_defaults and windows_registry reach their writers in configuration.go.
+
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/status"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// configSettingWriters writes one declared value of a single-key entry, per
+// tool. A gsettings entry is applied one key at a time through here; dconf
+// loads a whole keyfile and has its own path, applyDconf.
+var configSettingWriters = map[string]func(config types.Configuration, value string, set bool, initConfig *types.InitConfig) error{
+	types.ConfigurationToolKDE:             writeKDESetting,
+	types.ConfigurationToolXfconf:          writeXfconfSetting,
+	types.ConfigurationToolINI:             writeINISetting,
+	types.ConfigurationToolGSettings:       writeGSetting,
+	types.ConfigurationToolMacOSDefaults:   writeDefaultsSetting,
+	types.ConfigurationToolWindowsRegistry: writeRegistrySetting,
+}
+
+// readsBack reports whether an entry can be read back and compared. A
+// defaults entry of a plist kind (array, dict, ...) cannot, and is written
+// every run the way it always was.
+func readsBack(config types.Configuration) bool {
+	if config.Action != "" && config.Action != types.ConfigurationActionSet {
+		return false
+	}
+	switch config.Tool {
+	case types.ConfigurationToolDconf:
+		return true
+	case types.ConfigurationToolMacOSDefaults:
+		return status.ReadableDefaultsKind(config.Kind)
+	}
+	_, ok := configSettingWriters[config.Tool]
+	return ok
+}
+
+// configQuery reads through the executor, with the entry's elevation: an
+// elevated dconf or defaults entry writes root's settings, so that is what
+// has to be read.
+func configQuery(initConfig *types.InitConfig, elevated bool) status.ConfigQuery {
+	return status.ConfigQuery{
+		Run: func(cmd types.Command) (string, error) {
+			cmd.Elevated = elevated
+			return system.RunCommandOutput(cmd, initConfig.Variables.Flags.Debug)
+		},
+		Have: commandExists,
+	}
+}
+
+// configItemCount is the number of items a set of entries reports: one per
+// gsettings key, one per other entry.
+func configItemCount(configurations []types.Configuration) int {
+	n := 0
+	for _, config := range configurations {
+		if config.Tool == types.ConfigurationToolGSettings && readsBack(config) {
+			n += len(config.Settings)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// gsettingsKeys splits a gsettings entry into one single-key entry per
+// setting, named "<name>/<key>", in key order.
+func gsettingsKeys(config types.Configuration) []types.Configuration {
+	keys := make([]string, 0, len(config.Settings))
+	for key := range config.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([]types.Configuration, 0, len(keys))
+	for _, key := range keys {
+		entry := config
+		entry.Name = config.Name + "/" + key
+		entry.Key = key
+		entry.Value = config.Settings[key]
+		entry.Settings = nil
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// applyGSettings applies a gsettings entry key by key. A key that fails is
+// recorded and the rest still apply, as they always have.
+func applyGSettings(config types.Configuration, initConfig *types.InitConfig, track *progress) {
+	for _, entry := range gsettingsKeys(config) {
+		if err := applyConfigSetting(entry, initConfig, track); err != nil {
+			recordFailure("configuration", config.Schema+"."+entry.Key, err)
+		}
+	}
+}
+
+// desiredConfigValue renders the declared value the way the tool prints it
+// back: GVariant text for gsettings, the registry write form for the
+// registry.
+func desiredConfigValue(config types.Configuration) (string, error) {
+	switch config.Tool {
+	case types.ConfigurationToolGSettings:
+		return formatGSettingsValue(config.Value), nil
+	case types.ConfigurationToolWindowsRegistry:
+		return registryValueString(strings.ToLower(config.Type), config.Value)
+	}
+	return status.ConfigValueString(config.Value), nil
+}
+
+// plannedSetting is one read-back item as the plan lists it.
+type plannedSetting struct {
+	name    string
+	setting types.Configuration
+	desired string
+}
+
+// plannedSettings lists the items an entry reports, addressed for status to
+// read back, or nil when the entry cannot be read back. blueprintDir resolves
+// a dconf keyfile the way the processor does.
+func plannedSettings(config types.Configuration, blueprintDir string) []plannedSetting {
+	if config.Name == "" || !readsBack(config) {
+		return nil
+	}
+	switch config.Tool {
+	case types.ConfigurationToolDconf:
+		config.File = filepath.Join(blueprintDir, config.File)
+		return []plannedSetting{{name: config.Name, setting: config}}
+	case types.ConfigurationToolGSettings:
+		var planned []plannedSetting
+		for _, entry := range gsettingsKeys(config) {
+			planned = append(planned, plannedSetting{name: entry.Name, setting: entry, desired: formatGSettingsValue(entry.Value)})
+		}
+		return planned
+	}
+	desired, err := desiredConfigValue(config)
+	if err != nil {
+		return nil
+	}
+	if config.Tool == types.ConfigurationToolINI {
+		config.File = system.ExpandPath(config.File)
+	}
+	return []plannedSetting{{name: config.Name, setting: config, desired: desired}}
+}
+
+// applyConfigSetting reads, compares and - only when the value differs -
+// writes one read-back entry, reporting the outcome itself.
+func applyConfigSetting(config types.Configuration, initConfig *types.InitConfig, track *progress) error {
+	started := time.Now()
+	fail := func(err error) error {
+		log.Errorf("Error processing configuration %s: %v", config.Name, err)
+		track.item("", config.Name, "configure", types.StatusFailed, err.Error(), time.Since(started))
+		return fmt.Errorf("error processing configuration %s: %w", config.Name, err)
+	}
+
+	if err := checkConfigSetting(config); err != nil {
+		return fail(err)
+	}
+	desired, err := desiredConfigValue(config)
+	if err != nil {
+		return fail(err)
+	}
+	current, set, err := status.ReadConfigValue(config, configQuery(initConfig, config.Elevated))
+	if err != nil {
+		return fail(err)
+	}
+
+	if set && status.ConfigValueMatches(config.Tool, current, desired) {
+		log.Debugf("Configuration %s already set to %s", config.Name, desired)
+		track.item("", config.Name, "configure", types.StatusPresent, "already set", time.Since(started))
+		return nil
+	}
+
+	change := fmt.Sprintf("%s -> %s", describeConfigValue(current, set), desired)
+	if system.IsDryRun() {
+		log.Infof("[DRY-RUN] Would set %s %s: %s", config.Tool, config.Name, change)
+		track.item("", config.Name, "configure", types.StatusPlanned, change, 0)
+		return nil
+	}
+
+	if err := configSettingWriters[config.Tool](config, desired, set, initConfig); err != nil {
+		return fail(err)
+	}
+	log.Infof("Configuration %s: %s", config.Name, change)
+	track.itemIdentity("", config.Name, "configure", types.StatusOK, change, time.Since(started),
+		configSettingIdentity(config, desired, current, set))
+	return nil
+}
+
+// configSettingIdentity is the journal identity: where the key lives, plus the
+// value written and the one it replaced. The last three are guard values
+// (see state.guardKeys), so re-applying a key does not mint a new identity,
+// and the journal keeps the first apply's previous value for uninstall.
+func configSettingIdentity(config types.Configuration, value, previous string, previousSet bool) map[string]string {
+	identity := map[string]string{
+		"tool":         config.Tool,
+		"value":        value,
+		"previous":     previous,
+		"previous_set": fmt.Sprintf("%t", previousSet),
+	}
+	switch config.Tool {
+	case types.ConfigurationToolKDE:
+		identity["file"], identity["group"], identity["key"] = config.File, config.Group, config.Key
+	case types.ConfigurationToolXfconf:
+		identity["channel"], identity["property"], identity["type"] = config.Channel, config.Property, config.Type
+	case types.ConfigurationToolINI:
+		identity["file"], identity["section"], identity["key"] = system.ExpandPath(config.File), config.Section, config.Key
+	case types.ConfigurationToolGSettings:
+		identity["schema"], identity["key"] = config.Schema, config.Key
+	case types.ConfigurationToolMacOSDefaults:
+		identity["domain"], identity["key"], identity["kind"] = status.DefaultsDomain(config), config.Key, config.Kind
+	case types.ConfigurationToolWindowsRegistry:
+		identity["path"], identity["key"], identity["type"] = status.RegistryPath(config), config.Key, strings.ToLower(config.Type)
+	}
+	return identity
+}
+
+func describeConfigValue(value string, set bool) string {
+	if !set {
+		return "(unset)"
+	}
+	return value
+}
+
+// checkConfigSetting rejects an entry missing the fields its tool addresses
+// the key by.
+func checkConfigSetting(config types.Configuration) error {
+	var missing string
+	switch config.Tool {
+	case types.ConfigurationToolKDE:
+		switch {
+		case config.File == "":
+			missing = "file"
+		case config.Group == "":
+			missing = "group"
+		case config.Key == "":
+			missing = "key"
+		}
+	case types.ConfigurationToolXfconf:
+		switch {
+		case config.Channel == "":
+			missing = "channel"
+		case config.Property == "":
+			missing = "property"
+		}
+	case types.ConfigurationToolINI:
+		switch {
+		case config.File == "":
+			missing = "file"
+		case config.Key == "":
+			missing = "key"
+		}
+	case types.ConfigurationToolGSettings:
+		switch {
+		case config.Schema == "":
+			missing = "schema"
+		case config.Key == "":
+			missing = "key"
+		}
+	case types.ConfigurationToolMacOSDefaults:
+		switch {
+		case config.Key == "":
+			missing = "key"
+		case config.Kind == "":
+			missing = "kind"
+		}
+	case types.ConfigurationToolWindowsRegistry:
+		switch {
+		case config.Path == "":
+			missing = "path"
+		case config.Key == "":
+			missing = "key"
+		case registryScripts[strings.ToLower(config.Type)] == "":
+			return fmt.Errorf("unsupported registry value type: %s", config.Type)
+		}
+	}
+	if missing != "" {
+		return fmt.Errorf("the %s tool needs %s", config.Tool, missing)
+	}
+	// A gsettings value of null writes "[]", and a registry string may be
+	// empty; everywhere else a missing value is a mistake.
+	if config.Value == nil && config.Tool != types.ConfigurationToolGSettings && config.Tool != types.ConfigurationToolWindowsRegistry {
+		return errors.New("no value declared")
+	}
+	return nil
+}
+
+// writeKDESetting writes through kwriteconfig. "--" ends option parsing, so a
+// value beginning with "-" is written rather than taken for a flag.
+func writeKDESetting(config types.Configuration, value string, _ bool, initConfig *types.InitConfig) error {
+	_, write, ok := status.KDEConfigTools(commandExists)
+	if !ok {
+		return errors.New("neither kwriteconfig6 nor kwriteconfig5 is installed")
+	}
+	args := []string{"--file", config.File, "--group", config.Group, "--key", config.Key}
+	if config.Type != "" {
+		args = append(args, "--type", config.Type)
+	}
+	args = append(args, "--", value)
+	if err := system.RunCommand(types.Command{Exec: write, Args: args}, initConfig.Variables.Flags.Debug); err != nil {
+		return fmt.Errorf("error writing KDE setting: %w", err)
+	}
+	return nil
+}
+
+// writeXfconfSetting writes through xfconf-query. A property the channel does
+// not have yet must be created with --create and a --type; an existing one
+// keeps its type.
+func writeXfconfSetting(config types.Configuration, value string, set bool, initConfig *types.InitConfig) error {
+	args := []string{"--channel", config.Channel, "--property", config.Property}
+	if !set {
+		valueType := config.Type
+		if valueType == "" {
+			valueType = "string"
+		}
+		args = append(args, "--create", "--type", valueType)
+	}
+	// The attached form keeps a value beginning with "-" from reading as a flag.
+	args = append(args, "--set="+value)
+	if err := system.RunCommand(types.Command{Exec: "xfconf-query", Args: args}, initConfig.Variables.Flags.Debug); err != nil {
+		return fmt.Errorf("error writing xfconf property: %w", err)
+	}
+	return nil
+}
+
+// writeINISetting edits the one key in place and writes the file back through
+// the staged-write path, keeping its mode. A missing file (and its directory,
+// for an unelevated write) is created.
+func writeINISetting(config types.Configuration, value string, _ bool, _ *types.InitConfig) error {
+	path := system.ExpandPath(config.File)
+	mode := os.FileMode(0o644)
+	data, err := os.ReadFile(path) // #nosec G304 -- the file the blueprint manages
+	switch {
+	case err == nil:
+		if info, statErr := os.Stat(path); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+	case os.IsNotExist(err):
+		if !config.Elevated {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { // #nosec G301 -- a config directory under the user's home
+				return fmt.Errorf("error creating %s: %v", filepath.Dir(path), err)
+			}
+		}
+	default:
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	updated := helpers.SetINIValue(string(data), config.Section, config.Key, value)
+	if err := system.WriteFileChecked(path, updated, mode, config.Elevated, nil); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return nil
+}
+
+// writeGSetting writes one gsettings key, refusing a key the schema marks
+// read-only (a lockdown, for instance) rather than letting the set fail
+// obscurely.
+func writeGSetting(config types.Configuration, value string, _ bool, initConfig *types.InitConfig) error {
+	writable, err := system.RunCommandOutput(types.Command{
+		Exec: "gsettings",
+		Args: []string{"writable", config.Schema, config.Key},
+	}, initConfig.Variables.Flags.Debug)
+	if err != nil {
+		return fmt.Errorf("checking whether the key is writable: %w", err)
+	}
+	if strings.TrimSpace(writable) != "true" {
+		return errors.New("gsetting is not writable")
+	}
+	cmd := types.Command{Exec: "gsettings", Args: []string{"set", config.Schema, config.Key, value}}
+	if err := system.RunCommand(cmd, initConfig.Variables.Flags.Debug); err != nil {
+		return fmt.Errorf("applying value %s: %w", value, err)
+	}
+	return nil
+}
+
+func writeDefaultsSetting(config types.Configuration, _ string, _ bool, initConfig *types.InitConfig) error {
+	return processMacOSDefaults(config, initConfig)
+}
+
+func writeRegistrySetting(config types.Configuration, _ string, _ bool, initConfig *types.InitConfig) error {
+	return processWindowsRegistry(config, initConfig)
+}
+
+// applyDconf reads back every key of a dconf keyfile and loads the file only
+// when one of them differs. The journal records the keys that changed, with
+// their old and new values.
+func applyDconf(blueprintDir string, config types.Configuration, initConfig *types.InitConfig, track *progress) error {
+	started := time.Now()
+	fail := func(err error) error {
+		log.Errorf("Error processing configuration %s: %v", config.Name, err)
+		track.item("", config.Name, "configure", types.StatusFailed, err.Error(), time.Since(started))
+		return fmt.Errorf("error processing configuration %s: %w", config.Name, err)
+	}
+
+	if config.RunOnce {
+		if _, err := os.Stat(dconfMarker(config, initConfig)); err == nil {
+			track.item("", config.Name, "configure", types.StatusPresent, "run_once: already applied", time.Since(started))
+			return nil
+		}
+	}
+
+	file := filepath.Join(blueprintDir, config.File)
+	keyfile, err := os.ReadFile(file) // #nosec G304 -- blueprint-relative path
+	if err != nil {
+		return fail(fmt.Errorf("error reading dconf keyfile: %w", err))
+	}
+	changes, err := status.DconfChanges(string(keyfile), configQuery(initConfig, config.Elevated))
+	if err != nil {
+		return fail(err)
+	}
+	if len(changes) == 0 {
+		log.Debugf("Configuration %s: every key already set", config.Name)
+		track.item("", config.Name, "configure", types.StatusPresent, "already set", time.Since(started))
+		return nil
+	}
+
+	var detail, values, previous []string
+	for _, change := range changes {
+		detail = append(detail, fmt.Sprintf("%s: %s -> %s", change.Key, describeConfigValue(change.Current, change.Set), change.Desired))
+		values = append(values, change.Key+"="+change.Desired)
+		previous = append(previous, change.Key+"="+describeConfigValue(change.Current, change.Set))
+	}
+	if system.IsDryRun() {
+		log.Infof("[DRY-RUN] Would load dconf %s: %s", config.Name, strings.Join(detail, "; "))
+		track.item("", config.Name, "configure", types.StatusPlanned, strings.Join(detail, "; "), 0)
+		return nil
+	}
+
+	if err := processDconf(blueprintDir, config, initConfig); err != nil {
+		return fail(err)
+	}
+	log.Infof("Configuration %s: %s", config.Name, strings.Join(detail, "; "))
+	track.itemIdentity("", config.Name, "configure", types.StatusOK, strings.Join(detail, "; "), time.Since(started),
+		map[string]string{
+			"tool":     types.ConfigurationToolDconf,
+			"file":     file,
+			"value":    strings.Join(values, "\n"),
+			"previous": strings.Join(previous, "\n"),
+		})
+	return nil
+}
@@ -0,0 +1,285 @@
+// Tests for the read-back configuration tools. The kde and xfconf reads go
+// through the executor, so configExec answers them with a canned value and
+// records every write.
+
+package processors
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/exectest"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// configExec answers reads (Output) with current, or fails them when unset,
+// and records writes (Run).
+type configExec struct {
+	rec     *exectest.Recorder
+	current string
+	unset   bool
+}
+
+func (c configExec) Run(cmd types.Command, debug bool) error { return c.rec.Run(cmd, debug) }
+
+func (c configExec) Output(cmd types.Command, _ bool) (string, error) {
+	if c.unset {
+		return "", errors.New("exit status 1")
+	}
+	return c.current + "\n", nil
+}
+
+// desktop installs configExec, with only the named binaries on PATH.
+func desktop(t *testing.T, current string, unset bool, have ...string) *exectest.Recorder {
+	t.Helper()
+	prevExists := commandExists
+	commandExists = func(name string) bool {
+		for _, h := range have {
+			if h == name {
+				return true
+			}
+		}
+		return false
+	}
+	rec := exectest.New()
+	restore := system.SetExecutor(configExec{rec: rec, current: current, unset: unset})
+	t.Cleanup(func() {
+		restore()
+		commandExists = prevExists
+	})
+	return rec
+}
+
+func TestConfigSetting_KDEWritesWithSeparator(t *testing.T) {
+	rec := desktop(t, "true", false, "kwriteconfig6", "kreadconfig6")
+
+	config := types.Configuration{Name: "single-click", Tool: types.ConfigurationToolKDE,
+		File: "kdeglobals", Group: "KDE", Key: "SingleClick", Type: "bool", Value: false}
+	if err := applyConfigSetting(config, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+		t.Fatalf("applyConfigSetting: %v", err)
+	}
+	want := [][]string{{"kwriteconfig6", "--file", "kdeglobals", "--group", "KDE", "--key", "SingleClick", "--type", "bool", "--", "false"}}
+	if got := argvs(rec); !reflect.DeepEqual(got, want) {
+		t.Fatalf("writes:\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestConfigSetting_EqualValueIsNotRewritten(t *testing.T) {
+	// KDE files hold "True"; xfconf prints doubles with six decimals.
+	for _, tc := range []struct {
+		config  types.Configuration
+		current string
+	}{
+		{types.Configuration{Name: "k", Tool: types.ConfigurationToolKDE, File: "kdeglobals", Group: "KDE", Key: "SingleClick", Value: true}, "True"},
+		{types.Configuration{Name: "x", Tool: types.ConfigurationToolXfconf, Channel: "xsettings", Property: "/Xft/DPI", Value: 1.5}, "1.500000"},
+	} {
+		rec := desktop(t, tc.current, false, "kwriteconfig5", "xfconf-query")
+		if err := applyConfigSetting(tc.config, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+			t.Fatalf("%s: %v", tc.config.Name, err)
+		}
+		if len(rec.Calls) != 0 {
+			t.Errorf("%s: an unchanged value was rewritten: %v", tc.config.Name, argvs(rec))
+		}
+	}
+}
+
+func TestConfigSetting_XfconfCreatesMissingProperty(t *testing.T) {
+	rec := desktop(t, "", true, "xfconf-query")
+
+	config := types.Configuration{Name: "theme", Tool: types.ConfigurationToolXfconf,
+		Channel: "xfwm4", Property: "/general/theme", Value: "-dark"}
+	if err := applyConfigSetting(config, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+		t.Fatalf("applyConfigSetting: %v", err)
+	}
+	want := [][]string{{"xfconf-query", "--channel", "xfwm4", "--property", "/general/theme", "--create", "--type", "string", "--set=-dark"}}
+	if got := argvs(rec); !reflect.DeepEqual(got, want) {
+		t.Fatalf("writes:\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestConfigSetting_DryRunWritesNothing(t *testing.T) {
+	rec := desktop(t, "Adwaita", false, "xfconf-query")
+	path := filepath.Join(t.TempDir(), "settings.ini")
+
+	system.SetDryRun(true)
+	t.Cleanup(func() { system.SetDryRun(false) })
+
+	for _, config := range []types.Configuration{
+		{Name: "theme", Tool: types.ConfigurationToolXfconf, Channel: "xfwm4", Property: "/general/theme", Value: "Greybird"},
+		{Name: "gtk", Tool: types.ConfigurationToolINI, File: path, Section: "Settings", Key: "gtk-theme-name", Value: "Greybird"},
+	} {
+		if err := applyConfigSetting(config, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+			t.Fatalf("%s: %v", config.Name, err)
+		}
+	}
+	if len(rec.Calls) != 0 {
+		t.Errorf("dry run wrote: %v", argvs(rec))
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("dry run created the ini file")
+	}
+}
+
+func TestConfigSetting_INIEditsOneKey(t *testing.T) {
+	desktop(t, "", false)
+	path := filepath.Join(t.TempDir(), "qt5ct", "qt5ct.conf")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	original := "[Appearance]\n# chosen by hand\nicon_theme=breeze\nstyle=Breeze\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := types.Configuration{Name: "qt-style", Tool: types.ConfigurationToolINI,
+		File: path, Section: "Appearance", Key: "style", Value: "Fusion"}
+	if err := applyConfigSetting(config, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+		t.Fatalf("applyConfigSetting: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(original, "style=Breeze", "style=Fusion", 1); string(data) != want {
+		t.Errorf("file:\n%s\nwant:\n%s", data, want)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
+
+func TestConfigSettingIdentity_CarriesPreviousValue(t *testing.T) {
+	config := types.Configuration{Name: "k", Tool: types.ConfigurationToolKDE, File: "kdeglobals", Group: "KDE", Key: "SingleClick"}
+	identity := configSettingIdentity(config, "false", "", false)
+	if identity["previous_set"] != "false" || identity["value"] != "false" || identity["key"] != "SingleClick" {
+		t.Errorf("identity = %v", identity)
+	}
+}
+
+// answerExec answers each read by its argv (joined by spaces); an unlisted
+// read fails. Writes are recorded.
+type answerExec struct {
+	rec     *exectest.Recorder
+	outputs map[string]string
+}
+
+func (a answerExec) Run(cmd types.Command, debug bool) error { return a.rec.Run(cmd, debug) }
+
+func (a answerExec) Output(cmd types.Command, _ bool) (string, error) {
+	out, ok := a.outputs[strings.Join(append([]string{cmd.Exec}, cmd.Args...), " ")]
+	if !ok {
+		return "", errors.New("exit status 1")
+	}
+	return out, nil
+}
+
+func answering(t *testing.T, outputs map[string]string) *exectest.Recorder {
+	t.Helper()
+	prevExists := commandExists
+	commandExists = func(string) bool { return true }
+	rec := exectest.New()
+	restore := system.SetExecutor(answerExec{rec: rec, outputs: outputs})
+	t.Cleanup(func() {
+		restore()
+		commandExists = prevExists
+	})
+	return rec
+}
+
+// Only the gsettings key that differs is written; the equal one is not.
+func TestGSettings_WritesOnlyChangedKeys(t *testing.T) {
+	rec := answering(t, map[string]string{
+		"gsettings get org.gnome.desktop.interface color-scheme":      "'prefer-dark'\n",
+		"gsettings get org.gnome.desktop.interface clock-format":      "'24h'\n",
+		"gsettings writable org.gnome.desktop.interface clock-format": "true\n",
+	})
+	config := types.Configuration{Name: "interface", Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.interface",
+		Settings: map[string]interface{}{"color-scheme": "prefer-dark", "clock-format": "12h"}}
+	applyGSettings(config, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration))
+
+	want := [][]string{{"gsettings", "set", "org.gnome.desktop.interface", "clock-format", "'12h'"}}
+	if got := argvs(rec); !reflect.DeepEqual(got, want) {
+		t.Fatalf("writes:\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestDconf_LoadsOnlyWhenAKeyDiffers(t *testing.T) {
+	blueprintDir := t.TempDir()
+	keyfile := "[org/gnome/desktop/interface]\ncolor-scheme='prefer-dark'\nenable-animations=false\n"
+	if err := os.WriteFile(filepath.Join(blueprintDir, "desktop.ini"), []byte(keyfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := types.Configuration{Name: "desktop", Tool: types.ConfigurationToolDconf, File: "desktop.ini"}
+
+	rec := answering(t, map[string]string{
+		"dconf read /org/gnome/desktop/interface/color-scheme":      "'prefer-dark'\n",
+		"dconf read /org/gnome/desktop/interface/enable-animations": "false\n",
+	})
+	if err := applyDconf(blueprintDir, config, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+		t.Fatalf("applyDconf: %v", err)
+	}
+	if len(rec.Calls) != 0 {
+		t.Fatalf("an unchanged keyfile was loaded: %v", argvs(rec))
+	}
+
+	rec = answering(t, map[string]string{
+		"dconf read /org/gnome/desktop/interface/color-scheme":      "'default'\n",
+		"dconf read /org/gnome/desktop/interface/enable-animations": "\n",
+	})
+	if err := applyDconf(blueprintDir, config, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+		t.Fatalf("applyDconf: %v", err)
+	}
+	if got := argvs(rec); !reflect.DeepEqual(got, [][]string{{"dconf", "load", "/"}}) {
+		t.Fatalf("writes = %v, want one dconf load", got)
+	}
+}
+
+func TestConfigSetting_RegistryAndDefaultsCompareAsPrinted(t *testing.T) {
+	rec := answering(t, map[string]string{
+		"defaults read com.apple.dock tilesize": "36\n",
+	})
+	dock := types.Configuration{Name: "dock-size", Tool: types.ConfigurationToolMacOSDefaults,
+		Domain: "com.apple.dock", Key: "tilesize", Kind: "int", Value: 36}
+	if err := applyConfigSetting(dock, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	if len(rec.Calls) != 0 {
+		t.Fatalf("an unchanged defaults key was rewritten: %v", argvs(rec))
+	}
+
+	// The registry read does not answer here: the value is unset and is
+	// written with the same script as before.
+	rec = answering(t, nil)
+	reg := types.Configuration{Name: "telemetry", Tool: types.ConfigurationToolWindowsRegistry,
+		Path: `SOFTWARE\Policies\rwr`, Key: "AllowTelemetry", Type: "DWord", Value: 0}
+	if err := applyConfigSetting(reg, newTestInitConfig(), newProgress(types.BlueprintTypeConfiguration)); err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	if len(rec.Calls) != 1 || rec.Calls[0].Vars[registryValueEnv] != "0" {
+		t.Fatalf("registry writes = %v", rec.Calls)
+	}
+}
+
+func TestPlannedSettings(t *testing.T) {
+	gsettings := types.Configuration{Name: "interface", Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.interface",
+		Settings: map[string]interface{}{"color-scheme": "prefer-dark", "clock-format": "24h"}}
+	planned := plannedSettings(gsettings, "/bp")
+	if len(planned) != 2 || planned[0].name != "interface/clock-format" || planned[0].desired != "'24h'" {
+		t.Fatalf("gsettings planned = %+v", planned)
+	}
+
+	dconf := plannedSettings(types.Configuration{Name: "desktop", Tool: types.ConfigurationToolDconf, File: "desktop.ini"}, "/bp")
+	if len(dconf) != 1 || dconf[0].setting.File != filepath.Join("/bp", "desktop.ini") {
+		t.Fatalf("dconf planned = %+v", dconf)
+	}
+
+	plist := types.Configuration{Name: "dock-apps", Tool: types.ConfigurationToolMacOSDefaults, Key: "persistent-apps", Kind: "array"}
+	if plannedSettings(plist, "/bp") != nil {
+		t.Error("a plist-kind defaults entry was planned as readable")
+	}
+}
@@ -0,0 +1,415 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// FetchProcessor names the `rwr fetch` pass in events and on the dashboard.
+// It is not a blueprint type: nothing in the tree declares it.
+const FetchProcessor = "fetch"
+
+// The lanes fetch reports what is not a package under.
+const (
+	fetchLaneURLs  = "urls"
+	fetchLaneFonts = "fonts"
+	fetchLaneGit   = "git"
+)
+
+// cacheArtifact is one thing an offline run takes from the cache: a package
+// through its provider, a URL download, a Nerd Font archive, or a git
+// repository's mirror.
+type cacheArtifact struct {
+	processor string // the processor that uses it; bootstrap's are its own
+	lane      string // the provider for a package, otherwise the kind
+	name      string // a package or font name, or a URL
+	sha256    string // a URL download's expected digest
+	private   bool   // a git repository cloned with credentials
+}
+
+// cacheArtifacts is everything a run of this tree on this machine takes from
+// the network, in the order fetch downloads it: bootstrap first, then each
+// processor's, deduplicated. Profiles apply as they do in a run, and so do
+// `when:` conditions, through the plan - bootstrap's aside, which all count.
+// A tree that does not resolve cleanly is refused: what its broken blueprint
+// needs would be missing from the cache without a word.
+func cacheArtifacts(initConfig *types.InitConfig, osInfo *types.OSInfo) ([]cacheArtifact, error) {
+	plan, restore, err := resolvedCleanly(initConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	available := system.GetAvailableProviders()
+	defaultProvider, hasDefault := defaultProviderFor(osInfo, available)
+	profiles := initConfig.Variables.Flags.Profiles
+	table := system.PackageAliases()
+	installedBy := recordedPackageProviders()
+
+	var artifacts []cacheArtifact
+	seen := map[cacheArtifact]bool{}
+	add := func(artifact cacheArtifact) {
+		if !seen[artifact] {
+			seen[artifact] = true
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	addPackages := func(processor string, packages []types.Package) {
+		for _, pkg := range helpers.FilterByProfiles(packages, profiles) {
+			if pkg.Action != types.ActionInstall {
+				continue
+			}
+			names := pkg.Names
+			if len(names) == 0 && pkg.Name != "" {
+				names = []string{pkg.Name}
+			}
+			// The provider each package installs through, chosen as the
+			// run will choose it.
+			chain := pkg.PackageManager
+			if len(chain) == 0 {
+				chain = initConfig.DefaultPackageManager
+			}
+			switch {
+			case pkg.PackageManager.Pinned():
+				for _, name := range names {
+					add(cacheArtifact{processor: processor, lane: chain[0], name: name})
+				}
+			case len(chain) > 0:
+				providers := chainProviders(chain)
+				if len(providers) == 0 {
+					for _, name := range names {
+						add(cacheArtifact{processor: processor, name: name})
+					}
+					continue
+				}
+				picks, missing := pickFromChain(providers, names, types.ActionInstall, installedBy)
+				for _, pick := range picks {
+					for _, name := range pick.names {
+						add(cacheArtifact{processor: processor, lane: pick.provider.Name, name: name})
+					}
+				}
+				// What no provider in the chain has is left to the first,
+				// whose fetch - or the offline check - says so.
+				for _, logical := range missing {
+					name, _ := table.Resolve(logical, providers[0])
+					add(cacheArtifact{processor: processor, lane: providers[0].Name, name: name})
+				}
+			default:
+				for _, name := range names {
+					lane := ""
+					if hasDefault {
+						lane = defaultProvider.Name
+						name, _ = table.Resolve(name, defaultProvider)
+					}
+					add(cacheArtifact{processor: processor, lane: lane, name: name})
+				}
+			}
+		}
+	}
+	addFiles := func(processor string, files []types.File) {
+		for _, file := range helpers.FilterByProfiles(files, profiles) {
+			if isURLSource(file.Source) {
+				add(cacheArtifact{processor: processor, lane: fetchLaneURLs, name: file.Source, sha256: file.Sha256})
+			}
+		}
+	}
+	addGit := func(processor string, repos []types.Git) {
+		for _, repo := range helpers.FilterByProfiles(repos, profiles) {
+			if repo.URL != "" {
+				add(cacheArtifact{processor: processor, lane: fetchLaneGit, name: repo.URL, private: repo.Private})
+			}
+		}
+	}
+
+	if gitOpts := initConfig.Init.Git; gitOpts != nil && gitOpts.URL != "" {
+		add(cacheArtifact{processor: types.BlueprintTypeBootstrap, lane: fetchLaneGit, name: gitOpts.URL, private: gitOpts.Private})
+	}
+	if bootstrapFile := findBootstrapFile(initConfig.Init.Location); bootstrapFile != "" {
+		bootstrap, err := readBootstrap(bootstrapFile, initConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", bootstrapFile, err)
+		}
+		addPackages(types.BlueprintTypeBootstrap, bootstrap.Packages)
+		addFiles(types.BlueprintTypeBootstrap, bootstrap.Files)
+		addGit(types.BlueprintTypeBootstrap, bootstrap.Git)
+	}
+
+	packages, err := decodePlanned(initConfig, plan, types.BlueprintTypePackages,
+		func(d types.PackagesData) []types.Package { return d.Packages },
+		func(item types.Package) string { return item.Import })
+	if err != nil {
+		return nil, err
+	}
+	addPackages(types.BlueprintTypePackages, packages)
+
+	files, err := decodePlanned(initConfig, plan, types.BlueprintTypeFiles,
+		func(d types.FileData) []types.File { return d.Files },
+		func(item types.File) string { return item.Import })
+	if err != nil {
+		return nil, err
+	}
+	addFiles(types.BlueprintTypeFiles, files)
+
+	fonts, err := decodePlanned(initConfig, plan, types.BlueprintTypeFonts,
+		func(d types.FontsData) []types.Font { return d.Fonts }, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, font := range helpers.FilterByProfiles(fonts, profiles) {
+		if font.Action != types.ActionInstall {
+			continue
+		}
+		for _, name := range fontEntryNames(font) {
+			add(cacheArtifact{processor: types.BlueprintTypeFonts, lane: fetchLaneFonts, name: name})
+		}
+	}
+
+	repos, err := decodePlanned(initConfig, plan, types.BlueprintTypeGit,
+		func(d types.GitData) []types.Git { return d.Repos },
+		func(item types.Git) string { return item.Import })
+	if err != nil {
+		return nil, err
+	}
+	addGit(types.BlueprintTypeGit, repos)
+
+	return artifacts, nil
+}
+
+// decodePlanned decodes every file the plan resolved for processor, imports
+// followed when itemImport is given, into the entries they declare.
+func decodePlanned[D any, T any](initConfig *types.InitConfig, plan *types.Plan, processor string,
+	items func(D) []T, itemImport func(T) string) ([]T, error) {
+	decode := blueprintDecoder(initConfig, processor, items)
+
+	var all []T
+	for _, file := range plan.Files[processor] {
+		entries, err := decode(file.Resolved, file.Format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Path, err)
+		}
+		if itemImport != nil {
+			entries, err = helpers.ResolveImports(entries, filepath.Dir(file.Path), itemImport, decode, file.Format)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Path, err)
+			}
+		}
+		all = append(all, entries...)
+	}
+	return all, nil
+}
+
+// isURLSource reports whether a file's source is downloaded rather than read
+// from the tree.
+func isURLSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// FetchPlan is what the dashboard draws `rwr fetch` from: one lane per
+// provider and one each for URLs, fonts and git, with a row per artifact.
+func FetchPlan(initConfig *types.InitConfig, osInfo *types.OSInfo) (*types.Plan, error) {
+	if err := system.InitProviders(); err != nil {
+		return nil, fmt.Errorf("error initializing providers: %w", err)
+	}
+	artifacts, err := cacheArtifacts(initConfig, osInfo)
+	if err != nil {
+		return nil, err
+	}
+	plan := &types.Plan{Init: initConfig, Order: []string{FetchProcessor}}
+	for _, artifact := range artifacts {
+		plan.Resources = append(plan.Resources, types.Resource{
+			Processor: FetchProcessor,
+			Provider:  artifact.lane,
+			Name:      artifact.name,
+			Action:    FetchProcessor,
+			Status:    types.StatusPlanned,
+		})
+	}
+	return plan, nil
+}
+
+// Fetch is `rwr fetch`: it downloads everything a run of this tree on this
+// machine needs into the offline cache - packages through each provider's
+// offline fetch, into its own directory there; URL sources; Nerd Font
+// archives; and bare mirrors of git repositories, the blueprint repository's
+// included. What is already cached is refreshed or skipped, so fetching again
+// only downloads what changed. One artifact failing does not stop the rest.
+func Fetch(initConfig *types.InitConfig, osInfo *types.OSInfo) error {
+	resetFailures()
+	if system.IsOffline() {
+		return errors.New("fetch downloads the offline cache; run it without --offline")
+	}
+	if err := system.InitProviders(); err != nil {
+		return fmt.Errorf("error initializing providers: %w", err)
+	}
+	artifacts, err := cacheArtifacts(initConfig, osInfo)
+	if err != nil {
+		return err
+	}
+	log.Infof("Fetching %d artifact(s) into %s", len(artifacts), system.CacheDir())
+
+	reporting.SetCurrentProcessor(FetchProcessor)
+	reporting.Emit(reporting.ProcStarted{Processor: FetchProcessor})
+	started := time.Now()
+
+	track := newProgress(FetchProcessor)
+	for _, artifact := range artifacts {
+		track.expect(artifact.lane, 1)
+	}
+
+	// Fonts are downloaded from the latest release, looked up once.
+	var releaseURL string
+	var releaseErr error
+	if slices.ContainsFunc(artifacts, func(a cacheArtifact) bool { return a.lane == fetchLaneFonts }) {
+		releaseURL, releaseErr = getLatestReleaseURL()
+	}
+
+	for _, artifact := range artifacts {
+		if system.Cancelled() {
+			reporting.Emit(reporting.ProcFinished{Processor: FetchProcessor, Err: system.ErrCancelled, Dur: time.Since(started)})
+			return system.ErrCancelled
+		}
+		reporting.SetCurrentProvider(artifact.lane)
+		artifactStarted := time.Now()
+		var err error
+		switch artifact.lane {
+		case fetchLaneURLs:
+			err = system.FetchURL(artifact.name, artifact.sha256)
+		case fetchLaneFonts:
+			err = releaseErr
+			if err == nil {
+				err = fetchFont(artifact.name, releaseURL)
+			}
+		case fetchLaneGit:
+			err = helpers.MirrorGitRepository(types.GitOptions{URL: artifact.name, Private: artifact.private}, initConfig)
+		default:
+			err = fetchPackage(artifact.lane, artifact.name, initConfig.Variables.Flags.Debug)
+		}
+		if err != nil {
+			recordFailure(FetchProcessor, artifact.name, err)
+			track.item(artifact.lane, artifact.name, FetchProcessor, types.StatusFailed, err.Error(), time.Since(artifactStarted))
+			continue
+		}
+		track.item(artifact.lane, artifact.name, FetchProcessor, types.StatusOK, "", time.Since(artifactStarted))
+	}
+	reporting.SetCurrentProvider("")
+
+	err = failureError()
+	reporting.Emit(reporting.ProcFinished{Processor: FetchProcessor, Err: err, Dur: time.Since(started)})
+	if err != nil {
+		return err
+	}
+	log.Info("Fetch complete")
+	return nil
+}
+
+// fetchPackage downloads one package, and what it depends on, into its
+// provider's directory in the cache through the provider's offline fetch,
+// then records it fetched.
+func fetchPackage(providerName, name string, debug bool) error {
+	provider, ok := system.GetProvider(providerName)
+	if !ok {
+		return fmt.Errorf("package manager %q is not available on this system", providerName)
+	}
+	if provider.Offline == nil || provider.Offline.Fetch == "" {
+		return fmt.Errorf("%s cannot fetch packages for an offline install", provider.Name)
+	}
+	if strings.HasPrefix(name, "-") {
+		return errors.New("package name may not begin with '-'; it would be read as an option by the package manager")
+	}
+	// apt refuses a download directory without its partial/ beside it.
+	if err := os.MkdirAll(filepath.Join(system.ProviderCacheDir(provider.Name), "partial"), 0o755); err != nil { // #nosec G301 -- cache directory; its contents are public downloads
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	bin, args, environment := system.OfflineCommand(provider, provider.Offline.Fetch)
+	err := system.RunCommand(types.Command{
+		Exec:      bin,
+		Args:      append(args, name),
+		Elevated:  provider.Elevated,
+		Escalates: provider.Escalates,
+		Variables: environment,
+	}, debug)
+	if err != nil {
+		return fmt.Errorf("%s fetch: %w", provider.Name, err)
+	}
+	return system.RecordFetchedPackage(provider.Name, name)
+}
+
+// fetchFont downloads a Nerd Font's archive into the cache.
+func fetchFont(name, releaseURL string) error {
+	if err := validateFontName(name); err != nil {
+		return err
+	}
+	target := system.CachedFontPath(name)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil { // #nosec G301 -- cache directory; its contents are public downloads
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+	// Downloaded beside the archive and renamed over it, so a failed
+	// download leaves the last good one in place.
+	partial := target + ".part"
+	if err := downloadFontTarball(getFontURL(types.Font{Name: name}, releaseURL), partial); err != nil {
+		_ = os.Remove(partial)
+		return fmt.Errorf("error downloading font tarball: %v", err)
+	}
+	return os.Rename(partial, target)
+}
+
+// checkOfflineCache refuses an offline run the cache cannot serve, naming
+// everything missing at once rather than failing at the first of them halfway
+// through. Only what the processors in runOrder - and bootstrap, when it will
+// run - take from the network counts.
+func checkOfflineCache(initConfig *types.InitConfig, osInfo *types.OSInfo, runOrder []string) error {
+	artifacts, err := cacheArtifacts(initConfig, osInfo)
+	if err != nil {
+		return fmt.Errorf("cannot tell what this offline run needs: %w", err)
+	}
+	fetched, err := system.FetchedPackages()
+	if err != nil {
+		return err
+	}
+	bootstraps := !helpers.IsBootstrapped() || initConfig.Variables.Flags.ForceBootstrap
+
+	var missing []string
+	for _, artifact := range artifacts {
+		switch {
+		case artifact.processor == types.BlueprintTypeBootstrap && !bootstraps:
+			continue
+		case artifact.processor != types.BlueprintTypeBootstrap && !slices.Contains(runOrder, artifact.processor):
+			continue
+		}
+		var cached bool
+		switch artifact.lane {
+		case fetchLaneURLs:
+			cached = system.FileExists(system.CachedURLPath(artifact.name))
+		case fetchLaneFonts:
+			cached = system.FileExists(system.CachedFontPath(artifact.name))
+		case fetchLaneGit:
+			// The blueprint repository was resolved before the run began.
+			if gitOpts := initConfig.Init.Git; gitOpts != nil && gitOpts.URL == artifact.name {
+				continue
+			}
+			cached = system.FileExists(system.CachedGitPath(artifact.name))
+		default:
+			cached = slices.Contains(fetched[artifact.lane], artifact.name)
+		}
+		if !cached {
+			missing = append(missing, fmt.Sprintf("%s (%s)", artifact.name, artifact.lane))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d artifact(s) this run needs are %w:\n  %s",
+		len(missing), system.ErrNotCached, strings.Join(missing, "\n  "))
+}
@@ -0,0 +1,197 @@
+package processors
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/exectest"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// Before fetch an offline run is refused with everything missing named at
+// once; fetch downloads the packages through the provider's offline fetch
+// and the URL sources into the cache; after it the same run is let through,
+// and installs through the provider's offline install.
+func TestFetch_FillsTheCacheAnOfflineRunNeeds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("config"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("packages/base.yaml", `packages:
+  - names: [git, tmux]
+    action: install
+    package_manager: apt
+  - name: nano
+    action: remove
+    package_manager: apt
+`)
+	write("files/base.yaml", "files:\n  - name: tool.conf\n    action: copy\n    source: "+server.URL+"/tool.conf\n    target: "+filepath.ToSlash(t.TempDir())+"\n")
+
+	bin := "sh"
+	if runtime.GOOS == types.OSWindows {
+		bin = "cmd"
+	}
+	detection := types.DetectionConfig{Binary: bin, Distributions: []string{types.OSLinux, types.OSDarwin, types.OSWindows}}
+	defer system.SetProvidersForTest(map[string]*types.Provider{
+		"apt": {Name: "apt", Detection: detection,
+			Commands: types.CommandConfig{Install: "install -y", Remove: "remove -y"},
+			Offline: &types.OfflineConfig{
+				Fetch:   "install --download-only -o Dir::Cache::archives={{ .CacheDir }}",
+				Install: "install --no-download -o Dir::Cache::archives={{ .CacheDir }}",
+			}},
+	})()
+	system.SetCacheDir(t.TempDir())
+	defer system.SetCacheDir("")
+	rec := exectest.New()
+	defer system.SetExecutor(rec)()
+	t.Cleanup(resetFailures)
+
+	initConfig := &types.InitConfig{}
+	initConfig.Init.Location = dir
+	initConfig.Init.Format = "yaml"
+	osInfo := newTestOSInfo()
+	runOrder := []string{types.BlueprintTypePackages, types.BlueprintTypeFiles}
+
+	err := checkOfflineCache(initConfig, osInfo, runOrder)
+	if !errors.Is(err, system.ErrNotCached) {
+		t.Fatalf("preflight before fetch = %v, want ErrNotCached", err)
+	}
+	for _, want := range []string{"git (apt)", "tmux (apt)", "/tool.conf (urls)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("preflight error does not name %s:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "nano") {
+		t.Errorf("preflight names a removal:\n%v", err)
+	}
+
+	if err := Fetch(initConfig, osInfo); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	archives := "Dir::Cache::archives=" + system.ProviderCacheDir("apt")
+	var calls []string
+	for _, call := range rec.Calls {
+		calls = append(calls, strings.Join(call.Args, " "))
+	}
+	want := "install --download-only -o " + archives + " git|install --download-only -o " + archives + " tmux"
+	if strings.Join(calls, "|") != want {
+		t.Fatalf("fetch calls = %q, want %q", calls, want)
+	}
+
+	if err := checkOfflineCache(initConfig, osInfo, runOrder); err != nil {
+		t.Fatalf("preflight after fetch: %v", err)
+	}
+
+	system.SetOffline(true)
+	defer system.SetOffline(false)
+	rec.Calls = nil
+	packages := &types.PackagesData{Packages: []types.Package{{
+		Names: []string{"git"}, Action: types.ActionInstall, PackageManager: types.ProviderChain{"apt"},
+	}}}
+	if err := ProcessPackages(nil, packages, dir, "yaml", osInfo, initConfig); err != nil {
+		t.Fatalf("offline ProcessPackages: %v", err)
+	}
+	if len(rec.Calls) != 1 || strings.Join(rec.Calls[0].Args, " ") != "install --no-download -o "+archives+" git" {
+		t.Fatalf("offline install calls = %+v", rec.Calls)
+	}
+}
+
+// Fetch and an offline run choose a fallback chain's provider alike: fetch by
+// asking each provider, the offline run by what fetch cached - without
+// asking anything.
+func TestFetch_ChainPicksTheSameProviderOffline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "packages", "base.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`packages:
+  - names: [git, zed]
+    action: install
+    package_manager: [apt, brew]
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bin := "sh"
+	if runtime.GOOS == types.OSWindows {
+		bin = "cmd"
+	}
+	detection := types.DetectionConfig{Binary: bin, Distributions: []string{types.OSLinux, types.OSDarwin, types.OSWindows}}
+	offline := func(name string) *types.OfflineConfig {
+		return &types.OfflineConfig{Fetch: name + "-fetch", Install: name + "-install"}
+	}
+	defer system.SetProvidersForTest(map[string]*types.Provider{
+		"apt":  {Name: "apt", Detection: detection, Commands: types.CommandConfig{Install: "install -y", Info: "show"}, Offline: offline("apt")},
+		"brew": {Name: "brew", Detection: detection, Commands: types.CommandConfig{Install: "install", Info: "info"}, Offline: offline("brew")},
+	})()
+	system.SetCacheDir(t.TempDir())
+	defer system.SetCacheDir("")
+	rec := exectest.New()
+	rec.Respond = func(call exectest.Call) (string, error) {
+		// apt has git; only brew has zed.
+		if call.Args[0] == "show" && call.Args[len(call.Args)-1] == "zed" {
+			return "", errors.New("exit status 100")
+		}
+		return "", nil
+	}
+	defer system.SetExecutor(rec)()
+	t.Cleanup(resetFailures)
+
+	initConfig := &types.InitConfig{}
+	initConfig.Init.Location = dir
+	initConfig.Init.Format = "yaml"
+	osInfo := newTestOSInfo()
+
+	if err := Fetch(initConfig, osInfo); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	var fetched []string
+	for _, call := range rec.Calls {
+		if strings.HasSuffix(call.Args[0], "-fetch") {
+			fetched = append(fetched, strings.Join(call.Args, " "))
+		}
+	}
+	if strings.Join(fetched, "|") != "apt-fetch git|brew-fetch zed" {
+		t.Fatalf("fetched = %q, want git through apt and zed through brew", fetched)
+	}
+
+	system.SetOffline(true)
+	defer system.SetOffline(false)
+	if err := checkOfflineCache(initConfig, osInfo, []string{types.BlueprintTypePackages}); err != nil {
+		t.Fatalf("preflight after fetch: %v", err)
+	}
+	rec.Calls = nil
+	packages := &types.PackagesData{Packages: []types.Package{{
+		Names: []string{"git", "zed"}, Action: types.ActionInstall, PackageManager: types.ProviderChain{"apt", "brew"},
+	}}}
+	if err := ProcessPackages(nil, packages, dir, "yaml", osInfo, initConfig); err != nil {
+		t.Fatalf("offline ProcessPackages: %v", err)
+	}
+	var calls []string
+	for _, call := range rec.Calls {
+		calls = append(calls, strings.Join(call.Args, " "))
+	}
+	if strings.Join(calls, "|") != "apt-install git|brew-install zed" {
+		t.Fatalf("offline calls = %q, want the fetched providers' installs and no queries", calls)
+	}
+}
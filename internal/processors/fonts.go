@@ -94,7 +94,11 @@ func ProcessFonts(blueprintData []byte, blueprintDir string, format string, osIn
 	//
 	// GitHub being unreachable fails every font, but not the run: fonts are
 	// cosmetic, and the failures reach the exit code through the ledger.
-	releaseURL, err := getLatestReleaseURL()
+	// Offline there is no release to look up: the archives are in the cache.
+	var releaseURL string
+	if !system.IsOffline() {
+		releaseURL, err = getLatestReleaseURL()
+	}
 	if err != nil {
 		recordFailure("fonts", "nerd-fonts release lookup", err)
 		for _, font := range fontsData.Fonts {
@@ -174,8 +178,12 @@ func installFont(font types.Font, osInfo *types.OSInfo, releaseURL string) error
 	defer os.RemoveAll(tempDir) //nolint:errcheck
 
 	tarballPath := filepath.Join(tempDir, font.Name+".tar.xz")
-	err = downloadFontTarball(fontURL, tarballPath)
-	if err != nil {
+	if system.IsOffline() {
+		tarballPath = system.CachedFontPath(font.Name)
+		if !system.FileExists(tarballPath) {
+			return fmt.Errorf("font %s: %w", font.Name, system.ErrNotCached)
+		}
+	} else if err := downloadFontTarball(fontURL, tarballPath); err != nil {
 		return fmt.Errorf("error downloading font tarball: %v", err)
 	}
 
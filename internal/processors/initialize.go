@@ -185,6 +185,14 @@ func Initialize(initFilePath string, flags types.Flags, selectedProcessors ...st
 		return nil, err
 	}
 
+	// Strict for the same reason: a misspelled selector must not quietly
+	// leave a machine without its profiles.
+	rules, err := types.DecodeProfileRules(viper.Get("host_profiles"))
+	if err != nil {
+		return nil, fmt.Errorf("error in %s: %w", initFilePath, err)
+	}
+	initConfig.HostProfiles = rules
+
 	// Set user-defined variables and environment variables
 	if err := setUserDefinedAndEnvVariables(&initConfig); err != nil {
 		return nil, fmt.Errorf("error setting variables: %w", err)
@@ -1,6 +1,7 @@
 package processors
 
 import (
+	"strconv"
 	"sync"
 
 	"charm.land/log/v2"
@@ -73,7 +74,23 @@ func journalAppend(processor, provider, name, action string, status types.Status
 		return
 	case types.StatusOK, types.StatusFailed, types.StatusUnknown, types.StatusPresent:
 	}
+	journalWrite(writer, processor, provider, name, action, string(status), status == types.StatusOK, detail, identity)
+}
+
+// journalRetry records a failed attempt that will be tried again. Its outcome
+// is "retry", never OK, so it documents the attempt without counting as an
+// apply; the item's own entry follows once the attempts are over.
+func journalRetry(processor, provider, name, action, detail string, attempt int) {
+	journalMu.Lock()
+	writer := journal
+	journalMu.Unlock()
+	if writer == nil {
+		return
+	}
+	journalWrite(writer, processor, provider, name, action, "retry", false, detail, map[string]string{"attempt": strconv.Itoa(attempt)})
+}
 
+func journalWrite(writer *state.Writer, processor, provider, name, action, outcome string, ok bool, detail string, identity map[string]string) {
 	merged := map[string]string{}
 	for key, value := range identity {
 		merged[key] = value
@@ -89,7 +106,7 @@ func journalAppend(processor, provider, name, action string, status types.Status
 		Action:    action,
 		Identity:  merged,
 		Detail:    detail,
-		Outcome:   string(status),
-		OK:        status == types.StatusOK,
+		Outcome:   outcome,
+		OK:        ok,
 	})
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -12,8 +13,10 @@ import (
 	"charm.land/log/v2"
 	"github.com/fynxlabs/rwr/internal/helpers"
 	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/state"
 	"github.com/fynxlabs/rwr/internal/system"
 	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/viper"
 )
 
 func packageCommandEscalates(provider *types.Provider, args []string, name string, cache map[string]bool) bool {
@@ -95,14 +98,15 @@ func brewMetadataEscalates(data []byte) (escalates, known bool) {
 	return false, false
 }
 
-// ProcessPackages installs or removes packages based on blueprint definitions.
+// ProcessPackages installs, removes, upgrades, holds or unholds packages based
+// on blueprint definitions.
 // It supports two modes:
 //   - If data is provided: unmarshals it into package definitions
 //   - If packages is provided: uses the provided package list directly
 //
 // The function resolves import directives recursively (with circular detection),
 // filters packages based on active profiles from initConfig, detects available
-// package managers for the current OS, and executes install/remove commands.
+// package managers for the current OS, and executes each action's command.
 //
 // Returns an error if no package managers are available or if unmarshaling fails.
 // Individual package installation errors are logged but do not stop processing.
@@ -167,11 +171,8 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 	var units []packageUnit
 	brewEscalationCache := make(map[string]bool)
 	track := newProgress(types.BlueprintTypePackages)
+	var installedBy map[string]string
 	for _, pkg := range filteredPackages {
-		// Get provider
-		var provider *types.Provider
-		var exists bool
-
 		// A package entry that cannot reach a package manager is a FAILURE,
 		// not a skip. Blueprint trees are per-OS, so a declared
 		// package_manager is a demand: skipping here meant a machine without
@@ -182,24 +183,6 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 		if subject == "" && len(pkg.Names) > 0 {
 			subject = strings.Join(pkg.Names, " ")
 		}
-		if pkg.PackageManager != "" {
-			// Use specified package manager
-			provider, exists = system.GetProvider(pkg.PackageManager)
-			if !exists {
-				recordFailure("packages", subject,
-					fmt.Errorf("required package manager %q is not available on this system; declare it under packageManagers in the init file (action: install) or install it manually", pkg.PackageManager))
-				track.item(pkg.PackageManager, subject, pkg.Action, types.StatusFailed, "package manager not available", 0)
-				continue
-			}
-		} else {
-			provider, exists = defaultProviderFor(osInfo, available)
-			if !exists {
-				recordFailure("packages", subject,
-					errors.New("no package manager available for this entry; declare one under packageManagers in the init file (action: install) or set package_manager on the entry"))
-				track.item("", subject, pkg.Action, types.StatusFailed, "no package manager available", 0)
-				continue
-			}
-		}
 
 		// Get package names. `names` wins over `name` when both are set,
 		// matching files and fonts - packages had the precedence backwards,
@@ -214,8 +197,66 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 			names = []string{pkg.Name}
 		}
 
-		units = append(units, packageUnit{pkg: pkg, provider: provider, names: names})
-		track.expect(provider.Name, len(names))
+		// An upgrade naming no packages is one unit of work: everything.
+		work := len(names)
+		if work == 0 && pkg.Action == types.ActionUpgrade {
+			work = 1
+		}
+
+		chain := pkg.PackageManager
+		if len(chain) == 0 {
+			chain = initConfig.DefaultPackageManager
+		}
+		switch {
+		case pkg.PackageManager.Pinned():
+			provider, exists := system.GetProvider(chain[0])
+			if !exists {
+				recordFailure("packages", subject,
+					fmt.Errorf("required package manager %q is not available on this system; declare it under packageManagers in the init file (action: install) or install it manually", chain[0]))
+				track.item(chain[0], subject, pkg.Action, types.StatusFailed, "package manager not available", 0)
+				continue
+			}
+			units = append(units, packageUnit{pkg: pkg, provider: provider, names: names})
+			track.expect(provider.Name, work)
+		case len(chain) > 0:
+			providers := chainProviders(chain)
+			if len(providers) == 0 {
+				recordFailure("packages", subject,
+					fmt.Errorf("none of the package managers %s is available on this system; declare one under packageManagers in the init file (action: install) or install it manually", chain))
+				track.item("", subject, pkg.Action, types.StatusFailed, "no package manager in the chain available", 0)
+				continue
+			}
+			if len(names) == 0 && pkg.Action == types.ActionUpgrade {
+				// Everything, through every package manager in the chain.
+				for _, provider := range providers {
+					units = append(units, packageUnit{pkg: pkg, provider: provider})
+					track.expect(provider.Name, 1)
+				}
+				continue
+			}
+			if installedBy == nil {
+				installedBy = recordedPackageProviders()
+			}
+			picks, missing := pickFromChain(providers, names, pkg.Action, installedBy)
+			for _, pick := range picks {
+				units = append(units, packageUnit{pkg: pkg, provider: pick.provider, names: pick.names})
+				track.expect(pick.provider.Name, len(pick.names))
+			}
+			for _, name := range missing {
+				recordFailure("packages", name, fmt.Errorf("none of the package managers %s has %s", chain, name))
+				track.item(providers[0].Name, name, pkg.Action, types.StatusFailed, "not found by "+chain.String(), 0)
+			}
+		default:
+			provider, exists := defaultProviderFor(osInfo, available)
+			if !exists {
+				recordFailure("packages", subject,
+					errors.New("no package manager available for this entry; declare one under packageManagers in the init file (action: install) or set package_manager on the entry"))
+				track.item("", subject, pkg.Action, types.StatusFailed, "no package manager available", 0)
+				continue
+			}
+			units = append(units, packageUnit{pkg: pkg, provider: provider, names: resolveAliases(names, provider)})
+			track.expect(provider.Name, work)
+		}
 	}
 
 	// Homebrew starts Ruby for `brew info`; asking once per package made a
@@ -228,14 +269,11 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 		if !provider.Escalates || filepath.Base(provider.BinPath) != "brew" {
 			continue
 		}
-		if unit.pkg.Action != "install" && unit.pkg.Action != "remove" {
+		command, known := provider.Commands.ForAction(unit.pkg.Action)
+		if !known || command == "" {
 			continue
 		}
-		commandArgs := strings.Fields(provider.Commands.Install)
-		if unit.pkg.Action == "remove" {
-			commandArgs = strings.Fields(provider.Commands.Remove)
-		}
-		commandArgs = append(commandArgs, unit.pkg.Args...)
+		commandArgs := append(strings.Fields(command), unit.pkg.Args...)
 		explicitKind := false
 		for _, arg := range commandArgs {
 			if arg == "--cask" || arg == "--formula" {
@@ -270,20 +308,58 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 		// line this unit's work produces (rwr's own and captured output).
 		reporting.SetCurrentProvider(provider.Name)
 
+		// An upgrade is whatever the network has newer: offline there is
+		// nothing to upgrade to.
+		if pkg.Action == types.ActionUpgrade && system.IsOffline() {
+			names := unit.names
+			if len(names) == 0 {
+				names = []string{upgradeEverythingName}
+			}
+			for _, name := range names {
+				recordFailure("packages", name, errors.New("an upgrade needs the network; run it without --offline"))
+				track.itemIdentity(provider.Name, name, pkg.Action, types.StatusFailed, "offline", 0, packageIdentity(pkg.Action))
+			}
+			continue
+		}
+
+		// An upgrade naming no packages upgrades everything the provider has.
+		if len(unit.names) == 0 && pkg.Action == types.ActionUpgrade {
+			started := time.Now()
+			if err := upgradeEverything(provider, pkg.Elevated, initConfig.Variables.Flags.Debug); err != nil {
+				recordFailure("packages", provider.Name+" "+upgradeEverythingName, err)
+				track.itemIdentity(provider.Name, upgradeEverythingName, pkg.Action, types.StatusFailed, err.Error(), time.Since(started), packageIdentity(pkg.Action))
+				continue
+			}
+			track.itemIdentity(provider.Name, upgradeEverythingName, pkg.Action, types.StatusOK, "", time.Since(started), packageIdentity(pkg.Action))
+			log.Infof("Successfully upgraded all packages via %s", provider.Name)
+			continue
+		}
+
 		// Process each package
 		for _, name := range unit.names {
-			// Build command arguments
-			var args []string
-			switch pkg.Action {
-			case "install":
-				args = append(args, strings.Fields(provider.Commands.Install)...)
-			case "remove":
-				args = append(args, strings.Fields(provider.Commands.Remove)...)
-			default:
+			command, known := provider.Commands.ForAction(pkg.Action)
+			if !known {
 				recordFailure("packages", name, fmt.Errorf("unknown action %q", pkg.Action))
 				track.item(provider.Name, name, pkg.Action, types.StatusFailed, "unknown action", 0)
 				continue
 			}
+			if command == "" {
+				recordFailure("packages", name, fmt.Errorf("%s has no %s command", provider.Name, pkg.Action))
+				track.item(provider.Name, name, pkg.Action, types.StatusFailed, "no "+pkg.Action+" command", 0)
+				continue
+			}
+			bin, args := system.ProviderCommand(provider, command)
+			// Offline, an install comes from what rwr fetch put in the
+			// provider's cache, through its offline install.
+			environment := provider.Environment
+			if pkg.Action == types.ActionInstall && system.IsOffline() {
+				if provider.Offline == nil || provider.Offline.Install == "" {
+					recordFailure("packages", name, fmt.Errorf("%s cannot install offline", provider.Name))
+					track.item(provider.Name, name, pkg.Action, types.StatusFailed, "no offline install", 0)
+					continue
+				}
+				bin, args, environment = system.OfflineCommand(provider, provider.Offline.Install)
+			}
 
 			// A name beginning with "-" is read as an option by every package
 			// manager, not as a package: "--allow-downgrades", "-U <url>". Commands
@@ -304,7 +380,7 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 
 			// Execute command directly with environment variables
 			cmd := types.Command{
-				Exec: provider.BinPath,
+				Exec: bin,
 				Args: args,
 				// The provider decides whether its package manager needs elevation;
 				// a blueprint may ask for it on top (a user-scoped manager invoked
@@ -315,7 +391,7 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 				// before it rather than after it has already hung on a prompt
 				// nobody could see.
 				Escalates: packageCommandEscalates(provider, args, name, brewEscalationCache),
-				Variables: provider.Environment,
+				Variables: environment,
 				// Terminal handover only on an explicit per-item
 				// `interactive: true`. Routing every package through the
 				// terminal suspended the TUI per package and splattered raw
@@ -328,11 +404,11 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 			started := time.Now()
 			if err := system.RunCommand(cmd, initConfig.Variables.Flags.Debug); err != nil {
 				recordFailure("packages", name, fmt.Errorf("%s failed: %w", pkg.Action, err))
-				track.item(provider.Name, name, pkg.Action, types.StatusFailed, err.Error(), time.Since(started))
+				track.itemIdentity(provider.Name, name, pkg.Action, types.StatusFailed, err.Error(), time.Since(started), packageIdentity(pkg.Action))
 				continue
 			}
 
-			track.item(provider.Name, name, pkg.Action, types.StatusOK, "", time.Since(started))
+			track.itemIdentity(provider.Name, name, pkg.Action, types.StatusOK, "", time.Since(started), packageIdentity(pkg.Action))
 			log.Infof("Successfully %s package %s via %s", pastTense(pkg.Action), name, provider.Name)
 		}
 	}
@@ -341,6 +417,160 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 	return nil
 }
 
+// packageIdentity is the journal identity an action adds to provider and
+// name. A hold and an upgrade are kept apart from the install of the same
+// package - an uninstall unholds what was held and removes only what was
+// installed - while hold and unhold share one, so the later replaces the
+// earlier.
+func packageIdentity(action string) map[string]string {
+	switch action {
+	case types.ActionHold, types.ActionUnhold:
+		return map[string]string{"kind": types.ActionHold}
+	case types.ActionUpgrade:
+		return map[string]string{"kind": types.ActionUpgrade}
+	}
+	return nil
+}
+
+// resolveAliases maps an unpinned entry's names through the package alias
+// table to what provider calls them. A logical name the table has no name
+// for on provider is installed as written, with a warning: it may not be
+// what provider calls it.
+func resolveAliases(names []string, provider *types.Provider) []string {
+	table := system.PackageAliases()
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		providerName, mapped := table.Resolve(name, provider)
+		if !mapped {
+			log.Warnf("Package alias %q has no name for %s; installing it as written", name, provider.Name)
+		} else if providerName != name {
+			log.Debugf("Package alias %q resolves to %q for %s", name, providerName, provider.Name)
+		}
+		resolved = append(resolved, providerName)
+	}
+	return resolved
+}
+
+// chainProviders returns the providers of a fallback chain this machine has,
+// in the chain's order.
+func chainProviders(chain types.ProviderChain) []*types.Provider {
+	var providers []*types.Provider
+	for _, name := range chain {
+		if provider, ok := system.GetProvider(name); ok {
+			providers = append(providers, provider)
+		} else {
+			log.Debugf("Package manager %s in %s is not available; skipping it", name, chain)
+		}
+	}
+	return providers
+}
+
+// chainPick is the names of one entry that go through one provider of its
+// chain.
+type chainPick struct {
+	provider *types.Provider
+	names    []string
+}
+
+// pickFromChain chooses, name by name, the provider of a fallback chain each
+// of an entry's packages goes through, under what the alias table says that
+// provider calls it. Any action takes the provider installedBy records
+// installing it while the chain still lists it: an earlier provider that
+// has since gained the package must not install a second copy. Otherwise an
+// install takes the first provider that has the package - by chainHas, so a
+// run and fetch choose alike - and the ones none of them has come back as
+// missing; every other action - remove, upgrade, hold - takes the chain's
+// first.
+func pickFromChain(providers []*types.Provider, names []string, action string, installedBy map[string]string) ([]chainPick, []string) {
+	table := system.PackageAliases()
+	var has func(*types.Provider, string) bool
+	if action == types.ActionInstall {
+		has = chainHas()
+	}
+	var picks []chainPick
+	var missing []string
+	add := func(provider *types.Provider, name string) {
+		for i := range picks {
+			if picks[i].provider == provider {
+				picks[i].names = append(picks[i].names, name)
+				return
+			}
+		}
+		picks = append(picks, chainPick{provider: provider, names: []string{name}})
+	}
+
+	for _, logical := range names {
+		if strings.HasPrefix(logical, "-") {
+			add(providers[0], logical) // refused by the run loop, which says why
+			continue
+		}
+		pick := func(choose func(provider *types.Provider, name string) bool) bool {
+			for _, provider := range providers {
+				name, _ := table.Resolve(logical, provider)
+				if choose(provider, name) {
+					log.Debugf("Package %s goes through %s as %s", logical, provider.Name, name)
+					add(provider, name)
+					return true
+				}
+			}
+			return false
+		}
+		if pick(func(provider *types.Provider, name string) bool { return installedBy[name] == provider.Name }) {
+			continue
+		}
+		if action == types.ActionInstall && pick(func(provider *types.Provider, name string) bool {
+			// Nothing is asked in a dry run; the chain's first provider
+			// stands in for whichever would have it.
+			return system.IsDryRun() || has(provider, name)
+		}) {
+			continue
+		}
+		if action != types.ActionInstall {
+			// Never recorded: act on it where the chain would install it
+			// first. An unknown action is reported by the run loop.
+			name, _ := table.Resolve(logical, providers[0])
+			add(providers[0], name)
+			continue
+		}
+		missing = append(missing, logical)
+	}
+	return picks, missing
+}
+
+// chainHas is how a fallback chain learns whether a provider has a package:
+// by asking the provider, or offline - where asking would reach the network -
+// by whether fetch put it in that provider's part of the cache, the only
+// place an offline install can take it from.
+func chainHas() func(*types.Provider, string) bool {
+	if !system.IsOffline() {
+		return system.PackageAvailable
+	}
+	fetched, err := system.FetchedPackages()
+	if err != nil {
+		log.Warnf("Reading the offline cache's package index: %v", err)
+	}
+	return func(provider *types.Provider, name string) bool {
+		return slices.Contains(fetched[provider.Name], name)
+	}
+}
+
+// recordedPackageProviders maps each package the journal records installed,
+// and not since uninstalled, to the provider that installed it.
+func recordedPackageProviders() map[string]string {
+	installedBy := map[string]string{}
+	entries, err := state.Unreversed(viper.GetString("rwr.configdir"))
+	if err != nil {
+		log.Debugf("Reading the run journal for package providers: %v", err)
+		return installedBy
+	}
+	for _, entry := range entries {
+		if entry.Processor == types.BlueprintTypePackages && entry.Action == types.ActionInstall {
+			installedBy[entry.Identity["name"]] = entry.Identity["provider"]
+		}
+	}
+	return installedBy
+}
+
 // defaultProviderFor picks the provider to use for a package that did not name a
 // package_manager.
 //
@@ -351,8 +581,12 @@ func ProcessPackages(data []byte, packages *types.PackagesData, blueprintDir str
 // first" meant an unqualified package could be installed by a different package
 // manager on every run.
 // pastTense renders a package action for the success message. The old
-// "%sed" format produced "removeed": actions ending in "e" only take a "d".
+// "%sed" format produced "removeed": actions ending in "e" only take a "d",
+// and hold is irregular.
 func pastTense(action string) string {
+	if before, ok := strings.CutSuffix(action, "hold"); ok {
+		return before + "held"
+	}
 	if strings.HasSuffix(action, "e") {
 		return action + "d"
 	}
@@ -1,6 +1,7 @@
 package processors
 
 import (
+	"errors"
 	"runtime"
 	"strings"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/fynxlabs/rwr/internal/helpers"
 	"github.com/fynxlabs/rwr/internal/system"
 	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/viper"
 )
 
 // Test blueprint parsing without calling the actual ProcessPackages function.
@@ -41,7 +43,7 @@ packages:
 	if pkgData.Packages[0].Action != "install" {
 		t.Errorf("Expected first package action to be 'install', got '%s'", pkgData.Packages[0].Action)
 	}
-	if pkgData.Packages[0].PackageManager != "auto" {
+	if pkgData.Packages[0].PackageManager.String() != "auto" {
 		t.Errorf("Expected first package manager to be 'auto', got '%s'", pkgData.Packages[0].PackageManager)
 	}
 
@@ -167,7 +169,7 @@ func TestProcessPackages_PackageStructure(t *testing.T) {
 			pkg: types.Package{
 				Name:           "vim",
 				Action:         "install",
-				PackageManager: "apt",
+				PackageManager: types.ProviderChain{"apt"},
 			},
 			expectValid: true,
 		},
@@ -198,8 +200,8 @@ func TestProcessPackages_PackageStructure(t *testing.T) {
 			}
 
 			// Test package manager field
-			if tc.pkg.PackageManager != "" {
-				if tc.pkg.PackageManager == "" {
+			if len(tc.pkg.PackageManager) > 0 {
+				if tc.pkg.PackageManager[0] == "" {
 					t.Error("PackageManager should not be empty when set")
 				}
 			}
@@ -774,3 +776,190 @@ func TestSeedBrewEscalationCacheSkipsOnlyOptionLikeNames(t *testing.T) {
 		t.Fatalf("brew info calls = %d, want 0: %v", len(rec.Calls), rec.Calls)
 	}
 }
+
+// A fallback chain installs each package through the first of its providers
+// that has it - asked through info where a provider has one, search where it
+// does not - skips the providers this machine lacks, and fails the packages
+// none of them has.
+func TestProcessPackages_ChainFallsThrough(t *testing.T) {
+	bin := "sh"
+	if runtime.GOOS == types.OSWindows {
+		bin = "cmd"
+	}
+	detection := types.DetectionConfig{Binary: bin, Distributions: []string{types.OSLinux, types.OSDarwin, types.OSWindows}}
+	defer system.SetProvidersForTest(map[string]*types.Provider{
+		"apt":     {Name: "apt", Detection: detection, Commands: types.CommandConfig{Install: "apt-install", Info: "show"}},
+		"flatpak": {Name: "flatpak", Detection: detection, Commands: types.CommandConfig{Install: "flatpak-install", Search: "search"}},
+		"cargo":   {Name: "cargo", Detection: types.DetectionConfig{Binary: "no-such-binary-rwr", Distributions: detection.Distributions}, Commands: types.CommandConfig{Install: "cargo-install"}},
+	})()
+	rec := exectest.New()
+	rec.Respond = func(call exectest.Call) (string, error) {
+		name := call.Args[len(call.Args)-1]
+		switch {
+		case call.Args[0] == "show" && name == "git":
+			return "Package: git\n", nil
+		case call.Args[0] == "search" && name == "ripgrep":
+			return "ripgrep\tFast grep\torg.example.ripgrep\n", nil
+		case call.Args[0] == "search":
+			return "ripgrep-extras\tNot it\n", nil
+		}
+		return "", errors.New("exit status 100")
+	}
+	defer system.SetExecutor(rec)()
+	resetFailures()
+	t.Cleanup(resetFailures)
+
+	data := []byte("packages:\n  - names: [git, ripgrep, nonesuch]\n    action: install\n    package_manager: [cargo, apt, flatpak]\n")
+	if err := ProcessPackages(data, nil, t.TempDir(), "yaml", newTestOSInfo(), &types.InitConfig{}); err != nil {
+		t.Fatalf("ProcessPackages: %v", err)
+	}
+
+	installs := map[string]string{}
+	for _, call := range rec.Calls {
+		if strings.HasSuffix(call.Args[0], "-install") {
+			installs[call.Args[len(call.Args)-1]] = call.Args[0]
+		}
+	}
+	want := map[string]string{"git": "apt-install", "ripgrep": "flatpak-install"}
+	if len(installs) != len(want) || installs["git"] != want["git"] || installs["ripgrep"] != want["ripgrep"] {
+		t.Fatalf("installs = %v, want %v", installs, want)
+	}
+	err := failureError()
+	if err == nil || !strings.Contains(err.Error(), "nonesuch") {
+		t.Fatalf("a package no provider in the chain has was not a failure: %v", err)
+	}
+}
+
+// A package the journal records installed through a later provider of a
+// chain stays with it when an earlier provider gains the package: a second
+// copy through another manager is not an install.
+func TestProcessPackages_ChainKeepsTheRecordedProvider(t *testing.T) {
+	bin := "sh"
+	if runtime.GOOS == types.OSWindows {
+		bin = "cmd"
+	}
+	detection := types.DetectionConfig{Binary: bin, Distributions: []string{types.OSLinux, types.OSDarwin, types.OSWindows}}
+	defer system.SetProvidersForTest(map[string]*types.Provider{
+		"apt":     {Name: "apt", Detection: detection, Commands: types.CommandConfig{Install: "apt-install", Info: "show"}},
+		"flatpak": {Name: "flatpak", Detection: detection, Commands: types.CommandConfig{Install: "flatpak-install", Info: "info"}},
+	})()
+	aptHasGit := false
+	rec := exectest.New()
+	rec.Respond = func(call exectest.Call) (string, error) {
+		if call.Args[0] == "show" && !aptHasGit {
+			return "", errors.New("exit status 100")
+		}
+		return "", nil
+	}
+	defer system.SetExecutor(rec)()
+	viper.Set("rwr.configdir", t.TempDir())
+	defer viper.Set("rwr.configdir", "")
+	resetFailures()
+	t.Cleanup(resetFailures)
+
+	data := []byte("packages:\n  - names: [git]\n    action: install\n    package_manager: [apt, flatpak]\n")
+	run := func() []string {
+		t.Helper()
+		rec.Calls = nil
+		openJournal("tree")
+		defer closeJournal()
+		if err := ProcessPackages(data, nil, t.TempDir(), "yaml", newTestOSInfo(), &types.InitConfig{}); err != nil {
+			t.Fatalf("ProcessPackages: %v", err)
+		}
+		var installs []string
+		for _, call := range rec.Calls {
+			if strings.HasSuffix(call.Args[0], "-install") {
+				installs = append(installs, strings.Join(call.Args, " "))
+			}
+		}
+		return installs
+	}
+
+	if installs := run(); strings.Join(installs, "|") != "flatpak-install git" {
+		t.Fatalf("first run installs = %q, want git through flatpak", installs)
+	}
+	aptHasGit = true
+	if installs := run(); strings.Join(installs, "|") != "flatpak-install git" {
+		t.Fatalf("second run installs = %q, want git kept on flatpak", installs)
+	}
+}
+
+// The init file's default_package_manager chain stands in for the default
+// provider of entries that pin none.
+func TestProcessPackages_DefaultChain(t *testing.T) {
+	useTestProvider(t)
+	rec := exectest.New()
+	defer system.SetExecutor(rec)()
+	resetFailures()
+	t.Cleanup(resetFailures)
+
+	initConfig := &types.InitConfig{DefaultPackageManager: types.ProviderChain{"cargo", "pacman"}}
+	data := []byte("packages:\n  - name: vim\n    action: install\n")
+	if err := ProcessPackages(data, nil, t.TempDir(), "yaml", newTestOSInfo(), initConfig); err != nil {
+		t.Fatalf("ProcessPackages: %v", err)
+	}
+
+	// pacman's -Ss search ran, found nothing (the recorder prints nothing),
+	// and so nothing was installed.
+	if len(rec.Calls) != 1 || rec.Calls[0].Args[0] != "-Ss" {
+		t.Fatalf("calls = %v, want the one pacman search", rec.Calls)
+	}
+	if err := failureError(); err == nil || !strings.Contains(err.Error(), "vim") {
+		t.Fatalf("a package the default chain cannot find was not a failure: %v", err)
+	}
+}
+
+// upgrade, hold and unhold run the provider's own command for each, and an
+// upgrade naming nothing runs its update and then its upgrade-all. A manager
+// without a hold command fails the hold rather than quietly installing.
+func TestProcessPackages_UpgradeAndHold(t *testing.T) {
+	bin := "sh"
+	if runtime.GOOS == types.OSWindows {
+		bin = "cmd"
+	}
+	detection := types.DetectionConfig{Binary: bin, Distributions: []string{types.OSLinux, types.OSDarwin, types.OSWindows}}
+	defer system.SetProvidersForTest(map[string]*types.Provider{
+		"apt": {Name: "apt", Detection: detection, Commands: types.CommandConfig{
+			Install: "install -y", Update: "update", Upgrade: "install --only-upgrade -y", UpgradeAll: "upgrade -y",
+			Hold: "hold", Unhold: "unhold",
+		}},
+		"pacman": {Name: "pacman", Detection: detection, Commands: types.CommandConfig{Install: "-S", Update: "-Syu"}},
+	})()
+	rec := exectest.New()
+	defer system.SetExecutor(rec)()
+	resetFailures()
+	t.Cleanup(resetFailures)
+
+	data := []byte(`packages:
+  - names: [git]
+    action: upgrade
+    package_manager: apt
+  - action: upgrade
+    package_manager: apt
+  - names: [vim]
+    action: hold
+    package_manager: apt
+  - names: [curl]
+    action: unhold
+    package_manager: apt
+  - names: [linux]
+    action: hold
+    package_manager: pacman
+`)
+	if err := ProcessPackages(data, nil, t.TempDir(), "yaml", newTestOSInfo(), &types.InitConfig{}); err != nil {
+		t.Fatalf("ProcessPackages: %v", err)
+	}
+
+	var calls []string
+	for _, call := range rec.Calls {
+		calls = append(calls, strings.Join(call.Args, " "))
+	}
+	want := []string{"install --only-upgrade -y git", "update", "upgrade -y", "hold vim", "unhold curl"}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Fatalf("calls = %q, want %q", calls, want)
+	}
+	err := failureError()
+	if err == nil || !strings.Contains(err.Error(), "pacman has no hold command") {
+		t.Fatalf("a hold pacman cannot place was not a failure: %v", err)
+	}
+}
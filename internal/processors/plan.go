@@ -81,15 +81,58 @@ func ResolveStage1(initConfig *types.InitConfig) (*types.Plan, error) {
 				continue
 			}
 
+			// Entries whose `when:` is false on this machine are cut out here,
+			// as the run loop cuts them, and kept aside so stage 2 can list
+			// them as skipped. A condition that cannot be evaluated leaves the
+			// file whole; the run will stop on it.
+			kept, keptFormat, skipped, whenErr := helpers.FilterWhen(subset, subsetFormat, initConfig.Variables)
+			if whenErr != nil {
+				plan.Diags = append(plan.Diags, types.Diagnostic{Severity: types.SeverityError, Processor: processor, File: path, Msg: whenErr.Error()})
+			} else {
+				subset, subsetFormat = kept, keptFormat
+			}
+
 			plan.Files[processor] = append(plan.Files[processor], types.ResolvedFile{
 				Path:      path,
 				Processor: processor,
 				Format:    subsetFormat,
 				Raw:       raw,
 				Resolved:  subset,
+				Skipped:   skipped,
 			})
 		}
 	}
 
 	return plan, nil
 }
+
+// resolvedCleanly resolves the tree for a command that reads it whole before
+// acting - prune, fetch - and refuses one that does not resolve cleanly: a
+// blueprint that failed to decode would leave out everything it declares.
+// restore undoes the template variables imported files are resolved against,
+// the same as a run's; call it when done with the plan.
+func resolvedCleanly(initConfig *types.InitConfig) (plan *types.Plan, restore func(), err error) {
+	plan, err = ResolveStage1(initConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, diag := range plan.Diags {
+		if diag.Severity == types.SeverityError {
+			return nil, nil, fmt.Errorf("%s does not resolve (%s); run rwr validate", diag.File, diag.Msg)
+		}
+	}
+	return plan, helpers.SetTemplateVariables(&initConfig.Variables), nil
+}
+
+// blueprintDecoder decodes one processor's blueprint documents, under the
+// tree's schema version, into the entries items picks out of them.
+func blueprintDecoder[D any, T any](initConfig *types.InitConfig, processor string, items func(D) []T) func(data []byte, format string) ([]T, error) {
+	return func(data []byte, format string) ([]T, error) {
+		var d D
+		if err := helpers.DecodeBlueprintInto(data, format, processor,
+			helpers.TreeSchemaVersion(initConfig), &d); err != nil {
+			return nil, err
+		}
+		return items(d), nil
+	}
+}
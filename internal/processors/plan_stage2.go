@@ -1,6 +1,8 @@
 package processors
 
 import (
+	"path/filepath"
+
 	"github.com/fynxlabs/rwr/internal/helpers"
 	"github.com/fynxlabs/rwr/internal/system"
 	"github.com/fynxlabs/rwr/internal/types"
@@ -18,7 +20,13 @@ import (
 // LaneUpdate (which keys on the resolved provider) ever touched, so the
 // checklist showed a full brew bar and a ghost pending lane forever.
 func ResolveStage2(plan *types.Plan, osInfo *types.OSInfo) {
-	available := system.GetAvailableProviders()
+	ResolveStage2For(plan, osInfo, system.GetAvailableProviders())
+}
+
+// ResolveStage2For is ResolveStage2 against a given provider set instead of
+// the providers detected here. Validate uses it to plan for a simulated
+// machine (system.ProvidersFor) the same way a run plans for this one.
+func ResolveStage2For(plan *types.Plan, osInfo *types.OSInfo, available map[string]*types.Provider) {
 	for name, provider := range available {
 		plan.Providers = append(plan.Providers, types.ProviderState{
 			Name:      name,
@@ -27,43 +35,88 @@ func ResolveStage2(plan *types.Plan, osInfo *types.OSInfo) {
 		})
 	}
 
-	defaultProvider := ""
+	defaults := providerDefaults{available: available}
 	if provider, ok := defaultProviderFor(osInfo, available); ok {
-		defaultProvider = provider.Name
+		defaults.provider = provider.Name
 	}
+	if plan.Init != nil {
+		defaults.chain = plan.Init.DefaultPackageManager
+	}
+	plan.DefaultProvider = defaults.provider
 
+	runOnceDir := ""
+	if plan.Init != nil {
+		runOnceDir = plan.Init.Variables.Flags.RunOnceLocation
+	}
 	for processor, files := range plan.Files {
 		for _, file := range files {
-			plan.Resources = append(plan.Resources, enumerateResources(processor, file, defaultProvider)...)
+			plan.Resources = append(plan.Resources, enumerateResources(processor, file, defaults, runOnceDir)...)
+			plan.Resources = append(plan.Resources, whenSkippedResources(processor, file, defaults, runOnceDir)...)
+		}
+	}
+}
+
+// providerDefaults is what package and repository entries that pin no
+// package_manager resolve to: the machine's default provider, or the init
+// file's default_package_manager chain when it sets one. available, when
+// known, places a fallback chain's entries under the first of its providers
+// the machine has.
+type providerDefaults struct {
+	provider  string
+	chain     types.ProviderChain
+	available map[string]*types.Provider
+}
+
+// forPackage returns the provider a package entry pinning pinned is planned
+// under and, for a fallback chain, the chain itself - a run may install
+// through a later provider in it than the one planned.
+func (d providerDefaults) forPackage(pinned types.ProviderChain) (string, types.ProviderChain) {
+	chain := pinned
+	if len(chain) == 0 {
+		chain = d.chain
+	}
+	switch {
+	case len(chain) == 0:
+		return d.provider, nil
+	case pinned.Pinned():
+		return chain[0], nil
+	}
+	for _, name := range chain {
+		if _, ok := d.available[name]; ok {
+			return name, chain
 		}
 	}
+	return "", chain
 }
 
 // enumerateResources lists the planned units of work one resolved file
 // declares. Decode failures return nothing here: stage 1 already reported
 // them as diagnostics, and stage 2 must not duplicate the noise.
-// defaultProvider fills in for package/repository entries that do not pin a
+// defaults fill in for package/repository entries that do not pin a
 // package_manager, matching what the executor will resolve at run time.
-func enumerateResources(processor string, file types.ResolvedFile, defaultProvider string) []types.Resource {
+// runOnceDir locates run_once scripts' markers for status to read.
+func enumerateResources(processor string, file types.ResolvedFile, defaults providerDefaults, runOnceDir string) []types.Resource {
 	usesProviders := processor == types.BlueprintTypePackages || processor == types.BlueprintTypeRepositories
 	var resources []types.Resource
-	addAt := func(provider, name, action, location string) {
+	addAt := func(provider, name, action, location string, profiles []string) {
 		if name == "" {
 			return
 		}
 		if provider == "" && usesProviders {
-			provider = defaultProvider
+			provider = defaults.provider
 		}
 		resources = append(resources, types.Resource{
 			Processor: processor,
 			Provider:  provider,
 			Name:      name,
 			Location:  location,
+			File:      file.Path,
+			Profiles:  profiles,
 			Action:    action,
 			Status:    types.StatusPlanned,
 		})
 	}
-	add := func(provider, name, action string) { addAt(provider, name, action, "") }
+	add := func(provider, name, action string, profiles []string) { addAt(provider, name, action, "", profiles) }
 
 	switch processor {
 	case types.BlueprintTypePackages:
@@ -76,8 +129,29 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			if len(names) == 0 && pkg.Name != "" {
 				names = []string{pkg.Name}
 			}
+			provider, chain := defaults.forPackage(pkg.PackageManager)
 			for _, name := range names {
-				add(pkg.PackageManager, name, pkg.Action)
+				// Named as the run will install it: a logical name through
+				// the alias table for the provider it is planned under.
+				if !pkg.PackageManager.Pinned() && provider != "" {
+					name, _ = system.ResolvePackageName(name, provider)
+				}
+				if chain == nil {
+					add(provider, name, pkg.Action, pkg.Profiles)
+					continue
+				}
+				// A chain with nothing available stays unplaced rather
+				// than falling back to the default provider it replaces.
+				resources = append(resources, types.Resource{
+					Processor:  processor,
+					Provider:   provider,
+					Candidates: chain,
+					Name:       name,
+					File:       file.Path,
+					Profiles:   pkg.Profiles,
+					Action:     pkg.Action,
+					Status:     types.StatusPlanned,
+				})
 			}
 		}
 	case types.BlueprintTypeRepositories:
@@ -86,7 +160,7 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			return nil
 		}
 		for _, repo := range d.Repositories {
-			add(repo.PackageManager, repo.Name, repo.Action)
+			add(repo.PackageManager, repo.Name, repo.Action, repo.Profiles)
 		}
 	case types.BlueprintTypeFiles:
 		var d types.FileData
@@ -103,7 +177,7 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 				if f.Target != "" {
 					location = resolveTargetPath(f.Target, name)
 				}
-				addAt("", name, f.Action, location)
+				addAt("", name, f.Action, location, f.Profiles)
 			}
 		}
 		for _, tmpl := range d.Templates {
@@ -111,7 +185,7 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			if tmpl.Target != "" {
 				location = resolveTargetPath(tmpl.Target, tmpl.Name)
 			}
-			addAt("", tmpl.Name, "template", location)
+			addAt("", tmpl.Name, "template", location, tmpl.Profiles)
 		}
 		for _, dir := range d.Directories {
 			names := dir.Names
@@ -123,7 +197,7 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 				if dir.Target != "" {
 					location = system.ExpandPath(resolveTargetPath(dir.Target, name))
 				}
-				addAt("", name, dir.Action, location)
+				addAt("", name, dir.Action, location, dir.Profiles)
 			}
 		}
 	case types.BlueprintTypeServices:
@@ -132,7 +206,7 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			return nil
 		}
 		for _, svc := range d.Services {
-			add("", svc.Name, svc.Action)
+			add("", svc.Name, svc.Action, svc.Profiles)
 		}
 	case types.BlueprintTypeGit:
 		var d types.GitData
@@ -140,7 +214,7 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			return nil
 		}
 		for _, repo := range d.Repos {
-			addAt("", repo.Name, repo.Action, system.ExpandPath(repo.Path))
+			addAt("", repo.Name, repo.Action, system.ExpandPath(repo.Path), repo.Profiles)
 		}
 	case types.BlueprintTypeScripts:
 		var d types.ScriptData
@@ -148,7 +222,15 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			return nil
 		}
 		for _, script := range d.Scripts {
-			add("", script.Name, script.Action)
+			add("", script.Name, script.Action, script.Profiles)
+			if script.RunOnce && script.Name != "" && runOnceDir != "" {
+				// A run_once script's state is its marker: status compares the
+				// hash recorded there with the content the script has now.
+				if hash, err := scriptContentHash(script, filepath.Dir(file.Path)); err == nil {
+					resources[len(resources)-1].Marker = scriptMarkerPath(runOnceDir, script.Name)
+					resources[len(resources)-1].Desired = hash
+				}
+			}
 		}
 	case types.BlueprintTypeSSHKeys:
 		var d types.SSHKeyData
@@ -156,7 +238,7 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			return nil
 		}
 		for _, key := range d.SSHKeys {
-			add("", key.Name, "ssh_key")
+			add("", key.Name, "ssh_key", key.Profiles)
 		}
 	case types.BlueprintTypeFonts:
 		var d types.FontsData
@@ -169,7 +251,7 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 				names = []string{font.Name}
 			}
 			for _, name := range names {
-				add(font.Provider, name, font.Action)
+				add(font.Provider, name, font.Action, font.Profiles)
 			}
 		}
 	case types.BlueprintTypeUsers:
@@ -178,10 +260,24 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			return nil
 		}
 		for _, user := range d.Users {
-			add("", user.Name, user.Action)
+			add("", user.Name, user.Action, user.Profiles)
+			if user.Action == types.UserActionShell && user.Name != "" {
+				// A login shell is a value, not a presence; status compares it.
+				resources[len(resources)-1].Desired = user.Shell
+			}
 		}
 		for _, group := range d.Groups {
-			add("", group.Name, group.Action)
+			add("", group.Name, group.Action, group.Profiles)
+		}
+		for _, rule := range d.Sudoers {
+			if rule.Name != "" {
+				add("", filepath.Base(SudoersPath(rule)), rule.Action, rule.Profiles)
+			}
+		}
+		for _, rule := range d.Polkit {
+			if rule.Name != "" {
+				add("", filepath.Base(PolkitRulePath(rule)), rule.Action, rule.Profiles)
+			}
 		}
 	case types.BlueprintTypeConfiguration:
 		var d types.ConfigData
@@ -189,12 +285,22 @@ func enumerateResources(processor string, file types.ResolvedFile, defaultProvid
 			return nil
 		}
 		for _, cfg := range d.Configurations {
+			// A setting is a value, not a presence; status reads it back.
+			if planned := plannedSettings(cfg, filepath.Dir(file.Path)); planned != nil {
+				for _, p := range planned {
+					setting := p.setting
+					add("", p.name, "configure", cfg.Profiles)
+					resources[len(resources)-1].Desired = p.desired
+					resources[len(resources)-1].Setting = &setting
+				}
+				continue
+			}
 			names := cfg.Names
 			if len(names) == 0 && cfg.Name != "" {
 				names = []string{cfg.Name}
 			}
 			for _, name := range names {
-				add("", name, "configure")
+				add("", name, "configure", cfg.Profiles)
 			}
 		}
 	}
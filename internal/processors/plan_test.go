@@ -85,7 +85,7 @@ func TestEnumerateResourcesCarriesLocationIdentity(t *testing.T) {
 	files := enumerateResources(types.BlueprintTypeFiles, types.ResolvedFile{
 		Format:   "yaml",
 		Resolved: []byte("files:\n  - name: init.lua\n    action: create\n    target: " + filepath.ToSlash(dir) + "/\n"),
-	}, "")
+	}, providerDefaults{}, "")
 	if len(files) != 1 {
 		t.Fatalf("file resources = %d, want 1", len(files))
 	}
@@ -97,7 +97,7 @@ func TestEnumerateResourcesCarriesLocationIdentity(t *testing.T) {
 	git := enumerateResources(types.BlueprintTypeGit, types.ResolvedFile{
 		Format:   "yaml",
 		Resolved: []byte("git:\n  - name: source\n    action: clone\n    path: " + filepath.ToSlash(checkout) + "\n"),
-	}, "")
+	}, providerDefaults{}, "")
 	if len(git) != 1 {
 		t.Fatalf("git resources = %d, want 1", len(git))
 	}
@@ -105,3 +105,53 @@ func TestEnumerateResourcesCarriesLocationIdentity(t *testing.T) {
 		t.Errorf("git location = %q, want %q", git[0].Location, checkout)
 	}
 }
+
+// An entry whose `when:` is false on this machine is cut from the resolved
+// file and planned as skipped, with the condition as the reason; without
+// gathered facts (validate) nothing is cut.
+func TestResolveStage1_WhenSkipsEntries(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "services.yaml"), []byte(`services:
+  - name: sshd
+    action: enable
+  - name: nvidia-persistenced
+    action: enable
+    when: eq .Facts.gpuVendor "nvidia"
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	initConfig := &types.InitConfig{}
+	initConfig.Init.Location = dir
+	initConfig.Init.Format = "yaml"
+	initConfig.Variables.Facts = &types.Facts{GPUVendor: "intel"}
+
+	plan, err := ResolveStage1(initConfig)
+	if err != nil {
+		t.Fatalf("ResolveStage1: %v", err)
+	}
+	if len(plan.Diags) != 0 {
+		t.Fatalf("diagnostics: %+v", plan.Diags)
+	}
+	ResolveStage2For(plan, nil, map[string]*types.Provider{})
+
+	statuses := map[string]types.Resource{}
+	for _, r := range plan.Resources {
+		statuses[r.Name] = r
+	}
+	if statuses["sshd"].Status != types.StatusPlanned {
+		t.Errorf("sshd = %+v, want planned", statuses["sshd"])
+	}
+	skipped := statuses["nvidia-persistenced"]
+	if skipped.Status != types.StatusSkipped || skipped.Detail != `when: eq .Facts.gpuVendor "nvidia"` {
+		t.Errorf("nvidia-persistenced = %+v, want skipped with its condition", skipped)
+	}
+
+	initConfig.Variables.Facts = nil
+	plan, err = ResolveStage1(initConfig)
+	if err != nil {
+		t.Fatalf("ResolveStage1: %v", err)
+	}
+	if files := plan.Files[types.BlueprintTypeServices]; len(files) != 1 || len(files[0].Skipped) != 0 {
+		t.Errorf("without facts, entries were skipped: %+v", files)
+	}
+}
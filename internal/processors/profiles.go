@@ -179,10 +179,9 @@ func collectFrom[T interface{ GetProfiles() []string }](summary *ProfileSummary,
 			summary.BaseItems++
 			continue
 		}
-		for _, profile := range profiles {
-			if profile == "" {
-				continue
-			}
+		// Every name an entry refers to counts, excluded ones included:
+		// "!server" is what makes --profile server mean something to it.
+		for _, profile := range helpers.ProfileNames(profiles) {
 			summary.Counts[profile]++
 		}
 	}
@@ -89,3 +89,24 @@ func (p *progress) emitLane(provider string) {
 		Status:    status,
 	})
 }
+
+// retry reports a failed attempt at an item that will run again after delay.
+// It does not count toward the lane: the item's one item() call still comes,
+// with the final outcome.
+func (p *progress) retry(provider, name, action, detail string, attempt, attempts int, delay, dur time.Duration) {
+	reporting.Emit(reporting.ResourceRetry{
+		Resource: types.Resource{
+			Processor: p.processor,
+			Provider:  provider,
+			Name:      name,
+			Action:    action,
+			Status:    types.StatusFailed,
+			Detail:    detail,
+			Dur:       dur,
+		},
+		Attempt:  attempt,
+		Attempts: attempts,
+		Delay:    delay,
+	})
+	journalRetry(p.processor, provider, name, action, detail, attempt)
+}
@@ -0,0 +1,349 @@
+package processors
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/scan"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// pruneScan lists what each provider has explicitly installed. The list
+// verbs run outside the executor, so tests stand in for the scan here.
+var pruneScan = scan.Packages
+
+// PruneCandidates is what pruning would remove: on each provider the init
+// file's prune block opts in, the explicitly installed packages no entry in
+// the tree declares - bootstrap, imports and `when:`-skipped entries
+// included - and its keep list does not protect. They come back as packages
+// removals, in provider order.
+//
+// A provider without a list_explicit query is never pruned: its full list
+// is every dependency too, and removing those would take the declared
+// packages with them.
+func PruneCandidates(initConfig *types.InitConfig) ([]types.Resource, error) {
+	config := initConfig.Prune
+	if config == nil || len(config.Providers) == 0 {
+		log.Info("No package managers are opted into pruning; see prune in the init file")
+		return nil, nil
+	}
+	if err := system.InitProviders(); err != nil {
+		return nil, fmt.Errorf("error initializing providers: %w", err)
+	}
+	available := system.GetAvailableProviders()
+	opted := map[string]*types.Provider{}
+	for _, name := range config.Providers {
+		if provider, ok := available[name]; ok {
+			opted[name] = provider
+		} else {
+			log.Debugf("Package manager %s is opted into pruning but not available; skipping it", name)
+		}
+	}
+	if len(opted) == 0 {
+		return nil, nil
+	}
+
+	declared, err := declaredPackageNames(initConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	table := system.PackageAliases()
+	var candidates []types.Resource
+	for _, result := range pruneScan(opted) {
+		if result.Unfiltered {
+			log.Warnf("Not pruning %s: it has no list_explicit query, and its full list includes every dependency", result.Provider)
+			continue
+		}
+		provider := opted[result.Provider]
+		// What the tree calls each package, as written and as this
+		// provider calls it: brew lists a tap's formula by its short name.
+		keep := map[string]bool{}
+		for _, name := range declared {
+			keep[name] = true
+			keep[path.Base(name)] = true
+			resolved, _ := table.Resolve(name, provider)
+			keep[resolved] = true
+		}
+		for _, name := range result.Names {
+			if keep[name] || kept(config.Keep[result.Provider], name) {
+				continue
+			}
+			candidates = append(candidates, types.Resource{
+				Processor: types.BlueprintTypePackages,
+				Provider:  result.Provider,
+				Name:      name,
+				Action:    types.ActionRemove,
+				Status:    types.StatusPlanned,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// kept reports whether a keep list protects name: an entry is a name or a
+// path.Match glob.
+func kept(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if pattern == name {
+			return true
+		}
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// declaredPackageNames is every package name the tree declares with any
+// action but remove, whatever its profiles or `when:` say on this machine:
+// pruning is for what the tree has forgotten, not what this run skips. The
+// packages rwr installs on its own count too: a shell action's shell package
+// and the init file's packageManagers. A tree that does not resolve cleanly
+// is refused outright - a blueprint that failed to decode would make
+// everything it declares look undeclared.
+func declaredPackageNames(initConfig *types.InitConfig) ([]string, error) {
+	plan, restore, err := resolvedCleanly(initConfig)
+	if err != nil {
+		return nil, fmt.Errorf("not pruning: %w", err)
+	}
+	defer restore()
+
+	var declared []string
+	collect := func(packages []types.Package) {
+		for _, pkg := range packages {
+			if pkg.Action == types.ActionRemove {
+				continue
+			}
+			if pkg.Name != "" {
+				declared = append(declared, pkg.Name)
+			}
+			declared = append(declared, pkg.Names...)
+		}
+	}
+	decode := blueprintDecoder(initConfig, types.BlueprintTypePackages,
+		func(d types.PackagesData) []types.Package { return d.Packages })
+
+	collectShells := func(users []types.User) {
+		for _, user := range users {
+			if user.Action != types.UserActionShell || user.Shell == "" {
+				continue
+			}
+			if user.ShellPackage != "" {
+				declared = append(declared, user.ShellPackage)
+			} else {
+				declared = append(declared, filepath.Base(user.Shell))
+			}
+		}
+	}
+
+	for _, document := range allDocuments(plan.Files[types.BlueprintTypePackages]) {
+		packages, err := decode(document.Resolved, document.Format)
+		if err != nil {
+			return nil, fmt.Errorf("not pruning: %s: %w", document.Path, err)
+		}
+		packages, err = helpers.ResolveImports(packages, filepath.Dir(document.Path),
+			func(item types.Package) string { return item.Import }, decode, document.Format)
+		if err != nil {
+			return nil, fmt.Errorf("not pruning: %s: %w", document.Path, err)
+		}
+		collect(packages)
+	}
+
+	decodeUsers := blueprintDecoder(initConfig, types.BlueprintTypeUsers,
+		func(d types.UsersData) []types.User { return d.Users })
+	for _, document := range allDocuments(plan.Files[types.BlueprintTypeUsers]) {
+		users, err := decodeUsers(document.Resolved, document.Format)
+		if err != nil {
+			return nil, fmt.Errorf("not pruning: %s: %w", document.Path, err)
+		}
+		users, err = processUserImports(users, filepath.Dir(document.Path), document.Format, helpers.TreeSchemaVersion(initConfig))
+		if err != nil {
+			return nil, fmt.Errorf("not pruning: %s: %w", document.Path, err)
+		}
+		collectShells(users)
+	}
+
+	if bootstrapFile := findBootstrapFile(initConfig.Init.Location); bootstrapFile != "" {
+		bootstrap, err := readBootstrap(bootstrapFile, initConfig)
+		if err != nil {
+			return nil, fmt.Errorf("not pruning: %s: %w", bootstrapFile, err)
+		}
+		collect(bootstrap.Packages)
+		collectShells(bootstrap.Users)
+	}
+
+	for _, manager := range initConfig.PackageManagers {
+		if manager.Name != "" {
+			declared = append(declared, manager.Name)
+		}
+	}
+	return declared, nil
+}
+
+// allDocuments is each resolved file with the documents its `when:`
+// conditions skipped on this machine.
+func allDocuments(files []types.ResolvedFile) []types.ResolvedFile {
+	var documents []types.ResolvedFile
+	for _, file := range files {
+		documents = append(documents, file)
+		for _, entry := range file.Skipped {
+			documents = append(documents, types.ResolvedFile{Path: file.Path, Format: types.FormatJSON, Resolved: entry.Document})
+		}
+	}
+	return documents
+}
+
+// readBootstrap reads what a bootstrap blueprint declares, without
+// filtering its `when:` conditions.
+func readBootstrap(bootstrapFile string, initConfig *types.InitConfig) (types.BootstrapData, error) {
+	var bootstrapData types.BootstrapData
+	data, err := os.ReadFile(bootstrapFile) // #nosec G304 -- operator's own blueprint tree
+	if err != nil {
+		return bootstrapData, err
+	}
+	data, err = helpers.ResolveTemplate(data, initConfig.Variables)
+	if err != nil {
+		return bootstrapData, err
+	}
+	format, err := helpers.FormatForPath(bootstrapFile)
+	if err != nil {
+		return bootstrapData, err
+	}
+	err = helpers.DecodeBlueprintInto(data, format, types.BlueprintTypeBootstrap,
+		helpers.TreeSchemaVersion(initConfig), &bootstrapData)
+	return bootstrapData, err
+}
+
+// Prune is `rwr prune`: it removes the candidates, each confirmed first
+// unless assumeYes, through the packages processor's own remove - so each
+// removal is tracked, recorded on failure, and journaled like a blueprint's.
+func Prune(initConfig *types.InitConfig, osInfo *types.OSInfo, candidates []types.Resource, assumeYes bool) error {
+	resetFailures()
+	openJournal(initConfig.Init.Location)
+	defer closeJournal()
+
+	reporting.SetCurrentProcessor(types.BlueprintTypePackages)
+	reporting.Emit(reporting.ProcStarted{Processor: types.BlueprintTypePackages})
+	started := time.Now()
+	err := removeOrphans(initConfig, osInfo, candidates, assumeYes)
+	if err == nil {
+		err = failureError()
+	}
+	reporting.Emit(reporting.ProcFinished{Processor: types.BlueprintTypePackages, Err: err, Dur: time.Since(started)})
+	if err != nil {
+		return err
+	}
+	log.Info("Prune complete")
+	return nil
+}
+
+// prunePackages is the packages processor's --prune: find what the tree no
+// longer declares, then remove it as Prune does. An interactive run asks
+// about each; a non-interactive one - the agent, push, CI - has no one to
+// ask, and takes --prune itself as the confirmation, as rwr prune takes
+// --yes.
+func prunePackages(initConfig *types.InitConfig, osInfo *types.OSInfo) error {
+	candidates, err := PruneCandidates(initConfig)
+	if err != nil {
+		return err
+	}
+	return removeOrphans(initConfig, osInfo, candidates, !initConfig.Variables.Flags.Interactive)
+}
+
+// removeOrphans confirms and removes candidates, one pinned remove entry per
+// provider.
+func removeOrphans(initConfig *types.InitConfig, osInfo *types.OSInfo, candidates []types.Resource, assumeYes bool) error {
+	if len(candidates) == 0 {
+		log.Info("Nothing to prune: every explicitly installed package is declared or kept")
+		return nil
+	}
+	for _, candidate := range candidates {
+		log.Infof("No blueprint declares %s (%s)", candidate.Name, candidate.Provider)
+	}
+
+	confirmed, err := confirmPrune(candidates, initConfig.Variables.Flags.Interactive, assumeYes)
+	if err != nil {
+		return err
+	}
+	if len(confirmed) == 0 {
+		return nil
+	}
+
+	byProvider := map[string][]string{}
+	for _, candidate := range confirmed {
+		byProvider[candidate.Provider] = append(byProvider[candidate.Provider], candidate.Name)
+	}
+	providers := make([]string, 0, len(byProvider))
+	for provider := range byProvider {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	removals := &types.PackagesData{}
+	for _, provider := range providers {
+		removals.Packages = append(removals.Packages, types.Package{
+			Names:          byProvider[provider],
+			Action:         types.ActionRemove,
+			PackageManager: types.ProviderChain{provider},
+		})
+	}
+	return ProcessPackages(nil, removals, initConfig.Init.Location, initConfig.Init.Format, osInfo, initConfig)
+}
+
+// confirmPrune asks about each candidate in turn; "all" takes the rest
+// without asking. Nothing is asked under assumeYes or a dry run. A
+// non-interactive run removes nothing it was not told to: it names what it
+// left and how to remove it.
+func confirmPrune(candidates []types.Resource, interactive, assumeYes bool) ([]types.Resource, error) {
+	if assumeYes || system.IsDryRun() {
+		return candidates, nil
+	}
+	if !interactive {
+		log.Warnf("Not removing %d undeclared package(s) without confirmation; run rwr prune --yes to remove them", len(candidates))
+		return nil, nil
+	}
+	var confirmed []types.Resource
+	for i, candidate := range candidates {
+		yes, all, err := promptPrune(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if all {
+			return append(confirmed, candidates[i:]...), nil
+		}
+		if yes {
+			confirmed = append(confirmed, candidate)
+		}
+	}
+	return confirmed, nil
+}
+
+// promptPrune asks about one removal, inline under the dashboard and on the
+// terminal otherwise, as the overwrite prompt does.
+func promptPrune(candidate types.Resource) (yes, all bool, err error) {
+	prompt := fmt.Sprintf("Remove %s (%s)? No blueprint declares it", candidate.Name, candidate.Provider)
+	if reporting.SupportsInlinePrompts() {
+		return reporting.RequestConfirmationAll(prompt)
+	}
+	var input string
+	err = reporting.WithTerminal(func() error {
+		fmt.Printf("%s (y/n/a=all): ", prompt)
+		_, err := fmt.Scanln(&input)
+		return err
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("reading prune confirmation (run with --interactive=false to skip prompts): %w", err)
+	}
+	all = strings.EqualFold(input, "a") || strings.EqualFold(input, "all")
+	return all || strings.EqualFold(input, "y") || strings.EqualFold(input, "yes"), all, nil
+}
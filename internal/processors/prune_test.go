@@ -0,0 +1,186 @@
+package processors
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/exectest"
+	"github.com/fynxlabs/rwr/internal/scan"
+	"github.com/fynxlabs/rwr/internal/state"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/viper"
+)
+
+// Pruning removes only what no part of the tree declares - imports,
+// bootstrap, `when:`-skipped entries and the packages rwr installs itself for
+// a login shell or a package manager count - on the providers opted in,
+// sparing the keep list and any provider that cannot tell explicit installs
+// from dependencies; the removals land in the journal.
+func TestPrune_RemovesOnlyUndeclared(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("packages/base.yaml", `packages:
+  - names: [git]
+    action: install
+    package_manager: apt
+  - name: nvidia-driver
+    action: install
+    when: eq .Facts.gpuVendor "nvidia"
+  - name: tmux
+    action: remove
+  - import: extra.yaml
+`)
+	write("packages/extra.yaml", "packages:\n  - names: [ripgrep]\n    action: install\n")
+	write("bootstrap.yaml", "packages:\n  - names: [curl]\n    action: install\n")
+	write("users/base.yaml", `users:
+  - name: alice
+    action: shell
+    shell: /usr/bin/zsh
+  - name: bob
+    action: shell
+    shell: fish
+    shell_package: fish-shell
+`)
+
+	bin := "sh"
+	if runtime.GOOS == types.OSWindows {
+		bin = "cmd"
+	}
+	detection := types.DetectionConfig{Binary: bin, Distributions: []string{types.OSLinux, types.OSDarwin, types.OSWindows}}
+	defer system.SetProvidersForTest(map[string]*types.Provider{
+		"apt":  {Name: "apt", Detection: detection, Commands: types.CommandConfig{Install: "install -y", Remove: "remove -y", ListExplicit: "apt-mark showmanual"}},
+		"snap": {Name: "snap", Detection: detection, Commands: types.CommandConfig{Install: "install", Remove: "remove", List: "list"}},
+		"brew": {Name: "brew", Detection: detection, Commands: types.CommandConfig{Install: "install", Remove: "uninstall", ListExplicit: "leaves"}},
+	})()
+	var scanned []string
+	pruneScan = func(providers map[string]*types.Provider) []scan.PackageResult {
+		for name := range providers {
+			scanned = append(scanned, name)
+		}
+		return []scan.PackageResult{
+			{Provider: "apt", Names: []string{"git", "ripgrep", "curl", "nvidia-driver", "tmux", "htop", "linux-image-6.8", "ubuntu-minimal", "zsh", "fish-shell", "nala"}},
+			{Provider: "snap", Names: []string{"firefox"}, Unfiltered: true},
+		}
+	}
+	defer func() { pruneScan = scan.Packages }()
+
+	initConfig := &types.InitConfig{Prune: &types.PruneConfig{
+		Providers: []string{"apt", "snap"},
+		Keep:      map[string][]string{"apt": {"linux-*", "ubuntu-minimal"}},
+	}}
+	initConfig.Init.Location = dir
+	initConfig.Init.Format = "yaml"
+	initConfig.Variables.Facts = &types.Facts{GPUVendor: "intel"}
+	initConfig.PackageManagers = []types.PackageManagerInfo{{Name: "nala", Action: types.ActionInstall}}
+
+	candidates, err := PruneCandidates(initConfig)
+	if err != nil {
+		t.Fatalf("PruneCandidates: %v", err)
+	}
+	if strings.Contains(strings.Join(scanned, ","), "brew") {
+		t.Fatalf("a provider not opted in was scanned: %v", scanned)
+	}
+	var names []string
+	for _, candidate := range candidates {
+		names = append(names, candidate.Provider+" "+candidate.Name)
+	}
+	if strings.Join(names, ",") != "apt tmux,apt htop" {
+		t.Fatalf("candidates = %q, want the undeclared tmux and htop on apt", names)
+	}
+
+	configDir := t.TempDir()
+	viper.Set("rwr.configdir", configDir)
+	defer viper.Set("rwr.configdir", "")
+	rec := exectest.New()
+	defer system.SetExecutor(rec)()
+	t.Cleanup(resetFailures)
+
+	if err := Prune(initConfig, newTestOSInfo(), candidates, true); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	var calls []string
+	for _, call := range rec.Calls {
+		calls = append(calls, strings.Join(call.Args, " "))
+	}
+	if strings.Join(calls, "|") != "remove -y tmux|remove -y htop" {
+		t.Fatalf("calls = %q", calls)
+	}
+
+	applies, err := state.Applies(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var journaled []string
+	for _, entry := range applies {
+		journaled = append(journaled, entry.Action+" "+entry.Identity["name"])
+	}
+	if strings.Join(journaled, ",") != "remove tmux,remove htop" {
+		t.Fatalf("journaled = %q, want both removals", journaled)
+	}
+}
+
+// Without --yes a non-interactive prune lists and removes nothing.
+func TestConfirmPrune_NonInteractiveRemovesNothing(t *testing.T) {
+	candidates := []types.Resource{{Provider: "apt", Name: "htop"}}
+	confirmed, err := confirmPrune(candidates, false, false)
+	if err != nil || len(confirmed) != 0 {
+		t.Fatalf("confirmed = %v, %v; want nothing", confirmed, err)
+	}
+	confirmed, err = confirmPrune(candidates, false, true)
+	if err != nil || len(confirmed) != 1 {
+		t.Fatalf("--yes confirmed = %v, %v; want htop", confirmed, err)
+	}
+}
+
+// A non-interactive run's --prune is its own confirmation: with no one to
+// ask, the orphans are removed rather than only listed.
+func TestPrunePackages_NonInteractiveRemovesOrphans(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "packages"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "packages", "base.yaml"), []byte("packages:\n  - names: [git]\n    action: install\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bin := "sh"
+	if runtime.GOOS == types.OSWindows {
+		bin = "cmd"
+	}
+	detection := types.DetectionConfig{Binary: bin, Distributions: []string{types.OSLinux, types.OSDarwin, types.OSWindows}}
+	defer system.SetProvidersForTest(map[string]*types.Provider{
+		"apt": {Name: "apt", Detection: detection, Commands: types.CommandConfig{Install: "install -y", Remove: "remove -y", ListExplicit: "apt-mark showmanual"}},
+	})()
+	pruneScan = func(map[string]*types.Provider) []scan.PackageResult {
+		return []scan.PackageResult{{Provider: "apt", Names: []string{"git", "htop"}}}
+	}
+	defer func() { pruneScan = scan.Packages }()
+	rec := exectest.New()
+	defer system.SetExecutor(rec)()
+	t.Cleanup(resetFailures)
+
+	initConfig := &types.InitConfig{Prune: &types.PruneConfig{Providers: []string{"apt"}}}
+	initConfig.Init.Location = dir
+	initConfig.Init.Format = "yaml"
+	initConfig.Variables.Flags.Interactive = false
+
+	if err := prunePackages(initConfig, newTestOSInfo()); err != nil {
+		t.Fatalf("prunePackages: %v", err)
+	}
+	if len(rec.Calls) != 1 || strings.Join(rec.Calls[0].Args, " ") != "remove -y htop" {
+		t.Fatalf("calls = %+v, want htop removed", rec.Calls)
+	}
+}
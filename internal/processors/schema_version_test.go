@@ -104,7 +104,7 @@ var unsupportedVersionCases = []struct {
 		name:      "users",
 		blueprint: "schema_version: 99\nusers:\n  - name: tester\n    action: create\n",
 		run: func(data []byte, osInfo *types.OSInfo, init *types.InitConfig) error {
-			return ProcessUsers(data, "", "yaml", init)
+			return ProcessUsers(data, "", "yaml", nil, init)
 		},
 	},
 	{
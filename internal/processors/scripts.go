@@ -2,8 +2,9 @@ package processors
 
 import (
 	"fmt"
+	"maps"
 	"os"
-	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/fynxlabs/rwr/internal/helpers"
@@ -56,18 +57,38 @@ func processScripts(scripts []types.Script, osInfo *types.OSInfo, initConfig *ty
 	for _, script := range scripts {
 		log.Debugf("Processing script: %+v", script)
 
-		if system.IsDryRun() {
-			log.Infof("[DRY-RUN] Would run script: %s (exec: %s)", script.Name, script.Exec)
-			track.item("", script.Name, script.Action, types.StatusPlanned, "dry-run", 0)
-			continue
-		}
-
 		if script.Action == "run" {
 			started := time.Now()
-			err := runScript(script, osInfo, initConfig, blueprintDir)
+			limits, err := resolveScriptLimits(script)
+			if err != nil {
+				log.Errorf("Error in script %s: %v", script.Name, err)
+				track.item("", script.Name, script.Action, types.StatusFailed, err.Error(), 0)
+				return fmt.Errorf("error in script %s: %w", script.Name, err)
+			}
+			satisfied, err := scriptGuard(script, limits, osInfo, initConfig, blueprintDir)
 			if err != nil {
-				log.Errorf("Error running script %s: %v", script.Name, err)
+				log.Errorf("Error checking script %s: %v", script.Name, err)
 				track.item("", script.Name, script.Action, types.StatusFailed, err.Error(), time.Since(started))
+				return fmt.Errorf("error checking script %s: %w", script.Name, err)
+			}
+			if satisfied != "" {
+				log.Infof("Script %s skipped: %s", script.Name, satisfied)
+				track.item("", script.Name, script.Action, types.StatusPresent, satisfied, time.Since(started))
+				continue
+			}
+			if system.IsDryRun() {
+				log.Infof("[DRY-RUN] Would run script: %s (exec: %s)", script.Name, script.Exec)
+				track.item("", script.Name, script.Action, types.StatusPlanned, "dry-run", 0)
+				continue
+			}
+			attempts, err := runScriptAttempts(track, script, limits, osInfo, initConfig, blueprintDir)
+			if err != nil {
+				detail := err.Error()
+				if attempts > 1 {
+					detail = fmt.Sprintf("%s (after %d attempts)", detail, attempts)
+				}
+				log.Errorf("Error running script %s: %s", script.Name, detail)
+				track.item("", script.Name, script.Action, types.StatusFailed, detail, time.Since(started))
 				// Scripts stop at the first failure, where packages, files and
 				// the rest record the failure and carry on. That difference is
 				// deliberate: a script is arbitrary code, the scripts in a file
@@ -84,9 +105,24 @@ func processScripts(scripts []types.Script, osInfo *types.OSInfo, initConfig *ty
 				// to push on past a failure.
 				return fmt.Errorf("error running script %s: %w", script.Name, err)
 			}
+			if script.RunOnce {
+				if err := markScriptRan(script, initConfig, blueprintDir); err != nil {
+					track.item("", script.Name, script.Action, types.StatusFailed, err.Error(), time.Since(started))
+					return err
+				}
+			}
 			log.Infof("Script %s executed successfully", script.Name)
-			track.item("", script.Name, script.Action, types.StatusOK, "", time.Since(started))
+			detail := ""
+			if attempts > 1 {
+				detail = fmt.Sprintf("succeeded on attempt %d", attempts)
+			}
+			track.item("", script.Name, script.Action, types.StatusOK, detail, time.Since(started))
 		} else {
+			if system.IsDryRun() {
+				log.Infof("[DRY-RUN] Would run script: %s (exec: %s)", script.Name, script.Exec)
+				track.item("", script.Name, script.Action, types.StatusPlanned, "dry-run", 0)
+				continue
+			}
 			log.Errorf("Unsupported action for script %s: %s", script.Name, script.Action)
 			track.item("", script.Name, script.Action, types.StatusFailed, "unsupported action", 0)
 			return fmt.Errorf("unsupported action for script %s: %s", script.Name, script.Action)
@@ -95,7 +131,7 @@ func processScripts(scripts []types.Script, osInfo *types.OSInfo, initConfig *ty
 	return nil
 }
 
-func runScript(script types.Script, osInfo *types.OSInfo, initConfig *types.InitConfig, blueprintDir string) error {
+func runScript(script types.Script, limits scriptLimits, osInfo *types.OSInfo, initConfig *types.InitConfig, blueprintDir string) error {
 	var scriptCmd types.Command
 
 	log.Debugf("Running script: %s", script.Name)
@@ -115,7 +151,7 @@ func runScript(script types.Script, osInfo *types.OSInfo, initConfig *types.Init
 	// Determine the script source (from file or content)
 	var scriptPath string
 	if script.Source != "" {
-		scriptPath = filepath.Join(blueprintDir, script.Source, script.Name)
+		scriptPath = scriptSourcePath(script, blueprintDir)
 	} else if script.Content != "" {
 		// Write the script content to a temporary file, named for the executor
 		// that will run it. The extension used to be .sh unconditionally, and
@@ -227,12 +263,17 @@ func runScript(script types.Script, osInfo *types.OSInfo, initConfig *types.Init
 	// made a non-interactive hang look like the TUI had disappeared.
 	scriptCmd.Interactive = script.Interactive != nil && *script.Interactive
 
-	log.Debugf("Running script command: %+v", scriptCmd)
+	scriptCmd.Timeout = limits.timeout
+	scriptCmd.Variables = limits.env
+
+	// Names only: Variables can carry a credential from env_credentials.
+	log.Debugf("Running script command: %s %v (env %v, timeout %s)", scriptCmd.Exec, scriptCmd.LogArgs(), slices.Sorted(maps.Keys(scriptCmd.Variables)), scriptCmd.Timeout)
 
-	// Run the script
+	// Run the script. Wrapped, so the caller can tell a cancelled run or a
+	// timed-out attempt from the script failing.
 	err := system.RunCommand(scriptCmd, initConfig.Variables.Flags.Debug)
 	if err != nil {
-		return fmt.Errorf("error running script: %v", err)
+		return fmt.Errorf("error running script: %w", err)
 	}
 
 	return nil
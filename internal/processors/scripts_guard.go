@@ -0,0 +1,134 @@
+package processors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/status"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// A script has no state rwr can read back, so a plain script runs on every
+// run. The guards are how a blueprint says when it has nothing left to do:
+// creates and the two check commands ask the machine, run_once asks the
+// marker this file writes once the script has succeeded.
+
+// scriptGuard decides whether a script runs. A non-empty reason means it is
+// already satisfied and the reason says why. In a dry run the check commands
+// do not run - they are arbitrary commands - so only creates and run_once,
+// which just read files, can satisfy a script there.
+func scriptGuard(script types.Script, limits scriptLimits, osInfo *types.OSInfo, initConfig *types.InitConfig, blueprintDir string) (string, error) {
+	if script.RunOnce {
+		hash, err := scriptContentHash(script, blueprintDir)
+		if err != nil {
+			return "", err
+		}
+		marker, err := scriptMarker(initConfig, script.Name)
+		if err != nil {
+			return "", err
+		}
+		if status.RunOnceState(marker, hash) == status.Present {
+			return "run_once: already ran", nil
+		}
+	}
+	if script.Creates != "" {
+		path := system.ExpandPath(script.Creates)
+		if _, err := os.Lstat(path); err == nil {
+			return fmt.Sprintf("creates: %s exists", path), nil
+		}
+	}
+	if system.IsDryRun() {
+		return "", nil
+	}
+	if script.Unless != "" && scriptCheck(script, limits, script.Unless, osInfo, initConfig) {
+		return "unless: check succeeded", nil
+	}
+	if script.OnlyIf != "" && !scriptCheck(script, limits, script.OnlyIf, osInfo, initConfig) {
+		return "onlyif: check failed", nil
+	}
+	return "", nil
+}
+
+// scriptCheck runs a guard's check command through the shell, as the account
+// the script itself runs as and with its env and timeout, and reports whether
+// it exited zero.
+func scriptCheck(script types.Script, limits scriptLimits, check string, osInfo *types.OSInfo, initConfig *types.InitConfig) bool {
+	var cmd types.Command
+	if osInfo.System.OS == "windows" {
+		cmd = types.Command{Exec: osInfo.Tools.PowerShell.Bin, Args: []string{"-NoProfile", "-Command", check}}
+	} else {
+		shell := osInfo.Tools.Bash.Bin
+		if shell == "" {
+			shell = "sh"
+		}
+		cmd = types.Command{Exec: shell, Args: []string{"-c", check}}
+	}
+	cmd.Elevated = script.Elevated
+	cmd.Timeout = limits.timeout
+	cmd.Variables = limits.env
+	if !script.Elevated {
+		cmd.AsUser = script.AsUser
+	}
+	log.Debugf("Checking guard for script %s: %s", script.Name, check)
+	return system.RunCommand(cmd, initConfig.Variables.Flags.Debug) == nil
+}
+
+// scriptSourcePath is where a script's file lives: `source` names the
+// directory, `name` the file in it.
+func scriptSourcePath(script types.Script, blueprintDir string) string {
+	return filepath.Join(blueprintDir, script.Source, script.Name)
+}
+
+// scriptContentHash identifies what a script would run: its inline content,
+// or its file's bytes. Editing either re-arms run_once.
+func scriptContentHash(script types.Script, blueprintDir string) (string, error) {
+	content := []byte(script.Content)
+	if script.Source != "" {
+		var err error
+		content, err = os.ReadFile(scriptSourcePath(script, blueprintDir)) // #nosec G304 -- blueprint-relative path
+		if err != nil {
+			return "", fmt.Errorf("error reading script %s: %w", script.Name, err)
+		}
+	}
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// scriptMarker is the file whose content is the hash of a run_once script's
+// last successful run.
+func scriptMarker(initConfig *types.InitConfig, name string) (string, error) {
+	dir := initConfig.Variables.Flags.RunOnceLocation
+	if dir == "" {
+		return "", fmt.Errorf("run_once script %s: no run_once directory configured", name)
+	}
+	return scriptMarkerPath(dir, name), nil
+}
+
+// scriptMarkerPath names a run_once script's marker in runOnceDir. A script
+// name can be a relative path; the marker stays one file either way.
+func scriptMarkerPath(runOnceDir, name string) string {
+	return filepath.Join(runOnceDir, "script_"+strings.NewReplacer("/", "_", `\`, "_").Replace(name))
+}
+
+// markScriptRan records a successful run_once script.
+func markScriptRan(script types.Script, initConfig *types.InitConfig, blueprintDir string) error {
+	hash, err := scriptContentHash(script, blueprintDir)
+	if err != nil {
+		return err
+	}
+	marker, err := scriptMarker(initConfig, script.Name)
+	if err != nil {
+		return err
+	}
+	log.Debugf("RunOnce Set: Write marker %s", marker)
+	if err := os.WriteFile(marker, []byte(hash+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to record run_once script %s: %w", script.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package processors
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// guardExecutor records commands and fails the guard checks named in failing.
+type guardExecutor struct {
+	calls   []types.Command
+	failing map[string]bool
+}
+
+func (e *guardExecutor) Run(cmd types.Command, _ bool) error {
+	e.calls = append(e.calls, cmd)
+	if len(cmd.Args) == 2 && cmd.Args[0] == "-c" && e.failing[cmd.Args[1]] {
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+func (e *guardExecutor) Output(cmd types.Command, debug bool) (string, error) {
+	return "", e.Run(cmd, debug)
+}
+
+// scriptRuns counts the calls that ran a script rather than a check.
+func (e *guardExecutor) scriptRuns() int {
+	n := 0
+	for _, cmd := range e.calls {
+		if len(cmd.Args) == 0 || cmd.Args[0] != "-c" {
+			n++
+		}
+	}
+	return n
+}
+
+// resourceRecorder keeps the scripts processor's resource outcomes.
+type resourceRecorder struct{ resources []types.Resource }
+
+func (r *resourceRecorder) Emit(event reporting.Event) {
+	if done, ok := event.(reporting.ResourceDone); ok {
+		r.resources = append(r.resources, done.Resource)
+	}
+}
+
+// runGuardedScripts runs a scripts blueprint with its run_once markers in
+// runOnceDir.
+func runGuardedScripts(t *testing.T, runOnceDir, blueprint string, exec *guardExecutor) []types.Resource {
+	t.Helper()
+	defer system.SetExecutor(exec)()
+	recorder := &resourceRecorder{}
+	defer reporting.Set(recorder)()
+	config := &types.InitConfig{}
+	config.Variables.Flags.RunOnceLocation = runOnceDir
+	if err := ProcessScripts([]byte(blueprint), t.TempDir(), "yaml", scriptOSInfo(), config); err != nil {
+		t.Fatalf("ProcessScripts: %v", err)
+	}
+	return recorder.resources
+}
+
+func TestScriptGuard_CreatesSkipsWhenThePathExists(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "done")
+	if err := os.WriteFile(existing, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	exec := &guardExecutor{}
+	resources := runGuardedScripts(t, t.TempDir(), `
+scripts:
+  - name: made
+    action: run
+    content: "touch `+existing+`"
+    creates: `+existing+`
+  - name: not_made
+    action: run
+    content: "true"
+    creates: `+existing+`.missing
+`, exec)
+
+	if exec.scriptRuns() != 1 {
+		t.Fatalf("ran %d scripts, want only not_made: %v", exec.scriptRuns(), exec.calls)
+	}
+	if resources[0].Status != types.StatusPresent || !strings.Contains(resources[0].Detail, "creates") {
+		t.Errorf("made = %s (%s), want present by creates", resources[0].Status, resources[0].Detail)
+	}
+	if resources[1].Status != types.StatusOK {
+		t.Errorf("not_made = %s, want ok", resources[1].Status)
+	}
+}
+
+func TestScriptGuard_UnlessAndOnlyIf(t *testing.T) {
+	exec := &guardExecutor{failing: map[string]bool{"test -d /nope": true}}
+	resources := runGuardedScripts(t, t.TempDir(), `
+scripts:
+  - name: unless_passes
+    action: run
+    content: "true"
+    unless: "command -v jq"
+  - name: unless_fails
+    action: run
+    content: "true"
+    unless: "test -d /nope"
+  - name: onlyif_passes
+    action: run
+    content: "true"
+    onlyif: "command -v jq"
+  - name: onlyif_fails
+    action: run
+    content: "true"
+    onlyif: "test -d /nope"
+`, exec)
+
+	want := map[string]types.Status{
+		"unless_passes": types.StatusPresent,
+		"unless_fails":  types.StatusOK,
+		"onlyif_passes": types.StatusOK,
+		"onlyif_fails":  types.StatusPresent,
+	}
+	for _, resource := range resources {
+		if resource.Status != want[resource.Name] {
+			t.Errorf("%s = %s, want %s", resource.Name, resource.Status, want[resource.Name])
+		}
+	}
+	if exec.scriptRuns() != 2 {
+		t.Errorf("ran %d scripts, want 2", exec.scriptRuns())
+	}
+	// Checks run through the shell, one argument each.
+	check := exec.calls[0]
+	if check.Exec != "/bin/bash" || len(check.Args) != 2 || check.Args[1] != "command -v jq" {
+		t.Errorf("check = %s %q", check.Exec, check.Args)
+	}
+}
+
+// Checks run as the account the script runs as.
+func TestScriptGuard_CheckRunsAsTheScriptsAccount(t *testing.T) {
+	exec := &guardExecutor{}
+	runGuardedScripts(t, t.TempDir(), `
+scripts:
+  - name: builder
+    action: run
+    content: "true"
+    asUser: builder
+    unless: "test -f ~/.built"
+`, exec)
+	if exec.calls[0].AsUser != "builder" {
+		t.Errorf("check ran as %q, want builder", exec.calls[0].AsUser)
+	}
+}
+
+func TestScriptGuard_RunOnceRearmsWhenTheContentChanges(t *testing.T) {
+	exec := &guardExecutor{}
+	once := `
+scripts:
+  - name: migrate
+    action: run
+    run_once: true
+    content: "echo v1"
+`
+	dir := t.TempDir()
+	runGuardedScripts(t, dir, once, exec)
+	resources := runGuardedScripts(t, dir, once, exec)
+	if exec.scriptRuns() != 1 {
+		t.Fatalf("run_once script ran %d times, want 1", exec.scriptRuns())
+	}
+	if resources[0].Status != types.StatusPresent {
+		t.Errorf("second run = %s, want present", resources[0].Status)
+	}
+
+	runGuardedScripts(t, dir, strings.Replace(once, "v1", "v2", 1), exec)
+	if exec.scriptRuns() != 2 {
+		t.Fatalf("edited run_once script ran %d times in total, want 2", exec.scriptRuns())
+	}
+}
+
+// A failed run_once script is not marked, so the next run tries again.
+func TestScriptGuard_RunOnceIsNotMarkedOnFailure(t *testing.T) {
+	defer system.SetExecutor(failingExecutor{})()
+	dir := t.TempDir()
+	config := &types.InitConfig{}
+	config.Variables.Flags.RunOnceLocation = dir
+	blueprint := []byte("scripts:\n  - name: migrate\n    action: run\n    run_once: true\n    content: \"false\"\n")
+	if err := ProcessScripts(blueprint, t.TempDir(), "yaml", scriptOSInfo(), config); err == nil {
+		t.Fatal("ProcessScripts returned nil for a failing script")
+	}
+	if _, err := os.Stat(scriptMarkerPath(dir, "migrate")); !os.IsNotExist(err) {
+		t.Fatalf("failed run_once script was marked: %v", err)
+	}
+}
+
+type failingExecutor struct{}
+
+func (failingExecutor) Run(types.Command, bool) error { return errors.New("exit status 1") }
+func (failingExecutor) Output(types.Command, bool) (string, error) {
+	return "", errors.New("exit status 1")
+}
+
+// A dry run reads creates and run_once markers but never runs a check.
+func TestScriptGuard_DryRunRunsNoChecks(t *testing.T) {
+	system.SetDryRun(true)
+	defer system.SetDryRun(false)
+	existing := t.TempDir()
+	exec := &guardExecutor{}
+	resources := runGuardedScripts(t, t.TempDir(), `
+scripts:
+  - name: created
+    action: run
+    content: "true"
+    creates: `+existing+`
+  - name: checked
+    action: run
+    content: "true"
+    unless: "command -v jq"
+`, exec)
+	if len(exec.calls) != 0 {
+		t.Fatalf("dry run executed %v", exec.calls)
+	}
+	if resources[0].Status != types.StatusPresent || resources[1].Status != types.StatusPlanned {
+		t.Errorf("statuses = %s, %s; want present, planned", resources[0].Status, resources[1].Status)
+	}
+}
+
+// Status reads a run_once script's marker through the plan's resource.
+func TestEnumerateResources_RunOnceScriptCarriesItsMarker(t *testing.T) {
+	dir := t.TempDir()
+	resources := enumerateResources(types.BlueprintTypeScripts, types.ResolvedFile{
+		Path:     filepath.Join(dir, "scripts.yaml"),
+		Format:   "yaml",
+		Resolved: []byte("scripts:\n  - name: migrate\n    action: run\n    run_once: true\n    content: \"echo v1\"\n  - name: plain\n    action: run\n    content: \"true\"\n"),
+	}, providerDefaults{}, "/state/run_once")
+	if len(resources) != 2 {
+		t.Fatalf("resources = %+v", resources)
+	}
+	if resources[0].Marker != filepath.Join("/state/run_once", "script_migrate") || !strings.HasPrefix(resources[0].Desired, "sha256:") {
+		t.Errorf("run_once script = %+v", resources[0])
+	}
+	if resources[1].Marker != "" || resources[1].Desired != "" {
+		t.Errorf("plain script = %+v", resources[1])
+	}
+}
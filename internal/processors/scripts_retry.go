@@ -0,0 +1,81 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// scriptLimits is what a script's timeout, retries and env resolve to before
+// its first attempt. Resolving up front means a bad duration or an unexposed
+// credential fails the script once, rather than being retried.
+type scriptLimits struct {
+	timeout time.Duration
+	delays  []time.Duration
+	env     map[string]string
+}
+
+func resolveScriptLimits(script types.Script) (scriptLimits, error) {
+	timeout, err := types.ParseScriptDuration("timeout", script.Timeout)
+	if err != nil {
+		return scriptLimits{}, err
+	}
+	delays, err := script.RetryDelays()
+	if err != nil {
+		return scriptLimits{}, err
+	}
+	env, err := scriptEnvironment(script)
+	if err != nil {
+		return scriptLimits{}, err
+	}
+	return scriptLimits{timeout: timeout, delays: delays, env: env}, nil
+}
+
+// scriptEnvironment merges a script's env with its env_credentials. A
+// credential is read through the scripts gate: a blueprint naming one does
+// not by itself earn its value.
+func scriptEnvironment(script types.Script) (map[string]string, error) {
+	if len(script.Env) == 0 && len(script.EnvCredentials) == 0 {
+		return nil, nil
+	}
+	env := maps.Clone(script.Env)
+	if env == nil {
+		env = map[string]string{}
+	}
+	for name, credential := range script.EnvCredentials {
+		if _, ok := env[name]; ok {
+			return nil, fmt.Errorf("%s is set by both env and env_credentials", name)
+		}
+		value, err := types.ScriptCredentialValue(credential)
+		if err != nil {
+			return nil, fmt.Errorf("env_credentials %s: %w", name, err)
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// runScriptAttempts runs a script until it succeeds or its retries are spent,
+// and returns the number of attempts made. A failed attempt with retries left
+// is reported as a retry and waited out; a cancelled run is never retried.
+func runScriptAttempts(track *progress, script types.Script, limits scriptLimits, osInfo *types.OSInfo, initConfig *types.InitConfig, blueprintDir string) (int, error) {
+	attempts := len(limits.delays) + 1
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+		err := runScript(script, limits, osInfo, initConfig, blueprintDir)
+		if err == nil || attempt == attempts || errors.Is(err, system.ErrCancelled) {
+			return attempt, err
+		}
+		delay := limits.delays[attempt-1]
+		log.Warnf("Script %s failed (attempt %d of %d), retrying in %s: %v", script.Name, attempt, attempts, delay, err)
+		track.retry("", script.Name, script.Action, err.Error(), attempt, attempts, delay, time.Since(started))
+		if err := system.Wait(delay); err != nil {
+			return attempt, err
+		}
+	}
+}
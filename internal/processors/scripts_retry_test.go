@@ -0,0 +1,230 @@
+package processors
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/state"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+	"github.com/spf13/viper"
+)
+
+// flakyExecutor fails the first failures commands it runs, with err, then
+// succeeds. It keeps every command so the test can read what was passed.
+type flakyExecutor struct {
+	failures int
+	err      error
+	calls    []types.Command
+}
+
+func (e *flakyExecutor) Run(cmd types.Command, _ bool) error {
+	e.calls = append(e.calls, cmd)
+	if len(e.calls) <= e.failures {
+		return e.err
+	}
+	return nil
+}
+
+func (e *flakyExecutor) Output(cmd types.Command, debug bool) (string, error) {
+	return "", e.Run(cmd, debug)
+}
+
+// retryRecorder keeps the scripts processor's retries and outcomes.
+type retryRecorder struct {
+	retries []reporting.ResourceRetry
+	done    []types.Resource
+}
+
+func (r *retryRecorder) Emit(event reporting.Event) {
+	switch e := event.(type) {
+	case reporting.ResourceRetry:
+		r.retries = append(r.retries, e)
+	case reporting.ResourceDone:
+		r.done = append(r.done, e.Resource)
+	}
+}
+
+func runRetriedScripts(t *testing.T, blueprint string, exec *flakyExecutor) (*retryRecorder, error) {
+	t.Helper()
+	defer system.SetExecutor(exec)()
+	recorder := &retryRecorder{}
+	defer reporting.Set(recorder)()
+	return recorder, ProcessScripts([]byte(blueprint), t.TempDir(), "yaml", scriptOSInfo(), &types.InitConfig{})
+}
+
+const flakyScript = `
+scripts:
+  - name: fetch
+    action: run
+    content: "curl -fsSL https://example.com/tool.tar.gz"
+    retries: 3
+    retry_delay: 1ms
+`
+
+func TestScriptRetries_SucceedsOnALaterAttempt(t *testing.T) {
+	exec := &flakyExecutor{failures: 2, err: errors.New("exit status 7")}
+	recorder, err := runRetriedScripts(t, flakyScript, exec)
+	if err != nil {
+		t.Fatalf("ProcessScripts: %v", err)
+	}
+	if len(exec.calls) != 3 {
+		t.Fatalf("ran %d attempts, want 3", len(exec.calls))
+	}
+	if len(recorder.retries) != 2 {
+		t.Fatalf("retries = %+v, want 2", recorder.retries)
+	}
+	first := recorder.retries[0]
+	if first.Attempt != 1 || first.Attempts != 4 || first.Delay != time.Millisecond || !strings.Contains(first.Resource.Detail, "exit status 7") {
+		t.Errorf("first retry = %+v", first)
+	}
+	if recorder.retries[1].Delay != 2*time.Millisecond {
+		t.Errorf("second delay = %s, want the first doubled", recorder.retries[1].Delay)
+	}
+	if len(recorder.done) != 1 || recorder.done[0].Status != types.StatusOK || recorder.done[0].Detail != "succeeded on attempt 3" {
+		t.Errorf("outcome = %+v", recorder.done)
+	}
+}
+
+func TestScriptRetries_GivesUpAfterTheLastAttempt(t *testing.T) {
+	exec := &flakyExecutor{failures: 10, err: errors.New("exit status 7")}
+	recorder, err := runRetriedScripts(t, flakyScript, exec)
+	if err == nil {
+		t.Fatal("ProcessScripts returned nil for a script that never succeeded")
+	}
+	if len(exec.calls) != 4 || len(recorder.retries) != 3 {
+		t.Fatalf("attempts = %d, retries = %d; want 4 and 3", len(exec.calls), len(recorder.retries))
+	}
+	if recorder.done[0].Status != types.StatusFailed || !strings.Contains(recorder.done[0].Detail, "after 4 attempts") {
+		t.Errorf("outcome = %+v", recorder.done[0])
+	}
+}
+
+// Stopping the run is not a failure to retry.
+func TestScriptRetries_CancellationIsNotRetried(t *testing.T) {
+	exec := &flakyExecutor{failures: 10, err: system.ErrCancelled}
+	recorder, err := runRetriedScripts(t, flakyScript, exec)
+	if !errors.Is(err, system.ErrCancelled) {
+		t.Fatalf("err = %v, want ErrCancelled", err)
+	}
+	if len(exec.calls) != 1 || len(recorder.retries) != 0 {
+		t.Fatalf("a cancelled script was retried: %d attempts", len(exec.calls))
+	}
+}
+
+// A timed-out attempt is retried like a failed one.
+func TestScriptRetries_TimeoutReachesTheCommandAndIsRetried(t *testing.T) {
+	exec := &flakyExecutor{failures: 1, err: fmt.Errorf("%w after 1m30s", system.ErrTimedOut)}
+	blueprint := strings.Replace(flakyScript, "retries: 3", "retries: 1\n    timeout: 90s", 1)
+	recorder, err := runRetriedScripts(t, blueprint, exec)
+	if err != nil {
+		t.Fatalf("ProcessScripts: %v", err)
+	}
+	if len(exec.calls) != 2 || len(recorder.retries) != 1 {
+		t.Fatalf("attempts = %d, want 2", len(exec.calls))
+	}
+	if exec.calls[0].Timeout != 90*time.Second {
+		t.Errorf("command timeout = %s, want 90s", exec.calls[0].Timeout)
+	}
+	if !strings.Contains(recorder.retries[0].Resource.Detail, "timed out") {
+		t.Errorf("retry detail = %q", recorder.retries[0].Resource.Detail)
+	}
+}
+
+// Each failed attempt leaves a "retry" entry in the journal, which does not
+// fold into the applies; the final outcome is the one apply.
+func TestScriptRetries_AttemptsAreJournalled(t *testing.T) {
+	configDir := t.TempDir()
+	viper.Set("rwr.configdir", configDir)
+	defer viper.Set("rwr.configdir", "")
+	openJournal("tree")
+	exec := &flakyExecutor{failures: 1, err: errors.New("exit status 7")}
+	if _, err := runRetriedScripts(t, flakyScript, exec); err != nil {
+		t.Fatalf("ProcessScripts: %v", err)
+	}
+	closeJournal()
+
+	file, err := os.Open(state.JournalPath(configDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close() //nolint:errcheck
+	var outcomes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event state.Event
+		if json.Unmarshal(scanner.Bytes(), &event) == nil && event.Kind == "apply" {
+			outcomes = append(outcomes, event.Outcome+":"+event.Identity["attempt"])
+		}
+	}
+	if strings.Join(outcomes, ",") != "retry:1,ok:" {
+		t.Errorf("journal outcomes = %v, want a retry then ok", outcomes)
+	}
+	applies, err := state.Applies(configDir)
+	if err != nil || len(applies) != 1 {
+		t.Errorf("applies = %+v, %v; want the one ok", applies, err)
+	}
+}
+
+func TestScriptEnv_CredentialsAreGated(t *testing.T) {
+	types.RegisterCredentials([]types.CredentialSpec{{Name: "github_token"}, {Name: "deploy_key", Scope: []string{types.CredentialSurfaceTemplates}}})
+	types.SetCredentialValue("github_token", "ghp_secret")
+	types.SetCredentialValue("deploy_key", "hunter22")
+	defer types.RegisterCredentials(nil)
+	defer types.SetExposedCredentials(nil)
+
+	script := `
+scripts:
+  - name: release
+    action: run
+    content: "gh release list"
+    env:
+      GH_REPO: fynxlabs/rwr
+    env_credentials:
+      GH_TOKEN: github_token
+`
+	// Declared and resolved is not enough: the operator has to expose it.
+	exec := &flakyExecutor{}
+	if _, err := runRetriedScripts(t, script, exec); err == nil || !strings.Contains(err.Error(), "not exposed") {
+		t.Fatalf("unexposed credential: err = %v", err)
+	}
+	if len(exec.calls) != 0 {
+		t.Fatalf("script ran without its credential: %v", exec.calls)
+	}
+
+	types.SetExposedCredentials([]string{"github_token", "deploy_key"})
+	if _, err := runRetriedScripts(t, script, exec); err != nil {
+		t.Fatalf("ProcessScripts: %v", err)
+	}
+	vars := exec.calls[0].Variables
+	if vars["GH_TOKEN"] != "ghp_secret" || vars["GH_REPO"] != "fynxlabs/rwr" {
+		t.Errorf("env = %v", vars)
+	}
+
+	// A credential scoped to templates stays out of scripts even when exposed.
+	scoped := strings.Replace(script, "GH_TOKEN: github_token", "DEPLOY_KEY: deploy_key", 1)
+	if _, err := runRetriedScripts(t, scoped, exec); err == nil || !strings.Contains(err.Error(), "not exposed") {
+		t.Errorf("templates-scoped credential: err = %v", err)
+	}
+}
+
+func TestScriptRetryDelays(t *testing.T) {
+	delays, err := types.Script{Retries: 4, RetryDelay: "2m"}.RetryDelays()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	if fmt.Sprint(delays) != fmt.Sprint(want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+	if delays, _ := (types.Script{Retries: 1}).RetryDelays(); delays[0] != types.DefaultScriptRetryDelay {
+		t.Errorf("default delay = %v", delays)
+	}
+}
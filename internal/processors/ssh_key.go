@@ -181,14 +181,14 @@ func ensureSSHPackages(osInfo *types.OSInfo, initConfig *types.InitConfig) error
 	case "windows":
 		pkgData := &types.PackagesData{
 			Packages: []types.Package{
-				{Name: "openssh", Action: "install", PackageManager: "chocolatey"},
+				{Name: "openssh", Action: "install", PackageManager: types.ProviderChain{"chocolatey"}},
 			},
 		}
 		return ProcessPackages(nil, pkgData, "", "", osInfo, initConfig)
 	case "darwin":
 		pkgData := &types.PackagesData{
 			Packages: []types.Package{
-				{Name: "openssh", Action: "install", PackageManager: "brew"},
+				{Name: "openssh", Action: "install", PackageManager: types.ProviderChain{"brew"}},
 			},
 		}
 		return ProcessPackages(nil, pkgData, "", "", osInfo, initConfig)
@@ -0,0 +1,419 @@
+// Declarative privilege rules: sudoers snippets and polkit rules, both carried
+// by the users blueprint. A sudoers snippet is checked with `visudo -cf` on a
+// staged copy before it is moved into place - a typo written straight into
+// /etc/sudoers.d can lock every administrator out of sudo, and the only fix is
+// a root shell the typo just took away.
+
+package processors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// sudoersDir and polkitRulesDir are the drop-in directories the rules land in.
+// Variables so tests can point them at a temporary directory.
+var (
+	sudoersDir     = "/etc/sudoers.d"
+	polkitRulesDir = "/etc/polkit-1/rules.d"
+)
+
+var (
+	// policyRuleName is deliberately narrow: sudo's includedir silently skips
+	// any file whose name contains a "." or ends in "~", so a rule named
+	// "web.admins" would be written, reported as applied, and never read.
+	policyRuleName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// policyPrincipal is a user or group name as shadow-utils and Open Directory
+	// accept them. Anything else in a sudoers user list is either a typo or an
+	// attempt to smuggle in a different rule.
+	policyPrincipal = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*\$?$`)
+	envVarName      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	polkitActionID  = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*\.?$`)
+)
+
+// SudoersPath is where a sudoers rule is installed.
+func SudoersPath(rule types.Sudoers) string {
+	return filepath.Join(sudoersDir, "rwr-"+rule.Name)
+}
+
+// PolkitRulePath is where a polkit rule is installed. The 50- prefix sorts it
+// after the distribution's own rules but before the 90s that site policy
+// conventionally uses to override everything.
+func PolkitRulePath(rule types.PolkitRule) string {
+	return filepath.Join(polkitRulesDir, "50-rwr-"+rule.Name+".rules")
+}
+
+// RenderSudoers renders a rule as a sudoers.d snippet. Every value is checked
+// here, before visudo sees it: visudo proves the file parses, not that it says
+// what the blueprint meant, and a newline in a command would parse perfectly as
+// a second, undeclared rule.
+func RenderSudoers(rule types.Sudoers) (string, error) {
+	if !policyRuleName.MatchString(rule.Name) {
+		return "", fmt.Errorf("sudoers name %q may contain only letters, digits, '-' and '_'", rule.Name)
+	}
+	if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+		return "", fmt.Errorf("sudoers rule %s names no users or groups", rule.Name)
+	}
+
+	var principals []string
+	for _, user := range rule.Users {
+		if !policyPrincipal.MatchString(user) {
+			return "", fmt.Errorf("sudoers rule %s: %q is not a valid user name", rule.Name, user)
+		}
+		principals = append(principals, user)
+	}
+	for _, group := range rule.Groups {
+		if !policyPrincipal.MatchString(group) {
+			return "", fmt.Errorf("sudoers rule %s: %q is not a valid group name", rule.Name, group)
+		}
+		principals = append(principals, "%"+group)
+	}
+
+	runAs := "ALL"
+	if rule.RunAs != "" {
+		if rule.RunAs != "ALL" && !policyPrincipal.MatchString(rule.RunAs) {
+			return "", fmt.Errorf("sudoers rule %s: run_as %q is not a valid user name", rule.Name, rule.RunAs)
+		}
+		runAs = rule.RunAs
+	}
+
+	commands := []string{"ALL"}
+	if len(rule.Commands) > 0 {
+		commands = commands[:0]
+		for _, command := range rule.Commands {
+			if strings.ContainsAny(command, "\n\r\x00") {
+				return "", fmt.Errorf("sudoers rule %s: a command may not contain a line break", rule.Name)
+			}
+			if command != "ALL" && !strings.HasPrefix(command, "/") {
+				return "", fmt.Errorf("sudoers rule %s: command %q must be an absolute path or ALL", rule.Name, command)
+			}
+			commands = append(commands, sudoersEscape(command))
+		}
+	}
+
+	for _, name := range rule.EnvKeep {
+		if !envVarName.MatchString(name) {
+			return "", fmt.Errorf("sudoers rule %s: env_keep %q is not an environment variable name", rule.Name, name)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Managed by rwr (sudoers rule %q). Changes are overwritten on the next run.\n", rule.Name)
+	who := strings.Join(principals, ", ")
+	if len(rule.EnvKeep) > 0 {
+		fmt.Fprintf(&b, "Defaults:%s env_keep += \"%s\"\n", strings.Join(principals, ","), strings.Join(rule.EnvKeep, " "))
+	}
+	tag := ""
+	if rule.NoPasswd {
+		tag = "NOPASSWD: "
+	}
+	fmt.Fprintf(&b, "%s ALL=(%s) %s%s\n", who, runAs, tag, strings.Join(commands, ", "))
+	return b.String(), nil
+}
+
+// sudoersEscape escapes the characters sudoers treats as list and tag
+// separators inside a command line.
+func sudoersEscape(command string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `,`, `\,`, `:`, `\:`, `=`, `\=`)
+	return replacer.Replace(command)
+}
+
+// RenderPolkitRule renders a rule as a polkit JavaScript rules file. Values are
+// JSON-encoded into the script, so none of them can break out of its string
+// literals, and each is checked against what polkit itself accepts.
+func RenderPolkitRule(rule types.PolkitRule) (string, error) {
+	if !policyRuleName.MatchString(rule.Name) {
+		return "", fmt.Errorf("polkit rule name %q may contain only letters, digits, '-' and '_'", rule.Name)
+	}
+	if len(rule.Actions) == 0 {
+		return "", fmt.Errorf("polkit rule %s names no actions", rule.Name)
+	}
+	if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+		return "", fmt.Errorf("polkit rule %s names no users or groups", rule.Name)
+	}
+	for _, id := range rule.Actions {
+		if !polkitActionID.MatchString(id) {
+			return "", fmt.Errorf("polkit rule %s: %q is not a polkit action ID", rule.Name, id)
+		}
+	}
+	for _, principal := range append(append([]string{}, rule.Users...), rule.Groups...) {
+		if !policyPrincipal.MatchString(principal) {
+			return "", fmt.Errorf("polkit rule %s: %q is not a valid user or group name", rule.Name, principal)
+		}
+	}
+
+	result := rule.Result
+	if result == "" {
+		result = "yes"
+	}
+	known := false
+	for _, allowed := range types.PolkitResults {
+		if result == allowed {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return "", fmt.Errorf("polkit rule %s: result %q is not one of %v", rule.Name, result, types.PolkitResults)
+	}
+
+	actions, _ := json.Marshal(rule.Actions)       //nolint:errcheck // a []string always marshals
+	users, _ := json.Marshal(nonNil(rule.Users))   //nolint:errcheck // a []string always marshals
+	groups, _ := json.Marshal(nonNil(rule.Groups)) //nolint:errcheck // a []string always marshals
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Managed by rwr (polkit rule %q). Changes are overwritten on the next run.\n", rule.Name)
+	b.WriteString("polkit.addRule(function(action, subject) {\n")
+	fmt.Fprintf(&b, "    var actions = %s;\n", actions)
+	fmt.Fprintf(&b, "    var users = %s;\n", users)
+	fmt.Fprintf(&b, "    var groups = %s;\n", groups)
+	b.WriteString("    var matched = actions.some(function(id) {\n")
+	b.WriteString("        return id.charAt(id.length - 1) == \".\" ? action.id.indexOf(id) == 0 : action.id == id;\n")
+	b.WriteString("    });\n")
+	b.WriteString("    if (!matched) {\n        return polkit.Result.NOT_HANDLED;\n    }\n")
+	b.WriteString("    if (users.indexOf(subject.user) >= 0 || groups.some(function(g) { return subject.isInGroup(g); })) {\n")
+	fmt.Fprintf(&b, "        return polkit.Result.%s;\n", strings.ToUpper(result))
+	b.WriteString("    }\n    return polkit.Result.NOT_HANDLED;\n});\n")
+	return b.String(), nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// visudoCheck is the staged-file check for a sudoers snippet. Elevated because
+// visudo lives in sbin, outside an unprivileged PATH on most distributions.
+func visudoCheck(initConfig *types.InitConfig) func(staged string) error {
+	return func(staged string) error {
+		cmd := types.Command{
+			Exec:     "visudo",
+			Args:     []string{"-c", "-q", "-f", staged},
+			Elevated: true,
+		}
+		if err := system.RunCommand(cmd, initConfig.Variables.Flags.Debug); err != nil {
+			return fmt.Errorf("visudo rejected the rendered rule: %v", err)
+		}
+		return nil
+	}
+}
+
+func processSudoers(rules []types.Sudoers, initConfig *types.InitConfig, track *progress) error {
+	track.expect("", len(rules))
+	for _, rule := range rules {
+		path := SudoersPath(rule)
+		label := filepath.Base(path)
+		if userGOOS == "windows" {
+			log.Warnf("sudoers rules are not supported on Windows")
+			track.item("", label, rule.Action, types.StatusSkipped, "not supported on Windows", 0)
+			continue
+		}
+		started := time.Now()
+
+		var changed bool
+		var err error
+		switch rule.Action {
+		case types.PolicyActionCreate:
+			var content string
+			content, err = RenderSudoers(rule)
+			if err == nil {
+				changed, err = installPolicyFile(path, content, 0o440, visudoCheck(initConfig), initConfig)
+			}
+		case types.PolicyActionRemove:
+			changed, err = removePolicyFile(path, initConfig)
+		default:
+			err = fmt.Errorf("unsupported action %q", rule.Action)
+		}
+		if reportPolicyItem(track, label, rule.Action, path, changed, err, started) {
+			return fmt.Errorf("error processing sudoers rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func processPolkitRules(rules []types.PolkitRule, initConfig *types.InitConfig, track *progress) error {
+	track.expect("", len(rules))
+	for _, rule := range rules {
+		path := PolkitRulePath(rule)
+		label := filepath.Base(path)
+		if userGOOS != "linux" {
+			log.Warnf("polkit rules are only supported on Linux")
+			track.item("", label, rule.Action, types.StatusSkipped, "polkit is Linux-only", 0)
+			continue
+		}
+		started := time.Now()
+
+		var changed bool
+		var err error
+		switch rule.Action {
+		case types.PolicyActionCreate:
+			var content string
+			content, err = RenderPolkitRule(rule)
+			if err == nil {
+				changed, err = installPolicyFile(path, content, 0o644, nil, initConfig)
+			}
+		case types.PolicyActionRemove:
+			changed, err = removePolicyFile(path, initConfig)
+		default:
+			err = fmt.Errorf("unsupported action %q", rule.Action)
+		}
+		if reportPolicyItem(track, label, rule.Action, path, changed, err, started) {
+			return fmt.Errorf("error processing polkit rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// reportPolicyItem records one rule's outcome and reports whether it failed.
+func reportPolicyItem(track *progress, label, action, path string, changed bool, err error, started time.Time) bool {
+	identity := map[string]string{"path": path}
+	switch {
+	case err != nil:
+		log.Errorf("Error processing %s: %v", label, err)
+		track.itemIdentity("", label, action, types.StatusFailed, err.Error(), time.Since(started), identity)
+		return true
+	case system.IsDryRun():
+		track.item("", label, action, types.StatusPlanned, "dry-run", 0)
+	case changed:
+		log.Infof("%s %s", label, pastTense(action))
+		track.itemIdentity("", label, action, types.StatusOK, "", time.Since(started), identity)
+	default:
+		track.itemIdentity("", label, action, types.StatusPresent, "already up to date", time.Since(started), identity)
+	}
+	return false
+}
+
+// installPolicyFile writes a root-owned rule file when its content differs from
+// what is installed, or when the installed file is not root:root with mode -
+// sudo refuses an include file it does not trust, and a rule file the invoking
+// user owns is one they can rewrite into anything. The write is staged,
+// checked, and installed as root before it moves into place.
+func installPolicyFile(path, content string, mode os.FileMode, check func(string) error, initConfig *types.InitConfig) (bool, error) {
+	current, exists, err := readPolicyFile(path, initConfig)
+	if err != nil {
+		return false, err
+	}
+	if exists && current == content {
+		trusted, err := policyFileTrusted(path, mode, initConfig)
+		if err != nil || trusted {
+			return false, err
+		}
+		log.Infof("%s is not owned by root with mode %04o; reinstalling it", path, mode)
+	}
+	if system.IsDryRun() {
+		log.Infof("[DRY-RUN] Would write %s:\n%s", path, content)
+		return true, nil
+	}
+
+	if err := system.InstallRootFileChecked(path, content, mode, check); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// policyOwnedByRoot is the ownership half of policyFileTrusted, a variable so
+// tests can install rules without being root.
+var policyOwnedByRoot = system.OwnedByRoot
+
+// policyFileTrusted reports whether an installed rule is root:root with mode.
+// Like readPolicyFile, it falls back to an elevated stat when the directory is
+// not searchable by the invoking user.
+func policyFileTrusted(path string, mode os.FileMode, initConfig *types.InitConfig) (bool, error) {
+	info, statErr := os.Stat(path)
+	switch {
+	case statErr == nil:
+		return policyOwnedByRoot(info) && info.Mode().Perm() == mode.Perm(), nil
+	case !os.IsPermission(statErr):
+		return false, fmt.Errorf("error reading %s: %v", path, statErr)
+	}
+	args := []string{"-c", "%u:%g:%a", "--", path}
+	if userGOOS == "darwin" {
+		args = []string{"-f", "%u:%g:%Lp", path}
+	}
+	cmd := types.Command{Exec: "stat", Args: args, Elevated: true}
+	out, err := system.RunCommandOutput(cmd, initConfig.Variables.Flags.Debug)
+	if err != nil {
+		return false, fmt.Errorf("error reading the ownership of %s: %v", path, err)
+	}
+	return strings.TrimSpace(out) == fmt.Sprintf("0:0:%o", mode.Perm()), nil
+}
+
+func removePolicyFile(path string, initConfig *types.InitConfig) (bool, error) {
+	if _, exists, err := readPolicyFile(path, initConfig); err != nil || !exists {
+		return false, err
+	}
+	if system.IsDryRun() {
+		log.Infof("[DRY-RUN] Would remove %s", path)
+		return true, nil
+	}
+	cmd := types.Command{Exec: "rm", Args: []string{"-f", "--", path}, Elevated: true}
+	if err := system.RunCommand(cmd, initConfig.Variables.Flags.Debug); err != nil {
+		return false, fmt.Errorf("error removing %s: %v", path, err)
+	}
+	return true, nil
+}
+
+// readPolicyFile reads an installed rule. /etc/sudoers.d is not readable by an
+// unprivileged user, so a permission error falls back to an elevated cat
+// rather than being mistaken for an absent file.
+func readPolicyFile(path string, initConfig *types.InitConfig) (content string, exists bool, err error) {
+	data, readErr := os.ReadFile(path) // #nosec G304 -- path is built from a validated rule name under a fixed directory
+	switch {
+	case readErr == nil:
+		return string(data), true, nil
+	case os.IsNotExist(readErr):
+		return "", false, nil
+	case !os.IsPermission(readErr):
+		return "", false, fmt.Errorf("error reading %s: %v", path, readErr)
+	}
+	if system.IsDryRun() {
+		// Nothing executes in a dry run; assume it differs so the plan shows it.
+		return "", false, nil
+	}
+	probe := types.Command{Exec: "test", Args: []string{"-e", path}, Elevated: true}
+	if system.RunCommand(probe, initConfig.Variables.Flags.Debug) != nil {
+		return "", false, nil
+	}
+	cmd := types.Command{Exec: "cat", Args: []string{"--", path}, Elevated: true}
+	out, err := system.RunCommandOutput(cmd, initConfig.Variables.Flags.Debug)
+	if err != nil {
+		return "", false, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return out, true, nil
+}
+
+func processSudoersImports(items []types.Sudoers, blueprintDir string, format string, treeVersion int) ([]types.Sudoers, error) {
+	return helpers.ResolveImports(items, blueprintDir,
+		func(item types.Sudoers) string { return item.Import },
+		func(data []byte, fileFormat string) ([]types.Sudoers, error) {
+			var d types.UsersData
+			if err := helpers.DecodeBlueprintInto(data, fileFormat, types.BlueprintTypeUsers, treeVersion, &d); err != nil {
+				return nil, err
+			}
+			return d.Sudoers, nil
+		}, format)
+}
+
+func processPolkitImports(items []types.PolkitRule, blueprintDir string, format string, treeVersion int) ([]types.PolkitRule, error) {
+	return helpers.ResolveImports(items, blueprintDir,
+		func(item types.PolkitRule) string { return item.Import },
+		func(data []byte, fileFormat string) ([]types.PolkitRule, error) {
+			var d types.UsersData
+			if err := helpers.DecodeBlueprintInto(data, fileFormat, types.BlueprintTypeUsers, treeVersion, &d); err != nil {
+				return nil, err
+			}
+			return d.Polkit, nil
+		}, format)
+}
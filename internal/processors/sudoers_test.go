@@ -0,0 +1,231 @@
+// Tests for sudoers and polkit rules: rendering, the values rendering refuses,
+// and that a snippet visudo rejects never reaches /etc/sudoers.d.
+
+package processors
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/exectest"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// policyDirs points both drop-in directories at temporary ones.
+func policyDirs(t *testing.T) {
+	t.Helper()
+	prevSudoers, prevPolkit, prevOwned := sudoersDir, polkitRulesDir, policyOwnedByRoot
+	sudoersDir, polkitRulesDir = t.TempDir(), t.TempDir()
+	policyOwnedByRoot = func(os.FileInfo) bool { return true }
+	t.Cleanup(func() {
+		sudoersDir, polkitRulesDir, policyOwnedByRoot = prevSudoers, prevPolkit, prevOwned
+	})
+}
+
+// failingExec records every command and fails the ones named in fail.
+type failingExec struct {
+	rec  *exectest.Recorder
+	fail string
+}
+
+func (f failingExec) Run(cmd types.Command, debug bool) error {
+	err := f.rec.Run(cmd, debug)
+	if cmd.Exec == f.fail {
+		return errors.New("exit status 1")
+	}
+	return err
+}
+
+func (f failingExec) Output(cmd types.Command, debug bool) (string, error) {
+	return f.rec.Output(cmd, debug)
+}
+
+func TestRenderSudoers(t *testing.T) {
+	got, err := RenderSudoers(types.Sudoers{
+		Name:     "developers",
+		Users:    []string{"alice"},
+		Groups:   []string{"developers"},
+		Commands: []string{"/usr/bin/systemctl restart nginx", "/usr/bin/journalctl"},
+		NoPasswd: true,
+		EnvKeep:  []string{"HTTP_PROXY"},
+	})
+	if err != nil {
+		t.Fatalf("RenderSudoers: %v", err)
+	}
+	want := "# Managed by rwr (sudoers rule \"developers\"). Changes are overwritten on the next run.\n" +
+		"Defaults:alice,%developers env_keep += \"HTTP_PROXY\"\n" +
+		"alice, %developers ALL=(ALL) NOPASSWD: /usr/bin/systemctl restart nginx, /usr/bin/journalctl\n"
+	if got != want {
+		t.Errorf("RenderSudoers:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestRenderSudoers_RejectsUnsafeValues(t *testing.T) {
+	for name, rule := range map[string]types.Sudoers{
+		"dotted name":      {Name: "web.admins", Users: []string{"alice"}},
+		"no principals":    {Name: "empty"},
+		"bad user":         {Name: "r", Users: []string{"alice ALL=(ALL) ALL"}},
+		"relative command": {Name: "r", Users: []string{"alice"}, Commands: []string{"systemctl"}},
+		"newline command":  {Name: "r", Users: []string{"alice"}, Commands: []string{"/bin/true\nbob ALL=(ALL) ALL"}},
+		"bad run_as":       {Name: "r", Users: []string{"alice"}, RunAs: "root,bob"},
+		"bad env_keep":     {Name: "r", Users: []string{"alice"}, EnvKeep: []string{"A B"}},
+	} {
+		if _, err := RenderSudoers(rule); err == nil {
+			t.Errorf("%s: RenderSudoers accepted %+v", name, rule)
+		}
+	}
+}
+
+func TestRenderPolkitRule(t *testing.T) {
+	got, err := RenderPolkitRule(types.PolkitRule{
+		Name:    "network",
+		Actions: []string{"org.freedesktop.NetworkManager."},
+		Groups:  []string{"netadmin"},
+		Result:  "auth_self_keep",
+	})
+	if err != nil {
+		t.Fatalf("RenderPolkitRule: %v", err)
+	}
+	for _, want := range []string{
+		`var actions = ["org.freedesktop.NetworkManager."];`,
+		`var users = [];`,
+		`var groups = ["netadmin"];`,
+		`return polkit.Result.AUTH_SELF_KEEP;`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered rule is missing %q:\n%s", want, got)
+		}
+	}
+
+	for name, rule := range map[string]types.PolkitRule{
+		"no actions":   {Name: "r", Groups: []string{"wheel"}},
+		"quote in ID":  {Name: "r", Groups: []string{"wheel"}, Actions: []string{`a"b`}},
+		"bad result":   {Name: "r", Groups: []string{"wheel"}, Actions: []string{"a.b"}, Result: "always"},
+		"no principal": {Name: "r", Actions: []string{"a.b"}},
+	} {
+		if _, err := RenderPolkitRule(rule); err == nil {
+			t.Errorf("%s: RenderPolkitRule accepted %+v", name, rule)
+		}
+	}
+}
+
+func TestProcessSudoers_InstallsCheckedRootOwnedFile(t *testing.T) {
+	policyDirs(t)
+	rec := platform(t, "linux", true, false, "")
+	rule := types.Sudoers{Name: "ops", Action: types.PolicyActionCreate, Groups: []string{"ops"}}
+
+	if err := processSudoers([]types.Sudoers{rule}, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
+		t.Fatalf("processSudoers: %v", err)
+	}
+
+	path := SudoersPath(rule)
+	var order []string
+	for _, c := range rec.Calls {
+		order = append(order, c.Exec)
+		if !c.Elevated {
+			t.Errorf("%v should be elevated", c.Argv())
+		}
+	}
+	// Owned by root with its final mode before it is moved into place: a
+	// chown after the move leaves sudo reading a file the user owns.
+	if got, want := strings.Join(order, " "), "visudo install mv"; got != want {
+		t.Fatalf("commands = %v, want %s", argvs(rec), want)
+	}
+	visudo := rec.Calls[0]
+	staged := visudo.Args[len(visudo.Args)-1]
+	if strings.HasPrefix(filepath.Base(staged), "rwr-") || !strings.HasPrefix(filepath.Base(staged), ".") {
+		t.Errorf("staged file %s would be read by sudo's includedir", staged)
+	}
+	install := rec.Calls[1].Args
+	rootStaged := install[len(install)-1]
+	if got := strings.Join(install[:len(install)-1], " "); got != "-o 0 -g 0 -m 0440 -- "+staged {
+		t.Errorf("install = %v", rec.Calls[1].Argv())
+	}
+	if filepath.Dir(rootStaged) != sudoersDir || !strings.HasPrefix(filepath.Base(rootStaged), ".") {
+		t.Errorf("root-owned copy %s is not a dotted name beside the target", rootStaged)
+	}
+	if mv := rec.Calls[2].Args; mv[len(mv)-2] != rootStaged || mv[len(mv)-1] != path {
+		t.Errorf("mv = %v, want %s onto %s", rec.Calls[2].Argv(), rootStaged, path)
+	}
+}
+
+func TestProcessSudoers_VisudoRejectionRefusesInstall(t *testing.T) {
+	policyDirs(t)
+	platform(t, "linux", true, false, "")
+	rec := exectest.New()
+	restore := system.SetExecutor(failingExec{rec: rec, fail: "visudo"})
+	t.Cleanup(restore)
+
+	rule := types.Sudoers{Name: "ops", Action: types.PolicyActionCreate, Groups: []string{"ops"}}
+	err := processSudoers([]types.Sudoers{rule}, newTestInitConfig(), newProgress(types.BlueprintTypeUsers))
+	if err == nil {
+		t.Fatal("a rule visudo rejects was installed")
+	}
+	if len(rec.Find("mv")) != 0 || len(rec.Find("install")) != 0 {
+		t.Fatalf("rejected rule was moved into place: %v", argvs(rec))
+	}
+	entries, _ := os.ReadDir(sudoersDir)
+	if len(entries) != 0 {
+		t.Errorf("%s was left with %d entries after a rejected rule", sudoersDir, len(entries))
+	}
+}
+
+func TestProcessSudoers_UnchangedRuleIsLeftAlone(t *testing.T) {
+	policyDirs(t)
+	rec := platform(t, "linux", true, false, "")
+	rule := types.Sudoers{Name: "ops", Action: types.PolicyActionCreate, Groups: []string{"ops"}}
+	content, err := RenderSudoers(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(SudoersPath(rule), []byte(content), 0o440); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := processSudoers([]types.Sudoers{rule}, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
+		t.Fatalf("processSudoers: %v", err)
+	}
+	if len(rec.Calls) != 0 {
+		t.Fatalf("an installed, unchanged rule ran commands: %v", argvs(rec))
+	}
+}
+
+// The right content under the wrong owner or mode is not left alone: sudo
+// would not read it.
+func TestProcessSudoers_UntrustedOwnerIsReinstalled(t *testing.T) {
+	policyDirs(t)
+	policyOwnedByRoot = func(os.FileInfo) bool { return false }
+	rec := platform(t, "linux", true, false, "")
+	rule := types.Sudoers{Name: "ops", Action: types.PolicyActionCreate, Groups: []string{"ops"}}
+	content, err := RenderSudoers(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(SudoersPath(rule), []byte(content), 0o440); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := processSudoers([]types.Sudoers{rule}, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
+		t.Fatalf("processSudoers: %v", err)
+	}
+	if len(rec.Find("install")) != 1 || len(rec.Find("mv")) != 1 {
+		t.Fatalf("a rule sudo would not trust was left in place: %v", argvs(rec))
+	}
+}
+
+func TestProcessPolkitRules_SkippedOffLinux(t *testing.T) {
+	policyDirs(t)
+	rec := platform(t, "darwin", true, false, "")
+	rule := types.PolkitRule{Name: "net", Action: types.PolicyActionCreate, Actions: []string{"a.b"}, Groups: []string{"staff"}}
+
+	if err := processPolkitRules([]types.PolkitRule{rule}, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
+		t.Fatalf("processPolkitRules: %v", err)
+	}
+	if len(rec.Calls) != 0 {
+		t.Fatalf("polkit rule ran commands on darwin: %v", argvs(rec))
+	}
+}
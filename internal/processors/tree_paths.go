@@ -0,0 +1,139 @@
+package processors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// TreePaths is every path a run of the tree initFile names reads blueprints
+// from: the blueprint location, each blueprint file in it, bootstrap, and
+// the files they import, followed through. It reads the init file as
+// written, the way validate does - no credentials are resolved and nothing
+// runs - so a push can ship what the host's run will read.
+//
+// A location written as an absolute or home path is refused: the host would
+// resolve it on its own disk, not in the pushed tree. A tree whose
+// blueprints come from blueprints.git is the host's to clone, and contributes
+// only the init file.
+func TreePaths(initFile string) ([]string, error) {
+	data, err := os.ReadFile(initFile) // #nosec G304 -- operator-supplied init file
+	if err != nil {
+		return nil, err
+	}
+	format, err := helpers.FormatForPath(initFile)
+	if err != nil {
+		return nil, err
+	}
+	var initConfig types.InitConfig
+	if err := helpers.UnmarshalBlueprint(data, format, &initConfig); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", initFile, err)
+	}
+
+	paths := []string{initFile}
+	if git := initConfig.Init.Git; git != nil && git.URL != "" {
+		return paths, nil
+	}
+	location := initConfig.Init.Location
+	if filepath.IsAbs(location) || location == "~" || strings.HasPrefix(location, "~/") {
+		return nil, fmt.Errorf("%s: blueprints.location %q is a path on this machine, which the host would read from its own disk; write it relative to the init file", initFile, location)
+	}
+	initConfig.Init.Location = filepath.Join(filepath.Dir(initFile), location)
+	paths = append(paths, initConfig.Init.Location)
+
+	// Templates render as validate renders them, so an import named through
+	// a variable resolves to the same file.
+	declared := initConfig.Variables.UserDefined
+	if variables, err := helpers.DefaultVariables(); err == nil {
+		initConfig.Variables = variables
+	}
+	if initConfig.Variables.UserDefined == nil {
+		initConfig.Variables.UserDefined = map[string]interface{}{}
+	}
+	for key, value := range declared {
+		initConfig.Variables.UserDefined[key] = value
+	}
+
+	plan, err := ResolveStage1(&initConfig)
+	if err != nil {
+		return nil, err
+	}
+	visited := map[string]bool{}
+	for _, files := range plan.Files {
+		for _, document := range allDocuments(files) {
+			if !visited[document.Path] {
+				visited[document.Path] = true
+				paths = append(paths, document.Path)
+			}
+			paths = append(paths, importedPaths(document.Resolved, document.Format, filepath.Dir(document.Path), initConfig.Variables, visited)...)
+		}
+	}
+	if bootstrapFile := findBootstrapFile(initConfig.Init.Location); bootstrapFile != "" {
+		paths = append(paths, bootstrapFile)
+		if data, err := os.ReadFile(bootstrapFile); err == nil { // #nosec G304 -- operator's own blueprint tree
+			if format, err := helpers.FormatForPath(bootstrapFile); err == nil {
+				paths = append(paths, importedPaths(data, format, filepath.Dir(bootstrapFile), initConfig.Variables, visited)...)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// importedPaths is every file a blueprint document imports, whatever its
+// type, and what those files import in turn - each relative to the file
+// naming it, as ResolveImports reads them. A file that cannot be read or
+// decoded is listed and not followed: the run will report it.
+func importedPaths(data []byte, format, dir string, variables types.Variables, visited map[string]bool) []string {
+	var document interface{}
+	if err := helpers.UnmarshalBlueprint(data, format, &document); err != nil {
+		return nil
+	}
+	var paths []string
+	for _, name := range importNames(document) {
+		path := filepath.Join(dir, name)
+		if visited[path] {
+			continue
+		}
+		visited[path] = true
+		paths = append(paths, path)
+
+		imported, err := os.ReadFile(path) // #nosec G304 -- operator's own blueprint tree
+		if err != nil {
+			continue
+		}
+		if resolved, err := helpers.ResolveTemplate(imported, variables); err == nil {
+			imported = resolved
+		}
+		importedFormat, err := helpers.FormatForPath(path)
+		if err != nil {
+			importedFormat = format
+		}
+		paths = append(paths, importedPaths(imported, importedFormat, filepath.Dir(path), variables, visited)...)
+	}
+	return paths
+}
+
+// importNames collects the `import:` values of every entry in a decoded
+// document.
+func importNames(value interface{}) []string {
+	var names []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if name, ok := item.(string); ok && key == "import" && name != "" {
+				names = append(names, name)
+				continue
+			}
+			names = append(names, importNames(item)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			names = append(names, importNames(item)...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,141 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// UpgradeProcessor names the `rwr upgrade` pass in events and on the
+// dashboard. It is not a blueprint type: nothing in the tree declares it.
+const UpgradeProcessor = "upgrade"
+
+// upgradeEverythingName is the row an everything-upgrade reports under, in
+// place of a package name.
+const upgradeEverythingName = "all packages"
+
+// upgradableProviders are the available providers that can upgrade
+// everything, sorted so the lanes and the run go in the same order each time.
+func upgradableProviders() []*types.Provider {
+	available := system.GetAvailableProviders()
+	names := make([]string, 0, len(available))
+	for name, provider := range available {
+		if provider.Commands.Update != "" || provider.Commands.UpgradeAll != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	providers := make([]*types.Provider, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, available[name])
+	}
+	return providers
+}
+
+// UpgradePlan is what the dashboard draws `rwr upgrade` from: one lane per
+// provider Upgrade will run, each with its one upgrade.
+func UpgradePlan(initConfig *types.InitConfig) *types.Plan {
+	plan := &types.Plan{Init: initConfig, Order: []string{UpgradeProcessor}}
+	for _, provider := range upgradableProviders() {
+		plan.Resources = append(plan.Resources, types.Resource{
+			Processor: UpgradeProcessor,
+			Provider:  provider.Name,
+			Name:      provider.Name,
+			Action:    types.ActionUpgrade,
+			Status:    types.StatusPlanned,
+		})
+	}
+	return plan
+}
+
+// Upgrade brings everything every available package manager manages up to
+// date, one provider after another: its update, then its upgrade-all where
+// update only refreshes. A provider that fails is recorded and the rest still
+// run. Holds are the package managers' own, so what a blueprint held stays
+// where it is.
+func Upgrade(initConfig *types.InitConfig) error {
+	if system.IsOffline() {
+		return errors.New("an upgrade needs the network; run it without --offline")
+	}
+	resetFailures()
+	if err := system.InitProviders(); err != nil {
+		return fmt.Errorf("error initializing providers: %w", err)
+	}
+	providers := upgradableProviders()
+	if len(providers) == 0 {
+		return errors.New("no package managers available to upgrade")
+	}
+
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name)
+	}
+	reporting.Emit(reporting.ProcStarted{Processor: UpgradeProcessor, Providers: names})
+	started := time.Now()
+
+	track := newProgress(UpgradeProcessor)
+	for _, provider := range providers {
+		track.expect(provider.Name, 1)
+	}
+	for _, provider := range providers {
+		if system.Cancelled() {
+			reporting.Emit(reporting.ProcFinished{Processor: UpgradeProcessor, Err: system.ErrCancelled, Dur: time.Since(started)})
+			return system.ErrCancelled
+		}
+		reporting.SetCurrentProvider(provider.Name)
+		log.Infof("Upgrading %s", provider.Name)
+		providerStarted := time.Now()
+		if err := upgradeEverything(provider, false, initConfig.Variables.Flags.Debug); err != nil {
+			recordFailure(UpgradeProcessor, provider.Name, err)
+			track.item(provider.Name, provider.Name, types.ActionUpgrade, types.StatusFailed, err.Error(), time.Since(providerStarted))
+			continue
+		}
+		track.item(provider.Name, provider.Name, types.ActionUpgrade, types.StatusOK, "", time.Since(providerStarted))
+	}
+	reporting.SetCurrentProvider("")
+
+	err := failureError()
+	reporting.Emit(reporting.ProcFinished{Processor: UpgradeProcessor, Err: err, Dur: time.Since(started)})
+	if err != nil {
+		return err
+	}
+	log.Info("Upgrade complete")
+	return nil
+}
+
+// upgradeEverything runs provider's update and then, where it declares one,
+// its upgrade-all: apt's update only refreshes the lists, pacman's upgrades
+// the system on its own.
+func upgradeEverything(provider *types.Provider, elevated, debug bool) error {
+	commands := []string{provider.Commands.Update, provider.Commands.UpgradeAll}
+	ran := false
+	for _, command := range commands {
+		if command == "" {
+			continue
+		}
+		ran = true
+		bin, args := system.ProviderCommand(provider, command)
+		err := system.RunCommand(types.Command{
+			Exec:      bin,
+			Args:      args,
+			Elevated:  provider.Elevated || elevated,
+			Escalates: provider.Escalates,
+			Variables: provider.Environment,
+		}, debug)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", provider.Name, strings.Fields(command)[0], err)
+		}
+	}
+	if !ran {
+		return fmt.Errorf("%s has no update or upgrade command", provider.Name)
+	}
+	return nil
+}
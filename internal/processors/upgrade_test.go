@@ -0,0 +1,58 @@
+package processors
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/exectest"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// Every provider that can upgrade is upgraded, in name order, and one that
+// fails does not stop the ones after it.
+func TestUpgrade_EveryProviderInOnePass(t *testing.T) {
+	bin := "sh"
+	if runtime.GOOS == types.OSWindows {
+		bin = "cmd"
+	}
+	detection := types.DetectionConfig{Binary: bin, Distributions: []string{types.OSLinux, types.OSDarwin, types.OSWindows}}
+	defer system.SetProvidersForTest(map[string]*types.Provider{
+		"apt":     {Name: "apt", Detection: detection, Commands: types.CommandConfig{Install: "install", Update: "update", UpgradeAll: "upgrade -y"}},
+		"flatpak": {Name: "flatpak", Detection: detection, Commands: types.CommandConfig{Install: "install", Update: "update -y"}},
+		"pacman":  {Name: "pacman", Detection: detection, Commands: types.CommandConfig{Install: "-S", Update: "-Syu"}},
+		"mas":     {Name: "mas", Detection: detection, Commands: types.CommandConfig{Install: "install"}},
+	})()
+	rec := exectest.New()
+	rec.Fail = func(call exectest.Call) error {
+		if strings.Join(call.Args, " ") == "update -y" {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+	defer system.SetExecutor(rec)()
+	t.Cleanup(resetFailures)
+
+	plan := UpgradePlan(&types.InitConfig{})
+	var lanes []string
+	for _, resource := range plan.Resources {
+		lanes = append(lanes, resource.Provider)
+	}
+	if strings.Join(lanes, ",") != "apt,flatpak,pacman" {
+		t.Fatalf("planned lanes = %v, want the three that can upgrade", lanes)
+	}
+
+	err := Upgrade(&types.InitConfig{})
+	if err == nil || !strings.Contains(err.Error(), "flatpak") {
+		t.Fatalf("flatpak's failed update was not reported: %v", err)
+	}
+	var calls []string
+	for _, call := range rec.Calls {
+		calls = append(calls, strings.Join(call.Args, " "))
+	}
+	if strings.Join(calls, "|") != "update|upgrade -y|update -y|-Syu" {
+		t.Fatalf("calls = %q", calls)
+	}
+}
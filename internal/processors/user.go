@@ -37,9 +37,11 @@ const macDefaultPrimaryGroupID = "20"
 //
 // Linux goes through shadow-utils (useradd/usermod/userdel, groupadd/groupmod,
 // gpasswd); macOS goes through Open Directory (dscl, sysadminctl, dseditgroup,
-// pwpolicy) - none of the shadow-utils binaries exist there. Windows has no
-// implementation and logs a warning per entry.
-func ProcessUsers(blueprintData []byte, blueprintDir string, format string, initConfig *types.InitConfig) error {
+// pwpolicy) - none of the shadow-utils binaries exist there; Windows goes
+// through the PowerShell LocalAccounts cmdlets (see user_localaccounts.go). The
+// shell action is its own path on Linux and macOS (see user_shell.go), and
+// sudoers and polkit rules are applied after users and groups (see sudoers.go).
+func ProcessUsers(blueprintData []byte, blueprintDir string, format string, osInfo *types.OSInfo, initConfig *types.InitConfig) error {
 	var usersData types.UsersData
 	var err error
 
@@ -64,6 +66,18 @@ func ProcessUsers(blueprintData []byte, blueprintDir string, format string, init
 	}
 	usersData.Users = allUsers
 
+	allSudoers, err := processSudoersImports(usersData.Sudoers, blueprintDir, format, helpers.TreeSchemaVersion(initConfig))
+	if err != nil {
+		return fmt.Errorf("error processing sudoers imports: %w", err)
+	}
+	usersData.Sudoers = allSudoers
+
+	allPolkit, err := processPolkitImports(usersData.Polkit, blueprintDir, format, helpers.TreeSchemaVersion(initConfig))
+	if err != nil {
+		return fmt.Errorf("error processing polkit imports: %w", err)
+	}
+	usersData.Polkit = allPolkit
+
 	// Filter groups based on active profiles
 	filteredGroups := helpers.FilterByProfiles(usersData.Groups, initConfig.Variables.Flags.Profiles)
 	log.Debugf("Filtering groups: %d total, %d matching active profiles %v",
@@ -86,12 +100,23 @@ func ProcessUsers(blueprintData []byte, blueprintDir string, format string, init
 	}
 
 	// Process the filtered users
-	err = processUsers(filteredUsers, initConfig, track)
+	err = processUsers(filteredUsers, osInfo, initConfig, track)
 	if err != nil {
 		log.Errorf("Error processing users: %v", err)
 		return fmt.Errorf("error processing users: %w", err)
 	}
 
+	// Privilege rules last: a rule may name a user or group created above.
+	err = processSudoers(helpers.FilterByProfiles(usersData.Sudoers, initConfig.Variables.Flags.Profiles), initConfig, track)
+	if err != nil {
+		return fmt.Errorf("error processing sudoers rules: %w", err)
+	}
+
+	err = processPolkitRules(helpers.FilterByProfiles(usersData.Polkit, initConfig.Variables.Flags.Profiles), initConfig, track)
+	if err != nil {
+		return fmt.Errorf("error processing polkit rules: %w", err)
+	}
+
 	return nil
 }
 
@@ -134,17 +159,12 @@ func processGroups(groups []types.Group, initConfig *types.InitConfig, track *pr
 			track.item("", group.Name, group.Action, types.StatusFailed, "unsupported action", 0)
 			return fmt.Errorf("unsupported action for group %s: %s", group.Name, group.Action)
 		}
-		if userGOOS == "windows" {
-			// The per-action helpers warn and no-op on Windows.
-			track.item("", group.Name, group.Action, types.StatusSkipped, "not supported on Windows", 0)
-		} else {
-			track.item("", group.Name, group.Action, types.StatusOK, "", time.Since(started))
-		}
+		track.item("", group.Name, group.Action, types.StatusOK, "", time.Since(started))
 	}
 	return nil
 }
 
-func processUsers(users []types.User, initConfig *types.InitConfig, track *progress) error {
+func processUsers(users []types.User, osInfo *types.OSInfo, initConfig *types.InitConfig, track *progress) error {
 	track.expect("", len(users))
 	for _, user := range users {
 		if system.IsDryRun() {
@@ -153,6 +173,11 @@ func processUsers(users []types.User, initConfig *types.InitConfig, track *progr
 			continue
 		}
 		started := time.Now()
+		if err := resolvePasswordCredential(&user); err != nil {
+			log.Errorf("Error resolving password for user %s: %v", user.Name, err)
+			track.item("", user.Name, user.Action, types.StatusFailed, err.Error(), time.Since(started))
+			return fmt.Errorf("error resolving password for user %s: %w", user.Name, err)
+		}
 		switch user.Action {
 		case types.UserActionCreate:
 			err := createUser(user, initConfig)
@@ -170,6 +195,27 @@ func processUsers(users []types.User, initConfig *types.InitConfig, track *progr
 				return fmt.Errorf("error modifying user %s: %w", user.Name, err)
 			}
 			log.Infof("User %s modified successfully", user.Name)
+		case types.UserActionShell:
+			// Reported here rather than below: an account already on the
+			// declared shell is present, not applied, and the journal keeps the
+			// shell it had before.
+			changed, previous, err := setLoginShell(user, osInfo, initConfig)
+			if err != nil {
+				log.Errorf("Error setting login shell for user %s: %v", user.Name, err)
+				track.item("", user.Name, user.Action, types.StatusFailed, err.Error(), time.Since(started))
+				return fmt.Errorf("error setting login shell for user %s: %w", user.Name, err)
+			}
+			identity := map[string]string{"shell": user.Shell, "previous_shell": previous}
+			switch {
+			case userGOOS == "windows":
+				track.item("", user.Name, user.Action, types.StatusSkipped, "not supported on Windows", 0)
+			case changed:
+				log.Infof("Login shell for user %s set to %s", user.Name, user.Shell)
+				track.itemIdentity("", user.Name, user.Action, types.StatusOK, "", time.Since(started), identity)
+			default:
+				track.itemIdentity("", user.Name, user.Action, types.StatusPresent, "already the login shell", time.Since(started), identity)
+			}
+			continue
 		case types.UserActionRemove, types.UserActionDelete:
 			err := removeUser(user, initConfig)
 			if err != nil {
@@ -183,12 +229,7 @@ func processUsers(users []types.User, initConfig *types.InitConfig, track *progr
 			track.item("", user.Name, user.Action, types.StatusFailed, "unsupported action", 0)
 			return fmt.Errorf("unsupported action for user %s: %s", user.Name, user.Action)
 		}
-		if userGOOS == "windows" {
-			// The per-action helpers warn and no-op on Windows.
-			track.item("", user.Name, user.Action, types.StatusSkipped, "not supported on Windows", 0)
-		} else {
-			track.item("", user.Name, user.Action, types.StatusOK, "", time.Since(started))
-		}
+		track.item("", user.Name, user.Action, types.StatusOK, "", time.Since(started))
 	}
 	return nil
 }
@@ -213,6 +254,8 @@ func groupExists(name string, initConfig *types.InitConfig, assumeDryRun bool) b
 	switch userGOOS {
 	case "darwin":
 		cmd = types.Command{Exec: "dscl", Args: []string{".", "-read", "/Groups/" + name}}
+	case "windows":
+		return localAccountExists(localGroupProbeScript, name, initConfig, assumeDryRun)
 	default:
 		cmd = types.Command{Exec: "getent", Args: []string{"group", name}}
 	}
@@ -228,6 +271,8 @@ func userExists(name string, initConfig *types.InitConfig, assumeDryRun bool) bo
 	switch userGOOS {
 	case "darwin":
 		cmd = types.Command{Exec: "dscl", Args: []string{".", "-read", "/Users/" + name}}
+	case "windows":
+		return localAccountExists(localUserProbeScript, name, initConfig, assumeDryRun)
 	default:
 		cmd = types.Command{Exec: "getent", Args: []string{"passwd", name}}
 	}
@@ -245,11 +290,10 @@ func createGroup(group types.Group, initConfig *types.InitConfig) error {
 	case "darwin":
 		return createGroupDarwin(group, initConfig)
 	case "windows":
-		log.Warnf("Creating groups is not supported on Windows")
+		return createGroupWindows(group, initConfig)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", userGOOS)
 	}
-	return nil
 }
 
 func modifyGroup(group types.Group, initConfig *types.InitConfig) error {
@@ -259,11 +303,10 @@ func modifyGroup(group types.Group, initConfig *types.InitConfig) error {
 	case "darwin":
 		return modifyGroupDarwin(group, initConfig)
 	case "windows":
-		log.Warnf("Modifying groups is not supported on Windows")
+		return modifyGroupWindows(group, initConfig)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", userGOOS)
 	}
-	return nil
 }
 
 func removeGroup(group types.Group, initConfig *types.InitConfig) error {
@@ -287,7 +330,7 @@ func removeGroup(group types.Group, initConfig *types.InitConfig) error {
 			return fmt.Errorf("error removing group: %v", err)
 		}
 	case "windows":
-		log.Warnf("Removing groups is not supported on Windows")
+		return removeGroupWindows(group, initConfig)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", userGOOS)
 	}
@@ -305,11 +348,10 @@ func createUser(user types.User, initConfig *types.InitConfig) error {
 	case "darwin":
 		return createUserDarwin(user, initConfig)
 	case "windows":
-		log.Warnf("Creating users is not supported on Windows")
+		return createUserWindows(user, initConfig)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", userGOOS)
 	}
-	return nil
 }
 
 func modifyUser(user types.User, initConfig *types.InitConfig) error {
@@ -319,11 +361,10 @@ func modifyUser(user types.User, initConfig *types.InitConfig) error {
 	case "darwin":
 		return modifyUserDarwin(user, initConfig)
 	case "windows":
-		log.Warnf("Modifying users is not supported on Windows")
+		return modifyUserWindows(user, initConfig)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", userGOOS)
 	}
-	return nil
 }
 
 func removeUser(user types.User, initConfig *types.InitConfig) error {
@@ -333,17 +374,37 @@ func removeUser(user types.User, initConfig *types.InitConfig) error {
 	case "darwin":
 		return removeUserDarwin(user, initConfig)
 	case "windows":
-		log.Warnf("Removing users is not supported on Windows")
+		return removeUserWindows(user, initConfig)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", userGOOS)
 	}
-	return nil
 }
 
 // ---------------------------------------------------------------------------
 // password handling
 // ---------------------------------------------------------------------------
 
+// resolvePasswordCredential fills in the password from password_credential. The
+// credential is read ungated: rwr sets the password itself, so the value is
+// not being handed to a blueprint and needs no exposeCredentials opt-in.
+func resolvePasswordCredential(user *types.User) error {
+	if user.PasswordCredential == "" {
+		return nil
+	}
+	if user.Password != "" {
+		return fmt.Errorf("password and password_credential are both set")
+	}
+	if !types.IsManagedCredential(user.PasswordCredential) {
+		return fmt.Errorf("password_credential %q is not declared in the init file's credentials section", user.PasswordCredential)
+	}
+	value, ok := types.CredentialValue(user.PasswordCredential)
+	if !ok || value == "" {
+		return fmt.Errorf("credential %q has no value", user.PasswordCredential)
+	}
+	user.Password = value
+	return nil
+}
+
 // modernCryptHash matches the "$id$" prefix of every crypt(3) scheme glibc and
 // libxcrypt support ($1$, $5$, $6$, $2b$, $y$, $gy$, ...).
 var modernCryptHash = regexp.MustCompile(`^\$[A-Za-z0-9_-]{1,12}\$`)
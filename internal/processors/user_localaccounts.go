@@ -0,0 +1,292 @@
+// The local-accounts backend: Windows user and group management via the
+// Microsoft.PowerShell.LocalAccounts cmdlets (New-LocalUser, Set-LocalUser,
+// Add-LocalGroupMember, ...). Selected at runtime on the detected OS - like
+// user_opendirectory.go, deliberately NOT a _windows.go file, so the argv can
+// be asserted from the Linux CI runner.
+//
+// Every script is a compile-time constant that reads its inputs from $env:,
+// the same rule processWindowsRegistry follows: PowerShell tokenizes whatever
+// follows -Command, so an account name or description interpolated into it
+// could close a quote and run as a second statement. The password never goes
+// into the environment either; it is read from standard input.
+
+package processors
+
+import (
+	"errors"
+	"fmt"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// Environment variables carrying blueprint values into the scripts below.
+const (
+	localAccountNameEnv       = "RWR_ACCOUNT_NAME"
+	localAccountNewNameEnv    = "RWR_ACCOUNT_NEW_NAME"
+	localAccountCommentEnv    = "RWR_ACCOUNT_COMMENT"
+	localAccountExpireEnv     = "RWR_ACCOUNT_EXPIRE"
+	localAccountGroupEnv      = "RWR_ACCOUNT_GROUP"
+	localAccountPasswordEnv   = "RWR_ACCOUNT_HAS_PASSWORD"
+	localAccountRemoveHomeEnv = "RWR_ACCOUNT_REMOVE_HOME"
+)
+
+// Every script starts here so a failing cmdlet is a terminating error, which is
+// what turns it into a non-zero exit code under -Command.
+const localAccountsPrelude = `$ErrorActionPreference = 'Stop'
+`
+
+// localAccountParams builds the Set-LocalUser/New-LocalUser parameters shared
+// by create and modify. The password line is read only when the caller says
+// one was supplied, so an absent password never blocks on an open stdin.
+const localAccountParams = `$p = @{ Name = $env:RWR_ACCOUNT_NAME }
+if ($env:RWR_ACCOUNT_COMMENT) { $p.Description = $env:RWR_ACCOUNT_COMMENT }
+if ($env:RWR_ACCOUNT_EXPIRE) { $p.AccountExpires = [datetime]::ParseExact($env:RWR_ACCOUNT_EXPIRE, 'yyyy-MM-dd', [Globalization.CultureInfo]::InvariantCulture) }
+if ($env:RWR_ACCOUNT_HAS_PASSWORD -eq '1') { $p.Password = ConvertTo-SecureString -String ([Console]::In.ReadLine()) -AsPlainText -Force }
+`
+
+const (
+	localUserProbeScript  = localAccountsPrelude + `Get-LocalUser -Name $env:RWR_ACCOUNT_NAME | Out-Null`
+	localGroupProbeScript = localAccountsPrelude + `Get-LocalGroup -Name $env:RWR_ACCOUNT_NAME | Out-Null`
+
+	// New-LocalUser insists on one of -Password or -NoPassword.
+	localUserCreateScript = localAccountsPrelude + localAccountParams +
+		`if (-not $p.ContainsKey('Password')) { $p.NoPassword = $true }
+New-LocalUser @p | Out-Null`
+	localUserSetScript = localAccountsPrelude + localAccountParams +
+		`if ($p.Count -gt 1) { Set-LocalUser @p }`
+	localUserDisableScript = localAccountsPrelude + `Disable-LocalUser -Name $env:RWR_ACCOUNT_NAME`
+	localUserEnableScript  = localAccountsPrelude + `Enable-LocalUser -Name $env:RWR_ACCOUNT_NAME`
+	localUserRenameScript  = localAccountsPrelude + `Rename-LocalUser -Name $env:RWR_ACCOUNT_NAME -NewName $env:RWR_ACCOUNT_NEW_NAME`
+
+	// The profile is looked up by SID before the account goes: afterwards
+	// nothing ties the directory under C:\Users to the deleted name.
+	localUserRemoveScript = localAccountsPrelude + `$u = Get-LocalUser -Name $env:RWR_ACCOUNT_NAME
+Remove-LocalUser -InputObject $u
+if ($env:RWR_ACCOUNT_REMOVE_HOME -eq '1') {
+  Get-CimInstance -ClassName Win32_UserProfile | Where-Object { $_.SID -eq $u.SID.Value } | Remove-CimInstance
+}`
+
+	// Membership is matched on SID: Get-LocalGroupMember reports names as
+	// COMPUTER\name, and Add-LocalGroupMember fails outright on an existing
+	// member, which is the error that made a second run abort on Linux.
+	localGroupAddMemberScript = localAccountsPrelude + `$u = Get-LocalUser -Name $env:RWR_ACCOUNT_NAME
+if (-not (Get-LocalGroupMember -Group $env:RWR_ACCOUNT_GROUP | Where-Object { $_.SID -eq $u.SID })) {
+  Add-LocalGroupMember -Group $env:RWR_ACCOUNT_GROUP -Member $u
+}`
+	localGroupRemoveMemberScript = localAccountsPrelude + `$u = Get-LocalUser -Name $env:RWR_ACCOUNT_NAME
+if (Get-LocalGroupMember -Group $env:RWR_ACCOUNT_GROUP | Where-Object { $_.SID -eq $u.SID }) {
+  Remove-LocalGroupMember -Group $env:RWR_ACCOUNT_GROUP -Member $u
+}`
+
+	localGroupCreateScript = localAccountsPrelude + `New-LocalGroup -Name $env:RWR_ACCOUNT_NAME | Out-Null`
+	localGroupRenameScript = localAccountsPrelude + `Rename-LocalGroup -Name $env:RWR_ACCOUNT_NAME -NewName $env:RWR_ACCOUNT_NEW_NAME`
+	localGroupRemoveScript = localAccountsPrelude + `Remove-LocalGroup -Name $env:RWR_ACCOUNT_NAME`
+)
+
+// localAccountsCommand wraps a script in the powershell invocation every call
+// uses. Mutations are marked elevated; on Windows that means the run itself
+// must already be elevated, as for every other command (see system.spawn).
+func localAccountsCommand(script string, vars map[string]string, elevated bool) types.Command {
+	return types.Command{
+		Exec:      "powershell",
+		Args:      []string{"-NoProfile", "-NonInteractive", "-Command", script},
+		Elevated:  elevated,
+		Variables: vars,
+	}
+}
+
+func runLocalAccounts(script string, vars map[string]string, initConfig *types.InitConfig) error {
+	return system.RunCommand(localAccountsCommand(script, vars, true), initConfig.Variables.Flags.Debug)
+}
+
+// localAccountExists runs one of the probe scripts; see groupExists for
+// assumeDryRun.
+func localAccountExists(script, name string, initConfig *types.InitConfig, assumeDryRun bool) bool {
+	if system.IsDryRun() {
+		return assumeDryRun
+	}
+	cmd := localAccountsCommand(script, map[string]string{localAccountNameEnv: name}, false)
+	return system.RunCommand(cmd, initConfig.Variables.Flags.Debug) == nil
+}
+
+// ---------------------------------------------------------------------------
+// groups
+// ---------------------------------------------------------------------------
+
+func createGroupWindows(group types.Group, initConfig *types.InitConfig) error {
+	if group.GID != "" || group.System {
+		log.Warnf("Group %s: 'gid' and 'system' are ignored on Windows; local groups are identified by a SID Windows assigns", group.Name)
+	}
+	if groupExists(group.Name, initConfig, false) {
+		log.Infof("Group %s already exists", group.Name)
+		return nil
+	}
+	if err := runLocalAccounts(localGroupCreateScript, map[string]string{localAccountNameEnv: group.Name}, initConfig); err != nil {
+		return fmt.Errorf("error creating group: %v", err)
+	}
+	return nil
+}
+
+func modifyGroupWindows(group types.Group, initConfig *types.InitConfig) error {
+	if group.GID != "" {
+		log.Warnf("Group %s: 'gid' is ignored on Windows", group.Name)
+	}
+	if group.NewName == "" {
+		return nil
+	}
+	vars := map[string]string{localAccountNameEnv: group.Name, localAccountNewNameEnv: group.NewName}
+	if err := runLocalAccounts(localGroupRenameScript, vars, initConfig); err != nil {
+		return fmt.Errorf("error renaming group: %v", err)
+	}
+	return nil
+}
+
+func removeGroupWindows(group types.Group, initConfig *types.InitConfig) error {
+	if !groupExists(group.Name, initConfig, true) {
+		log.Infof("Group %s does not exist, nothing to remove", group.Name)
+		return nil
+	}
+	if err := runLocalAccounts(localGroupRemoveScript, map[string]string{localAccountNameEnv: group.Name}, initConfig); err != nil {
+		return fmt.Errorf("error removing group: %v", err)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// users
+// ---------------------------------------------------------------------------
+
+func createUserWindows(user types.User, initConfig *types.InitConfig) error {
+	if err := checkWindowsUser(user); err != nil {
+		return err
+	}
+
+	if userExists(user.Name, initConfig, false) {
+		log.Infof("User %s already exists, converging declared attributes instead of creating", user.Name)
+		if err := setLocalUser(user.Name, user, initConfig); err != nil {
+			return fmt.Errorf("error converging existing user: %v", err)
+		}
+		return windowsGroupMembership(user.Name, user.Groups, localGroupAddMemberScript, initConfig)
+	}
+
+	cmd := localAccountsCommand(localUserCreateScript, localUserVars(user.Name, user), true)
+	cmd.Stdin = windowsPasswordStdin(user)
+	if err := system.RunCommand(cmd, initConfig.Variables.Flags.Debug); err != nil {
+		// The error deliberately excludes the password, which is on stdin.
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	return windowsGroupMembership(user.Name, user.Groups, localGroupAddMemberScript, initConfig)
+}
+
+func modifyUserWindows(user types.User, initConfig *types.InitConfig) error {
+	if err := checkWindowsUser(user); err != nil {
+		return err
+	}
+	if user.NewShell != "" || user.NewHome != "" {
+		log.Warnf("User %s: 'new_shell' and 'new_home' are ignored on Windows", user.Name)
+	}
+
+	if err := setLocalUser(user.Name, user, initConfig); err != nil {
+		return fmt.Errorf("error modifying user: %v", err)
+	}
+
+	vars := map[string]string{localAccountNameEnv: user.Name}
+	if user.Lock {
+		if err := runLocalAccounts(localUserDisableScript, vars, initConfig); err != nil {
+			return fmt.Errorf("error locking user: %v", err)
+		}
+	}
+	if user.Unlock {
+		if err := runLocalAccounts(localUserEnableScript, vars, initConfig); err != nil {
+			return fmt.Errorf("error unlocking user: %v", err)
+		}
+	}
+
+	if err := windowsGroupMembership(user.Name, user.AddGroups, localGroupAddMemberScript, initConfig); err != nil {
+		return err
+	}
+	if err := windowsGroupMembership(user.Name, user.RemoveGroups, localGroupRemoveMemberScript, initConfig); err != nil {
+		return err
+	}
+
+	// Renaming last: every edit above addresses the account by its old name.
+	if user.NewName != "" {
+		vars := map[string]string{localAccountNameEnv: user.Name, localAccountNewNameEnv: user.NewName}
+		if err := runLocalAccounts(localUserRenameScript, vars, initConfig); err != nil {
+			return fmt.Errorf("error renaming user: %v", err)
+		}
+	}
+	return nil
+}
+
+func removeUserWindows(user types.User, initConfig *types.InitConfig) error {
+	if !userExists(user.Name, initConfig, true) {
+		log.Infof("User %s does not exist, nothing to remove", user.Name)
+		return nil
+	}
+	vars := map[string]string{localAccountNameEnv: user.Name}
+	if user.RemoveHome {
+		vars[localAccountRemoveHomeEnv] = "1"
+	}
+	if err := runLocalAccounts(localUserRemoveScript, vars, initConfig); err != nil {
+		return fmt.Errorf("error removing user: %v", err)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Windows helpers
+// ---------------------------------------------------------------------------
+
+// checkWindowsUser refuses what Windows cannot honor and warns about the
+// fields it has no equivalent for, rather than dropping them silently.
+func checkWindowsUser(user types.User) error {
+	if user.Password != "" && isCryptHash(user.Password) {
+		return errors.New("a crypt(3) password hash cannot be used on Windows; supply the cleartext password, ideally through password_credential")
+	}
+	if user.UID != "" || user.System || user.Shell != "" || user.Home != "" {
+		log.Warnf("User %s: 'uid', 'system', 'shell' and 'home' are ignored on Windows", user.Name)
+	}
+	return nil
+}
+
+// setLocalUser converges description, expiry and password on an existing
+// account. The script skips Set-LocalUser when none is declared.
+func setLocalUser(name string, user types.User, initConfig *types.InitConfig) error {
+	cmd := localAccountsCommand(localUserSetScript, localUserVars(name, user), true)
+	cmd.Stdin = windowsPasswordStdin(user)
+	return system.RunCommand(cmd, initConfig.Variables.Flags.Debug)
+}
+
+func localUserVars(name string, user types.User) map[string]string {
+	vars := map[string]string{localAccountNameEnv: name}
+	if user.Comment != "" {
+		vars[localAccountCommentEnv] = user.Comment
+	}
+	if user.Expire != "" {
+		vars[localAccountExpireEnv] = user.Expire
+	}
+	if user.Password != "" {
+		vars[localAccountPasswordEnv] = "1"
+	}
+	return vars
+}
+
+func windowsPasswordStdin(user types.User) string {
+	if user.Password == "" {
+		return ""
+	}
+	return user.Password + "\n"
+}
+
+func windowsGroupMembership(name string, groups []string, script string, initConfig *types.InitConfig) error {
+	for _, group := range groups {
+		vars := map[string]string{localAccountNameEnv: name, localAccountGroupEnv: group}
+		if err := runLocalAccounts(script, vars, initConfig); err != nil {
+			return fmt.Errorf("error updating membership of user %s in group %s: %v", name, group, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Argv tests for the Windows local-accounts backend. Like the Open Directory
+// tests they run on any GOOS: userGOOS is pointed at "windows" and the
+// recorder captures the PowerShell invocations instead of running them.
+
+package processors
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/exectest"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// scripts lists the script each recorded powershell call ran, in order.
+func scripts(t *testing.T, rec *exectest.Recorder) []string {
+	t.Helper()
+	var out []string
+	for _, c := range rec.Calls {
+		if c.Exec != "powershell" || len(c.Args) != 4 || c.Args[2] != "-Command" {
+			t.Fatalf("unexpected command %v", c.Argv())
+		}
+		out = append(out, c.Args[3])
+	}
+	return out
+}
+
+func assertScripts(t *testing.T, rec *exectest.Recorder, want ...string) {
+	t.Helper()
+	if got := scripts(t, rec); !reflect.DeepEqual(got, want) {
+		t.Fatalf("scripts:\ngot:  %q\nwant: %q", got, want)
+	}
+	for _, c := range rec.Calls {
+		if isProbeCall(c) == c.Elevated {
+			t.Errorf("%q: elevated=%v, want probes unelevated and mutations elevated", c.Args[3], c.Elevated)
+		}
+	}
+}
+
+func withCredential(t *testing.T, name, value string) {
+	t.Helper()
+	types.RegisterCredentials([]types.CredentialSpec{{Name: name}})
+	types.SetCredentialValue(name, value)
+	t.Cleanup(func() { types.RegisterCredentials(nil) })
+}
+
+func TestCreateUserWindows_NewAccountWithCredentialPassword(t *testing.T) {
+	rec := platform(t, "windows", false, false, "")
+	withCredential(t, "build_password", "s3cret'; Remove-Item C:\\ #")
+
+	users := []types.User{{
+		Name:               "builder",
+		Action:             types.UserActionCreate,
+		Comment:            "CI build account",
+		PasswordCredential: "build_password",
+		Groups:             []string{"Administrators"},
+	}}
+	if err := processUsers(users, nil, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
+		t.Fatalf("processUsers: %v", err)
+	}
+
+	assertScripts(t, rec, localUserProbeScript, localUserCreateScript, localGroupAddMemberScript)
+	create := rec.Calls[1]
+	if create.Stdin != "s3cret'; Remove-Item C:\\ #\n" {
+		t.Errorf("password should reach New-LocalUser on stdin, got %q", create.Stdin)
+	}
+	wantVars := map[string]string{
+		localAccountNameEnv:     "builder",
+		localAccountCommentEnv:  "CI build account",
+		localAccountPasswordEnv: "1",
+	}
+	if !reflect.DeepEqual(create.Vars, wantVars) {
+		t.Errorf("create vars = %v, want %v", create.Vars, wantVars)
+	}
+	for _, c := range rec.Calls {
+		if strings.Contains(strings.Join(c.Argv(), " "), "s3cret") {
+			t.Errorf("password leaked into argv: %v", c.Argv())
+		}
+		for _, v := range c.Vars {
+			if strings.Contains(v, "s3cret") {
+				t.Errorf("password leaked into the environment of %q", c.Args[3])
+			}
+		}
+	}
+	if member := rec.Calls[2].Vars; member[localAccountGroupEnv] != "Administrators" || member[localAccountNameEnv] != "builder" {
+		t.Errorf("group membership vars = %v", member)
+	}
+}
+
+// A second run must converge, not fail on New-LocalUser's "already exists".
+func TestCreateUserWindows_ExistingAccountConverges(t *testing.T) {
+	rec := platform(t, "windows", true, false, "")
+
+	user := types.User{Name: "builder", Action: types.UserActionCreate, Expire: "2027-01-31"}
+	if err := createUser(user, newTestInitConfig()); err != nil {
+		t.Fatalf("createUser: %v", err)
+	}
+	assertScripts(t, rec, localUserProbeScript, localUserSetScript)
+	if got := rec.Calls[1].Vars[localAccountExpireEnv]; got != "2027-01-31" {
+		t.Errorf("expire = %q", got)
+	}
+	if rec.Calls[1].Stdin != "" {
+		t.Error("no password was declared, but stdin was fed")
+	}
+}
+
+func TestModifyUserWindows_OrderAndLock(t *testing.T) {
+	rec := platform(t, "windows", true, false, "")
+
+	user := types.User{
+		Name:         "builder",
+		Action:       types.UserActionModify,
+		Lock:         true,
+		AddGroups:    []string{"Users"},
+		RemoveGroups: []string{"Administrators"},
+		NewName:      "ci",
+	}
+	if err := modifyUser(user, newTestInitConfig()); err != nil {
+		t.Fatalf("modifyUser: %v", err)
+	}
+	assertScripts(t, rec,
+		localUserSetScript,
+		localUserDisableScript,
+		localGroupAddMemberScript,
+		localGroupRemoveMemberScript,
+		localUserRenameScript,
+	)
+	if got := rec.Calls[4].Vars[localAccountNewNameEnv]; got != "ci" {
+		t.Errorf("rename target = %q", got)
+	}
+}
+
+func TestRemoveUserWindows(t *testing.T) {
+	rec := platform(t, "windows", true, false, "")
+
+	if err := removeUser(types.User{Name: "builder", RemoveHome: true}, newTestInitConfig()); err != nil {
+		t.Fatalf("removeUser: %v", err)
+	}
+	assertScripts(t, rec, localUserProbeScript, localUserRemoveScript)
+	if rec.Calls[1].Vars[localAccountRemoveHomeEnv] != "1" {
+		t.Errorf("remove_home was not passed: %v", rec.Calls[1].Vars)
+	}
+
+	rec = platform(t, "windows", false, false, "")
+	if err := removeUser(types.User{Name: "ghost"}, newTestInitConfig()); err != nil {
+		t.Fatalf("removeUser of an absent account: %v", err)
+	}
+	assertScripts(t, rec, localUserProbeScript)
+}
+
+func TestCreateUserWindows_RefusesCryptHash(t *testing.T) {
+	rec := platform(t, "windows", false, false, "")
+
+	if err := createUser(types.User{Name: "builder", Password: testCryptHash}, newTestInitConfig()); err == nil {
+		t.Fatal("a crypt(3) hash was accepted as a Windows password")
+	}
+	if len(rec.Calls) != 0 {
+		t.Errorf("commands ran for a refused account: %v", argvs(rec))
+	}
+}
+
+func TestGroupsWindows(t *testing.T) {
+	rec := platform(t, "windows", false, false, "")
+	if err := createGroup(types.Group{Name: "builders"}, newTestInitConfig()); err != nil {
+		t.Fatalf("createGroup: %v", err)
+	}
+	assertScripts(t, rec, localGroupProbeScript, localGroupCreateScript)
+
+	rec = platform(t, "windows", true, false, "")
+	if err := createGroup(types.Group{Name: "builders"}, newTestInitConfig()); err != nil {
+		t.Fatalf("createGroup of an existing group: %v", err)
+	}
+	assertScripts(t, rec, localGroupProbeScript)
+
+	rec = platform(t, "windows", true, false, "")
+	if err := modifyGroup(types.Group{Name: "builders", NewName: "ci"}, newTestInitConfig()); err != nil {
+		t.Fatalf("modifyGroup: %v", err)
+	}
+	if err := removeGroup(types.Group{Name: "ci"}, newTestInitConfig()); err != nil {
+		t.Fatalf("removeGroup: %v", err)
+	}
+	assertScripts(t, rec, localGroupRenameScript, localGroupProbeScript, localGroupRemoveScript)
+}
+
+func TestResolvePasswordCredential(t *testing.T) {
+	withCredential(t, "build_password", "hunter2")
+
+	user := types.User{Name: "builder", PasswordCredential: "build_password"}
+	if err := resolvePasswordCredential(&user); err != nil || user.Password != "hunter2" {
+		t.Fatalf("resolvePasswordCredential = %v, password %q", err, user.Password)
+	}
+
+	for name, user := range map[string]types.User{
+		"undeclared": {Name: "a", PasswordCredential: "nope"},
+		"both set":   {Name: "a", PasswordCredential: "build_password", Password: "x"},
+	} {
+		if err := resolvePasswordCredential(&user); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
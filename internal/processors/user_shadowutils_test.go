@@ -511,7 +511,7 @@ func TestProcessUsers_UnsupportedAction(t *testing.T) {
 		{Name: "baduser", Action: "destroy"},
 	}
 
-	err := processUsers(users, newTestInitConfig(), newProgress(types.BlueprintTypeUsers))
+	err := processUsers(users, nil, newTestInitConfig(), newProgress(types.BlueprintTypeUsers))
 	if err == nil {
 		t.Error("Expected error for unsupported user action 'destroy'")
 	}
@@ -525,7 +525,7 @@ func TestProcessGroups_EmptySlice(t *testing.T) {
 }
 
 func TestProcessUsers_EmptySlice(t *testing.T) {
-	err := processUsers([]types.User{}, newTestInitConfig(), newProgress(types.BlueprintTypeUsers))
+	err := processUsers([]types.User{}, nil, newTestInitConfig(), newProgress(types.BlueprintTypeUsers))
 	if err != nil {
 		t.Errorf("Expected no error for empty users, got: %v", err)
 	}
@@ -654,7 +654,7 @@ func TestProcessUsers_DryRunSkipsExecution(t *testing.T) {
 		{Name: "user3", Action: "remove"},
 	}
 
-	err := processUsers(users, newTestInitConfig(), newProgress(types.BlueprintTypeUsers))
+	err := processUsers(users, nil, newTestInitConfig(), newProgress(types.BlueprintTypeUsers))
 	if err != nil {
 		t.Errorf("processUsers should succeed in dry-run mode, got: %v", err)
 	}
@@ -802,7 +802,7 @@ func TestLinuxPasswordNeverReachesArgv(t *testing.T) {
 			rec := platform(t, "linux", false, false, "")
 
 			users := []types.User{{Name: "alice", Password: secret, Action: action}}
-			if err := processUsers(users, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
+			if err := processUsers(users, nil, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
 				t.Fatalf("processUsers: %v", err)
 			}
 
@@ -831,7 +831,7 @@ func TestLinuxHashedPasswordUsesEncryptedFlag(t *testing.T) {
 	rec := platform(t, "linux", false, false, "")
 
 	users := []types.User{{Name: "alice", Password: testCryptHash, Action: "create"}}
-	if err := processUsers(users, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
+	if err := processUsers(users, nil, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
 		t.Fatalf("processUsers: %v", err)
 	}
 
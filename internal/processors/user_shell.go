@@ -0,0 +1,200 @@
+// The login-shell action: "make zsh my login shell" without the root-only
+// user-modify path. It installs the shell when it is missing, lists it in
+// /etc/shells, and then runs chsh (Linux) or dscl (macOS) - only when the
+// account's current shell differs.
+
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/status"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// shellsFile is the list of permitted login shells chsh checks against. A
+// variable so tests can point it at a temporary file.
+var shellsFile = "/etc/shells"
+
+// shellLookPath resolves a bare shell name on PATH. Indirected for the same
+// reason as commandExists: the test runner has no zsh to find.
+var shellLookPath = exec.LookPath
+
+// setLoginShell makes user.Shell the account's login shell. It reports whether
+// anything changed, so an account already on the declared shell is reported as
+// present rather than applied, plus the shell it had before for the journal.
+func setLoginShell(user types.User, osInfo *types.OSInfo, initConfig *types.InitConfig) (changed bool, previous string, err error) {
+	if user.Shell == "" {
+		return false, "", errors.New("the shell action needs a shell")
+	}
+	if userGOOS == "windows" {
+		log.Warnf("Setting a login shell is not supported on Windows")
+		return false, "", nil
+	}
+
+	path, err := ensureShellInstalled(user, osInfo, initConfig)
+	if err != nil {
+		return false, "", err
+	}
+
+	if err := ensureShellListed(path); err != nil {
+		return false, "", err
+	}
+
+	previous = currentLoginShell(user.Name, initConfig)
+	if status.SameShell(previous, path) {
+		log.Infof("User %s already has login shell %s", user.Name, path)
+		return false, previous, nil
+	}
+	if previous != "" {
+		log.Infof("Changing login shell for %s: %s -> %s", user.Name, previous, path)
+	}
+
+	var cmd types.Command
+	switch userGOOS {
+	case "darwin":
+		cmd = types.Command{
+			Exec:     "dscl",
+			Args:     []string{".", "-create", "/Users/" + user.Name, "UserShell", path},
+			Elevated: true,
+		}
+	default:
+		// Elevated so chsh does not stop to ask for the account's own password,
+		// which it does for an unprivileged caller even changing their own shell.
+		cmd = types.Command{
+			Exec:     "chsh",
+			Args:     []string{"--shell", path, user.Name},
+			Elevated: true,
+		}
+	}
+	if err := system.RunCommand(cmd, initConfig.Variables.Flags.Debug); err != nil {
+		return false, previous, fmt.Errorf("error changing login shell: %v", err)
+	}
+	return true, previous, nil
+}
+
+// ensureShellInstalled resolves the declared shell to an absolute path,
+// installing its package first when the shell cannot be found. A bare name is
+// looked up on PATH; shell_package names the package when it differs from the
+// shell's own name.
+func ensureShellInstalled(user types.User, osInfo *types.OSInfo, initConfig *types.InitConfig) (string, error) {
+	if path, ok := resolveShellPath(user.Shell); ok {
+		return path, nil
+	}
+
+	pkg := user.ShellPackage
+	if pkg == "" {
+		pkg = filepath.Base(user.Shell)
+	}
+	if err := installShellPackage(pkg, osInfo, initConfig); err != nil {
+		return "", err
+	}
+
+	path, ok := resolveShellPath(user.Shell)
+	if !ok {
+		return "", fmt.Errorf("shell %s is still not available after installing package %s", user.Shell, pkg)
+	}
+	return path, nil
+}
+
+// resolveShellPath reports the absolute path of an installed shell.
+func resolveShellPath(shell string) (string, bool) {
+	if filepath.IsAbs(shell) {
+		info, err := os.Stat(shell)
+		if err != nil || info.IsDir() {
+			return "", false
+		}
+		return shell, true
+	}
+	path, err := shellLookPath(shell)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// installShellPackage installs pkg with the machine's default package
+// manager - the same provider an unpinned packages entry would use.
+func installShellPackage(pkg string, osInfo *types.OSInfo, initConfig *types.InitConfig) error {
+	if strings.HasPrefix(pkg, "-") {
+		return fmt.Errorf("shell package %q may not begin with '-'", pkg)
+	}
+	if err := system.InitProviders(); err != nil {
+		return fmt.Errorf("error initializing providers: %w", err)
+	}
+	provider, ok := defaultProviderFor(osInfo, system.GetAvailableProviders())
+	if !ok {
+		return fmt.Errorf("shell package %s is not installed and no package manager is available to install it", pkg)
+	}
+
+	log.Infof("Installing package %s via %s to provide the login shell", pkg, provider.Name)
+	args := append(strings.Fields(provider.Commands.Install), pkg)
+	cmd := types.Command{
+		Exec:      provider.BinPath,
+		Args:      args,
+		Elevated:  provider.Elevated,
+		Escalates: provider.Escalates,
+		Variables: provider.Environment,
+	}
+	if err := system.RunCommand(cmd, initConfig.Variables.Flags.Debug); err != nil {
+		return fmt.Errorf("error installing shell package %s: %w", pkg, err)
+	}
+	return nil
+}
+
+// ensureShellListed appends path to /etc/shells when it is not there yet.
+// chsh refuses any shell missing from that list, and on macOS a shell outside
+// it is refused at login by some services even though dscl accepts it.
+func ensureShellListed(path string) error {
+	data, err := os.ReadFile(shellsFile) // #nosec G304 -- fixed system path, overridden only by tests
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading %s: %v", shellsFile, err)
+	}
+	if shellListed(string(data), path) {
+		return nil
+	}
+	log.Infof("Adding %s to %s", path, shellsFile)
+	if err := system.AppendToFile(shellsFile, path, true); err != nil {
+		return fmt.Errorf("error adding %s to %s: %v", path, shellsFile, err)
+	}
+	return nil
+}
+
+// shellListed reports whether an /etc/shells body lists path, ignoring
+// comments and surrounding whitespace.
+func shellListed(body, path string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == path {
+			return true
+		}
+	}
+	return false
+}
+
+// currentLoginShell reads the account's login shell, or "" when it cannot be
+// read.
+func currentLoginShell(name string, initConfig *types.InitConfig) string {
+	var cmd types.Command
+	switch userGOOS {
+	case "darwin":
+		cmd = types.Command{Exec: "dscl", Args: []string{".", "-read", "/Users/" + name, "UserShell"}}
+	default:
+		cmd = types.Command{Exec: "getent", Args: []string{"passwd", name}}
+	}
+	out, err := system.RunCommandOutput(cmd, initConfig.Variables.Flags.Debug)
+	if err != nil {
+		return ""
+	}
+	return status.ParseLoginShell(userGOOS, out)
+}
@@ -0,0 +1,148 @@
+// Tests for the login-shell action: /etc/shells handling, the chsh and dscl
+// argv, and that an account already on the declared shell is left alone.
+
+package processors
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// shellFixture points the action at a temporary /etc/shells and a PATH that
+// resolves only the named shells.
+func shellFixture(t *testing.T, listed string, onPath map[string]string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "shells")
+	if err := os.WriteFile(file, []byte(listed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prevFile, prevLook := shellsFile, shellLookPath
+	shellsFile = file
+	shellLookPath = func(name string) (string, error) {
+		if path, ok := onPath[name]; ok {
+			return path, nil
+		}
+		return "", errors.New("not found")
+	}
+	t.Cleanup(func() {
+		shellsFile, shellLookPath = prevFile, prevLook
+	})
+	return file
+}
+
+func TestSetLoginShell_LinuxRunsChshForADifferentShell(t *testing.T) {
+	shellFixture(t, "/bin/sh\n/bin/bash\n/usr/bin/zsh\n", map[string]string{"zsh": "/usr/bin/zsh"})
+	rec := platform(t, "linux", true, false, "alice:x:1000:1000::/home/alice:/bin/bash\n")
+
+	changed, previous, err := setLoginShell(types.User{Name: "alice", Action: types.UserActionShell, Shell: "zsh"}, nil, newTestInitConfig())
+	if err != nil {
+		t.Fatalf("setLoginShell: %v", err)
+	}
+	if !changed || previous != "/bin/bash" {
+		t.Fatalf("changed=%v previous=%q, want true and /bin/bash", changed, previous)
+	}
+	chsh := rec.Find("chsh")
+	if len(chsh) != 1 {
+		t.Fatalf("expected one chsh call, got %v", argvs(rec))
+	}
+	if want := []string{"chsh", "--shell", "/usr/bin/zsh", "alice"}; !reflect.DeepEqual(chsh[0].Argv(), want) {
+		t.Errorf("chsh argv = %v, want %v", chsh[0].Argv(), want)
+	}
+	if !chsh[0].Elevated {
+		t.Error("chsh should be elevated")
+	}
+}
+
+func TestSetLoginShell_AlreadyCurrentIsLeftAlone(t *testing.T) {
+	shellFixture(t, "/usr/bin/zsh\n", map[string]string{"zsh": "/usr/bin/zsh"})
+	rec := platform(t, "linux", true, false, "alice:x:1000:1000::/home/alice:/usr/bin/zsh\n")
+
+	changed, _, err := setLoginShell(types.User{Name: "alice", Action: types.UserActionShell, Shell: "zsh"}, nil, newTestInitConfig())
+	if err != nil {
+		t.Fatalf("setLoginShell: %v", err)
+	}
+	if changed {
+		t.Fatal("an account already on the declared shell reported a change")
+	}
+	if len(rec.Find("chsh")) != 0 {
+		t.Fatalf("chsh ran for an unchanged shell: %v", argvs(rec))
+	}
+}
+
+// On a merged-/usr system passwd says /bin/zsh while PATH finds
+// /usr/bin/zsh: the same shell, as status already reports it, so chsh is not
+// run again on every apply.
+func TestSetLoginShell_MergedUsrIsLeftAlone(t *testing.T) {
+	root := t.TempDir()
+	usrBin := filepath.Join(root, "usr", "bin")
+	if err := os.MkdirAll(usrBin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(usrBin, "zsh"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(usrBin, filepath.Join(root, "bin")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	binZsh, usrZsh := filepath.Join(root, "bin", "zsh"), filepath.Join(usrBin, "zsh")
+	shellFixture(t, binZsh+"\n"+usrZsh+"\n", map[string]string{"zsh": usrZsh})
+	rec := platform(t, "linux", true, false, "alice:x:1000:1000::/home/alice:"+binZsh+"\n")
+
+	changed, _, err := setLoginShell(types.User{Name: "alice", Action: types.UserActionShell, Shell: "zsh"}, nil, newTestInitConfig())
+	if err != nil {
+		t.Fatalf("setLoginShell: %v", err)
+	}
+	if changed || len(rec.Find("chsh")) != 0 {
+		t.Fatalf("changed=%v, chsh calls %v; want the shell left alone", changed, argvs(rec))
+	}
+}
+
+// chsh refuses a shell /etc/shells does not list, so the action adds it first.
+func TestSetLoginShell_AddsUnlistedShell(t *testing.T) {
+	shellFixture(t, "# comment\n/bin/bash\n", map[string]string{"zsh": "/usr/bin/zsh"})
+	rec := platform(t, "linux", true, false, "alice:x:1000:1000::/home/alice:/bin/bash\n")
+
+	if _, _, err := setLoginShell(types.User{Name: "alice", Action: types.UserActionShell, Shell: "zsh"}, nil, newTestInitConfig()); err != nil {
+		t.Fatalf("setLoginShell: %v", err)
+	}
+	mv := rec.Find("mv")
+	if len(mv) != 1 || mv[0].Args[len(mv[0].Args)-1] != shellsFile {
+		t.Fatalf("expected an elevated move into %s, got %v", shellsFile, argvs(rec))
+	}
+	if !mv[0].Elevated {
+		t.Error("the /etc/shells write should be elevated")
+	}
+}
+
+func TestSetLoginShell_DarwinUsesDscl(t *testing.T) {
+	shellFixture(t, "/bin/zsh\n", map[string]string{"zsh": "/bin/zsh"})
+	rec := platform(t, "darwin", true, false, "UserShell: /bin/bash\n")
+
+	if _, _, err := setLoginShell(types.User{Name: "alice", Action: types.UserActionShell, Shell: "zsh"}, nil, newTestInitConfig()); err != nil {
+		t.Fatalf("setLoginShell: %v", err)
+	}
+	last, _ := rec.Last()
+	if want := []string{"dscl", ".", "-create", "/Users/alice", "UserShell", "/bin/zsh"}; !reflect.DeepEqual(last.Argv(), want) {
+		t.Errorf("last command = %v, want %v", last.Argv(), want)
+	}
+}
+
+func TestShellListed(t *testing.T) {
+	body := "# /etc/shells\n/bin/sh\n  /usr/bin/zsh  \n#/usr/bin/fish\n"
+	for path, want := range map[string]bool{
+		"/bin/sh":       true,
+		"/usr/bin/zsh":  true,
+		"/usr/bin/fish": false,
+		"/bin/bash":     false,
+	} {
+		if got := shellListed(body, path); got != want {
+			t.Errorf("shellListed(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -38,6 +38,8 @@ func isProbe(cmd types.Command) bool {
 		return len(cmd.Args) >= 2 && cmd.Args[1] == "-read"
 	case "dseditgroup":
 		return len(cmd.Args) >= 2 && cmd.Args[1] == "checkmember"
+	case "powershell":
+		return len(cmd.Args) == 4 && (cmd.Args[3] == localUserProbeScript || cmd.Args[3] == localGroupProbeScript)
 	}
 	return false
 }
@@ -122,7 +124,7 @@ func TestProcessUsers_SecondRunDoesNotAbort(t *testing.T) {
 	rec := platform(t, "linux", true, false, "")
 
 	users := []types.User{{Name: "alice", Shell: "/bin/zsh", Action: "create"}}
-	if err := processUsers(users, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
+	if err := processUsers(users, nil, newTestInitConfig(), newProgress(types.BlueprintTypeUsers)); err != nil {
 		t.Fatalf("second run aborted: %v", err)
 	}
 	if len(rec.Calls) == 0 || isProbeCall(rec.Calls[len(rec.Calls)-1]) {
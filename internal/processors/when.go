@@ -0,0 +1,33 @@
+package processors
+
+import (
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// whenSkippedResources enumerates the entries a file's `when:` conditions
+// left out, as skipped resources whose detail is the condition - the reason
+// the plan and the summary give for not applying them.
+func whenSkippedResources(processor string, file types.ResolvedFile, defaults providerDefaults, runOnceDir string) []types.Resource {
+	var resources []types.Resource
+	for _, entry := range file.Skipped {
+		declared := types.ResolvedFile{Path: file.Path, Processor: processor, Format: types.FormatJSON, Resolved: entry.Document}
+		for _, resource := range enumerateResources(processor, declared, defaults, runOnceDir) {
+			resource.Status = types.StatusSkipped
+			resource.Detail = "when: " + entry.When
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// reportWhenSkipped tells the run's reporters about the entries a blueprint's
+// `when:` conditions left out, one skipped outcome per resource. Skips are
+// not journalled: nothing was done to the machine.
+func reportWhenSkipped(processor string, file types.ResolvedFile, defaults providerDefaults, runOnceDir string) {
+	for _, resource := range whenSkippedResources(processor, file, defaults, runOnceDir) {
+		log.Infof("Skipping %s %s: %s", processor, resource.Name, resource.Detail)
+		reporting.Emit(reporting.ResourceDone{Resource: resource})
+	}
+}
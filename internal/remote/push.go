@@ -0,0 +1,163 @@
+package remote
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/state"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// pushDir is where a push stages the binary and the tree on the host. Each
+// push replaces it, so the host never runs a stale tree.
+const pushDir = "$HOME/.cache/rwr/push"
+
+// remoteJournal is the host's run journal, at rwr's default config
+// directory.
+const remoteJournal = "$HOME/.config/rwr/state/journal.jsonl"
+
+// Upload copies binary, as rwr, and the tree under root to the host's push
+// directory. root may be empty when the init file is a URL the host fetches
+// itself. .git directories stay behind.
+func Upload(ctx context.Context, t Transport, host types.InventoryHost, binary, root string) error {
+	reader, writer := io.Pipe()
+	defer func() { _ = reader.Close() }()
+	go func() { writer.CloseWithError(writeArchive(writer, binary, root)) }()
+
+	var stderr bytes.Buffer
+	command := `dir="` + pushDir + `" && rm -rf "$dir" && mkdir -p "$dir" && tar -xzf - -C "$dir"`
+	if err := t.Run(ctx, host, command, reader, io.Discard, &stderr); err != nil {
+		return fmt.Errorf("%s: copying rwr and the tree: %w%s", host.Label(), err, stderrSuffix(stderr.String()))
+	}
+	return nil
+}
+
+// writeArchive writes the push as a gzipped tar: the binary at rwr, the
+// tree under tree/.
+func writeArchive(w io.Writer, binary, root string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := addFile(tw, binary, "rwr", 0o755); err != nil {
+		return err
+	}
+	if root != "" {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(filepath.Join("tree", rel))
+
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			return copyInto(tw, path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, path, name string, mode int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	return copyInto(tw, path)
+}
+
+func copyInto(w io.Writer, path string) error {
+	f, err := os.Open(path) // #nosec G304 -- the operator's own binary and tree
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Command is the shell command that runs the pushed rwr with args, from the
+// pushed tree's root - relative paths in args resolve inside the tree.
+func Command(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return `cd "` + pushDir + `/tree" 2>/dev/null || cd "` + pushDir + `"; "` + pushDir + `/rwr" ` + strings.Join(quoted, " ")
+}
+
+// Run runs the pushed rwr with args, relaying its output.
+func Run(ctx context.Context, t Transport, host types.InventoryHost, args []string, relay *Relay) error {
+	err := t.Run(ctx, host, Command(args), nil, relay.Stdout(), relay.Stderr())
+	relay.Flush()
+	return err
+}
+
+// JournalPath is where a host's journal copy lives under a config
+// directory: hosts/<name>/state/journal.jsonl.
+func JournalPath(configDir string, host types.InventoryHost) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, host.Label())
+	return state.JournalPath(filepath.Join(configDir, "hosts", name))
+}
+
+// PullJournal copies the host's run journal to path. A host with no journal
+// yet (every run so far a dry run) leaves path as it was.
+func PullJournal(ctx context.Context, t Transport, host types.InventoryHost, path string) error {
+	var stdout, stderr bytes.Buffer
+	command := `f="` + remoteJournal + `"; if [ -f "$f" ]; then cat "$f"; fi`
+	if err := t.Run(ctx, host, command, nil, &stdout, &stderr); err != nil {
+		return fmt.Errorf("%s: reading the journal: %w%s", host.Label(), err, stderrSuffix(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, stdout.Bytes(), 0o600)
+}
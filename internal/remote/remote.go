@@ -0,0 +1,108 @@
+// Package remote applies a blueprint tree to another machine over SSH: it
+// copies this rwr binary and the tree to the host, runs rwr there - with the
+// host's own OS detection - and relays the run's reporting events and log
+// back into this process's reporter, so the dashboard and the streaming log
+// show a remote run the way they show a local one.
+//
+// Everything goes through one Transport call, "run this shell command with
+// this stdin". Files travel as a tar stream on stdin, so the remote needs a
+// POSIX shell and tar - nothing else, and no sftp subsystem.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// Transport runs a shell command on a host.
+type Transport interface {
+	Run(ctx context.Context, host types.InventoryHost, command string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// OpenSSH is the Transport over the system ssh client, so ~/.ssh/config,
+// the agent, known_hosts and jump hosts all apply as they do for the
+// operator's own ssh sessions.
+type OpenSSH struct {
+	// Binary is the ssh client to run; empty is "ssh" from PATH.
+	Binary string
+}
+
+// Run implements Transport. BatchMode keeps ssh from prompting: a push has
+// no terminal to prompt on, and a password prompt would hang it.
+func (o OpenSSH) Run(ctx context.Context, host types.InventoryHost, command string, stdin io.Reader, stdout, stderr io.Writer) error {
+	binary := o.Binary
+	if binary == "" {
+		binary = "ssh"
+	}
+	cmd := exec.CommandContext(ctx, binary, sshArgs(host, command)...) // #nosec G204 -- the operator's ssh client and host
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+func sshArgs(host types.InventoryHost, command string) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if host.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(host.Port))
+	}
+	if host.Identity != "" {
+		args = append(args, "-i", host.Identity)
+	}
+	return append(args, "--", host.Address, command)
+}
+
+// Platform reports the host's GOOS and GOARCH, from uname.
+func Platform(ctx context.Context, t Transport, host types.InventoryHost) (goos, goarch string, err error) {
+	var stdout, stderr bytes.Buffer
+	if err := t.Run(ctx, host, "uname -sm", nil, &stdout, &stderr); err != nil {
+		return "", "", fmt.Errorf("%s: cannot detect the platform: %w%s", host.Label(), err, stderrSuffix(stderr.String()))
+	}
+	fields := strings.Fields(stdout.String())
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("%s: unexpected uname output %q", host.Label(), stdout.String())
+	}
+	goos, goarch = strings.ToLower(fields[0]), unameArch[fields[1]]
+	if goos != "linux" && goos != "darwin" {
+		return "", "", fmt.Errorf("%s: pushing to %s is not supported; the host needs a POSIX shell", host.Label(), fields[0])
+	}
+	if goarch == "" {
+		return "", "", fmt.Errorf("%s: unknown architecture %q", host.Label(), fields[1])
+	}
+	return goos, goarch, nil
+}
+
+// unameArch maps `uname -m` to GOARCH.
+var unameArch = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv7l":  "arm",
+	"armv6l":  "arm",
+	"i386":    "386",
+	"i686":    "386",
+	"riscv64": "riscv64",
+}
+
+func stderrSuffix(stderr string) string {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return ": " + stderr
+	}
+	return ""
+}
+
+// shellQuote quotes s as one POSIX shell word.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,@%+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
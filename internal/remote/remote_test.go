@@ -0,0 +1,218 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// fakeSSH is the local sshd stand-in: an ssh client that runs the command
+// with sh on this machine, under a scratch HOME playing the host's.
+const fakeSSH = `#!/bin/sh
+while [ "$1" != "--" ]; do shift; done
+shift 2
+HOME="$RWR_TEST_REMOTE_HOME" exec sh -c "$1"
+`
+
+func standIn(t *testing.T) (OpenSSH, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the ssh stand-in is a shell script")
+	}
+	dir := t.TempDir()
+	ssh := filepath.Join(dir, "ssh")
+	if err := os.WriteFile(ssh, []byte(fakeSSH), 0o755); err != nil { // #nosec G306 -- test executable
+		t.Fatal(err)
+	}
+	home := filepath.Join(dir, "home")
+	if err := os.MkdirAll(home, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RWR_TEST_REMOTE_HOME", home)
+	return OpenSSH{Binary: ssh}, home
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	events []reporting.Event
+}
+
+func (r *recorder) Emit(event reporting.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestSSHArgs(t *testing.T) {
+	got := strings.Join(sshArgs(types.InventoryHost{Address: "me@box", Port: 2222, Identity: "/k"}, "uname"), " ")
+	want := "-o BatchMode=yes -p 2222 -i /k -- me@box uname"
+	if got != want {
+		t.Errorf("sshArgs = %q, want %q", got, want)
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	ssh, _ := standIn(t)
+	goos, goarch, err := Platform(context.Background(), ssh, types.InventoryHost{Address: "box"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if goos != runtime.GOOS || goarch != runtime.GOARCH {
+		t.Errorf("Platform = %s/%s, want %s/%s", goos, goarch, runtime.GOOS, runtime.GOARCH)
+	}
+}
+
+// TestPushRoundTrip pushes a tree and a stand-in rwr, runs it, relays its
+// events into the local reporter, and pulls its journal back.
+func TestPushRoundTrip(t *testing.T) {
+	ssh, _ := standIn(t)
+	ctx := context.Background()
+	host := types.InventoryHost{Name: "box", Address: "me@box"}
+
+	// What the far end's EventWriter would print.
+	var events bytes.Buffer
+	writer := NewEventWriter(&events)
+	writer.Plan(&types.Plan{Order: []string{"packages"}})
+	writer.Emit(reporting.ProcStarted{Processor: "packages", Files: 1})
+	writer.Emit(reporting.LaneUpdate{Processor: "packages", Provider: "apt", Done: 1, Total: 1, Status: types.StatusOK})
+	writer.Emit(reporting.RunFinished{Errs: []types.StepError{{Processor: "packages", Err: errors.New("boom")}}})
+
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"init.yaml":       "init:\n  format: yaml\n",
+		"events.out":      events.String(),
+		".git/HEAD":       "ref: refs/heads/main\n",
+		"nested/pkg.yaml": "packages: []\n",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The stand-in rwr runs from the tree: it prints the events, a log
+	// record and its arguments, and records a journal.
+	binary := filepath.Join(t.TempDir(), "rwr")
+	script := `#!/bin/sh
+test -f init.yaml && test ! -d .git || exit 3
+cat events.out
+echo "args: $*"
+echo '{"level":"warn","msg":"careful","file":"x"}' >&2
+mkdir -p "$HOME/.config/rwr/state"
+echo '{"id":"1"}' > "$HOME/.config/rwr/state/journal.jsonl"
+`
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil { // #nosec G306 -- test executable
+		t.Fatal(err)
+	}
+
+	if err := Upload(ctx, ssh, host, binary, root); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recorder{}
+	defer reporting.Set(rec)()
+	var plan *types.Plan
+	relay := &Relay{Host: host.Label(), OnPlan: func(p *types.Plan) { plan = p }}
+	if err := Run(ctx, ssh, host, []string{"all", "--init-file", "init.yaml"}, relay); err != nil {
+		t.Fatal(err)
+	}
+
+	if plan == nil || len(plan.Order) != 1 || plan.Order[0] != "packages" {
+		t.Errorf("plan = %+v, want order [packages]", plan)
+	}
+	if len(rec.events) != 3 {
+		t.Fatalf("relayed %d events, want 3: %#v", len(rec.events), rec.events)
+	}
+	if lane, ok := rec.events[1].(reporting.LaneUpdate); !ok || lane.Provider != "apt" || lane.Status != types.StatusOK {
+		t.Errorf("events[1] = %#v, want the apt lane update", rec.events[1])
+	}
+	finished, ok := rec.events[2].(reporting.RunFinished)
+	if !ok || len(finished.Errs) != 1 || finished.Errs[0].Err == nil || finished.Errs[0].Err.Error() != "boom" {
+		t.Errorf("events[2] = %#v, want RunFinished with packages: boom", rec.events[2])
+	}
+
+	path := JournalPath(t.TempDir(), host)
+	if err := PullJournal(ctx, ssh, host, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\"id\":\"1\"}\n" {
+		t.Errorf("pulled journal = %q", data)
+	}
+	if !strings.Contains(filepath.ToSlash(path), "/hosts/box/state/") {
+		t.Errorf("journal copy at %s, want it under hosts/box", path)
+	}
+}
+
+// TestRelay_SplitWrites relays frames and output however the stream is
+// chunked.
+func TestRelay_SplitWrites(t *testing.T) {
+	var events bytes.Buffer
+	NewEventWriter(&events).Emit(reporting.ProcSkipped{Processor: "fonts", Reason: "no fonts"})
+	stream := "hello\n" + events.String()
+
+	rec := &recorder{}
+	defer reporting.Set(rec)()
+	relay := &Relay{Host: "box"}
+	out := relay.Stdout()
+	for i := 0; i < len(stream); i += 3 {
+		end := min(i+3, len(stream))
+		if _, err := out.Write([]byte(stream[i:end])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	relay.Flush()
+
+	if len(rec.events) != 1 {
+		t.Fatalf("relayed %d events, want 1", len(rec.events))
+	}
+	if skipped, ok := rec.events[0].(reporting.ProcSkipped); !ok || skipped.Reason != "no fonts" {
+		t.Errorf("event = %#v, want fonts skipped", rec.events[0])
+	}
+}
+
+// A retry crosses the wire with its attempt count and delay.
+func TestRelay_Retry(t *testing.T) {
+	var events bytes.Buffer
+	NewEventWriter(&events).Emit(reporting.ResourceRetry{
+		Resource: types.Resource{Processor: "scripts", Name: "fetch", Action: "run", Status: types.StatusFailed, Detail: "exit status 7"},
+		Attempt:  2, Attempts: 4, Delay: 10 * time.Second,
+	})
+
+	rec := &recorder{}
+	defer reporting.Set(rec)()
+	relay := &Relay{Host: "box"}
+	if _, err := relay.Stdout().Write(events.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.events) != 1 {
+		t.Fatalf("relayed %d events, want 1", len(rec.events))
+	}
+	retry, ok := rec.events[0].(reporting.ResourceRetry)
+	if !ok || retry.Resource.Name != "fetch" || retry.Attempt != 2 || retry.Attempts != 4 || retry.Delay != 10*time.Second {
+		t.Errorf("event = %#v, want the fetch retry", rec.events[0])
+	}
+}
+
+func TestCommand_QuotesArgs(t *testing.T) {
+	got := Command([]string{"all", "--profile", "it's"})
+	if !strings.HasSuffix(got, `/rwr" all --profile 'it'\''s'`) {
+		t.Errorf("Command = %q", got)
+	}
+}
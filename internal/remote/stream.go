@@ -0,0 +1,295 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// The remote rwr writes its events to stdout, one frame per line, each
+// starting with frameMarker; anything else on stdout is a command's own
+// output. Its log goes to stderr in charm log's JSON form. The relay turns
+// both back into local events and log records.
+const frameMarker = "\x1erwr "
+
+// frame is one event on the wire. The fields used depend on Type.
+type frame struct {
+	Type      string          `json:"type"`
+	Plan      *types.Plan     `json:"plan,omitempty"`
+	Processor string          `json:"processor,omitempty"`
+	Provider  string          `json:"provider,omitempty"`
+	Providers []string        `json:"providers,omitempty"`
+	Files     int             `json:"files,omitempty"`
+	Done      int             `json:"done,omitempty"`
+	Total     int             `json:"total,omitempty"`
+	Status    types.Status    `json:"status,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+	Err       string          `json:"err,omitempty"`
+	Dur       time.Duration   `json:"dur,omitempty"`
+	Resource  *types.Resource `json:"resource,omitempty"`
+	Errs      []frameError    `json:"errs,omitempty"`
+}
+
+type frameError struct {
+	Processor string `json:"processor"`
+	Err       string `json:"err"`
+}
+
+// EventWriter is the reporter the remote rwr runs under. Progress events
+// are framed onto its writer; prompts and terminal handoffs are not
+// relayable, and are answered on the host exactly as a headless run
+// answers them.
+type EventWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewEventWriter returns an EventWriter framing onto w.
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{w: w}
+}
+
+// Emit implements reporting.Reporter.
+func (e *EventWriter) Emit(event reporting.Event) {
+	f, ok := encodeEvent(event)
+	if !ok {
+		reporting.LogReporter{}.Emit(event)
+		return
+	}
+	e.write(f)
+}
+
+// Plan sends the run's plan, which the dashboard is built from. The init
+// config and file contents stay on the host; the display needs neither.
+func (e *EventWriter) Plan(plan *types.Plan) {
+	sent := *plan
+	sent.Init = nil
+	sent.Files = nil
+	e.write(frame{Type: "plan", Plan: &sent})
+}
+
+func (e *EventWriter) write(f frame) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		log.Debugf("encoding %s event: %v", f.Type, err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = fmt.Fprintf(e.w, "%s%s\n", frameMarker, data)
+}
+
+func encodeEvent(event reporting.Event) (frame, bool) {
+	switch e := event.(type) {
+	case reporting.ProcStarted:
+		return frame{Type: "proc_started", Processor: e.Processor, Files: e.Files, Providers: e.Providers}, true
+	case reporting.ProcFinished:
+		return frame{Type: "proc_finished", Processor: e.Processor, Err: errString(e.Err), Dur: e.Dur}, true
+	case reporting.ProcSkipped:
+		return frame{Type: "proc_skipped", Processor: e.Processor, Reason: e.Reason}, true
+	case reporting.LaneUpdate:
+		return frame{Type: "lane", Processor: e.Processor, Provider: e.Provider, Done: e.Done, Total: e.Total, Status: e.Status}, true
+	case reporting.ResourceDone:
+		resource := e.Resource
+		return frame{Type: "resource", Resource: &resource}, true
+	case reporting.ResourceRetry:
+		// An attempt is progress through a resource, so it rides the lane
+		// counters: Done is the attempt, Total the attempts, Dur the delay.
+		resource := e.Resource
+		return frame{Type: "retry", Resource: &resource, Done: e.Attempt, Total: e.Attempts, Dur: e.Delay}, true
+	case reporting.RunFinished:
+		f := frame{Type: "run_finished"}
+		for _, stepErr := range e.Errs {
+			f.Errs = append(f.Errs, frameError{Processor: stepErr.Processor, Err: errString(stepErr.Err)})
+		}
+		return f, true
+	}
+	return frame{}, false
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func errFrom(message string) error {
+	if message == "" {
+		return nil
+	}
+	return errors.New(message)
+}
+
+func (f frame) event() (reporting.Event, bool) {
+	switch f.Type {
+	case "proc_started":
+		return reporting.ProcStarted{Processor: f.Processor, Files: f.Files, Providers: f.Providers}, true
+	case "proc_finished":
+		return reporting.ProcFinished{Processor: f.Processor, Err: errFrom(f.Err), Dur: f.Dur}, true
+	case "proc_skipped":
+		return reporting.ProcSkipped{Processor: f.Processor, Reason: f.Reason}, true
+	case "lane":
+		return reporting.LaneUpdate{Processor: f.Processor, Provider: f.Provider, Done: f.Done, Total: f.Total, Status: f.Status}, true
+	case "resource":
+		if f.Resource == nil {
+			return nil, false
+		}
+		return reporting.ResourceDone{Resource: *f.Resource}, true
+	case "retry":
+		if f.Resource == nil {
+			return nil, false
+		}
+		return reporting.ResourceRetry{Resource: *f.Resource, Attempt: f.Done, Attempts: f.Total, Delay: f.Dur}, true
+	case "run_finished":
+		var errs []types.StepError
+		for _, e := range f.Errs {
+			errs = append(errs, types.StepError{Processor: e.Processor, Err: errFrom(e.Err)})
+		}
+		return reporting.RunFinished{Errs: errs}, true
+	}
+	return nil, false
+}
+
+// Relay turns a remote run's output back into local events and log records,
+// each record tagged with the host.
+type Relay struct {
+	Host string
+	// OnPlan receives the remote run's plan, sent before any event.
+	OnPlan func(*types.Plan)
+
+	stdout, stderr lineWriter
+}
+
+// Stdout is the writer for the remote's stdout: frames and command output.
+func (r *Relay) Stdout() io.Writer {
+	r.stdout.line = r.stdoutLine
+	return &r.stdout
+}
+
+// Stderr is the writer for the remote's stderr: its log.
+func (r *Relay) Stderr() io.Writer {
+	r.stderr.line = r.logLine
+	return &r.stderr
+}
+
+// Flush relays a last line that did not end in a newline.
+func (r *Relay) Flush() {
+	r.stdout.flush()
+	r.stderr.flush()
+}
+
+func (r *Relay) stdoutLine(line string) {
+	i := strings.Index(line, frameMarker)
+	if i < 0 {
+		r.output(line)
+		return
+	}
+	r.output(line[:i])
+
+	var f frame
+	if err := json.Unmarshal([]byte(line[i+len(frameMarker):]), &f); err != nil {
+		log.Debugf("%s: undecodable event: %v", r.Host, err)
+		return
+	}
+	if f.Type == "plan" {
+		if f.Plan != nil && r.OnPlan != nil {
+			r.OnPlan(f.Plan)
+		}
+		return
+	}
+	event, ok := f.event()
+	if !ok {
+		log.Debugf("%s: unknown event %q", r.Host, f.Type)
+		return
+	}
+	if started, ok := event.(reporting.ProcStarted); ok {
+		reporting.SetCurrentProcessor(started.Processor)
+	}
+	reporting.Emit(event)
+}
+
+// output relays a command's own output, which a local run prints through.
+func (r *Relay) output(line string) {
+	if strings.TrimSpace(line) != "" {
+		log.Info(line, "host", r.Host)
+	}
+}
+
+// logLine re-logs one of the remote's JSON log records at its level. A line
+// that is not a record (a panic, a shell error) is relayed as it is.
+func (r *Relay) logLine(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		log.Info(line, "host", r.Host)
+		return
+	}
+	msg, _ := record["msg"].(string)
+	level, _ := record["level"].(string)
+	for _, key := range []string{"msg", "level", "time", "caller", "prefix"} {
+		delete(record, key)
+	}
+	keyvals := []any{"host", r.Host}
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		keyvals = append(keyvals, key, record[key])
+	}
+
+	switch level {
+	case "debug":
+		log.Debug(msg, keyvals...)
+	case "warn":
+		log.Warn(msg, keyvals...)
+	case "error", "fatal":
+		log.Error(msg, keyvals...)
+	default:
+		log.Info(msg, keyvals...)
+	}
+}
+
+// lineWriter calls line for each complete line written to it.
+type lineWriter struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	line func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		w.line(strings.TrimSuffix(line, "\n"))
+	}
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 && w.line != nil {
+		w.line(w.buf.String())
+		w.buf.Reset()
+	}
+}
@@ -74,6 +74,16 @@ type ResourceDone struct {
 	Resource types.Resource
 }
 
+// ResourceRetry reports a failed attempt at a unit of work that will be tried
+// again after Delay. Resource carries the failure; the ResourceDone that
+// eventually follows is the outcome. Attempt counts from 1.
+type ResourceRetry struct {
+	Resource types.Resource
+	Attempt  int
+	Attempts int
+	Delay    time.Duration
+}
+
 // TerminalReq asks the display layer to hand the real terminal to a command.
 // stderr of interactive commands is never piped - capturing it swallows
 // sudo's password prompt and hangs the run.
@@ -158,17 +168,18 @@ type RunFinished struct {
 	Errs []types.StepError
 }
 
-func (ProcStarted) runEvent()  {}
-func (ProcFinished) runEvent() {}
-func (ProcSkipped) runEvent()  {}
-func (LaneUpdate) runEvent()   {}
-func (ResourceDone) runEvent() {}
-func (TerminalReq) runEvent()  {}
-func (TerminalFunc) runEvent() {}
-func (SecretReq) runEvent()    {}
-func (ConfirmReq) runEvent()   {}
-func (HaltReq) runEvent()      {}
-func (RunFinished) runEvent()  {}
+func (ProcStarted) runEvent()   {}
+func (ProcFinished) runEvent()  {}
+func (ProcSkipped) runEvent()   {}
+func (LaneUpdate) runEvent()    {}
+func (ResourceDone) runEvent()  {}
+func (ResourceRetry) runEvent() {}
+func (TerminalReq) runEvent()   {}
+func (TerminalFunc) runEvent()  {}
+func (SecretReq) runEvent()     {}
+func (ConfirmReq) runEvent()    {}
+func (HaltReq) runEvent()       {}
+func (RunFinished) runEvent()   {}
 
 // processorLabels are the exact strings the pre-event loop logged per
 // processor; LogReporter must reproduce them byte-for-byte.
@@ -234,7 +245,7 @@ func (LogReporter) Emit(event Event) {
 			return
 		}
 		e.Decision <- HaltAbort
-	case ProcFinished, LaneUpdate, ResourceDone, RunFinished:
+	case ProcFinished, LaneUpdate, ResourceDone, ResourceRetry, RunFinished:
 		// The streaming output never printed these as their own lines; the
 		// processors' own log calls carry the detail.
 	}
@@ -313,12 +324,40 @@ func Set(r Reporter) (restore func()) {
 	}
 }
 
-// Emit sends an event to the active reporter.
+// observers see every event after the active reporter has, whichever
+// reporter that is: sinks that record a run (hooks, webhooks, metrics)
+// compose with the dashboard or the log instead of replacing them. An
+// observer must not block, and must leave interactive events unanswered -
+// the reporter owns those.
+var observers []Reporter
+
+// Observe adds an observer and returns the func that removes it.
+func Observe(o Reporter) (remove func()) {
+	currentMu.Lock()
+	observers = append(observers, o)
+	currentMu.Unlock()
+	return func() {
+		currentMu.Lock()
+		defer currentMu.Unlock()
+		for i, existing := range observers {
+			if existing == o {
+				observers = append(observers[:i:i], observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Emit sends an event to the active reporter, then to the observers.
 func Emit(event Event) {
 	currentMu.RLock()
 	r := current
+	watching := observers
 	currentMu.RUnlock()
 	r.Emit(event)
+	for _, o := range watching {
+		o.Emit(event)
+	}
 }
 
 // terminalLost, when set, is closed by the TUI runner the moment the
@@ -2,8 +2,10 @@ package scan
 
 import (
 	"path"
+	"sort"
 
 	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/types"
 )
 
 // EmitPackages renders package results as a packages blueprint block in the
@@ -67,6 +69,143 @@ func EmitConfigs(configs []ConfigResult, home, format string) ([]byte, error) {
 	return helpers.EncodeBlueprintDoc(map[string]interface{}{"files": entries}, format)
 }
 
+// EmitRepositories renders package sources as a repositories blueprint
+// block. apt and dnf sources are emitted without key_url when the scan could
+// not find one; the caller warns, since the add step needs the key.
+func EmitRepositories(results []RepositoryResult, format string) ([]byte, error) {
+	var entries []interface{}
+	for _, result := range results {
+		entry := map[string]interface{}{
+			"name":            result.Name,
+			"package_manager": result.Provider,
+			"action":          "add",
+			"url":             result.URL,
+		}
+		for key, value := range map[string]string{
+			"channel":     result.Channel,
+			"component":   result.Component,
+			"arch":        result.Arch,
+			"key_url":     result.KeyURL,
+			"description": result.Description,
+		} {
+			if value != "" {
+				entry[key] = value
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return helpers.EncodeBlueprintDoc(map[string]interface{}{"repositories": entries}, format)
+}
+
+// EmitFonts renders installed Nerd Fonts as a fonts blueprint block: one
+// entry for the per-user fonts and one for the system-wide ones.
+func EmitFonts(results []FontResult, format string) ([]byte, error) {
+	var user, system []string
+	for _, result := range results {
+		if result.System {
+			system = append(system, result.Name)
+		} else {
+			user = append(user, result.Name)
+		}
+	}
+	var entries []interface{}
+	if len(user) > 0 {
+		entries = append(entries, map[string]interface{}{
+			"name":   "nerd-fonts",
+			"names":  user,
+			"action": "install",
+		})
+	}
+	if len(system) > 0 {
+		entries = append(entries, map[string]interface{}{
+			"name":     "nerd-fonts-system",
+			"names":    system,
+			"action":   "install",
+			"location": "system",
+		})
+	}
+	return helpers.EncodeBlueprintDoc(map[string]interface{}{"fonts": entries}, format)
+}
+
+// EmitUsers renders local accounts and groups as a users blueprint block.
+// Passwords are never captured: an account created from it is locked until
+// the operator adds password_credential.
+func EmitUsers(users []UserResult, groups []GroupResult, format string) ([]byte, error) {
+	doc := map[string]interface{}{}
+	var groupEntries []interface{}
+	for _, group := range groups {
+		groupEntries = append(groupEntries, map[string]interface{}{
+			"name":   group.Name,
+			"gid":    group.GID,
+			"action": "create",
+		})
+	}
+	if len(groupEntries) > 0 {
+		doc["groups"] = groupEntries
+	}
+	var userEntries []interface{}
+	for _, u := range users {
+		entry := map[string]interface{}{
+			"name":   u.Name,
+			"uid":    u.UID,
+			"action": "create",
+		}
+		for key, value := range map[string]string{"comment": u.Comment, "shell": u.Shell, "home": u.Home} {
+			if value != "" {
+				entry[key] = value
+			}
+		}
+		if len(u.Groups) > 0 {
+			entry["groups"] = u.Groups
+		}
+		userEntries = append(userEntries, entry)
+	}
+	if len(userEntries) > 0 {
+		doc["users"] = userEntries
+	}
+	return helpers.EncodeBlueprintDoc(doc, format)
+}
+
+// EmitSettings renders changed settings as a configuration blueprint block:
+// one gsettings entry per schema carrying its keys, one macos_defaults entry
+// per key.
+func EmitSettings(results []SettingResult, format string) ([]byte, error) {
+	var entries []interface{}
+	bySchema := map[string]map[string]interface{}{}
+	var schemas []string
+	for _, result := range results {
+		switch result.Tool {
+		case types.ConfigurationToolGSettings:
+			if bySchema[result.Schema] == nil {
+				bySchema[result.Schema] = map[string]interface{}{}
+				schemas = append(schemas, result.Schema)
+			}
+			bySchema[result.Schema][result.Key] = result.Value
+		case types.ConfigurationToolMacOSDefaults:
+			entries = append(entries, map[string]interface{}{
+				"name":   result.Domain + "-" + result.Key,
+				"tool":   result.Tool,
+				"domain": result.Domain,
+				"key":    result.Key,
+				"kind":   result.Kind,
+				"value":  result.Value,
+			})
+		}
+	}
+	sort.Strings(schemas)
+	gsettings := make([]interface{}, 0, len(schemas))
+	for _, schema := range schemas {
+		gsettings = append(gsettings, map[string]interface{}{
+			"name":     schema,
+			"tool":     types.ConfigurationToolGSettings,
+			"schema":   schema,
+			"settings": bySchema[schema],
+		})
+	}
+	entries = append(gsettings, entries...)
+	return helpers.EncodeBlueprintDoc(map[string]interface{}{"configurations": entries}, format)
+}
+
 // templatedHome rewrites an absolute path under home as a User.home
 // template, so the emitted blueprint works on a machine with another
 // username.
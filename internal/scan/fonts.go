@@ -0,0 +1,128 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// FontResult is one installed Nerd Font, named the way the nerd-fonts
+// release names its archive - what a fonts blueprint entry takes.
+type FontResult struct {
+	Name   string
+	System bool // installed system-wide rather than for the user
+}
+
+// nerdFontFace matches a face file of either release generation:
+// "JetBrainsMonoNerdFont-Regular.ttf" (v3) or "Hack Regular Nerd Font
+// Complete.ttf" (v2). The first group is the patched family name.
+var nerdFontFace = regexp.MustCompile(`^(.+?)\s*Nerd ?Font`)
+
+// nerdFontArchives maps the patched family names that differ from their
+// archive's - the fonts whose upstream license forbids the original name.
+var nerdFontArchives = map[string]string{
+	"AurulentSansM":  "AurulentSansMono",
+	"BlexMono":       "IBMPlexMono",
+	"CaskaydiaCove":  "CascadiaCode",
+	"CaskaydiaMono":  "CascadiaMono",
+	"DejaVuSansM":    "DejaVuSansMono",
+	"FantasqueSansM": "FantasqueSansMono",
+	"Hurmit":         "Hermit",
+	"Literation":     "LiberationMono",
+	"LiterationMono": "LiberationMono",
+	"SauceCodePro":   "SourceCodePro",
+	"ShureTechMono":  "ShareTechMono",
+	"Terminess":      "Terminus",
+}
+
+// fontStyles are the style words a v2 face name puts after its family.
+var fontStyles = map[string]bool{
+	"regular": true, "bold": true, "italic": true, "oblique": true, "light": true,
+	"medium": true, "thin": true, "book": true, "black": true, "heavy": true,
+	"semibold": true, "extrabold": true, "extralight": true, "retina": true,
+	"bolditalic": true, "lightitalic": true, "mediumitalic": true,
+}
+
+// fontDirs are the directories the fonts processor installs into: the
+// per-user one on every platform, and the system one.
+func fontDirs(home string) (user string, system string) {
+	user = filepath.Join(home, ".local", "share", "fonts")
+	switch runtime.GOOS {
+	case "darwin":
+		system = "/Library/Fonts"
+	case "windows":
+		system = filepath.Join(os.Getenv("WINDIR"), "Fonts")
+	default:
+		system = "/usr/local/share/fonts"
+	}
+	return user, system
+}
+
+// Fonts reports the Nerd Fonts installed where the fonts processor would put
+// them. A family installed both per-user and system-wide is reported once,
+// as per-user.
+func Fonts(home string) []FontResult {
+	userDir, systemDir := fontDirs(home)
+	seen := map[string]bool{}
+	var results []FontResult
+	for _, dir := range []struct {
+		path   string
+		system bool
+	}{{userDir, false}, {systemDir, true}} {
+		for _, name := range nerdFontFamilies(dir.path) {
+			if !seen[name] {
+				seen[name] = true
+				results = append(results, FontResult{Name: name, System: dir.system})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
+
+// nerdFontFamilies lists the archive names of the Nerd Font faces under dir.
+func nerdFontFamilies(dir string) []string {
+	var names []string
+	seen := map[string]bool{}
+	_ = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil //nolint:nilerr // an unreadable directory holds no fonts we can report
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".ttf" && ext != ".otf" {
+			return nil
+		}
+		if name := nerdFontArchive(entry.Name()); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names
+}
+
+// nerdFontArchive names the archive a face file came from, or "" for a face
+// that is not a Nerd Font.
+func nerdFontArchive(file string) string {
+	match := nerdFontFace.FindStringSubmatch(file)
+	if match == nil {
+		return ""
+	}
+	// v2 faces carry the style before "Nerd Font": "Sauce Code Pro Bold
+	// Nerd Font Complete".
+	words := strings.Fields(match[1])
+	for len(words) > 1 && fontStyles[strings.ToLower(words[len(words)-1])] {
+		words = words[:len(words)-1]
+	}
+	family := strings.Join(words, "")
+	if strings.HasPrefix(family, "MesloLG") {
+		return "Meslo"
+	}
+	if archive, ok := nerdFontArchives[family]; ok {
+		return archive
+	}
+	return family
+}
@@ -0,0 +1,300 @@
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/helpers"
+)
+
+// RepositoryResult is one third-party package source: an apt source or PPA, a
+// dnf repository or COPR, a Homebrew tap, a flatpak remote. Its fields are the
+// ones the provider's repository steps render.
+type RepositoryResult struct {
+	Provider    string
+	Name        string
+	URL         string
+	Channel     string // apt suite
+	Component   string // apt components, space-separated
+	Arch        string // apt architecture
+	KeyURL      string // dnf gpgkey, when it is a URL
+	Description string // dnf name=
+}
+
+// output runs a read-only query; a variable so tests can answer for the
+// tools a scan machine does not have.
+var output = func(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output() // #nosec G204 -- fixed read-only queries built by this package
+}
+
+// have reports whether a tool is on PATH; a variable for the same reason.
+var have = func(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// Where the package sources live; variables so tests can point them at a
+// temporary tree.
+var (
+	aptSourcesDir = "/etc/apt/sources.list.d"
+	yumReposDir   = "/etc/yum.repos.d"
+)
+
+// Repositories reports the package sources the operator added. A source
+// file a package owns (the distribution's own, or one a -release package
+// installed) is not operator intent and is left out; the package that owns
+// it is what the packages scan captures.
+func Repositories() []RepositoryResult {
+	var results []RepositoryResult
+	if runtime.GOOS == "linux" {
+		if have("dpkg") {
+			results = append(results, aptRepositories()...)
+		}
+		if have("rpm") {
+			results = append(results, dnfRepositories()...)
+		}
+	}
+	if have("brew") {
+		results = append(results, brewTaps()...)
+	}
+	if have("flatpak") {
+		results = append(results, flatpakRemotes()...)
+	}
+	return results
+}
+
+// repositoryNameUnsafe is what a repository name must not contain: it names a
+// file under the provider's sources directory.
+var repositoryNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func repositoryName(raw string) string {
+	return strings.Trim(repositoryNameUnsafe.ReplaceAllString(raw, "-"), "-.")
+}
+
+// aptRepositories reads the one-line (.list) and deb822 (.sources) files
+// under sources.list.d. Only binary ("deb") sources are reported.
+func aptRepositories() []RepositoryResult {
+	entries, err := os.ReadDir(aptSourcesDir)
+	if err != nil {
+		return nil
+	}
+	var results []RepositoryResult
+	for _, entry := range entries {
+		path := filepath.Join(aptSourcesDir, entry.Name())
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".list" && ext != ".sources") || packageOwned("dpkg", "-S", path) {
+			continue
+		}
+		data, err := os.ReadFile(path) // #nosec G304 -- a file under the apt sources directory
+		if err != nil {
+			continue
+		}
+		name := repositoryName(strings.TrimSuffix(entry.Name(), ext))
+		var sources []RepositoryResult
+		if ext == ".list" {
+			sources = parseAptList(string(data))
+		} else {
+			sources = parseDeb822(string(data))
+		}
+		// One file is one blueprint entry: the apt provider writes one line
+		// per name. A file with several sources keeps its first.
+		if len(sources) > 0 {
+			source := sources[0]
+			source.Provider, source.Name = "apt", name
+			results = append(results, source)
+		}
+	}
+	return results
+}
+
+// parseAptList reads "deb [options] URL suite component..." lines.
+func parseAptList(content string) []RepositoryResult {
+	var results []RepositoryResult
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "deb" {
+			continue
+		}
+		fields = fields[1:]
+		arch := ""
+		if strings.HasPrefix(fields[0], "[") {
+			end := 0
+			for end < len(fields) && !strings.HasSuffix(fields[end], "]") {
+				end++
+			}
+			if end == len(fields) {
+				continue
+			}
+			options := strings.Trim(strings.Join(fields[:end+1], " "), "[]")
+			for _, option := range strings.Fields(options) {
+				if value, ok := strings.CutPrefix(option, "arch="); ok {
+					arch = value
+				}
+			}
+			fields = fields[end+1:]
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		results = append(results, RepositoryResult{
+			URL:       fields[0],
+			Channel:   fields[1],
+			Component: strings.Join(fields[2:], " "),
+			Arch:      arch,
+		})
+	}
+	return results
+}
+
+// parseDeb822 reads the stanzas of a .sources file.
+func parseDeb822(content string) []RepositoryResult {
+	var results []RepositoryResult
+	for _, stanza := range strings.Split(content, "\n\n") {
+		fields := map[string]string{}
+		for _, line := range strings.Split(stanza, "\n") {
+			if key, value, ok := strings.Cut(line, ":"); ok && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "#") {
+				fields[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+			}
+		}
+		if !strings.Contains(fields["types"], "deb") || strings.EqualFold(fields["enabled"], "no") {
+			continue
+		}
+		uris, suites := strings.Fields(fields["uris"]), strings.Fields(fields["suites"])
+		if len(uris) == 0 || len(suites) == 0 {
+			continue
+		}
+		results = append(results, RepositoryResult{
+			URL:       uris[0],
+			Channel:   suites[0],
+			Component: fields["components"],
+			Arch:      fields["architectures"],
+		})
+	}
+	return results
+}
+
+// dnfRepositories reads the enabled sections of the .repo files under
+// yum.repos.d that no package owns: COPRs and hand-added repositories.
+func dnfRepositories() []RepositoryResult {
+	entries, err := os.ReadDir(yumReposDir)
+	if err != nil {
+		return nil
+	}
+	var results []RepositoryResult
+	for _, entry := range entries {
+		path := filepath.Join(yumReposDir, entry.Name())
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".repo" || packageOwned("rpm", "-qf", path) {
+			continue
+		}
+		data, err := os.ReadFile(path) // #nosec G304 -- a file under the dnf repository directory
+		if err != nil {
+			continue
+		}
+		results = append(results, parseRepoFile(string(data))...)
+	}
+	return results
+}
+
+// parseRepoFile reads the enabled sections of a dnf .repo file.
+func parseRepoFile(content string) []RepositoryResult {
+	var results []RepositoryResult
+	sections := map[string]bool{}
+	var order []string
+	for _, key := range helpers.INIKeys(content) {
+		if !sections[key.Section] {
+			sections[key.Section] = true
+			order = append(order, key.Section)
+		}
+	}
+	for _, section := range order {
+		if enabled, _ := helpers.INIValue(content, section, "enabled"); enabled == "0" {
+			continue
+		}
+		url, _ := helpers.INIValue(content, section, "baseurl")
+		if url == "" {
+			continue
+		}
+		result := RepositoryResult{
+			Provider: "dnf",
+			Name:     repositoryName(coprName(section)),
+			URL:      url,
+		}
+		result.Description, _ = helpers.INIValue(content, section, "name")
+		if key, _ := helpers.INIValue(content, section, "gpgkey"); strings.HasPrefix(key, "https://") || strings.HasPrefix(key, "http://") {
+			result.KeyURL = key
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// coprName shortens a COPR section ("copr:copr.fedorainfracloud.org:owner:project")
+// to "copr-owner-project".
+func coprName(section string) string {
+	parts := strings.Split(section, ":")
+	if len(parts) == 4 && parts[0] == "copr" {
+		return "copr-" + parts[2] + "-" + parts[3]
+	}
+	return section
+}
+
+// packageOwned reports whether the package database claims a file.
+func packageOwned(query ...string) bool {
+	_, err := output(query[0], query[1:]...)
+	return err == nil
+}
+
+// defaultTaps ship with Homebrew; nobody adds them by hand.
+var defaultTaps = map[string]bool{"homebrew/core": true, "homebrew/cask": true}
+
+func brewTaps() []RepositoryResult {
+	out, err := output("brew", "tap")
+	if err != nil {
+		return nil
+	}
+	var results []RepositoryResult
+	for _, tap := range strings.Fields(string(out)) {
+		if defaultTaps[tap] {
+			continue
+		}
+		results = append(results, RepositoryResult{Provider: "brew", Name: repositoryName(tap), URL: tap})
+	}
+	return results
+}
+
+// flatpakRepoFiles are the .flatpakrepo files of the well-known remotes.
+// remote-add given a bare repository URL adds the remote without its signing
+// key, so a remote with a known description file is emitted with that
+// instead.
+var flatpakRepoFiles = map[string]string{
+	"flathub":      "https://dl.flathub.org/repo/flathub.flatpakrepo",
+	"flathub-beta": "https://dl.flathub.org/beta-repo/flathub-beta.flatpakrepo",
+}
+
+func flatpakRemotes() []RepositoryResult {
+	out, err := output("flatpak", "remotes", "--columns=name,url")
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var results []RepositoryResult
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+		url := fields[1]
+		if known, ok := flatpakRepoFiles[fields[0]]; ok {
+			url = known
+		}
+		results = append(results, RepositoryResult{Provider: "flatpak", Name: fields[0], URL: url})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
@@ -1,9 +1,11 @@
 package scan
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -172,7 +174,7 @@ func TestEmit_RoundTrips(t *testing.T) {
 		if err := helpers.DecodeBlueprintInto(block, decodeFormat, types.BlueprintTypePackages, 0, &d); err != nil {
 			t.Fatalf("%s block does not strict-decode: %v\n%s", format, err, block)
 		}
-		if len(d.Packages) != 1 || d.Packages[0].PackageManager != "pacman" {
+		if len(d.Packages) != 1 || d.Packages[0].PackageManager.String() != "pacman" {
 			t.Fatalf("%s round-trip = %+v", format, d.Packages)
 		}
 	}
@@ -186,6 +188,62 @@ func TestEmit_RoundTrips(t *testing.T) {
 	if err != nil || !strings.Contains(string(files), "src/.bashrc") {
 		t.Fatalf("config emission (%v):\n%s", err, files)
 	}
+
+	// The newer categories strict-decode too.
+	repositories := []RepositoryResult{
+		{Provider: "apt", Name: "docker", URL: "https://download.docker.com/linux/ubuntu", Channel: "noble", Component: "stable"},
+		{Provider: "flatpak", Name: "flathub", URL: "https://dl.flathub.org/repo/flathub.flatpakrepo"},
+	}
+	fonts := []FontResult{{Name: "JetBrainsMono"}, {Name: "Hack", System: true}}
+	users := []UserResult{{Name: "builder", UID: "1001", Shell: "/bin/bash", Groups: []string{"devs"}}}
+	groups := []GroupResult{{Name: "devs", GID: "1002"}}
+	settings := []SettingResult{
+		{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.interface", Key: "color-scheme", Value: "prefer-dark"},
+		{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.interface", Key: "clock-show-seconds", Value: true},
+		{Tool: types.ConfigurationToolMacOSDefaults, Domain: "com.apple.dock", Key: "tilesize", Kind: "int", Value: int64(36)},
+	}
+	for _, format := range []string{"yaml", "json", "toml", "cue"} {
+		decodeFormat := format
+		if format == "cue" {
+			decodeFormat = "json"
+		}
+
+		block, err := EmitRepositories(repositories, format)
+		var r types.RepositoriesData
+		if err == nil {
+			err = helpers.DecodeBlueprintInto(block, decodeFormat, types.BlueprintTypeRepositories, 0, &r)
+		}
+		if err != nil || len(r.Repositories) != 2 || r.Repositories[0].Channel != "noble" {
+			t.Fatalf("%s repositories (%v): %+v\n%s", format, err, r.Repositories, block)
+		}
+
+		block, err = EmitFonts(fonts, format)
+		var f types.FontsData
+		if err == nil {
+			err = helpers.DecodeBlueprintInto(block, decodeFormat, types.BlueprintTypeFonts, 0, &f)
+		}
+		if err != nil || len(f.Fonts) != 2 || f.Fonts[1].Location != "system" {
+			t.Fatalf("%s fonts (%v): %+v\n%s", format, err, f.Fonts, block)
+		}
+
+		block, err = EmitUsers(users, groups, format)
+		var u types.UsersData
+		if err == nil {
+			err = helpers.DecodeBlueprintInto(block, decodeFormat, types.BlueprintTypeUsers, 0, &u)
+		}
+		if err != nil || len(u.Users) != 1 || len(u.Groups) != 1 || u.Users[0].UID != "1001" {
+			t.Fatalf("%s users (%v): %+v\n%s", format, err, u, block)
+		}
+
+		block, err = EmitSettings(settings, format)
+		var c types.ConfigData
+		if err == nil {
+			err = helpers.DecodeBlueprintInto(block, decodeFormat, types.BlueprintTypeConfiguration, 0, &c)
+		}
+		if err != nil || len(c.Configurations) != 2 || len(c.Configurations[0].Settings) != 2 {
+			t.Fatalf("%s settings (%v): %+v\n%s", format, err, c.Configurations, block)
+		}
+	}
 }
 
 func TestSecretShaped(t *testing.T) {
@@ -198,3 +256,231 @@ func TestSecretShaped(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAptList(t *testing.T) {
+	sources := parseAptList("# comment\n" +
+		"deb [arch=amd64 signed-by=/etc/apt/keyrings/docker.gpg] https://download.docker.com/linux/ubuntu noble stable\n" +
+		"deb-src https://download.docker.com/linux/ubuntu noble stable\n")
+	if len(sources) != 1 {
+		t.Fatalf("sources = %+v, want the one binary source", sources)
+	}
+	got := sources[0]
+	if got.URL != "https://download.docker.com/linux/ubuntu" || got.Channel != "noble" || got.Component != "stable" || got.Arch != "amd64" {
+		t.Fatalf("source = %+v", got)
+	}
+}
+
+func TestParseDeb822(t *testing.T) {
+	sources := parseDeb822("Types: deb\nURIs: https://ppa.launchpadcontent.net/neovim-ppa/stable/ubuntu/\n" +
+		"Suites: noble\nComponents: main\nSigned-By: /etc/apt/keyrings/neovim.gpg\n\n" +
+		"Types: deb\nURIs: https://example.com/off\nSuites: x\nEnabled: no\n")
+	if len(sources) != 1 || sources[0].Channel != "noble" || sources[0].Component != "main" {
+		t.Fatalf("sources = %+v, want the enabled stanza only", sources)
+	}
+}
+
+// Disabled sections are skipped, a COPR section is named the way people say
+// it, and a file-path gpgkey is not a key_url.
+func TestParseRepoFile(t *testing.T) {
+	repos := parseRepoFile("[copr:copr.fedorainfracloud.org:atim:lazygit]\n" +
+		"name=Copr repo for lazygit owned by atim\n" +
+		"baseurl=https://download.copr.fedorainfracloud.org/results/atim/lazygit/fedora-$releasever-$basearch/\n" +
+		"gpgkey=https://download.copr.fedorainfracloud.org/results/atim/lazygit/pubkey.gpg\nenabled=1\n\n" +
+		"[local]\nbaseurl=file:///srv/repo\ngpgkey=file:///etc/pki/rpm-gpg/local\nenabled=1\n\n" +
+		"[off]\nbaseurl=https://example.com\nenabled=0\n")
+	if len(repos) != 2 {
+		t.Fatalf("repos = %+v", repos)
+	}
+	if repos[0].Name != "copr-atim-lazygit" || repos[0].KeyURL == "" || repos[0].Description == "" {
+		t.Fatalf("copr = %+v", repos[0])
+	}
+	if repos[1].Name != "local" || repos[1].KeyURL != "" {
+		t.Fatalf("local = %+v, want no key_url for a file key", repos[1])
+	}
+}
+
+// A package-owned source file is the distribution's, not the operator's.
+func TestRepositories_SkipsPackageOwned(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("apt sources are scanned on Linux only")
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"ubuntu.sources": "Types: deb\nURIs: http://archive.ubuntu.com/ubuntu\nSuites: noble\nComponents: main\n",
+		"docker.list":    "deb https://download.docker.com/linux/ubuntu noble stable\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldDir, oldOutput, oldHave := aptSourcesDir, output, have
+	t.Cleanup(func() { aptSourcesDir, output, have = oldDir, oldOutput, oldHave })
+	aptSourcesDir = dir
+	have = func(name string) bool { return name == "dpkg" }
+	output = func(name string, args ...string) ([]byte, error) {
+		if name == "dpkg" && strings.HasSuffix(args[len(args)-1], "ubuntu.sources") {
+			return []byte("base-files: " + args[len(args)-1]), nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	repos := Repositories()
+	if len(repos) != 1 || repos[0].Name != "docker" || repos[0].Provider != "apt" {
+		t.Fatalf("repos = %+v, want only the hand-added docker source", repos)
+	}
+}
+
+func TestNerdFontArchive(t *testing.T) {
+	for file, want := range map[string]string{
+		"JetBrainsMonoNerdFont-Regular.ttf":          "JetBrainsMono",
+		"JetBrainsMonoNerdFontMono-Bold.ttf":         "JetBrainsMono",
+		"CaskaydiaCoveNerdFontPropo-Italic.ttf":      "CascadiaCode",
+		"MesloLGSNerdFont-Regular.ttf":               "Meslo",
+		"Hack Regular Nerd Font Complete.ttf":        "Hack",
+		"Sauce Code Pro Nerd Font Complete Mono.ttf": "SourceCodePro",
+		"DejaVuSans.ttf":                             "",
+	} {
+		if got := nerdFontArchive(file); got != want {
+			t.Errorf("nerdFontArchive(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestParseAccounts(t *testing.T) {
+	passwd := "root:x:0:0:root:/root:/bin/bash\n" +
+		"me:x:1000:1000:Me:/home/me:/bin/zsh\n" +
+		"builder:x:1001:1001:Build Bot,,,:/home/builder:/bin/bash\n" +
+		"nobody:x:65534:65534:nobody:/:/usr/sbin/nologin\n"
+	group := "wheel:x:10:builder\nme:x:1000:\nbuilder:x:1001:\ndevs:x:1002:builder,me\n"
+
+	users, groups := parseAccounts(passwd, group, "UID_MIN 1000\nUID_MAX 60000\n", "me")
+	if len(users) != 1 || users[0].Name != "builder" || users[0].Comment != "Build Bot" {
+		t.Fatalf("users = %+v, want only builder", users)
+	}
+	if strings.Join(users[0].Groups, ",") != "wheel,devs" {
+		t.Fatalf("builder groups = %v", users[0].Groups)
+	}
+	if len(groups) != 1 || groups[0].Name != "devs" {
+		t.Fatalf("groups = %+v, want devs only - private groups go with their user", groups)
+	}
+}
+
+func TestGVariantValue(t *testing.T) {
+	for text, want := range map[string]interface{}{
+		"'prefer-dark'":            "prefer-dark",
+		`"it's"`:                   "it's",
+		"true":                     true,
+		"uint32 5":                 int64(5),
+		"1.25":                     1.25,
+		"['firefox.desktop', 'x']": "['firefox.desktop', 'x']",
+		"@as []":                   "[]",
+		"{'a': <1>}":               nil,
+		"'[looks-formatted]'":      nil,
+	} {
+		got, ok := gvariantValue(text)
+		if want == nil {
+			if ok {
+				t.Errorf("gvariantValue(%q) = %v, want it skipped", text, got)
+			}
+			continue
+		}
+		if !ok || got != want {
+			t.Errorf("gvariantValue(%q) = %v (%v), want %v", text, got, ok, want)
+		}
+	}
+}
+
+// fakeTools answers the scan's queries from a table keyed by the command
+// line.
+func fakeTools(t *testing.T, tools []string, answers map[string]string) {
+	t.Helper()
+	oldOutput, oldHave := output, have
+	t.Cleanup(func() { output, have = oldOutput, oldHave })
+	have = func(name string) bool {
+		for _, tool := range tools {
+			if tool == name {
+				return true
+			}
+		}
+		return false
+	}
+	output = func(name string, args ...string) ([]byte, error) {
+		if answer, ok := answers[strings.Join(append([]string{name}, args...), " ")]; ok {
+			return []byte(answer), nil
+		}
+		return nil, os.ErrNotExist
+	}
+}
+
+const (
+	gsettingsDefaults = "org.gnome.desktop.interface color-scheme 'default'\n" +
+		"org.gnome.desktop.interface clock-format '24h'\n" +
+		"org.gnome.desktop.interface text-scaling-factor 1.0\n" +
+		"org.gnome.shell favorite-apps ['org.gnome.Nautilus.desktop']\n" +
+		"org.gnome.nautilus.window-state initial-size (890, 550)\n"
+	gsettingsSchemaPaths = "org.gnome.desktop.interface /org/gnome/desktop/interface/\n" +
+		"org.gnome.shell /org/gnome/shell/\n" +
+		"org.gnome.nautilus.window-state /org/gnome/nautilus/window-state/\n"
+)
+
+// Only keys away from their schema default are captured: a key dconf holds
+// at its default value is not a change, relocatable paths and window state
+// are left out.
+func TestGnomeSettings_DconfDelta(t *testing.T) {
+	fakeTools(t, []string{"gsettings", "dconf"}, map[string]string{
+		"env GSETTINGS_BACKEND=memory gsettings list-recursively": gsettingsDefaults,
+		"gsettings list-schemas --print-paths":                    gsettingsSchemaPaths,
+		"dconf dump /": "[org/gnome/desktop/interface]\ncolor-scheme='prefer-dark'\nclock-format='24h'\n\n" +
+			"[org/gnome/nautilus/window-state]\ninitial-size=(1200, 800)\n\n" +
+			"[org/gnome/terminal/legacy/profiles:/:b1dcc9dd/]\nfont='Mono 12'\n",
+	})
+	settings := gnomeSettings()
+	if len(settings) != 1 || settings[0].Schema != "org.gnome.desktop.interface" || settings[0].Value != "prefer-dark" {
+		t.Fatalf("settings = %+v, want only color-scheme", settings)
+	}
+	if settings[0].Path != "/org/gnome/desktop/interface/color-scheme" || settings[0].Default != "'default'" {
+		t.Fatalf("setting = %+v", settings[0])
+	}
+}
+
+// Without dconf the current values come from gsettings itself; type
+// annotations do not make equal values differ.
+func TestGnomeSettings_GSettingsDelta(t *testing.T) {
+	fakeTools(t, []string{"gsettings"}, map[string]string{
+		"env GSETTINGS_BACKEND=memory gsettings list-recursively": gsettingsDefaults,
+		"gsettings list-schemas --print-paths":                    gsettingsSchemaPaths,
+		"gsettings list-recursively": "org.gnome.desktop.interface color-scheme 'default'\n" +
+			"org.gnome.desktop.interface clock-format '12h'\n" +
+			"org.gnome.desktop.interface text-scaling-factor 1.25\n" +
+			"org.gnome.shell favorite-apps ['org.gnome.Nautilus.desktop', 'firefox.desktop']\n",
+	})
+	var got []string
+	for _, setting := range gnomeSettings() {
+		got = append(got, fmt.Sprintf("%s=%v", setting.Key, setting.Value))
+	}
+	want := "clock-format=12h,text-scaling-factor=1.25,favorite-apps=['org.gnome.Nautilus.desktop', 'firefox.desktop']"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("settings = %v\nwant %s", got, want)
+	}
+}
+
+// A defaults key set to what a fresh install has is not a change; one left
+// unset is not scanned at all.
+func TestMacOSDefaults_Baseline(t *testing.T) {
+	fakeTools(t, []string{"defaults"}, map[string]string{
+		"defaults read-type com.apple.dock autohide":    "Type is boolean",
+		"defaults read com.apple.dock autohide":         "1",
+		"defaults read-type com.apple.dock orientation": "Type is string",
+		"defaults read com.apple.dock orientation":      "bottom",
+		"defaults read-type com.apple.dock tilesize":    "Type is integer",
+		"defaults read com.apple.dock tilesize":         "36",
+	})
+	var got []string
+	for _, setting := range macOSDefaults() {
+		got = append(got, fmt.Sprintf("%s %s=%v (%s)", setting.Domain, setting.Key, setting.Value, setting.Kind))
+	}
+	want := "com.apple.dock autohide=true (bool),com.apple.dock tilesize=36 (int)"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("settings = %v\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,337 @@
+package scan
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// SettingResult is one desktop setting changed from its default: a
+// gsettings key (with the dconf path it is stored at) or a macOS defaults
+// key.
+type SettingResult struct {
+	Tool    string // gsettings or macos_defaults
+	Schema  string // gsettings
+	Path    string // gsettings: the key's full dconf path, when the schema has one
+	Domain  string // macos_defaults
+	Key     string
+	Kind    string // macos_defaults: bool, int, float or string
+	Value   interface{}
+	Default string // the value it differs from, as the tool prints it; empty when unset by default
+}
+
+// Settings reports only the desktop settings the operator changed: GNOME
+// keys whose value differs from their schema default, and the curated macOS
+// defaults keys whose value differs from a fresh install's.
+func Settings() []SettingResult {
+	switch runtime.GOOS {
+	case "linux":
+		if have("gsettings") {
+			return gnomeSettings()
+		}
+	case "darwin":
+		return macOSDefaults()
+	}
+	return nil
+}
+
+// gsetting is one key as gsettings or dconf prints it.
+type gsetting struct {
+	schema, key, value string
+}
+
+// gnomeSettings compares the current values against the schema defaults,
+// which gsettings prints when it runs against the empty memory backend.
+// The current values come from `dconf dump /` when dconf is there - it
+// holds only keys something wrote - and from `gsettings list-recursively`
+// otherwise. A key written back to its default is not a change. A dconf
+// path no fixed-path schema claims belongs to a relocatable schema (a
+// terminal profile, a custom keybinding) whose instance cannot be addressed
+// by schema alone, and is left out.
+func gnomeSettings() []SettingResult {
+	defaults, err := output("env", "GSETTINGS_BACKEND=memory", "gsettings", "list-recursively")
+	if err != nil {
+		return nil
+	}
+	schemas, err := output("gsettings", "list-schemas", "--print-paths")
+	if err != nil {
+		return nil
+	}
+	paths := schemaPaths(string(schemas))
+	var current []gsetting
+	if have("dconf") {
+		dump, err := output("dconf", "dump", "/")
+		if err != nil {
+			return nil
+		}
+		current = dconfDumpKeys(string(dump), paths)
+	} else {
+		listing, err := output("gsettings", "list-recursively")
+		if err != nil {
+			return nil
+		}
+		current = listedKeys(string(listing))
+	}
+	return settingsDelta(current, listedKeys(string(defaults)), paths)
+}
+
+// schemaPaths maps a dconf directory ("org/gnome/desktop/interface") to the
+// schema stored there, from `gsettings list-schemas --print-paths`.
+func schemaPaths(out string) map[string]string {
+	paths := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			paths[strings.Trim(fields[1], "/")] = fields[0]
+		}
+	}
+	return paths
+}
+
+// listedKeys reads `gsettings list-recursively`: "schema key value" lines.
+func listedKeys(out string) []gsetting {
+	var keys []gsetting
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+		if len(fields) == 3 {
+			keys = append(keys, gsetting{schema: fields[0], key: fields[1], value: fields[2]})
+		}
+	}
+	return keys
+}
+
+// dconfDumpKeys reads the keys of a dconf dump that sit under a fixed-path
+// schema.
+func dconfDumpKeys(dump string, paths map[string]string) []gsetting {
+	var keys []gsetting
+	for _, entry := range helpers.INIKeys(dump) {
+		if schema, ok := paths[strings.Trim(entry.Section, "/")]; ok {
+			keys = append(keys, gsetting{schema: schema, key: entry.Key, value: entry.Value})
+		}
+	}
+	return keys
+}
+
+// settingsDelta keeps the current keys that differ from their defaults. A
+// key with no default listed belongs to no installed schema and is left
+// out, as is a value a blueprint cannot carry.
+func settingsDelta(current, defaults []gsetting, paths map[string]string) []SettingResult {
+	defaultOf := map[string]string{}
+	for _, d := range defaults {
+		defaultOf[d.schema+"\x00"+d.key] = d.value
+	}
+	dirOf := map[string]string{}
+	for dir, schema := range paths {
+		dirOf[schema] = dir
+	}
+	seen := map[string]bool{}
+	var results []SettingResult
+	for _, c := range current {
+		id := c.schema + "\x00" + c.key
+		def, known := defaultOf[id]
+		if !known || seen[id] || settingNoise(c.schema, c.key) ||
+			stripGVariantType(c.value) == stripGVariantType(def) {
+			continue
+		}
+		seen[id] = true
+		value, ok := gvariantValue(c.value)
+		if !ok {
+			continue
+		}
+		result := SettingResult{
+			Tool:    types.ConfigurationToolGSettings,
+			Schema:  c.schema,
+			Key:     c.key,
+			Value:   value,
+			Default: def,
+		}
+		if dir, ok := dirOf[c.schema]; ok {
+			result.Path = "/" + dir + "/" + c.key
+		}
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Schema != results[j].Schema {
+			return results[i].Schema < results[j].Schema
+		}
+		return results[i].Key < results[j].Key
+	})
+	return results
+}
+
+// settingNoise matches keys a desktop rewrites on its own: window geometry,
+// last-used locations, recent lists.
+func settingNoise(schema, key string) bool {
+	if strings.Contains(schema, "window-state") || strings.HasSuffix(schema, ".state") {
+		return true
+	}
+	for _, prefix := range []string{"window-", "last-", "recent-"} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return strings.HasSuffix(key, "-geometry") || strings.HasSuffix(key, "-maximized") || key == "maximized"
+}
+
+// gvariantValue turns GVariant text into the value a gsettings entry
+// declares: a string, bool or number as itself, an array or tuple as its
+// text (which the gsettings tool passes through). Text a blueprint value
+// cannot carry faithfully - dictionaries, variants, bytestrings, or a string
+// the tool would mistake for pre-formatted text - reports false.
+func gvariantValue(text string) (interface{}, bool) {
+	text = stripGVariantType(text)
+	switch {
+	case text == "true" || text == "false":
+		return text == "true", true
+	case strings.HasPrefix(text, "'") || strings.HasPrefix(text, `"`):
+		s, ok := gvariantUnquote(text)
+		if !ok || strings.HasPrefix(s, "[") || strings.HasPrefix(s, "(") {
+			return nil, false
+		}
+		return s, true
+	case strings.HasPrefix(text, "["), strings.HasPrefix(text, "("):
+		if strings.Contains(text, "<") || strings.Contains(text, "{") {
+			return nil, false
+		}
+		return text, true
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+var gvariantTypes = map[string]bool{
+	"byte": true, "int16": true, "uint16": true, "int32": true, "uint32": true,
+	"int64": true, "uint64": true, "handle": true, "double": true,
+}
+
+// stripGVariantType drops a type annotation ("uint32 5", "@as []"): dconf
+// prints them and gsettings does not, and the schema fixes the type on
+// write.
+func stripGVariantType(text string) string {
+	text = strings.TrimSpace(text)
+	if fields := strings.SplitN(text, " ", 2); len(fields) == 2 && (gvariantTypes[fields[0]] || strings.HasPrefix(fields[0], "@")) {
+		return fields[1]
+	}
+	return text
+}
+
+// gvariantUnquote reads one quoted GVariant string, undoing its escapes.
+func gvariantUnquote(text string) (string, bool) {
+	quote := text[0]
+	if len(text) < 2 || text[len(text)-1] != quote {
+		return "", false
+	}
+	var b strings.Builder
+	body := text[1 : len(text)-1]
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '\\' && i+1 < len(body) {
+			i++
+			c = body[i]
+		} else if c == quote {
+			return "", false // two strings, not one
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), true
+}
+
+// macOSBaseline is the snapshot a capture compares against: the defaults
+// keys people set on every new Mac, with the value a fresh install has, as
+// `defaults read` prints it. An empty baseline is a key a fresh install
+// leaves unset. Scanning every domain would capture thousands of keys the
+// system writes on its own.
+var macOSBaseline = []struct{ domain, key, baseline string }{
+	{"NSGlobalDomain", "AppleInterfaceStyle", ""},
+	{"NSGlobalDomain", "AppleShowAllExtensions", "0"},
+	{"NSGlobalDomain", "ApplePressAndHoldEnabled", "1"},
+	{"NSGlobalDomain", "InitialKeyRepeat", "25"},
+	{"NSGlobalDomain", "KeyRepeat", "6"},
+	{"NSGlobalDomain", "NSAutomaticSpellingCorrectionEnabled", "1"},
+	{"NSGlobalDomain", "NSAutomaticCapitalizationEnabled", "1"},
+	{"NSGlobalDomain", "NSAutomaticQuoteSubstitutionEnabled", "1"},
+	{"NSGlobalDomain", "NSAutomaticDashSubstitutionEnabled", "1"},
+	{"NSGlobalDomain", "com.apple.swipescrolldirection", "1"},
+	{"com.apple.dock", "autohide", "0"},
+	{"com.apple.dock", "orientation", "bottom"},
+	{"com.apple.dock", "tilesize", "48"},
+	{"com.apple.dock", "show-recents", "1"},
+	{"com.apple.dock", "mru-spaces", "1"},
+	{"com.apple.finder", "AppleShowAllFiles", "0"},
+	{"com.apple.finder", "ShowPathbar", "0"},
+	{"com.apple.finder", "ShowStatusBar", "0"},
+	{"com.apple.finder", "FXPreferredViewStyle", "icnv"},
+	{"com.apple.finder", "_FXShowPosixPathInTitle", "0"},
+	{"com.apple.screencapture", "location", ""},
+	{"com.apple.screencapture", "type", "png"},
+	{"com.apple.AppleMultitouchTrackpad", "Clicking", "0"},
+}
+
+// defaultsKinds maps `defaults read-type` to a blueprint kind. Plist types
+// (array, dictionary, date, data) are not in it: they cannot be read back.
+var defaultsKinds = map[string]string{
+	"boolean": "bool",
+	"integer": "int",
+	"float":   "float",
+	"string":  "string",
+}
+
+func macOSDefaults() []SettingResult {
+	var results []SettingResult
+	for _, setting := range macOSBaseline {
+		typeOut, err := output("defaults", "read-type", setting.domain, setting.key)
+		if err != nil {
+			continue // unset: the default applies
+		}
+		kind, ok := defaultsKinds[strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(typeOut)), "Type is"))]
+		if !ok {
+			continue
+		}
+		valueOut, err := output("defaults", "read", setting.domain, setting.key)
+		if err != nil {
+			continue
+		}
+		text := strings.TrimSpace(string(valueOut))
+		if text == setting.baseline {
+			continue // set, but to what a fresh install has
+		}
+		value, ok := defaultsValue(kind, text)
+		if !ok {
+			continue
+		}
+		results = append(results, SettingResult{
+			Tool:    types.ConfigurationToolMacOSDefaults,
+			Domain:  setting.domain,
+			Key:     setting.key,
+			Kind:    kind,
+			Value:   value,
+			Default: setting.baseline,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Domain < results[j].Domain })
+	return results
+}
+
+// defaultsValue converts `defaults read` output to a blueprint value of the
+// given kind; booleans print as 1 and 0.
+func defaultsValue(kind, text string) (interface{}, bool) {
+	switch kind {
+	case "bool":
+		return text == "1", text == "1" || text == "0"
+	case "int":
+		n, err := strconv.ParseInt(text, 10, 64)
+		return n, err == nil
+	case "float":
+		f, err := strconv.ParseFloat(text, 64)
+		return f, err == nil
+	}
+	return text, true
+}
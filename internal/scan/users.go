@@ -0,0 +1,150 @@
+package scan
+
+import (
+	"os"
+	"os/user"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UserResult is one local account the operator created.
+type UserResult struct {
+	Name    string
+	UID     string
+	Comment string
+	Shell   string
+	Home    string
+	Groups  []string // supplementary groups
+}
+
+// GroupResult is one local group the operator created.
+type GroupResult struct {
+	Name string
+	GID  string
+}
+
+// The account databases; variables so tests can point them at a temporary
+// tree.
+var (
+	passwdFile    = "/etc/passwd"
+	groupFile     = "/etc/group"
+	loginDefsFile = "/etc/login.defs"
+)
+
+// nobodyID is the overflow ID: inside the regular range on most
+// distributions, and never anyone's account.
+const nobodyID = 65534
+
+// Users reports the regular accounts and groups on a Linux machine - those
+// in login.defs' UID_MIN..UID_MAX range - other than the one running the
+// scan, which a blueprint cannot create for itself. A group that is only
+// some user's private group is left out: creating the user creates it.
+func Users() ([]UserResult, []GroupResult) {
+	if runtime.GOOS != "linux" {
+		return nil, nil
+	}
+	passwd, err := os.ReadFile(passwdFile)
+	if err != nil {
+		return nil, nil
+	}
+	groups, err := os.ReadFile(groupFile)
+	if err != nil {
+		return nil, nil
+	}
+	defs, _ := os.ReadFile(loginDefsFile) //nolint:errcheck // missing login.defs means the shadow-utils defaults
+	self := ""
+	if current, err := user.Current(); err == nil {
+		self = current.Username
+	}
+	return parseAccounts(string(passwd), string(groups), string(defs), self)
+}
+
+// parseAccounts is Users over the file contents.
+func parseAccounts(passwd, group, loginDefs, self string) ([]UserResult, []GroupResult) {
+	low, high := idRange(loginDefs, "UID_MIN", "UID_MAX")
+	gidLow, gidHigh := idRange(loginDefs, "GID_MIN", "GID_MAX")
+	regular := func(id string, low, high int) bool {
+		n, err := strconv.Atoi(id)
+		return err == nil && n >= low && n <= high && n != nobodyID
+	}
+
+	// primary maps a GID to the user whose primary group it is.
+	primary := map[string]string{}
+	var users []UserResult
+	for _, fields := range accountLines(passwd, 7) {
+		primary[fields[3]] = fields[0]
+		if fields[0] == self || !regular(fields[2], low, high) {
+			continue
+		}
+		users = append(users, UserResult{
+			Name:    fields[0],
+			UID:     fields[2],
+			Comment: strings.Split(fields[4], ",")[0],
+			Home:    fields[5],
+			Shell:   fields[6],
+		})
+	}
+
+	byName := map[string]int{}
+	for i, u := range users {
+		byName[u.Name] = i
+	}
+	var groups []GroupResult
+	for _, fields := range accountLines(group, 4) {
+		name, gid, members := fields[0], fields[2], fields[3]
+		for _, member := range strings.Split(members, ",") {
+			if i, ok := byName[member]; ok {
+				users[i].Groups = append(users[i].Groups, name)
+			}
+		}
+		// A user-private group shares its owner's name and is their primary
+		// group; the owner's own group is left out with the owner.
+		if owner := primary[gid]; owner == name || name == self || !regular(gid, gidLow, gidHigh) {
+			continue
+		}
+		groups = append(groups, GroupResult{Name: name, GID: gid})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return users, groups
+}
+
+// accountLines splits a colon-separated account database into the lines
+// with at least n fields.
+func accountLines(content string, n int) [][]string {
+	var lines [][]string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if fields := strings.Split(line, ":"); len(fields) >= n {
+			lines = append(lines, fields)
+		}
+	}
+	return lines
+}
+
+// idRange reads a MIN/MAX pair from login.defs, falling back to the
+// shadow-utils defaults.
+func idRange(loginDefs, minKey, maxKey string) (int, int) {
+	low, high := 1000, 60000
+	for _, line := range strings.Split(loginDefs, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case minKey:
+			low = n
+		case maxKey:
+			high = n
+		}
+	}
+	return low, high
+}
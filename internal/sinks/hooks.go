@@ -0,0 +1,75 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"charm.land/log/v2"
+)
+
+// hookTimeout bounds each hook command.
+const hookTimeout = 2 * time.Minute
+
+// runHooks runs each command with the summary as JSON on stdin and in
+// RWR_HOOK_* variables. Hooks run after the run, under their own timeout
+// rather than the run's context: an interrupted run is exactly when
+// on_failure has something to say.
+func runHooks(name string, commands []string, summary Summary) {
+	if len(commands) == 0 {
+		return
+	}
+	input, err := json.Marshal(summary)
+	if err != nil {
+		log.Warnf("Encoding the run summary for %s hooks: %v", name, err)
+		return
+	}
+	env := append(os.Environ(),
+		"RWR_HOOK="+name,
+		"RWR_HOOK_STATUS="+summary.Status,
+		"RWR_HOOK_FAILURES="+strconv.Itoa(len(summary.Failures)),
+		"RWR_HOOK_DURATION_SECONDS="+strconv.FormatFloat(summary.Duration, 'f', 3, 64),
+		"RWR_HOOK_DRY_RUN="+strconv.FormatBool(summary.DryRun),
+	)
+	for _, command := range commands {
+		if err := runHook(command, env, input); err != nil {
+			log.Warnf("%s hook %q: %v", name, command, err)
+		}
+	}
+}
+
+func runHook(command string, env []string, input []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, command) // #nosec G204 -- the operator's own config.yaml
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(input)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	log.Debugf("Running hook: %s", command)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", hookTimeout)
+		}
+		if text := bytes.TrimSpace(output.Bytes()); len(text) > 0 {
+			return fmt.Errorf("%w: %s", err, text)
+		}
+		return err
+	}
+	if text := bytes.TrimSpace(output.Bytes()); len(text) > 0 {
+		log.Info(string(text), "hook", command)
+	}
+	return nil
+}
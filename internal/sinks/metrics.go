@@ -0,0 +1,73 @@
+package sinks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeTextfile writes the run as OpenMetrics text for node_exporter's
+// textfile collector. Every metric describes the last run, so all are
+// gauges. The file is replaced by rename: the collector must never scrape
+// half of it.
+func writeTextfile(path string, s Summary) error {
+	var b strings.Builder
+	gauge := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	boolValue := func(v bool) int {
+		if v {
+			return 1
+		}
+		return 0
+	}
+
+	gauge("rwr_last_run_timestamp_seconds", "When the last rwr run finished.")
+	fmt.Fprintf(&b, "rwr_last_run_timestamp_seconds %.3f\n", float64(s.Finished.UnixMilli())/1000)
+	gauge("rwr_last_run_duration_seconds", "How long the last rwr run took.")
+	fmt.Fprintf(&b, "rwr_last_run_duration_seconds %.3f\n", s.Duration)
+	gauge("rwr_last_run_success", "Whether the last rwr run finished without failures.")
+	fmt.Fprintf(&b, "rwr_last_run_success %d\n", boolValue(s.Status == "ok"))
+	gauge("rwr_last_run_failures", "Failed steps in the last rwr run.")
+	fmt.Fprintf(&b, "rwr_last_run_failures %d\n", len(s.Failures))
+
+	if len(s.Processors) > 0 {
+		gauge("rwr_processor_duration_seconds", "How long each processor took in the last rwr run.")
+		for _, p := range s.Processors {
+			fmt.Fprintf(&b, "rwr_processor_duration_seconds{processor=%q} %.3f\n", p.Processor, p.Duration)
+		}
+		gauge("rwr_processor_success", "Whether each processor finished without failures in the last rwr run.")
+		for _, p := range s.Processors {
+			fmt.Fprintf(&b, "rwr_processor_success{processor=%q} %d\n", p.Processor, boolValue(p.Status == "ok"))
+		}
+		gauge("rwr_resources", "Resources the last rwr run handled, by processor and outcome.")
+		for _, p := range s.Processors {
+			for _, status := range statusKeys(p.Resources) {
+				fmt.Fprintf(&b, "rwr_resources{processor=%q,status=%q} %d\n", p.Processor, status, p.Resources[status])
+			}
+		}
+	}
+	b.WriteString("# EOF\n")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { // #nosec G301 -- the collector reads this directory
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rwr-metrics-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// node_exporter usually runs as its own user.
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil { // #nosec G302 -- metrics are meant to be read
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,216 @@
+// Package sinks sends a run's events where config.yaml says: hook commands
+// when it finishes, JSON POSTs to webhooks, and an OpenMetrics textfile for
+// node_exporter. A Run observes the reporting stream alongside the dashboard
+// or the log (reporting.Observe) and does its work at Finish, so nothing
+// here can slow or fail the run it records.
+package sinks
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// Run records one run for the configured sinks.
+type Run struct {
+	config  types.Sinks
+	dryRun  bool
+	host    string
+	started time.Time
+
+	mu         sync.Mutex
+	processors map[string]*processorStats
+	order      []string
+	errs       []types.StepError
+	sawFinish  bool
+	webhooks   []*webhook
+}
+
+// processorStats is one processor's part of the run.
+type processorStats struct {
+	Processor string         `json:"processor"`
+	Status    string         `json:"status"`
+	Duration  float64        `json:"duration_seconds"`
+	Error     string         `json:"error,omitempty"`
+	Resources map[string]int `json:"resources,omitempty"`
+}
+
+// Start begins recording a run. Nothing configured is a nil Run, whose
+// methods do nothing.
+func Start(config types.Sinks, dryRun bool) *Run {
+	if config.Empty() {
+		return nil
+	}
+	host, _ := os.Hostname()
+	r := &Run{
+		config:     config,
+		dryRun:     dryRun,
+		host:       host,
+		started:    time.Now(),
+		processors: map[string]*processorStats{},
+	}
+	for _, hook := range config.Webhooks {
+		r.webhooks = append(r.webhooks, startWebhook(hook))
+	}
+	return r
+}
+
+// Emit implements reporting.Reporter, as an observer.
+func (r *Run) Emit(event reporting.Event) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e := event.(type) {
+	case reporting.ProcStarted:
+		r.processor(e.Processor).Status = "running"
+		r.post("proc_started", map[string]any{"processor": e.Processor, "files": e.Files})
+	case reporting.ProcFinished:
+		stats := r.processor(e.Processor)
+		stats.Status, stats.Duration = "ok", e.Dur.Seconds()
+		if e.Err != nil {
+			stats.Status, stats.Error = "failed", e.Err.Error()
+		}
+		r.post("proc_finished", map[string]any{"processor": e.Processor, "status": stats.Status, "duration_seconds": stats.Duration, "error": stats.Error})
+	case reporting.ProcSkipped:
+		r.processor(e.Processor).Status = "skipped"
+		r.post("proc_skipped", map[string]any{"processor": e.Processor, "reason": e.Reason})
+	case reporting.ResourceDone:
+		stats := r.processor(e.Resource.Processor)
+		if stats.Resources == nil {
+			stats.Resources = map[string]int{}
+		}
+		stats.Resources[string(e.Resource.Status)]++
+		r.post("resource", map[string]any{
+			"processor": e.Resource.Processor, "provider": e.Resource.Provider, "name": e.Resource.Name,
+			"action": e.Resource.Action, "status": e.Resource.Status, "detail": e.Resource.Detail,
+		})
+	case reporting.ResourceRetry:
+		r.post("resource_retry", map[string]any{
+			"processor": e.Resource.Processor, "name": e.Resource.Name, "action": e.Resource.Action,
+			"detail": e.Resource.Detail, "attempt": e.Attempt, "attempts": e.Attempts, "delay_seconds": e.Delay.Seconds(),
+		})
+	case reporting.RunFinished:
+		// The dashboard runner re-emits RunFinished after the run returns;
+		// the first is the run's own.
+		if !r.sawFinish {
+			r.sawFinish = true
+			r.errs = e.Errs
+		}
+	}
+}
+
+func (r *Run) processor(name string) *processorStats {
+	stats, ok := r.processors[name]
+	if !ok {
+		stats = &processorStats{Processor: name}
+		r.processors[name] = stats
+		r.order = append(r.order, name)
+	}
+	return stats
+}
+
+// Finish ends the run: it posts run_finished, writes the metrics, runs the
+// hooks, and waits for the webhooks to drain. runErr is the run's own
+// error, which counts as a failure when the run died before reporting one.
+// Sink failures are logged, never returned: the run already happened.
+func (r *Run) Finish(runErr error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	errs := r.errs
+	if runErr != nil && len(errs) == 0 {
+		errs = []types.StepError{{Processor: "run", Err: runErr}}
+	}
+	summary := r.summary(errs)
+	r.post("run_finished", summary)
+	r.mu.Unlock()
+
+	if r.config.Metrics.Textfile != "" {
+		if r.dryRun {
+			log.Debugf("Dry run: not writing metrics to %s", r.config.Metrics.Textfile)
+		} else if err := writeTextfile(r.config.Metrics.Textfile, summary); err != nil {
+			log.Warnf("Writing metrics to %s: %v", r.config.Metrics.Textfile, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		runHooks("on_failure", r.config.Hooks.OnFailure, summary)
+	}
+	runHooks("on_run_finished", r.config.Hooks.OnRunFinished, summary)
+
+	for _, hook := range r.webhooks {
+		hook.close()
+	}
+}
+
+// Summary is the run_finished payload: what hooks read on stdin and webhooks
+// receive.
+type Summary struct {
+	Status     string            `json:"status"`
+	Host       string            `json:"host"`
+	DryRun     bool              `json:"dry_run"`
+	Started    time.Time         `json:"started"`
+	Finished   time.Time         `json:"finished"`
+	Duration   float64           `json:"duration_seconds"`
+	Failures   []Failure         `json:"failures,omitempty"`
+	Processors []*processorStats `json:"processors,omitempty"`
+}
+
+// Failure is one failed step.
+type Failure struct {
+	Processor string `json:"processor"`
+	Error     string `json:"error"`
+}
+
+func (r *Run) summary(errs []types.StepError) Summary {
+	finished := time.Now()
+	s := Summary{
+		Status:   "ok",
+		Host:     r.host,
+		DryRun:   r.dryRun,
+		Started:  r.started,
+		Finished: finished,
+		Duration: finished.Sub(r.started).Seconds(),
+	}
+	for _, stepErr := range errs {
+		message := ""
+		if stepErr.Err != nil {
+			message = stepErr.Err.Error()
+		}
+		s.Failures = append(s.Failures, Failure{Processor: stepErr.Processor, Error: message})
+	}
+	if len(s.Failures) > 0 {
+		s.Status = "failed"
+	}
+	for _, name := range r.order {
+		stats := *r.processors[name]
+		if stats.Resources != nil {
+			resources := make(map[string]int, len(stats.Resources))
+			for status, count := range stats.Resources {
+				resources[status] = count
+			}
+			stats.Resources = resources
+		}
+		s.Processors = append(s.Processors, &stats)
+	}
+	return s
+}
+
+// statusKeys lists a resource count map's statuses in a stable order.
+func statusKeys(resources map[string]int) []string {
+	keys := make([]string, 0, len(resources))
+	for status := range resources {
+		keys = append(keys, status)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,195 @@
+package sinks
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fynxlabs/rwr/internal/reporting"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// emitRun feeds a run with one good and one failed processor.
+func emitRun(r *Run) {
+	r.Emit(reporting.ProcStarted{Processor: "packages"})
+	r.Emit(reporting.ResourceDone{Resource: types.Resource{Processor: "packages", Name: "jq", Status: types.StatusOK}})
+	r.Emit(reporting.ResourceDone{Resource: types.Resource{Processor: "packages", Name: "fd", Status: types.StatusOK}})
+	r.Emit(reporting.ProcFinished{Processor: "packages", Dur: 2 * time.Second})
+	r.Emit(reporting.ProcStarted{Processor: "services"})
+	r.Emit(reporting.ResourceDone{Resource: types.Resource{Processor: "services", Name: "sshd", Status: types.StatusFailed}})
+	r.Emit(reporting.ProcFinished{Processor: "services", Dur: time.Second, Err: errors.New("sshd: exit 1")})
+	r.Emit(reporting.RunFinished{Errs: []types.StepError{{Processor: "services", Err: errors.New("sshd: exit 1")}}})
+	// The dashboard's second RunFinished must not replace the first.
+	r.Emit(reporting.RunFinished{})
+}
+
+func TestStart_EmptyIsNil(t *testing.T) {
+	r := Start(types.Sinks{}, false)
+	if r != nil {
+		t.Fatal("no sinks configured should start nothing")
+	}
+	r.Emit(reporting.RunFinished{})
+	r.Finish(nil)
+}
+
+func TestTextfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "textfile", "rwr.prom")
+	r := Start(types.Sinks{Metrics: types.Metrics{Textfile: path}}, false)
+	emitRun(r)
+	r.Finish(nil)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, want := range []string{
+		"# TYPE rwr_last_run_success gauge\nrwr_last_run_success 0\n",
+		"rwr_last_run_failures 1\n",
+		`rwr_processor_duration_seconds{processor="packages"} 2.000` + "\n",
+		`rwr_processor_success{processor="services"} 0` + "\n",
+		`rwr_resources{processor="packages",status="ok"} 2` + "\n",
+		`rwr_resources{processor="services",status="failed"} 1` + "\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("textfile missing %q:\n%s", want, text)
+		}
+	}
+	if !strings.HasSuffix(text, "# EOF\n") {
+		t.Errorf("textfile must end with # EOF:\n%s", text)
+	}
+}
+
+func TestTextfile_NotOnDryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rwr.prom")
+	r := Start(types.Sinks{Metrics: types.Metrics{Textfile: path}}, true)
+	r.Finish(nil)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote metrics: %v", err)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+	dir := t.TempDir()
+	finished := filepath.Join(dir, "finished")
+	failed := filepath.Join(dir, "failed")
+	r := Start(types.Sinks{Hooks: types.Hooks{
+		OnRunFinished: []string{`{ echo "$RWR_HOOK $RWR_HOOK_STATUS $RWR_HOOK_FAILURES"; cat; } > ` + finished},
+		OnFailure:     []string{`echo "$RWR_HOOK" > ` + failed},
+	}}, false)
+	emitRun(r)
+	r.Finish(nil)
+
+	data, err := os.ReadFile(finished)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, body, _ := strings.Cut(string(data), "\n")
+	if header != "on_run_finished failed 1" {
+		t.Errorf("hook env = %q", header)
+	}
+	var summary Summary
+	if err := json.Unmarshal([]byte(body), &summary); err != nil {
+		t.Fatalf("hook stdin is not the summary: %v\n%s", err, body)
+	}
+	if len(summary.Failures) != 1 || summary.Failures[0].Processor != "services" || len(summary.Processors) != 2 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if data, err := os.ReadFile(failed); err != nil || strings.TrimSpace(string(data)) != "on_failure" {
+		t.Errorf("on_failure hook: %q, %v", data, err)
+	}
+}
+
+// A run that dies before reporting still fails, and still runs on_failure.
+func TestHooks_RunErrorWithoutRunFinished(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+	failed := filepath.Join(t.TempDir(), "failed")
+	r := Start(types.Sinks{Hooks: types.Hooks{OnFailure: []string{"cat > " + failed}}}, false)
+	r.Finish(errors.New("context canceled"))
+
+	data, err := os.ReadFile(failed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"error":"context canceled"`) {
+		t.Errorf("summary = %s", data)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []map[string]any
+		auth   []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var event map[string]any
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("body is not JSON: %s", body)
+		}
+		mu.Lock()
+		events = append(events, event)
+		auth = append(auth, req.Header.Get("Authorization"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	r := Start(types.Sinks{Webhooks: []types.Webhook{
+		{URL: server.URL, Events: []string{"proc_finished", "run_finished"}, Headers: map[string]string{"Authorization": "Bearer t"}},
+	}}, false)
+	emitRun(r)
+	r.Finish(nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for _, event := range events {
+		names = append(names, event["event"].(string))
+	}
+	if got := strings.Join(names, ","); got != "proc_finished,proc_finished,run_finished" {
+		t.Fatalf("events = %s", got)
+	}
+	last := events[len(events)-1]
+	if last["status"] != "failed" || last["host"] == nil {
+		t.Errorf("run_finished = %v", last)
+	}
+	for _, header := range auth {
+		if header != "Bearer t" {
+			t.Errorf("Authorization = %q", header)
+		}
+	}
+}
+
+// A webhook that fails is logged; it does not fail the run or stop the
+// others.
+func TestWebhook_FailureIsNotFatal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "rwr.prom")
+
+	r := Start(types.Sinks{
+		Webhooks: []types.Webhook{{URL: server.URL}},
+		Metrics:  types.Metrics{Textfile: path},
+	}, false)
+	r.Finish(nil)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("metrics not written: %v", err)
+	}
+}
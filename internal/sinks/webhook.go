@@ -0,0 +1,121 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// webhookTimeout bounds each POST, and webhookDrain how long Finish waits
+// for queued posts: a dead endpoint must not hold the run's exit hostage.
+const (
+	webhookTimeout = 10 * time.Second
+	webhookDrain   = 30 * time.Second
+	webhookQueue   = 256
+)
+
+// webhook posts one endpoint's events in order, off the run's goroutine.
+type webhook struct {
+	config types.Webhook
+	queue  chan []byte
+	done   chan struct{}
+}
+
+func startWebhook(config types.Webhook) *webhook {
+	if len(config.Events) == 0 {
+		config.Events = []string{"run_finished"}
+	}
+	w := &webhook{config: config, queue: make(chan []byte, webhookQueue), done: make(chan struct{})}
+	go w.send()
+	return w
+}
+
+// post queues an event for every webhook that subscribed to it. A full
+// queue drops the event rather than stall the run. Callers hold r.mu.
+func (r *Run) post(event string, payload any) {
+	var body []byte
+	for _, hook := range r.webhooks {
+		if !slices.Contains(hook.config.Events, event) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = encodeEvent(event, r, payload); err != nil {
+				log.Debugf("encoding %s for webhooks: %v", event, err)
+				return
+			}
+		}
+		select {
+		case hook.queue <- body:
+		default:
+			log.Debugf("Webhook %s is behind; dropped a %s event", hook.config.URL, event)
+		}
+	}
+}
+
+// encodeEvent wraps a payload with the fields every event carries.
+func encodeEvent(event string, r *Run, payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["event"] = event
+	fields["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	fields["host"] = r.host
+	fields["dry_run"] = r.dryRun
+	return json.Marshal(fields)
+}
+
+func (w *webhook) send() {
+	defer close(w.done)
+	client := system.NewHTTPClient(webhookTimeout)
+	for body := range w.queue {
+		if err := w.postOne(client, body); err != nil {
+			log.Warnf("Webhook %s: %v", w.config.URL, err)
+		}
+	}
+}
+
+func (w *webhook) postOne(client *http.Client, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rwr")
+	for key, value := range w.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// close stops queueing and waits for what is queued to be sent.
+func (w *webhook) close() {
+	close(w.queue)
+	select {
+	case <-w.done:
+	case <-time.After(webhookDrain):
+		log.Warnf("Webhook %s: gave up on %d unsent event(s)", w.config.URL, len(w.queue))
+	}
+}
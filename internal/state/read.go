@@ -25,6 +25,7 @@ func Applies(configDir string) ([]Entry, error) {
 	// that came before it: apply, uninstall, re-apply leaves the file on disk
 	// and the record has to say so.
 	reversedAt := map[string]int{}
+	reversals := map[string][]int{}
 	position := 0
 
 	legacy, err := legacyEntries(configDir)
@@ -65,7 +66,9 @@ func Applies(configDir string) ([]Entry, error) {
 					applyPos = append(applyPos, position)
 				}
 			case "reverse":
-				reversedAt[Key(event.Processor, event.Identity)] = position
+				key := Key(event.Processor, event.Identity)
+				reversedAt[key] = position
+				reversals[key] = append(reversals[key], position)
 			}
 		}
 	} else if !os.IsNotExist(err) {
@@ -76,12 +79,20 @@ func Applies(configDir string) ([]Entry, error) {
 	// its guard values (a file's sha256) are the ones consumers see - the
 	// hash of the content actually on disk after the most recent apply,
 	// which is what a hash-guarded delete needs.
+	//
+	// The state before rwr is the exception: it comes from the first apply
+	// since the unit was last reversed (see originKeys).
 	latest := map[string]int{}
+	origin := map[string]int{}
 	var order []string
 	for i, entry := range entries {
 		key := Key(entry.Processor, entry.Identity)
-		if _, seen := latest[key]; !seen {
+		previous, seen := latest[key]
+		if !seen {
 			order = append(order, key)
+			origin[key] = i
+		} else if reversedBetween(reversals[key], applyPos[previous], applyPos[i]) {
+			origin[key] = i
 		}
 		latest[key] = i
 	}
@@ -89,6 +100,9 @@ func Applies(configDir string) ([]Entry, error) {
 	for _, key := range order {
 		index := latest[key]
 		entry := entries[index]
+		if first := origin[key]; first != index {
+			entry.Identity = withOrigin(entry.Identity, entries[first].Identity)
+		}
 		// Reversed only when the reversal came after the apply being kept.
 		// Treating any reversal as cancelling the identity forever meant
 		// `rwr all`, `rwr uninstall`, `rwr all` left every re-applied unit
@@ -131,7 +145,53 @@ func Unreversed(configDir string) ([]Entry, error) {
 // per content version, `rwr uninstall` planned N deletes for one path (N-1
 // reporting "already absent"), and `rwr status` could report a live file as
 // stale.
-var guardKeys = map[string]bool{"sha256": true}
+//
+// The value a configuration key was set to, and the value (or login shell)
+// an apply replaced, are guards for the same reason: setting the same key a
+// second time is the same unit. What it replaced is kept from the first of
+// those applies, not the latest (see originKeys).
+var guardKeys = map[string]bool{
+	"sha256":         true,
+	"value":          true,
+	"previous":       true,
+	"previous_set":   true,
+	"previous_shell": true,
+}
+
+// originKeys are the guard keys that record what an apply replaced. Folded,
+// they come from the earliest apply rather than the latest: after a key goes
+// A -> B -> C the latest apply replaced B, rwr's own earlier value, and
+// uninstall restoring that would leave the machine on a value nobody had
+// before rwr. A reversal puts the original back, so the next apply starts
+// again from what it replaced.
+var originKeys = []string{"previous", "previous_set", "previous_shell"}
+
+// withOrigin is identity with its originKeys taken from first.
+func withOrigin(identity, first map[string]string) map[string]string {
+	merged := make(map[string]string, len(identity))
+	for k, v := range identity {
+		merged[k] = v
+	}
+	for _, k := range originKeys {
+		if v, ok := first[k]; ok {
+			merged[k] = v
+		} else {
+			delete(merged, k)
+		}
+	}
+	return merged
+}
+
+// reversedBetween reports whether any of a unit's reversals lies between two
+// of its applies.
+func reversedBetween(positions []int, after, before int) bool {
+	for _, pos := range positions {
+		if pos > after && pos < before {
+			return true
+		}
+	}
+	return false
+}
 
 // Key renders the identifying part of an identity deterministically, so the
 // same unit keys the same way across runs and across a reversal.
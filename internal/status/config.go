@@ -0,0 +1,388 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// ConfigQuery is how a configuration read reaches the machine. Status runs the
+// commands itself; the configuration processor passes its executor, so apply
+// and status read a key the same way and tests can answer for both.
+type ConfigQuery struct {
+	// Run executes a read-only command and returns its stdout. Only Exec,
+	// Args and Variables are read; a status query never elevates.
+	Run func(cmd types.Command) (string, error)
+	// Have reports whether a binary is on PATH.
+	Have func(name string) bool
+}
+
+// LocalConfigQuery runs the queries directly, unelevated.
+func LocalConfigQuery() ConfigQuery {
+	return ConfigQuery{
+		Run: func(cmd types.Command) (string, error) {
+			query := exec.Command(cmd.Exec, cmd.Args...) // #nosec G204 -- argv-exec'd read-only query built by ReadConfigValue
+			if len(cmd.Variables) > 0 {
+				query.Env = os.Environ()
+				for name, value := range cmd.Variables {
+					query.Env = append(query.Env, name+"="+value)
+				}
+			}
+			out, err := query.Output()
+			return string(out), err
+		},
+		Have: func(name string) bool {
+			_, err := exec.LookPath(name)
+			return err == nil
+		},
+	}
+}
+
+// KDEConfigTools names the kreadconfig/kwriteconfig pair to use: Plasma 6's
+// when present, otherwise Plasma 5's.
+func KDEConfigTools(have func(string) bool) (read, write string, ok bool) {
+	for _, version := range []string{"6", "5"} {
+		if have("kwriteconfig" + version) {
+			return "kreadconfig" + version, "kwriteconfig" + version, true
+		}
+	}
+	return "", "", false
+}
+
+// registryReadScript prints a registry value the way the processor's write
+// scripts take it: binary as comma-separated bytes, everything else as text.
+// It reads the same environment variables they do, so nothing from a
+// blueprint is parsed as PowerShell, and exits 1 for a missing value.
+const registryReadScript = `$ErrorActionPreference = 'Stop'
+$v = (Get-Item -LiteralPath $env:RWR_REGISTRY_PATH).GetValue($env:RWR_REGISTRY_NAME, $null, 'DoNotExpandEnvironmentNames')
+if ($null -eq $v) { exit 1 }
+if ($v -is [byte[]]) { $v -join ',' } else { [string]$v }`
+
+// ReadConfigValue reads the current value of one configuration key. set is
+// false when the key is absent, which is a value in its own right: it is
+// what uninstall restores by deleting the key again.
+//
+// A gsettings entry is read one key at a time (config.Key names it), and a
+// dconf read takes the key's full path in config.Key.
+func ReadConfigValue(config types.Configuration, query ConfigQuery) (value string, set bool, err error) {
+	run := func(exec string, args ...string) (string, error) {
+		return query.Run(types.Command{Exec: exec, Args: args})
+	}
+	switch config.Tool {
+	case types.ConfigurationToolKDE:
+		read, _, ok := KDEConfigTools(query.Have)
+		if !ok {
+			return "", false, errors.New("neither kwriteconfig6 nor kwriteconfig5 is installed")
+		}
+		// kreadconfig prints an empty line for a missing key and cannot tell
+		// it apart from an empty value; both read as unset.
+		out, err := run(read, "--file", config.File, "--group", config.Group, "--key", config.Key)
+		if err != nil {
+			return "", false, fmt.Errorf("reading %s: %v", config.Key, err)
+		}
+		value = strings.TrimRight(out, "\r\n")
+		return value, value != "", nil
+	case types.ConfigurationToolXfconf:
+		if !query.Have("xfconf-query") {
+			return "", false, errors.New("xfconf-query is not installed")
+		}
+		// xfconf-query exits non-zero for a property the channel does not have.
+		out, err := run("xfconf-query", "--channel", config.Channel, "--property", config.Property)
+		if err != nil {
+			return "", false, nil //nolint:nilerr // a missing property is an unset key, not a failure
+		}
+		return strings.TrimRight(out, "\r\n"), true, nil
+	case types.ConfigurationToolINI:
+		data, err := os.ReadFile(system.ExpandPath(config.File)) // #nosec G304 -- the file the blueprint manages
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		value, set = helpers.INIValue(string(data), config.Section, config.Key)
+		return value, set, nil
+	case types.ConfigurationToolDconf:
+		if !query.Have("dconf") {
+			return "", false, errors.New("dconf is not installed")
+		}
+		// dconf read prints nothing for a key that holds no user value.
+		out, err := run("dconf", "read", config.Key)
+		if err != nil {
+			return "", false, fmt.Errorf("reading %s: %v", config.Key, err)
+		}
+		value = strings.TrimSpace(out)
+		return value, value != "", nil
+	case types.ConfigurationToolGSettings:
+		if !query.Have("gsettings") {
+			return "", false, errors.New("gsettings is not installed")
+		}
+		// A GSettings key always has a value - the schema default if nothing
+		// else - so a failure is a missing schema or key, not an unset one.
+		out, err := run("gsettings", "get", config.Schema, config.Key)
+		if err != nil {
+			return "", false, fmt.Errorf("reading %s %s: %v", config.Schema, config.Key, err)
+		}
+		return strings.TrimSpace(out), true, nil
+	case types.ConfigurationToolMacOSDefaults:
+		if !query.Have("defaults") {
+			return "", false, errors.New("defaults is not available")
+		}
+		// defaults read exits non-zero for a missing domain or key.
+		out, err := run("defaults", "read", DefaultsDomain(config), config.Key)
+		if err != nil {
+			return "", false, nil //nolint:nilerr // a missing key is an unset key, not a failure
+		}
+		return strings.TrimRight(out, "\r\n"), true, nil
+	case types.ConfigurationToolWindowsRegistry:
+		if !query.Have("powershell") {
+			return "", false, errors.New("powershell is not available")
+		}
+		out, err := query.Run(types.Command{
+			Exec: "powershell",
+			Args: []string{"-NoProfile", "-NonInteractive", "-Command", registryReadScript},
+			Variables: map[string]string{
+				"RWR_REGISTRY_PATH": RegistryPath(config),
+				"RWR_REGISTRY_NAME": config.Key,
+			},
+		})
+		if err != nil {
+			return "", false, nil //nolint:nilerr // a missing value or key is unset, not a failure
+		}
+		return strings.TrimRight(out, "\r\n"), true, nil
+	}
+	return "", false, fmt.Errorf("configuration tool %s cannot be read back", config.Tool)
+}
+
+// DefaultsDomain is the domain a macos_defaults entry writes to.
+func DefaultsDomain(config types.Configuration) string {
+	if config.Domain == "" {
+		return "NSGlobalDomain"
+	}
+	return config.Domain
+}
+
+// RegistryPath is the PowerShell path of a windows_registry entry's key.
+// Entries name a path under HKEY_LOCAL_MACHINE.
+func RegistryPath(config types.Configuration) string {
+	return `HKLM:\` + config.Path
+}
+
+// ReadableDefaultsKind reports whether defaults read prints a value of this
+// -kind in a form that compares with the declared one. Arrays, dicts, dates
+// and data print as plists, so those entries are written every run.
+func ReadableDefaultsKind(kind string) bool {
+	switch strings.ToLower(kind) {
+	case "string", "int", "integer", "float", "bool", "boolean":
+		return true
+	}
+	return false
+}
+
+// DconfKey is the full path dconf addresses a keyfile entry by.
+func DconfKey(section, key string) string {
+	return "/" + strings.Trim(section, "/") + "/" + key
+}
+
+// ConfigValueString renders a blueprint value the way the tools print it, so
+// a value read back compares equal to the one declared.
+func ConfigValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// ConfigValueMatches compares a value read back with a declared one as the
+// tool prints it: GVariant text for dconf and gsettings, plain text for the
+// rest.
+func ConfigValueMatches(tool, current, desired string) bool {
+	if tool == types.ConfigurationToolDconf || tool == types.ConfigurationToolGSettings {
+		current, desired = normalizeGVariant(current), normalizeGVariant(desired)
+	}
+	return ConfigValuesEqual(current, desired)
+}
+
+// normalizeGVariant reduces GVariant text to a comparable form. gsettings
+// prints "['a', 'b']" for what a blueprint writes as "['a','b']", a string
+// holding a quote in double quotes, and a type annotation ("uint32 5",
+// "@as []") for values whose type the text alone does not fix.
+func normalizeGVariant(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "@") {
+		if _, rest, ok := strings.Cut(text, " "); ok {
+			text = rest
+		}
+	}
+	for _, annotation := range []string{"byte ", "int16 ", "uint16 ", "int32 ", "uint32 ", "int64 ", "uint64 ", "handle ", "objectpath ", "signature "} {
+		text = strings.TrimPrefix(text, annotation)
+	}
+	if unquoted, ok := gvariantString(text); ok {
+		return unquoted
+	}
+	// Drop whitespace outside string literals.
+	var out strings.Builder
+	var quote rune
+	escaped := false
+	for _, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '\'' || r == '"'):
+			quote = r
+		case quote == 0 && (r == ' ' || r == '\t' || r == '\n'):
+			continue
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// gvariantString unquotes a lone string literal in either quote style.
+func gvariantString(text string) (string, bool) {
+	if len(text) < 2 {
+		return "", false
+	}
+	quote := text[0]
+	if (quote != '\'' && quote != '"') || text[len(text)-1] != quote {
+		return "", false
+	}
+	var out strings.Builder
+	escaped := false
+	for _, r := range text[1 : len(text)-1] {
+		switch {
+		case escaped:
+			out.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == rune(quote):
+			return "", false // more than one literal, e.g. an array or tuple
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String(), true
+}
+
+// ConfigValuesEqual compares a value read back with a declared one. Numbers
+// compare numerically - xfconf prints a double as "1.500000" - and booleans
+// case-insensitively, since KDE files hold "True" as often as "true".
+func ConfigValuesEqual(current, desired string) bool {
+	if current == desired {
+		return true
+	}
+	if a, err := strconv.ParseFloat(current, 64); err == nil {
+		if b, err := strconv.ParseFloat(desired, 64); err == nil {
+			return a == b
+		}
+	}
+	if a, err := strconv.ParseBool(strings.ToLower(current)); err == nil {
+		if b, err := strconv.ParseBool(strings.ToLower(desired)); err == nil {
+			return a == b
+		}
+	}
+	return false
+}
+
+// ConfigChange is one key whose current value differs from the declared one.
+type ConfigChange struct {
+	Key     string
+	Current string
+	Set     bool
+	Desired string
+}
+
+// DconfChanges reads back every key a dconf keyfile declares and returns the
+// ones that differ, in keyfile order.
+func DconfChanges(keyfile string, query ConfigQuery) ([]ConfigChange, error) {
+	var changes []ConfigChange
+	for _, entry := range helpers.INIKeys(keyfile) {
+		key := DconfKey(entry.Section, entry.Key)
+		current, set, err := ReadConfigValue(types.Configuration{Tool: types.ConfigurationToolDconf, Key: key}, query)
+		if err != nil {
+			return nil, err
+		}
+		if set && ConfigValueMatches(types.ConfigurationToolDconf, current, entry.Value) {
+			continue
+		}
+		changes = append(changes, ConfigChange{Key: key, Current: current, Set: set, Desired: entry.Value})
+	}
+	return changes, nil
+}
+
+// configQuery is the read path status uses, a variable so tests need no
+// desktop.
+var configQuery = LocalConfigQuery
+
+// settingState compares a configuration resource's declared value with the
+// machine's. A dconf resource covers every key of its keyfile.
+func settingState(resource types.Resource) (Class, string) {
+	setting := resource.Setting
+	if setting == nil {
+		return UnknownItem, "not queryable"
+	}
+	// An elevated dconf or defaults entry writes root's settings, which an
+	// unelevated read cannot see.
+	if setting.Elevated && (setting.Tool == types.ConfigurationToolDconf || setting.Tool == types.ConfigurationToolMacOSDefaults) {
+		return UnknownItem, "elevated setting; status does not elevate"
+	}
+	query := configQuery()
+
+	if setting.Tool == types.ConfigurationToolDconf {
+		keyfile, err := os.ReadFile(setting.File) // #nosec G304 -- the keyfile the blueprint declares
+		if err != nil {
+			return UnknownItem, "keyfile not readable"
+		}
+		changes, err := DconfChanges(string(keyfile), query)
+		if err != nil {
+			return UnknownItem, err.Error()
+		}
+		switch len(changes) {
+		case 0:
+			return InSync, ""
+		case 1:
+			return ModifiedItem, describeChange(changes[0])
+		}
+		return ModifiedItem, fmt.Sprintf("%d keys differ; first %s", len(changes), describeChange(changes[0]))
+	}
+
+	current, set, err := ReadConfigValue(*setting, query)
+	switch {
+	case err != nil:
+		return UnknownItem, err.Error()
+	case !set:
+		return Missing, "not set"
+	case ConfigValueMatches(setting.Tool, current, resource.Desired):
+		return InSync, current
+	}
+	return ModifiedItem, fmt.Sprintf("is %s, want %s", current, resource.Desired)
+}
+
+func describeChange(change ConfigChange) string {
+	if !change.Set {
+		return fmt.Sprintf("%s is not set, want %s", change.Key, change.Desired)
+	}
+	return fmt.Sprintf("%s is %s, want %s", change.Key, change.Current, change.Desired)
+}
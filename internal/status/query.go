@@ -7,6 +7,7 @@ import (
 	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -90,6 +91,20 @@ func FileState(dest, recordedSHA string) Presence {
 	return Modified
 }
 
+// RunOnceState reads a run_once script's marker: present when it records a
+// run of exactly this content, modified when the script changed since, absent
+// when it never succeeded.
+func RunOnceState(marker, hash string) Presence {
+	data, err := os.ReadFile(marker) // #nosec G304 -- rwr's own run_once directory
+	if err != nil {
+		return Absent
+	}
+	if strings.TrimSpace(string(data)) == hash {
+		return Present
+	}
+	return Modified
+}
+
 // PathPresent is the existence check fonts and git checkouts get.
 func PathPresent(path string) Presence {
 	if path == "" {
@@ -164,3 +179,108 @@ func launchdState(name string) Presence {
 	}
 	return Present
 }
+
+// LoginShell reads an account's login shell read-only: getent on Linux, the
+// UserShell attribute on macOS. "" means it could not be read.
+func LoginShell(name string) string {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return ""
+	}
+	var query *exec.Cmd
+	switch runtime.GOOS {
+	case types.OSLinux:
+		query = exec.Command("getent", "passwd", name) // #nosec G204 -- argv-exec'd read-only query; a leading '-' is refused above
+	case types.OSDarwin:
+		query = exec.Command("dscl", ".", "-read", "/Users/"+name, "UserShell") // #nosec G204 -- argv-exec'd read-only query
+	default:
+		return ""
+	}
+	out, err := query.Output()
+	if err != nil {
+		return ""
+	}
+	return ParseLoginShell(runtime.GOOS, string(out))
+}
+
+// SameShell reports whether an account's login shell current is the declared
+// shell desired. A bare declared name ("zsh") is the path PATH finds it at, or
+// an /etc/shells entry with that base name - not any binary that happens to
+// share it, so /opt/evil/zsh is not zsh. Paths match once symlinks are
+// resolved, so /bin/zsh is /usr/bin/zsh on a merged-/usr system. Status and
+// the shell action both decide "already set" with it.
+func SameShell(current, desired string) bool {
+	if current == "" || desired == "" {
+		return false
+	}
+	if current == desired {
+		return true
+	}
+	actual := resolvedShell(current)
+	for _, candidate := range shellPaths(desired) {
+		if resolvedShell(candidate) == actual {
+			return true
+		}
+	}
+	return false
+}
+
+// shellPaths is every path a declared shell may stand for: itself when it is
+// a path, otherwise where PATH finds it and each /etc/shells entry with that
+// base name.
+func shellPaths(desired string) []string {
+	if filepath.IsAbs(desired) {
+		return []string{desired}
+	}
+	var paths []string
+	if path, err := shellLookPath(desired); err == nil {
+		paths = append(paths, path)
+	}
+	data, err := os.ReadFile(shellsFile) // #nosec G304 -- fixed system path, overridden only by tests
+	if err != nil {
+		return paths
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if filepath.IsAbs(line) && filepath.Base(line) == desired {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// shellLookPath and shellsFile are where a bare shell name is looked up,
+// variables so tests need no installed shell.
+var (
+	shellLookPath = exec.LookPath
+	shellsFile    = "/etc/shells"
+)
+
+// resolvedShell is path with its symlinks resolved, or path itself when it
+// cannot be.
+func resolvedShell(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// ParseLoginShell extracts the shell from a `getent passwd` line (Linux) or a
+// `dscl . -read ... UserShell` record (macOS). The users processor parses its
+// own probe with it, so status and apply agree about what "current" means.
+func ParseLoginShell(goos, out string) string {
+	out = strings.TrimSpace(out)
+	if goos == types.OSDarwin {
+		// "UserShell: /bin/zsh"
+		_, value, ok := strings.Cut(out, "UserShell:")
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(value)
+	}
+	line, _, _ := strings.Cut(out, "\n")
+	fields := strings.Split(line, ":")
+	if len(fields) < 7 {
+		return ""
+	}
+	return strings.TrimSpace(fields[6])
+}
@@ -3,6 +3,7 @@ package status
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -45,7 +46,8 @@ func Rows(plan *types.Plan, applies []state.Entry, querier *Querier) []Row {
 		entry := &applies[i]
 		key := entryStatusKey(*entry)
 		recorded[key] = entry
-		if !entry.Reversed {
+		// An upgrade leaves nothing behind of its own to go stale.
+		if !entry.Reversed && entry.Identity["kind"] != types.ActionUpgrade {
 			unreversed[key] = entry
 		}
 	}
@@ -53,6 +55,11 @@ func Rows(plan *types.Plan, applies []state.Entry, querier *Querier) []Row {
 	var rows []Row
 	seen := map[string]bool{}
 	for _, resource := range plan.Resources {
+		// A `when:` false on this machine is not desired here: it is neither
+		// classified nor counted as still declared.
+		if resource.Status == types.StatusSkipped {
+			continue
+		}
 		key := resourceStatusKey(resource)
 		seen[key] = true
 		rows = append(rows, classify(resource, recorded[key], querier))
@@ -156,15 +163,58 @@ func classify(resource types.Resource, entry *state.Entry, querier *Querier) Row
 		} else {
 			row.Class = Missing
 		}
+	case types.BlueprintTypeUsers:
+		if resource.Action != types.UserActionShell {
+			row.Class, row.Note = UnknownItem, "not queryable"
+			return row
+		}
+		row.Class, row.Note = loginShellState(resource.Name, resource.Desired)
+	case types.BlueprintTypeConfiguration:
+		row.Class, row.Note = settingState(resource)
+	case types.BlueprintTypeScripts:
+		if resource.Marker == "" {
+			row.Class, row.Note = UnknownItem, "not queryable"
+			return row
+		}
+		switch RunOnceState(resource.Marker, resource.Desired) {
+		case Present:
+			row.Class, row.Note = InSync, "run_once: ran"
+		case Modified:
+			row.Class, row.Note = ModifiedItem, "run_once: pending, the script changed since it ran"
+		default:
+			row.Class, row.Note = Missing, "run_once: pending"
+		}
 	default:
-		// scripts, configuration, users, ssh_keys, repositories, fonts: a
-		// query that cannot be honest is worse than none.
+		// ssh_keys, repositories, fonts, and users other than a
+		// login shell: a query that cannot be honest is worse
+		// than none.
 		row.Class, row.Note = UnknownItem, "not queryable"
 	}
 	return row
 }
 
+// loginShellState compares an account's login shell with the declared one,
+// as SameShell does.
+func loginShellState(name, desired string) (Class, string) {
+	current := loginShell(name)
+	if current == "" || desired == "" {
+		return UnknownItem, "login shell not readable"
+	}
+	if SameShell(current, desired) {
+		return InSync, current
+	}
+	return ModifiedItem, fmt.Sprintf("login shell is %s, want %s", current, desired)
+}
+
+// loginShell is the login-shell query, a variable so tests need no account.
+var loginShell = LoginShell
+
 func providerFor(resource types.Resource, entry *state.Entry) string {
+	// A fallback chain installs through whichever provider first had the
+	// package; the journal says which one that was.
+	if entry != nil && slices.Contains(resource.Candidates, entry.Identity["provider"]) {
+		return entry.Identity["provider"]
+	}
 	if resource.Provider != "" {
 		return resource.Provider
 	}
@@ -1,7 +1,9 @@
 package status
 
 import (
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -201,3 +203,244 @@ func TestRowsMatchSameNamedFilesByDestination(t *testing.T) {
 		t.Fatalf("classes = [%s, %s], want [in-sync, missing]", rows[0].Class, rows[1].Class)
 	}
 }
+
+func TestParseLoginShell(t *testing.T) {
+	t.Parallel()
+
+	if got := ParseLoginShell(types.OSLinux, "alice:x:1000:1000:Alice:/home/alice:/usr/bin/zsh\n"); got != "/usr/bin/zsh" {
+		t.Errorf("getent line = %q, want /usr/bin/zsh", got)
+	}
+	if got := ParseLoginShell(types.OSDarwin, "UserShell: /bin/zsh\n"); got != "/bin/zsh" {
+		t.Errorf("dscl record = %q, want /bin/zsh", got)
+	}
+	if got := ParseLoginShell(types.OSLinux, "not a passwd line"); got != "" {
+		t.Errorf("garbage = %q, want empty", got)
+	}
+}
+
+// bareShell points the PATH lookup of name at path - nowhere when path is
+// empty - and /etc/shells at an empty temp file, so a test needs no
+// installed shell.
+func bareShell(t *testing.T, name, path string) {
+	t.Helper()
+	prevLook, prevFile := shellLookPath, shellsFile
+	shellLookPath = func(file string) (string, error) {
+		if file == name && path != "" {
+			return path, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	shellsFile = filepath.Join(t.TempDir(), "shells")
+	t.Cleanup(func() { shellLookPath, shellsFile = prevLook, prevFile })
+}
+
+// A declared path matches the account's shell through a symlink: /bin is
+// /usr/bin on a merged-/usr system. A bare name matches where it resolves,
+// not every binary sharing its base name.
+func TestSameShell(t *testing.T) {
+	dir := t.TempDir()
+	usrBin := filepath.Join(dir, "usr", "bin")
+	if err := os.MkdirAll(usrBin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(usrBin, "zsh"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(usrBin, filepath.Join(dir, "bin")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	bareShell(t, "zsh", filepath.Join(usrBin, "zsh"))
+
+	for _, tt := range []struct {
+		current, desired string
+		same             bool
+	}{
+		{filepath.Join(dir, "bin", "zsh"), filepath.Join(usrBin, "zsh"), true},
+		{filepath.Join(usrBin, "zsh"), "zsh", true},
+		{filepath.Join(dir, "bin", "zsh"), "zsh", true},
+		{"/opt/evil/zsh", "zsh", false},
+		{"/bin/bash", "zsh", false},
+		{"/bin/bash", filepath.Join(usrBin, "zsh"), false},
+		{"/usr/bin/fish", "fish", false},
+		{"", "zsh", false},
+	} {
+		if got := SameShell(tt.current, tt.desired); got != tt.same {
+			t.Errorf("SameShell(%q, %q) = %v, want %v", tt.current, tt.desired, got, tt.same)
+		}
+	}
+}
+
+// A bare name PATH does not find still matches a shell /etc/shells lists
+// under that name, and only that one.
+func TestSameShellListed(t *testing.T) {
+	bareShell(t, "zsh", "")
+	if err := os.WriteFile(shellsFile, []byte("# /etc/shells\n/bin/sh\n/usr/local/bin/zsh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !SameShell("/usr/local/bin/zsh", "zsh") {
+		t.Error("a listed zsh should match the bare name")
+	}
+	if SameShell("/opt/evil/zsh", "zsh") {
+		t.Error("an unlisted zsh should not match the bare name")
+	}
+}
+
+// A login shell is compared by value: a bare declared name matches the
+// account's shell where it resolves, and a different shell is modified, not
+// missing - the account is there, its shell is wrong.
+func TestRowsLoginShell(t *testing.T) {
+	bareShell(t, "zsh", "/usr/bin/zsh")
+	prev := loginShell
+	loginShell = func(name string) string {
+		if name == "alice" {
+			return "/usr/bin/zsh"
+		}
+		if name == "bob" {
+			return "/bin/bash"
+		}
+		return ""
+	}
+	t.Cleanup(func() { loginShell = prev })
+
+	plan := &types.Plan{Resources: []types.Resource{
+		{Processor: types.BlueprintTypeUsers, Name: "alice", Action: types.UserActionShell, Desired: "zsh"},
+		{Processor: types.BlueprintTypeUsers, Name: "bob", Action: types.UserActionShell, Desired: "/usr/bin/fish"},
+		{Processor: types.BlueprintTypeUsers, Name: "carol", Action: types.UserActionShell, Desired: "zsh"},
+		{Processor: types.BlueprintTypeUsers, Name: "dave", Action: types.UserActionCreate},
+	}}
+	rows := Rows(plan, nil, NewQuerier())
+	want := []Class{InSync, ModifiedItem, UnknownItem, UnknownItem}
+	for i, row := range rows {
+		if row.Class != want[i] {
+			t.Errorf("%s = %s (%s), want %s", row.Name, row.Class, row.Note, want[i])
+		}
+	}
+	if !strings.Contains(rows[1].Note, "/bin/bash") || !strings.Contains(rows[1].Note, "/usr/bin/fish") {
+		t.Errorf("modified note %q should name the current and desired shells", rows[1].Note)
+	}
+}
+
+// answers fakes the configuration reads: each argv (joined by spaces) maps to
+// its output, and anything unlisted fails.
+func answers(t *testing.T, outputs map[string]string) {
+	t.Helper()
+	prev := configQuery
+	configQuery = func() ConfigQuery {
+		return ConfigQuery{
+			Run: func(cmd types.Command) (string, error) {
+				out, ok := outputs[strings.Join(append([]string{cmd.Exec}, cmd.Args...), " ")]
+				if !ok {
+					return "", errors.New("exit status 1")
+				}
+				return out, nil
+			},
+			Have: func(string) bool { return true },
+		}
+	}
+	t.Cleanup(func() { configQuery = prev })
+}
+
+func TestRowsConfiguration(t *testing.T) {
+	keyfile := filepath.Join(t.TempDir(), "desktop.ini")
+	if err := os.WriteFile(keyfile, []byte("[org/gnome/desktop/interface]\ncolor-scheme='prefer-dark'\nfont-name='Inter 11'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	answers(t, map[string]string{
+		"gsettings get org.gnome.desktop.wm.preferences button-layout": "'appmenu:close'\n",
+		"gsettings get org.gnome.shell favorite-apps":                  "['firefox.desktop', 'org.gnome.Nautilus.desktop']\n",
+		"defaults read NSGlobalDomain AppleShowAllExtensions":          "1\n",
+		"dconf read /org/gnome/desktop/interface/color-scheme":         "'prefer-dark'\n",
+		"dconf read /org/gnome/desktop/interface/font-name":            "\n",
+	})
+
+	setting := func(config types.Configuration) *types.Configuration { return &config }
+	plan := &types.Plan{Resources: []types.Resource{
+		{Processor: types.BlueprintTypeConfiguration, Name: "wm/button-layout", Desired: "'appmenu:close'",
+			Setting: setting(types.Configuration{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.desktop.wm.preferences", Key: "button-layout"})},
+		{Processor: types.BlueprintTypeConfiguration, Name: "shell/favorite-apps", Desired: "['firefox.desktop','org.gnome.Terminal.desktop']",
+			Setting: setting(types.Configuration{Tool: types.ConfigurationToolGSettings, Schema: "org.gnome.shell", Key: "favorite-apps"})},
+		{Processor: types.BlueprintTypeConfiguration, Name: "show-extensions", Desired: "true",
+			Setting: setting(types.Configuration{Tool: types.ConfigurationToolMacOSDefaults, Key: "AppleShowAllExtensions", Kind: "bool"})},
+		{Processor: types.BlueprintTypeConfiguration, Name: "dock-size", Desired: "36",
+			Setting: setting(types.Configuration{Tool: types.ConfigurationToolMacOSDefaults, Domain: "com.apple.dock", Key: "tilesize", Kind: "int"})},
+		{Processor: types.BlueprintTypeConfiguration, Name: "desktop",
+			Setting: setting(types.Configuration{Tool: types.ConfigurationToolDconf, File: keyfile})},
+		{Processor: types.BlueprintTypeConfiguration, Name: "dock-apps"},
+	}}
+	rows := Rows(plan, nil, NewQuerier())
+	want := []Class{InSync, ModifiedItem, InSync, Missing, ModifiedItem, UnknownItem}
+	for i, row := range rows {
+		if row.Class != want[i] {
+			t.Errorf("%s = %s (%s), want %s", row.Name, row.Class, row.Note, want[i])
+		}
+	}
+	if !strings.Contains(rows[4].Note, "font-name is not set") {
+		t.Errorf("dconf note %q should name the unset key", rows[4].Note)
+	}
+}
+
+func TestConfigValueMatches_GVariant(t *testing.T) {
+	for _, tc := range []struct {
+		current, desired string
+		want             bool
+	}{
+		{"['a', 'b']", "['a','b']", true},
+		{"@as []", "[]", true},
+		{"uint32 5", "5", true},
+		{"1.5", "1.500000", true},
+		{`"it's"`, `'it\'s'`, true},
+		{"'a b'", "'ab'", false},
+		{"['a', 'b']", "['b','a']", false},
+	} {
+		if got := ConfigValueMatches(types.ConfigurationToolGSettings, tc.current, tc.desired); got != tc.want {
+			t.Errorf("%s vs %s = %v, want %v", tc.current, tc.desired, got, tc.want)
+		}
+	}
+}
+
+func TestRowsRunOnceScripts(t *testing.T) {
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "script_ran")
+	changed := filepath.Join(dir, "script_changed")
+	for path, hash := range map[string]string{ran: "sha256:aa", changed: "sha256:old"} {
+		if err := os.WriteFile(path, []byte(hash+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan := &types.Plan{Resources: []types.Resource{
+		{Processor: types.BlueprintTypeScripts, Name: "ran", Marker: ran, Desired: "sha256:aa"},
+		{Processor: types.BlueprintTypeScripts, Name: "changed", Marker: changed, Desired: "sha256:new"},
+		{Processor: types.BlueprintTypeScripts, Name: "never", Marker: filepath.Join(dir, "script_never"), Desired: "sha256:bb"},
+		{Processor: types.BlueprintTypeScripts, Name: "plain"},
+	}}
+	rows := Rows(plan, nil, NewQuerier())
+	want := []Class{InSync, ModifiedItem, Missing, UnknownItem}
+	for i, row := range rows {
+		if row.Class != want[i] {
+			t.Errorf("%s = %s (%s), want %s", row.Name, row.Class, row.Note, want[i])
+		}
+	}
+	for _, row := range rows[1:3] {
+		if !strings.Contains(row.Note, "pending") {
+			t.Errorf("%s note %q should say the script is pending", row.Name, row.Note)
+		}
+	}
+}
+
+// A fallback chain's package is queried through the provider the journal
+// recorded installing it, not the one the plan placed it under.
+func TestProviderForFollowsTheRecordedChainProvider(t *testing.T) {
+	resource := types.Resource{Processor: types.BlueprintTypePackages, Name: "ripgrep", Provider: "apt", Candidates: types.ProviderChain{"apt", "cargo"}}
+	cargo := &state.Entry{Identity: map[string]string{"name": "ripgrep", "provider": "cargo"}}
+	if got := providerFor(resource, cargo); got != "cargo" {
+		t.Errorf("recorded cargo install = %s, want cargo", got)
+	}
+	if got := providerFor(resource, nil); got != "apt" {
+		t.Errorf("no record = %s, want the planned apt", got)
+	}
+	// A record from outside the chain is a different declaration's.
+	brew := &state.Entry{Identity: map[string]string{"name": "ripgrep", "provider": "brew"}}
+	if got := providerFor(resource, brew); got != "apt" {
+		t.Errorf("recorded brew install = %s, want the planned apt", got)
+	}
+}
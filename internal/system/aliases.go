@@ -0,0 +1,131 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"charm.land/log/v2"
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// AliasesFileName is the package alias table, embedded with the provider
+// definitions and, as an override, in the providers directory beside them.
+const AliasesFileName = "aliases.cue"
+
+var (
+	aliases     types.PackageAliases
+	aliasesInit bool
+	aliasesMu   sync.Mutex
+)
+
+// PackageAliases returns the package alias table: the embedded one with the
+// providers directory's aliases.cue laid over it, provider by provider, so
+// an override adds or corrects one name without restating the rest. The
+// table is loaded once; a broken override is warned about and left out.
+func PackageAliases() types.PackageAliases {
+	aliasesMu.Lock()
+	defer aliasesMu.Unlock()
+	if aliasesInit {
+		return aliases
+	}
+	aliasesInit = true
+
+	embedded, err := LoadEmbeddedAliases()
+	if err != nil {
+		log.Errorf("Failed to load the embedded package alias table: %v", err)
+		embedded = types.PackageAliases{}
+	}
+	aliases = embedded
+
+	providersPath, err := GetProvidersPath()
+	if err != nil {
+		return aliases
+	}
+	path := filepath.Join(providersPath, AliasesFileName)
+	if _, statErr := os.Stat(path); statErr != nil {
+		return aliases
+	}
+	if !isProviderFileTrusted(path) {
+		return aliases
+	}
+	override, err := LoadAliasesOverride(path)
+	if err != nil {
+		log.Warnf("Ignoring package aliases in %s: %v", path, err)
+		return aliases
+	}
+	for logical, names := range override {
+		if aliases[logical] == nil {
+			aliases[logical] = map[string]string{}
+		}
+		for provider, name := range names {
+			aliases[logical][provider] = name
+		}
+	}
+	log.Debugf("PackageAliases: applied %d overrides from %s", len(override), path)
+	return aliases
+}
+
+// ResolvePackageName returns the name the named provider installs a package
+// by, and false when the alias table knows the name but not for that
+// provider (see types.PackageAliases.Resolve).
+func ResolvePackageName(name, providerName string) (string, bool) {
+	provider, _ := GetProviderDefinition(providerName)
+	return PackageAliases().Resolve(name, provider)
+}
+
+// LoadEmbeddedAliases evaluates the embedded package alias table.
+func LoadEmbeddedAliases() (types.PackageAliases, error) {
+	value, err := evaluateEmbeddedDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	aliasesValue := value.LookupPath(cue.ParsePath("aliases"))
+	if aliasesValue.Err() != nil {
+		return nil, fmt.Errorf("embedded definitions carry no aliases struct: %w", aliasesValue.Err())
+	}
+	var table types.PackageAliases
+	if err := aliasesValue.Decode(&table); err != nil {
+		return nil, fmt.Errorf("embedded aliases: %w", err)
+	}
+	return table, nil
+}
+
+// LoadAliasesOverride evaluates an aliases.cue override - logical names at
+// the top level, as in the embedded table's aliases struct - against the
+// embedded #Aliases schema.
+func LoadAliasesOverride(path string) (types.PackageAliases, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the operator's own providers directory
+	if err != nil {
+		return nil, err
+	}
+	schema, err := embeddedProviderCUE.ReadFile("definitions/schema.cue")
+	if err != nil {
+		return nil, err
+	}
+	ctx := cuecontext.New()
+	schemaValue := ctx.CompileBytes(schema, cue.Filename("schema.cue"))
+	if schemaValue.Err() != nil {
+		return nil, schemaValue.Err()
+	}
+	aliasesDef := schemaValue.LookupPath(cue.ParsePath("#Aliases"))
+	if aliasesDef.Err() != nil {
+		return nil, aliasesDef.Err()
+	}
+	value := ctx.CompileBytes(data, cue.Filename(path), cue.Scope(schemaValue))
+	if value.Err() != nil {
+		return nil, fmt.Errorf("%s does not parse: %w", path, value.Err())
+	}
+	unified := aliasesDef.Unify(value)
+	if err := unified.Validate(cue.Concrete(true)); err != nil {
+		return nil, fmt.Errorf("%s fails the aliases schema: %w", path, err)
+	}
+	var table types.PackageAliases
+	if err := unified.Decode(&table); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return table, nil
+}
@@ -0,0 +1,67 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Every provider the embedded alias table names is a provider rwr defines:
+// a misspelled key would be an alias nothing ever resolves through.
+func TestLoadEmbeddedAliases(t *testing.T) {
+	table, err := LoadEmbeddedAliases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	providers, err := LoadEmbeddedProviders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for logical, names := range table {
+		for provider := range names {
+			if _, ok := providers[provider]; !ok {
+				t.Errorf("alias %q names provider %q, which is not defined", logical, provider)
+			}
+		}
+	}
+
+	if name, ok := table.Resolve("fd", providers["apt"]); !ok || name != "fd-find" {
+		t.Errorf("fd on apt = %q, %v", name, ok)
+	}
+	// The AUR helpers install pacman's names.
+	if name, ok := table.Resolve("fd", providers["paru"]); !ok || name != "fd" {
+		t.Errorf("fd on paru = %q, %v", name, ok)
+	}
+	if name, ok := table.Resolve("build-tools", providers["dnf"]); ok || name != "build-tools" {
+		t.Errorf("build-tools on dnf = %q, %v; want unmapped", name, ok)
+	}
+	if name, ok := table.Resolve("htop", providers["apt"]); !ok || name != "htop" {
+		t.Errorf("htop, not a logical name, = %q, %v", name, ok)
+	}
+}
+
+func TestLoadAliasesOverride(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "aliases.cue")
+	if err := os.WriteFile(good, []byte(`fd: dnf: "fd"
+"7zip": {apt: "p7zip-full", pacman: "7zip"}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	table, err := LoadAliasesOverride(good)
+	if err != nil {
+		t.Fatalf("good override rejected: %v", err)
+	}
+	if table["fd"]["dnf"] != "fd" || table["7zip"]["apt"] != "p7zip-full" {
+		t.Errorf("decoded = %v", table)
+	}
+
+	bad := filepath.Join(dir, "bad.cue")
+	if err := os.WriteFile(bad, []byte(`fd: apt: "--allow-downgrades"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAliasesOverride(bad); err == nil || !strings.Contains(err.Error(), "fails the aliases schema") {
+		t.Errorf("a name beginning with '-' = %v, want a schema failure", err)
+	}
+}
@@ -0,0 +1,75 @@
+package system
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// ProviderCommand splits one of a provider's commands into what to execute
+// and its arguments. Most are verbs of the provider's own binary ("-Qe",
+// "info"); a command whose first word the definition declares a companion
+// ("apt-mark hold" with apt-mark among apt's companions) runs that binary in
+// the provider's place when it is on PATH. Nothing else is read as a binary,
+// whatever PATH holds: apk's "info" is not GNU info.
+func ProviderCommand(provider *types.Provider, command string) (string, []string) {
+	fields := strings.Fields(command)
+	if len(fields) > 0 && slices.Contains(provider.Commands.Companions, fields[0]) {
+		if path, err := exec.LookPath(fields[0]); err == nil {
+			return path, fields[1:]
+		}
+	}
+	return provider.BinPath, fields
+}
+
+// PackageAvailable reports whether provider has a package called name. It
+// asks the provider's info command, whose exit status is the answer, or -
+// for a provider without one - looks for name among its search results. A
+// provider with neither cannot say, and is taken at its word that it has it:
+// the install will tell.
+func PackageAvailable(provider *types.Provider, name string) bool {
+	if strings.HasPrefix(name, "-") {
+		return false
+	}
+	query, byExit := provider.Commands.Info, true
+	if query == "" {
+		query, byExit = provider.Commands.Search, false
+	}
+	if query == "" {
+		log.Debugf("PackageAvailable: %s has no info or search command; assuming it has %s", provider.Name, name)
+		return true
+	}
+
+	bin, args := ProviderCommand(provider, query)
+	output, err := RunCommandOutput(types.Command{
+		Exec:      bin,
+		Args:      append(args, name),
+		Variables: provider.Environment,
+	}, false)
+	if err != nil {
+		log.Debugf("PackageAvailable: %s has no %s: %v", provider.Name, name, err)
+		return false
+	}
+	if byExit {
+		return true
+	}
+	return searchFinds(output, name)
+}
+
+// searchFinds reports whether search output lists name itself rather than
+// merely something that mentions it. Managers print a result's name with a
+// repository in front ("extra/ripgrep") or alone ("ripgrep 14.1.0"), in the
+// first column or a later one (flatpak's application ID).
+func searchFinds(output, name string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		for _, field := range strings.Fields(line) {
+			if field == name || strings.HasSuffix(field, "/"+name) {
+				return true
+			}
+		}
+	}
+	return false
+}
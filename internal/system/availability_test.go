@@ -0,0 +1,70 @@
+package system
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/exectest"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// An info command answers by exit status; a search command by whether the
+// package itself - not merely something mentioning it - is among its results.
+func TestPackageAvailable(t *testing.T) {
+	rec := exectest.New()
+	rec.Respond = func(call exectest.Call) (string, error) {
+		switch call.Args[len(call.Args)-1] {
+		case "git":
+			return "extra/git 2.47.0-1\n    the fast distributed version control system\n", nil
+		case "ripgrep":
+			return "extra/ripgrep-all 0.10.6-1\n    rga: ripgrep, but also search in PDFs\n", nil
+		}
+		return "", errors.New("exit status 1")
+	}
+	defer SetExecutor(rec)()
+
+	info := &types.Provider{Name: "apt", BinPath: "/usr/bin/apt", Commands: types.CommandConfig{Info: "show", Search: "search"}}
+	search := &types.Provider{Name: "pacman", BinPath: "/usr/bin/pacman", Commands: types.CommandConfig{Search: "-Ss"}}
+
+	cases := []struct {
+		provider *types.Provider
+		name     string
+		want     bool
+	}{
+		{info, "git", true},
+		{info, "nonesuch", false},
+		{search, "git", true},
+		{search, "ripgrep", false},
+		{search, "nonesuch", false},
+		{search, "--overwrite", false},
+		{&types.Provider{Name: "bare"}, "git", true},
+	}
+	for _, tc := range cases {
+		if got := PackageAvailable(tc.provider, tc.name); got != tc.want {
+			t.Errorf("%s has %s = %v, want %v", tc.provider.Name, tc.name, got, tc.want)
+		}
+	}
+	for _, call := range rec.Calls {
+		if call.Args[0] != "show" && call.Args[0] != "-Ss" {
+			t.Errorf("ran %v: only info and search commands may run", call)
+		}
+	}
+}
+
+// Only a first word the definition lists among its companions runs in the
+// provider's place; any other is the provider's own verb even when a binary
+// of that name is on PATH: apk's "info" is not GNU info.
+func TestProviderCommand(t *testing.T) {
+	provider := &types.Provider{Name: "apk", BinPath: "/sbin/apk"}
+	if bin, args := ProviderCommand(provider, "sh -c true"); bin != "/sbin/apk" || len(args) != 3 {
+		t.Errorf("undeclared binary = %s %v, want the provider's own verb", bin, args)
+	}
+	provider.Commands.Companions = []string{"sh"}
+	if bin, args := ProviderCommand(provider, "sh -c true"); bin == "/sbin/apk" || len(args) != 2 {
+		t.Errorf("companion binary = %s %v, want sh from PATH", bin, args)
+	}
+	provider.Commands.Companions = []string{"rwr-no-such-companion"}
+	if bin, args := ProviderCommand(provider, "rwr-no-such-companion -l"); bin != "/sbin/apk" || len(args) != 2 {
+		t.Errorf("companion off PATH = %s %v, want the provider's binary", bin, args)
+	}
+}
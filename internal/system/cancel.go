@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 )
 
 // ErrCancelled is returned by the executor once the run has been cancelled.
@@ -76,3 +77,17 @@ func RunContext() context.Context {
 	defer cancelMu.Unlock()
 	return runCtx
 }
+
+// Wait pauses for d, or until the run is cancelled, in which case it returns
+// ErrCancelled. A retry's backoff waits through it so that stopping a run does
+// not first sit out a delay for an attempt that will never be made.
+func Wait(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-RunContext().Done():
+		return ErrCancelled
+	}
+}
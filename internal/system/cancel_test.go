@@ -184,3 +184,31 @@ func TestKilledCommandReportsCancellationNotFailure(t *testing.T) {
 		t.Fatal("cancelling did not stop the running command")
 	}
 }
+
+// A command's own Timeout kills it through the same process-group kill, and
+// says it timed out rather than that the run was cancelled.
+func TestCommandTimeoutKillsItAndSaysSo(t *testing.T) {
+	if runtime.GOOS == types.OSWindows {
+		t.Skip("sleep is posix here")
+	}
+	defer BeginRun()()
+
+	for name, run := range map[string]func(types.Command) error{
+		"run":    func(cmd types.Command) error { return RunCommand(cmd, false) },
+		"output": func(cmd types.Command) error { _, err := RunCommandOutput(cmd, false); return err },
+	} {
+		t.Run(name, func(t *testing.T) {
+			started := time.Now()
+			err := run(types.Command{Exec: "sleep", Args: []string{"60"}, Timeout: 300 * time.Millisecond})
+			if !errors.Is(err, ErrTimedOut) || errors.Is(err, ErrCancelled) {
+				t.Fatalf("err = %v, want ErrTimedOut", err)
+			}
+			if elapsed := time.Since(started); elapsed > 10*time.Second {
+				t.Errorf("timeout took %v to take effect", elapsed)
+			}
+			if Cancelled() {
+				t.Error("a command's timeout cancelled the whole run")
+			}
+		})
+	}
+}
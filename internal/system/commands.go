@@ -42,8 +42,8 @@ import (
 // whose name begins with a dash cannot be absorbed as a sudo flag. Windows has no
 // sudo: Elevated is a no-op there and the process must already be elevated, which
 // matches the previous behavior of running through `cmd /C` without any elevation.
-func buildCommand(cmd types.Command) *exec.Cmd {
-	built := spawn(cmd)
+func buildCommand(ctx context.Context, cmd types.Command) *exec.Cmd {
+	built := spawn(ctx, cmd)
 	configureCommandCancellation(built)
 	return built
 }
@@ -51,8 +51,7 @@ func buildCommand(cmd types.Command) *exec.Cmd {
 // buildAuthenticatedCommand binds a freshly-read password to the exact sudo
 // process that runs the requested command. Some sudo policies do not make a
 // ticket created by a separate `sudo -v` available to the later process.
-func buildAuthenticatedCommand(cmd types.Command) *exec.Cmd {
-	ctx := RunContext()
+func buildAuthenticatedCommand(ctx context.Context, cmd types.Command) *exec.Cmd {
 	var args []string
 	if cmd.Elevated {
 		args = append([]string{"-S", "-p", "", "--", cmd.Exec}, cmd.Args...)
@@ -79,9 +78,9 @@ func configureCommandCancellation(built *exec.Cmd) {
 }
 
 // spawn builds the *exec.Cmd for a command, before cancellation is wired onto
-// it. Every path goes through CommandContext so the run's context can kill it.
-func spawn(cmd types.Command) *exec.Cmd {
-	ctx := RunContext()
+// it. Every path goes through CommandContext so the run's context (or a
+// command's own deadline under it, see commandContext) can kill it.
+func spawn(ctx context.Context, cmd types.Command) *exec.Cmd {
 	if runtime.GOOS != "windows" {
 		if cmd.Elevated {
 			log.Debugf("Running command as sudo - Running Command: %v %v", cmd.Exec, cmd.LogArgs())
@@ -353,20 +352,48 @@ func zeroBytes(value []byte) {
 	}
 }
 
-func commandForRun(cmd types.Command) (*exec.Cmd, []byte, error) {
+func commandForRun(ctx context.Context, cmd types.Command) (*exec.Cmd, []byte, error) {
 	if runtime.GOOS == "windows" || (!cmd.Elevated && cmd.AsUser == "") || sudoCredentialsReusableByManagedCommand() {
-		return buildCommand(cmd), nil, nil
+		return buildCommand(ctx, cmd), nil, nil
 	}
 	password, err := validatedSudoPassword()
 	if err != nil {
 		if errors.Is(err, errNoSudoTerminal) {
-			return buildCommand(cmd), nil, nil
+			return buildCommand(ctx, cmd), nil, nil
 		}
 		return nil, nil, fmt.Errorf("%w: %v", ErrSudoAuthentication, err)
 	}
 	input := passwordInput(password, cmd.Stdin)
 	zeroBytes(password)
-	return buildAuthenticatedCommand(cmd), input, nil
+	return buildAuthenticatedCommand(ctx, cmd), input, nil
+}
+
+// ErrTimedOut is returned for a command killed because it outran its
+// Timeout. Like ErrCancelled it is distinct from the command failing on its
+// own: a stalled download reads differently from a bad checksum.
+var ErrTimedOut = errors.New("command timed out")
+
+// commandContext is the context a command runs under: the run's, narrowed to
+// the command's Timeout when it has one. Cancelling the run still kills it,
+// and so does the deadline, through the same process-group kill.
+func commandContext(cmd types.Command) (context.Context, context.CancelFunc) {
+	if cmd.Timeout <= 0 {
+		return RunContext(), func() {}
+	}
+	return context.WithTimeout(RunContext(), cmd.Timeout)
+}
+
+// interrupted names why a command that failed was stopped, if it was: the run
+// was cancelled, or the command's own deadline passed. Nil means it failed on
+// its own.
+func interrupted(ctx context.Context, cmd types.Command) error {
+	if Cancelled() {
+		return ErrCancelled
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrTimedOut, cmd.Timeout)
+	}
+	return nil
 }
 
 // runOnTerminal hands cmd the real terminal via the display layer, falling
@@ -433,7 +460,9 @@ func runCommand(cmd types.Command, debug bool) error {
 		return nil
 	}
 
-	command, sudoInput, err := commandForRun(cmd)
+	ctx, cancel := commandContext(cmd)
+	defer cancel()
+	command, sudoInput, err := commandForRun(ctx, cmd)
 	if err != nil {
 		if Cancelled() {
 			return ErrCancelled
@@ -478,8 +507,8 @@ func runCommand(cmd types.Command, debug bool) error {
 			// the context. Without this it reports "signal: killed" and gets
 			// logged as an error, which is the same contract break the
 			// captured path guards against below.
-			if Cancelled() {
-				return ErrCancelled
+			if stopped := interrupted(ctx, cmd); stopped != nil {
+				return stopped
 			}
 			log.Errorf("Error running command: %v (stderr above)", err)
 			return err
@@ -519,9 +548,10 @@ func runCommand(cmd types.Command, debug bool) error {
 	if err := command.Run(); err != nil {
 		// A command killed by cancellation exits non-zero like any failure.
 		// Reporting it as one would fill the summary with "signal: killed"
-		// for work the operator deliberately stopped.
-		if Cancelled() {
-			return ErrCancelled
+		// for work the operator deliberately stopped. A command that outran
+		// its own timeout says so for the same reason.
+		if stopped := interrupted(ctx, cmd); stopped != nil {
+			return stopped
 		}
 		// stderr was streamed live (to the log view under the TUI, to the
 		// real stderr headless); repeating the blob here renders it twice.
@@ -563,7 +593,9 @@ func runCommandOutput(cmd types.Command, debug bool) (string, error) {
 		}
 	}
 
-	command, sudoInput, err := commandForRun(cmd)
+	ctx, cancel := commandContext(cmd)
+	defer cancel()
+	command, sudoInput, err := commandForRun(ctx, cmd)
 	if err != nil {
 		if Cancelled() {
 			return "", ErrCancelled
@@ -585,8 +617,8 @@ func runCommandOutput(cmd types.Command, debug bool) (string, error) {
 
 	err = command.Run()
 	if err != nil {
-		if Cancelled() {
-			return "", ErrCancelled
+		if stopped := interrupted(ctx, cmd); stopped != nil {
+			return "", stopped
 		}
 		errMsg := fmt.Sprintf("Error running command: %v\nStderr: %s", err, stderr.String())
 		log.Error(errMsg)
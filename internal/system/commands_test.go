@@ -103,7 +103,7 @@ func TestGetBinPath_EmptyCommand(t *testing.T) {
 // below would instead see a three-element `sh -c "<joined string>"`.
 func argvOf(t *testing.T, cmd types.Command) []string {
 	t.Helper()
-	return buildCommand(cmd).Args
+	return buildCommand(RunContext(), cmd).Args
 }
 
 func TestBuildCommand_NoShellIsInterposed(t *testing.T) {
@@ -0,0 +1,116 @@
+// The package alias table: logical package names a blueprint can write with
+// no package_manager, and what each provider calls them. A provider whose
+// definition sets packageNames (the AUR helpers, as pacman) uses that
+// provider's entry when it has none of its own. Only names that differ
+// somewhere belong here; a provider missing from an entry is unmapped, and
+// `rwr validate --target` says so.
+package providers
+
+aliases: {
+	"fd": {
+		apt:        "fd-find"
+		dnf:        "fd-find"
+		pacman:     "fd"
+		brew:       "fd"
+		apk:        "fd"
+		zypper:     "fd"
+		xbps:       "fd"
+		nix:        "fd"
+		scoop:      "fd"
+		chocolatey: "fd"
+		winget:     "sharkdp.fd"
+	}
+	"ag": {
+		apt:    "silversearcher-ag"
+		dnf:    "the_silver_searcher"
+		pacman: "the_silver_searcher"
+		brew:   "the_silver_searcher"
+		apk:    "the_silver_searcher"
+		zypper: "the_silver_searcher"
+		xbps:   "the_silver_searcher"
+	}
+	"python": {
+		apt:    "python3"
+		dnf:    "python3"
+		pacman: "python"
+		brew:   "python"
+		apk:    "python3"
+		zypper: "python3"
+		xbps:   "python3"
+		scoop:  "python"
+	}
+	"pip": {
+		apt:    "python3-pip"
+		dnf:    "python3-pip"
+		pacman: "python-pip"
+		apk:    "py3-pip"
+		zypper: "python3-pip"
+		xbps:   "python3-pip"
+	}
+	"go": {
+		apt:    "golang-go"
+		dnf:    "golang"
+		pacman: "go"
+		brew:   "go"
+		apk:    "go"
+		zypper: "go"
+		xbps:   "go"
+		winget: "GoLang.Go"
+		scoop:  "go"
+	}
+	"nodejs": {
+		apt:    "nodejs"
+		dnf:    "nodejs"
+		pacman: "nodejs"
+		brew:   "node"
+		apk:    "nodejs"
+		zypper: "nodejs"
+		xbps:   "nodejs"
+		winget: "OpenJS.NodeJS"
+		scoop:  "nodejs"
+	}
+	"neovim": {
+		apt:    "neovim"
+		dnf:    "neovim"
+		pacman: "neovim"
+		brew:   "neovim"
+		apk:    "neovim"
+		zypper: "neovim"
+		xbps:   "neovim"
+		winget: "Neovim.Neovim"
+		scoop:  "neovim"
+	}
+	"vim": {
+		apt:    "vim"
+		dnf:    "vim-enhanced"
+		pacman: "vim"
+		brew:   "vim"
+		apk:    "vim"
+		zypper: "vim"
+		xbps:   "vim"
+	}
+	"gnupg": {
+		apt:    "gnupg"
+		dnf:    "gnupg2"
+		pacman: "gnupg"
+		brew:   "gnupg"
+		apk:    "gnupg"
+		zypper: "gpg2"
+		xbps:   "gnupg"
+	}
+	"ssh": {
+		apt:    "openssh-client"
+		dnf:    "openssh-clients"
+		pacman: "openssh"
+		brew:   "openssh"
+		apk:    "openssh-client"
+		zypper: "openssh-clients"
+		xbps:   "openssh"
+	}
+	"build-tools": {
+		apt:    "build-essential"
+		pacman: "base-devel"
+		apk:    "build-base"
+		xbps:   "base-devel"
+	}
+}
@@ -21,7 +21,10 @@ providers: "apk": {
   "list": "info",
   "listExplicit": "cat /etc/apk/world",
   "search": "search",
-  "clean": "cache clean"
+  "upgrade": "upgrade",
+  "upgradeAll": "upgrade",
+  "clean": "cache clean",
+  "companions": ["cat"]
  },
  "corePackages": {
   "openssl": [
@@ -10,8 +10,21 @@ providers: "apt": #DebianFamily & {
  "list": "dpkg --get-selections",
  "listExplicit": "apt-mark showmanual",
  "search": "search",
- "clean": "clean"
+ "info": "show",
+ "upgrade": "install --only-upgrade -y",
+ "upgradeAll": "upgrade -y",
+ "hold": "apt-mark hold",
+ "unhold": "apt-mark unhold",
+ "clean": "clean",
+ "companions": ["dpkg", "apt-mark"]
 }
+	// Offline, apt installs from the archives fetch downloaded, against the
+	// package lists already on the machine. --reinstall makes fetch download
+	// a package the fetching machine already has.
+	offline: {
+		fetch:   "install --download-only --reinstall -y -o Dir::Cache::archives={{ .CacheDir }}"
+		install: "install --no-download -y -o Dir::Cache::archives={{ .CacheDir }}"
+	}
 	corePackages: {
  "openssl": [
   "openssl",
@@ -37,8 +37,23 @@ providers: "brew": {
   "list": "list",
   "listExplicit": "leaves",
   "search": "search",
+  "info": "info",
+  "upgrade": "upgrade",
+  "upgradeAll": "upgrade",
+  "hold": "pin",
+  "unhold": "unpin",
   "clean": "cleanup -q"
  },
+ // Offline, brew installs the bottles rwr fetch left in HOMEBREW_CACHE,
+ // without updating itself first.
+ "offline": {
+  "fetch": "fetch --deps",
+  "install": "install -fq",
+  "environment": {
+   "HOMEBREW_CACHE": "{{ .CacheDir }}",
+   "HOMEBREW_NO_AUTO_UPDATE": "1"
+  }
+ },
  "install": {
   "steps": [
    {
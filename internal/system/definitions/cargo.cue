@@ -28,6 +28,8 @@ providers: "cargo": {
   "list": "install --list",
   "listExplicit": "install --list",
   "search": "search",
+  "info": "info",
+  "upgrade": "install --locked",
   "clean": "cache --autoclean"
  },
  "corePackages": {
@@ -18,6 +18,7 @@ providers: "chocolatey": {
   "remove": "uninstall -y",
   "list": "list --local-only",
   "search": "search",
+  "upgrade": "upgrade -y",
   "clean": "cache delete"
  },
  "install": {
@@ -22,8 +22,18 @@ providers: "dnf": {
   "list": "list installed",
   "listExplicit": "repoquery --userinstalled --qf %{name}",
   "search": "search",
+  "info": "info",
+  "upgrade": "upgrade -y",
+  "hold": "versionlock add",
+  "unhold": "versionlock delete",
   "clean": "clean all"
  },
+ // Offline, dnf resolves from the cache fetch filled - the packages and the
+ // repository metadata next to them, which `dnf download` would not keep.
+ "offline": {
+  "fetch": "install -y --downloadonly --setopt=cachedir={{ .CacheDir }}",
+  "install": "install -y --cacheonly --setopt=cachedir={{ .CacheDir }}"
+ },
  "corePackages": {
   "openssl": [
    "openssl",
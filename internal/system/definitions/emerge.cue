@@ -21,7 +21,9 @@ providers: "emerge": {
   "list": "qlist -I",
   "listExplicit": "cat /var/lib/portage/world",
   "search": "-s",
-  "clean": "--depclean"
+  "upgrade": "-qvu",
+  "clean": "--depclean",
+  "companions": ["qlist", "cat"]
  },
  "repository": {
   "paths": {
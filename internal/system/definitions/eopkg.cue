@@ -20,6 +20,8 @@ providers: "eopkg": {
   "remove": "rm -y",
   "list": "li",
   "search": "sr",
+  "upgrade": "up -y",
+  "upgradeAll": "up -y",
   "clean": "rmo -y"
  },
  "repository": {
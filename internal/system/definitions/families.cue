@@ -7,6 +7,7 @@ package providers
 // packages shared by pacman and every AUR helper. A family-wide fix (the
 // staging move, --needed) is one edit here instead of six that must not drift.
 #PacmanFamily: #Provider & {
+	packageNames: "pacman"
 	detection: {
 		files: [
  "/etc/pacman.conf",
@@ -110,6 +111,7 @@ package providers
 // #DebianFamily: detection and repository shape shared by the Debian tools
 // (apt today; apt-get/aptitude would join here rather than re-declaring it).
 #DebianFamily: #Provider & {
+	packageNames: "apt"
 	detection: {
 		files: [
  "/etc/apt"
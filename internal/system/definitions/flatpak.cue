@@ -21,6 +21,9 @@ providers: "flatpak": {
   "list": "list",
   "listExplicit": "list --app --columns=application",
   "search": "search",
+  "upgrade": "update -y",
+  "hold": "mask",
+  "unhold": "mask --remove",
   "clean": "uninstall --unused -y"
  },
  "repository": {
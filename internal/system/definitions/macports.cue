@@ -19,6 +19,8 @@ providers: "macports": {
   "remove": "uninstall",
   "list": "installed",
   "search": "search",
+  "upgrade": "upgrade",
+  "upgradeAll": "upgrade outdated",
   "clean": "clean --all all"
  },
  "corePackages": {
@@ -19,6 +19,7 @@ providers: "mas": {
   "remove": "uninstall",
   "list": "list",
   "search": "search",
+  "upgrade": "upgrade",
   "clean": "reset"
  },
  "repository": {
@@ -23,7 +23,8 @@ providers: "nix": {
   "remove": "-e",
   "list": "-q",
   "search": "nix search",
-  "clean": "nix-collect-garbage -d"
+  "clean": "nix-collect-garbage -d",
+  "companions": ["nix-collect-garbage"]
  },
  "corePackages": {
   "openssl": [
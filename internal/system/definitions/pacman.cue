@@ -10,6 +10,14 @@ providers: "pacman": #PacmanFamily & {
  "list": "-Q",
  "listExplicit": "-Qe",
  "search": "-Ss",
+ "info": "-Si",
+ "upgrade": "-S --needed --noconfirm",
  "clean": "-Sc --noconfirm"
 }
+	// Offline, pacman installs from the packages fetch downloaded, against
+	// the sync databases already on the machine: -S without the y.
+	offline: {
+		fetch:   "-Sw --noconfirm --cachedir {{ .CacheDir }}"
+		install: "-S --noconfirm --cachedir {{ .CacheDir }}"
+	}
 }
@@ -10,6 +10,8 @@ providers: "paru": #ArchAURHelper & {
  "list": "-Qm",
  "listExplicit": "-Qme",
  "search": "-Ss",
+ "info": "-Si",
+ "upgrade": "-S --needed --noconfirm",
  "clean": "-Scc --noconfirm"
 }
 }
@@ -66,7 +66,23 @@ package providers
 	list?:         string
 	listExplicit?: string
 	search?:       string
+	info?:         string
+	upgrade?:      string
+	upgradeAll?:   string
+	hold?:         string
+	unhold?:       string
 	clean?:   string
+	// companions: binaries other than the provider's own that a command may
+	// start with; only these run in the provider's place.
+	companions?: [...string]
+}
+
+// #Offline: how a provider works from rwr's offline cache. {{ .CacheDir }}
+// in a command or an environment value is the provider's cache directory.
+#Offline: {
+	fetch:   string
+	install: string
+	environment?: {[string]: string}
 }
 
 #RepositoryPaths: {
@@ -107,7 +123,19 @@ package providers
 	install?: {steps?: [...#InstallStep]}
 	remove?: {steps?: [...#InstallStep]}
 	environment?: {[string]: string}
+	offline?:     #Offline
+	// packageNames names the provider whose package names this one installs
+	// by, for the alias table: the AUR helpers take pacman's.
+	packageNames?: string
 }
 
 // providers is the exported value: one entry per definition file.
 providers: {[Name=string]: #Provider & {name: Name}}
+
+// #Aliases: the package alias table - for each logical package name, what
+// each provider calls it. A name beginning with '-' would reach the package
+// manager as an option, so it cannot be written here either.
+#Aliases: {[string]: {[string]: string & !="" & !~"^-"}}
+
+// aliases is the exported table (aliases.cue).
+aliases: #Aliases
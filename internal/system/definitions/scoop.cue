@@ -18,6 +18,10 @@ providers: "scoop": {
   "remove": "uninstall",
   "list": "list",
   "search": "search",
+  "upgrade": "update",
+  "upgradeAll": "update *",
+  "hold": "hold",
+  "unhold": "unhold",
   "clean": "cache rm *"
  },
  "corePackages": {
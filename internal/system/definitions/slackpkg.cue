@@ -20,6 +20,8 @@ providers: "slackpkg": {
   "remove": "remove",
   "list": "search installed",
   "search": "search",
+  "upgrade": "upgrade",
+  "upgradeAll": "upgrade-all",
   "clean": "clean-system"
  },
  "repository": {
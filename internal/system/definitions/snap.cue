@@ -21,6 +21,10 @@ providers: "snap": {
   "list": "list",
   "listExplicit": "list",
   "search": "find",
+  "info": "info",
+  "upgrade": "refresh",
+  "hold": "refresh --hold",
+  "unhold": "refresh --unhold",
   "clean": "refresh"
  },
  "repository": {
@@ -10,6 +10,8 @@ providers: "trizen": #ArchAURHelper & {
  "list": "-Qm",
  "listExplicit": "-Qme",
  "search": "-Ss",
+ "info": "-Si",
+ "upgrade": "-S --needed --noconfirm --noedit",
  "clean": "-Sc --noconfirm"
 }
 }
@@ -18,6 +18,9 @@ providers: "winget": {
   "remove": "uninstall",
   "list": "list",
   "search": "search",
+  "upgrade": "upgrade --silent",
+  "hold": "pin add",
+  "unhold": "pin remove",
   "clean": "source reset"
  },
  "corePackages": {
@@ -21,7 +21,9 @@ providers: "xbps": {
   "list": "xbps-query -l",
   "listExplicit": "xbps-query -m",
   "search": "xbps-query -Rs",
-  "clean": "xbps-remove -O"
+  "upgrade": "-Suy",
+  "clean": "xbps-remove -O",
+  "companions": ["xbps-query", "xbps-remove"]
  },
  "repository": {
   "paths": {
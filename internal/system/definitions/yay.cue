@@ -10,6 +10,8 @@ providers: "yay": #ArchAURHelper & {
  "list": "-Qm",
  "listExplicit": "-Qme",
  "search": "-Ss",
+ "info": "-Si",
+ "upgrade": "-S --needed --noconfirm",
  "clean": "-Yc --noconfirm"
 }
 }
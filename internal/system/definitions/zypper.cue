@@ -20,6 +20,9 @@ providers: "zypper": {
   "remove": "remove -y",
   "list": "packages --installed-only",
   "search": "search",
+  "upgrade": "update -y",
+  "hold": "addlock",
+  "unhold": "removelock",
   "clean": "clean"
  },
  "corePackages": {
@@ -22,29 +22,9 @@ var embeddedProviderCUE embed.FS
 // by provider name. Each call returns fresh values: callers own what they
 // get, exactly as they did with the per-call decode this replaced.
 func LoadEmbeddedProviders() (map[string]*types.Provider, error) {
-	overlay := map[string]load.Source{}
-	err := fs.WalkDir(embeddedProviderCUE, "definitions", func(path string, d fs.DirEntry, walkErr error) error {
-		if walkErr != nil || d.IsDir() || !strings.HasSuffix(path, ".cue") {
-			return walkErr
-		}
-		data, readErr := embeddedProviderCUE.ReadFile(path)
-		if readErr != nil {
-			return readErr
-		}
-		overlay["/providers/"+filepath.Base(path)] = load.FromBytes(data)
-		return nil
-	})
+	value, err := evaluateEmbeddedDefinitions()
 	if err != nil {
-		return nil, fmt.Errorf("reading embedded provider definitions: %w", err)
-	}
-
-	instances := load.Instances([]string{"/providers"}, &load.Config{Dir: "/", Overlay: overlay})
-	if len(instances) == 0 || instances[0].Err != nil {
-		return nil, fmt.Errorf("loading embedded provider definitions: %w", instances[0].Err)
-	}
-	value := cuecontext.New().BuildInstance(instances[0])
-	if value.Err() != nil {
-		return nil, fmt.Errorf("evaluating embedded provider definitions: %w", value.Err())
+		return nil, err
 	}
 	providersValue := value.LookupPath(cue.ParsePath("providers"))
 	if providersValue.Err() != nil {
@@ -69,6 +49,36 @@ func LoadEmbeddedProviders() (map[string]*types.Provider, error) {
 	return providers, nil
 }
 
+// evaluateEmbeddedDefinitions builds the embedded definitions - providers and
+// the package alias table - as one CUE instance.
+func evaluateEmbeddedDefinitions() (cue.Value, error) {
+	overlay := map[string]load.Source{}
+	err := fs.WalkDir(embeddedProviderCUE, "definitions", func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() || !strings.HasSuffix(path, ".cue") {
+			return walkErr
+		}
+		data, readErr := embeddedProviderCUE.ReadFile(path)
+		if readErr != nil {
+			return readErr
+		}
+		overlay["/providers/"+filepath.Base(path)] = load.FromBytes(data)
+		return nil
+	})
+	if err != nil {
+		return cue.Value{}, fmt.Errorf("reading embedded provider definitions: %w", err)
+	}
+
+	instances := load.Instances([]string{"/providers"}, &load.Config{Dir: "/", Overlay: overlay})
+	if len(instances) == 0 || instances[0].Err != nil {
+		return cue.Value{}, fmt.Errorf("loading embedded provider definitions: %w", instances[0].Err)
+	}
+	value := cuecontext.New().BuildInstance(instances[0])
+	if value.Err() != nil {
+		return cue.Value{}, fmt.Errorf("evaluating embedded provider definitions: %w", value.Err())
+	}
+	return value, nil
+}
+
 // decodeCUEOverride evaluates one filesystem override written in CUE,
 // unified against the embedded #Provider schema so an override gets exactly
 // the validation a shipped definition gets.
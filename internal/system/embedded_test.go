@@ -430,3 +430,22 @@ func TestLoadEmbeddedProviders_BrewTapTrustStep(t *testing.T) {
 		t.Fatal("brew trust step must be optional - older brew has no trust command")
 	}
 }
+
+// fetch and --offline only work with a provider whose offline block decodes:
+// both commands on each provider that ships one, and brew's cache variable,
+// which it reads in place of a flag.
+func TestLoadEmbeddedProviders_OfflineDecodes(t *testing.T) {
+	providers, err := LoadEmbeddedProviders()
+	if err != nil {
+		t.Fatalf("LoadEmbeddedProviders() failed: %v", err)
+	}
+	for _, name := range []string{"apt", "dnf", "pacman", "brew"} {
+		offline := providers[name].Offline
+		if offline == nil || offline.Fetch == "" || offline.Install == "" {
+			t.Errorf("%s offline block not decoded from CUE; got %+v", name, offline)
+		}
+	}
+	if providers["brew"].Offline.Environment["HOMEBREW_CACHE"] != "{{ .CacheDir }}" {
+		t.Fatalf("brew offline environment not decoded; got %v", providers["brew"].Offline.Environment)
+	}
+}
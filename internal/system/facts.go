@@ -0,0 +1,344 @@
+package system
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// FactsDirName is the directory under the config directory holding the
+// operator's custom fact executables.
+const FactsDirName = "facts.d"
+
+// factTimeout bounds each probe and each facts.d executable. Facts are read
+// before anything is applied, so a hung probe would hang the whole run.
+const factTimeout = 10 * time.Second
+
+// GatherFacts reads the machine's facts and runs the facts.d executables in
+// factsDir (empty skips them). Nothing here fails: a fact that cannot be read
+// is left at its zero value and logged at debug.
+//
+// The probes are read-only and run directly rather than through the executor,
+// like the rest of detection: a dry run needs the same facts a real run does.
+func GatherFacts(factsDir string) types.Facts {
+	log.Debug("Gathering facts.")
+	facts := types.Facts{CPUs: runtime.NumCPU()}
+	if hostname, err := os.Hostname(); err == nil {
+		facts.Hostname = strings.ToLower(hostname)
+	} else {
+		log.Debugf("Error reading hostname: %v", err)
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		gatherLinuxFacts(&facts)
+	case "darwin":
+		gatherDarwinFacts(&facts)
+	case "windows":
+		gatherWindowsFacts(&facts)
+	}
+	if len(facts.GPUVendors) > 0 {
+		facts.GPUVendor = facts.GPUVendors[0]
+	}
+
+	if factsDir != "" {
+		facts.Custom = customFacts(factsDir)
+	}
+
+	log.Debugf("Facts: %+v", facts)
+	return facts
+}
+
+func gatherLinuxFacts(facts *types.Facts) {
+	if data, err := os.ReadFile("/proc/meminfo"); err == nil {
+		facts.MemoryMB = parseMeminfo(string(data))
+	}
+	if out, err := factOutput("lspci"); err == nil {
+		facts.GPUVendors = parseLspciGPUs(out)
+	} else {
+		log.Debugf("Error running lspci: %v", err)
+	}
+	batteries, _ := filepath.Glob("/sys/class/power_supply/BAT*")
+	facts.Laptop = len(batteries) > 0
+	facts.Virtualization, facts.Container = linuxVirtualization()
+	facts.Desktop = linuxDesktop(os.Getenv("XDG_CURRENT_DESKTOP"), os.Getenv("DESKTOP_SESSION"))
+}
+
+// linuxVirtualization asks systemd-detect-virt first and falls back to the
+// marker files container runtimes leave and the DMI vendor the hypervisor
+// reports.
+func linuxVirtualization() (virtualization, container string) {
+	if out, err := factOutput("systemd-detect-virt", "--vm"); err == nil {
+		virtualization = detectVirtName(out)
+	} else if cpuinfo, err := os.ReadFile("/proc/cpuinfo"); err == nil && strings.Contains(string(cpuinfo), " hypervisor") {
+		vendor, _ := os.ReadFile("/sys/class/dmi/id/sys_vendor")
+		virtualization = hypervisorFromVendor(string(vendor))
+	}
+
+	// systemd-detect-virt exits non-zero with "none" outside a container.
+	if out, err := factOutput("systemd-detect-virt", "--container"); err == nil {
+		container = detectVirtName(out)
+	}
+	if container == "" {
+		switch {
+		case fileExists("/.dockerenv"):
+			container = "docker"
+		case fileExists("/run/.containerenv"):
+			container = "podman"
+		default:
+			container = strings.ToLower(os.Getenv("container"))
+		}
+	}
+	return virtualization, container
+}
+
+func detectVirtName(out string) string {
+	name := strings.ToLower(strings.TrimSpace(out))
+	if name == "none" {
+		return ""
+	}
+	return name
+}
+
+// hypervisorFromVendor names the hypervisor from the vendor a guest's firmware
+// reports, or "vm" for one it does not recognise.
+func hypervisorFromVendor(vendor string) string {
+	vendor = strings.ToLower(vendor)
+	for _, known := range [][2]string{
+		{"qemu", "kvm"}, {"vmware", "vmware"}, {"innotek", "virtualbox"}, {"xen", "xen"},
+		{"microsoft", "microsoft"}, {"parallels", "parallels"}, {"amazon", "amazon"}, {"google", "google"},
+	} {
+		if strings.Contains(vendor, known[0]) {
+			return known[1]
+		}
+	}
+	return "vm"
+}
+
+// linuxDesktop names the session's desktop. XDG_CURRENT_DESKTOP is a
+// colon-separated list from most to least specific ("ubuntu:GNOME"); the last
+// entry is the desktop itself rather than a distribution's flavor of it.
+func linuxDesktop(current, session string) string {
+	desktop := current
+	if desktop == "" {
+		desktop = session
+	}
+	if parts := strings.Split(desktop, ":"); len(parts) > 0 {
+		desktop = parts[len(parts)-1]
+	}
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(desktop)), "x-")
+}
+
+// parseMeminfo returns MemTotal from /proc/meminfo, in mebibytes.
+func parseMeminfo(meminfo string) int {
+	for _, line := range strings.Split(meminfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return 0
+			}
+			return kb / 1024
+		}
+	}
+	return 0
+}
+
+// parseLspciGPUs returns the vendor of every display adapter lspci lists.
+func parseLspciGPUs(out string) []string {
+	var vendors []string
+	for _, line := range strings.Split(out, "\n") {
+		_, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		class, device, ok := strings.Cut(rest, ": ")
+		if !ok {
+			continue
+		}
+		switch class {
+		case "VGA compatible controller", "3D controller", "Display controller":
+			vendors = append(vendors, gpuVendor(device))
+		}
+	}
+	return vendors
+}
+
+// gpuVendor normalizes an adapter description to a short vendor name.
+func gpuVendor(device string) string {
+	lower := strings.ToLower(device)
+	switch {
+	case strings.Contains(lower, "nvidia"):
+		return "nvidia"
+	case strings.Contains(lower, "advanced micro devices"), strings.Contains(lower, "amd"), strings.Contains(lower, "ati "):
+		return "amd"
+	case strings.Contains(lower, "intel"):
+		return "intel"
+	case strings.Contains(lower, "apple"):
+		return "apple"
+	}
+	if fields := strings.Fields(lower); len(fields) > 0 {
+		return strings.Trim(fields[0], ",.")
+	}
+	return ""
+}
+
+func gatherDarwinFacts(facts *types.Facts) {
+	if out, err := factOutput("sysctl", "-n", "hw.memsize"); err == nil {
+		if bytes, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64); err == nil {
+			facts.MemoryMB = int(bytes / (1024 * 1024))
+		}
+	}
+	if out, err := factOutput("system_profiler", "SPDisplaysDataType"); err == nil {
+		for _, line := range strings.Split(out, "\n") {
+			if model, ok := strings.CutPrefix(strings.TrimSpace(line), "Chipset Model:"); ok {
+				facts.GPUVendors = append(facts.GPUVendors, gpuVendor(model))
+			}
+		}
+	}
+	if out, err := factOutput("pmset", "-g", "batt"); err == nil {
+		facts.Laptop = strings.Contains(out, "InternalBattery")
+	}
+	if out, err := factOutput("sysctl", "-n", "kern.hv_vmm_present"); err == nil && strings.TrimSpace(out) == "1" {
+		facts.Virtualization = "vm"
+	}
+	facts.Desktop = "aqua"
+}
+
+// windowsFactsScript prints the facts WMI knows as key=value lines, the same
+// shape a facts.d executable may print.
+const windowsFactsScript = `$cs = Get-CimInstance Win32_ComputerSystem
+"memory=$([int]($cs.TotalPhysicalMemory / 1MB))"
+"model=$($cs.Model)"
+"battery=$(@(Get-CimInstance Win32_Battery).Count)"
+Get-CimInstance Win32_VideoController | ForEach-Object { "gpu=$($_.Name)" }`
+
+func gatherWindowsFacts(facts *types.Facts) {
+	facts.Desktop = "windows"
+	out, err := factOutput("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsFactsScript)
+	if err != nil {
+		log.Debugf("Error reading Windows facts: %v", err)
+		return
+	}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "memory":
+			facts.MemoryMB, _ = strconv.Atoi(value)
+		case "battery":
+			count, _ := strconv.Atoi(value)
+			facts.Laptop = count > 0
+		case "gpu":
+			facts.GPUVendors = append(facts.GPUVendors, gpuVendor(value))
+		case "model":
+			if lower := strings.ToLower(value); strings.Contains(lower, "virtual") || strings.Contains(lower, "vmware") {
+				facts.Virtualization = hypervisorFromVendor(lower)
+			}
+		}
+	}
+}
+
+// customFacts runs every executable in dir, in name order, and merges what
+// they print. An executable that fails, hangs or prints something unreadable
+// is skipped with a warning; the rest still count. When two report the same
+// fact, the later one wins.
+func customFacts(dir string) map[string]interface{} {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Error reading %s: %v", dir, err)
+		}
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	custom := map[string]interface{}{}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if !isFactExecutable(path) {
+			continue
+		}
+		out, err := factOutput(path)
+		if err != nil {
+			log.Warnf("Skipping custom facts from %s: %v", path, err)
+			continue
+		}
+		reported, err := parseCustomFacts(out)
+		if err != nil {
+			log.Warnf("Skipping custom facts from %s: %v", path, err)
+			continue
+		}
+		for key, value := range reported {
+			if _, seen := custom[key]; seen {
+				log.Warnf("Custom fact %q from %s replaces an earlier executable's", key, path)
+			}
+			custom[key] = value
+		}
+	}
+	return custom
+}
+
+// isFactExecutable skips directories and, outside Windows (which has no
+// executable bit), files not marked executable - a README beside the facts
+// is not a fact.
+func isFactExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0o111 != 0
+}
+
+// parseCustomFacts reads a facts.d executable's output: a JSON object, or
+// key=value lines (blank lines and # comments ignored), whose values stay
+// strings.
+func parseCustomFacts(out string) (map[string]interface{}, error) {
+	trimmed := strings.TrimSpace(out)
+	facts := map[string]interface{}{}
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal([]byte(trimmed), &facts); err != nil {
+			return nil, err
+		}
+		return facts, nil
+	}
+	scanner := bufio.NewScanner(strings.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("expected a JSON object or key=value lines, got %q", line)
+		}
+		facts[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return facts, scanner.Err()
+}
+
+// factOutput runs a probe under factTimeout and the run's context.
+func factOutput(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(RunContext(), factTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).Output() // #nosec G204 -- fixed probes and the operator's own facts.d executables
+	return string(out), err
+}
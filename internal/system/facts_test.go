@@ -0,0 +1,66 @@
+package system
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseLspciGPUs(t *testing.T) {
+	out := `00:00.0 Host bridge: Intel Corporation 12th Gen Core Processor Host Bridge/DRAM Registers (rev 02)
+00:02.0 VGA compatible controller: Intel Corporation Alder Lake-P GT2 [Iris Xe Graphics] (rev 0c)
+01:00.0 3D controller: NVIDIA Corporation GA107M [GeForce RTX 3050 Mobile] (rev a1)
+03:00.0 VGA compatible controller: Advanced Micro Devices, Inc. [AMD/ATI] Navi 23
+04:00.0 Display controller: Matrox Electronics Systems Ltd. G200eR2
+`
+	want := []string{"intel", "nvidia", "amd", "matrox"}
+	if got := parseLspciGPUs(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLspciGPUs = %v, want %v", got, want)
+	}
+}
+
+func TestParseMeminfo(t *testing.T) {
+	if got := parseMeminfo("MemTotal:       32594316 kB\nMemFree:        1000 kB\n"); got != 31830 {
+		t.Errorf("parseMeminfo = %d, want 31830", got)
+	}
+	if got := parseMeminfo("garbage"); got != 0 {
+		t.Errorf("parseMeminfo(garbage) = %d, want 0", got)
+	}
+}
+
+func TestLinuxDesktop(t *testing.T) {
+	tests := []struct{ current, session, want string }{
+		{"ubuntu:GNOME", "", "gnome"},
+		{"KDE", "plasma", "kde"},
+		{"X-Cinnamon", "", "cinnamon"},
+		{"", "sway", "sway"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := linuxDesktop(tt.current, tt.session); got != tt.want {
+			t.Errorf("linuxDesktop(%q, %q) = %q, want %q", tt.current, tt.session, got, tt.want)
+		}
+	}
+}
+
+func TestParseCustomFacts(t *testing.T) {
+	got, err := parseCustomFacts("# role of this box\nrole = workstation\n\nrack=4\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "map[rack:4 role:workstation]" {
+		t.Errorf("key=value facts = %v", got)
+	}
+
+	got, err = parseCustomFacts(`{"rack": 4, "tags": ["gpu"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["rack"] != float64(4) || fmt.Sprint(got["tags"]) != "[gpu]" {
+		t.Errorf("JSON facts = %v", got)
+	}
+
+	if _, err := parseCustomFacts("not a fact"); err == nil {
+		t.Error("unreadable output was accepted")
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Every executable in facts.d counts, in name order, later ones winning a
+// clash; a failing executable and a non-executable file are left out.
+func TestCustomFacts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, mode os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("10-role", "#!/bin/sh\necho role=laptop\necho site=home\n", 0o755)
+	write("20-override", "#!/bin/sh\necho '{\"role\": \"workstation\"}'\n", 0o755)
+	write("30-broken", "#!/bin/sh\nexit 3\n", 0o755)
+	write("README", "site=ignored\n", 0o644)
+
+	facts := customFacts(dir)
+	if facts["role"] != "workstation" || facts["site"] != "home" || len(facts) != 2 {
+		t.Errorf("customFacts = %v", facts)
+	}
+
+	if facts := customFacts(filepath.Join(dir, "missing")); facts != nil {
+		t.Errorf("missing facts.d = %v, want nil", facts)
+	}
+}
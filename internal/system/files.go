@@ -1,6 +1,7 @@
 package system
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -297,6 +298,10 @@ func downloadFileContent(url, filePath string) error {
 	if err := ValidateDownloadURL(url); err != nil {
 		return err
 	}
+	// Offline, every download is the copy rwr fetch made of it.
+	if IsOffline() {
+		return copyFromCache(url, filePath)
+	}
 
 	// Send an HTTP GET request to the URL
 	response, err := DownloadClient.Get(url) // #nosec G107 -- scheme restricted by ValidateDownloadURL above and per redirect hop by the client
@@ -519,6 +524,89 @@ func RemoveSectionFromFile(filePath, section string, elevated bool) error {
 	return replaceFileContent(filePath, strings.Join(kept, "\n"), elevated)
 }
 
+// WriteFileChecked replaces filePath with content only once check accepts the
+// staged copy, so a file that would break something when installed - a sudoers
+// snippet that locks every administrator out - never reaches its target.
+//
+// The staged name ends in ".tmp" and starts with a dot: when staging lands in
+// the target directory itself, programs that read every file in a drop-in
+// directory (sudo's includedir, for one) skip dotted names, so the unchecked
+// copy is never read during the window before check runs.
+func WriteFileChecked(filePath, content string, mode os.FileMode, elevated bool, check func(staged string) error) error {
+	staged, err := stageChecked(filePath, content, mode, check)
+	if err != nil {
+		return err
+	}
+	return moveIntoPlace(staged, filePath, mode, elevated)
+}
+
+// InstallRootFileChecked is WriteFileChecked for a file root must own, in a
+// directory only root can write: a sudoers or polkit drop-in. The checked copy
+// is installed root:root with mode under a dotted name beside the target and
+// renamed over it, so the target never exists owned by the invoking user -
+// sudo refuses an include file it does not trust, the very lockout the check
+// is there to prevent - nor half written.
+func InstallRootFileChecked(filePath, content string, mode os.FileMode, check func(staged string) error) error {
+	staged, err := stageChecked(filePath, content, mode, check)
+	if err != nil {
+		return err
+	}
+	defer removeStaged(staged)
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("error naming staged file: %v", err)
+	}
+	rootStaged := filepath.Join(filepath.Dir(filePath), ".rwr-"+hex.EncodeToString(suffix)+".tmp")
+
+	install := types.Command{
+		Exec:     "install",
+		Args:     []string{"-o", "0", "-g", "0", "-m", fmt.Sprintf("%04o", mode.Perm()), "--", staged, rootStaged},
+		Elevated: true,
+	}
+	if err := RunCommand(install, false); err != nil {
+		return fmt.Errorf("error staging %s as root: %v", filePath, err)
+	}
+	if err := moveFileWithElevatedPrivileges(rootStaged, filePath); err != nil {
+		cleanup := types.Command{Exec: "rm", Args: []string{"-f", "--", rootStaged}, Elevated: true}
+		if rmErr := RunCommand(cleanup, false); rmErr != nil {
+			log.Debugf("could not remove staging file %s: %v", rootStaged, rmErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// stageChecked writes content to a staging file with mode and runs check on
+// it, returning the staged path. A rejected or failed staging removes itself.
+func stageChecked(filePath, content string, mode os.FileMode, check func(staged string) error) (string, error) {
+	tempFile, err := tempFileNextTo(filePath, ".rwr-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file: %v", err)
+	}
+
+	if _, err := tempFile.WriteString(content); err != nil {
+		cleanupStaged(tempFile)
+		return "", fmt.Errorf("error writing to temporary file: %v", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		removeStaged(tempFile.Name())
+		return "", fmt.Errorf("error closing temporary file: %v", err)
+	}
+	if chmodErr := os.Chmod(tempFile.Name(), mode); chmodErr != nil && runtime.GOOS != "windows" {
+		removeStaged(tempFile.Name())
+		return "", fmt.Errorf("error setting permissions on temporary file: %v", chmodErr)
+	}
+
+	if check != nil {
+		if err := check(tempFile.Name()); err != nil {
+			removeStaged(tempFile.Name())
+			return "", fmt.Errorf("refusing to install %s: %w", filePath, err)
+		}
+	}
+	return tempFile.Name(), nil
+}
+
 // replaceFileContent stages content next to the target and moves it into place,
 // keeping the target's existing mode.
 func replaceFileContent(filePath, content string, elevated bool) error {
@@ -29,3 +29,13 @@ func ownedByRootOrEUID(info os.FileInfo) bool {
 	}
 	return st.Uid == 0 || int(st.Uid) == os.Geteuid()
 }
+
+// OwnedByRoot reports whether the file belongs to root, user and group - what
+// sudo requires of an include file before it will read it.
+func OwnedByRoot(info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return st.Uid == 0 && st.Gid == 0
+}
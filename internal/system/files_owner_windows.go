@@ -16,3 +16,8 @@ func fileOwnedByEUID(os.FileInfo) bool {
 func ownedByRootOrEUID(os.FileInfo) bool {
 	return true
 }
+
+// OwnedByRoot: see fileOwnedByEUID - there is no root to own anything.
+func OwnedByRoot(os.FileInfo) bool {
+	return true
+}
@@ -8,13 +8,16 @@ import (
 	"github.com/fynxlabs/rwr/internal/types"
 )
 
+// macOSPreferredManagers is the default package manager preference on macOS.
+var macOSPreferredManagers = []string{"brew", "macports"}
+
 // SetMacOSDetails populates the OSInfo struct with macOS-specific package manager
 // details, detecting Homebrew and other available providers.
 func SetMacOSDetails(osInfo *types.OSInfo) error {
 	log.Debug("Setting macOS package manager details.")
 
 	collectAvailableManagers(osInfo)
-	setDefaultManager(osInfo, []string{"brew", "macports"})
+	setDefaultManager(osInfo, macOSPreferredManagers)
 
 	return nil
 }
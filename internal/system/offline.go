@@ -0,0 +1,201 @@
+package system
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// The offline cache is what `rwr fetch` downloads and an --offline run takes
+// in place of the network. Under the cache directory:
+//
+//	urls/<sha256 of the URL>/<file name>  URL-sourced files and step downloads
+//	fonts/<name>.tar.xz                   Nerd Font archives
+//	git/<sha256 of the URL>.git           bare mirrors of git repositories
+//	packages/<provider>/                  each package manager's own cache
+//	packages.json                         the packages each provider fetched
+var (
+	offlineMu    sync.RWMutex
+	offlineMode  bool
+	offlineCache string
+)
+
+// ErrNotCached is an offline run reaching for something fetch did not
+// download.
+var ErrNotCached = errors.New("not in the offline cache; run rwr fetch where there is network access")
+
+// fetchedIndexName records the packages fetch downloaded: a package
+// manager's cache holds files named by version and architecture, not by the
+// names a blueprint installs.
+const fetchedIndexName = "packages.json"
+
+// SetOffline enables or disables offline mode globally.
+func SetOffline(enabled bool) {
+	offlineMu.Lock()
+	offlineMode = enabled
+	offlineMu.Unlock()
+}
+
+// IsOffline returns whether offline mode is enabled.
+func IsOffline() bool {
+	offlineMu.RLock()
+	defer offlineMu.RUnlock()
+	return offlineMode
+}
+
+// SetCacheDir sets where fetch downloads to and an offline run reads from.
+func SetCacheDir(dir string) {
+	offlineMu.Lock()
+	offlineCache = dir
+	offlineMu.Unlock()
+}
+
+// CacheDir is the offline cache directory.
+func CacheDir() string {
+	offlineMu.RLock()
+	defer offlineMu.RUnlock()
+	return offlineCache
+}
+
+// CachedURLPath is where the cache keeps url's download. The directory is
+// the URL's hash - two URLs may end in the same file name - and the file
+// keeps the URL's own name so an archive's extension survives.
+func CachedURLPath(rawURL string) string {
+	name := "download"
+	if parsed, err := url.Parse(rawURL); err == nil {
+		if base := path.Base(parsed.Path); base != "." && base != "/" && base != "" {
+			name = base
+		}
+	}
+	return filepath.Join(CacheDir(), "urls", urlKey(rawURL), name)
+}
+
+// CachedFontPath is where the cache keeps a Nerd Font's archive.
+func CachedFontPath(name string) string {
+	return filepath.Join(CacheDir(), "fonts", name+".tar.xz")
+}
+
+// CachedGitPath is where the cache keeps a bare mirror of a git repository.
+func CachedGitPath(rawURL string) string {
+	return filepath.Join(CacheDir(), "git", urlKey(rawURL)+".git")
+}
+
+// ProviderCacheDir is a package manager's own directory in the cache: what
+// {{ .CacheDir }} renders to in its offline commands.
+func ProviderCacheDir(provider string) string {
+	return filepath.Join(CacheDir(), "packages", provider)
+}
+
+func urlKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// OfflineCommand is one of a provider's offline commands - always a verb of
+// the provider's own binary - with {{ .CacheDir }} rendered per argument, so
+// a cache path with a space in it stays one argument, and the environment it
+// runs under: the provider's own plus the offline block's.
+func OfflineCommand(provider *types.Provider, command string) (string, []string, map[string]string) {
+	dir := ProviderCacheDir(provider.Name)
+	render := func(s string) string { return strings.ReplaceAll(s, "{{ .CacheDir }}", dir) }
+
+	// The placeholder has spaces of its own: it is swapped for a token
+	// without any before the command is split, and rendered after.
+	const token = "\x00cachedir\x00"
+	fields := strings.Fields(strings.ReplaceAll(command, "{{ .CacheDir }}", token))
+	rendered := make([]string, 0, len(fields))
+	for _, arg := range fields {
+		rendered = append(rendered, strings.ReplaceAll(arg, token, dir))
+	}
+
+	environment := map[string]string{}
+	for key, value := range provider.Environment {
+		environment[key] = value
+	}
+	if provider.Offline != nil {
+		for key, value := range provider.Offline.Environment {
+			environment[key] = render(value)
+		}
+	}
+	return provider.BinPath, rendered, environment
+}
+
+// FetchURL downloads url into the cache, verified against sha256Hex when one
+// is given. A URL already cached is not downloaded again.
+func FetchURL(rawURL, sha256Hex string) error {
+	target := CachedURLPath(rawURL)
+	if FileExists(target) {
+		if sha256Hex == "" {
+			return nil
+		}
+		if err := verifyFileSHA256(target, sha256Hex); err == nil {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil { // #nosec G301 -- cache directory; its contents are public downloads
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+	return DownloadFileWithChecksum(rawURL, target, false, sha256Hex)
+}
+
+// copyFromCache is an offline download: url's cached copy, written into the
+// staging file the download would have filled.
+func copyFromCache(rawURL, filePath string) error {
+	cached := CachedURLPath(rawURL)
+	if !FileExists(cached) {
+		return fmt.Errorf("%s: %w", rawURL, ErrNotCached)
+	}
+	return copyFileContentMode(cached, filePath, 0o600)
+}
+
+// RecordFetchedPackage adds name to the packages fetch has downloaded for
+// provider.
+func RecordFetchedPackage(provider, name string) error {
+	fetched, err := FetchedPackages()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(fetched[provider], name) {
+		return nil
+	}
+	fetched[provider] = append(fetched[provider], name)
+	sort.Strings(fetched[provider])
+
+	data, err := json.MarshalIndent(fetched, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(CacheDir(), 0o755); err != nil { // #nosec G301 -- cache directory; its contents are public downloads
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+	return os.WriteFile(filepath.Join(CacheDir(), fetchedIndexName), append(data, '\n'), 0o644) // #nosec G306 -- an index of package names
+}
+
+// FetchedPackages is every package fetch has downloaded, by provider. An
+// empty cache has none.
+func FetchedPackages() (map[string][]string, error) {
+	fetched := map[string][]string{}
+	data, err := os.ReadFile(filepath.Join(CacheDir(), fetchedIndexName)) // #nosec G304 -- rwr's own cache index
+	if errors.Is(err, os.ErrNotExist) {
+		return fetched, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fetched); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", fetchedIndexName, err)
+	}
+	return fetched, nil
+}
@@ -0,0 +1,74 @@
+package system
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// What fetch downloads, an offline download takes from the cache - verified
+// against the same digest - with the network gone; what it did not is
+// ErrNotCached, not an attempt to reach the network.
+func TestDownloadFile_OfflineFromCache(t *testing.T) {
+	body := "payload"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	url := server.URL + "/tool.tar.gz"
+	sum := sha256.Sum256([]byte(body))
+	digest := hex.EncodeToString(sum[:])
+
+	SetCacheDir(t.TempDir())
+	defer SetCacheDir("")
+	if err := FetchURL(url, digest); err != nil {
+		t.Fatalf("FetchURL: %v", err)
+	}
+	server.Close()
+
+	SetOffline(true)
+	defer SetOffline(false)
+	target := filepath.Join(t.TempDir(), "tool.tar.gz")
+	if err := DownloadFileWithChecksum(url, target, false, digest); err != nil {
+		t.Fatalf("offline download: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != body {
+		t.Fatalf("target = %q, %v; want the cached payload", data, err)
+	}
+
+	err := DownloadFile(server.URL+"/other", filepath.Join(t.TempDir(), "other"), false)
+	if !errors.Is(err, ErrNotCached) {
+		t.Fatalf("err = %v, want ErrNotCached", err)
+	}
+}
+
+// A cache path with a space in it stays one argument, and the offline
+// environment is rendered over the provider's own.
+func TestOfflineCommand_RendersCacheDirPerArgument(t *testing.T) {
+	SetCacheDir(filepath.Join(t.TempDir(), "my cache"))
+	defer SetCacheDir("")
+	provider := &types.Provider{
+		Name:        "brew",
+		BinPath:     "/opt/homebrew/bin/brew",
+		Environment: map[string]string{"HOMEBREW_NO_ANALYTICS": "1"},
+		Offline: &types.OfflineConfig{
+			Install:     "install --cachedir {{ .CacheDir }}",
+			Environment: map[string]string{"HOMEBREW_CACHE": "{{ .CacheDir }}"},
+		},
+	}
+	bin, args, environment := OfflineCommand(provider, provider.Offline.Install)
+	dir := ProviderCacheDir("brew")
+	if bin != provider.BinPath || strings.Join(args, "|") != "install|--cachedir|"+dir {
+		t.Fatalf("command = %s %q", bin, args)
+	}
+	if environment["HOMEBREW_CACHE"] != dir || environment["HOMEBREW_NO_ANALYTICS"] != "1" {
+		t.Fatalf("environment = %v", environment)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"sync"
 
 	"charm.land/log/v2"
@@ -373,6 +374,11 @@ func LoadProviders(definitionsPath string) error {
 	}
 
 	for _, entry := range entries {
+		// The package alias table sits beside the providers it names, but
+		// is not one; PackageAliases reads it.
+		if entry.Name() == AliasesFileName {
+			continue
+		}
 		if !entry.IsDir() && (filepath.Ext(entry.Name()) == ".toml" || filepath.Ext(entry.Name()) == ".json" || filepath.Ext(entry.Name()) == ".cue") {
 			path := filepath.Join(definitionsPath, entry.Name())
 			if !isProviderFileTrusted(path) {
@@ -525,6 +531,19 @@ func GetProviderWithAlternatives(name string) (*types.Provider, bool) {
 	return &providerCopy, true
 }
 
+// ProviderNames returns the name of every loaded provider definition,
+// embedded and filesystem, whether or not its binary is on this system.
+func ProviderNames() []string {
+	if err := InitProviders(); err != nil {
+		log.Debugf("ProviderNames: %v", err)
+	}
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	names := getProviderNames()
+	sort.Strings(names)
+	return names
+}
+
 // getProviderNames returns a sorted list of provider names for logging.
 func getProviderNames() []string {
 	names := make([]string, 0, len(providers))
@@ -0,0 +1,70 @@
+package system
+
+import (
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// ProvidersFor returns the providers whose definitions declare the given
+// operating system and distribution, without looking for their binaries or
+// detection files: what a machine of that shape offers once its package
+// managers are installed. GetAvailableProviders answers the same question
+// for the machine rwr runs on, from evidence; this answers it for one rwr is
+// not running on, from declarations.
+//
+// The entries are copies, with BinPath set to the bare detection binary - a
+// plan built against them must not point at this machine's paths.
+func ProvidersFor(targetOS, targetDistro string) map[string]*types.Provider {
+	available := make(map[string]*types.Provider)
+	if err := InitProviders(); err != nil {
+		log.Errorf("ProvidersFor: Error initializing providers: %v", err)
+		return available
+	}
+
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	for name, provider := range providers {
+		if !targetsOS(provider, targetOS) || !supportsSystem(provider, targetOS, targetDistro) {
+			continue
+		}
+		declared := *provider
+		declared.BinPath = provider.Detection.Binary
+		available[name] = &declared
+	}
+	log.Debugf("ProvidersFor: %s/%s declares %v", targetOS, targetDistro, getAvailableProviderNames(available))
+	return available
+}
+
+// SimulateOS builds the OSInfo DetectOS would return on a target machine:
+// its system variables, the package managers ProvidersFor declares there,
+// and the default the per-OS preference picks among them. Nothing about
+// this machine leaks in; version is unknown and there are no tools.
+func SimulateOS(target types.Target) *types.OSInfo {
+	osInfo := &types.OSInfo{
+		System: types.System{
+			OS:       target.OS,
+			OSFamily: target.Distro,
+			OSArch:   target.Arch,
+		},
+	}
+	if target.OS != types.OSLinux {
+		// DetectOS reports the OS itself as the family off Linux.
+		osInfo.System.OSFamily = target.OS
+	}
+
+	osInfo.PackageManager.Managers = make(map[string]types.PackageManagerInfo)
+	for name, provider := range ProvidersFor(target.OS, target.Distro) {
+		osInfo.PackageManager.Managers[name] = GetPackageManagerInfo(provider, provider.BinPath)
+	}
+
+	switch target.OS {
+	case types.OSLinux:
+		setDefaultManager(osInfo, linuxPreferredManagers(osInfo))
+	case types.OSDarwin:
+		setDefaultManager(osInfo, macOSPreferredManagers)
+	case types.OSWindows:
+		setDefaultManager(osInfo, windowsPreferredManagers)
+	}
+	return osInfo
+}
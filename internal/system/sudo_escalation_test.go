@@ -136,7 +136,7 @@ func TestCancelledPasswordPromptMapsToRunCancellation(t *testing.T) {
 func TestAuthenticatedCommandRunsTheTargetInThePasswordReceivingSudo(t *testing.T) {
 	t.Parallel()
 
-	command := buildAuthenticatedCommand(types.Command{
+	command := buildAuthenticatedCommand(RunContext(), types.Command{
 		Exec:     "dscl",
 		Args:     []string{".", "-create", "/Users/levi", "UserShell", "/opt/homebrew/bin/fish"},
 		Elevated: true,
@@ -155,7 +155,7 @@ func TestPasswordInputKeepsSecretOutOfArgvAndPreservesCommandInput(t *testing.T)
 	if got, want := string(input), "correct horse battery staple\ntarget input\n"; got != want {
 		t.Fatalf("stdin = %q, want %q", got, want)
 	}
-	command := buildAuthenticatedCommand(types.Command{Exec: "chpasswd", Elevated: true})
+	command := buildAuthenticatedCommand(RunContext(), types.Command{Exec: "chpasswd", Elevated: true})
 	for _, arg := range command.Args {
 		if strings.Contains(arg, "correct horse") {
 			t.Fatal("password appeared in sudo argv")
@@ -293,7 +293,7 @@ func TestPromptedPasswordIsReusedBySeparateManagedSudo(t *testing.T) {
 		t.Fatalf("warming for self-escalating command: %v", err)
 	}
 	for range 2 {
-		command, input, err := commandForRun(types.Command{Exec: "dscl", Elevated: true})
+		command, input, err := commandForRun(RunContext(), types.Command{Exec: "dscl", Elevated: true})
 		if err != nil {
 			t.Fatalf("commandForRun: %v", err)
 		}
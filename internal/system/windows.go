@@ -8,13 +8,16 @@ import (
 	"github.com/fynxlabs/rwr/internal/types"
 )
 
+// windowsPreferredManagers is the default package manager preference on Windows.
+var windowsPreferredManagers = []string{"winget", "chocolatey", "scoop"}
+
 // SetWindowsDetails populates the OSInfo struct with Windows-specific package manager
 // details, detecting Chocolatey, Scoop, and WinGet providers.
 func SetWindowsDetails(osInfo *types.OSInfo) error {
 	log.Debug("Setting Windows package manager details.")
 
 	collectAvailableManagers(osInfo)
-	setDefaultManager(osInfo, []string{"winget", "chocolatey", "scoop"})
+	setDefaultManager(osInfo, windowsPreferredManagers)
 
 	return nil
 }
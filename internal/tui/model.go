@@ -218,7 +218,8 @@ func New(theme Theme, plan *types.Plan, store *reporting.Store, dryRun bool, run
 	// progresses (profiles can filter the runtime count below the plan's).
 	for _, res := range plan.Resources {
 		i, ok := m.order[res.Processor]
-		if !ok {
+		if !ok || res.Status == types.StatusSkipped {
+			// Entries a `when:` left out are summary rows, not lane work.
 			continue
 		}
 		name := res.Provider
@@ -332,7 +333,10 @@ func (m *Model) apply(e reporting.Event) tea.Cmd {
 		matched := false
 		for i := range m.plan.Resources {
 			planned := &m.plan.Resources[i]
-			if planned.Status != types.StatusPlanned || planned.Processor != res.Processor || planned.Name != res.Name {
+			// The run reports the `when:` skips the plan already lists;
+			// those match the plan's skipped row rather than duplicating it.
+			pending := planned.Status == types.StatusPlanned || (planned.Status == types.StatusSkipped && res.Status == types.StatusSkipped)
+			if !pending || planned.Processor != res.Processor || planned.Name != res.Name {
 				continue
 			}
 			if planned.Location != "" {
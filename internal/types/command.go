@@ -1,6 +1,9 @@
 package types
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 // minRedactableSecret is the shortest secret worth substring-replacing in a log
 // line. See LogArgs.
@@ -40,6 +43,12 @@ type Command struct {
 	// Stdin - but it should still never be written to a log file. Callers that put
 	// a credential in Args are expected to list it here.
 	Secrets []string `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
+
+	// Timeout bounds how long the command may run; zero means as long as the
+	// run does. Past it the command's process group is killed, the same way a
+	// cancelled run kills it, and the caller gets system.ErrTimedOut. A script's
+	// `timeout` is the blueprint-facing way to set it.
+	Timeout time.Duration `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
 }
 
 // LogArgs returns Args with every value in Secrets replaced, for logging.
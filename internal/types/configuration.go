@@ -4,6 +4,7 @@ type Configuration struct {
 	Name     string                 `mapstructure:"name,omitempty" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`
 	Names    []string               `mapstructure:"names,omitempty" yaml:"names,omitempty" json:"names,omitempty" toml:"names,omitempty"`
 	Profiles []string               `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+	When     string                 `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`
 	Action   string                 `mapstructure:"action" yaml:"action" json:"action" toml:"action"`
 	Elevated bool                   `mapstructure:"elevated,omitempty" yaml:"elevated,omitempty" json:"elevated,omitempty" toml:"elevated,omitempty"`
 	Tool     string                 `mapstructure:"tool" yaml:"tool" json:"tool" toml:"tool"`
@@ -17,6 +18,10 @@ type Configuration struct {
 	Kind     string                 `mapstructure:"kind,omitempty" yaml:"kind,omitempty" json:"kind,omitempty" toml:"kind,omitempty"`
 	Type     string                 `mapstructure:"type,omitempty" yaml:"type,omitempty" json:"type,omitempty" toml:"type,omitempty"`
 	Settings map[string]interface{} `mapstructure:"settings,omitempty" yaml:"settings,omitempty" json:"settings,omitempty" toml:"settings,omitempty"`
+	Group    string                 `mapstructure:"group,omitempty" yaml:"group,omitempty" json:"group,omitempty" toml:"group,omitempty"`             // kde: config group
+	Channel  string                 `mapstructure:"channel,omitempty" yaml:"channel,omitempty" json:"channel,omitempty" toml:"channel,omitempty"`     // xfconf: channel
+	Property string                 `mapstructure:"property,omitempty" yaml:"property,omitempty" json:"property,omitempty" toml:"property,omitempty"` // xfconf: property path
+	Section  string                 `mapstructure:"section,omitempty" yaml:"section,omitempty" json:"section,omitempty" toml:"section,omitempty"`     // ini: section
 }
 
 type ConfigData struct {
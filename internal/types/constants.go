@@ -42,15 +42,29 @@ const (
 const (
 	ActionInstall = "install"
 	ActionRemove  = "remove"
+	// ActionUpgrade upgrades the named packages through the provider's upgrade
+	// command, or - with no names - everything the provider manages.
+	//
 	// There is deliberately no per-package "update" action. Validation accepted one
 	// and the processor never implemented it, so `action: update` passed
 	// `rwr validate` and then failed the run. It cannot be implemented on top of
 	// the providers' `update` commands either: they disagree about what update
 	// means - apt's refreshes the package lists, while pacman's is a full system
 	// upgrade - so appending a package name would do something different, and in
-	// pacman's case drastic, on each distribution.
+	// pacman's case drastic, on each distribution. Upgrade uses the separate
+	// `upgrade` command each provider declares for exactly one package.
+	ActionUpgrade = "upgrade"
+	// ActionHold and ActionUnhold pin a package at its installed version, and
+	// release it, through the provider's hold commands (apt-mark hold, dnf
+	// versionlock, brew pin). The hold lives in the package manager, so every
+	// later upgrade - rwr's or anyone's - leaves the package alone.
+	ActionHold   = "hold"
+	ActionUnhold = "unhold"
 )
 
+// PackageActions is every action a package entry may declare.
+var PackageActions = []string{ActionInstall, ActionRemove, ActionUpgrade, ActionHold, ActionUnhold}
+
 // Service actions for service management operations.
 const (
 	GitActionClone = "clone"
@@ -59,6 +73,17 @@ const (
 	// ConfigurationActionSet is the only action the configuration tools implement.
 	ConfigurationActionSet = "set"
 
+	// Configuration tools. Each reads a key back before writing it, so an
+	// unchanged key is left alone; the value it replaced is journaled, and for
+	// kde, xfconf and ini uninstall restores it.
+	ConfigurationToolDconf           = "dconf"
+	ConfigurationToolGSettings       = "gsettings"
+	ConfigurationToolMacOSDefaults   = "macos_defaults"
+	ConfigurationToolWindowsRegistry = "windows_registry"
+	ConfigurationToolKDE             = "kde"
+	ConfigurationToolXfconf          = "xfconf"
+	ConfigurationToolINI             = "ini"
+
 	ServiceActionEnable  = "enable"
 	ServiceActionDisable = "disable"
 	ServiceActionStart   = "start"
@@ -115,4 +140,18 @@ const (
 	// implemented "remove", so each name failed at the opposite end of the run:
 	// "delete" validated and then aborted, "remove" ran and failed validation.
 	UserActionDelete = "delete"
+	// UserActionShell changes only the account's login shell. It needs none of
+	// the other user fields, and it makes sure the shell is installed and listed
+	// in /etc/shells first - chsh refuses a shell that is not.
+	UserActionShell = "shell"
+)
+
+// Policy actions for sudoers and polkit rules: the rule file is written, or it
+// is removed.
+const (
+	PolicyActionCreate = "create"
+	PolicyActionRemove = "remove"
 )
+
+// PolkitResults are the authorization answers a polkit rule may return.
+var PolkitResults = []string{"yes", "no", "auth_self", "auth_self_keep", "auth_admin", "auth_admin_keep"}
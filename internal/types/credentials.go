@@ -232,6 +232,24 @@ func ExportedCredentialEnv() map[string]string {
 	return out
 }
 
+// ScriptCredentialValue returns a credential for a script's env_credentials:
+// declared, opted into via exposeCredentials, in a scope that admits scripts,
+// and resolved. It is the same gate as ExportedCredentialEnv, for a value the
+// blueprint names rather than one every script receives.
+func ScriptCredentialValue(name string) (string, error) {
+	if !IsManagedCredential(name) {
+		return "", fmt.Errorf("credential %q is not declared in the init file's credentials section", name)
+	}
+	if !IsCredentialExposed(name) || !credentialInSurface(name, CredentialSurfaceScripts) {
+		return "", fmt.Errorf("credential %q is not exposed to scripts (see exposeCredentials and the credential's scope)", name)
+	}
+	value, ok := CredentialValue(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("credential %q has no value", name)
+	}
+	return value, nil
+}
+
 // TemplateCredentialNames returns the exposed template-scope names, for the
 // debug dump of template variables: the names are loggable, the values are not.
 func TemplateCredentialNames() []string {
@@ -0,0 +1,64 @@
+package types
+
+import "maps"
+
+// Facts describes the machine a run applies to, beyond what System says about
+// its operating system: what kind of hardware it is, what it runs inside, and
+// what the operator's own facts.d executables report. Blueprints read them as
+// {{ .Facts.<key> }} and test them in `when:` conditions.
+//
+// Every fact is best effort. One that cannot be read is left at its zero
+// value - an empty string, 0, false - rather than failing the run: a
+// condition on a fact the machine cannot report should be false, not fatal.
+type Facts struct {
+	Hostname string
+	CPUs     int
+	// MemoryMB is the installed memory in mebibytes.
+	MemoryMB int
+	// GPUVendors lists the display adapters' vendors ("nvidia", "amd",
+	// "intel", ...), in the order the machine lists them. GPUVendor is the
+	// first, which on a hybrid laptop is usually the integrated one.
+	GPUVendors []string
+	GPUVendor  string
+	// Laptop is true when the machine has a battery.
+	Laptop bool
+	// Virtualization names the hypervisor the machine is a guest of ("kvm",
+	// "vmware", "virtualbox", ...), empty on bare metal. Container names the
+	// container runtime it runs in ("docker", "podman", "lxc", ...), empty
+	// when it is not in one.
+	Virtualization string
+	Container      string
+	// Desktop is the desktop environment of the session rwr runs in ("gnome",
+	// "kde", "sway", ...), lowercased; empty without one. Windows and macOS
+	// report "windows" and "aqua".
+	Desktop string
+	// Custom holds the facts the facts.d executables report, by name.
+	Custom map[string]interface{}
+}
+
+// ToMap exposes the facts to templates as {{ .Facts.* }}. A nil Facts - a
+// tree read without gathering them, as validate does - exposes every key at
+// its zero value, so references still resolve and typos are still caught.
+func (f *Facts) ToMap() map[string]interface{} {
+	if f == nil {
+		f = &Facts{}
+	}
+	custom := map[string]interface{}{}
+	maps.Copy(custom, f.Custom)
+	gpus := f.GPUVendors
+	if gpus == nil {
+		gpus = []string{}
+	}
+	return map[string]interface{}{
+		"hostname":       f.Hostname,
+		"cpus":           f.CPUs,
+		"memoryMB":       f.MemoryMB,
+		"gpuVendors":     gpus,
+		"gpuVendor":      f.GPUVendor,
+		"laptop":         f.Laptop,
+		"virtualization": f.Virtualization,
+		"container":      f.Container,
+		"desktop":        f.Desktop,
+		"custom":         custom,
+	}
+}
@@ -212,6 +212,7 @@ type File struct {
 	Name        string                 `mapstructure:"name" yaml:"name" json:"name" toml:"name"`
 	Names       []string               `mapstructure:"names,omitempty" yaml:"names,omitempty" json:"names,omitempty" toml:"names,omitempty"`
 	Profiles    []string               `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+	When        string                 `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`
 	Action      string                 `mapstructure:"action" yaml:"action" json:"action" toml:"action"`
 	Content     string                 `mapstructure:"content,omitempty" yaml:"content,omitempty" json:"content,omitempty" toml:"content,omitempty"`
 	Source      string                 `mapstructure:"source,omitempty" yaml:"source,omitempty" json:"source,omitempty" toml:"source,omitempty"`
@@ -230,6 +231,7 @@ type Directory struct {
 	Name        string   `mapstructure:"name,omitempty" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`
 	Names       []string `mapstructure:"names,omitempty" yaml:"names,omitempty" json:"names,omitempty" toml:"names,omitempty"`
 	Profiles    []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+	When        string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`
 	Action      string   `mapstructure:"action" yaml:"action" json:"action" toml:"action"`
 	Source      string   `mapstructure:"source,omitempty" yaml:"source,omitempty" json:"source,omitempty" toml:"source,omitempty"`
 	Target      string   `mapstructure:"target" yaml:"target" json:"target" toml:"target"`
@@ -4,6 +4,7 @@ type Font struct {
 	Name     string   `mapstructure:"name" yaml:"name" json:"name" toml:"name"`
 	Names    []string `mapstructure:"names,omitempty" yaml:"names,omitempty" json:"names,omitempty" toml:"names,omitempty"`
 	Profiles []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+	When     string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`
 	Action   string   `mapstructure:"action" yaml:"action" json:"action" toml:"action"`
 	Provider string   `mapstructure:"provider,omitempty" yaml:"provider,omitempty" json:"provider,omitempty" toml:"provider,omitempty"`
 	Location string   `mapstructure:"location,omitempty" yaml:"location,omitempty" json:"location,omitempty" toml:"location,omitempty"`
@@ -11,6 +11,7 @@ type GitOptions struct {
 type Git struct {
 	Name        string   `mapstructure:"name" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`                                       // Name of the git operation
 	Profiles    []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`             // Profiles this git operation belongs to
+	When        string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`                             // Condition that must hold for this entry to apply
 	Action      string   `mapstructure:"action" yaml:"action,omitempty" json:"action,omitempty" toml:"action,omitempty"`                               // Action to perform with git
 	Path        string   `mapstructure:"path" yaml:"path,omitempty" json:"path,omitempty" toml:"path,omitempty"`                                       // Path for the git operation
 	URL         string   `mapstructure:"url" yaml:"url,omitempty" json:"url,omitempty" toml:"url,omitempty"`                                           // URL of the git repository
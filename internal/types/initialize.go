@@ -31,6 +31,7 @@ type Flags struct {
 	ConfigLocation   string
 	RunOnceLocation  string
 	Profiles         []string
+	Prune            bool
 }
 
 type System struct {
@@ -46,9 +47,12 @@ type System struct {
 // at runtime and are explicitly not decodable, so a blueprint cannot claim to be
 // running as a different user or with different flags than it is.
 type Variables struct {
-	Flags       Flags                  `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
-	User        UserInfo               `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
-	System      System                 `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
+	Flags  Flags    `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
+	User   UserInfo `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
+	System System   `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
+	// Facts is nil until a run gathers them; a nil Facts also tells stage 1
+	// not to evaluate `when:` conditions (see Facts.ToMap).
+	Facts       *Facts                 `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
 	UserDefined map[string]interface{} `mapstructure:"userDefined,omitempty" yaml:"userDefined,omitempty" json:"userDefined,omitempty" toml:"userDefined,omitempty"`
 }
 
@@ -56,6 +60,11 @@ type Variables struct {
 type InitConfig struct {
 	Init            Init                 `mapstructure:"blueprints" yaml:"blueprints" json:"blueprints" toml:"blueprints"`
 	PackageManagers []PackageManagerInfo `mapstructure:"packageManagers,omitempty" yaml:"packageManagers,omitempty" json:"packageManagers,omitempty" toml:"packageManagers,omitempty"`
+	// DefaultPackageManager is the provider chain package entries that pin no
+	// package_manager install through, in place of the machine's one default
+	// provider: the first provider in it that is available and has the
+	// package wins. See docs/blueprints/packages.md.
+	DefaultPackageManager ProviderChain `mapstructure:"default_package_manager,omitempty" yaml:"default_package_manager,omitempty" json:"default_package_manager,omitempty" toml:"default_package_manager,omitempty"`
 	// The inline resource sections (repositories, packages, services, files,
 	// templates, directories, configuration) are gone: they were decoded,
 	// validated, profile-counted - and never applied at runtime. Blueprints
@@ -73,6 +82,22 @@ type InitConfig struct {
 	// (resolved up front, redacted, withheld); exposing it to blueprints still
 	// requires ExposeCredentials. See docs/credentials.md.
 	Credentials []CredentialSpec `mapstructure:"credentials,omitempty" yaml:"credentials,omitempty" json:"credentials,omitempty" toml:"credentials,omitempty"`
+	// DefaultProfiles are activated when no profile is requested and no
+	// HostProfiles rule matches, in place of the everything-applies default.
+	// See docs/profiles.md.
+	DefaultProfiles []string `mapstructure:"default_profiles,omitempty" yaml:"default_profiles,omitempty" json:"default_profiles,omitempty" toml:"default_profiles,omitempty"`
+	// HostProfiles maps machines - by hostname, OS, distro or facts - to the
+	// profiles they activate without a --profile.
+	HostProfiles []ProfileRule `mapstructure:"host_profiles,omitempty" yaml:"host_profiles,omitempty" json:"host_profiles,omitempty" toml:"host_profiles,omitempty"`
+	// ActiveProfiles records why each of this run's profiles is active; it is
+	// computed once the machine's facts are known, never decoded.
+	ActiveProfiles []ProfileActivation `mapstructure:"-" yaml:"-" json:"-" toml:"-"`
+	// Lint configures `rwr lint` for this tree; runs ignore it. See
+	// docs/cli/lint.md.
+	Lint *LintConfig `mapstructure:"lint,omitempty" yaml:"lint,omitempty" json:"lint,omitempty" toml:"lint,omitempty"`
+	// Prune opts package managers into `rwr prune` and `--prune`, and lists
+	// what each must keep. Nil prunes nothing. See docs/cli/prune.md.
+	Prune *PruneConfig `mapstructure:"prune,omitempty" yaml:"prune,omitempty" json:"prune,omitempty" toml:"prune,omitempty"`
 }
 
 func (u UserInfo) ToMap() map[string]interface{} {
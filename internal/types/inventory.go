@@ -0,0 +1,25 @@
+package types
+
+// Inventory lists the machines `rwr all --inventory` pushes a tree to.
+type Inventory struct {
+	Hosts []InventoryHost `mapstructure:"hosts" yaml:"hosts" json:"hosts" toml:"hosts"`
+}
+
+// InventoryHost is one machine reachable over SSH. Address is what ssh
+// takes ([user@]host, or a Host alias from ~/.ssh/config); Name defaults to
+// it and names the host in output and in its journal copy.
+type InventoryHost struct {
+	Name     string   `mapstructure:"name,omitempty" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`
+	Address  string   `mapstructure:"address" yaml:"address" json:"address" toml:"address"`
+	Port     int      `mapstructure:"port,omitempty" yaml:"port,omitempty" json:"port,omitempty" toml:"port,omitempty"`
+	Identity string   `mapstructure:"identity,omitempty" yaml:"identity,omitempty" json:"identity,omitempty" toml:"identity,omitempty"`
+	Profiles []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+}
+
+// Label is the host's name in output: Name, else Address.
+func (h InventoryHost) Label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.Address
+}
@@ -0,0 +1,13 @@
+package types
+
+// LintConfig is the init file's lint block: which profiles the tree
+// declares, and per-rule severity overrides.
+type LintConfig struct {
+	// Profiles declares the profiles this tree uses. When set, an entry
+	// naming any other profile, or a declared profile no entry names, is a
+	// finding. Empty leaves profiles unchecked.
+	Profiles []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+	// Rules maps a rule ID to the severity it reports at - error, warning
+	// or info - or to off.
+	Rules map[string]string `mapstructure:"rules,omitempty" yaml:"rules,omitempty" json:"rules,omitempty" toml:"rules,omitempty"`
+}
@@ -15,6 +15,17 @@ type ResolvedFile struct {
 	Format    string
 	Raw       []byte
 	Resolved  []byte
+	// Skipped are the entries a false `when:` condition left out of
+	// Resolved.
+	Skipped []SkippedEntry
+}
+
+// SkippedEntry is one blueprint entry whose `when:` condition was false on
+// this machine: the condition, and a JSON document declaring only that entry
+// under its section key, so it can be enumerated like any other.
+type SkippedEntry struct {
+	When     string
+	Document []byte
 }
 
 // ProviderState is one detected provider and the lane it will run.
@@ -42,11 +53,33 @@ const (
 type Resource struct {
 	Processor string
 	Provider  string // empty for files, services, git, scripts
-	Name      string // "neovim", "~/.config/nvim/"
+	// Candidates is a package entry's fallback chain, when it has one:
+	// Provider is the first of them the machine has, and a run installs
+	// through whichever of them first has the package.
+	Candidates ProviderChain
+	Name       string // "neovim", "~/.config/nvim/"
 	// Location identifies resources whose name is not unique: the destination
 	// of a file/directory or the target of a git checkout. Empty for resources
 	// such as packages and services that are identified by name.
 	Location string
+	// Desired is the declared value for resources whose state is a value
+	// rather than presence: a user's login shell, a configuration key's
+	// value in the form the tool prints it, a run_once script's content
+	// hash. Status compares it against what the machine reports. Empty for
+	// everything else.
+	Desired string
+	// Setting addresses a configuration resource's key (or, for dconf, its
+	// resolved keyfile) for status to read back. Nil when the entry cannot be
+	// read back, and for every other processor.
+	Setting *Configuration
+	// Marker is a run_once script's marker file, whose recorded hash status
+	// compares with Desired. Empty for everything else.
+	Marker string
+	// File is the blueprint that declares the resource.
+	File string
+	// Profiles are the profiles the declaring entry belongs to; empty for
+	// base entries.
+	Profiles []string
 	Action   string // install, copy, enable, clone
 	Status   Status
 	Detail   string
@@ -87,6 +120,9 @@ type Plan struct {
 	Order     []string
 	Files     map[string][]ResolvedFile
 	Providers []ProviderState
-	Resources []Resource
-	Diags     []Diagnostic
+	// DefaultProvider installs the package entries that pin no
+	// package_manager; empty when the machine has none.
+	DefaultProvider string
+	Resources       []Resource
+	Diags           []Diagnostic
 }
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// ProfileRule is one entry of the init file's `host_profiles:` section: the
+// profiles a machine activates by itself when it matches. Every selector the
+// rule sets must match; a rule sets at least one.
+type ProfileRule struct {
+	// Hosts are hostnames or hostname globs ("work-*"), any of which may
+	// match. Matching ignores case and tries both the full hostname and its
+	// first label, so "laptop" matches laptop.local.
+	Hosts []string `mapstructure:"hosts,omitempty" yaml:"hosts,omitempty" json:"hosts,omitempty" toml:"hosts,omitempty"`
+	// OS is the operating system: linux, darwin or windows.
+	OS string `mapstructure:"os,omitempty" yaml:"os,omitempty" json:"os,omitempty" toml:"os,omitempty"`
+	// Distro is the distribution, as {{ .System.osFamily }} reports it.
+	Distro string `mapstructure:"distro,omitempty" yaml:"distro,omitempty" json:"distro,omitempty" toml:"distro,omitempty"`
+	// When is a condition over facts and variables, as an entry's `when:`.
+	When string `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`
+	// Profiles are activated when the rule matches.
+	Profiles []string `mapstructure:"profiles" yaml:"profiles" json:"profiles" toml:"profiles"`
+}
+
+// Describe names the rule's selectors, for the reason a profile is active.
+func (r ProfileRule) Describe() string {
+	var parts []string
+	if len(r.Hosts) > 0 {
+		parts = append(parts, "hosts "+strings.Join(r.Hosts, ", "))
+	}
+	if r.OS != "" {
+		parts = append(parts, "os "+r.OS)
+	}
+	if r.Distro != "" {
+		parts = append(parts, "distro "+r.Distro)
+	}
+	if r.When != "" {
+		parts = append(parts, "when "+r.When)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// DecodeProfileRules strictly decodes the raw `host_profiles:` section as read
+// by viper, for the same reason DecodeCredentialSpecs does: a misspelled
+// selector would otherwise vanish, and a rule with no selectors left matches
+// nothing - a machine silently missing its profiles.
+func DecodeProfileRules(raw interface{}) ([]ProfileRule, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var rules []ProfileRule
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:      &rules,
+		ErrorUnused: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return nil, fmt.Errorf("host_profiles section: %w", err)
+	}
+	return rules, ValidateProfileRules(rules)
+}
+
+// ValidateProfileRules rejects a rule rwr cannot apply: one with no selector,
+// one activating nothing, or a hostname glob that does not parse. A `when`
+// condition is checked where conditions are parsed (helpers.EvalWhen).
+func ValidateProfileRules(rules []ProfileRule) error {
+	for i, rule := range rules {
+		if len(rule.Hosts) == 0 && rule.OS == "" && rule.Distro == "" && rule.When == "" {
+			return fmt.Errorf("host_profiles[%d] has no selector: set hosts, os, distro or when", i)
+		}
+		if len(rule.Profiles) == 0 {
+			return fmt.Errorf("host_profiles[%d] (%s) activates no profiles", i, rule.Describe())
+		}
+		for _, host := range rule.Hosts {
+			if _, err := path.Match(host, ""); err != nil {
+				return fmt.Errorf("host_profiles[%d]: hosts pattern %q: %w", i, host, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ProfileActivation is one active profile and why it is active: "--profile",
+// "default_profiles", or the host_profiles rule that matched.
+type ProfileActivation struct {
+	Profile string
+	Reason  string
+}
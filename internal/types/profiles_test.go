@@ -0,0 +1,23 @@
+package types
+
+import "testing"
+
+func TestDecodeProfileRules(t *testing.T) {
+	rules, err := DecodeProfileRules([]interface{}{
+		map[string]interface{}{"hosts": []interface{}{"work-*"}, "profiles": []interface{}{"work"}},
+	})
+	if err != nil || len(rules) != 1 || rules[0].Hosts[0] != "work-*" {
+		t.Fatalf("rules = %+v, %v", rules, err)
+	}
+
+	for name, raw := range map[string]interface{}{
+		"misspelled selector": map[string]interface{}{"host": "work-*", "profiles": []interface{}{"work"}},
+		"no selector":         map[string]interface{}{"profiles": []interface{}{"work"}},
+		"no profiles":         map[string]interface{}{"os": "linux"},
+		"bad glob":            map[string]interface{}{"hosts": []interface{}{"work-["}, "profiles": []interface{}{"work"}},
+	} {
+		if _, err := DecodeProfileRules([]interface{}{raw}); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderChain is a package entry's package_manager: one provider, or a
+// list of them to fall back through, written either as a string or as a list.
+//
+//	package_manager: apt
+//	package_manager: [apt, flatpak, cargo]
+//
+// One provider is a demand: the entry installs through it or fails. A list
+// is a preference order: the entry installs through the first provider in it
+// that is available here and has the package (see docs/blueprints/packages.md).
+// Unlike ScriptArgs the string form is never split - a provider name has no
+// spaces in it, and "apt flatpak" is a mistake worth reporting, not a list.
+type ProviderChain []string
+
+// Pinned reports whether the chain names exactly one provider.
+func (c ProviderChain) Pinned() bool { return len(c) == 1 }
+
+// String renders the chain the way messages name it: "apt", or
+// "apt, flatpak, cargo".
+func (c ProviderChain) String() string { return strings.Join(c, ", ") }
+
+// UnmarshalYAML reads package_manager from YAML as either a scalar or a
+// sequence of scalars.
+func (c *ProviderChain) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Tag {
+	case "!!null":
+		*c = nil
+		return nil
+	case "!!str":
+		*c = providerChainOf(node.Value)
+		return nil
+	case "!!seq":
+		// Checked element by element, as ScriptArgs does: yaml.v3 would
+		// coerce [1, true] to strings that JSON, TOML and CUE reject.
+		chain := make(ProviderChain, 0, len(node.Content))
+		for i, element := range node.Content {
+			if element.Tag != "!!str" {
+				return fmt.Errorf("line %d: package_manager[%d] must be a string, got %q", element.Line, i, element.Value)
+			}
+			chain = append(chain, element.Value)
+		}
+		*c = chain
+		return nil
+	default:
+		return fmt.Errorf("line %d: package_manager must be a string or a list of strings, got %s", node.Line, node.Tag)
+	}
+}
+
+// UnmarshalJSON reads package_manager from JSON (and CUE, which evaluates to
+// JSON) as either a string or an array.
+func (c *ProviderChain) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	switch {
+	case raw == "null":
+		*c = nil
+		return nil
+	case strings.HasPrefix(raw, "["):
+		var list []string
+		if err := json.Unmarshal(data, &list); err != nil {
+			return fmt.Errorf("package_manager list must contain only strings: %w", err)
+		}
+		*c = list
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("package_manager must be a string or a list of strings")
+	}
+	*c = providerChainOf(s)
+	return nil
+}
+
+// UnmarshalTOML reads package_manager from TOML, checking a list's elements
+// one by one as BurntSushi hands them over untyped.
+func (c *ProviderChain) UnmarshalTOML(data any) error {
+	switch v := data.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case string:
+		*c = providerChainOf(v)
+		return nil
+	case []any:
+		chain := make(ProviderChain, 0, len(v))
+		for i, element := range v {
+			s, ok := element.(string)
+			if !ok {
+				return fmt.Errorf("package_manager[%d] must be a string, got %T", i, element)
+			}
+			chain = append(chain, s)
+		}
+		*c = chain
+		return nil
+	default:
+		return fmt.Errorf("package_manager must be a string or a list of strings, got %T", data)
+	}
+}
+
+// providerChainOf is the string form: one provider, or none for "".
+func providerChainOf(s string) ProviderChain {
+	if s == "" {
+		return nil
+	}
+	return ProviderChain{s}
+}
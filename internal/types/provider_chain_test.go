@@ -0,0 +1,68 @@
+package types
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// package_manager means the same thing in every format: a string is one
+// provider, a list is a chain, and anything else is refused.
+func TestProviderChain_Decode(t *testing.T) {
+	cases := []struct {
+		name             string
+		yaml, json, toml string
+		want             ProviderChain
+		wantErr          string
+	}{
+		{
+			name: "one provider",
+			yaml: `package_manager: apt`, json: `"package_manager": "apt"`, toml: `package_manager = "apt"`,
+			want: ProviderChain{"apt"},
+		},
+		{
+			name: "a chain",
+			yaml: `package_manager: [apt, flatpak]`, json: `"package_manager": ["apt", "flatpak"]`, toml: `package_manager = ["apt", "flatpak"]`,
+			want: ProviderChain{"apt", "flatpak"},
+		},
+		{
+			name: "not split on spaces",
+			yaml: `package_manager: "apt flatpak"`, json: `"package_manager": "apt flatpak"`, toml: `package_manager = "apt flatpak"`,
+			want: ProviderChain{"apt flatpak"},
+		},
+		{
+			name: "a number in the list",
+			yaml: `package_manager: [apt, 1]`, json: `"package_manager": ["apt", 1]`, toml: `package_manager = ["apt", 1]`,
+			wantErr: "package_manager",
+		},
+	}
+
+	for _, tc := range cases {
+		decoders := map[string]func(*Package) error{
+			"yaml": func(p *Package) error { return yaml.Unmarshal([]byte(tc.yaml), p) },
+			"json": func(p *Package) error { return json.Unmarshal([]byte("{"+tc.json+"}"), p) },
+			"toml": func(p *Package) error { return toml.Unmarshal([]byte(tc.toml), p) },
+		}
+		for format, decode := range decoders {
+			var p Package
+			err := decode(&p)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("%s/%s: err = %v, want one naming %q", tc.name, format, err, tc.wantErr)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s/%s: %v", tc.name, format, err)
+				continue
+			}
+			if !slices.Equal(p.PackageManager, tc.want) {
+				t.Errorf("%s/%s = %q, want %q", tc.name, format, p.PackageManager, tc.want)
+			}
+		}
+	}
+}
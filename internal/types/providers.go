@@ -16,7 +16,45 @@ type Provider struct {
 	Install      InstallConfig                   `toml:"install"`
 	Remove       RemoveConfig                    `toml:"remove"`
 	Environment  map[string]string               `toml:"environment"`
-	BinPath      string
+	// PackageNames is the provider whose package names this one installs by,
+	// for the package alias table: the AUR helpers install pacman's names.
+	PackageNames string `toml:"packageNames"`
+	// Offline is how this provider fetches into rwr's offline cache and
+	// installs from it; nil for a provider that cannot work offline.
+	Offline *OfflineConfig `toml:"offline"`
+	BinPath string
+}
+
+// OfflineConfig is how a provider works from the offline cache: Fetch
+// downloads the named packages into it without installing them, Install
+// installs them from it without the network. {{ .CacheDir }} in either, and
+// in Environment's values, is the provider's own directory in the cache.
+type OfflineConfig struct {
+	Fetch       string            `toml:"fetch"`
+	Install     string            `toml:"install"`
+	Environment map[string]string `toml:"environment"`
+}
+
+// PackageAliases is the package alias table: what each provider calls a
+// logical package name. aliases["fd"]["apt"] is "fd-find".
+type PackageAliases map[string]map[string]string
+
+// Resolve returns the name provider installs a package by. A name the table
+// does not know is not a logical name and comes back as written. A logical
+// name with no entry for the provider, nor for the provider whose names it
+// uses, also comes back as written, with false: it is unmapped there.
+func (a PackageAliases) Resolve(name string, provider *Provider) (string, bool) {
+	names, logical := a[name]
+	if !logical || provider == nil {
+		return name, true
+	}
+	if resolved, ok := names[provider.Name]; ok {
+		return resolved, true
+	}
+	if resolved, ok := names[provider.PackageNames]; ok && provider.PackageNames != "" {
+		return resolved, true
+	}
+	return name, false
 }
 
 // ProviderAlternatives defines distribution-specific alternatives for package names.
@@ -52,6 +90,46 @@ type CommandConfig struct {
 	ListExplicit string `toml:"list_explicit"`
 	Search       string `toml:"search"`
 	Clean        string `toml:"clean"`
+	// Info describes one package by name and exits non-zero when the
+	// manager has no such package (apt show, pacman -Si). Fallback chains
+	// ask it which provider has a package, or Search when a manager has none.
+	Info string `toml:"info"`
+	// Upgrade brings the named packages up to date and nothing else (apt
+	// install --only-upgrade, dnf upgrade). Update cannot: on some managers it
+	// refreshes the package lists, on others it upgrades the whole system.
+	Upgrade string `toml:"upgrade"`
+	// UpgradeAll upgrades everything, for a manager whose Update only
+	// refreshes its lists (apt upgrade after apt update). Where Update
+	// already upgrades everything (pacman -Syu), it is left empty.
+	UpgradeAll string `toml:"upgrade_all"`
+	// Hold and Unhold pin a package at its installed version and release it
+	// (apt-mark hold, dnf versionlock add, brew pin).
+	Hold   string `toml:"hold"`
+	Unhold string `toml:"unhold"`
+	// Companions names the binaries other than the provider's own that its
+	// commands start with (apt's dpkg and apt-mark). A command's first word
+	// runs in the provider's place only when it is listed here.
+	Companions []string `toml:"companions"`
+}
+
+// ForAction returns the command a package action runs for each package, and
+// whether the action is one package entries know. A known action the
+// provider has no command for comes back empty: not every manager can hold
+// a package (pacman keeps its holds in pacman.conf) or upgrade just one.
+func (c CommandConfig) ForAction(action string) (string, bool) {
+	switch action {
+	case ActionInstall:
+		return c.Install, true
+	case ActionRemove:
+		return c.Remove, true
+	case ActionUpgrade:
+		return c.Upgrade, true
+	case ActionHold:
+		return c.Hold, true
+	case ActionUnhold:
+		return c.Unhold, true
+	}
+	return "", false
 }
 
 // RepositoryConfig defines repository management configuration.
@@ -0,0 +1,13 @@
+package types
+
+// PruneConfig is the init file's prune block: which package managers orphan
+// removal may touch, and what it must leave alone on each.
+type PruneConfig struct {
+	// Providers opts package managers into pruning. A provider not named here
+	// is never pruned, whatever it has installed.
+	Providers []string `mapstructure:"providers,omitempty" yaml:"providers,omitempty" json:"providers,omitempty" toml:"providers,omitempty"`
+	// Keep maps a provider to the packages pruning never removes from it
+	// even though no blueprint declares them - the base system. Entries are
+	// names or globs (linux-*).
+	Keep map[string][]string `mapstructure:"keep,omitempty" yaml:"keep,omitempty" json:"keep,omitempty" toml:"keep,omitempty"`
+}
@@ -3,6 +3,7 @@ package types
 type Repository struct {
 	Name           string   `mapstructure:"name" yaml:"name" json:"name" toml:"name"`                                                                     // Name of the repository
 	Profiles       []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`             // Profiles this repository belongs to
+	When           string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`                             // Condition that must hold for this entry to apply
 	PackageManager string   `mapstructure:"package_manager" yaml:"package_manager" json:"package_manager" toml:"package_manager"`                         // Package manager to use
 	Action         string   `mapstructure:"action" yaml:"action" json:"action" toml:"action"`                                                             // Action to perform with the repository
 	URL            string   `mapstructure:"url" yaml:"url" json:"url" toml:"url"`                                                                         // URL of the repository
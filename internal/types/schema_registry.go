@@ -1,6 +1,9 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // A blueprint's wire format and the struct the processors consume are two
 // different things, and versioning is what separates them.
@@ -63,6 +66,17 @@ func NewSchemaVariant(blueprintType string, version int) (SchemaVariant, error)
 	return factory(), nil
 }
 
+// RegisteredBlueprintTypes lists the blueprint types a decoder exists for,
+// sorted.
+func RegisteredBlueprintTypes() []string {
+	names := make([]string, 0, len(schemaRegistry))
+	for name := range schemaRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // HasSchemaVariant reports whether a decoder exists for this type and version.
 func HasSchemaVariant(blueprintType string, version int) bool {
 	_, ok := schemaRegistry[blueprintType][version]
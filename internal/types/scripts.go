@@ -1,8 +1,15 @@
 package types
 
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
 type Script struct {
 	Name     string   `mapstructure:"name" yaml:"name" json:"name" toml:"name"`
 	Profiles []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+	When     string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`
 	Action   string   `mapstructure:"action" yaml:"action" json:"action" toml:"action"`
 	Exec     string   `mapstructure:"exec" yaml:"exec" json:"exec" toml:"exec"`
 	Source   string   `mapstructure:"source,omitempty" yaml:"source,omitempty" json:"source,omitempty" toml:"source,omitempty"`
@@ -16,7 +23,30 @@ type Script struct {
 	AsUser      string `mapstructure:"asUser,omitempty" yaml:"asUser,omitempty" json:"asUser,omitempty" toml:"asUser,omitempty"`
 	Log         string `mapstructure:"log,omitempty" yaml:"log,omitempty" json:"log,omitempty" toml:"log,omitempty"`
 	Interactive *bool  `mapstructure:"interactive,omitempty" yaml:"interactive,omitempty" json:"interactive,omitempty" toml:"interactive,omitempty"`
-	Import      string `mapstructure:"import,omitempty" yaml:"import,omitempty" json:"import,omitempty" toml:"import,omitempty"`
+	// Guards decide whether the script runs at all; a guarded-out script is
+	// reported present. Creates skips it when the path exists. Unless skips
+	// it when the check command succeeds, OnlyIf when it fails. Both checks
+	// run through the shell, as the account the script runs as.
+	Creates string `mapstructure:"creates,omitempty" yaml:"creates,omitempty" json:"creates,omitempty" toml:"creates,omitempty"`
+	Unless  string `mapstructure:"unless,omitempty" yaml:"unless,omitempty" json:"unless,omitempty" toml:"unless,omitempty"`
+	OnlyIf  string `mapstructure:"onlyif,omitempty" yaml:"onlyif,omitempty" json:"onlyif,omitempty" toml:"onlyif,omitempty"`
+	// RunOnce runs the script until it succeeds once, then never again until
+	// its content changes: the marker records a hash of what ran.
+	RunOnce bool `mapstructure:"run_once,omitempty" yaml:"run_once,omitempty" json:"run_once,omitempty" toml:"run_once,omitempty"`
+	// Timeout bounds one attempt (a Go duration: "90s", "10m"); past it the
+	// script's process group is killed. Retries re-runs a failed or timed-out
+	// attempt, waiting RetryDelay (default 5s) before the first retry and
+	// twice as long before each one after, up to maxScriptRetryDelay.
+	Timeout    string `mapstructure:"timeout,omitempty" yaml:"timeout,omitempty" json:"timeout,omitempty" toml:"timeout,omitempty"`
+	Retries    int    `mapstructure:"retries,omitempty" yaml:"retries,omitempty" json:"retries,omitempty" toml:"retries,omitempty"`
+	RetryDelay string `mapstructure:"retry_delay,omitempty" yaml:"retry_delay,omitempty" json:"retry_delay,omitempty" toml:"retry_delay,omitempty"`
+	// Env adds variables to the script's environment. EnvCredentials maps a
+	// variable to a managed credential by name; the value is only handed over
+	// for a credential exposed to scripts (see ScriptCredentialValue).
+	Env            map[string]string `mapstructure:"env,omitempty" yaml:"env,omitempty" json:"env,omitempty" toml:"env,omitempty"`
+	EnvCredentials map[string]string `mapstructure:"env_credentials,omitempty" yaml:"env_credentials,omitempty" json:"env_credentials,omitempty" toml:"env_credentials,omitempty"`
+
+	Import string `mapstructure:"import,omitempty" yaml:"import,omitempty" json:"import,omitempty" toml:"import,omitempty"`
 }
 
 type ScriptData struct {
@@ -29,3 +59,57 @@ type ScriptData struct {
 func (s Script) GetProfiles() []string {
 	return s.Profiles
 }
+
+// DefaultScriptRetryDelay is the wait before a script's first retry when the
+// blueprint does not set retry_delay.
+const DefaultScriptRetryDelay = 5 * time.Second
+
+// maxScriptRetryDelay caps the doubling: a flaky mirror is worth waiting out,
+// an unattended run stalled for an hour between attempts is not.
+const maxScriptRetryDelay = 5 * time.Minute
+
+// scriptEnvName is what a shell accepts as a variable name.
+var scriptEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidScriptEnvName reports whether name can be an environment variable.
+func ValidScriptEnvName(name string) bool {
+	return scriptEnvName.MatchString(name)
+}
+
+// ParseScriptDuration reads a script's timeout or retry_delay. Empty is zero;
+// anything set must be a positive Go duration.
+func ParseScriptDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not a duration (use e.g. 90s or 10m)", field, value)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s %q must be positive", field, value)
+	}
+	return d, nil
+}
+
+// RetryDelays returns the wait before each of the script's retries: RetryDelay
+// (or the default), doubling each time, capped. Retries <= 0 means none.
+func (s Script) RetryDelays() ([]time.Duration, error) {
+	if s.Retries <= 0 {
+		return nil, nil
+	}
+	delay, err := ParseScriptDuration("retry_delay", s.RetryDelay)
+	if err != nil {
+		return nil, err
+	}
+	if delay == 0 {
+		delay = DefaultScriptRetryDelay
+	}
+	delays := make([]time.Duration, s.Retries)
+	for i := range delays {
+		delay = min(delay, maxScriptRetryDelay)
+		delays[i] = delay
+		delay *= 2
+	}
+	return delays, nil
+}
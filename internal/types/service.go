@@ -3,6 +3,7 @@ package types
 type Service struct {
 	Name        string   `mapstructure:"name" yaml:"name" json:"name" toml:"name"`                                                                     // Name of the service
 	Profiles    []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`             // Profiles this service belongs to
+	When        string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`                             // Condition that must hold for this entry to apply
 	Action      string   `mapstructure:"action" yaml:"action" json:"action" toml:"action"`                                                             // Action to perform with the service
 	Elevated    bool     `mapstructure:"elevated,omitempty" yaml:"elevated,omitempty" json:"elevated,omitempty" toml:"elevated,omitempty"`             // Whether the service requires elevated privileges
 	Target      string   `mapstructure:"target,omitempty" yaml:"target,omitempty" json:"target,omitempty" toml:"target,omitempty"`                     // Target of the service
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// Sinks are where run events go besides the display: config.yaml's hooks,
+// webhooks and metrics sections. They are the machine's, not the tree's - a
+// blueprint repository cannot make a machine run commands or post its runs
+// somewhere by adding a key.
+type Sinks struct {
+	Hooks    Hooks     `mapstructure:"hooks"`
+	Webhooks []Webhook `mapstructure:"webhooks"`
+	Metrics  Metrics   `mapstructure:"metrics"`
+}
+
+// Hooks are shell commands run when a run finishes. Each takes one command or
+// a list.
+type Hooks struct {
+	OnRunFinished []string `mapstructure:"on_run_finished"`
+	OnFailure     []string `mapstructure:"on_failure"`
+}
+
+// Webhook receives events as JSON POSTs.
+type Webhook struct {
+	URL string `mapstructure:"url"`
+	// Events names the events posted; empty is run_finished alone.
+	Events  []string          `mapstructure:"events"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// Metrics configures the OpenMetrics textfile written after each run.
+type Metrics struct {
+	// Textfile is the file to write, in node_exporter's textfile directory.
+	Textfile string `mapstructure:"textfile"`
+}
+
+// WebhookEvents are the event names a webhook can subscribe to.
+var WebhookEvents = []string{"proc_started", "proc_finished", "proc_skipped", "resource", "resource_retry", "run_finished"}
+
+// Empty reports whether no sink is configured.
+func (s Sinks) Empty() bool {
+	return len(s.Hooks.OnRunFinished) == 0 && len(s.Hooks.OnFailure) == 0 &&
+		len(s.Webhooks) == 0 && s.Metrics.Textfile == ""
+}
+
+// DecodeSinks strictly decodes the hooks, webhooks and metrics sections as
+// read by viper: a misspelled hook name would otherwise never run, silently.
+func DecodeSinks(hooks, webhooks, metrics interface{}) (Sinks, error) {
+	var sinks Sinks
+	raw := map[string]interface{}{}
+	for key, value := range map[string]interface{}{"hooks": hooks, "webhooks": webhooks, "metrics": metrics} {
+		if value != nil {
+			raw[key] = value
+		}
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:      &sinks,
+		ErrorUnused: true,
+		// A single hook command is a list of one.
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return sinks, err
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return sinks, fmt.Errorf("hooks, webhooks or metrics section: %w", err)
+	}
+
+	for i, hook := range sinks.Webhooks {
+		if err := validateWebhookURL(hook.URL); err != nil {
+			return sinks, fmt.Errorf("webhooks[%d]: %w", i, err)
+		}
+		for _, event := range hook.Events {
+			if !slices.Contains(WebhookEvents, event) {
+				return sinks, fmt.Errorf("webhooks[%d]: unknown event %q (events: %v)", i, event, WebhookEvents)
+			}
+		}
+	}
+	return sinks, nil
+}
+
+// validateWebhookURL admits https, and plain http only to this machine: a
+// run's events name its packages, files and failures.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid url %q", raw)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+		return fmt.Errorf("refusing to post run events to %s over plain http; use https", raw)
+	}
+	return fmt.Errorf("unsupported url scheme %q in %s", u.Scheme, raw)
+}
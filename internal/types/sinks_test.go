@@ -0,0 +1,76 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeSinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		hooks    interface{}
+		webhooks interface{}
+		metrics  interface{}
+		wantErr  string
+	}{
+		{name: "absent sections"},
+		{
+			name:     "well-formed",
+			hooks:    map[string]interface{}{"on_failure": []interface{}{"notify-send rwr failed"}},
+			webhooks: []interface{}{map[string]interface{}{"url": "https://hooks.example.com/rwr", "events": []interface{}{"run_finished", "proc_finished"}}},
+			metrics:  map[string]interface{}{"textfile": "/var/lib/node_exporter/rwr.prom"},
+		},
+		{
+			name:    "misspelled hook errors",
+			hooks:   map[string]interface{}{"on_falure": "true"},
+			wantErr: "on_falure",
+		},
+		{
+			name:     "unknown event",
+			webhooks: []interface{}{map[string]interface{}{"url": "https://example.com", "events": []interface{}{"run_done"}}},
+			wantErr:  "unknown event",
+		},
+		{
+			name:     "plain http off this machine",
+			webhooks: []interface{}{map[string]interface{}{"url": "http://example.com/rwr"}},
+			wantErr:  "use https",
+		},
+		{
+			name:     "plain http to loopback",
+			webhooks: []interface{}{map[string]interface{}{"url": "http://127.0.0.1:9000/rwr"}},
+		},
+		{
+			name:     "missing url",
+			webhooks: []interface{}{map[string]interface{}{"events": []interface{}{"resource"}}},
+			wantErr:  "invalid url",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeSinks(tt.hooks, tt.webhooks, tt.metrics)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// A single hook command is written as a string; it decodes as a list of one.
+func TestDecodeSinks_SingleHookCommand(t *testing.T) {
+	sinks, err := DecodeSinks(map[string]interface{}{"on_run_finished": "echo done"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sinks.Hooks.OnRunFinished; len(got) != 1 || got[0] != "echo done" {
+		t.Fatalf("OnRunFinished = %q", got)
+	}
+	if sinks.Empty() {
+		t.Fatal("a configured hook is not empty")
+	}
+}
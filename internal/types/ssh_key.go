@@ -3,6 +3,7 @@ package types
 type SSHKey struct {
 	Name           string   `mapstructure:"name" yaml:"name" json:"name" toml:"name"`
 	Profiles       []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+	When           string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`
 	Type           string   `mapstructure:"type" yaml:"type" json:"type" toml:"type"`
 	Path           string   `mapstructure:"path" yaml:"path" json:"path" toml:"path"`
 	Comment        string   `mapstructure:"comment" yaml:"comment" json:"comment" toml:"comment"`
@@ -0,0 +1,62 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Target is a machine validation simulates instead of the one rwr runs on:
+// its operating system, Linux distribution and architecture. Distro and Arch
+// are optional; an empty distro is "any Linux", which only the providers
+// declaring the linux wildcard satisfy.
+type Target struct {
+	Name   string // label in reports; the spec when empty
+	OS     string
+	Distro string
+	Arch   string
+}
+
+// ParseTarget reads an os[/distro[/arch]] spec: "darwin", "linux/ubuntu",
+// "linux/fedora/arm64", "darwin//arm64".
+func ParseTarget(spec string) (Target, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(spec)), "/")
+	if len(parts) > 3 {
+		return Target{}, fmt.Errorf("target %q: want os[/distro[/arch]]", spec)
+	}
+	target := Target{OS: parts[0]}
+	if len(parts) > 1 {
+		target.Distro = parts[1]
+	}
+	if len(parts) > 2 {
+		target.Arch = parts[2]
+	}
+	switch target.OS {
+	case OSLinux, OSDarwin, OSWindows:
+	default:
+		return Target{}, fmt.Errorf("target %q: unknown os %q (want %s, %s or %s)", spec, target.OS, OSLinux, OSDarwin, OSWindows)
+	}
+	if target.Distro != "" && target.OS != OSLinux {
+		return Target{}, fmt.Errorf("target %q: only linux takes a distribution", spec)
+	}
+	return target, nil
+}
+
+// String labels the target in reports: its name, else its spec.
+func (t Target) String() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Spec()
+}
+
+// Spec renders the target as a spec ParseTarget reads back.
+func (t Target) Spec() string {
+	spec := t.OS
+	if t.Distro != "" || t.Arch != "" {
+		spec += "/" + t.Distro
+	}
+	if t.Arch != "" {
+		spec += "/" + t.Arch
+	}
+	return spec
+}
@@ -0,0 +1,31 @@
+package types
+
+import "testing"
+
+func TestParseTarget(t *testing.T) {
+	good := map[string]Target{
+		"darwin":             {OS: OSDarwin},
+		"Linux/Ubuntu":       {OS: OSLinux, Distro: "ubuntu"},
+		"linux/fedora/arm64": {OS: OSLinux, Distro: "fedora", Arch: "arm64"},
+		"darwin//arm64":      {OS: OSDarwin, Arch: "arm64"},
+	}
+	for spec, want := range good {
+		got, err := ParseTarget(spec)
+		if err != nil {
+			t.Errorf("ParseTarget(%q): %v", spec, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseTarget(%q) = %+v, want %+v", spec, got, want)
+		}
+		if again, _ := ParseTarget(got.Spec()); again != got {
+			t.Errorf("%q does not read back from its spec %q", spec, got.Spec())
+		}
+	}
+
+	for _, spec := range []string{"", "beos", "darwin/ubuntu", "linux/a/b/c"} {
+		if _, err := ParseTarget(spec); err == nil {
+			t.Errorf("ParseTarget(%q) accepted", spec)
+		}
+	}
+}
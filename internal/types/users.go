@@ -3,6 +3,7 @@ package types
 type Group struct {
 	Name     string   `mapstructure:"name" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`                           // Name of the group
 	Profiles []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"` // Profiles this group belongs to
+	When     string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`                 // Condition that must hold for this entry to apply
 	NewName  string   `mapstructure:"new_name,omitempty" yaml:"new_name,omitempty" json:"new_name,omitempty" toml:"new_name,omitempty"` // New name for the group (for modify action)
 	GID      string   `mapstructure:"gid,omitempty" yaml:"gid,omitempty" json:"gid,omitempty" toml:"gid,omitempty"`                     // Group ID to assign
 	System   bool     `mapstructure:"system,omitempty" yaml:"system,omitempty" json:"system,omitempty" toml:"system,omitempty"`         // Create as a system group
@@ -11,34 +12,69 @@ type Group struct {
 }
 
 type User struct {
-	Name         string   `mapstructure:"name" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`                                               // Name of the user
-	Profiles     []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`                     // Profiles this user belongs to
-	NewName      string   `mapstructure:"new_name,omitempty" yaml:"new_name,omitempty" json:"new_name,omitempty" toml:"new_name,omitempty"`                     // New name for the user (for modify action)
-	Action       string   `mapstructure:"action" yaml:"action,omitempty" json:"action,omitempty" toml:"action,omitempty"`                                       // Action to perform with the user
-	UID          string   `mapstructure:"uid,omitempty" yaml:"uid,omitempty" json:"uid,omitempty" toml:"uid,omitempty"`                                         // User ID to assign
-	Password     string   `mapstructure:"password,omitempty" yaml:"password,omitempty" json:"password,omitempty" toml:"password,omitempty"`                     // Password of the user
-	Groups       []string `mapstructure:"groups,omitempty" yaml:"groups,omitempty" json:"groups,omitempty" toml:"groups,omitempty"`                             // Groups of the user
-	AddGroups    []string `mapstructure:"add_groups,omitempty" yaml:"add_groups,omitempty" json:"add_groups,omitempty" toml:"add_groups,omitempty"`             // Groups to add the user to (for modify action)
-	RemoveGroups []string `mapstructure:"remove_groups,omitempty" yaml:"remove_groups,omitempty" json:"remove_groups,omitempty" toml:"remove_groups,omitempty"` // Groups to remove the user from (for modify action)
-	RemoveHome   bool     `mapstructure:"remove_home,omitempty" yaml:"remove_home,omitempty" json:"remove_home,omitempty" toml:"remove_home,omitempty"`         // Flag to remove the user's home directory (for remove action)
-	Shell        string   `mapstructure:"shell,omitempty" yaml:"shell,omitempty" json:"shell,omitempty" toml:"shell,omitempty"`                                 // Shell of the user
-	NewShell     string   `mapstructure:"new_shell,omitempty" yaml:"new_shell,omitempty" json:"new_shell,omitempty" toml:"new_shell,omitempty"`                 // New shell for the user (for modify action)
-	Home         string   `mapstructure:"home,omitempty" yaml:"home,omitempty" json:"home,omitempty" toml:"home,omitempty"`                                     // Home directory of the user
-	NewHome      string   `mapstructure:"new_home,omitempty" yaml:"new_home,omitempty" json:"new_home,omitempty" toml:"new_home,omitempty"`                     // New home directory for the user (for modify action)
-	Comment      string   `mapstructure:"comment,omitempty" yaml:"comment,omitempty" json:"comment,omitempty" toml:"comment,omitempty"`                         // GECOS comment field
-	System       bool     `mapstructure:"system,omitempty" yaml:"system,omitempty" json:"system,omitempty" toml:"system,omitempty"`                             // Create as a system user
-	Expire       string   `mapstructure:"expire,omitempty" yaml:"expire,omitempty" json:"expire,omitempty" toml:"expire,omitempty"`                             // Account expiration date (YYYY-MM-DD)
-	Lock         bool     `mapstructure:"lock,omitempty" yaml:"lock,omitempty" json:"lock,omitempty" toml:"lock,omitempty"`                                     // Lock the user account (for modify action)
-	Unlock       bool     `mapstructure:"unlock,omitempty" yaml:"unlock,omitempty" json:"unlock,omitempty" toml:"unlock,omitempty"`                             // Unlock the user account (for modify action)
-	Interactive  *bool    `mapstructure:"interactive,omitempty" yaml:"interactive,omitempty" json:"interactive,omitempty" toml:"interactive,omitempty"`         // Override global interactive mode
-	Import       string   `mapstructure:"import,omitempty" yaml:"import,omitempty" json:"import,omitempty" toml:"import,omitempty"`                             // Import path for external user definitions
+	Name               string   `mapstructure:"name" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`                                                                       // Name of the user
+	Profiles           []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`                                             // Profiles this user belongs to
+	When               string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`                                                             // Condition that must hold for this entry to apply
+	NewName            string   `mapstructure:"new_name,omitempty" yaml:"new_name,omitempty" json:"new_name,omitempty" toml:"new_name,omitempty"`                                             // New name for the user (for modify action)
+	Action             string   `mapstructure:"action" yaml:"action,omitempty" json:"action,omitempty" toml:"action,omitempty"`                                                               // Action to perform with the user
+	UID                string   `mapstructure:"uid,omitempty" yaml:"uid,omitempty" json:"uid,omitempty" toml:"uid,omitempty"`                                                                 // User ID to assign
+	Password           string   `mapstructure:"password,omitempty" yaml:"password,omitempty" json:"password,omitempty" toml:"password,omitempty"`                                             // Password of the user
+	PasswordCredential string   `mapstructure:"password_credential,omitempty" yaml:"password_credential,omitempty" json:"password_credential,omitempty" toml:"password_credential,omitempty"` // Managed credential holding the password, instead of password
+	Groups             []string `mapstructure:"groups,omitempty" yaml:"groups,omitempty" json:"groups,omitempty" toml:"groups,omitempty"`                                                     // Groups of the user
+	AddGroups          []string `mapstructure:"add_groups,omitempty" yaml:"add_groups,omitempty" json:"add_groups,omitempty" toml:"add_groups,omitempty"`                                     // Groups to add the user to (for modify action)
+	RemoveGroups       []string `mapstructure:"remove_groups,omitempty" yaml:"remove_groups,omitempty" json:"remove_groups,omitempty" toml:"remove_groups,omitempty"`                         // Groups to remove the user from (for modify action)
+	RemoveHome         bool     `mapstructure:"remove_home,omitempty" yaml:"remove_home,omitempty" json:"remove_home,omitempty" toml:"remove_home,omitempty"`                                 // Flag to remove the user's home directory (for remove action)
+	Shell              string   `mapstructure:"shell,omitempty" yaml:"shell,omitempty" json:"shell,omitempty" toml:"shell,omitempty"`                                                         // Shell of the user
+	NewShell           string   `mapstructure:"new_shell,omitempty" yaml:"new_shell,omitempty" json:"new_shell,omitempty" toml:"new_shell,omitempty"`                                         // New shell for the user (for modify action)
+	ShellPackage       string   `mapstructure:"shell_package,omitempty" yaml:"shell_package,omitempty" json:"shell_package,omitempty" toml:"shell_package,omitempty"`                         // Package that provides the shell, installed when it is missing (for shell action)
+	Home               string   `mapstructure:"home,omitempty" yaml:"home,omitempty" json:"home,omitempty" toml:"home,omitempty"`                                                             // Home directory of the user
+	NewHome            string   `mapstructure:"new_home,omitempty" yaml:"new_home,omitempty" json:"new_home,omitempty" toml:"new_home,omitempty"`                                             // New home directory for the user (for modify action)
+	Comment            string   `mapstructure:"comment,omitempty" yaml:"comment,omitempty" json:"comment,omitempty" toml:"comment,omitempty"`                                                 // GECOS comment field
+	System             bool     `mapstructure:"system,omitempty" yaml:"system,omitempty" json:"system,omitempty" toml:"system,omitempty"`                                                     // Create as a system user
+	Expire             string   `mapstructure:"expire,omitempty" yaml:"expire,omitempty" json:"expire,omitempty" toml:"expire,omitempty"`                                                     // Account expiration date (YYYY-MM-DD)
+	Lock               bool     `mapstructure:"lock,omitempty" yaml:"lock,omitempty" json:"lock,omitempty" toml:"lock,omitempty"`                                                             // Lock the user account (for modify action)
+	Unlock             bool     `mapstructure:"unlock,omitempty" yaml:"unlock,omitempty" json:"unlock,omitempty" toml:"unlock,omitempty"`                                                     // Unlock the user account (for modify action)
+	Interactive        *bool    `mapstructure:"interactive,omitempty" yaml:"interactive,omitempty" json:"interactive,omitempty" toml:"interactive,omitempty"`                                 // Override global interactive mode
+	Import             string   `mapstructure:"import,omitempty" yaml:"import,omitempty" json:"import,omitempty" toml:"import,omitempty"`                                                     // Import path for external user definitions
+}
+
+// Sudoers is one sudo rule, rendered into /etc/sudoers.d/rwr-<name> and checked
+// with `visudo -cf` before it is moved into place.
+type Sudoers struct {
+	Name     string   `mapstructure:"name" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`                           // Rule name; the file is /etc/sudoers.d/rwr-<name>
+	Profiles []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"` // Profiles this rule belongs to
+	When     string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`                 // Condition that must hold for this entry to apply
+	Action   string   `mapstructure:"action" yaml:"action,omitempty" json:"action,omitempty" toml:"action,omitempty"`                   // create or remove
+	Users    []string `mapstructure:"users,omitempty" yaml:"users,omitempty" json:"users,omitempty" toml:"users,omitempty"`             // Users the rule applies to
+	Groups   []string `mapstructure:"groups,omitempty" yaml:"groups,omitempty" json:"groups,omitempty" toml:"groups,omitempty"`         // Groups the rule applies to
+	RunAs    string   `mapstructure:"run_as,omitempty" yaml:"run_as,omitempty" json:"run_as,omitempty" toml:"run_as,omitempty"`         // Target user the commands may run as (default ALL)
+	Commands []string `mapstructure:"commands,omitempty" yaml:"commands,omitempty" json:"commands,omitempty" toml:"commands,omitempty"` // Absolute command paths, or ALL (default ALL)
+	NoPasswd bool     `mapstructure:"nopasswd,omitempty" yaml:"nopasswd,omitempty" json:"nopasswd,omitempty" toml:"nopasswd,omitempty"` // Run the commands without a password
+	EnvKeep  []string `mapstructure:"env_keep,omitempty" yaml:"env_keep,omitempty" json:"env_keep,omitempty" toml:"env_keep,omitempty"` // Environment variables sudo preserves for these principals
+	Import   string   `mapstructure:"import,omitempty" yaml:"import,omitempty" json:"import,omitempty" toml:"import,omitempty"`         // Import path for external sudoers definitions
+}
+
+// PolkitRule is one polkit authorization rule for desktop systems, rendered into
+// /etc/polkit-1/rules.d/50-rwr-<name>.rules.
+type PolkitRule struct {
+	Name     string   `mapstructure:"name" yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`                           // Rule name; the file is 50-rwr-<name>.rules
+	Profiles []string `mapstructure:"profiles,omitempty" yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"` // Profiles this rule belongs to
+	When     string   `mapstructure:"when,omitempty" yaml:"when,omitempty" json:"when,omitempty" toml:"when,omitempty"`                 // Condition that must hold for this entry to apply
+	Action   string   `mapstructure:"action" yaml:"action,omitempty" json:"action,omitempty" toml:"action,omitempty"`                   // create or remove
+	Actions  []string `mapstructure:"actions,omitempty" yaml:"actions,omitempty" json:"actions,omitempty" toml:"actions,omitempty"`     // polkit action IDs; a trailing "." matches every action under that prefix
+	Users    []string `mapstructure:"users,omitempty" yaml:"users,omitempty" json:"users,omitempty" toml:"users,omitempty"`             // Users the rule applies to
+	Groups   []string `mapstructure:"groups,omitempty" yaml:"groups,omitempty" json:"groups,omitempty" toml:"groups,omitempty"`         // Groups the rule applies to
+	Result   string   `mapstructure:"result,omitempty" yaml:"result,omitempty" json:"result,omitempty" toml:"result,omitempty"`         // yes, no, auth_self, auth_admin, ... (default yes)
+	Import   string   `mapstructure:"import,omitempty" yaml:"import,omitempty" json:"import,omitempty" toml:"import,omitempty"`         // Import path for external polkit definitions
 }
 
 type UsersData struct {
 	// SchemaVersion, when set, overrides the tree-wide version from the init file.
 	SchemaVersion `mapstructure:",squash" yaml:",inline" json:",inline" toml:",inline"`
-	Groups        []Group `mapstructure:"groups,omitempty" yaml:"groups,omitempty" json:"groups,omitempty" toml:"groups,omitempty"` // Groups data
-	Users         []User  `mapstructure:"users,omitempty" yaml:"users,omitempty" json:"users,omitempty" toml:"users,omitempty"`     // Users data
+	Groups        []Group      `mapstructure:"groups,omitempty" yaml:"groups,omitempty" json:"groups,omitempty" toml:"groups,omitempty"`     // Groups data
+	Users         []User       `mapstructure:"users,omitempty" yaml:"users,omitempty" json:"users,omitempty" toml:"users,omitempty"`         // Users data
+	Sudoers       []Sudoers    `mapstructure:"sudoers,omitempty" yaml:"sudoers,omitempty" json:"sudoers,omitempty" toml:"sudoers,omitempty"` // sudo rules
+	Polkit        []PolkitRule `mapstructure:"polkit,omitempty" yaml:"polkit,omitempty" json:"polkit,omitempty" toml:"polkit,omitempty"`     // polkit rules
 }
 
 // GetProfiles returns the profiles for this group.
@@ -50,3 +86,13 @@ func (g Group) GetProfiles() []string {
 func (u User) GetProfiles() []string {
 	return u.Profiles
 }
+
+// GetProfiles returns the profiles for this sudo rule.
+func (s Sudoers) GetProfiles() []string {
+	return s.Profiles
+}
+
+// GetProfiles returns the profiles for this polkit rule.
+func (p PolkitRule) GetProfiles() []string {
+	return p.Profiles
+}
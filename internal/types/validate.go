@@ -22,6 +22,9 @@ type ValidationIssue struct {
 	RawMessage  string
 	Category    string
 	Description string
+	// Target labels the simulated machine the issue was found on; empty
+	// when validating against the machine rwr runs on.
+	Target string
 }
 
 // ValidationResults contains the results of a validation run.
@@ -30,6 +33,9 @@ type ValidationResults struct {
 	ErrorCount   int
 	WarningCount int
 	InfoCount    int
+	// Targets lists the simulated machines validated, in order; issues
+	// carry the one they were found on.
+	Targets []string
 }
 
 // ValidationOptions contains options for the validation process.
@@ -38,4 +44,10 @@ type ValidationOptions struct {
 	ValidateBlueprints bool
 	ValidateProviders  bool
 	Verbose            bool
+	// Targets validates blueprints as if on each of these machines instead
+	// of this one.
+	Targets []Target
+	// Matrix validates each configuration of the path's manifest against
+	// the machine its matchers describe.
+	Matrix bool
 }
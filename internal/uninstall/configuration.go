@@ -0,0 +1,97 @@
+package uninstall
+
+import (
+	"errors"
+	"os"
+
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/state"
+	"github.com/fynxlabs/rwr/internal/status"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// restorableConfigTools are the configuration tools whose journal entries
+// carry the value they replaced.
+var restorableConfigTools = map[string]bool{
+	types.ConfigurationToolKDE:    true,
+	types.ConfigurationToolXfconf: true,
+	types.ConfigurationToolINI:    true,
+}
+
+// configQuery reads a key's current value; a variable so a test can answer.
+var configQuery = status.LocalConfigQuery
+
+// reverseConfiguration puts back the value a read-back tool replaced, or
+// deletes the key when there was none. It is guarded the way a file delete is:
+// a key whose value is no longer the one rwr wrote has been changed by
+// someone since, and their value wins.
+func reverseConfiguration(entry state.Entry) (string, error) {
+	config := configFromIdentity(entry.Identity)
+	query := configQuery()
+	current, set, err := status.ReadConfigValue(config, query)
+	if err != nil {
+		return "cannot read the current value; not restoring blind", nil //nolint:nilerr // deliberate skip
+	}
+	if !set || !status.ConfigValueMatches(config.Tool, current, entry.Identity["value"]) {
+		return "changed since the recorded apply - not restoring", nil
+	}
+	restore := entry.Identity["previous_set"] == "true"
+	previous := entry.Identity["previous"]
+
+	switch config.Tool {
+	case types.ConfigurationToolKDE:
+		_, write, ok := status.KDEConfigTools(query.Have)
+		if !ok {
+			return "kwriteconfig is not installed", nil
+		}
+		args := []string{"--file", config.File, "--group", config.Group, "--key", config.Key}
+		if restore {
+			args = append(args, "--", previous)
+		} else {
+			args = append(args, "--delete")
+		}
+		return "", system.RunCommand(types.Command{Exec: write, Args: args}, false)
+	case types.ConfigurationToolXfconf:
+		args := []string{"--channel", config.Channel, "--property", config.Property}
+		if restore {
+			args = append(args, "--set="+previous)
+		} else {
+			args = append(args, "--reset")
+		}
+		return "", system.RunCommand(types.Command{Exec: "xfconf-query", Args: args}, false)
+	case types.ConfigurationToolINI:
+		data, err := os.ReadFile(config.File) // #nosec G304 -- path from rwr's own journal
+		if err != nil {
+			return "", err
+		}
+		info, err := os.Stat(config.File)
+		if err != nil {
+			return "", err
+		}
+		content := helpers.UnsetINIValue(string(data), config.Section, config.Key)
+		if restore {
+			content = helpers.SetINIValue(string(data), config.Section, config.Key, previous)
+		}
+		// Written unelevated: a file only root can write fails here and stays
+		// unreversed for a re-run, rather than prompting mid-uninstall.
+		return "", system.WriteFileChecked(config.File, content, info.Mode().Perm(), false, nil)
+	}
+	return "", errors.New("no reversal for configuration tool " + config.Tool)
+}
+
+// configFromIdentity rebuilds the addressing fields of a configuration entry
+// from its journal identity.
+func configFromIdentity(identity map[string]string) types.Configuration {
+	return types.Configuration{
+		Name:     identity["name"],
+		Tool:     identity["tool"],
+		File:     identity["file"],
+		Group:    identity["group"],
+		Key:      identity["key"],
+		Channel:  identity["channel"],
+		Property: identity["property"],
+		Type:     identity["type"],
+		Section:  identity["section"],
+	}
+}
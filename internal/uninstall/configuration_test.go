@@ -0,0 +1,154 @@
+package uninstall
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/state"
+	"github.com/fynxlabs/rwr/internal/status"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+func iniEntry(path, value, previous string, previousSet bool) state.Entry {
+	set := "false"
+	if previousSet {
+		set = "true"
+	}
+	return state.Entry{Processor: types.BlueprintTypeConfiguration, Action: "configure", OK: true, Outcome: "ok",
+		Identity: map[string]string{"name": "gtk", "tool": types.ConfigurationToolINI, "file": path,
+			"section": "Settings", "key": "gtk-theme-name", "value": value, "previous": previous, "previous_set": set}}
+}
+
+func TestPlan_ConfigurationReversibleOnlyForReadBackTools(t *testing.T) {
+	entries := []state.Entry{
+		iniEntry("/tmp/settings.ini", "Greybird", "Adwaita", true),
+		{Processor: types.BlueprintTypeConfiguration, OK: true, Identity: map[string]string{"name": "night-light", "tool": "dconf"}},
+	}
+	items, skipped, err := Plan(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || !strings.Contains(items[0].Action, `"Adwaita"`) {
+		t.Fatalf("items = %v", items)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0], "night-light") {
+		t.Fatalf("not-reversible list = %v", skipped)
+	}
+}
+
+func TestReverseConfiguration_INI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.ini")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// Still what rwr wrote: the previous value goes back.
+	write("[Settings]\ngtk-theme-name=Greybird\n")
+	if note, err := reverseConfiguration(iniEntry(path, "Greybird", "Adwaita", true)); err != nil || note != "" {
+		t.Fatalf("restore: note %q, err %v", note, err)
+	}
+	if got := read(); got != "[Settings]\ngtk-theme-name=Adwaita\n" {
+		t.Errorf("restored file = %q", got)
+	}
+
+	// No previous value: the key is removed.
+	write("[Settings]\ngtk-theme-name=Greybird\n")
+	if _, err := reverseConfiguration(iniEntry(path, "Greybird", "", false)); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "[Settings]\n" {
+		t.Errorf("unset file = %q", got)
+	}
+
+	// Changed by hand since: left alone.
+	write("[Settings]\ngtk-theme-name=Nordic\n")
+	note, err := reverseConfiguration(iniEntry(path, "Greybird", "Adwaita", true))
+	if err != nil || note == "" {
+		t.Fatalf("changed value: note %q, err %v", note, err)
+	}
+	if got := read(); got != "[Settings]\ngtk-theme-name=Nordic\n" {
+		t.Errorf("a hand-changed value was overwritten: %q", got)
+	}
+}
+
+// A key rwr set twice - Adwaita before rwr, then Greybird, then Nordic - goes
+// back to Adwaita, not to Greybird, rwr's own earlier value. Once reversed,
+// the next apply starts again from what it replaced.
+func TestExecute_ConfigurationRestoresThePreRwrValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.ini")
+	if err := os.WriteFile(path, []byte("[Settings]\ngtk-theme-name=Nordic\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configDir := t.TempDir()
+	uninstall := func() {
+		t.Helper()
+		entries, err := state.Unreversed(configDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, _, err := Plan(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		journal, err := state.NewWriter(configDir, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if failed := Execute(&out, items, status.NewQuerier(), journal); failed != 0 {
+			t.Fatalf("failed = %d, output:\n%s", failed, out.String())
+		}
+		if err := journal.Finalize(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apply := func(entries ...state.Entry) {
+		t.Helper()
+		journal, err := state.NewWriter(configDir, "test", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			journal.Append(entry)
+		}
+		if err := journal.Finalize(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	apply(iniEntry(path, "Greybird", "Adwaita", true))
+	apply(iniEntry(path, "Nordic", "Greybird", true))
+	uninstall()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "[Settings]\ngtk-theme-name=Adwaita\n" {
+		t.Fatalf("restored file = %q, want the value from before rwr", got)
+	}
+
+	// Applied again after the reversal: it replaced Adwaita, and that is
+	// what the record carries now - not the first apply's.
+	apply(iniEntry(path, "Nordic", "Dracula", true))
+	entries, err := state.Unreversed(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Identity["previous"] != "Dracula" {
+		t.Fatalf("entries after re-apply = %+v", entries)
+	}
+}
@@ -24,6 +24,7 @@ import (
 // reverseOrder is the inverse of the apply order: what was applied last is
 // removed first.
 var reverseOrder = []string{
+	types.BlueprintTypeConfiguration,
 	types.BlueprintTypeGit,
 	types.BlueprintTypeServices,
 	types.BlueprintTypeFonts,
@@ -35,7 +36,6 @@ var reverseOrder = []string{
 // printed up front so the operator knows before confirming.
 var NotReversible = []string{
 	types.BlueprintTypeScripts,
-	types.BlueprintTypeConfiguration,
 	types.BlueprintTypeUsers,
 	types.BlueprintTypeSSHKeys,
 	types.BlueprintTypeRepositories,
@@ -67,15 +67,49 @@ func Plan(entries []state.Entry) (items []Item, skipped []string, err error) {
 		// Within a processor, reverse the recorded order too.
 		group := byProcessor[processor]
 		for i := len(group) - 1; i >= 0; i-- {
+			if !reversible(group[i]) {
+				skipped = append(skipped, fmt.Sprintf("%s: %s", group[i].Processor, group[i].Identity["name"]))
+				continue
+			}
 			items = append(items, Item{Entry: group[i], Action: describe(group[i])})
 		}
 	}
 	return items, skipped, nil
 }
 
+// reversible reports whether uninstall can undo an entry of a processor in
+// reverseOrder. Configuration is only partly reversible: the read-back tools
+// record the value they replaced; dconf, gsettings, defaults and the registry
+// do not.
+func reversible(entry state.Entry) bool {
+	switch entry.Processor {
+	case types.BlueprintTypeConfiguration:
+		return restorableConfigTools[entry.Identity["tool"]]
+	case types.BlueprintTypePackages:
+		// A hold is undone by unholding; an upgrade, or a hold already
+		// released, leaves nothing to undo.
+		switch entry.Identity["kind"] {
+		case types.ActionHold:
+			return entry.Action == types.ActionHold
+		case types.ActionUpgrade:
+			return false
+		}
+	}
+	return true
+}
+
 func describe(entry state.Entry) string {
 	switch entry.Processor {
+	case types.BlueprintTypeConfiguration:
+		previous := entry.Identity["previous"]
+		if entry.Identity["previous_set"] != "true" {
+			return fmt.Sprintf("unset %s setting %s", entry.Identity["tool"], entry.Identity["name"])
+		}
+		return fmt.Sprintf("restore %s setting %s to %q", entry.Identity["tool"], entry.Identity["name"], previous)
 	case types.BlueprintTypePackages:
+		if entry.Identity["kind"] == types.ActionHold {
+			return fmt.Sprintf("unhold package %s via %s", entry.Identity["name"], entry.Identity["provider"])
+		}
 		return fmt.Sprintf("remove package %s via %s", entry.Identity["name"], entry.Identity["provider"])
 	case types.BlueprintTypeFiles:
 		return fmt.Sprintf("delete %s (hash-guarded)", entry.Identity["dest"])
@@ -130,6 +164,8 @@ func reverse(entry state.Entry, querier *status.Querier) (skipReason string, err
 		return reverseService(entry)
 	case types.BlueprintTypeFonts:
 		return reverseFont(entry)
+	case types.BlueprintTypeConfiguration:
+		return reverseConfiguration(entry)
 	}
 	return "no reversal implemented", nil
 }
@@ -167,6 +203,9 @@ func reversePackage(entry state.Entry, querier *status.Querier) (string, error)
 	if !ok {
 		return "provider not available on this system", nil
 	}
+	if entry.Identity["kind"] == types.ActionHold {
+		return unholdPackage(entry, provider)
+	}
 	if querier.PackagePresent(provider, name) == status.Absent {
 		return "already absent", nil
 	}
@@ -179,6 +218,21 @@ func reversePackage(entry state.Entry, querier *status.Querier) (string, error)
 	return "", system.RunCommand(cmd, false)
 }
 
+// unholdPackage releases a hold the journal records, through the provider
+// that placed it.
+func unholdPackage(entry state.Entry, provider *types.Provider) (string, error) {
+	if provider.Commands.Unhold == "" {
+		return "provider has no unhold command", nil
+	}
+	bin, args := system.ProviderCommand(provider, provider.Commands.Unhold)
+	cmd := types.Command{
+		Exec:     bin,
+		Args:     append(args, entry.Identity["name"]),
+		Elevated: provider.Elevated || entry.Elevated,
+	}
+	return "", system.RunCommand(cmd, false)
+}
+
 func reverseFile(entry state.Entry) (string, error) {
 	dest := entry.Identity["dest"]
 	if dest == "" {
@@ -49,6 +49,32 @@ func TestPlan_ReverseOrderAndNotReversible(t *testing.T) {
 	}
 }
 
+// A hold is reversed by unholding, before the install it held is removed;
+// an upgrade, and a hold since released, have nothing to reverse.
+func TestPlan_HoldsAndUpgrades(t *testing.T) {
+	entries := []state.Entry{
+		{Processor: types.BlueprintTypePackages, Action: types.ActionInstall, OK: true, Identity: map[string]string{"name": "git", "provider": "apt"}},
+		{Processor: types.BlueprintTypePackages, Action: types.ActionHold, OK: true, Identity: map[string]string{"name": "git", "provider": "apt", "kind": "hold"}},
+		{Processor: types.BlueprintTypePackages, Action: types.ActionUnhold, OK: true, Identity: map[string]string{"name": "vim", "provider": "apt", "kind": "hold"}},
+		{Processor: types.BlueprintTypePackages, Action: types.ActionUpgrade, OK: true, Identity: map[string]string{"name": "curl", "provider": "apt", "kind": "upgrade"}},
+	}
+	items, skipped, err := Plan(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, item := range items {
+		actions = append(actions, item.Action)
+	}
+	want := "unhold package git via apt,remove package git via apt"
+	if strings.Join(actions, ",") != want {
+		t.Fatalf("items = %v, want %s", actions, want)
+	}
+	if len(skipped) != 2 || !strings.Contains(strings.Join(skipped, " "), "vim") || !strings.Contains(strings.Join(skipped, " "), "curl") {
+		t.Fatalf("not-reversible list = %v", skipped)
+	}
+}
+
 func TestExecute_HashGuardAndAbsentSkip(t *testing.T) {
 	dir := t.TempDir()
 
@@ -3,12 +3,15 @@ package validate
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"charm.land/log/v2"
 	"github.com/fynxlabs/rwr/internal/helpers"
 	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/system"
 	"github.com/fynxlabs/rwr/internal/types"
 )
 
@@ -33,28 +36,24 @@ func ValidateBlueprints(path string, verbose bool, results *types.ValidationResu
 		return nil // Continue with other validations
 	}
 
-	// Validate init file
-	initConfig, err := validateInitFile(initFile, results)
-	if err != nil {
-		return fmt.Errorf("error validating init file: %w", err)
+	if err := validateTree(initFile, path, results, osInfo, nil); err != nil {
+		return err
 	}
 
-	if initConfig == nil {
-		// If init config is nil, we can't continue with blueprint validation
-		return nil
+	if verbose {
+		log.Infof("Blueprint validation completed")
 	}
 
-	// Stage-1 resolve produces the routed, template-resolved tree once;
-	// validate consumes it instead of walking and rendering a second copy of
-	// the same logic (add-tui task 1). The resolver reports per-file problems
-	// as diagnostics, so one bad file does not hide the rest of the tree.
-	// The tree being validated is the path argument, always: the init file's
-	// own location ("." is common) is relative to a run's working directory,
-	// not to wherever validate happens to be invoked from.
-	initConfig.Init.Location = path
-	plan, err := processors.ResolveStage1(initConfig)
-	if err != nil {
-		return fmt.Errorf("error resolving blueprint tree: %w", err)
+	return nil
+}
+
+// validateTree validates the tree an init file describes, rooted at path.
+// available is the provider set of a simulated machine (system.ProvidersFor);
+// nil validates for this one.
+func validateTree(initFile, path string, results *types.ValidationResults, osInfo *types.OSInfo, available map[string]*types.Provider) error {
+	initConfig, plan, err := loadTree(initFile, path, results, osInfo)
+	if err != nil || plan == nil {
+		return err
 	}
 
 	for _, diag := range plan.Diags {
@@ -92,13 +91,133 @@ func ValidateBlueprints(path string, verbose bool, results *types.ValidationResu
 		}
 	}
 
-	if verbose {
-		log.Infof("Blueprint validation completed")
+	validateActivatedProfiles(initConfig, initFile, results)
+	for _, provider := range initConfig.DefaultPackageManager {
+		validateProviderExists(provider, "setting", "default_package_manager", initFile, results)
 	}
-
+	validatePrune(initConfig.Prune, initFile, results)
+	validateProviderAvailability(plan, osInfo, available, results)
 	return nil
 }
 
+// validatePrune checks the init file's prune block: providers rwr knows, a
+// provider that can say what was explicitly installed, and keep patterns
+// that parse. A provider without that query is not pruned at all, which the
+// operator who opted it in should hear about before the run.
+func validatePrune(prune *types.PruneConfig, initFile string, results *types.ValidationResults) {
+	if prune == nil {
+		return
+	}
+	for _, name := range prune.Providers {
+		validateProviderExists(name, "setting", "prune.providers", initFile, results)
+		if provider, ok := system.GetProviderDefinition(name); ok && provider.Commands.ListExplicit == "" {
+			AddIssue(results, types.ValidationWarning,
+				fmt.Sprintf("Package manager '%s' is opted into pruning but cannot list what was explicitly installed; it will not be pruned", name),
+				initFile, 0, "Remove it from prune.providers")
+		}
+	}
+	for name, patterns := range prune.Keep {
+		validateProviderExists(name, "setting", "prune.keep", initFile, results)
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				AddIssue(results, types.ValidationError,
+					fmt.Sprintf("prune.keep pattern %q for %s is not a valid glob: %v", pattern, name, err),
+					initFile, 0, "")
+			}
+		}
+	}
+}
+
+// validateActivatedProfiles warns about a profile the init file activates -
+// by default or by a host_profiles rule - that no blueprint declares: the
+// machines it is meant for would get nothing from it.
+func validateActivatedProfiles(initConfig *types.InitConfig, initFile string, results *types.ValidationResults) {
+	if len(initConfig.DefaultProfiles) == 0 && len(initConfig.HostProfiles) == 0 {
+		return
+	}
+	summary, err := processors.CollectProfiles(initConfig)
+	if err != nil {
+		return
+	}
+	warned := map[string]bool{}
+	warn := func(profile, where string) {
+		if warned[profile] || profile == "all" || slices.Contains(summary.Names, profile) {
+			return
+		}
+		warned[profile] = true
+		AddIssue(results, types.ValidationWarning, fmt.Sprintf("%s activates profile %q, which no blueprint declares", where, profile), initFile, 0,
+			"Check the spelling, or remove the profile from the init file")
+	}
+	for _, profile := range initConfig.DefaultProfiles {
+		warn(profile, "default_profiles")
+	}
+	for i, rule := range initConfig.HostProfiles {
+		for _, profile := range rule.Profiles {
+			warn(profile, fmt.Sprintf("host_profiles[%d]", i))
+		}
+	}
+}
+
+// loadTree reads an init file and resolves the tree rooted at path through
+// stage 1, as a run on osInfo's machine would see it. Problems with the init
+// file itself are recorded in results; a nil plan means it was unusable.
+func loadTree(initFile, path string, results *types.ValidationResults, osInfo *types.OSInfo) (*types.InitConfig, *types.Plan, error) {
+	initConfig, err := validateInitFile(initFile, results)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error validating init file: %w", err)
+	}
+
+	if initConfig == nil {
+		// If init config is nil, we can't continue with blueprint validation
+		return nil, nil, nil
+	}
+
+	// Templates render against the machine being validated for, as a run
+	// renders them against the machine it runs on.
+	if osInfo != nil {
+		initConfig.Variables.System = osInfo.System
+	}
+
+	// Stage-1 resolve produces the routed, template-resolved tree once;
+	// validate consumes it instead of walking and rendering a second copy of
+	// the same logic (add-tui task 1). The resolver reports per-file problems
+	// as diagnostics, so one bad file does not hide the rest of the tree.
+	// The tree being validated is the path argument, always: the init file's
+	// own location ("." is common) is relative to a run's working directory,
+	// not to wherever validate happens to be invoked from.
+	initConfig.Init.Location = path
+	plan, err := processors.ResolveStage1(initConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error resolving blueprint tree: %w", err)
+	}
+	return initConfig, plan, nil
+}
+
+// ResolveTree resolves the blueprint tree at path through stage 1 for other
+// commands that read a tree the way validate does, without a run's init
+// (lint). It fails when path has no usable init file; the tree's own
+// problems are the plan's diagnostics.
+func ResolveTree(path string, osInfo *types.OSInfo) (*types.Plan, error) {
+	initFile := findInitFile(path)
+	if initFile == "" {
+		return nil, fmt.Errorf("no init file in %s", path)
+	}
+	results := &types.ValidationResults{}
+	_, plan, err := loadTree(initFile, path, results, osInfo)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		for _, issue := range results.Issues {
+			if issue.Severity == types.ValidationError {
+				return nil, fmt.Errorf("%s: %s", initFile, issue.Message)
+			}
+		}
+		return nil, fmt.Errorf("%s is not a usable init file", initFile)
+	}
+	return plan, nil
+}
+
 // isBootstrapFileName reports whether a filename is a bootstrap file, in any
 // registered format.
 func isBootstrapFileName(name string) bool {
@@ -191,9 +310,33 @@ func validateInitFile(initFile string, results *types.ValidationResults) (*types
 		}
 	}
 
+	validateProfileRules(initData, initFormat, initFile, results)
+
 	return &initConfig, nil
 }
 
+// validateProfileRules checks the init file's host_profiles strictly, as a run
+// decodes them, and that each rule's condition parses.
+func validateProfileRules(initData []byte, initFormat, initFile string, results *types.ValidationResults) {
+	var top map[string]interface{}
+	if err := helpers.UnmarshalBlueprint(initData, initFormat, &top); err != nil {
+		return // already reported by the caller's decode
+	}
+	rules, err := types.DecodeProfileRules(top["host_profiles"])
+	if err != nil {
+		AddIssue(results, types.ValidationError, err.Error(), initFile, 0, "See host_profiles in docs/profiles.md")
+		return
+	}
+	for i, rule := range rules {
+		if rule.When == "" {
+			continue
+		}
+		if err := helpers.ParseWhen(rule.When); err != nil {
+			AddIssue(results, types.ValidationError, fmt.Sprintf("host_profiles[%d]: %s", i, err), initFile, 0, "")
+		}
+	}
+}
+
 // validateBlueprintFile validates a blueprint file.
 func validateBlueprintFile(blueprintFile string, initConfig *types.InitConfig, results *types.ValidationResults) error {
 	log.Debugf("Validating blueprint file: %s", blueprintFile)
@@ -341,6 +484,11 @@ var blueprintValidators = map[string]blueprintValidator{
 			return err
 		}
 		ValidateUsers(d.Users, file, results)
+		for i, group := range d.Groups {
+			validateProfiles(group.Profiles, fmt.Sprintf("groups[%d].profiles", i), file, results)
+		}
+		ValidateSudoers(d.Sudoers, file, results)
+		ValidatePolkitRules(d.Polkit, file, results)
 		return nil
 	},
 	// fonts and configuration were absent, so every fonts and configuration
@@ -348,11 +496,21 @@ var blueprintValidators = map[string]blueprintValidator{
 	// the schema check alone is worth running against them.
 	types.BlueprintTypeFonts: func(data []byte, format string, file string, results *types.ValidationResults) error {
 		var d types.FontsData
-		return decode(data, format, types.BlueprintTypeFonts, &d)
+		if err := decode(data, format, types.BlueprintTypeFonts, &d); err != nil {
+			return err
+		}
+		for i, font := range d.Fonts {
+			validateProfiles(font.Profiles, fmt.Sprintf("fonts[%d].profiles", i), file, results)
+		}
+		return nil
 	},
 	types.BlueprintTypeConfiguration: func(data []byte, format string, file string, results *types.ValidationResults) error {
 		var d types.ConfigData
-		return decode(data, format, types.BlueprintTypeConfiguration, &d)
+		if err := decode(data, format, types.BlueprintTypeConfiguration, &d); err != nil {
+			return err
+		}
+		ValidateConfigurations(d.Configurations, file, results)
+		return nil
 	},
 }
 
@@ -3,14 +3,18 @@ package validate
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/system"
 	"github.com/fynxlabs/rwr/internal/types"
 )
 
 // ValidatePackages validates package definitions.
 // It checks that each package has required fields (name, action) and validates
-// that the action is one of the supported types (install, remove, update).
+// that the action is one of the supported types (install, remove, upgrade,
+// hold, unhold).
 // It also verifies that specified package managers exist in the system.
 // Validation issues are added to the results parameter.
 func ValidatePackages(packages []types.Package, file string, results *types.ValidationResults) {
@@ -20,6 +24,8 @@ func ValidatePackages(packages []types.Package, file string, results *types.Vali
 			continue
 		}
 
+		validateProfiles(pkg.Profiles, fmt.Sprintf("packages[%d].profiles", i), file, results)
+
 		// Only the names list is processed when both are declared; say so
 		// rather than silently ignoring one of them.
 		if pkg.Name != "" && len(pkg.Names) > 0 {
@@ -30,15 +36,16 @@ func ValidatePackages(packages []types.Package, file string, results *types.Vali
 
 		// A package entry names one package with `name` or several with `names`.
 		// Requiring `name` and separately warning on an empty `names` reported two
-		// problems against every correct entry, whichever form it used.
-		if pkg.Name == "" && len(pkg.Names) == 0 {
+		// problems against every correct entry, whichever form it used. An
+		// upgrade names none to upgrade everything.
+		if pkg.Name == "" && len(pkg.Names) == 0 && pkg.Action != types.ActionUpgrade {
 			AddIssue(results, types.ValidationError,
 				fmt.Sprintf("Missing required field 'packages[%d].name'", i), file, 0,
 				"Add a name field, or a names list, to the package")
 		}
 
 		validateEnum(pkg.Action, fmt.Sprintf("packages[%d].action", i),
-			[]string{types.ActionInstall, types.ActionRemove}, file, results)
+			types.PackageActions, file, results)
 
 		// The processor refuses names beginning with '-': argv-exec'd, such a
 		// name reads as an option to the elevated package manager. What a
@@ -53,8 +60,28 @@ func ValidatePackages(packages []types.Package, file string, results *types.Vali
 
 		// package_manager is optional: without one, the package is installed by the
 		// default manager detected for this machine, which is the common case.
-		if pkg.PackageManager != "" {
-			validateProviderExists(pkg.PackageManager, "package", packageLabel(pkg), file, results)
+		// A list is a fallback chain, each provider in it checked the same way.
+		for j, provider := range pkg.PackageManager {
+			if slices.Contains(pkg.PackageManager[:j], provider) {
+				AddIssue(results, types.ValidationWarning,
+					fmt.Sprintf("packages[%d].package_manager lists '%s' twice", i, provider),
+					file, 0, "A fallback chain tries each package manager once; drop the repeat")
+				continue
+			}
+			validateProviderExists(provider, "package", packageLabel(pkg), file, results)
+		}
+
+		// A pinned manager without a command for the action - pacman cannot
+		// hold a package - fails each of the entry's packages at run time.
+		optional := pkg.Action == types.ActionUpgrade || pkg.Action == types.ActionHold || pkg.Action == types.ActionUnhold
+		if optional && pkg.PackageManager.Pinned() && (pkg.Name != "" || len(pkg.Names) > 0) {
+			if definition, ok := system.GetProviderDefinition(pkg.PackageManager[0]); ok {
+				if command, _ := definition.Commands.ForAction(pkg.Action); command == "" {
+					AddIssue(results, types.ValidationError,
+						fmt.Sprintf("packages[%d]: package manager '%s' has no %s command", i, definition.Name, pkg.Action),
+						file, 0, "Use a package manager that can "+pkg.Action+" packages, or drop the entry")
+				}
+			}
 		}
 	}
 }
@@ -71,6 +98,8 @@ func ValidateRepositories(repositories []types.Repository, file string, results
 			continue
 		}
 
+		validateProfiles(repo.Profiles, fmt.Sprintf("repositories[%d].profiles", i), file, results)
+
 		validateRequired(repo.Name, fmt.Sprintf("repositories[%d].name", i), file, results, "Add name field to repository")
 
 		validateRequired(repo.PackageManager, fmt.Sprintf("repositories[%d].package_manager", i), file, results, "Add package_manager field to repository")
@@ -97,6 +126,8 @@ func ValidateFiles(files []types.File, file string, results *types.ValidationRes
 			continue
 		}
 
+		validateProfiles(f.Profiles, fmt.Sprintf("files[%d].profiles", i), file, results)
+
 		validateRequired(f.Target, fmt.Sprintf("files[%d].target", i), file, results, "Add target field to file")
 
 		validateEnum(f.Action, fmt.Sprintf("files[%d].action", i), types.FileActions, file, results)
@@ -124,6 +155,8 @@ func ValidateDirectories(directories []types.Directory, file string, results *ty
 			continue
 		}
 
+		validateProfiles(d.Profiles, fmt.Sprintf("directories[%d].profiles", i), file, results)
+
 		validateRequired(d.Target, fmt.Sprintf("directories[%d].target", i), file, results, "Add target field to directory")
 
 		validateEnum(d.Action, fmt.Sprintf("directories[%d].action", i), types.FileActions, file, results)
@@ -201,6 +234,8 @@ func ValidateGitRepositories(gitRepositories []types.Git, file string, results *
 			continue
 		}
 
+		validateProfiles(repo.Profiles, fmt.Sprintf("git[%d].profiles", i), file, results)
+
 		validateRequired(repo.URL, fmt.Sprintf("git[%d].url", i), file, results, "Add URL field to git repository")
 		validateRequired(repo.Path, fmt.Sprintf("git[%d].path", i), file, results, "Add path field to git repository")
 
@@ -224,6 +259,8 @@ func ValidateScripts(scripts []types.Script, file string, results *types.Validat
 			continue
 		}
 
+		validateProfiles(script.Profiles, fmt.Sprintf("scripts[%d].profiles", i), file, results)
+
 		validateRequired(script.Name, fmt.Sprintf("scripts[%d].name", i), file, results, "Add name field to script")
 
 		// A script comes from a file on disk (source), from inline content, or is a
@@ -235,6 +272,31 @@ func ValidateScripts(scripts []types.Script, file string, results *types.Validat
 				fmt.Sprintf("Missing required field 'scripts[%d].exec', 'scripts[%d].content' or 'scripts[%d].source'", i, i, i),
 				file, 0, "Add an exec, content, or source field to the script")
 		}
+
+		for _, field := range []struct{ name, value string }{{"timeout", script.Timeout}, {"retry_delay", script.RetryDelay}} {
+			if _, err := types.ParseScriptDuration(field.name, field.value); err != nil {
+				AddIssue(results, types.ValidationError, fmt.Sprintf("scripts[%d]: %v", i, err), file, 0,
+					"Use a Go duration such as 30s, 10m or 1h30m")
+			}
+		}
+		if script.Retries < 0 {
+			AddIssue(results, types.ValidationError, fmt.Sprintf("scripts[%d].retries is %d; it cannot be negative", i, script.Retries),
+				file, 0, "Set retries to 0 or more")
+		}
+		for _, env := range []map[string]string{script.Env, script.EnvCredentials} {
+			for name := range env {
+				if !types.ValidScriptEnvName(name) {
+					AddIssue(results, types.ValidationError, fmt.Sprintf("scripts[%d]: %q is not a valid environment variable name", i, name),
+						file, 0, "Use letters, digits and underscores, not starting with a digit")
+				}
+			}
+		}
+		for name := range script.EnvCredentials {
+			if _, ok := script.Env[name]; ok {
+				AddIssue(results, types.ValidationError, fmt.Sprintf("scripts[%d]: %s is set by both env and env_credentials", i, name),
+					file, 0, "Keep one of the two")
+			}
+		}
 	}
 }
 
@@ -251,6 +313,8 @@ func ValidateServices(services []types.Service, file string, results *types.Vali
 			continue
 		}
 
+		validateProfiles(service.Profiles, fmt.Sprintf("services[%d].profiles", i), file, results)
+
 		validateRequired(service.Name, fmt.Sprintf("services[%d].name", i), file, results, "Add name field to service")
 
 		validateEnum(service.Action, fmt.Sprintf("services[%d].action", i),
@@ -274,6 +338,8 @@ func ValidateSSHKeys(sshKeys []types.SSHKey, file string, results *types.Validat
 			continue
 		}
 
+		validateProfiles(key.Profiles, fmt.Sprintf("ssh_keys[%d].profiles", i), file, results)
+
 		validateRequired(key.Name, fmt.Sprintf("ssh_keys[%d].name", i), file, results, "Add name field to SSH key")
 
 		if key.Type == "" {
@@ -292,7 +358,8 @@ func ValidateSSHKeys(sshKeys []types.SSHKey, file string, results *types.Validat
 
 // ValidateUsers validates user definitions.
 // It checks that each user has required fields (name, action) and validates
-// that the action is one of the supported types (create, modify, delete).
+// that the action is one of the supported types (create, modify, delete,
+// shell); the shell action also needs a shell.
 // Validation issues are added to the results parameter.
 func ValidateUsers(users []types.User, file string, results *types.ValidationResults) {
 	blueprintDir := filepath.Dir(file)
@@ -301,10 +368,100 @@ func ValidateUsers(users []types.User, file string, results *types.ValidationRes
 			continue
 		}
 
+		validateProfiles(user.Profiles, fmt.Sprintf("users[%d].profiles", i), file, results)
+
 		validateRequired(user.Name, fmt.Sprintf("users[%d].name", i), file, results, "Add name field to user")
 
 		validateEnum(user.Action, fmt.Sprintf("users[%d].action", i),
-			[]string{types.UserActionCreate, types.UserActionModify, types.UserActionRemove, types.UserActionDelete}, file, results)
+			[]string{types.UserActionCreate, types.UserActionModify, types.UserActionRemove, types.UserActionDelete, types.UserActionShell}, file, results)
+
+		if user.Password != "" && user.PasswordCredential != "" {
+			AddIssue(results, types.ValidationError,
+				fmt.Sprintf("users[%d] sets both 'password' and 'password_credential'", i),
+				file, 0, "Keep password_credential and remove the inline password")
+		}
+
+		if user.Action == types.UserActionShell {
+			validateRequired(user.Shell, fmt.Sprintf("users[%d].shell", i), file, results, "Add the shell to make the login shell, e.g. zsh or /bin/zsh")
+		}
+	}
+}
+
+// ValidateSudoers validates sudoers rules. A create rule is rendered the way a
+// run renders it, so every value the processor would refuse - a bad principal,
+// a relative command, a name sudo's includedir would skip - is reported here
+// instead of failing halfway through a run.
+func ValidateSudoers(rules []types.Sudoers, file string, results *types.ValidationResults) {
+	blueprintDir := filepath.Dir(file)
+	for i, rule := range rules {
+		if validateImport(rule.Import, fmt.Sprintf("sudoers[%d]", i), blueprintDir, file, results, &types.UsersData{}) {
+			continue
+		}
+
+		validateProfiles(rule.Profiles, fmt.Sprintf("sudoers[%d].profiles", i), file, results)
+
+		validateRequired(rule.Name, fmt.Sprintf("sudoers[%d].name", i), file, results, "Add name field to sudoers rule")
+		validateEnum(rule.Action, fmt.Sprintf("sudoers[%d].action", i),
+			[]string{types.PolicyActionCreate, types.PolicyActionRemove}, file, results)
+
+		if rule.Action == types.PolicyActionCreate && rule.Name != "" {
+			if _, err := processors.RenderSudoers(rule); err != nil {
+				AddIssue(results, types.ValidationError, fmt.Sprintf("sudoers[%d]: %v", i, err), file, 0, "")
+			}
+		}
+	}
+}
+
+// ValidatePolkitRules validates polkit rules, rendering create rules the same
+// way ValidateSudoers does.
+func ValidatePolkitRules(rules []types.PolkitRule, file string, results *types.ValidationResults) {
+	blueprintDir := filepath.Dir(file)
+	for i, rule := range rules {
+		if validateImport(rule.Import, fmt.Sprintf("polkit[%d]", i), blueprintDir, file, results, &types.UsersData{}) {
+			continue
+		}
+
+		validateProfiles(rule.Profiles, fmt.Sprintf("polkit[%d].profiles", i), file, results)
+
+		validateRequired(rule.Name, fmt.Sprintf("polkit[%d].name", i), file, results, "Add name field to polkit rule")
+		validateEnum(rule.Action, fmt.Sprintf("polkit[%d].action", i),
+			[]string{types.PolicyActionCreate, types.PolicyActionRemove}, file, results)
+
+		if rule.Action == types.PolicyActionCreate && rule.Name != "" {
+			if _, err := processors.RenderPolkitRule(rule); err != nil {
+				AddIssue(results, types.ValidationError, fmt.Sprintf("polkit[%d]: %v", i, err), file, 0, "")
+			}
+		}
+	}
+}
+
+// ValidateConfigurations validates configuration entries. Only the read-back
+// tools are checked field by field: each addresses its key by a fixed set of
+// fields, and a missing one would otherwise surface as a failed run.
+func ValidateConfigurations(configs []types.Configuration, file string, results *types.ValidationResults) {
+	for i, config := range configs {
+		validateProfiles(config.Profiles, fmt.Sprintf("configurations[%d].profiles", i), file, results)
+
+		required := map[string][]string{
+			types.ConfigurationToolKDE:    {"file", "group", "key"},
+			types.ConfigurationToolXfconf: {"channel", "property"},
+			types.ConfigurationToolINI:    {"file", "key"},
+		}[config.Tool]
+		if required == nil {
+			continue
+		}
+		values := map[string]string{
+			"file": config.File, "group": config.Group, "key": config.Key,
+			"channel": config.Channel, "property": config.Property,
+		}
+		for _, field := range required {
+			validateRequired(values[field], fmt.Sprintf("configurations[%d].%s", i, field), file, results,
+				fmt.Sprintf("The %s tool needs %s", config.Tool, field))
+		}
+		if config.Value == nil {
+			AddIssue(results, types.ValidationError,
+				fmt.Sprintf("configurations[%d] declares no value", i), file, 0, "Add the value to set")
+		}
 	}
 }
 
@@ -14,7 +14,7 @@ func TestValidatePackages(t *testing.T) {
 	}{
 		{
 			"valid package",
-			[]types.Package{{Name: "vim", Action: "install", PackageManager: "apt", Names: []string{"vim"}}},
+			[]types.Package{{Name: "vim", Action: "install", PackageManager: types.ProviderChain{"apt"}, Names: []string{"vim"}}},
 			0,
 		},
 		{
@@ -22,19 +22,31 @@ func TestValidatePackages(t *testing.T) {
 			// several packages at once. This case used to expect an error, which is
 			// what made every multi-package entry in the shipped examples fail.
 			"names without name",
-			[]types.Package{{Action: "install", PackageManager: "apt", Names: []string{"vim", "curl"}}},
+			[]types.Package{{Action: "install", PackageManager: types.ProviderChain{"apt"}, Names: []string{"vim", "curl"}}},
 			0,
 		},
 		{
 			"neither name nor names",
-			[]types.Package{{Action: "install", PackageManager: "apt"}},
+			[]types.Package{{Action: "install", PackageManager: types.ProviderChain{"apt"}}},
 			1,
 		},
 		{
 			"invalid action",
-			[]types.Package{{Name: "vim", Action: "destroy", PackageManager: "apt", Names: []string{"vim"}}},
+			[]types.Package{{Name: "vim", Action: "destroy", PackageManager: types.ProviderChain{"apt"}, Names: []string{"vim"}}},
 			1,
 		},
+		{
+			// Unquoted in YAML, !server is a tag on an empty value.
+			"empty profile name",
+			[]types.Package{{Name: "vim", Action: "install", Profiles: []string{"dev", ""}}},
+			1,
+		},
+		{
+			// A contradiction is a warning, not an error.
+			"profiles that can never match",
+			[]types.Package{{Name: "vim", Action: "install", Profiles: []string{"dev", "!dev"}}},
+			0,
+		},
 		{
 			"empty packages slice",
 			[]types.Package{},
@@ -185,6 +197,23 @@ func TestValidateScripts(t *testing.T) {
 			[]types.Script{{Name: "setup"}},
 			1,
 		},
+		{
+			"timeout, retries and env",
+			[]types.Script{{Name: "fetch", Content: "curl -fsSL example.com", Timeout: "10m", Retries: 3, RetryDelay: "30s",
+				Env: map[string]string{"HTTPS_PROXY": "http://proxy:3128"}, EnvCredentials: map[string]string{"GH_TOKEN": "github_token"}}},
+			0,
+		},
+		{
+			"bad durations and negative retries",
+			[]types.Script{{Name: "fetch", Content: "true", Timeout: "ten minutes", RetryDelay: "-5s", Retries: -1}},
+			3,
+		},
+		{
+			"bad env names and a doubly-set variable",
+			[]types.Script{{Name: "fetch", Content: "true",
+				Env: map[string]string{"1BAD": "x", "TOKEN": "x"}, EnvCredentials: map[string]string{"TOKEN": "github_token"}}},
+			2,
+		},
 	}
 
 	for _, tt := range tests {
@@ -56,6 +56,28 @@ func validatePath(path string, fieldName string, file string, results *types.Val
 	}
 }
 
+// validateProfiles checks an entry's profiles list: an element that does not
+// parse is an error, and an alternative no choice of profiles can satisfy is
+// a warning - the entry it was meant to select stays off everywhere.
+func validateProfiles(profiles []string, fieldPath string, file string, results *types.ValidationResults) {
+	never, unreachable, err := helpers.CheckProfiles(profiles)
+	if err != nil {
+		AddIssue(results, types.ValidationError,
+			fmt.Sprintf("Invalid '%s': %s", fieldPath, err),
+			file, 0, "Use profile names, \"!name\" to exclude one, or \"all_of:a,b\" to require several")
+		return
+	}
+	if len(never) == 0 {
+		return
+	}
+	message := fmt.Sprintf("'%s' has alternatives that can never match: %s", fieldPath, strings.Join(never, ", "))
+	if unreachable {
+		message = fmt.Sprintf("'%s' can never match: only a run with no active profile, or --profile all, applies this entry", fieldPath)
+	}
+	AddIssue(results, types.ValidationWarning, message, file, 0,
+		"An alternative that requires a profile the entry also excludes selects nothing")
+}
+
 // validateImport checks that an import path references a valid, parseable blueprint file.
 // It verifies the file exists, detects circular imports, and can be unmarshaled as the
 // expected blueprint type. Returns true if this item is an import (so callers can skip
@@ -171,13 +193,13 @@ func validatePackagesWithVisited(packages []types.Package, file string, results
 		// name or names, the same contract ValidatePackages applies - this
 		// copy still required `name` alone, so a names-list entry that
 		// validated fine as a file errored the moment it was imported.
-		if pkg.Name == "" && len(pkg.Names) == 0 {
+		if pkg.Name == "" && len(pkg.Names) == 0 && pkg.Action != types.ActionUpgrade {
 			AddIssue(results, types.ValidationError,
 				fmt.Sprintf("Missing required field 'packages[%d].name'", i), file, 0,
 				"Add a name field, or a names list, to the package")
 		}
 		validateEnum(pkg.Action, fmt.Sprintf("packages[%d].action", i),
-			[]string{types.ActionInstall, types.ActionRemove}, file, results)
+			types.PackageActions, file, results)
 	}
 }
 
@@ -262,15 +284,16 @@ func validateUsersWithVisited(users []types.User, file string, results *types.Va
 	}
 }
 
-// validateProviderExists checks if a named package manager provider exists.
+// validateProviderExists checks that a named package manager has a provider
+// definition. Whether the machine has it is a separate question, answered
+// per machine by validateProviderAvailability: this check holds everywhere.
 func validateProviderExists(providerName string, itemType string, itemName string, file string, results *types.ValidationResults) {
 	if providerName == "" {
 		return
 	}
-	_, exists := system.GetProvider(providerName)
-	if !exists {
+	if _, exists := system.GetProviderDefinition(providerName); !exists {
 		AddIssue(results, types.ValidationWarning,
 			fmt.Sprintf("Package manager '%s' not found for %s '%s'", providerName, itemType, itemName),
-			file, 0, "Use an available package manager")
+			file, 0, "Use a package manager rwr has a provider definition for")
 	}
 }
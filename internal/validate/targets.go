@@ -0,0 +1,294 @@
+package validate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"charm.land/log/v2"
+	"github.com/fynxlabs/rwr/internal/helpers"
+	"github.com/fynxlabs/rwr/internal/processors"
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// targetRun is one tree validated for one machine.
+type targetRun struct {
+	target   types.Target
+	initFile string
+	path     string
+	host     bool // validate for this machine: a manifest entry with no matchers
+}
+
+// validateTargets validates the blueprints at options.Path once per target
+// machine instead of once for this one, tagging every issue with the target
+// it was found on. A manifest repo validates the configurations each target
+// would select; a matrix validates every configuration against the machine
+// its own matchers describe.
+func validateTargets(options types.ValidationOptions, osInfo *types.OSInfo, results *types.ValidationResults) error {
+	runs, err := planTargetRuns(options, results)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		log.Infof("Validating blueprints in %s for %s", run.path, run.target)
+		simulated, available := osInfo, map[string]*types.Provider(nil)
+		if !run.host {
+			simulated = system.SimulateOS(run.target)
+			available = system.ProvidersFor(run.target.OS, run.target.Distro)
+		}
+
+		results.Targets = append(results.Targets, run.target.String())
+		own := &types.ValidationResults{}
+		err := validateTree(run.initFile, run.path, own, simulated, available)
+		for i := range own.Issues {
+			own.Issues[i].Target = run.target.String()
+		}
+		results.Issues = append(results.Issues, own.Issues...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", run.target, err)
+		}
+	}
+
+	if options.Verbose {
+		log.Infof("Blueprint validation completed for %d targets", len(runs))
+	}
+	return nil
+}
+
+// planTargetRuns pairs each target with the tree it validates.
+func planTargetRuns(options types.ValidationOptions, results *types.ValidationResults) ([]targetRun, error) {
+	path := options.Path
+	initFile := findInitFile(path)
+	manifestPath := ""
+	if initFile == "" {
+		manifestPath = helpers.FindManifest(path)
+	}
+
+	if manifestPath == "" {
+		if options.Matrix {
+			return nil, fmt.Errorf("a matrix is read from a manifest, and %s has none", path)
+		}
+		if initFile == "" {
+			AddIssue(results, types.ValidationError, "Failed to find init file", path, 0, "Create an init file in the specified directory")
+			return nil, nil
+		}
+		runs := make([]targetRun, 0, len(options.Targets))
+		for _, target := range options.Targets {
+			runs = append(runs, targetRun{target: target, initFile: initFile, path: path})
+		}
+		return runs, nil
+	}
+
+	validateManifest(manifestPath, results)
+	manifest, err := helpers.LoadManifest(manifestPath)
+	if err != nil {
+		return nil, nil // reported by validateManifest
+	}
+	root := filepath.Dir(manifestPath)
+
+	var runs []targetRun
+	add := func(target types.Target, entry types.ManifestEntry, host bool) {
+		initPath := filepath.Join(root, entry.Init)
+		if _, statErr := os.Stat(initPath); statErr != nil {
+			return // reported by validateManifest
+		}
+		runs = append(runs, targetRun{target: target, initFile: initPath, path: filepath.Dir(initPath), host: host})
+	}
+
+	if options.Matrix {
+		for _, entry := range manifest.Configurations {
+			target, host := entryTarget(entry)
+			add(target, entry, host)
+		}
+	}
+	for _, target := range options.Targets {
+		matched := helpers.MatchManifest(manifest, system.SimulateOS(target))
+		if len(matched) == 0 {
+			AddIssue(results, types.ValidationWarning,
+				fmt.Sprintf("No manifest configuration matches %s", target), manifestPath, 0,
+				"Add a configuration for it, or drop the target")
+			continue
+		}
+		for _, entry := range matched {
+			named := target
+			named.Name = fmt.Sprintf("%s (%s)", entry.Name, target)
+			add(named, entry, false)
+		}
+	}
+	return runs, nil
+}
+
+// entryTarget describes the machine a manifest entry's matchers select. A
+// distro or family implies Linux; an entry that names no OS at all matches
+// any machine, so it is validated for this one.
+func entryTarget(entry types.ManifestEntry) (types.Target, bool) {
+	target := types.Target{
+		OS:     strings.ToLower(entry.OS),
+		Distro: strings.ToLower(entry.Distro),
+		Arch:   strings.ToLower(entry.Arch),
+	}
+	if target.Distro == "" {
+		target.Distro = strings.ToLower(entry.Family)
+	}
+	if target.OS == "" && target.Distro != "" {
+		target.OS = types.OSLinux
+	}
+	if target.OS == "" {
+		target.Name = entry.Name + " (this machine)"
+		return target, true
+	}
+	target.Name = fmt.Sprintf("%s (%s)", entry.Name, target.Spec())
+	return target, false
+}
+
+// validateProviderAvailability plans the tree's packages and repositories
+// for the machine being validated (stage 2) and reports the package
+// managers they need that it lacks. For this machine "has" means
+// installed. A simulated machine has nothing to look at, so it has what
+// the provider definitions declare for its OS and distribution - and an
+// entry that pins no package_manager needs that machine to have a default.
+func validateProviderAvailability(plan *types.Plan, osInfo *types.OSInfo, available map[string]*types.Provider, results *types.ValidationResults) {
+	simulated := available != nil
+	// Planning for this machine needs no provider set: only the managers
+	// entries pin are checked, one at a time below.
+	processors.ResolveStage2For(plan, osInfo, available)
+
+	where := "this machine"
+	if simulated {
+		where = osInfo.System.OS
+		if osInfo.System.OS == types.OSLinux && osInfo.System.OSFamily != "" {
+			where += "/" + osInfo.System.OSFamily
+		}
+	}
+
+	validatePackageAliases(plan, where, results)
+
+	// One issue per file and package manager, naming its entries: a
+	// packages file pinned to apt is one finding on macOS, not forty.
+	type key struct {
+		file, provider, itemType string
+		chain                    bool
+	}
+	var order []key
+	missing := map[key][]string{}
+	installed := map[string]bool{}
+	has := func(provider string) bool {
+		if simulated {
+			_, ok := available[provider]
+			return ok
+		}
+		ok, seen := installed[provider]
+		if !seen {
+			_, ok = system.GetProvider(provider)
+			installed[provider] = ok
+		}
+		return ok
+	}
+	for _, resource := range plan.Resources {
+		itemType := providerItemType[resource.Processor]
+		if itemType == "" {
+			continue
+		}
+		k := key{resource.File, resource.Provider, itemType, false}
+		switch {
+		case len(resource.Candidates) > 0:
+			// A fallback chain needs one provider it names, not all of them.
+			if resource.Provider != "" || slices.ContainsFunc(resource.Candidates, has) {
+				continue
+			}
+			k = key{resource.File, resource.Candidates.String(), itemType, true}
+		case resource.Provider == "":
+			if !simulated {
+				continue
+			}
+		default:
+			if _, defined := system.GetProviderDefinition(resource.Provider); !defined {
+				continue // reported against the entry by validateProviderExists
+			}
+			if has(resource.Provider) {
+				continue
+			}
+		}
+		if _, seen := missing[k]; !seen {
+			order = append(order, k)
+		}
+		missing[k] = append(missing[k], resource.Name)
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].file != order[j].file {
+			return order[i].file < order[j].file
+		}
+		return order[i].provider < order[j].provider
+	})
+	for _, k := range order {
+		names := strings.Join(missing[k], ", ")
+		switch {
+		case k.chain:
+			AddIssue(results, types.ValidationWarning,
+				fmt.Sprintf("None of the package managers %s is available on %s for %s entries: %s", k.provider, where, k.itemType, names),
+				k.file, 0, "Add a package manager available there to the chain, or install one in bootstrap")
+		case k.provider == "":
+			AddIssue(results, types.ValidationWarning,
+				fmt.Sprintf("No package manager is available on %s for %s entries without one: %s", where, k.itemType, names),
+				k.file, 0, "Pin a package_manager the target's providers declare")
+		default:
+			AddIssue(results, types.ValidationWarning,
+				fmt.Sprintf("Package manager '%s' is not available on %s for %s entries: %s", k.provider, where, k.itemType, names),
+				k.file, 0, "Use a package manager available there, or install it in bootstrap")
+		}
+	}
+}
+
+// validatePackageAliases reports the logical package names - those the
+// package alias table knows - that entries pinning no package_manager use
+// and that the alias table has no name for on the machine's default
+// provider. A run installs them as written, which may be nothing that
+// provider has. A default_package_manager chain replaces the default
+// provider, and moves on to its next provider instead.
+func validatePackageAliases(plan *types.Plan, where string, results *types.ValidationResults) {
+	if plan.DefaultProvider == "" {
+		return // reported by validateProviderAvailability
+	}
+	if plan.Init != nil && len(plan.Init.DefaultPackageManager) > 0 {
+		return
+	}
+	for _, file := range plan.Files[types.BlueprintTypePackages] {
+		var d types.PackagesData
+		if helpers.DecodeBlueprintInto(file.Resolved, file.Format, types.BlueprintTypePackages, 0, &d) != nil {
+			continue // reported by the packages validator
+		}
+		var unmapped []string
+		for _, pkg := range d.Packages {
+			if len(pkg.PackageManager) > 0 {
+				continue
+			}
+			names := pkg.Names
+			if len(names) == 0 && pkg.Name != "" {
+				names = []string{pkg.Name}
+			}
+			for _, name := range names {
+				if _, mapped := system.ResolvePackageName(name, plan.DefaultProvider); !mapped && !slices.Contains(unmapped, name) {
+					unmapped = append(unmapped, name)
+				}
+			}
+		}
+		if len(unmapped) > 0 {
+			AddIssue(results, types.ValidationWarning,
+				fmt.Sprintf("Package aliases have no name for %s on %s: %s", plan.DefaultProvider, where, strings.Join(unmapped, ", ")),
+				file.Path, 0, fmt.Sprintf("Add the %s names to aliases.cue in the providers directory, or pin a package_manager", plan.DefaultProvider))
+		}
+	}
+}
+
+// providerItemType names the entries of the blueprint types that go through
+// a package manager.
+var providerItemType = map[string]string{
+	types.BlueprintTypePackages:     "package",
+	types.BlueprintTypeRepositories: "repository",
+}
@@ -0,0 +1,188 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fynxlabs/rwr/internal/system"
+	"github.com/fynxlabs/rwr/internal/types"
+)
+
+// Targets are validated from provider declarations, not from what this
+// machine has installed, so these providers need no binaries.
+func withTargetProviders(t *testing.T) {
+	t.Helper()
+	t.Cleanup(system.SetProvidersForTest(map[string]*types.Provider{
+		"apt":  {Name: "apt", Detection: types.DetectionConfig{Binary: "apt-get", Distributions: []string{"debian", "ubuntu"}}},
+		"brew": {Name: "brew", Detection: types.DetectionConfig{Binary: "brew", Distributions: []string{"darwin"}}},
+	}))
+}
+
+func issuesFor(results *types.ValidationResults, target string) []types.ValidationIssue {
+	var issues []types.ValidationIssue
+	for _, issue := range results.Issues {
+		if issue.Target == target {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func TestValidate_TargetsUseDeclaredProviders(t *testing.T) {
+	withTargetProviders(t)
+	root := writeTree(t, map[string]string{
+		"init.yaml":         "blueprints:\n  format: yaml\n",
+		"packages/apt.yaml": "packages:\n  - name: git\n    action: install\n    package_manager: apt\n  - name: vim\n    action: install\n    package_manager: apt\n",
+		"packages/any.yaml": "packages:\n  - name: jq\n    action: install\n",
+	})
+
+	results, err := Validate(types.ValidationOptions{
+		Path:               root,
+		ValidateBlueprints: true,
+		Targets:            []types.Target{{OS: types.OSDarwin}, {OS: types.OSLinux, Distro: "ubuntu"}, {OS: types.OSLinux, Distro: "alpine"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(results.Targets, ",") != "darwin,linux/ubuntu,linux/alpine" {
+		t.Fatalf("targets = %v", results.Targets)
+	}
+
+	if issues := issuesFor(results, "linux/ubuntu"); len(issues) != 0 {
+		t.Errorf("ubuntu has apt, yet: %+v", issues)
+	}
+	darwin := issuesFor(results, "darwin")
+	if len(darwin) != 1 || !strings.Contains(darwin[0].Message, "'apt' is not available on darwin") || !strings.Contains(darwin[0].Message, "git, vim") {
+		t.Errorf("darwin issues = %+v, want one naming apt's entries", darwin)
+	}
+	// Nothing is declared for alpine: the pinned entries and the one left to
+	// the default both have no package manager there.
+	alpine := issuesFor(results, "linux/alpine")
+	if len(alpine) != 2 || !strings.Contains(alpine[0].Message, "No package manager is available on linux/alpine") {
+		t.Errorf("alpine issues = %+v", alpine)
+	}
+}
+
+// Templates render against the target's system variables, so a branch
+// taken only on one OS is validated there.
+func TestValidate_TargetsRenderTemplates(t *testing.T) {
+	withTargetProviders(t)
+	root := writeTree(t, map[string]string{
+		"init.yaml":         "blueprints:\n  format: yaml\n",
+		"packages/dev.yaml": "packages:\n  - name: git\n    action: {{ if eq .System.os \"darwin\" }}explode{{ else }}install{{ end }}\n",
+	})
+
+	results, err := Validate(types.ValidationOptions{
+		Path:               root,
+		ValidateBlueprints: true,
+		Targets:            []types.Target{{OS: types.OSDarwin}, {OS: types.OSLinux, Distro: "debian"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := issuesFor(results, "linux/debian"); len(issues) != 0 {
+		t.Errorf("debian renders install, yet: %+v", issues)
+	}
+	found := false
+	for _, issue := range issuesFor(results, "darwin") {
+		found = found || (issue.Severity == types.ValidationError && strings.Contains(issue.Message, "explode"))
+	}
+	if !found {
+		t.Errorf("darwin's rendered action not reported: %+v", results.Issues)
+	}
+}
+
+func TestValidate_MatrixFollowsManifest(t *testing.T) {
+	withTargetProviders(t)
+	pinned := "packages:\n  - name: git\n    action: install\n    package_manager: apt\n"
+	root := writeTree(t, map[string]string{
+		"manifest.yaml": "configurations:\n" +
+			"  - name: laptop\n    init: mac/init.yaml\n    os: darwin\n" +
+			"  - name: server\n    init: deb/init.yaml\n    family: debian\n",
+		"mac/init.yaml":         "blueprints:\n  format: yaml\n",
+		"mac/packages/dev.yaml": pinned,
+		"deb/init.yaml":         "blueprints:\n  format: yaml\n",
+		"deb/packages/dev.yaml": pinned,
+	})
+
+	results, err := Validate(types.ValidationOptions{Path: root, ValidateBlueprints: true, Matrix: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(results.Targets, ",") != "laptop (darwin),server (linux/debian)" {
+		t.Fatalf("targets = %v", results.Targets)
+	}
+	if len(issuesFor(results, "laptop (darwin)")) != 1 || len(issuesFor(results, "server (linux/debian)")) != 0 {
+		t.Errorf("issues = %+v", results.Issues)
+	}
+
+	// A target on a manifest repo validates the configurations it selects.
+	results, err = Validate(types.ValidationOptions{
+		Path: root, ValidateBlueprints: true,
+		Targets: []types.Target{{OS: types.OSLinux, Distro: "ubuntu"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(results.Targets, ",") != "server (linux/ubuntu)" {
+		t.Errorf("targets = %v", results.Targets)
+	}
+
+	if _, err := Validate(types.ValidationOptions{Path: root + "/mac", ValidateBlueprints: true, Matrix: true}, nil); err == nil {
+		t.Error("a matrix without a manifest validated")
+	}
+}
+
+// A logical package name the alias table has no name for on a target's
+// default provider is reported for that target only.
+func TestValidate_TargetsReportUnmappedAliases(t *testing.T) {
+	withTargetProviders(t)
+	root := writeTree(t, map[string]string{
+		"init.yaml":         "blueprints:\n  format: yaml\n",
+		"packages/dev.yaml": "packages:\n  - names: [fd, build-tools, jq]\n    action: install\n",
+	})
+
+	results, err := Validate(types.ValidationOptions{
+		Path:               root,
+		ValidateBlueprints: true,
+		Targets:            []types.Target{{OS: types.OSDarwin}, {OS: types.OSLinux, Distro: "ubuntu"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := issuesFor(results, "linux/ubuntu"); len(issues) != 0 {
+		t.Errorf("apt names every alias, yet: %+v", issues)
+	}
+	darwin := issuesFor(results, "darwin")
+	if len(darwin) != 1 || !strings.Contains(darwin[0].Message, "no name for brew on darwin: build-tools") {
+		t.Errorf("darwin issues = %+v, want one naming build-tools", darwin)
+	}
+}
+
+// A fallback chain needs one of its package managers on the target, not all
+// of them.
+func TestValidate_TargetsAcceptAnyProviderInAChain(t *testing.T) {
+	withTargetProviders(t)
+	root := writeTree(t, map[string]string{
+		"init.yaml":         "blueprints:\n  format: yaml\n",
+		"packages/dev.yaml": "packages:\n  - names: [git, jq]\n    action: install\n    package_manager: [apt, brew]\n",
+	})
+
+	results, err := Validate(types.ValidationOptions{
+		Path:               root,
+		ValidateBlueprints: true,
+		Targets:            []types.Target{{OS: types.OSDarwin}, {OS: types.OSLinux, Distro: "ubuntu"}, {OS: types.OSLinux, Distro: "alpine"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"darwin", "linux/ubuntu"} {
+		if issues := issuesFor(results, target); len(issues) != 0 {
+			t.Errorf("%s has one of the chain, yet: %+v", target, issues)
+		}
+	}
+	alpine := issuesFor(results, "linux/alpine")
+	if len(alpine) != 1 || !strings.Contains(alpine[0].Message, "None of the package managers apt, brew is available on linux/alpine") || !strings.Contains(alpine[0].Message, "git, jq") {
+		t.Errorf("alpine issues = %+v, want one naming the chain's entries", alpine)
+	}
+}
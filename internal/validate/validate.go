@@ -19,9 +19,15 @@ func Validate(options types.ValidationOptions, osInfo *types.OSInfo) (*types.Val
 		Issues: []types.ValidationIssue{},
 	}
 
-	// Validate blueprints if requested
+	// Validate blueprints if requested, for this machine or for targets
 	if options.ValidateBlueprints {
-		if err := ValidateBlueprints(options.Path, options.Verbose, results, osInfo); err != nil {
+		var err error
+		if options.Matrix || len(options.Targets) > 0 {
+			err = validateTargets(options, osInfo, results)
+		} else {
+			err = ValidateBlueprints(options.Path, options.Verbose, results, osInfo)
+		}
+		if err != nil {
 			return results, fmt.Errorf("error validating blueprints: %w", err)
 		}
 	}